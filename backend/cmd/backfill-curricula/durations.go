@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/thomas/skillhive-api/internal/config"
+	"github.com/thomas/skillhive-api/internal/duration"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+// durationsPage is the number of documents listed per query.
+const durationsPage = 100
+
+// runDurations implements the durations subcommand: it stores
+// durationSeconds on the assets and curriculum elements written before it
+// existed, parsed from their duration text, which is left as it is.
+// Curricula derive theirs and are fixed by reconcile.
+func runDurations(args []string) {
+	flags := flag.NewFlagSet("durations", flag.ExitOnError)
+	project := flags.String("project", "", "GCP project ID (overrides GCP_PROJECT env var)")
+	dryRun := flags.Bool("dry-run", false, "Count documents to fix without writing")
+	flags.Parse(args)
+
+	cfg := config.Load()
+	if *project != "" {
+		cfg.GCPProject = *project
+	}
+	ctx := context.Background()
+
+	s, closeStore, err := openStore(ctx, cfg)
+	if err != nil {
+		slog.Error("failed to open store", "backend", cfg.StoreBackend, "error", err)
+		os.Exit(1)
+	}
+	defer closeStore()
+
+	slog.Info("durations backfill starting", "backend", cfg.StoreBackend, "dryRun", *dryRun)
+
+	var stats durationStats
+	if err := backfillAssetDurations(ctx, s, *dryRun, &stats); err != nil {
+		slog.Error("asset durations backfill failed", "error", err)
+		os.Exit(1)
+	}
+	if err := backfillElementDurations(ctx, s, *dryRun, &stats); err != nil {
+		slog.Error("element durations backfill failed", "error", err)
+		os.Exit(1)
+	}
+
+	slog.Info("durations backfill complete",
+		"assetsFixed", stats.assets,
+		"elementsFixed", stats.elements,
+		"unparseable", stats.unparseable,
+		"fixed", !*dryRun,
+		"errors", stats.errors,
+	)
+	if stats.errors > 0 {
+		os.Exit(1)
+	}
+}
+
+type durationStats struct {
+	assets      int
+	elements    int
+	unparseable int
+	errors      int
+}
+
+// missingSeconds returns the seconds to store for a duration that has none,
+// or false when there is nothing to store.
+func missingSeconds(text *string, seconds *int, stats *durationStats) (int, bool) {
+	if seconds != nil || text == nil || *text == "" {
+		return 0, false
+	}
+	n, ok := duration.Parse(*text)
+	if !ok {
+		stats.unparseable++
+	}
+	return n, ok
+}
+
+func backfillAssetDurations(ctx context.Context, s store.Store, dryRun bool, stats *durationStats) error {
+	q := store.NewQuery().Limit(durationsPage)
+	for {
+		page, err := s.Assets().List(ctx, q)
+		if err != nil {
+			return err
+		}
+		for i := range page {
+			a := &page[i]
+			seconds, ok := missingSeconds(a.Duration, a.DurationSeconds, stats)
+			if !ok {
+				continue
+			}
+			stats.assets++
+			if dryRun {
+				continue
+			}
+			if err := s.Assets().Update(ctx, a.ID, []store.Update{{Path: "durationSeconds", Value: &seconds}}); err != nil {
+				stats.errors++
+				slog.Error("failed to update asset", "assetID", a.ID, "error", err)
+			}
+		}
+		if len(page) < durationsPage {
+			return nil
+		}
+		last := &page[len(page)-1]
+		q = q.StartAfter(store.CursorAt(q, last, last.ID))
+	}
+}
+
+func backfillElementDurations(ctx context.Context, s store.Store, dryRun bool, stats *durationStats) error {
+	q := store.NewQuery().Limit(durationsPage)
+	for {
+		page, err := s.Curricula().List(ctx, q)
+		if err != nil {
+			return err
+		}
+		for i := range page {
+			id := page[i].ID
+			elements, err := s.Elements().List(ctx, id, store.NewQuery())
+			if err != nil {
+				stats.errors++
+				slog.Error("failed to list elements", "curriculumID", id, "error", err)
+				continue
+			}
+			for j := range elements {
+				e := &elements[j]
+				if e.Type == model.ElementTypeClip || e.Type == model.ElementTypeSection {
+					continue
+				}
+				seconds, ok := missingSeconds(e.Duration, e.DurationSeconds, stats)
+				if !ok {
+					continue
+				}
+				stats.elements++
+				if dryRun {
+					continue
+				}
+				if err := s.Elements().Update(ctx, id, e.ID, []store.Update{{Path: "durationSeconds", Value: &seconds}}); err != nil {
+					stats.errors++
+					slog.Error("failed to update element", "curriculumID", id, "elementID", e.ID, "error", err)
+				}
+			}
+		}
+		if len(page) < durationsPage {
+			return nil
+		}
+		last := &page[len(page)-1]
+		q = q.StartAfter(store.CursorAt(q, last, last.ID))
+	}
+}
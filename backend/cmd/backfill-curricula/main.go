@@ -18,6 +18,19 @@ import (
 const firestoreMaxBatchSize = 500
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		runReconcile(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "durations" {
+		runDurations(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "sessions" {
+		runSessions(os.Args[2:])
+		return
+	}
+
 	project := flag.String("project", "", "GCP project ID (overrides GCP_PROJECT env var)")
 	dryRun := flag.Bool("dry-run", false, "Preview changes without writing to Firestore")
 	flag.Parse()
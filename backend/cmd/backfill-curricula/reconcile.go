@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/thomas/skillhive-api/internal/config"
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+// runReconcile implements the reconcile subcommand: it recomputes the
+// derived fields of every curriculum (elementCount, totalDurationSeconds,
+// durationSeconds, allTagIds and searchText) from its elements and fixes
+// the ones that drifted. It works on the store selected by STORE_BACKEND
+// (firestore, sqlite or postgres).
+func runReconcile(args []string) {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	project := flags.String("project", "", "GCP project ID (overrides GCP_PROJECT env var)")
+	discipline := flags.String("discipline", "", "Only reconcile curricula of this discipline")
+	dryRun := flags.Bool("dry-run", false, "Report drift without writing")
+	flags.Parse(args)
+
+	cfg := config.Load()
+	if *project != "" {
+		cfg.GCPProject = *project
+	}
+	ctx := context.Background()
+
+	s, closeStore, err := openStore(ctx, cfg)
+	if err != nil {
+		slog.Error("failed to open store", "backend", cfg.StoreBackend, "error", err)
+		os.Exit(1)
+	}
+	defer closeStore()
+
+	slog.Info("reconcile starting", "backend", cfg.StoreBackend, "dryRun", *dryRun)
+
+	drifted, failed := 0, 0
+	checked, err := curriculum.ReconcileAll(ctx, s, *discipline, *dryRun,
+		func(d curriculum.Drift) {
+			drifted++
+			slog.Info("curriculum drifted",
+				"curriculumID", d.CurriculumID,
+				"fields", d.Fields,
+				"storedElementCount", d.Stored.ElementCount,
+				"elementCount", d.Actual.ElementCount,
+				"storedTotalDurationSeconds", d.Stored.TotalDurationSeconds,
+				"totalDurationSeconds", d.Actual.TotalDurationSeconds,
+			)
+		},
+		func(id string, err error) {
+			failed++
+			slog.Error("failed to reconcile curriculum", "curriculumID", id, "error", err)
+		},
+	)
+	if err != nil {
+		slog.Error("reconcile failed", "error", err)
+		os.Exit(1)
+	}
+
+	slog.Info("reconcile complete",
+		"curriculaChecked", checked,
+		"curriculaDrifted", drifted,
+		"fixed", !*dryRun,
+		"errors", failed,
+	)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func openStore(ctx context.Context, cfg *config.Config) (store.Store, func(), error) {
+	switch cfg.StoreBackend {
+	case "sqlite", "postgres":
+		db, err := store.NewSQL(ctx, cfg.StoreBackend, cfg.DatabaseURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, func() { db.Close() }, nil
+	case "firestore":
+		clients, err := store.NewFirebaseClients(ctx, cfg.GCPProject, cfg.FirebaseKeyPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store.NewFirestore(clients.Firestore), func() { clients.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown STORE_BACKEND %q (want firestore, sqlite or postgres)", cfg.StoreBackend)
+	}
+}
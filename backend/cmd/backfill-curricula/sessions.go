@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/config"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/schedule"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+// runSessions implements the sessions subcommand: it stores endsAt, the end
+// of their last occurrence, on the sessions written before it existed or
+// whose stored value is out of date. Firestore leaves sessions without it out
+// of the schedule; SQL counts them as repeating forever.
+func runSessions(args []string) {
+	flags := flag.NewFlagSet("sessions", flag.ExitOnError)
+	project := flags.String("project", "", "GCP project ID (overrides GCP_PROJECT env var)")
+	dryRun := flags.Bool("dry-run", false, "Count sessions to fix without writing")
+	flags.Parse(args)
+
+	cfg := config.Load()
+	if *project != "" {
+		cfg.GCPProject = *project
+	}
+	ctx := context.Background()
+
+	s, closeStore, err := openStore(ctx, cfg)
+	if err != nil {
+		slog.Error("failed to open store", "backend", cfg.StoreBackend, "error", err)
+		os.Exit(1)
+	}
+	defer closeStore()
+
+	slog.Info("sessions backfill starting", "backend", cfg.StoreBackend, "dryRun", *dryRun)
+
+	checked, fixed, failed, err := backfillSessionEnds(ctx, s, *dryRun)
+	if err != nil {
+		slog.Error("sessions backfill failed", "error", err)
+		os.Exit(1)
+	}
+
+	slog.Info("sessions backfill complete",
+		"sessionsChecked", checked,
+		"sessionsFixed", fixed,
+		"fixed", !*dryRun,
+		"errors", failed,
+	)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func backfillSessionEnds(ctx context.Context, s store.Store, dryRun bool) (checked, fixed, failed int, err error) {
+	q := store.NewQuery().Limit(durationsPage)
+	for {
+		page, err := s.Sessions().List(ctx, q)
+		if err != nil {
+			return checked, fixed, failed, err
+		}
+		for i := range page {
+			session := &page[i]
+			checked++
+			endsAt, err := sessionEnd(session)
+			if err != nil {
+				failed++
+				slog.Error("invalid session schedule", "sessionID", session.ID, "error", err)
+				continue
+			}
+			if endsAt.Equal(session.EndsAt) {
+				continue
+			}
+			fixed++
+			if dryRun {
+				continue
+			}
+			if err := s.Sessions().Update(ctx, session.ID, []store.Update{{Path: "endsAt", Value: endsAt}}); err != nil {
+				failed++
+				slog.Error("failed to update session", "sessionID", session.ID, "error", err)
+			}
+		}
+		if len(page) < durationsPage {
+			return checked, fixed, failed, nil
+		}
+		last := &page[len(page)-1]
+		q = q.StartAfter(store.CursorAt(q, last, last.ID))
+	}
+}
+
+// sessionEnd computes the endsAt of a stored session as the API does.
+func sessionEnd(session *model.Session) (time.Time, error) {
+	loc, err := time.LoadLocation(session.TimeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var rule *schedule.Rule
+	if session.Recurrence != "" {
+		if rule, err = schedule.ParseRule(session.Recurrence, loc); err != nil {
+			return time.Time{}, err
+		}
+	}
+	length := time.Duration(session.DurationSeconds) * time.Second
+	return schedule.End(session.StartsAt.In(loc), rule, length).UTC(), nil
+}
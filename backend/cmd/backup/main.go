@@ -24,6 +24,10 @@ var collections = []string{
 	"assets",
 	"tags",
 	"curricula",
+	"sessions",
+	"calendarFeeds",
+	"progress",
+	"techniqueEdges",
 }
 
 // Collections that have known subcollections
@@ -11,6 +11,8 @@ import (
 	"cloud.google.com/go/firestore"
 	"firebase.google.com/go/v4/auth"
 	"github.com/thomas/skillhive-api/internal/config"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/schedule"
 	"github.com/thomas/skillhive-api/internal/store"
 	"google.golang.org/api/iterator"
 )
@@ -23,11 +25,15 @@ var topCollections = []string{
 	"tags",
 	"assets",
 	"curricula",
+	"sessions",
+	"calendarFeeds",
+	"progress",
+	"techniqueEdges",
 }
 
 // Subcollections keyed by parent collection name.
 var subcollections = map[string][]string{
-	"curricula": {"elements"},
+	"curricula": {"elements", "revisions"},
 }
 
 const outputPath = "seed/production-data.json"
@@ -47,16 +53,24 @@ type Export struct {
 
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run ./cmd/firestore-sync <export|import>")
+		fmt.Println("Usage: go run ./cmd/firestore-sync <export|import|sql-import>")
 		fmt.Println()
-		fmt.Println("  export  Read from Firestore and write to seed/production-data.json")
-		fmt.Println("  import  Read from seed/production-data.json and write to Firestore (emulator only)")
+		fmt.Println("  export      Read from Firestore and write to seed/production-data.json")
+		fmt.Println("  import      Read from seed/production-data.json and write to Firestore (emulator only)")
+		fmt.Println("  sql-import  Read from seed/production-data.json and write to the SQL store")
+		fmt.Println("              selected by STORE_BACKEND (sqlite|postgres) and DATABASE_URL")
 		os.Exit(1)
 	}
 
 	cfg := config.Load()
 	ctx := context.Background()
 
+	// The SQL import needs no Google Cloud access at all.
+	if os.Args[1] == "sql-import" {
+		doSQLImport(ctx, cfg)
+		return
+	}
+
 	clients, err := store.NewFirebaseClients(ctx, cfg.GCPProject, cfg.FirebaseKeyPath)
 	if err != nil {
 		slog.Error("failed to initialize Firebase", "error", err)
@@ -210,6 +224,177 @@ func doImport(ctx context.Context, clients *store.FirebaseClients) {
 	slog.Info("import complete", "totalDocuments", totalDocs, "ownerUid", localUID)
 }
 
+// --- SQL import ---
+
+// doSQLImport copies an export into a SQL store. Documents are upserted by ID,
+// so re-running an import is safe; documents missing from the export are kept.
+func doSQLImport(ctx context.Context, cfg *config.Config) {
+	if cfg.StoreBackend != "sqlite" && cfg.StoreBackend != "postgres" {
+		slog.Error("STORE_BACKEND must be sqlite or postgres for sql-import", "backend", cfg.StoreBackend)
+		os.Exit(1)
+	}
+
+	bytes, err := os.ReadFile(outputPath)
+	if err != nil {
+		slog.Error("failed to read file", "path", outputPath, "error", err)
+		os.Exit(1)
+	}
+
+	var export Export
+	if err := json.Unmarshal(bytes, &export); err != nil {
+		slog.Error("failed to parse JSON", "error", err)
+		os.Exit(1)
+	}
+
+	db, err := store.NewSQL(ctx, cfg.StoreBackend, cfg.DatabaseURL)
+	if err != nil {
+		slog.Error("failed to open SQL store", "error", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	slog.Info("importing data into SQL", "exportedAt", export.ExportedAt, "driver", cfg.StoreBackend)
+
+	totalDocs, failed := 0, 0
+	for _, name := range topCollections {
+		docs := export.Collections[name]
+		if len(docs) == 0 {
+			continue
+		}
+		slog.Info("importing", "collection", name, "count", len(docs))
+
+		for _, doc := range docs {
+			if err := writeSQLDocument(ctx, db, name, doc); err != nil {
+				slog.Error("failed to write document", "collection", name, "id", doc.ID, "error", err)
+				failed++
+				continue
+			}
+			totalDocs++
+
+			for _, sub := range subcollections[name] {
+				for _, subDoc := range doc.Subs[sub] {
+					if err := writeSQLSubDocument(ctx, db, name+"/"+doc.ID+"/"+sub, doc.ID, sub, subDoc); err != nil {
+						slog.Error("failed to write subcollection doc", "parent", doc.ID, "sub", sub, "id", subDoc.ID, "error", err)
+						failed++
+						continue
+					}
+					totalDocs++
+				}
+			}
+		}
+	}
+
+	slog.Info("sql import complete", "totalDocuments", totalDocs, "failed", failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// writeSQLDocument decodes a top-level export document into its model type
+// and upserts it.
+func writeSQLDocument(ctx context.Context, db store.Store, collection string, doc Document) error {
+	switch collection {
+	case store.CollDisciplines:
+		var v model.Discipline
+		if err := decodeSQLDocument(collection, doc, &v); err != nil {
+			return err
+		}
+		return db.Disciplines().Set(ctx, doc.ID, &v)
+	case store.CollTags:
+		var v model.Tag
+		if err := decodeSQLDocument(collection, doc, &v); err != nil {
+			return err
+		}
+		return db.Tags().Set(ctx, doc.ID, &v)
+	case store.CollCategories:
+		var v model.Category
+		if err := decodeSQLDocument(collection, doc, &v); err != nil {
+			return err
+		}
+		return db.Categories().Set(ctx, doc.ID, &v)
+	case store.CollTechniques:
+		var v model.Technique
+		if err := decodeSQLDocument(collection, doc, &v); err != nil {
+			return err
+		}
+		return db.Techniques().Set(ctx, doc.ID, &v)
+	case store.CollAssets:
+		var v model.Asset
+		if err := decodeSQLDocument(collection, doc, &v); err != nil {
+			return err
+		}
+		return db.Assets().Set(ctx, doc.ID, &v)
+	case store.CollCurricula:
+		var v model.Curriculum
+		if err := decodeSQLDocument(collection, doc, &v); err != nil {
+			return err
+		}
+		return db.Curricula().Set(ctx, doc.ID, &v)
+	case store.CollSessions:
+		var v model.Session
+		if err := decodeSQLDocument(collection, doc, &v); err != nil {
+			return err
+		}
+		// Sessions written before endsAt must not drop out of the schedule.
+		if v.EndsAt.IsZero() {
+			v.EndsAt = schedule.Forever
+		}
+		return db.Sessions().Set(ctx, doc.ID, &v)
+	case store.CollCalendarFeeds:
+		var v model.CalendarFeed
+		if err := decodeSQLDocument(collection, doc, &v); err != nil {
+			return err
+		}
+		return db.CalendarFeeds().Set(ctx, doc.ID, &v)
+	case store.CollProgress:
+		var v model.Progress
+		if err := decodeSQLDocument(collection, doc, &v); err != nil {
+			return err
+		}
+		return db.Progress().Set(ctx, doc.ID, &v)
+	case store.CollTechniqueEdges:
+		var v model.TechniqueEdge
+		if err := decodeSQLDocument(collection, doc, &v); err != nil {
+			return err
+		}
+		return db.TechniqueEdges().Set(ctx, doc.ID, &v)
+	}
+	return fmt.Errorf("unknown collection %q", collection)
+}
+
+// writeSQLSubDocument decodes a subcollection export document of a
+// curriculum into its model type and writes it.
+func writeSQLSubDocument(ctx context.Context, db store.Store, path, curriculumID, sub string, doc Document) error {
+	switch sub {
+	case store.CollElements:
+		var v model.CurriculumElement
+		if err := decodeSQLDocument(path, doc, &v); err != nil {
+			return err
+		}
+		return db.Elements().Set(ctx, curriculumID, doc.ID, &v)
+	case store.CollRevisions:
+		var v model.CurriculumRevision
+		if err := decodeSQLDocument(path, doc, &v); err != nil {
+			return err
+		}
+		return db.Revisions().Set(ctx, curriculumID, doc.ID, &v)
+	}
+	return fmt.Errorf("unknown subcollection %q", sub)
+}
+
+// decodeSQLDocument decodes export data, warning about fields the model
+// does not know (they cannot be stored in SQL).
+func decodeSQLDocument(collection string, doc Document, dst interface{}) error {
+	skipped, err := store.DecodeDocument(doc.Data, dst)
+	if err != nil {
+		return err
+	}
+	if len(skipped) > 0 {
+		slog.Warn("dropping unknown fields", "collection", collection, "id", doc.ID, "fields", skipped)
+	}
+	return nil
+}
+
 // ensureAdminUser creates the admin user in the Auth emulator if it doesn't exist,
 // and returns their UID for ownerUid remapping.
 func ensureAdminUser(ctx context.Context, authClient *auth.Client) string {
@@ -0,0 +1,143 @@
+// Command import-curriculum builds a curriculum from a Markdown or YAML
+// outline (see package outline) and prints the preview as JSON. With
+// -commit it also writes the curriculum, owned by -owner, to the store
+// selected by STORE_BACKEND (firestore, sqlite or postgres).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/config"
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/outline"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+func main() {
+	project := flag.String("project", "", "GCP project ID (overrides GCP_PROJECT env var)")
+	discipline := flag.String("discipline", "", "Discipline to import into (required)")
+	file := flag.String("file", "", "Outline file (required)")
+	format := flag.String("format", "", "markdown or yaml (default: from the file extension)")
+	owner := flag.String("owner", "", "UID owning the imported curriculum (required with -commit)")
+	title := flag.String("title", "", "Override the outline's title")
+	commit := flag.Bool("commit", false, "Write the curriculum instead of only previewing it")
+	allowUnresolved := flag.Bool("allow-unresolved", false, "Commit unresolved references as text elements")
+	flag.Parse()
+
+	if *discipline == "" || *file == "" {
+		fmt.Fprintln(os.Stderr, "error: --discipline and --file are required")
+		os.Exit(1)
+	}
+	if *commit && *owner == "" {
+		fmt.Fprintln(os.Stderr, "error: --owner is required with --commit")
+		os.Exit(1)
+	}
+	f := outline.Format(*format)
+	if f == "" {
+		f = formatOf(*file)
+	}
+	if f != outline.FormatMarkdown && f != outline.FormatYAML {
+		fmt.Fprintln(os.Stderr, "error: --format must be markdown or yaml")
+		os.Exit(1)
+	}
+
+	src, err := os.ReadFile(*file)
+	if err != nil {
+		slog.Error("failed to read outline", "file", *file, "error", err)
+		os.Exit(1)
+	}
+	draft, err := outline.Parse(f, string(src))
+	if err != nil {
+		slog.Error("failed to parse outline", "file", *file, "error", err)
+		os.Exit(1)
+	}
+	if *title != "" {
+		draft.Title = *title
+	}
+
+	cfg := config.Load()
+	if *project != "" {
+		cfg.GCPProject = *project
+	}
+	ctx := context.Background()
+
+	s, closeStore, err := openStore(ctx, cfg)
+	if err != nil {
+		slog.Error("failed to open store", "backend", cfg.StoreBackend, "error", err)
+		os.Exit(1)
+	}
+	defer closeStore()
+
+	preview, err := outline.Build(ctx, outline.NewResolver(s, *discipline), draft)
+	if err != nil {
+		slog.Error("failed to build outline", "file", *file, "error", err)
+		os.Exit(1)
+	}
+	for _, u := range preview.Unresolved {
+		slog.Warn("unresolved reference", "line", u.Line, "kind", u.Kind, "ref", u.Ref)
+	}
+
+	if *commit {
+		if len(preview.Unresolved) > 0 && !*allowUnresolved {
+			slog.Error("outline has unresolved references; fix them or pass --allow-unresolved",
+				"unresolved", len(preview.Unresolved))
+			os.Exit(1)
+		}
+		change := curriculum.Change{Action: model.RevisionCreate, ActorUID: *owner, At: time.Now()}
+		if err := outline.Commit(ctx, s, preview, change); err != nil {
+			slog.Error("failed to import curriculum", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("curriculum imported",
+			"curriculumID", preview.Curriculum.ID,
+			"elements", len(preview.Elements),
+			"unresolved", len(preview.Unresolved),
+		)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(preview); err != nil {
+		slog.Error("failed to write preview", "error", err)
+		os.Exit(1)
+	}
+}
+
+// formatOf infers the outline format from a file name.
+func formatOf(name string) outline.Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown":
+		return outline.FormatMarkdown
+	case ".yaml", ".yml":
+		return outline.FormatYAML
+	}
+	return ""
+}
+
+func openStore(ctx context.Context, cfg *config.Config) (store.Store, func(), error) {
+	switch cfg.StoreBackend {
+	case "sqlite", "postgres":
+		db, err := store.NewSQL(ctx, cfg.StoreBackend, cfg.DatabaseURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, func() { db.Close() }, nil
+	case "firestore":
+		clients, err := store.NewFirebaseClients(ctx, cfg.GCPProject, cfg.FirebaseKeyPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store.NewFirestore(clients.Firestore), func() { clients.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown STORE_BACKEND %q (want firestore, sqlite or postgres)", cfg.StoreBackend)
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"cloud.google.com/go/firestore"
 	"github.com/thomas/skillhive-api/internal/config"
+	"github.com/thomas/skillhive-api/internal/duration"
 	"github.com/thomas/skillhive-api/internal/store"
 	"github.com/thomas/skillhive-api/internal/validate"
 	"google.golang.org/api/iterator"
@@ -400,6 +401,9 @@ func main() {
 		if v.Original.ThumbnailURL != "" {
 			assetData["thumbnailUrl"] = v.Original.ThumbnailURL
 		}
+		if text, seconds, ok := duration.Normalize(v.Original.Duration); ok {
+			assetData["duration"], assetData["durationSeconds"] = text, seconds
+		}
 
 		_, err = assetRef.Set(ctx, assetData)
 		if err != nil {
@@ -14,6 +14,7 @@ import (
 
 	"cloud.google.com/go/firestore"
 	"github.com/thomas/skillhive-api/internal/config"
+	"github.com/thomas/skillhive-api/internal/duration"
 	"github.com/thomas/skillhive-api/internal/llm"
 	"github.com/thomas/skillhive-api/internal/model"
 	"github.com/thomas/skillhive-api/internal/store"
@@ -356,6 +357,10 @@ func main() {
 			"updatedAt":    now,
 		}
 
+		if text, seconds, ok := duration.Normalize(v.Original.Duration); ok {
+			asset["duration"], asset["durationSeconds"] = text, seconds
+		}
+
 		if finalVideoType != "" {
 			asset["videoType"] = finalVideoType
 		}
@@ -12,6 +12,7 @@ import (
 
 	"cloud.google.com/go/firestore"
 	"github.com/thomas/skillhive-api/internal/config"
+	"github.com/thomas/skillhive-api/internal/duration"
 	"github.com/thomas/skillhive-api/internal/model"
 	"github.com/thomas/skillhive-api/internal/store"
 	"google.golang.org/api/option"
@@ -140,6 +141,10 @@ func main() {
 			"updatedAt":    now,
 		}
 
+		if text, seconds, ok := duration.Normalize(v.Duration); ok {
+			asset["duration"], asset["durationSeconds"] = text, seconds
+		}
+
 		if *videoType != "" {
 			asset["videoType"] = *videoType
 		}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +17,18 @@ type Config struct {
 	YouTubeAPIKey      string
 	GeminiAPIKey       string
 	GeminiModel        string
+	StoreBackend       string
+	DatabaseURL        string
+	// SnapshotSweepInterval is how often curriculum element snapshots are
+	// refreshed in the background; zero disables the sweep.
+	SnapshotSweepInterval time.Duration
+	// DeletePolicy* are what deleting a tag, category, technique or asset
+	// does to the documents referring to it, unless the request says:
+	// "restrict" or "detach".
+	DeletePolicyTags       string
+	DeletePolicyCategories string
+	DeletePolicyTechniques string
+	DeletePolicyAssets     string
 }
 
 func Load() *Config {
@@ -29,6 +43,15 @@ func Load() *Config {
 		YouTubeAPIKey:      getEnv("YOUTUBE_API_KEY", ""),
 		GeminiAPIKey:       getEnv("GEMINI_API_KEY", ""),
 		GeminiModel:        getEnv("GEMINI_MODEL", "gemini-2.0-flash"),
+		StoreBackend:       getEnv("STORE_BACKEND", "firestore"),
+		DatabaseURL:        getEnv("DATABASE_URL", "skillhive.db"),
+
+		SnapshotSweepInterval: getDuration("SNAPSHOT_SWEEP_INTERVAL", 6*time.Hour),
+
+		DeletePolicyTags:       getDeletePolicy("DELETE_POLICY_TAGS"),
+		DeletePolicyCategories: getDeletePolicy("DELETE_POLICY_CATEGORIES"),
+		DeletePolicyTechniques: getDeletePolicy("DELETE_POLICY_TECHNIQUES"),
+		DeletePolicyAssets:     getDeletePolicy("DELETE_POLICY_ASSETS"),
 	}
 }
 
@@ -42,3 +65,33 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getDuration parses a duration such as "30m" or "6h", falling back on an
+// unset or invalid value.
+func getDuration(key string, fallback time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil || d < 0 {
+		slog.Warn("invalid duration, using default", "key", key, "value", val, "default", fallback)
+		return fallback
+	}
+	return d
+}
+
+// getDeletePolicy reads a default delete policy, "detach" unless set to
+// "restrict". Reassigning needs a replacement, so only requests can ask
+// for it.
+func getDeletePolicy(key string) string {
+	val := os.Getenv(key)
+	switch val {
+	case "":
+		return "detach"
+	case "restrict", "detach":
+		return val
+	}
+	slog.Warn("invalid delete policy, using default", "key", key, "value", val, "default", "detach")
+	return "detach"
+}
@@ -0,0 +1,66 @@
+package curriculum
+
+import (
+	"slices"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+// Access is what a user may do with one curriculum. Each level includes the
+// ones below it.
+type Access int
+
+const (
+	// AccessNone hides a private curriculum from the user.
+	AccessNone Access = iota
+	// AccessView allows reading the curriculum, its elements and revisions,
+	// exporting and forking it.
+	AccessView
+	// AccessEdit allows changing the curriculum and its elements, restoring
+	// revisions and pulling upstream changes.
+	AccessEdit
+	// AccessOwner allows deleting the curriculum, changing its visibility
+	// and managing its collaborators.
+	AccessOwner
+)
+
+// AccessOf returns the access uid has to c: owners and invited collaborators
+// get theirs, and everyone else may view public curricula.
+func AccessOf(c *model.Curriculum, uid string) Access {
+	switch {
+	case uid == "":
+	case uid == c.OwnerUID:
+		return AccessOwner
+	case slices.Contains(c.EditorUIDs, uid):
+		return AccessEdit
+	case slices.Contains(c.ViewerUIDs, uid):
+		return AccessView
+	}
+	if c.IsPublic {
+		return AccessView
+	}
+	return AccessNone
+}
+
+// Readers returns the users who may view c, or nil when it is public.
+func Readers(c *model.Curriculum) []string {
+	if c.IsPublic {
+		return nil
+	}
+	readers := []string{c.OwnerUID}
+	readers = append(readers, c.EditorUIDs...)
+	return append(readers, c.ViewerUIDs...)
+}
+
+// Collaborators returns the users invited to c with their roles, editors
+// first, without account details.
+func Collaborators(c *model.Curriculum) []model.Collaborator {
+	out := make([]model.Collaborator, 0, len(c.EditorUIDs)+len(c.ViewerUIDs))
+	for _, uid := range c.EditorUIDs {
+		out = append(out, model.Collaborator{UID: uid, Role: model.CollaboratorEditor})
+	}
+	for _, uid := range c.ViewerUIDs {
+		out = append(out, model.Collaborator{UID: uid, Role: model.CollaboratorViewer})
+	}
+	return out
+}
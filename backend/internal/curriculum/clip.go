@@ -0,0 +1,49 @@
+package curriculum
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/youtube"
+)
+
+// ClipSeconds returns the length of a clip element, or 0 for other elements.
+func ClipSeconds(e *model.CurriculumElement) int {
+	if e.Type != model.ElementTypeClip || e.StartSeconds == nil || e.EndSeconds == nil {
+		return 0
+	}
+	return max(*e.EndSeconds-*e.StartSeconds, 0)
+}
+
+// ClipURL returns a link that plays the segment of videoURL from start:
+// YouTube's watch URL with t, or any other URL with a media fragment.
+func ClipURL(videoURL string, start, end int) string {
+	if id := youtube.ExtractVideoID(videoURL); id != "" {
+		return fmt.Sprintf("https://www.youtube.com/watch?v=%s&t=%ds", url.QueryEscape(id), start)
+	}
+	base, _, _ := strings.Cut(videoURL, "#")
+	return fmt.Sprintf("%s#t=%d,%d", base, start, end)
+}
+
+// ClipEmbedURL returns the YouTube embed URL that plays only the segment of
+// videoURL, or "" when it is not a YouTube video.
+func ClipEmbedURL(videoURL string, start, end int) string {
+	id := youtube.ExtractVideoID(videoURL)
+	if id == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://www.youtube.com/embed/%s?start=%d&end=%d", url.PathEscape(id), start, end)
+}
+
+// SetClipLinks sets the links of e when it is a clip whose snapshot has the
+// video's URL.
+func SetClipLinks(e *model.CurriculumElement) {
+	if e.Type != model.ElementTypeClip || e.StartSeconds == nil || e.EndSeconds == nil ||
+		e.Snapshot == nil || e.Snapshot.URL == "" {
+		return
+	}
+	e.ClipURL = ClipURL(e.Snapshot.URL, *e.StartSeconds, *e.EndSeconds)
+	e.EmbedURL = ClipEmbedURL(e.Snapshot.URL, *e.StartSeconds, *e.EndSeconds)
+}
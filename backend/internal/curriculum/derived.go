@@ -0,0 +1,253 @@
+// Package curriculum maintains the data derived from a curriculum's elements
+// that is stored on the curriculum document itself: the element counters and
+// the denormalized allTagIds and searchText. Every element write reads the
+// curriculum and all of its elements in a transaction and stores the
+// recomputed fields with the write, so they cannot go stale. The same
+// transaction records the change as a revision.
+package curriculum
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/thomas/skillhive-api/internal/duration"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+	"github.com/thomas/skillhive-api/internal/validate"
+)
+
+// Counters are the element summaries stored on a curriculum.
+type Counters struct {
+	ElementCount         int `json:"elementCount"`
+	TotalDurationSeconds int `json:"totalDurationSeconds"`
+}
+
+// Derived holds every stored curriculum field computed from the curriculum's
+// own title, description, duration and tags and from its elements.
+type Derived struct {
+	Counters
+	DurationSeconds int      `json:"durationSeconds"`
+	AllTagIDs       []string `json:"allTagIds"`
+	SearchText      string   `json:"searchText"`
+}
+
+// Stored returns the derived fields currently stored on c.
+func Stored(c *model.Curriculum) Derived {
+	return Derived{
+		Counters:        Counters{ElementCount: c.ElementCount, TotalDurationSeconds: c.TotalDurationSeconds},
+		DurationSeconds: c.DurationSeconds,
+		AllTagIDs:       c.AllTagIDs,
+		SearchText:      c.SearchText,
+	}
+}
+
+// Apply sets the derived fields of c to d.
+func (d Derived) Apply(c *model.Curriculum) {
+	c.ElementCount, c.TotalDurationSeconds = d.ElementCount, d.TotalDurationSeconds
+	c.DurationSeconds = d.DurationSeconds
+	c.AllTagIDs, c.SearchText = d.AllTagIDs, d.SearchText
+}
+
+// Derive computes the derived fields of c with the given elements. The
+// result does not depend on the order of elements.
+func Derive(c *model.Curriculum, elements []model.CurriculumElement) Derived {
+	elements = slices.Clone(elements)
+	slices.SortFunc(elements, func(a, b model.CurriculumElement) int { return strings.Compare(a.ID, b.ID) })
+
+	d := Derived{Counters: Counters{ElementCount: len(elements)}, DurationSeconds: duration.Of(c.Duration)}
+	tagIDs := slices.Clone(c.TagIDs)
+	parts := []string{c.Title, c.Description}
+	for i := range elements {
+		e := &elements[i]
+		d.TotalDurationSeconds += ElementSeconds(e)
+		if e.Title != nil {
+			parts = append(parts, *e.Title)
+		}
+		if e.Details != nil {
+			parts = append(parts, validate.StripAllHTML(*e.Details))
+		}
+		parts = append(parts, e.Items...)
+		if e.Caption != nil {
+			parts = append(parts, *e.Caption)
+		}
+		if e.Snapshot != nil {
+			parts = append(parts, e.Snapshot.Name, e.Snapshot.Description)
+			tagIDs = append(tagIDs, e.Snapshot.TagIDs...)
+		}
+	}
+
+	d.AllTagIDs = []string{}
+	for _, id := range tagIDs {
+		if !slices.Contains(d.AllTagIDs, id) {
+			d.AllTagIDs = append(d.AllTagIDs, id)
+		}
+	}
+	parts = slices.DeleteFunc(parts, func(s string) bool { return s == "" })
+	d.SearchText = strings.ToLower(strings.Join(parts, " "))
+	return d
+}
+
+// ElementSeconds is what an element adds to its curriculum's total duration:
+// the length of a clip, or the element's duration. Elements without a
+// parseable duration add nothing.
+func ElementSeconds(e *model.CurriculumElement) int {
+	switch {
+	case e.Type == model.ElementTypeClip:
+		return ClipSeconds(e)
+	case e.DurationSeconds != nil:
+		return *e.DurationSeconds
+	}
+	return duration.Of(e.Duration)
+}
+
+// Updates returns the updates that store d.
+func (d Derived) Updates() []store.Update {
+	return []store.Update{
+		{Path: "elementCount", Value: d.ElementCount},
+		{Path: "totalDurationSeconds", Value: d.TotalDurationSeconds},
+		{Path: "durationSeconds", Value: d.DurationSeconds},
+		{Path: "allTagIds", Value: d.AllTagIDs},
+		{Path: "searchText", Value: d.SearchText},
+	}
+}
+
+// Diff returns the stored names of the fields that differ between d and o.
+func (d Derived) Diff(o Derived) []string {
+	var fields []string
+	if d.ElementCount != o.ElementCount {
+		fields = append(fields, "elementCount")
+	}
+	if d.TotalDurationSeconds != o.TotalDurationSeconds {
+		fields = append(fields, "totalDurationSeconds")
+	}
+	if d.DurationSeconds != o.DurationSeconds {
+		fields = append(fields, "durationSeconds")
+	}
+	if !slices.Equal(d.AllTagIDs, o.AllTagIDs) {
+		fields = append(fields, "allTagIds")
+	}
+	if d.SearchText != o.SearchText {
+		fields = append(fields, "searchText")
+	}
+	return fields
+}
+
+// Load reads a curriculum and all of its elements in tx.
+func Load(s store.Store, tx store.Tx, id string) (*model.Curriculum, []model.CurriculumElement, error) {
+	var c model.Curriculum
+	if err := tx.Get(s.Curricula().Ref(id), &c); err != nil {
+		return nil, nil, err
+	}
+	var elements []model.CurriculumElement
+	if err := tx.List(store.ElementsPath(id), store.NewQuery(), &elements); err != nil {
+		return nil, nil, err
+	}
+	return &c, elements, nil
+}
+
+// Save queues the update of a curriculum in tx: the given field updates, its
+// derived fields for the elements it will have once tx commits, and a new
+// revision recording the change. c must already reflect the field updates.
+func Save(s store.Store, tx store.Tx, c *model.Curriculum, elements []model.CurriculumElement, change Change, updates ...store.Update) error {
+	rev, err := Record(s, tx, c, elements, change)
+	if err != nil {
+		return err
+	}
+	updates = append(updates, store.Update{Path: "revision", Value: rev.Number})
+	tx.Update(s.Curricula().Ref(c.ID), append(updates, Derive(c, elements).Updates()...))
+	return nil
+}
+
+// Create queues in tx a new curriculum c, whose ID the caller sets, with
+// elements in the given reading order, and records it as revision 1.
+// Elements without an ID get one, and all are numbered (see Number).
+func Create(s store.Store, tx store.Tx, c *model.Curriculum, elements []model.CurriculumElement, change Change) error {
+	if len(elements) > ElementsPerTx {
+		return ErrTooLarge
+	}
+	Number(elements)
+	for i := range elements {
+		if elements[i].ID == "" {
+			elements[i].ID = store.NewID()
+		}
+		tx.Set(s.Elements().Ref(c.ID, elements[i].ID), &elements[i])
+	}
+	Derive(c, elements).Apply(c)
+	c.Revision = 0
+	if _, err := Record(s, tx, c, elements, change); err != nil {
+		return err
+	}
+	tx.Set(s.Curricula().Ref(c.ID), c)
+	return nil
+}
+
+// Drift describes a curriculum whose stored derived fields differ from what
+// its elements give.
+type Drift struct {
+	CurriculumID string   `json:"curriculumId"`
+	Title        string   `json:"title"`
+	Fields       []string `json:"fields"`
+	Stored       Derived  `json:"stored"`
+	Actual       Derived  `json:"actual"`
+}
+
+// Reconcile recomputes the derived fields of a curriculum and, unless dryRun
+// is set, stores them. It returns nil when nothing drifted.
+func Reconcile(ctx context.Context, s store.Store, id string, dryRun bool) (*Drift, error) {
+	var drift *Drift
+	err := s.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		drift = nil
+		c, elements, err := Load(s, tx, id)
+		if err != nil {
+			return err
+		}
+		actual := Derive(c, elements)
+		fields := Stored(c).Diff(actual)
+		if len(fields) == 0 {
+			return nil
+		}
+		drift = &Drift{CurriculumID: id, Title: c.Title, Fields: fields, Stored: Stored(c), Actual: actual}
+		if !dryRun {
+			tx.Update(s.Curricula().Ref(id), actual.Updates())
+		}
+		return nil
+	})
+	return drift, err
+}
+
+// reconcilePage is the number of curricula listed per query by ReconcileAll.
+const reconcilePage = 100
+
+// ReconcileAll reconciles every curriculum of a discipline, or of all
+// disciplines when disciplineID is empty, calling report for each one that
+// drifted. A curriculum that fails to reconcile is passed to onError and
+// skipped.
+func ReconcileAll(ctx context.Context, s store.Store, disciplineID string, dryRun bool, report func(Drift), onError func(id string, err error)) (checked int, err error) {
+	q := store.NewQuery()
+	if disciplineID != "" {
+		q = q.Where("disciplineId", store.OpEqual, disciplineID)
+	}
+	q = q.Limit(reconcilePage)
+	for {
+		page, err := s.Curricula().List(ctx, q)
+		if err != nil {
+			return checked, err
+		}
+		for i := range page {
+			checked++
+			drift, err := Reconcile(ctx, s, page[i].ID, dryRun)
+			switch {
+			case err != nil:
+				onError(page[i].ID, err)
+			case drift != nil:
+				report(*drift)
+			}
+		}
+		if len(page) < reconcilePage {
+			return checked, nil
+		}
+		last := &page[len(page)-1]
+		q = q.StartAfter(store.CursorAt(q, last, last.ID))
+	}
+}
@@ -0,0 +1,194 @@
+package curriculum
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+// FieldChange is a field whose value differs between two revisions. Values
+// are JSON, null for an absent field.
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+// ElementMove is an element that changed places relative to the others.
+// Positions count from 1.
+type ElementMove struct {
+	ID   string `json:"id"`
+	From int    `json:"from"`
+	To   int    `json:"to"`
+}
+
+// ElementChange is an element present in both revisions with different
+// content. Fields lists the differing element fields.
+type ElementChange struct {
+	ID     string                  `json:"id"`
+	Fields []string                `json:"fields"`
+	From   model.CurriculumElement `json:"from"`
+	To     model.CurriculumElement `json:"to"`
+}
+
+// RevisionDiff describes how to get from one revision to another.
+type RevisionDiff struct {
+	From    int                       `json:"from"`
+	To      int                       `json:"to"`
+	Fields  []FieldChange             `json:"fields"`
+	Added   []model.CurriculumElement `json:"added"`
+	Removed []model.CurriculumElement `json:"removed"`
+	Moved   []ElementMove             `json:"moved"`
+	Changed []ElementChange           `json:"changed"`
+}
+
+// DiffRevisions compares the content of two revisions of a curriculum.
+// Elements are matched by ID. Moved lists the fewest elements whose moves
+// explain the new order: those outside the longest common subsequence of
+// the two orders. Only element content is compared (see elementContent).
+func DiffRevisions(from, to *model.CurriculumRevision) (*RevisionDiff, error) {
+	a, err := Content(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := Content(to)
+	if err != nil {
+		return nil, err
+	}
+	d := &RevisionDiff{
+		From:    from.Number,
+		To:      to.Number,
+		Fields:  []FieldChange{},
+		Added:   []model.CurriculumElement{},
+		Removed: []model.CurriculumElement{},
+		Moved:   []ElementMove{},
+		Changed: []ElementChange{},
+	}
+
+	fa, fb := jsonFields(a, "elements"), jsonFields(b, "elements")
+	for _, name := range changedFields(fa, fb) {
+		d.Fields = append(d.Fields, FieldChange{Field: name, From: orNull(fa[name]), To: orNull(fb[name])})
+	}
+
+	posA, posB := positions(a.Elements), positions(b.Elements)
+	for _, e := range a.Elements {
+		if _, ok := posB[e.ID]; !ok {
+			d.Removed = append(d.Removed, e)
+		}
+	}
+	var commonA, commonB []string
+	for _, e := range a.Elements {
+		if _, ok := posB[e.ID]; ok {
+			commonA = append(commonA, e.ID)
+		}
+	}
+	for _, e := range b.Elements {
+		i, ok := posA[e.ID]
+		if !ok {
+			d.Added = append(d.Added, e)
+			continue
+		}
+		commonB = append(commonB, e.ID)
+		fields := changedFields(elementContent(a.Elements[i-1]), elementContent(e))
+		if len(fields) > 0 {
+			d.Changed = append(d.Changed, ElementChange{ID: e.ID, Fields: fields, From: a.Elements[i-1], To: e})
+		}
+	}
+	stay := longestCommon(commonA, commonB)
+	for _, id := range commonB {
+		if !stay[id] {
+			d.Moved = append(d.Moved, ElementMove{ID: id, From: posA[id], To: posB[id]})
+		}
+	}
+	return d, nil
+}
+
+// positions maps element IDs to their 1-based position.
+func positions(elements []model.CurriculumElement) map[string]int {
+	pos := make(map[string]int, len(elements))
+	for i, e := range elements {
+		pos[e.ID] = i + 1
+	}
+	return pos
+}
+
+// jsonFields returns the JSON-encoded fields of v, except the skipped ones.
+// Comparing encodings treats nil and empty omitempty slices alike.
+func jsonFields(v interface{}, skip ...string) map[string]json.RawMessage {
+	data, _ := json.Marshal(v)
+	var fields map[string]json.RawMessage
+	_ = json.Unmarshal(data, &fields)
+	for _, name := range skip {
+		delete(fields, name)
+	}
+	return fields
+}
+
+// elementContent returns the JSON-encoded content fields of an element:
+// all but its identity, position and timestamps.
+func elementContent(e model.CurriculumElement) map[string]json.RawMessage {
+	return jsonFields(e, "id", "ord", "createdAt", "updatedAt", "sourceElementId")
+}
+
+// sameContent reports whether two elements have the same content.
+func sameContent(a, b model.CurriculumElement) bool {
+	return len(changedFields(elementContent(a), elementContent(b))) == 0
+}
+
+// changedFields returns the sorted names of the fields that differ.
+func changedFields(a, b map[string]json.RawMessage) []string {
+	var names []string
+	for name := range a {
+		if !bytes.Equal(a[name], b[name]) {
+			names = append(names, name)
+		}
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func orNull(v json.RawMessage) json.RawMessage {
+	if v == nil {
+		return json.RawMessage("null")
+	}
+	return v
+}
+
+// longestCommon returns the IDs of a longest common subsequence of a and b.
+func longestCommon(a, b []string) map[string]bool {
+	// n[i][j] is the LCS length of a[i:] and b[j:].
+	n := make([][]int, len(a)+1)
+	for i := range n {
+		n[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				n[i][j] = n[i+1][j+1] + 1
+			} else {
+				n[i][j] = max(n[i+1][j], n[i][j+1])
+			}
+		}
+	}
+	in := map[string]bool{}
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			in[a[i]] = true
+			i++
+			j++
+		case n[i+1][j] >= n[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return in
+}
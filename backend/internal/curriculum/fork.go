@@ -0,0 +1,425 @@
+package curriculum
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+var (
+	// ErrNotFork is returned when pulling into a curriculum that is not a fork.
+	ErrNotFork = errors.New("curriculum: not a fork")
+	// ErrUpstreamGone is returned when the upstream of a fork was deleted.
+	ErrUpstreamGone = errors.New("curriculum: upstream curriculum not found")
+	// ErrNoBaseRevision is returned when the upstream revision a fork last
+	// caught up with is not recorded, so changes since cannot be told apart.
+	ErrNoBaseRevision = errors.New("curriculum: upstream revision of the fork not found")
+)
+
+// Remap translates the discipline-scoped references of a curriculum copied
+// into another discipline: tags and techniques to the ones with the same
+// slug there, assets to the one with the same URL. References without a
+// match are dropped. Within one discipline nothing changes.
+type Remap struct {
+	same       bool
+	tags       map[string]string
+	techniques map[string]string
+	assets     map[string]string
+}
+
+// NewRemap looks up in tx the counterparts in discipline to of the given
+// tags and of everything the elements reference.
+func NewRemap(s store.Store, tx store.Tx, from, to string, tagIDs []string, elements ...[]model.CurriculumElement) (*Remap, error) {
+	r := &Remap{same: from == to, tags: map[string]string{}, techniques: map[string]string{}, assets: map[string]string{}}
+	if r.same {
+		return r, nil
+	}
+	tag := func(id string) error {
+		return lookup(r.tags, id, func() (string, error) {
+			return counterpart(tx, s.Tags().Ref(id), to, "slug", func(t *model.Tag) (string, string) { return t.ID, t.Slug })
+		})
+	}
+	for _, list := range elements {
+		for _, e := range list {
+			if e.TechniqueID != nil {
+				err := lookup(r.techniques, *e.TechniqueID, func() (string, error) {
+					return counterpart(tx, s.Techniques().Ref(*e.TechniqueID), to, "slug",
+						func(t *model.Technique) (string, string) { return t.ID, t.Slug })
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+			if e.AssetID != nil {
+				err := lookup(r.assets, *e.AssetID, func() (string, error) {
+					return counterpart(tx, s.Assets().Ref(*e.AssetID), to, "url",
+						func(a *model.Asset) (string, string) { return a.ID, a.URL })
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+			if e.Snapshot != nil {
+				tagIDs = append(tagIDs, e.Snapshot.TagIDs...)
+			}
+		}
+	}
+	for _, id := range tagIDs {
+		if err := tag(id); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// lookup fills m[id] with find unless it is known already.
+func lookup(m map[string]string, id string, find func() (string, error)) error {
+	if _, ok := m[id]; ok {
+		return nil
+	}
+	match, err := find()
+	m[id] = match
+	return err
+}
+
+// counterpart returns the ID of the document in discipline to whose key
+// field has the value of that field on the document at ref, or "" when
+// there is none. keyOf returns the ID and key of a document.
+func counterpart[T any](tx store.Tx, ref store.DocRef, to, key string, keyOf func(*T) (string, string)) (string, error) {
+	var doc T
+	if err := tx.Get(ref, &doc); errors.Is(err, store.ErrNotFound) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	_, value := keyOf(&doc)
+	var matches []T
+	q := store.NewQuery().Where("disciplineId", store.OpEqual, to).Where(key, store.OpEqual, value).Limit(1)
+	if err := tx.List(ref.Collection, q, &matches); err != nil || len(matches) == 0 {
+		return "", err
+	}
+	id, _ := keyOf(&matches[0])
+	return id, nil
+}
+
+// Tags translates tag IDs, dropping those without a counterpart.
+func (r *Remap) Tags(ids []string) []string {
+	out := []string{}
+	for _, id := range ids {
+		if !r.same {
+			id = r.tags[id]
+		}
+		if id != "" && !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// Element returns a copy of e with its references translated. The snapshot
+// is kept, so an element whose reference has no counterpart still shows
+// what it pointed to.
+func (r *Remap) Element(e model.CurriculumElement) model.CurriculumElement {
+	if r.same {
+		return e
+	}
+	ref := func(m map[string]string, id *string) *string {
+		if id == nil || m[*id] == "" {
+			return nil
+		}
+		match := m[*id]
+		return &match
+	}
+	e.TechniqueID = ref(r.techniques, e.TechniqueID)
+	e.AssetID = ref(r.assets, e.AssetID)
+	if e.Snapshot != nil {
+		snap := *e.Snapshot
+		snap.TagIDs = r.Tags(snap.TagIDs)
+		e.Snapshot = &snap
+	}
+	return e
+}
+
+// Fork queues in tx a copy of src and its elements as the new curriculum
+// fork, whose ID, discipline, owner, title and timestamps the caller sets.
+// The copy is private, and its elements get new IDs and remember the
+// upstream element they were copied from.
+func Fork(s store.Store, tx store.Tx, src *model.Curriculum, elements []model.CurriculumElement, remap *Remap, fork *model.Curriculum, change Change) error {
+	fork.Description = src.Description
+	fork.Duration = src.Duration
+	fork.IsPublic = false
+	fork.TagIDs = remap.Tags(src.TagIDs)
+	fork.SourceCurriculumID = src.ID
+	fork.SourceRevision = src.Revision
+
+	copies := make([]model.CurriculumElement, 0, len(elements))
+	ids := map[string]string{}
+	for _, e := range Order(elements) {
+		e = remap.Element(e)
+		e.SourceElementID = e.ID
+		e.ID = store.NewID()
+		e.CreatedAt, e.UpdatedAt = change.At, change.At
+		ids[e.SourceElementID] = e.ID
+		copies = append(copies, e)
+	}
+	for i := range copies {
+		if parent, ok := ids[ParentOf(&copies[i])]; ok {
+			copies[i].ParentID = &parent
+		} else {
+			copies[i].ParentID = nil
+		}
+	}
+	return Create(s, tx, fork, copies, change)
+}
+
+// PullConflict is an upstream change that was not applied because the fork
+// changed the same thing. The fork keeps its version.
+type PullConflict struct {
+	Field           string `json:"field,omitempty"`
+	ElementID       string `json:"elementId,omitempty"`
+	SourceElementID string `json:"sourceElementId,omitempty"`
+	Reason          string `json:"reason"`
+}
+
+// PullReport describes the upstream changes merged into a fork.
+type PullReport struct {
+	FromRevision int                       `json:"fromRevision"`
+	ToRevision   int                       `json:"toRevision"`
+	Fields       []string                  `json:"fields"`
+	Added        []model.CurriculumElement `json:"added"`
+	Updated      []model.CurriculumElement `json:"updated"`
+	Removed      []model.CurriculumElement `json:"removed"`
+	Conflicts    []PullConflict            `json:"conflicts"`
+	Applied      bool                      `json:"applied"`
+}
+
+// merge3 decides a three-way merge of one value: take reports whether the
+// upstream value replaces the fork's, conflict whether both changed it
+// differently.
+func merge3[T any](ours, base, theirs T, equal func(a, b T) bool) (take, conflict bool) {
+	if equal(theirs, base) || equal(ours, theirs) {
+		return false, false
+	}
+	if equal(ours, base) {
+		return true, false
+	}
+	return false, true
+}
+
+// Pull merges the upstream changes made since the fork last caught up into
+// fork id. A change is applied when the fork left the changed field or
+// element as it was upstream; when both sides changed it, the fork's version
+// stays and the change is reported as a conflict. New upstream elements are
+// placed after the element they follow upstream, in the fork's copy of their
+// section. Unless dryRun is set, the
+// merge is written with a new revision of the fork, and the fork is marked
+// as caught up with the current upstream revision.
+func Pull(s store.Store, tx store.Tx, id string, dryRun bool, change Change) (*PullReport, error) {
+	fork, ours, err := Load(s, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if fork.SourceCurriculumID == "" {
+		return nil, ErrNotFork
+	}
+	src, theirs, err := Load(s, tx, fork.SourceCurriculumID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrUpstreamGone
+	}
+	if err != nil {
+		return nil, err
+	}
+	report := &PullReport{
+		FromRevision: fork.SourceRevision,
+		ToRevision:   src.Revision,
+		Fields:       []string{},
+		Added:        []model.CurriculumElement{},
+		Updated:      []model.CurriculumElement{},
+		Removed:      []model.CurriculumElement{},
+		Conflicts:    []PullConflict{},
+		Applied:      !dryRun,
+	}
+	if src.Revision == fork.SourceRevision {
+		return report, nil
+	}
+	var baseRev model.CurriculumRevision
+	if err := tx.Get(s.Revisions().Ref(src.ID, RevisionID(fork.SourceRevision)), &baseRev); errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNoBaseRevision
+	} else if err != nil {
+		return nil, err
+	}
+	base, err := Content(&baseRev)
+	if err != nil {
+		return nil, err
+	}
+	remap, err := NewRemap(s, tx, src.DisciplineID, fork.DisciplineID,
+		append(slices.Clone(src.TagIDs), base.TagIDs...), theirs, base.Elements)
+	if err != nil {
+		return nil, err
+	}
+
+	// Curriculum fields.
+	var updates []store.Update
+	field := func(name string, value interface{}, take, conflict bool) {
+		switch {
+		case take:
+			report.Fields = append(report.Fields, name)
+			updates = append(updates, store.Update{Path: name, Value: value})
+		case conflict:
+			report.Conflicts = append(report.Conflicts, PullConflict{Field: name, Reason: "changed in the fork and upstream"})
+		}
+	}
+	same := func(a, b string) bool { return a == b }
+	take, conflict := merge3(fork.Title, base.Title, src.Title, same)
+	field("title", src.Title, take, conflict)
+	take, conflict = merge3(fork.Description, base.Description, src.Description, same)
+	field("description", src.Description, take, conflict)
+	take, conflict = merge3(fork.Duration, base.Duration, src.Duration, func(a, b *string) bool {
+		return (a == nil) == (b == nil) && (a == nil || *a == *b)
+	})
+	field("duration", src.Duration, take, conflict)
+	theirTags := remap.Tags(src.TagIDs)
+	take, conflict = merge3(fork.TagIDs, remap.Tags(base.TagIDs), theirTags, slices.Equal[[]string])
+	field("tagIds", theirTags, take, conflict)
+
+	// Elements, matched through the upstream element they were copied from.
+	ours = Order(ours)
+	mine := map[string]int{}
+	forkIDs := map[string]string{} // upstream element ID to the fork's
+	for i, e := range ours {
+		if e.SourceElementID != "" {
+			mine[e.SourceElementID] = i
+			forkIDs[e.SourceElementID] = e.ID
+		}
+	}
+	// local translates an upstream element for the fork: its references,
+	// and its section to the fork's copy, or the top level without one.
+	local := func(e model.CurriculumElement) model.CurriculumElement {
+		e = remap.Element(e)
+		if e.ParentID != nil {
+			if id, ok := forkIDs[*e.ParentID]; ok {
+				e.ParentID = &id
+			} else {
+				e.ParentID = nil
+			}
+		}
+		return e
+	}
+	baseByID := map[string]model.CurriculumElement{}
+	for _, e := range base.Elements {
+		baseByID[e.ID] = local(e)
+	}
+	var writes []model.CurriculumElement
+	removed, added := map[string]bool{}, map[string]bool{}
+	theirs = Order(theirs)
+	theirIDs := map[string]bool{}
+	for _, e := range theirs {
+		theirIDs[e.ID] = true
+	}
+	for _, b := range base.Elements {
+		i, ok := mine[b.ID]
+		if theirIDs[b.ID] || !ok {
+			continue
+		}
+		if sameContent(ours[i], baseByID[b.ID]) {
+			removed[ours[i].ID] = true
+			report.Removed = append(report.Removed, ours[i])
+		} else {
+			report.Conflicts = append(report.Conflicts, PullConflict{ElementID: ours[i].ID, SourceElementID: b.ID,
+				Reason: "changed in the fork, deleted upstream"})
+		}
+	}
+
+	order := slices.Clone(ours)
+	order = slices.DeleteFunc(order, func(e model.CurriculumElement) bool { return removed[e.ID] })
+	after := "" // ID in order of the fork element for the previous upstream element
+	for _, t := range theirs {
+		up := local(t)
+		b, inBase := baseByID[t.ID]
+		i, inFork := mine[t.ID]
+		switch {
+		case !inBase && !inFork:
+			up.SourceElementID, up.ID = t.ID, store.NewID()
+			forkIDs[t.ID] = up.ID
+			up.CreatedAt, up.UpdatedAt = change.At, change.At
+			at := 0
+			if after != "" {
+				at = slices.IndexFunc(order, func(e model.CurriculumElement) bool { return e.ID == after }) + 1
+			}
+			order = slices.Insert(order, at, up)
+			writes = append(writes, up)
+			added[up.ID] = true
+			after = up.ID
+			continue
+		case !inFork:
+			if !sameContent(up, b) {
+				report.Conflicts = append(report.Conflicts, PullConflict{SourceElementID: t.ID,
+					Reason: "deleted in the fork, changed upstream"})
+			}
+			continue
+		case inBase:
+			take, conflict := merge3(ours[i], b, up, sameContent)
+			if take {
+				merged := up
+				merged.ID, merged.SourceElementID = ours[i].ID, t.ID
+				merged.CreatedAt, merged.UpdatedAt = ours[i].CreatedAt, change.At
+				j := slices.IndexFunc(order, func(e model.CurriculumElement) bool { return e.ID == merged.ID })
+				order[j] = merged
+				writes = append(writes, merged)
+			} else if conflict {
+				report.Conflicts = append(report.Conflicts, PullConflict{ElementID: ours[i].ID, SourceElementID: t.ID,
+					Reason: "changed in the fork and upstream"})
+			}
+		}
+		after = ours[i].ID
+	}
+
+	// Renumber, writing the elements whose place changed.
+	written := map[string]bool{}
+	for _, e := range writes {
+		written[e.ID] = true
+	}
+	var moved []model.CurriculumElement
+	for _, i := range Number(order) {
+		if !written[order[i].ID] {
+			moved = append(moved, order[i])
+		}
+	}
+	for i := range writes {
+		j := slices.IndexFunc(order, func(e model.CurriculumElement) bool { return e.ID == writes[i].ID })
+		writes[i].Ord, writes[i].ParentID = order[j].Ord, order[j].ParentID
+	}
+	for _, e := range writes {
+		if added[e.ID] {
+			report.Added = append(report.Added, e)
+		} else {
+			report.Updated = append(report.Updated, e)
+		}
+	}
+	if dryRun {
+		return report, nil
+	}
+	if len(removed)+len(writes)+len(moved) > ElementsPerTx {
+		return nil, ErrTooLarge
+	}
+
+	for elemID := range removed {
+		tx.Delete(s.Elements().Ref(id, elemID))
+	}
+	for i := range writes {
+		tx.Set(s.Elements().Ref(id, writes[i].ID), &writes[i])
+	}
+	for _, e := range moved {
+		tx.Update(s.Elements().Ref(id, e.ID), []store.Update{{Path: "ord", Value: e.Ord}, {Path: "parentId", Value: e.ParentID}})
+	}
+	updates = append(updates,
+		store.Update{Path: "sourceRevision", Value: src.Revision},
+		store.Update{Path: "updatedAt", Value: change.At})
+	if err := store.ApplyUpdates(fork, updates); err != nil {
+		return nil, err
+	}
+	change.Action = model.RevisionPull
+	return report, Save(s, tx, fork, order, change, updates...)
+}
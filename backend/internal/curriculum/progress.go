@@ -0,0 +1,87 @@
+package curriculum
+
+import (
+	"slices"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+// TakesProgress reports whether learners record progress on e: sections
+// only group other elements.
+func TakesProgress(e *model.CurriculumElement) bool {
+	return e.Type != model.ElementTypeSection
+}
+
+// Progress summarizes one learner's progress through c from their records,
+// which it lists in reading order. Records of elements that no
+// longer take progress are left out.
+func Progress(c *model.Curriculum, elements []model.CurriculumElement, records []model.Progress) model.CurriculumProgress {
+	p := model.CurriculumProgress{CurriculumID: c.ID, Title: c.Title, Elements: []model.Progress{}}
+	states := map[string]string{}
+	pos := map[string]int{}
+	elements = Order(elements)
+	for i := range elements {
+		if TakesProgress(&elements[i]) {
+			states[elements[i].ID] = model.ProgressNotStarted
+			pos[elements[i].ID] = i
+			p.Total++
+		}
+	}
+	for _, r := range records {
+		if _, ok := states[r.ElementID]; !ok {
+			continue
+		}
+		states[r.ElementID] = r.State
+		p.Elements = append(p.Elements, r)
+		if r.UpdatedAt.After(p.UpdatedAt) {
+			p.UpdatedAt = r.UpdatedAt
+		}
+	}
+	slices.SortFunc(p.Elements, func(a, b model.Progress) int { return pos[a.ElementID] - pos[b.ElementID] })
+	for _, state := range states {
+		switch state {
+		case model.ProgressWatched:
+			p.Counts.Watched++
+		case model.ProgressDrilled:
+			p.Counts.Drilled++
+		case model.ProgressMastered:
+			p.Counts.Mastered++
+		default:
+			p.Counts.NotStarted++
+		}
+	}
+	if p.Total > 0 {
+		p.PercentComplete = p.Counts.Mastered * 100 / p.Total
+	}
+	return p
+}
+
+// Report aggregates the progress through c of every learner with records.
+func Report(c *model.Curriculum, elements []model.CurriculumElement, records []model.Progress) model.ProgressReport {
+	byLearner := map[string][]model.Progress{}
+	for _, r := range records {
+		byLearner[r.UID] = append(byLearner[r.UID], r)
+	}
+	rep := model.ProgressReport{CurriculumID: c.ID, Title: c.Title, Total: Progress(c, elements, nil).Total}
+	percent := 0
+	for _, learner := range byLearner {
+		p := Progress(c, elements, learner)
+		if len(p.Elements) == 0 {
+			// Only records of deleted elements.
+			continue
+		}
+		rep.Learners++
+		if p.Total > 0 && p.Counts.Mastered == p.Total {
+			rep.Completed++
+		}
+		percent += p.PercentComplete
+		rep.Counts.NotStarted += p.Counts.NotStarted
+		rep.Counts.Watched += p.Counts.Watched
+		rep.Counts.Drilled += p.Counts.Drilled
+		rep.Counts.Mastered += p.Counts.Mastered
+	}
+	if rep.Learners > 0 {
+		rep.AveragePercent = percent / rep.Learners
+	}
+	return rep
+}
@@ -0,0 +1,103 @@
+package curriculum
+
+import (
+	"context"
+	"slices"
+
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+// eachPage is the number of curricula listed per query by Each.
+const eachPage = 100
+
+// Each calls fn with each curriculum matching q, listed a page at a time,
+// and stops at the first error.
+func Each(ctx context.Context, s store.Store, q store.Query, fn func(c *model.Curriculum) error) error {
+	q = q.Limit(eachPage)
+	for {
+		page, err := s.Curricula().List(ctx, q)
+		if err != nil {
+			return err
+		}
+		for i := range page {
+			if err := fn(&page[i]); err != nil {
+				return err
+			}
+		}
+		if len(page) < eachPage {
+			return nil
+		}
+		last := &page[len(page)-1]
+		q = q.StartAfter(store.CursorAt(q, last, last.ID))
+	}
+}
+
+// Rewriter changes the references a curriculum holds. Tags returns the
+// curriculum's own tag IDs rewritten, and Element rewrites an element in
+// place; each reports whether it changed anything. Either may be nil.
+type Rewriter struct {
+	Tags    func(ids []string) ([]string, bool)
+	Element func(e *model.CurriculumElement) bool
+}
+
+// Rewrite applies rw to a curriculum and its elements and saves them, with
+// their derived fields, as a new revision. It returns whether the
+// curriculum's tags changed and the IDs of the elements that did. Nothing is
+// written when nothing changed, or when dryRun is set.
+func Rewrite(ctx context.Context, s store.Store, id string, rw Rewriter, dryRun bool, change Change) (tagged bool, changed []string, err error) {
+	err = s.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		tagged, changed = false, []string{}
+		c, elements, err := Load(s, tx, id)
+		if err != nil {
+			return err
+		}
+		var updates []store.Update
+		if rw.Tags != nil {
+			if c.TagIDs, tagged = rw.Tags(c.TagIDs); tagged {
+				updates = append(updates, store.Update{Path: "tagIds", Value: c.TagIDs})
+			}
+		}
+		if rw.Element != nil {
+			for i := range elements {
+				if rw.Element(&elements[i]) {
+					changed = append(changed, elements[i].ID)
+				}
+			}
+		}
+		if !tagged && len(changed) == 0 || dryRun {
+			return nil
+		}
+		if len(changed) > ElementsPerTx {
+			return ErrTooLarge
+		}
+		for i := range elements {
+			if slices.Contains(changed, elements[i].ID) {
+				tx.Set(s.Elements().Ref(id, elements[i].ID), &elements[i])
+			}
+		}
+		return Save(s, tx, c, elements, change, updates...)
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	return tagged, changed, nil
+}
+
+// Retarget points the technique elements of a curriculum that refer to one
+// of the techniques in from at t instead, with a snapshot of t, and saves
+// the curriculum with its derived fields as a new revision. It returns the
+// IDs of those elements. Nothing is written when there are none, or when
+// dryRun is set.
+func Retarget(ctx context.Context, s store.Store, id string, from []string, t *model.Technique, dryRun bool, change Change) ([]string, error) {
+	_, changed, err := Rewrite(ctx, s, id, Rewriter{Element: func(e *model.CurriculumElement) bool {
+		if e.Type != model.ElementTypeTechnique || e.TechniqueID == nil || !slices.Contains(from, *e.TechniqueID) {
+			return false
+		}
+		techniqueID := t.ID
+		e.TechniqueID = &techniqueID
+		e.Snapshot = TechniqueSnapshot(t)
+		return true
+	}}, dryRun, change)
+	return changed, err
+}
@@ -0,0 +1,160 @@
+package curriculum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+// ErrTooLarge is returned when a change to a curriculum, such as restoring a
+// revision, would take more writes than fit in one transaction.
+var ErrTooLarge = errors.New("curriculum: change too large for one transaction")
+
+// ElementsPerTx is the number of element writes that fit in one transaction
+// saving a curriculum. Besides the elements, the curriculum and its new
+// revision are written.
+const ElementsPerTx = store.MaxBatchSize - 2
+
+// Change describes a write to a curriculum for the revision recording it.
+type Change struct {
+	Action   model.RevisionAction
+	ActorUID string
+	At       time.Time
+	// RestoredFrom is the revision number a restore went back to.
+	RestoredFrom int
+}
+
+// RevisionID returns the document ID of revision number n.
+func RevisionID(n int) string {
+	return strconv.Itoa(n)
+}
+
+// Record queues in tx the revision that follows c's latest one, recording c
+// and elements as they will be once tx commits, and advances c.Revision.
+// The caller stores the new number on the curriculum; Save does both.
+func Record(s store.Store, tx store.Tx, c *model.Curriculum, elements []model.CurriculumElement, change Change) (*model.CurriculumRevision, error) {
+	elements = Order(elements)
+	content, err := json.Marshal(model.RevisionContent{
+		Title:       c.Title,
+		Description: c.Description,
+		Duration:    c.Duration,
+		IsPublic:    c.IsPublic,
+		TagIDs:      c.TagIDs,
+		Elements:    elements,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode revision: %w", err)
+	}
+
+	c.Revision++
+	rev := &model.CurriculumRevision{
+		ID:           RevisionID(c.Revision),
+		Number:       c.Revision,
+		Action:       change.Action,
+		ActorUID:     change.ActorUID,
+		RestoredFrom: change.RestoredFrom,
+		Title:        c.Title,
+		ElementCount: len(elements),
+		CreatedAt:    change.At,
+		Content:      string(content),
+	}
+	tx.Set(s.Revisions().Ref(c.ID, rev.ID), rev)
+	return rev, nil
+}
+
+// sortByOrd returns a copy of elements sorted by ord, which orders siblings;
+// Order gives the reading order.
+func sortByOrd(elements []model.CurriculumElement) []model.CurriculumElement {
+	elements = slices.Clone(elements)
+	slices.SortFunc(elements, func(a, b model.CurriculumElement) int {
+		if a.Ord != b.Ord {
+			return a.Ord - b.Ord
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return elements
+}
+
+// Content decodes the curriculum state recorded by rev.
+func Content(rev *model.CurriculumRevision) (*model.RevisionContent, error) {
+	var content model.RevisionContent
+	if err := json.Unmarshal([]byte(rev.Content), &content); err != nil {
+		return nil, fmt.Errorf("decode revision %s: %w", rev.ID, err)
+	}
+	if content.TagIDs == nil {
+		content.TagIDs = []string{}
+	}
+	if content.Elements == nil {
+		content.Elements = []model.CurriculumElement{}
+	}
+	return &content, nil
+}
+
+// RevisionDetail is a revision together with its decoded content.
+type RevisionDetail struct {
+	model.CurriculumRevision
+	Content model.RevisionContent `json:"content"`
+}
+
+// Restore puts a curriculum back into the state recorded by revision number,
+// elements included, and records that as a new revision. Visibility is not
+// part of the restore: isPublic keeps its current value. It returns
+// store.ErrNotFound when the curriculum or the revision does not exist.
+func Restore(s store.Store, tx store.Tx, id string, number int, change Change) (*model.Curriculum, error) {
+	c, elements, err := Load(s, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	var rev model.CurriculumRevision
+	if err := tx.Get(s.Revisions().Ref(id, RevisionID(number)), &rev); err != nil {
+		return nil, err
+	}
+	content, err := Content(&rev)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := map[string]bool{}
+	for _, e := range content.Elements {
+		keep[e.ID] = true
+	}
+	var gone []string
+	for _, e := range elements {
+		if !keep[e.ID] {
+			gone = append(gone, e.ID)
+		}
+	}
+	if len(content.Elements)+len(gone) > ElementsPerTx {
+		return nil, ErrTooLarge
+	}
+
+	for _, elemID := range gone {
+		tx.Delete(s.Elements().Ref(id, elemID))
+	}
+	for i := range content.Elements {
+		tx.Set(s.Elements().Ref(id, content.Elements[i].ID), &content.Elements[i])
+	}
+	updates := []store.Update{
+		{Path: "title", Value: content.Title},
+		{Path: "description", Value: content.Description},
+		{Path: "duration", Value: content.Duration},
+		{Path: "tagIds", Value: content.TagIDs},
+		{Path: "updatedAt", Value: change.At},
+	}
+	if err := store.ApplyUpdates(c, updates); err != nil {
+		return nil, err
+	}
+	change.Action, change.RestoredFrom = model.RevisionRestore, number
+	if err := Save(s, tx, c, content.Elements, change, updates...); err != nil {
+		return nil, err
+	}
+	Derive(c, content.Elements).Apply(c)
+	return c, nil
+}
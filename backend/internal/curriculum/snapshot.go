@@ -0,0 +1,266 @@
+package curriculum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+// TechniqueSnapshot returns the snapshot a technique element keeps of t.
+func TechniqueSnapshot(t *model.Technique) *model.Snapshot {
+	return &model.Snapshot{
+		Name:            t.Name,
+		Description:     t.Description,
+		TagIDs:          t.TagIDs,
+		SourceUpdatedAt: t.UpdatedAt,
+	}
+}
+
+// AssetSnapshot returns the snapshot an asset element keeps of a.
+func AssetSnapshot(a *model.Asset) *model.Snapshot {
+	snapshot := &model.Snapshot{
+		Name:            a.Title,
+		URL:             a.URL,
+		Description:     a.Description,
+		TagIDs:          a.TagIDs,
+		SourceUpdatedAt: a.UpdatedAt,
+	}
+	if a.ThumbnailURL != nil {
+		snapshot.ThumbnailURL = *a.ThumbnailURL
+	}
+	return snapshot
+}
+
+// Sources reads the techniques and assets elements refer to, each once, and
+// snapshots them.
+type Sources struct {
+	technique func(id string) (*model.Technique, error)
+	asset     func(id string) (*model.Asset, error)
+	snapshots map[string]*model.Snapshot
+}
+
+// NewSources reads sources from s outside any transaction.
+func NewSources(ctx context.Context, s store.Store) *Sources {
+	return &Sources{
+		technique: func(id string) (*model.Technique, error) { return s.Techniques().Get(ctx, id) },
+		asset:     func(id string) (*model.Asset, error) { return s.Assets().Get(ctx, id) },
+	}
+}
+
+// TxSources reads sources in tx.
+func TxSources(s store.Store, tx store.Tx) *Sources {
+	return &Sources{
+		technique: func(id string) (*model.Technique, error) {
+			var t model.Technique
+			return &t, tx.Get(s.Techniques().Ref(id), &t)
+		},
+		asset: func(id string) (*model.Asset, error) {
+			var a model.Asset
+			return &a, tx.Get(s.Assets().Ref(id), &a)
+		},
+	}
+}
+
+// Snapshot returns a fresh snapshot of the technique or asset e refers to,
+// also for clips, nil when e refers to neither, or store.ErrNotFound when the source is gone.
+func (src *Sources) Snapshot(e *model.CurriculumElement) (*model.Snapshot, error) {
+	var key string
+	switch {
+	case e.Type == model.ElementTypeTechnique && e.TechniqueID != nil:
+		key = store.CollTechniques + "/" + *e.TechniqueID
+	case (e.Type == model.ElementTypeAsset || e.Type == model.ElementTypeClip) && e.AssetID != nil:
+		key = store.CollAssets + "/" + *e.AssetID
+	default:
+		return nil, nil
+	}
+	if snap, ok := src.snapshots[key]; ok {
+		if snap == nil {
+			return nil, store.ErrNotFound
+		}
+		return snap, nil
+	}
+
+	var snap *model.Snapshot
+	var err error
+	if e.Type == model.ElementTypeTechnique {
+		var t *model.Technique
+		if t, err = src.technique(*e.TechniqueID); err == nil {
+			snap = TechniqueSnapshot(t)
+		}
+	} else {
+		var a *model.Asset
+		if a, err = src.asset(*e.AssetID); err == nil {
+			snap = AssetSnapshot(a)
+		}
+	}
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+	if src.snapshots == nil {
+		src.snapshots = map[string]*model.Snapshot{}
+	}
+	src.snapshots[key] = snap
+	return snap, err
+}
+
+// Status compares the snapshot of e with its source. It returns "" for
+// elements that refer to no technique or asset.
+func (src *Sources) Status(e *model.CurriculumElement) (model.SnapshotStatus, error) {
+	fresh, err := src.Snapshot(e)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return model.SnapshotBroken, nil
+	case err != nil:
+		return "", err
+	case fresh == nil:
+		return "", nil
+	case e.Snapshot == nil || !e.Snapshot.SourceUpdatedAt.Equal(fresh.SourceUpdatedAt):
+		return model.SnapshotStale, nil
+	}
+	return model.SnapshotCurrent, nil
+}
+
+// Check sets the SnapshotStatus of every element.
+func (src *Sources) Check(elements []model.CurriculumElement) error {
+	for i := range elements {
+		status, err := src.Status(&elements[i])
+		if err != nil {
+			return err
+		}
+		elements[i].SnapshotStatus = status
+	}
+	return nil
+}
+
+// sameSnapshot reports whether two snapshots hold the same data.
+func sameSnapshot(a, b *model.Snapshot) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
+
+// RefreshReport describes a snapshot refresh of one curriculum. Refreshed
+// lists the elements whose snapshot was replaced, Broken the selected ones
+// whose source is gone, which keep their snapshot.
+type RefreshReport struct {
+	CurriculumID string   `json:"curriculumId"`
+	Refreshed    []string `json:"refreshed"`
+	Broken       []string `json:"broken"`
+}
+
+// Refresh re-snapshots the technique and asset elements of a curriculum, or
+// only those in elementIDs when it is not empty, and saves the curriculum
+// with its derived fields as a new revision. Nothing is written when no
+// snapshot changed. An unknown element ID gives store.ErrNotFound.
+func Refresh(ctx context.Context, s store.Store, id string, elementIDs []string, change Change) (*RefreshReport, error) {
+	var report *RefreshReport
+	err := s.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		report = &RefreshReport{CurriculumID: id, Refreshed: []string{}, Broken: []string{}}
+		c, elements, err := Load(s, tx, id)
+		if err != nil {
+			return err
+		}
+		for _, eid := range elementIDs {
+			if !slices.ContainsFunc(elements, func(e model.CurriculumElement) bool { return e.ID == eid }) {
+				return store.ErrNotFound
+			}
+		}
+
+		src := TxSources(s, tx)
+		for i := range elements {
+			e := &elements[i]
+			if len(elementIDs) > 0 && !slices.Contains(elementIDs, e.ID) {
+				continue
+			}
+			fresh, err := src.Snapshot(e)
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				report.Broken = append(report.Broken, e.ID)
+				continue
+			case err != nil:
+				return err
+			case fresh == nil || sameSnapshot(e.Snapshot, fresh):
+				continue
+			}
+			e.Snapshot = fresh
+			report.Refreshed = append(report.Refreshed, e.ID)
+		}
+		if len(report.Refreshed) == 0 {
+			return nil
+		}
+		if len(report.Refreshed) > ElementsPerTx {
+			return ErrTooLarge
+		}
+		for _, e := range elements {
+			if slices.Contains(report.Refreshed, e.ID) {
+				tx.Update(s.Elements().Ref(id, e.ID), []store.Update{{Path: "snapshot", Value: e.Snapshot}})
+			}
+		}
+		return Save(s, tx, c, elements, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// RefreshAll refreshes the stale snapshots of every curriculum, calling
+// report for each one that had any refreshed or broken. Curricula whose
+// snapshots are current are checked without a transaction. A curriculum that
+// fails to refresh is passed to onError and skipped.
+func RefreshAll(ctx context.Context, s store.Store, report func(RefreshReport), onError func(id string, err error)) (checked int, err error) {
+	src := NewSources(ctx, s)
+	q := store.NewQuery().Limit(reconcilePage)
+	for {
+		page, err := s.Curricula().List(ctx, q)
+		if err != nil {
+			return checked, err
+		}
+		for i := range page {
+			checked++
+			id := page[i].ID
+			elements, err := s.Elements().List(ctx, id, store.NewQuery())
+			if err == nil {
+				err = src.Check(elements)
+			}
+			if err != nil {
+				onError(id, err)
+				continue
+			}
+			// Broken references are reported, but only stale snapshots are
+			// worth a transaction.
+			var stale, broken []string
+			for _, e := range elements {
+				switch e.SnapshotStatus {
+				case model.SnapshotStale:
+					stale = append(stale, e.ID)
+				case model.SnapshotBroken:
+					broken = append(broken, e.ID)
+				}
+			}
+			if len(stale) == 0 {
+				if len(broken) > 0 {
+					report(RefreshReport{CurriculumID: id, Refreshed: []string{}, Broken: broken})
+				}
+				continue
+			}
+			r, err := Refresh(ctx, s, id, nil, Change{Action: model.RevisionElementRefresh, ActorUID: "system", At: time.Now()})
+			switch {
+			case err != nil:
+				onError(id, err)
+			case len(r.Refreshed) > 0 || len(r.Broken) > 0:
+				report(*r)
+			}
+		}
+		if len(page) < reconcilePage {
+			return checked, nil
+		}
+		last := &page[len(page)-1]
+		q = q.StartAfter(store.CursorAt(q, last, last.ID))
+	}
+}
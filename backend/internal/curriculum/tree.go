@@ -0,0 +1,193 @@
+package curriculum
+
+import (
+	"errors"
+
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+// ErrInvalidParent is returned when an element is placed under something
+// other than a top-level section of its curriculum, or a section is placed
+// under another.
+var ErrInvalidParent = errors.New("curriculum: parent must be a top-level section")
+
+// ParentOf returns the ID of the section holding e, or "" at the top level.
+func ParentOf(e *model.CurriculumElement) string {
+	if e.ParentID == nil {
+		return ""
+	}
+	return *e.ParentID
+}
+
+// sectionIDs returns the IDs of the top-level sections among elements.
+func sectionIDs(elements []model.CurriculumElement) map[string]bool {
+	ids := map[string]bool{}
+	for i := range elements {
+		if elements[i].Type == model.ElementTypeSection && ParentOf(&elements[i]) == "" {
+			ids[elements[i].ID] = true
+		}
+	}
+	return ids
+}
+
+// Order returns a copy of elements in reading order: the top-level elements
+// by ord, each section followed by its own elements by ord. An element whose
+// parent is not a top-level section is read as a top-level one.
+func Order(elements []model.CurriculumElement) []model.CurriculumElement {
+	sorted := sortByOrd(elements)
+	sections := sectionIDs(sorted)
+	var top []model.CurriculumElement
+	children := map[string][]model.CurriculumElement{}
+	for _, e := range sorted {
+		if p := ParentOf(&e); sections[p] {
+			children[p] = append(children[p], e)
+		} else {
+			top = append(top, e)
+		}
+	}
+	out := make([]model.CurriculumElement, 0, len(elements))
+	for _, e := range top {
+		out = append(out, e)
+		out = append(out, children[e.ID]...)
+	}
+	return out
+}
+
+// Number sets the ord of elements, given in reading order, to their position
+// among their siblings, counting from 1, and moves elements whose parent is
+// not a top-level section to the top level. It returns the indexes of the
+// elements it changed.
+func Number(elements []model.CurriculumElement) []int {
+	sections := sectionIDs(elements)
+	next := map[string]int{}
+	var changed []int
+	for i := range elements {
+		e := &elements[i]
+		p, moved := ParentOf(e), false
+		if p != "" && !sections[p] {
+			e.ParentID, p, moved = nil, "", true
+		}
+		next[p]++
+		if e.Ord != next[p] || moved {
+			e.Ord = next[p]
+			changed = append(changed, i)
+		}
+	}
+	return changed
+}
+
+// SetTotals sets the total duration of each section among elements to the
+// sum of its elements' durations.
+func SetTotals(elements []model.CurriculumElement) {
+	totals := map[string]int{}
+	for i := range elements {
+		if p := ParentOf(&elements[i]); p != "" {
+			totals[p] += ElementSeconds(&elements[i])
+		}
+	}
+	for i := range elements {
+		if elements[i].Type == model.ElementTypeSection {
+			elements[i].TotalDurationSeconds = totals[elements[i].ID]
+		}
+	}
+}
+
+// Tree nests elements, given in reading order, under their sections, with
+// the sections' totals set.
+func Tree(elements []model.CurriculumElement) []model.CurriculumElement {
+	elements = Order(elements)
+	SetTotals(elements)
+	roots := []model.CurriculumElement{}
+	at := map[string]int{} // index in roots of each section
+	for _, e := range elements {
+		if i, ok := at[ParentOf(&e)]; ok {
+			roots[i].Children = append(roots[i].Children, e)
+			continue
+		}
+		if e.Type == model.ElementTypeSection {
+			e.Children = []model.CurriculumElement{}
+			at[e.ID] = len(roots)
+		}
+		roots = append(roots, e)
+	}
+	return roots
+}
+
+// CheckParent returns ErrInvalidParent unless e may be placed under section
+// parentID ("" for the top level) of a curriculum with the given elements.
+func CheckParent(elements []model.CurriculumElement, parentID string, e *model.CurriculumElement) error {
+	if parentID == "" {
+		return nil
+	}
+	if e.Type == model.ElementTypeSection || !sectionIDs(elements)[parentID] {
+		return ErrInvalidParent
+	}
+	return nil
+}
+
+// Arrange places the elements orderedIDs names, in that order, first among
+// the elements of section parentID, or of the top level when it is empty;
+// the other elements there follow in their current order. It returns the
+// elements in their new reading order, renumbered, and the IDs of those
+// whose ord or parent changed. An unknown ID gives store.ErrNotFound.
+func Arrange(elements []model.CurriculumElement, parentID string, orderedIDs []string) ([]model.CurriculumElement, []string, error) {
+	elements = Order(elements)
+	type place struct {
+		ord    int
+		parent string
+	}
+	before := map[string]place{}
+	index := map[string]int{}
+	for i := range elements {
+		before[elements[i].ID] = place{elements[i].Ord, ParentOf(&elements[i])}
+		index[elements[i].ID] = i
+	}
+
+	named := map[string]bool{}
+	var first []model.CurriculumElement
+	for _, id := range orderedIDs {
+		i, ok := index[id]
+		if !ok {
+			return nil, nil, store.ErrNotFound
+		}
+		e := elements[i]
+		if err := CheckParent(elements, parentID, &e); err != nil {
+			return nil, nil, err
+		}
+		e.ParentID = nil
+		if parentID != "" {
+			e.ParentID = &parentID
+		}
+		named[id] = true
+		first = append(first, e)
+	}
+
+	// Group the siblings, named elements first under their new parent.
+	sections := sectionIDs(elements)
+	siblings := map[string][]model.CurriculumElement{parentID: first}
+	for _, e := range elements {
+		if named[e.ID] {
+			continue
+		}
+		p := ParentOf(&e)
+		if !sections[p] {
+			p = ""
+		}
+		siblings[p] = append(siblings[p], e)
+	}
+	out := make([]model.CurriculumElement, 0, len(elements))
+	for _, e := range siblings[""] {
+		out = append(out, e)
+		out = append(out, siblings[e.ID]...)
+	}
+	Number(out)
+
+	var moved []string
+	for i := range out {
+		if was := before[out[i].ID]; was.ord != out[i].Ord || was.parent != ParentOf(&out[i]) {
+			moved = append(moved, out[i].ID)
+		}
+	}
+	return out, moved, nil
+}
@@ -0,0 +1,120 @@
+// Package duration parses the durations stored as text on assets, curricula
+// and elements: ISO-8601 values from the YouTube API and whatever editors
+// type into the duration fields.
+package duration
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	isoPattern   = regexp.MustCompile(`^p(?:(\d+)d)?(?:t(?:(\d+)h)?(?:(\d+)m)?(?:(\d+(?:\.\d+)?)s)?)?$`)
+	clockPattern = regexp.MustCompile(`^(\d+):([0-5]?\d)(?::([0-5]?\d))?$`)
+	unitPattern  = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-z]+)[\s,]*`)
+)
+
+// unitSeconds maps the unit spellings accepted in "1h 30m" style input.
+var unitSeconds = map[string]float64{
+	"h": 3600, "hr": 3600, "hrs": 3600, "hour": 3600, "hours": 3600,
+	"m": 60, "min": 60, "mins": 60, "minute": 60, "minutes": 60,
+	"s": 1, "sec": 1, "secs": 1, "second": 1, "seconds": 1,
+}
+
+// Examples lists the input formats Parse accepts, for error messages.
+const Examples = "15m, 1h 30m, 1:30:00 or PT1H30M"
+
+// MaxSeconds is the longest duration Parse accepts, 9999 hours. Longer
+// values are typos, and summing them could overflow the stored seconds.
+const MaxSeconds = 9999 * 3600
+
+// Parse returns the length of s in seconds. It accepts ISO-8601 durations
+// ("PT1H2M3S"), clock notation ("1:30:00" is h:mm:ss, "5:00" is m:ss) and
+// units ("1h 30m", "15m", "90 seconds"), up to MaxSeconds. ok is false for
+// anything else, including the empty string and bare numbers, whose unit is
+// ambiguous.
+func Parse(s string) (seconds int, ok bool) {
+	// Numbers are read as floats, which grow to +Inf rather than wrap.
+	total, ok := parse(strings.ToLower(strings.TrimSpace(s)))
+	if !ok || total > MaxSeconds {
+		return 0, false
+	}
+	return int(math.Round(total)), true
+}
+
+func parse(s string) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+
+	if m := isoPattern.FindStringSubmatch(s); m != nil && s != "p" && s != "pt" {
+		return num(m[1])*86400 + num(m[2])*3600 + num(m[3])*60 + num(m[4]), true
+	}
+
+	if m := clockPattern.FindStringSubmatch(s); m != nil {
+		if m[3] == "" {
+			return num(m[1])*60 + num(m[2]), true
+		}
+		return num(m[1])*3600 + num(m[2])*60 + num(m[3]), true
+	}
+
+	total := 0.0
+	for rest := s; rest != ""; {
+		m := unitPattern.FindStringSubmatch(rest)
+		if m == nil {
+			return 0, false
+		}
+		unit, known := unitSeconds[m[2]]
+		if !known {
+			return 0, false
+		}
+		total += num(m[1]) * unit
+		rest = rest[len(m[0]):]
+	}
+	return total, true
+}
+
+// Normalize parses s and returns it as durations are stored: in clock
+// notation (see Format) and in seconds.
+func Normalize(s string) (text string, seconds int, ok bool) {
+	seconds, ok = Parse(s)
+	if !ok {
+		return "", 0, false
+	}
+	return Format(seconds), seconds, true
+}
+
+// Of returns the seconds of an optional duration field, or 0 when it is
+// unset or cannot be parsed.
+func Of(s *string) int {
+	if s == nil {
+		return 0
+	}
+	seconds, _ := Parse(*s)
+	return seconds
+}
+
+// num reads a matched number, which is empty for an absent component.
+func num(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	n, _ := strconv.ParseFloat(s, 64)
+	return n
+}
+
+// Format writes seconds in clock notation, "m:ss" under an hour and "h:mm:ss"
+// from an hour up, which Parse reads back.
+func Format(seconds int) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	h, m, s := seconds/3600, seconds/60%60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
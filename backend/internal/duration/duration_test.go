@@ -0,0 +1,87 @@
+package duration
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		in      string
+		seconds int
+		ok      bool
+	}{
+		// ISO-8601
+		{"PT1H2M3S", 3723, true},
+		{"pt15m", 900, true},
+		{"PT1.6S", 2, true},
+		{"P1DT1S", 86401, true},
+		{"P1D", 86400, true},
+		{"P", 0, false},
+		{"PT", 0, false},
+		// Clock notation
+		{"5:00", 300, true},
+		{"1:30:00", 5400, true},
+		{"90:05", 5405, true},
+		{"1:60", 0, false},
+		// Units
+		{"1h 30m", 5400, true},
+		{"15m", 900, true},
+		{"90 seconds", 90, true},
+		{"1.5 hours", 5400, true},
+		{"2 hrs, 5 mins", 7500, true},
+		{" 45 MIN ", 2700, true},
+		{"3 weeks", 0, false},
+		// Neither
+		{"", 0, false},
+		{"90", 0, false},
+		{"soon", 0, false},
+		// Bounds
+		{"9999h", MaxSeconds, true},
+		{"9999:00:00", MaxSeconds, true},
+		{"10000h", 0, false},
+		{"99999999999999999999h", 0, false},
+		{"99999999999999999999:00", 0, false},
+		{"P416D", 416 * 86400, true},
+		{"P417D", 0, false},
+		{"P99999999999999999999D", 0, false},
+		{"PT99999999999999999999999999999999999S", 0, false},
+	} {
+		seconds, ok := Parse(tt.in)
+		if seconds != tt.seconds || ok != tt.ok {
+			t.Errorf("Parse(%q) = %d, %v; want %d, %v", tt.in, seconds, ok, tt.seconds, tt.ok)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for _, tt := range []struct {
+		in, text string
+		seconds  int
+	}{
+		{"PT1H2M3S", "1:02:03", 3723},
+		{"90 seconds", "1:30", 90},
+		{"0:00", "0:00", 0},
+	} {
+		text, seconds, ok := Normalize(tt.in)
+		if !ok || text != tt.text || seconds != tt.seconds {
+			t.Errorf("Normalize(%q) = %q, %d, %v; want %q, %d", tt.in, text, seconds, ok, tt.text, tt.seconds)
+		}
+		// Formatted durations parse back to the same length.
+		if again, ok := Parse(text); !ok || again != seconds {
+			t.Errorf("Parse(%q) = %d, %v; want %d", text, again, ok, seconds)
+		}
+	}
+	if _, _, ok := Normalize("10000h"); ok {
+		t.Error("Normalize accepted a duration above MaxSeconds")
+	}
+}
+
+func TestOf(t *testing.T) {
+	valid, invalid := "5m", "later"
+	for _, tt := range []struct {
+		in   *string
+		want int
+	}{{nil, 0}, {&valid, 300}, {&invalid, 0}} {
+		if got := Of(tt.in); got != tt.want {
+			t.Errorf("Of(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
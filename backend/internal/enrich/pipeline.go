@@ -8,15 +8,17 @@ import (
 	"sync"
 	"time"
 
-	"cloud.google.com/go/firestore"
+	"github.com/thomas/skillhive-api/internal/duration"
 	"github.com/thomas/skillhive-api/internal/llm"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+	"github.com/thomas/skillhive-api/internal/validate"
 	"github.com/thomas/skillhive-api/internal/youtube"
-	"google.golang.org/api/iterator"
 )
 
 // Pipeline orchestrates async YouTube asset enrichment.
 type Pipeline struct {
-	fs       *firestore.Client
+	store    store.Store
 	llm      llm.Client
 	ytAPIKey string
 	sem      chan struct{}
@@ -24,9 +26,9 @@ type Pipeline struct {
 }
 
 // NewPipeline creates a new enrichment pipeline.
-func NewPipeline(fs *firestore.Client, llmClient llm.Client, ytAPIKey string) *Pipeline {
+func NewPipeline(s store.Store, llmClient llm.Client, ytAPIKey string) *Pipeline {
 	return &Pipeline{
-		fs:       fs,
+		store:    s,
 		llm:      llmClient,
 		ytAPIKey: ytAPIKey,
 		sem:      make(chan struct{}, 3), // max 3 concurrent enrichments
@@ -70,17 +72,19 @@ func (p *Pipeline) EnrichAsset(ctx context.Context, assetID, videoURL, disciplin
 	titleVal := meta.Title
 	originator := meta.ChannelTitle
 	thumbnailURL := meta.ThumbnailURL
-	duration := meta.Duration
-	updates := []firestore.Update{
+	updates := []store.Update{
 		{Path: "title", Value: titleVal},
 		{Path: "originator", Value: &originator},
 		{Path: "thumbnailUrl", Value: &thumbnailURL},
 		{Path: "updatedAt", Value: now},
 	}
-	if duration != "" {
-		updates = append(updates, firestore.Update{Path: "duration", Value: &duration})
+	// YouTube reports ISO-8601 durations; they are stored like typed ones.
+	if text, seconds, ok := duration.Normalize(meta.Duration); ok {
+		updates = append(updates,
+			store.Update{Path: "duration", Value: &text},
+			store.Update{Path: "durationSeconds", Value: &seconds})
 	}
-	if _, err := p.fs.Collection("assets").Doc(assetID).Update(ctx, updates); err != nil {
+	if err := p.store.Assets().Update(ctx, assetID, updates); err != nil {
 		slog.Warn("failed to update asset with metadata", "assetId", assetID, "error", err)
 		// Continue enrichment — this is not fatal
 	}
@@ -194,7 +198,7 @@ func (p *Pipeline) EnrichAsset(ctx context.Context, assetID, videoURL, disciplin
 		enrichedOriginator = &originator
 	}
 
-	finalUpdates := []firestore.Update{
+	finalUpdates := []store.Update{
 		{Path: "title", Value: enrichedTitle},
 		{Path: "description", Value: enrichedDesc},
 		{Path: "videoType", Value: videoType},
@@ -207,7 +211,7 @@ func (p *Pipeline) EnrichAsset(ctx context.Context, assetID, videoURL, disciplin
 		{Path: "updatedAt", Value: time.Now()},
 	}
 
-	if _, err := p.fs.Collection("assets").Doc(assetID).Update(ctx, finalUpdates); err != nil {
+	if err := p.store.Assets().Update(ctx, assetID, finalUpdates); err != nil {
 		slog.Error("failed to update asset with enriched data", "assetId", assetID, "error", err)
 		p.setError(assetID, fmt.Sprintf("Failed to save enriched data: %v", err))
 		return
@@ -225,7 +229,7 @@ func (p *Pipeline) EnrichAsset(ctx context.Context, assetID, videoURL, disciplin
 // setStatus updates the processing status of an asset.
 func (p *Pipeline) setStatus(assetID, status string) {
 	ctx := context.Background()
-	_, err := p.fs.Collection("assets").Doc(assetID).Update(ctx, []firestore.Update{
+	err := p.store.Assets().Update(ctx, assetID, []store.Update{
 		{Path: "processingStatus", Value: status},
 		{Path: "updatedAt", Value: time.Now()},
 	})
@@ -237,7 +241,7 @@ func (p *Pipeline) setStatus(assetID, status string) {
 // setError updates the asset with a failed status and error message.
 func (p *Pipeline) setError(assetID, errMsg string) {
 	ctx := context.Background()
-	_, err := p.fs.Collection("assets").Doc(assetID).Update(ctx, []firestore.Update{
+	err := p.store.Assets().Update(ctx, assetID, []store.Update{
 		{Path: "processingStatus", Value: "failed"},
 		{Path: "processingError", Value: &errMsg},
 		{Path: "updatedAt", Value: time.Now()},
@@ -252,100 +256,68 @@ func (p *Pipeline) fetchEntityContext(ctx context.Context, disciplineID string)
 	ec := &EntityContext{}
 
 	// Fetch disciplines
-	discIter := p.fs.Collection("disciplines").Documents(ctx)
-	defer discIter.Stop()
-	for {
-		doc, err := discIter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			break
-		}
-		if slug, ok := doc.Data()["slug"].(string); ok {
-			ec.Disciplines = append(ec.Disciplines, slug)
+	if disciplines, err := p.store.Disciplines().List(ctx, store.NewQuery()); err == nil {
+		for _, d := range disciplines {
+			if d.Slug != "" {
+				ec.Disciplines = append(ec.Disciplines, d.Slug)
+			}
 		}
 	}
 
+	byDiscipline := store.NewQuery().Where("disciplineId", "==", disciplineID)
+
 	// Fetch tags for discipline
-	tagIter := p.fs.Collection("tags").Where("disciplineId", "==", disciplineID).Documents(ctx)
-	defer tagIter.Stop()
-	for {
-		doc, err := tagIter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			break
-		}
-		data := doc.Data()
-		name, _ := data["name"].(string)
-		slug, _ := data["slug"].(string)
-		if name != "" && slug != "" {
-			ec.Tags = append(ec.Tags, NameSlugPair{Name: name, Slug: slug})
+	if tags, err := p.store.Tags().List(ctx, byDiscipline); err == nil {
+		for _, t := range tags {
+			if t.Name != "" && t.Slug != "" {
+				ec.Tags = append(ec.Tags, NameSlugPair{Name: t.Name, Slug: t.Slug})
+			}
 		}
 	}
 
 	// Fetch techniques for discipline
-	techIter := p.fs.Collection("techniques").Where("disciplineId", "==", disciplineID).Documents(ctx)
-	defer techIter.Stop()
-	for {
-		doc, err := techIter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			break
-		}
-		data := doc.Data()
-		name, _ := data["name"].(string)
-		slug, _ := data["slug"].(string)
-		if name != "" && slug != "" {
-			ec.Techniques = append(ec.Techniques, NameSlugPair{Name: name, Slug: slug})
+	if techniques, err := p.store.Techniques().List(ctx, byDiscipline); err == nil {
+		for _, t := range techniques {
+			if t.Name != "" && t.Slug != "" {
+				ec.Techniques = append(ec.Techniques, NameSlugPair{Name: t.Name, Slug: t.Slug})
+			}
 		}
 	}
 
 	// Fetch categories for discipline
-	catIter := p.fs.Collection("categories").Where("disciplineId", "==", disciplineID).Documents(ctx)
-	defer catIter.Stop()
-	for {
-		doc, err := catIter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			break
-		}
-		data := doc.Data()
-		name, _ := data["name"].(string)
-		slug, _ := data["slug"].(string)
-		parentID, _ := data["parentId"].(string)
-		if name != "" && slug != "" {
-			ec.Categories = append(ec.Categories, CategoryHierarchy{
-				Name:     name,
-				Slug:     slug,
-				ParentID: parentID,
-			})
+	if categories, err := p.store.Categories().List(ctx, byDiscipline); err == nil {
+		for _, c := range categories {
+			parentID := ""
+			if c.ParentID != nil {
+				parentID = *c.ParentID
+			}
+			if c.Name != "" && c.Slug != "" {
+				ec.Categories = append(ec.Categories, CategoryHierarchy{
+					Name:     c.Name,
+					Slug:     c.Slug,
+					ParentID: parentID,
+				})
+			}
 		}
 	}
 
 	return ec, nil
 }
 
+// bySlug matches a single document by discipline and slug.
+func bySlug(disciplineID, slug string) store.Query {
+	return store.NewQuery().
+		Where("disciplineId", "==", disciplineID).
+		Where("slug", "==", slug).
+		Limit(1)
+}
+
 // findOrCreateTag finds an existing tag by slug or creates a new one.
 func (p *Pipeline) findOrCreateTag(ctx context.Context, disciplineID, ownerUID, tagSlug string) (string, error) {
 	slug := slugify(tagSlug)
 
-	iter := p.fs.Collection("tags").
-		Where("disciplineId", "==", disciplineID).
-		Where("slug", "==", slug).
-		Limit(1).
-		Documents(ctx)
-	defer iter.Stop()
-
-	doc, err := iter.Next()
-	if err == nil {
-		return doc.Ref.ID, nil
+	if existing, err := p.store.Tags().List(ctx, bySlug(disciplineID, slug)); err == nil && len(existing) > 0 {
+		return existing[0].ID, nil
 	}
 
 	// Create new tag
@@ -354,88 +326,97 @@ func (p *Pipeline) findOrCreateTag(ctx context.Context, disciplineID, ownerUID,
 	name := strings.ReplaceAll(tagSlug, "-", " ")
 	name = strings.Title(name) //nolint:staticcheck
 
-	ref, _, err := p.fs.Collection("tags").Add(ctx, map[string]interface{}{
-		"name":         name,
-		"slug":         slug,
-		"description":  "",
-		"disciplineId": disciplineID,
-		"ownerUid":     ownerUID,
-		"createdAt":    now,
-		"updatedAt":    now,
+	id, err := p.store.Tags().Create(ctx, &model.Tag{
+		Name:         name,
+		Slug:         slug,
+		Description:  "",
+		DisciplineID: disciplineID,
+		OwnerUID:     ownerUID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
 	})
 	if err != nil {
 		return "", err
 	}
 
-	slog.Info("created tag", "id", ref.ID, "slug", slug)
-	return ref.ID, nil
+	slog.Info("created tag", "id", id, "slug", slug)
+	return id, nil
 }
 
-// findTechniqueBySlug finds an existing technique by slug.
-func (p *Pipeline) findTechniqueBySlug(ctx context.Context, disciplineID, slug string) (string, error) {
-	iter := p.fs.Collection("techniques").
+// resolveTechnique returns the ID of the technique known by slug, as its
+// own slug or an alias's, or "" if there is none.
+func (p *Pipeline) resolveTechnique(ctx context.Context, disciplineID, slug string) (string, error) {
+	existing, err := p.store.Techniques().List(ctx, bySlug(disciplineID, slug))
+	if err != nil || len(existing) > 0 {
+		return techniqueID(existing), err
+	}
+	existing, err = p.store.Techniques().List(ctx, store.NewQuery().
 		Where("disciplineId", "==", disciplineID).
-		Where("slug", "==", slug).
-		Limit(1).
-		Documents(ctx)
-	defer iter.Stop()
+		Where("aliasSlugs", "array-contains", slug).
+		Limit(1))
+	return techniqueID(existing), err
+}
 
-	doc, err := iter.Next()
-	if err != nil {
+func techniqueID(found []model.Technique) string {
+	if len(found) == 0 {
+		return ""
+	}
+	return found[0].ID
+}
+
+// findTechniqueBySlug finds an existing technique by slug or alias.
+func (p *Pipeline) findTechniqueBySlug(ctx context.Context, disciplineID, slug string) (string, error) {
+	id, err := p.resolveTechnique(ctx, disciplineID, slug)
+	if err != nil || id == "" {
 		return "", fmt.Errorf("technique not found: %s", slug)
 	}
-	return doc.Ref.ID, nil
+	return id, nil
 }
 
-// findOrCreateTechnique finds an existing technique by name/slug or creates a new one.
+// findOrCreateTechnique finds an existing technique by name/slug or alias,
+// or creates a new one. Alias slugs are generated by validate.GenerateSlug,
+// which also strips punctuation, so both slug forms are tried.
 func (p *Pipeline) findOrCreateTechnique(ctx context.Context, disciplineID, ownerUID, techName string) (string, error) {
 	slug := slugify(techName)
 
-	iter := p.fs.Collection("techniques").
-		Where("disciplineId", "==", disciplineID).
-		Where("slug", "==", slug).
-		Limit(1).
-		Documents(ctx)
-	defer iter.Stop()
-
-	doc, err := iter.Next()
-	if err == nil {
-		return doc.Ref.ID, nil
+	candidates := []string{slug}
+	if canonical := validate.GenerateSlug(techName); canonical != "" && canonical != slug {
+		candidates = append(candidates, canonical)
+	}
+	for _, candidate := range candidates {
+		if id, err := p.resolveTechnique(ctx, disciplineID, candidate); err == nil && id != "" {
+			return id, nil
+		}
 	}
 
 	// Create new technique
 	now := time.Now()
-	ref, _, err := p.fs.Collection("techniques").Add(ctx, map[string]interface{}{
-		"name":         techName,
-		"slug":         slug,
-		"description":  "",
-		"disciplineId": disciplineID,
-		"categoryIds":  []string{},
-		"tagIds":       []string{},
-		"ownerUid":     ownerUID,
-		"createdAt":    now,
-		"updatedAt":    now,
+	id, err := p.store.Techniques().Create(ctx, &model.Technique{
+		Name:         techName,
+		Slug:         slug,
+		Aliases:      []string{},
+		AliasSlugs:   []string{},
+		Description:  "",
+		DisciplineID: disciplineID,
+		CategoryIDs:  []string{},
+		TagIDs:       []string{},
+		OwnerUID:     ownerUID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
 	})
 	if err != nil {
 		return "", err
 	}
 
-	slog.Info("created technique", "id", ref.ID, "name", techName)
-	return ref.ID, nil
+	slog.Info("created technique", "id", id, "name", techName)
+	return id, nil
 }
 
 // findCategoryBySlug finds an existing category by slug (no auto-creation).
 func (p *Pipeline) findCategoryBySlug(ctx context.Context, disciplineID, slug string) (string, error) {
-	iter := p.fs.Collection("categories").
-		Where("disciplineId", "==", disciplineID).
-		Where("slug", "==", slug).
-		Limit(1).
-		Documents(ctx)
-	defer iter.Stop()
-
-	doc, err := iter.Next()
-	if err != nil {
+	existing, err := p.store.Categories().List(ctx, bySlug(disciplineID, slug))
+	if err != nil || len(existing) == 0 {
 		return "", fmt.Errorf("category not found: %s", slug)
 	}
-	return doc.Ref.ID, nil
+	return existing[0].ID, nil
 }
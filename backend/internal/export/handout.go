@@ -0,0 +1,198 @@
+// Package export renders a curriculum as a class handout in Markdown, HTML
+// or PDF. The PDF writer is self-contained, using the standard PDF fonts, so
+// no external tools are needed to produce it.
+package export
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/duration"
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+// Format is an export format.
+type Format string
+
+const (
+	FormatMarkdown Format = "md"
+	FormatHTML     Format = "html"
+	FormatPDF      Format = "pdf"
+)
+
+// ParseFormat returns the format named s, and false if there is none.
+func ParseFormat(s string) (Format, bool) {
+	switch f := Format(s); f {
+	case FormatMarkdown, FormatHTML, FormatPDF:
+		return f, true
+	}
+	return "", false
+}
+
+// ContentType returns the media type of documents in format f.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatPDF:
+		return "application/pdf"
+	}
+	return "text/markdown; charset=utf-8"
+}
+
+// Handout is a curriculum laid out for export: its elements in order, each
+// with a heading and a duration, and the total duration.
+type Handout struct {
+	Curriculum   *model.Curriculum
+	Items        []Item
+	TotalSeconds int
+}
+
+// Item is one element of a handout.
+type Item struct {
+	// Number is the element's position, "3", or "3.1" for the first element
+	// of the section numbered 3.
+	Number string
+	// Level is 1 at the top level and 2 within a section.
+	Level   int
+	Element *model.CurriculumElement
+	// Heading is the element's title, falling back to the name of what it
+	// references.
+	Heading string
+	// Duration is the element's duration in clock notation, or as entered
+	// when it cannot be parsed; empty when unset. Sections show the total of
+	// their elements.
+	Duration string
+}
+
+// NewHandout lays out c with elements, which must be in reading order (see
+// curriculum.Order).
+func NewHandout(c *model.Curriculum, elements []model.CurriculumElement) *Handout {
+	h := &Handout{Curriculum: c}
+	curriculum.SetTotals(elements)
+	top, sub, section := 0, 0, ""
+	for i := range elements {
+		e := &elements[i]
+		item := Item{Element: e, Heading: heading(e)}
+		if p := curriculum.ParentOf(e); p != "" && p == section {
+			sub++
+			item.Number, item.Level = fmt.Sprintf("%d.%d", top, sub), 2
+		} else {
+			top, sub, section = top+1, 0, ""
+			item.Number, item.Level = strconv.Itoa(top), 1
+			if e.Type == model.ElementTypeSection {
+				section = e.ID
+			}
+		}
+		if e.TotalDurationSeconds > 0 {
+			item.Duration = duration.Format(e.TotalDurationSeconds)
+		}
+		if e.Duration != nil && *e.Duration != "" {
+			item.Duration = *e.Duration
+			if seconds, ok := duration.Parse(*e.Duration); ok {
+				item.Duration = duration.Format(seconds)
+			}
+		}
+		if seconds := curriculum.ClipSeconds(e); seconds > 0 {
+			item.Heading += fmt.Sprintf(" [%s–%s]", duration.Format(*e.StartSeconds), duration.Format(*e.EndSeconds))
+			item.Duration = duration.Format(seconds)
+		}
+		h.TotalSeconds += curriculum.ElementSeconds(e)
+		h.Items = append(h.Items, item)
+	}
+	return h
+}
+
+// Total returns the total duration in clock notation, or "" when no element
+// has a duration.
+func (h *Handout) Total() string {
+	if h.TotalSeconds == 0 {
+		return ""
+	}
+	return duration.Format(h.TotalSeconds)
+}
+
+// ImageURLs returns the URLs of the images a handout shows: image elements
+// and the thumbnails of technique and asset snapshots.
+func (h *Handout) ImageURLs() []string {
+	var urls []string
+	for _, item := range h.Items {
+		if u := imageURL(item.Element); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// imageURL returns the URL of the image shown for e, if any.
+func imageURL(e *model.CurriculumElement) string {
+	switch {
+	case e.Type == model.ElementTypeImage && e.ImageURL != nil:
+		return *e.ImageURL
+	case e.Snapshot != nil:
+		return e.Snapshot.ThumbnailURL
+	}
+	return ""
+}
+
+// link returns the URL an element points to: where a clip starts playing, or
+// the URL in its snapshot.
+func link(e *model.CurriculumElement) string {
+	if e.Snapshot == nil || e.Snapshot.URL == "" {
+		return ""
+	}
+	if curriculum.ClipSeconds(e) > 0 {
+		return curriculum.ClipURL(e.Snapshot.URL, *e.StartSeconds, *e.EndSeconds)
+	}
+	return e.Snapshot.URL
+}
+
+func heading(e *model.CurriculumElement) string {
+	if e.Title != nil && strings.TrimSpace(*e.Title) != "" {
+		return strings.TrimSpace(*e.Title)
+	}
+	if e.Snapshot != nil && e.Snapshot.Name != "" {
+		return e.Snapshot.Name
+	}
+	switch e.Type {
+	case model.ElementTypeImage:
+		return "Image"
+	case model.ElementTypeList:
+		return "List"
+	case model.ElementTypeTechnique:
+		return "Technique"
+	case model.ElementTypeAsset:
+		return "Asset"
+	case model.ElementTypeClip:
+		return "Clip"
+	case model.ElementTypeSection:
+		return "Section"
+	}
+	return "Notes"
+}
+
+// Filename returns a download file name for h in format f, made from the
+// curriculum title.
+func (h *Handout) Filename(f Format) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(h.Curriculum.Title) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	name := b.String()
+	if name == "" {
+		name = "curriculum"
+	}
+	return fmt.Sprintf("%s.%s", name, f)
+}
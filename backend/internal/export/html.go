@@ -0,0 +1,127 @@
+package export
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+var handoutTemplate = template.Must(template.New("handout").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Curriculum.Title}}</title>
+<style>
+@page { margin: 2cm; }
+body { font-family: Helvetica, Arial, sans-serif; font-size: 11pt; line-height: 1.4; color: #222; max-width: 48rem; margin: 2rem auto; }
+h1 { margin-bottom: 0.25rem; }
+.summary { color: #666; }
+section { border-top: 1px solid #ddd; padding-top: 0.5rem; margin-top: 1rem; break-inside: avoid; }
+h2 { font-size: 13pt; display: flex; justify-content: space-between; }
+.duration { color: #666; font-weight: normal; }
+.thumbnail { float: right; max-width: 10rem; max-height: 7.5rem; margin: 0 0 0.5rem 1rem; }
+.image { max-width: 100%; max-height: 20rem; }
+.link { word-break: break-all; }
+section::after { content: ""; display: block; clear: both; }
+section.child { margin-left: 1.5rem; }
+</style>
+</head>
+<body>
+<h1>{{.Curriculum.Title}}</h1>
+{{with .Curriculum.Description}}<p>{{.}}</p>
+{{end}}<p class="summary">{{.Summary}}</p>
+{{range .Items}}<section{{if eq .Level 2}} class="child"{{end}}>
+<h2><span>{{.Number}}. {{.Heading}}</span>{{with .Duration}}<span class="duration">{{.}}</span>{{end}}</h2>
+{{if .Image}}<img class="image" src="{{.Image}}" alt="{{.Heading}}">
+{{else if .Thumbnail}}<img class="thumbnail" src="{{.Thumbnail}}" alt="">
+{{end}}{{with .Link}}<p class="link"><a href="{{.}}" rel="nofollow">{{.}}</a></p>
+{{end}}{{with .Description}}<p>{{.}}</p>
+{{end}}{{.Body}}
+</section>
+{{end}}</body>
+</html>
+`))
+
+type htmlItem struct {
+	Item
+	Image, Thumbnail, Link string
+	Description            string
+	Body                   template.HTML
+}
+
+// HTML writes h as a standalone HTML page, styled for printing.
+func HTML(w io.Writer, h *Handout) error {
+	items := make([]htmlItem, 0, len(h.Items))
+	for _, item := range h.Items {
+		e := item.Element
+		hi := htmlItem{Item: item, Body: renderHTML(detailBlocks(e))}
+		if u := imageURL(e); u != "" && safeHref(u) {
+			if e.Snapshot != nil {
+				hi.Thumbnail = u
+			} else {
+				hi.Image = u
+			}
+		}
+		if e.Snapshot != nil {
+			hi.Link, hi.Description = link(e), e.Snapshot.Description
+		}
+		items = append(items, hi)
+	}
+	return handoutTemplate.Execute(w, struct {
+		*Handout
+		Summary string
+		Items   []htmlItem
+	}{h, summary(h), items})
+}
+
+// renderHTML writes blocks as HTML. Element titles are h2, so headings in
+// details start at h3.
+func renderHTML(blocks []block) template.HTML {
+	var b strings.Builder
+	for _, bl := range blocks {
+		switch bl.kind {
+		case headingBlock:
+			level := min(bl.level+2, 6)
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", level, renderSpans(bl.spans), level)
+		case bullets, numbers:
+			tag := "ul"
+			if bl.kind == numbers {
+				tag = "ol"
+			}
+			fmt.Fprintf(&b, "<%s>\n", tag)
+			for _, it := range bl.items {
+				fmt.Fprintf(&b, "<li>%s</li>\n", renderSpans(it))
+			}
+			fmt.Fprintf(&b, "</%s>\n", tag)
+		case quote:
+			fmt.Fprintf(&b, "<blockquote>%s</blockquote>\n", renderSpans(bl.spans))
+		case code:
+			fmt.Fprintf(&b, "<pre><code>%s</code></pre>\n", template.HTMLEscapeString(bl.text))
+		default:
+			fmt.Fprintf(&b, "<p>%s</p>\n", renderSpans(bl.spans))
+		}
+	}
+	return template.HTML(b.String())
+}
+
+func renderSpans(spans []span) string {
+	var b strings.Builder
+	for _, sp := range spans {
+		text := template.HTMLEscapeString(sp.text)
+		if sp.code {
+			text = "<code>" + text + "</code>"
+		}
+		if sp.em {
+			text = "<em>" + text + "</em>"
+		}
+		if sp.strong {
+			text = "<strong>" + text + "</strong>"
+		}
+		if sp.href != "" {
+			text = fmt.Sprintf(`<a href="%s" rel="nofollow">%s</a>`, template.HTMLEscapeString(sp.href), text)
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
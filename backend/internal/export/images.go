@@ -0,0 +1,146 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // decoders for fetched images
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	maxImageBytes  = 5 << 20
+	maxImagePixels = 4096 * 4096
+	fetchWorkers   = 4
+)
+
+// ImageFetcher downloads the image at url for embedding in a PDF.
+type ImageFetcher func(ctx context.Context, url string) ([]byte, error)
+
+// errPrivateAddress is returned when an image URL resolves to an address
+// that is not on the public internet.
+var errPrivateAddress = errors.New("export: image host is not public")
+
+// imageClient only connects to public addresses, so image URLs entered by
+// editors cannot reach services next to the API.
+var imageClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(_, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				ip := net.ParseIP(host)
+				if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+					ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+					return errPrivateAddress
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+	},
+}
+
+// FetchImage downloads an image over HTTP(S) from a public host, up to 5 MB.
+func FetchImage(ctx context.Context, url string) ([]byte, error) {
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return nil, fmt.Errorf("export: unsupported image url %q", url)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := imageClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("export: fetch image: status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageBytes {
+		return nil, errors.New("export: image too large")
+	}
+	return data, nil
+}
+
+// FetchImages downloads the given URLs with fetch, a few at a time, and
+// returns the images that could be fetched by URL. Failures are left out:
+// the PDF shows the URL instead.
+func FetchImages(ctx context.Context, fetch ImageFetcher, urls []string) map[string][]byte {
+	images := map[string][]byte{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, fetchWorkers)
+	seen := map[string]bool{}
+	for _, u := range urls {
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if data, err := fetch(ctx, u); err == nil {
+				mu.Lock()
+				images[u] = data
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return images
+}
+
+// pdfImage is an image ready to embed: JPEG data with its pixel size.
+type pdfImage struct {
+	data          []byte
+	width, height int
+}
+
+// decodeImage converts a JPEG, PNG or GIF image to an embeddable JPEG,
+// flattening transparency onto white.
+func decodeImage(data []byte) (*pdfImage, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, errors.New("export: image dimensions out of range")
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := src.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Over)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return &pdfImage{data: buf.Bytes(), width: bounds.Dx(), height: bounds.Dy()}, nil
+}
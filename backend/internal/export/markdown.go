@@ -0,0 +1,267 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/validate"
+)
+
+// Markdown writes h as a Markdown document. Element details are written as
+// stored, which is already sanitized Markdown.
+func Markdown(w io.Writer, h *Handout) error {
+	bw := bufio.NewWriter(w)
+	c := h.Curriculum
+	fmt.Fprintf(bw, "# %s\n\n", oneLine(c.Title))
+	if c.Description != "" {
+		fmt.Fprintf(bw, "%s\n\n", c.Description)
+	}
+	fmt.Fprintf(bw, "%s\n", summary(h))
+
+	for _, item := range h.Items {
+		e := item.Element
+		fmt.Fprintf(bw, "\n%s %s. %s", strings.Repeat("#", item.Level+1), item.Number, oneLine(item.Heading))
+		if item.Duration != "" {
+			fmt.Fprintf(bw, " (%s)", item.Duration)
+		}
+		bw.WriteString("\n\n")
+		if u := imageURL(e); u != "" {
+			fmt.Fprintf(bw, "![%s](<%s>)\n\n", oneLine(item.Heading), u)
+		}
+		if u := link(e); u != "" {
+			fmt.Fprintf(bw, "<%s>\n\n", u)
+		}
+		if e.Snapshot != nil && e.Snapshot.Description != "" {
+			fmt.Fprintf(bw, "%s\n\n", e.Snapshot.Description)
+		}
+		if e.Caption != nil && *e.Caption != "" {
+			fmt.Fprintf(bw, "%s\n\n", oneLine(*e.Caption))
+		}
+		if e.Details != nil && *e.Details != "" {
+			fmt.Fprintf(bw, "%s\n\n", strings.TrimSpace(*e.Details))
+		}
+		for _, it := range e.Items {
+			fmt.Fprintf(bw, "- %s\n", oneLine(it))
+		}
+		if len(e.Items) > 0 {
+			bw.WriteString("\n")
+		}
+	}
+	return bw.Flush()
+}
+
+// summary is the line under the title: element count and total duration.
+func summary(h *Handout) string {
+	s := fmt.Sprintf("%d elements", len(h.Items))
+	if len(h.Items) == 1 {
+		s = "1 element"
+	}
+	if total := h.Total(); total != "" {
+		s += " · total " + total
+	}
+	return s
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// The HTML and PDF renderers read the Markdown of element details through
+// this small parser. It covers what editors use in class notes: headings,
+// paragraphs, bullet and numbered lists, quotes, fenced code, emphasis, code
+// spans and links. Anything else is shown as text.
+
+type blockKind int
+
+const (
+	paragraph blockKind = iota
+	headingBlock
+	bullets
+	numbers
+	quote
+	code
+)
+
+type block struct {
+	kind  blockKind
+	level int      // heading level
+	spans []span   // paragraph, heading, quote
+	items [][]span // bullets, numbers
+	text  string   // code
+}
+
+type span struct {
+	text   string
+	strong bool
+	em     bool
+	code   bool
+	href   string
+}
+
+var (
+	headingLine = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	bulletLine  = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	numberLine  = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	quoteLine   = regexp.MustCompile(`^\s*>\s?(.*)$`)
+)
+
+// parseMarkdown splits stored details into blocks. Details are sanitized
+// HTML-flavoured Markdown; tags are dropped and entities decoded first, and
+// the renderers escape the text again.
+func parseMarkdown(src string) []block {
+	src = html.UnescapeString(validate.StripAllHTML(src))
+	var blocks []block
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, block{kind: paragraph, spans: parseInline(strings.Join(para, " "))})
+			para = nil
+		}
+	}
+	// item appends to the list block of kind, starting one if needed.
+	item := func(kind blockKind, text string) {
+		if n := len(blocks); n == 0 || blocks[n-1].kind != kind || len(para) > 0 {
+			flush()
+			blocks = append(blocks, block{kind: kind})
+		}
+		b := &blocks[len(blocks)-1]
+		b.items = append(b.items, parseInline(text))
+	}
+
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch m := headingLine.FindStringSubmatch(trimmed); {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			var body []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				body = append(body, lines[i])
+			}
+			blocks = append(blocks, block{kind: code, text: strings.Join(body, "\n")})
+		case m != nil:
+			flush()
+			blocks = append(blocks, block{kind: headingBlock, level: len(m[1]), spans: parseInline(m[2])})
+		case bulletLine.MatchString(line):
+			item(bullets, bulletLine.FindStringSubmatch(line)[1])
+		case numberLine.MatchString(line):
+			item(numbers, numberLine.FindStringSubmatch(line)[1])
+		case quoteLine.MatchString(line):
+			flush()
+			text := quoteLine.FindStringSubmatch(line)[1]
+			if n := len(blocks); n > 0 && blocks[n-1].kind == quote {
+				blocks[n-1].spans = append(blocks[n-1].spans, parseInline(" "+text)...)
+			} else {
+				blocks = append(blocks, block{kind: quote, spans: parseInline(text)})
+			}
+		case len(para) == 0 && len(blocks) > 0 && len(line) > len(trimmed) &&
+			(blocks[len(blocks)-1].kind == bullets || blocks[len(blocks)-1].kind == numbers):
+			// An indented line continues the last list item.
+			b := &blocks[len(blocks)-1]
+			last := &b.items[len(b.items)-1]
+			*last = append(*last, parseInline(" "+trimmed)...)
+		default:
+			para = append(para, trimmed)
+		}
+	}
+	flush()
+	return blocks
+}
+
+// parseInline splits a line of Markdown into spans of uniform style.
+func parseInline(s string) []span {
+	var spans []span
+	var cur span
+	var text strings.Builder
+	emit := func() {
+		if text.Len() > 0 {
+			cur.text = text.String()
+			spans = append(spans, cur)
+			text.Reset()
+		}
+	}
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune("\\`*_[]()#+-.!>", rune(rest[1])):
+			text.WriteByte(rest[1])
+			i += 2
+			continue
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				emit()
+				spans = append(spans, span{text: rest[1 : end+1], code: true})
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if cur.strong || strings.Contains(rest[2:], rest[:2]) {
+				emit()
+				cur.strong = !cur.strong
+				i += 2
+				continue
+			}
+		case rest[0] == '*' || rest[0] == '_':
+			if cur.em || strings.ContainsRune(rest[1:], rune(rest[0])) {
+				emit()
+				cur.em = !cur.em
+				i++
+				continue
+			}
+		case rest[0] == '[':
+			if mid := strings.Index(rest, "]("); mid > 0 {
+				if end := strings.IndexByte(rest[mid:], ')'); end > 0 {
+					emit()
+					href := rest[mid+2 : mid+end]
+					for _, sp := range parseInline(rest[1:mid]) {
+						sp.strong, sp.em = sp.strong || cur.strong, sp.em || cur.em
+						if safeHref(href) {
+							sp.href = href
+						}
+						spans = append(spans, sp)
+					}
+					i += mid + end + 1
+					continue
+				}
+			}
+		}
+		text.WriteByte(rest[0])
+		i++
+	}
+	emit()
+	return spans
+}
+
+// safeHref reports whether a link target may be kept.
+func safeHref(href string) bool {
+	lower := strings.ToLower(href)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "mailto:")
+}
+
+// detailBlocks returns the blocks shown for an element after its snapshot:
+// the caption of a clip, its details and list items.
+func detailBlocks(e *model.CurriculumElement) []block {
+	var blocks []block
+	if e.Caption != nil && *e.Caption != "" {
+		blocks = append(blocks, block{kind: paragraph, spans: []span{{text: oneLine(*e.Caption)}}})
+	}
+	if e.Details != nil {
+		blocks = append(blocks, parseMarkdown(*e.Details)...)
+	}
+	if len(e.Items) > 0 {
+		b := block{kind: bullets}
+		for _, it := range e.Items {
+			b.items = append(b.items, []span{{text: it}})
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
@@ -0,0 +1,446 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A4 in points, with the margins of the text area.
+const (
+	pageWidth    = 595.28
+	pageHeight   = 841.89
+	margin       = 56.0
+	contentWidth = pageWidth - 2*margin
+
+	titleSize   = 20.0
+	headingSize = 13.0
+	bodySize    = 10.5
+	smallSize   = 9.0
+	codeSize    = 9.0
+	footerSize  = 8.0
+
+	thumbnailWidth  = 160.0
+	thumbnailHeight = 120.0
+	imageHeight     = 300.0
+)
+
+const (
+	colorText  = "0 g"
+	colorMuted = "0.4 g"
+	colorLink  = "0.1 0.3 0.7 rg"
+)
+
+// PDF writes h as an A4 PDF. images holds image data by URL, as returned by
+// FetchImages; an image that is missing or cannot be decoded is replaced by
+// its URL.
+func PDF(w io.Writer, h *Handout, images map[string][]byte) error {
+	p := &pdfWriter{title: h.Curriculum.Title, images: map[string]int{}}
+	p.newPage()
+
+	p.text([]span{{text: h.Curriculum.Title}}, textStyle{size: titleSize, font: fontBold})
+	if h.Curriculum.Description != "" {
+		p.text([]span{{text: h.Curriculum.Description}}, textStyle{size: bodySize})
+	}
+	p.text([]span{{text: summary(h)}}, textStyle{size: smallSize, color: colorMuted})
+
+	for _, item := range h.Items {
+		e := item.Element
+		p.gap(6)
+		p.rule()
+		p.gap(6)
+		p.heading(fmt.Sprintf("%s. %s", item.Number, oneLine(item.Heading)), item.Duration)
+
+		if u := imageURL(e); u != "" {
+			maxW, maxH := contentWidth, imageHeight
+			if e.Snapshot != nil {
+				maxW, maxH = thumbnailWidth, thumbnailHeight
+			}
+			if !p.image(u, images[u], maxW, maxH) && e.Snapshot == nil {
+				p.text([]span{{text: u, href: u}}, textStyle{size: smallSize})
+			}
+		}
+		if e.Snapshot != nil {
+			if u := link(e); u != "" {
+				p.text([]span{{text: u, href: u}}, textStyle{size: smallSize})
+			}
+			if e.Snapshot.Description != "" {
+				p.text([]span{{text: e.Snapshot.Description}}, textStyle{size: bodySize})
+			}
+		}
+		p.blocks(detailBlocks(e))
+	}
+
+	return p.writeTo(w)
+}
+
+// pdfWriter lays out text and images top to bottom, starting a new page
+// when the current one is full.
+type pdfWriter struct {
+	title  string
+	pages  []*bytes.Buffer
+	page   *bytes.Buffer
+	y      float64 // top of the free space on the page
+	images map[string]int
+	embed  []*pdfImage
+}
+
+type textStyle struct {
+	size   float64
+	font   pdfFont
+	color  string
+	indent float64
+	// bullet is drawn in the indent before the first line.
+	bullet string
+}
+
+func (p *pdfWriter) newPage() {
+	p.page = &bytes.Buffer{}
+	p.pages = append(p.pages, p.page)
+	p.y = pageHeight - margin
+}
+
+// ensure starts a new page unless height fits on the current one.
+func (p *pdfWriter) ensure(height float64) {
+	if p.y-height < margin && p.y < pageHeight-margin {
+		p.newPage()
+	}
+}
+
+func (p *pdfWriter) gap(height float64) {
+	p.y -= height
+}
+
+func (p *pdfWriter) rule() {
+	p.ensure(1)
+	fmt.Fprintf(p.page, "0.8 G 0.5 w %s %s m %s %s l S\n", num(margin), num(p.y), num(pageWidth-margin), num(p.y))
+}
+
+// heading writes an element heading with its duration right-aligned.
+func (p *pdfWriter) heading(title, dur string) {
+	durText := winAnsi(dur)
+	durWidth := fontRegular.width(durText, headingSize)
+	p.ensure(headingSize * 1.4)
+	top := p.y
+	p.text([]span{{text: title}}, textStyle{size: headingSize, font: fontBold}, durWidth+12)
+	if len(durText) > 0 {
+		fmt.Fprintf(p.page, "BT %s /F%d %s Tf %s %s Td (%s) Tj ET\n", colorMuted, fontRegular, num(headingSize),
+			num(pageWidth-margin-durWidth), num(top-headingSize), escape(durText))
+	}
+}
+
+// blocks writes parsed Markdown.
+func (p *pdfWriter) blocks(blocks []block) {
+	for _, b := range blocks {
+		switch b.kind {
+		case headingBlock:
+			p.text(b.spans, textStyle{size: max(headingSize-float64(b.level), bodySize), font: fontBold})
+		case bullets, numbers:
+			for i, it := range b.items {
+				bullet := "•"
+				if b.kind == numbers {
+					bullet = strconv.Itoa(i+1) + "."
+				}
+				p.text(it, textStyle{size: bodySize, indent: 16, bullet: bullet})
+			}
+		case quote:
+			p.text(b.spans, textStyle{size: bodySize, font: fontItalic, color: colorMuted, indent: 16})
+		case code:
+			for _, line := range strings.Split(b.text, "\n") {
+				p.text([]span{{text: line, code: true}}, textStyle{size: codeSize, indent: 8})
+			}
+		default:
+			p.text(b.spans, textStyle{size: bodySize})
+		}
+	}
+}
+
+// piece is a run of text in one font; a word is made of pieces with no
+// space between them.
+type piece struct {
+	text []byte
+	font pdfFont
+	link bool
+}
+
+type word []piece
+
+func (w word) width(size float64) float64 {
+	total := 0.0
+	for _, pc := range w {
+		total += pc.font.width(pc.text, size)
+	}
+	return total
+}
+
+// words splits spans into words, styling each piece. Links whose text is not
+// their URL are followed by the URL, since a printed page cannot be clicked.
+func words(spans []span, base pdfFont) []word {
+	var out []word
+	glue := false // whether the next piece continues the last word
+	for i, sp := range spans {
+		font := base
+		switch {
+		case sp.code:
+			font = fontMono
+		case (sp.strong || base == fontBold) && (sp.em || base == fontItalic):
+			font = fontBoldItalic
+		case sp.strong:
+			font = fontBold
+		case sp.em:
+			font = fontItalic
+		}
+		text := sp.text
+		if sp.href != "" && sp.href != text && (i+1 == len(spans) || spans[i+1].href != sp.href) {
+			text += " <" + sp.href + ">"
+		}
+		for j, field := range strings.Split(text, " ") {
+			if j > 0 {
+				glue = false
+			}
+			if field == "" {
+				continue
+			}
+			pc := piece{text: winAnsi(field), font: font, link: sp.href != ""}
+			if glue && len(out) > 0 {
+				out[len(out)-1] = append(out[len(out)-1], pc)
+			} else {
+				out = append(out, word{pc})
+			}
+			glue = true
+		}
+	}
+	return out
+}
+
+// text writes a paragraph, wrapping it to the text area less reserve points
+// on the right.
+func (p *pdfWriter) text(spans []span, st textStyle, reserve ...float64) {
+	width := contentWidth - st.indent
+	for _, r := range reserve {
+		width -= r
+	}
+	if st.color == "" {
+		st.color = colorText
+	}
+	lineHeight := st.size * 1.4
+
+	var lines [][]word
+	var line []word
+	lineWidth := 0.0
+	for _, w := range words(spans, st.font) {
+		for _, part := range splitWord(w, width, st.size) {
+			ww := part.width(st.size)
+			space := part[0].font.width([]byte{' '}, st.size)
+			if len(line) > 0 && lineWidth+space+ww > width {
+				lines = append(lines, line)
+				line, lineWidth = nil, 0
+			}
+			if len(line) > 0 {
+				lineWidth += space
+			}
+			line = append(line, part)
+			lineWidth += ww
+		}
+	}
+	if len(line) > 0 || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+
+	for i, line := range lines {
+		p.ensure(lineHeight)
+		baseline := p.y - st.size
+		x := margin + st.indent
+		fmt.Fprintf(p.page, "BT %s /F%d %s Tf %s %s Td", st.color, st.font, num(st.size), num(x), num(baseline))
+		if i == 0 && st.bullet != "" {
+			bullet := winAnsi(st.bullet)
+			fmt.Fprintf(p.page, " %s 0 Td (%s) Tj %s 0 Td", num(-st.indent+4), escape(bullet), num(st.indent-4))
+		}
+		font, link := st.font, false
+		for j, w := range line {
+			for k, pc := range w {
+				if pc.font != font {
+					fmt.Fprintf(p.page, " /F%d %s Tf", pc.font, num(st.size))
+					font = pc.font
+				}
+				if pc.link != link {
+					if pc.link {
+						fmt.Fprintf(p.page, " %s", colorLink)
+					} else {
+						fmt.Fprintf(p.page, " %s", st.color)
+					}
+					link = pc.link
+				}
+				text := pc.text
+				if j > 0 && k == 0 {
+					text = append([]byte{' '}, text...)
+				}
+				fmt.Fprintf(p.page, " (%s) Tj", escape(text))
+			}
+		}
+		p.page.WriteString(" ET\n")
+		p.y -= lineHeight
+	}
+	p.y -= st.size * 0.4
+}
+
+// splitWord breaks a word wider than width into parts that fit, so long
+// URLs do not run off the page.
+func splitWord(w word, width, size float64) []word {
+	if w.width(size) <= width {
+		return []word{w}
+	}
+	var parts []word
+	var cur word
+	curWidth := 0.0
+	for _, pc := range w {
+		start := 0
+		for i, c := range pc.text {
+			cw := pc.font.width([]byte{c}, size)
+			if curWidth+cw > width && (len(cur) > 0 || i > start) {
+				if i > start {
+					cur = append(cur, piece{text: pc.text[start:i], font: pc.font, link: pc.link})
+				}
+				parts = append(parts, cur)
+				cur, curWidth, start = nil, 0, i
+			}
+			curWidth += cw
+		}
+		if start < len(pc.text) {
+			cur = append(cur, piece{text: pc.text[start:], font: pc.font, link: pc.link})
+		}
+	}
+	if len(cur) > 0 {
+		parts = append(parts, cur)
+	}
+	return parts
+}
+
+// image draws the image at url scaled into maxWidth by maxHeight, never
+// larger than 0.75 point per pixel, and reports whether it could.
+func (p *pdfWriter) image(url string, data []byte, maxWidth, maxHeight float64) bool {
+	idx, ok := p.images[url]
+	if !ok {
+		if data == nil {
+			return false
+		}
+		img, err := decodeImage(data)
+		if err != nil {
+			return false
+		}
+		idx = len(p.embed)
+		p.embed = append(p.embed, img)
+		p.images[url] = idx
+	}
+	img := p.embed[idx]
+	scale := min(maxWidth/float64(img.width), maxHeight/float64(img.height), 0.75)
+	w, h := float64(img.width)*scale, float64(img.height)*scale
+	p.ensure(h)
+	fmt.Fprintf(p.page, "q %s 0 0 %s %s %s cm /Im%d Do Q\n", num(w), num(h), num(margin), num(p.y-h), idx)
+	p.y -= h + 6
+	return true
+}
+
+// writeTo serializes the document: catalog, page tree, info, fonts, images,
+// then each page with its content stream.
+func (p *pdfWriter) writeTo(w io.Writer) error {
+	var out bytes.Buffer
+	var offsets []int
+	obj := func(body string, stream []byte) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s", len(offsets), body)
+		if stream != nil {
+			out.WriteString("\nstream\n")
+			out.Write(stream)
+			out.WriteString("\nendstream")
+		}
+		out.WriteString("\nendobj\n")
+	}
+
+	const firstFont = 4
+	firstImage := firstFont + len(fontNames)
+	firstPage := firstImage + len(p.embed)
+
+	var resources strings.Builder
+	resources.WriteString("<< /Font <<")
+	for i := range fontNames {
+		fmt.Fprintf(&resources, " /F%d %d 0 R", i, firstFont+i)
+	}
+	resources.WriteString(" >> /XObject <<")
+	for i := range p.embed {
+		fmt.Fprintf(&resources, " /Im%d %d 0 R", i, firstImage+i)
+	}
+	resources.WriteString(" >> >>")
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	obj("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	var kids []string
+	for i := range p.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", firstPage+2*i))
+	}
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)), nil)
+	obj(fmt.Sprintf("<< /Title (%s) /Producer (SkillHive) >>", escape(winAnsi(p.title))), nil)
+	for _, name := range fontNames {
+		obj(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name), nil)
+	}
+	for _, img := range p.embed {
+		obj(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB "+
+			"/BitsPerComponent 8 /Filter /DCTDecode /Length %d >>", img.width, img.height, len(img.data)), img.data)
+	}
+	for i, page := range p.pages {
+		footer := winAnsi(fmt.Sprintf("%s · %d / %d", oneLine(p.title), i+1, len(p.pages)))
+		fmt.Fprintf(page, "BT %s /F%d %s Tf %s %s Td (%s) Tj ET\n", colorMuted, fontRegular, num(footerSize),
+			num(margin), num(margin/2), escape(footer))
+		content, err := deflate(page.Bytes())
+		if err != nil {
+			return err
+		}
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources %s /Contents %d 0 R >>",
+			num(pageWidth), num(pageHeight), resources.String(), firstPage+2*i+1), nil)
+		obj(fmt.Sprintf("<< /Filter /FlateDecode /Length %d >>", len(content)), content)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// escape quotes WinAnsi text for a PDF string literal.
+func escape(text []byte) string {
+	var b strings.Builder
+	for _, c := range text {
+		if c == '\\' || c == '(' || c == ')' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// num formats a coordinate with at most two decimals.
+func num(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
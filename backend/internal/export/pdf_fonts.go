@@ -0,0 +1,99 @@
+package export
+
+// The PDF uses the standard Type 1 fonts every reader provides, so no font
+// is embedded. Text is encoded in WinAnsi and measured with the fonts'
+// published metrics.
+
+type pdfFont int
+
+const (
+	fontRegular pdfFont = iota
+	fontBold
+	fontItalic
+	fontBoldItalic
+	fontMono
+)
+
+var fontNames = [...]string{
+	fontRegular:    "Helvetica",
+	fontBold:       "Helvetica-Bold",
+	fontItalic:     "Helvetica-Oblique",
+	fontBoldItalic: "Helvetica-BoldOblique",
+	fontMono:       "Courier",
+}
+
+// Advance widths in 1/1000 em of the printable ASCII characters, from the
+// Adobe font metrics. The oblique faces share the widths of the upright
+// ones, and Courier is monospaced.
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// charWidth returns the width of WinAnsi character c in 1/1000 em.
+func (f pdfFont) charWidth(c byte) int {
+	if f == fontMono {
+		return 600
+	}
+	widths := &helveticaWidths
+	if f == fontBold || f == fontBoldItalic {
+		widths = &helveticaBoldWidths
+	}
+	if c >= 32 && c < 127 {
+		return widths[c-32]
+	}
+	// Accented letters and punctuation outside ASCII: close enough to a
+	// lowercase letter for line breaking.
+	return widths['n'-32]
+}
+
+// width returns the width of WinAnsi text at size points.
+func (f pdfFont) width(text []byte, size float64) float64 {
+	total := 0
+	for _, c := range text {
+		total += f.charWidth(c)
+	}
+	return float64(total) * size / 1000
+}
+
+// winAnsiExtra maps the characters WinAnsi places in 0x80-0x9F.
+var winAnsiExtra = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// winAnsi encodes s for the standard fonts. Characters WinAnsi lacks become
+// '?'.
+func winAnsi(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			out = append(out, ' ')
+		case r >= 32 && r < 127, r >= 0xA0 && r <= 0xFF:
+			out = append(out, byte(r))
+		case winAnsiExtra[r] != 0:
+			out = append(out, winAnsiExtra[r])
+		case r < 32:
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
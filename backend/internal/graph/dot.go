@@ -0,0 +1,33 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+// WriteDOT writes a technique graph in the Graphviz DOT language, as a
+// digraph whose nodes are labelled with technique names and whose edges are
+// labelled with their types.
+func WriteDOT(w io.Writer, g *model.TechniqueGraph) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph %s {\n", quote(g.DisciplineID))
+	for _, n := range g.Nodes {
+		fmt.Fprintf(bw, "  %s [label=%s];\n", quote(n.ID), quote(n.Name))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "  %s -> %s [label=%s];\n", quote(e.FromID), quote(e.ToID), quote(e.Type))
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// quote returns s as a DOT quoted string. Newlines become \n escapes, which
+// Graphviz renders as line breaks in labels.
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r", "", "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
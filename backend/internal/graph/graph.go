@@ -0,0 +1,147 @@
+// Package graph traverses the technique graph of a discipline: techniques
+// joined by typed, directed edges.
+package graph
+
+import (
+	"slices"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+// Directions of an edge relative to a technique.
+const (
+	Out  = "out"
+	In   = "in"
+	Both = "both"
+)
+
+// Graph indexes edges by the techniques they join. Edges keep the order they
+// were given in, which makes every traversal deterministic.
+type Graph struct {
+	out map[string][]*model.TechniqueEdge
+	in  map[string][]*model.TechniqueEdge
+}
+
+// New builds a graph of the edges of the given types, or of all edges when
+// types is empty.
+func New(edges []model.TechniqueEdge, types []string) *Graph {
+	g := &Graph{out: map[string][]*model.TechniqueEdge{}, in: map[string][]*model.TechniqueEdge{}}
+	for i := range edges {
+		e := &edges[i]
+		if len(types) > 0 && !slices.Contains(types, e.Type) {
+			continue
+		}
+		g.out[e.FromID] = append(g.out[e.FromID], e)
+		g.in[e.ToID] = append(g.in[e.ToID], e)
+	}
+	return g
+}
+
+// Step is an edge taken from one technique to the next, possibly against
+// its direction.
+type Step struct {
+	Edge      *model.TechniqueEdge
+	Direction string
+	// To is the technique the step arrives at.
+	To string
+}
+
+// Neighbors returns the steps from id along its edges in direction: Out,
+// In or Both. Outgoing edges come first.
+func (g *Graph) Neighbors(id, direction string) []Step {
+	var steps []Step
+	if direction != In {
+		for _, e := range g.out[id] {
+			steps = append(steps, Step{Edge: e, Direction: Out, To: e.ToID})
+		}
+	}
+	if direction != Out {
+		for _, e := range g.in[id] {
+			steps = append(steps, Step{Edge: e, Direction: In, To: e.FromID})
+		}
+	}
+	return steps
+}
+
+// ShortestPath returns the fewest steps from one technique to another,
+// following edges only in their direction when directed is set, and false
+// when there is no such path. The path from a technique to itself is empty.
+func (g *Graph) ShortestPath(from, to string, directed bool) ([]Step, bool) {
+	direction := Both
+	if directed {
+		direction = Out
+	}
+	// prev records the step that first reached each technique.
+	prev := map[string]Step{from: {}}
+	queue := []string{from}
+	for len(queue) > 0 && to != from {
+		id := queue[0]
+		queue = queue[1:]
+		for _, s := range g.Neighbors(id, direction) {
+			if _, seen := prev[s.To]; seen {
+				continue
+			}
+			prev[s.To] = s
+			if s.To == to {
+				queue = nil
+				break
+			}
+			queue = append(queue, s.To)
+		}
+	}
+	if _, ok := prev[to]; !ok {
+		return nil, false
+	}
+	var path []Step
+	for id := to; id != from; {
+		s := prev[id]
+		path = append(path, s)
+		if s.Direction == Out {
+			id = s.Edge.FromID
+		} else {
+			id = s.Edge.ToID
+		}
+	}
+	slices.Reverse(path)
+	return path, true
+}
+
+// Chains returns the chains of outgoing edges from a technique that visit
+// no technique twice and end after maxLen edges or where they cannot go on.
+// It stops after limit chains and reports whether there were more.
+func (g *Graph) Chains(from string, maxLen, limit int) ([][]Step, bool) {
+	var chains [][]Step
+	truncated := false
+	visited := map[string]bool{from: true}
+	var walk func(id string, chain []Step)
+	walk = func(id string, chain []Step) {
+		if truncated {
+			return
+		}
+		extended := false
+		if len(chain) < maxLen {
+			for _, s := range g.Neighbors(id, Out) {
+				if visited[s.To] {
+					continue
+				}
+				extended = true
+				visited[s.To] = true
+				walk(s.To, append(chain, s))
+				visited[s.To] = false
+				if truncated {
+					return
+				}
+			}
+		}
+		if extended || len(chain) == 0 {
+			return
+		}
+		if len(chains) == limit {
+			truncated = true
+			return
+		}
+		chains = append(chains, slices.Clone(chain))
+	}
+	walk(from, nil)
+	return chains, truncated
+}
@@ -2,20 +2,23 @@ package handler
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"firebase.google.com/go/v4/auth"
 	"github.com/go-chi/chi/v5"
+	"github.com/thomas/skillhive-api/internal/curriculum"
 	"github.com/thomas/skillhive-api/internal/enrich"
 	"github.com/thomas/skillhive-api/internal/middleware"
 	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
 	"google.golang.org/api/iterator"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 var validRoles = map[string]bool{
@@ -24,19 +27,48 @@ var validRoles = map[string]bool{
 	"admin":  true,
 }
 
+// UserAdmin is the subset of the Firebase Auth admin API used for user and
+// role management. Use NewFirebaseUserAdmin to adapt an *auth.Client.
+type UserAdmin interface {
+	Users(ctx context.Context, pageToken string) UserIterator
+	GetUser(ctx context.Context, uid string) (*auth.UserRecord, error)
+	GetUserByEmail(ctx context.Context, email string) (*auth.UserRecord, error)
+	SetCustomUserClaims(ctx context.Context, uid string, claims map[string]interface{}) error
+}
+
+// UserIterator pages through users, as *auth.UserIterator does.
+type UserIterator interface {
+	Next() (*auth.ExportedUserRecord, error)
+	PageInfo() *iterator.PageInfo
+}
+
+type firebaseUserAdmin struct {
+	*auth.Client
+}
+
+// NewFirebaseUserAdmin adapts a Firebase Auth client to UserAdmin.
+func NewFirebaseUserAdmin(c *auth.Client) UserAdmin {
+	return firebaseUserAdmin{Client: c}
+}
+
+func (f firebaseUserAdmin) Users(ctx context.Context, pageToken string) UserIterator {
+	return f.Client.Users(ctx, pageToken)
+}
+
 type AdminHandler struct {
-	authClient *auth.Client
-	fs         *firestore.Client
+	authClient UserAdmin
+	store      store.Store
 	pipeline   *enrich.Pipeline
 	enrichCtx  context.Context
 }
 
-func NewAdminHandler(authClient *auth.Client, fs *firestore.Client, pipeline *enrich.Pipeline, enrichCtx context.Context) *AdminHandler {
-	return &AdminHandler{authClient: authClient, fs: fs, pipeline: pipeline, enrichCtx: enrichCtx}
+func NewAdminHandler(authClient UserAdmin, s store.Store, pipeline *enrich.Pipeline, enrichCtx context.Context) *AdminHandler {
+	return &AdminHandler{authClient: authClient, store: s, pipeline: pipeline, enrichCtx: enrichCtx}
 }
 
 // ListUsers returns all users with optional role filtering and pagination.
 // GET /api/v1/admin/users?disciplineId=X&role=Y&pageSize=Z&pageToken=T
+// pageToken (or cursor) is the nextPageToken of the previous page.
 // role: "all" (default), "admin", "editor", "viewer", "none" (no role in discipline)
 func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -60,6 +92,14 @@ func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	pageToken := r.URL.Query().Get("pageToken")
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		pageToken = c
+	}
+	start, err := decodeUserCursor(pageToken)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid page token")
+		return
+	}
 
 	// Parse role filter: "all", "admin", "editor", "viewer", "none"
 	roleFilter := r.URL.Query().Get("role")
@@ -67,9 +107,20 @@ func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 		roleFilter = "all"
 	}
 
-	// Iterate Firebase Auth users with pagination
+	// Iterate Firebase Auth users with pagination, skipping the users of the
+	// first Firebase page that earlier requests already went through
 	var users []model.UserInfo
-	iter := h.authClient.Users(ctx, pageToken)
+	iter := &userPager{it: h.authClient.Users(ctx, start.PageToken)}
+	done := false
+	for i := 0; i < start.Skip && !done; i++ {
+		if _, err := iter.next(); err == iterator.Done {
+			done = true
+		} else if err != nil {
+			slog.Error("failed to iterate users", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to list users")
+			return
+		}
+	}
 
 	// We need to fetch more than pageSize to account for filtering
 	// Firebase doesn't support server-side filtering on custom claims
@@ -81,9 +132,10 @@ func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	fetched := 0
 	var nextPageToken string
 
-	for len(users) < pageSize {
-		u, err := iter.Next()
+	for !done && len(users) < pageSize {
+		u, err := iter.next()
 		if err == iterator.Done {
+			done = true
 			break
 		}
 		if err != nil {
@@ -122,9 +174,14 @@ func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Get next page token if there are more users
-	if pi := iter.PageInfo(); pi != nil && pi.Token != "" {
-		nextPageToken = pi.Token
+	// The next page resumes right after the last user looked at, so users
+	// skipped by the role filter or left in Firebase's page are not lost
+	if !done {
+		nextPageToken = iter.cursor().encode()
+		params := r.URL.Query()
+		params.Del("pageToken")
+		params.Set("cursor", nextPageToken)
+		w.Header().Add("Link", "<"+r.URL.Path+"?"+params.Encode()+`>; rel="next"`)
 	}
 
 	if users == nil {
@@ -137,6 +194,61 @@ func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// userCursor is the position of a user listing: the token of a Firebase
+// page and the number of users of that page already consumed.
+type userCursor struct {
+	PageToken string `json:"p,omitempty"`
+	Skip      int    `json:"s,omitempty"`
+}
+
+func (c userCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeUserCursor(token string) (userCursor, error) {
+	var c userCursor
+	if token == "" {
+		return c, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.Skip < 0 {
+		return c, errors.New("invalid page token")
+	}
+	return c, nil
+}
+
+// userPager tracks which Firebase page the last user came from. PageInfo's
+// Token starts as the requested token and moves on whenever Next fetches a
+// page, so a changed token means the user opened a new page.
+type userPager struct {
+	it        UserIterator
+	started   bool
+	pageToken string // token the current page was fetched with
+	pos       int    // index of the last user within that page
+}
+
+func (p *userPager) next() (*auth.ExportedUserRecord, error) {
+	before := p.it.PageInfo().Token
+	u, err := p.it.Next()
+	if err != nil {
+		return nil, err
+	}
+	if !p.started || p.it.PageInfo().Token != before {
+		p.started, p.pageToken, p.pos = true, before, 0
+	} else {
+		p.pos++
+	}
+	return u, nil
+}
+
+func (p *userPager) cursor() userCursor {
+	return userCursor{PageToken: p.pageToken, Skip: p.pos + 1}
+}
+
 // SearchUsers finds a user by exact email.
 // GET /api/v1/admin/users/search?email=X
 func (h *AdminHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
@@ -209,7 +321,7 @@ func (h *AdminHandler) SetRole(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate discipline exists
-	_, err := h.fs.Collection("disciplines").Doc(req.DisciplineID).Get(ctx)
+	_, err := h.store.Disciplines().Get(ctx, req.DisciplineID)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "discipline not found")
 		return
@@ -313,6 +425,8 @@ func (h *AdminHandler) RevokeRole(w http.ResponseWriter, r *http.Request) {
 }
 
 // ListAssets returns all assets for a discipline including inactive ones.
+// It accepts the filters of the asset list; status is an alias of
+// processingStatus.
 // GET /api/v1/admin/assets?disciplineId=X&status=Y
 func (h *AdminHandler) ListAssets(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -327,44 +441,102 @@ func (h *AdminHandler) ListAssets(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := h.fs.Collection("assets").
-		Where("disciplineId", "==", disciplineID).
-		OrderBy("createdAt", firestore.Desc)
+	filter, err := parseListFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if statusFilter := r.URL.Query().Get("status"); statusFilter != "" && !slices.Contains(filter.statuses, statusFilter) {
+		filter.statuses = append(filter.statuses, statusFilter)
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+
+	query, _ := filter.pushDown(store.NewQuery().
+		Where("disciplineId", "==", disciplineID), "techniqueIds", "categoryIds", "tagIds")
+	query = filter.pushDownCreated(query).OrderBy("createdAt", store.Desc)
 
-	iter := query.Documents(ctx)
-	defer iter.Stop()
+	keep := func(a *model.Asset) bool {
+		normalizeAsset(a)
+		return filter.matchAsset(a)
+	}
 
-	statusFilter := r.URL.Query().Get("status")
+	list := h.store.Assets().List
+	idOf := func(a *model.Asset) string { return a.ID }
+	assets, next, err := listPage(ctx, list, query, page, idOf, keep)
+	if err != nil {
+		writeListError(w, err, "assets")
+		return
+	}
+	setNextLink(w, r, next)
 
-	assets := []model.Asset{}
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
+	if wantFacets(r) {
+		facets, err := listFacets(ctx, list, query, page, assets, next, idOf, keep, assetFacets)
 		if err != nil {
-			slog.Error("failed to list admin assets", "error", err)
-			writeError(w, http.StatusInternalServerError, "failed to list assets")
+			writeListError(w, err, "assets")
 			return
 		}
+		writeJSON(w, http.StatusOK, model.FacetedList[model.Asset]{Items: assets, Facets: facets})
+		return
+	}
 
-		var a model.Asset
-		if err := doc.DataTo(&a); err != nil {
-			slog.Error("failed to parse asset", "docID", doc.Ref.ID, "error", err)
-			continue
-		}
-		a.ID = doc.Ref.ID
-		normalizeAsset(&a)
+	writeJSON(w, http.StatusOK, assets)
+}
 
-		// Apply status filter
-		if statusFilter != "" && a.ProcessingStatus != statusFilter {
-			continue
-		}
+// denormReport is the response of the curriculum denormalization endpoints.
+type denormReport struct {
+	Checked  int                `json:"checked"`
+	Drifted  []curriculum.Drift `json:"drifted"`
+	Failed   []string           `json:"failed"`
+	Repaired bool               `json:"repaired"`
+}
 
-		assets = append(assets, a)
+// CurriculumDenormReport lists the curricula of a discipline whose derived
+// fields (elementCount, totalDurationSeconds, allTagIds, searchText) no
+// longer match their elements.
+// GET /api/v1/admin/curricula/denorm?disciplineId=X
+func (h *AdminHandler) CurriculumDenormReport(w http.ResponseWriter, r *http.Request) {
+	h.reconcileCurricula(w, r, true)
+}
+
+// RepairCurriculumDenorm recomputes and stores the derived fields of every
+// drifted curriculum of a discipline, and reports what it fixed.
+// POST /api/v1/admin/curricula/denorm/repair?disciplineId=X
+func (h *AdminHandler) RepairCurriculumDenorm(w http.ResponseWriter, r *http.Request) {
+	h.reconcileCurricula(w, r, false)
+}
+
+func (h *AdminHandler) reconcileCurricula(w http.ResponseWriter, r *http.Request, dryRun bool) {
+	ctx := r.Context()
+	disciplineID := r.URL.Query().Get("disciplineId")
+	if disciplineID == "" {
+		writeError(w, http.StatusBadRequest, "disciplineId query parameter is required")
+		return
 	}
 
-	writeJSON(w, http.StatusOK, assets)
+	if err := middleware.RequireAdmin(ctx, disciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "admin role required for this discipline")
+		return
+	}
+
+	report := denormReport{Drifted: []curriculum.Drift{}, Failed: []string{}, Repaired: !dryRun}
+	checked, err := curriculum.ReconcileAll(ctx, h.store, disciplineID, dryRun,
+		func(d curriculum.Drift) { report.Drifted = append(report.Drifted, d) },
+		func(id string, err error) {
+			slog.Error("failed to reconcile curriculum", "curriculumID", id, "error", err)
+			report.Failed = append(report.Failed, id)
+		})
+	if err != nil {
+		slog.Error("failed to list curricula", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list curricula")
+		return
+	}
+	report.Checked = checked
+
+	writeJSON(w, http.StatusOK, report)
 }
 
 // ToggleAssetActive sets the active field on an asset.
@@ -373,10 +545,9 @@ func (h *AdminHandler) ToggleAssetActive(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
-	ref := h.fs.Collection("assets").Doc(id)
-	doc, err := ref.Get(ctx)
+	existing, err := h.store.Assets().Get(ctx, id)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "asset not found")
 			return
 		}
@@ -384,12 +555,6 @@ func (h *AdminHandler) ToggleAssetActive(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	var existing model.Asset
-	if err := doc.DataTo(&existing); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse asset")
-		return
-	}
-
 	if err := middleware.RequireAdmin(ctx, existing.DisciplineID); err != nil {
 		writeError(w, http.StatusForbidden, "admin role required for this discipline")
 		return
@@ -403,7 +568,7 @@ func (h *AdminHandler) ToggleAssetActive(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if _, err := ref.Update(ctx, []firestore.Update{
+	if err := h.store.Assets().Update(ctx, id, []store.Update{
 		{Path: "active", Value: req.Active},
 		{Path: "updatedAt", Value: time.Now()},
 	}); err != nil {
@@ -432,10 +597,9 @@ func (h *AdminHandler) RetryEnrichment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ref := h.fs.Collection("assets").Doc(id)
-	doc, err := ref.Get(ctx)
+	existing, err := h.store.Assets().Get(ctx, id)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "asset not found")
 			return
 		}
@@ -443,19 +607,13 @@ func (h *AdminHandler) RetryEnrichment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var existing model.Asset
-	if err := doc.DataTo(&existing); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse asset")
-		return
-	}
-
 	if err := middleware.RequireAdmin(ctx, existing.DisciplineID); err != nil {
 		writeError(w, http.StatusForbidden, "admin role required for this discipline")
 		return
 	}
 
 	// Reset status
-	if _, err := ref.Update(ctx, []firestore.Update{
+	if err := h.store.Assets().Update(ctx, id, []store.Update{
 		{Path: "processingStatus", Value: "pending"},
 		{Path: "processingError", Value: nil},
 		{Path: "updatedAt", Value: time.Now()},
@@ -480,10 +638,9 @@ func (h *AdminHandler) UpdateAssetStatus(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
-	ref := h.fs.Collection("assets").Doc(id)
-	doc, err := ref.Get(ctx)
+	existing, err := h.store.Assets().Get(ctx, id)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "asset not found")
 			return
 		}
@@ -491,12 +648,6 @@ func (h *AdminHandler) UpdateAssetStatus(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	var existing model.Asset
-	if err := doc.DataTo(&existing); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse asset")
-		return
-	}
-
 	if err := middleware.RequireAdmin(ctx, existing.DisciplineID); err != nil {
 		writeError(w, http.StatusForbidden, "admin role required for this discipline")
 		return
@@ -524,17 +675,17 @@ func (h *AdminHandler) UpdateAssetStatus(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	updates := []firestore.Update{
+	updates := []store.Update{
 		{Path: "processingStatus", Value: req.ProcessingStatus},
 		{Path: "updatedAt", Value: time.Now()},
 	}
 	if req.ProcessingError != nil {
-		updates = append(updates, firestore.Update{Path: "processingError", Value: *req.ProcessingError})
+		updates = append(updates, store.Update{Path: "processingError", Value: *req.ProcessingError})
 	} else {
-		updates = append(updates, firestore.Update{Path: "processingError", Value: nil})
+		updates = append(updates, store.Update{Path: "processingError", Value: nil})
 	}
 
-	if _, err := ref.Update(ctx, updates); err != nil {
+	if err := h.store.Assets().Update(ctx, id, updates); err != nil {
 		slog.Error("failed to update asset status", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to update asset status")
 		return
@@ -2,34 +2,33 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
-	"strconv"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/go-chi/chi/v5"
+	"github.com/thomas/skillhive-api/internal/duration"
 	"github.com/thomas/skillhive-api/internal/enrich"
 	"github.com/thomas/skillhive-api/internal/middleware"
 	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
 	"github.com/thomas/skillhive-api/internal/validate"
 	"github.com/thomas/skillhive-api/internal/youtube"
-	"google.golang.org/api/iterator"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type AssetHandler struct {
-	fs        *firestore.Client
-	pipeline  *enrich.Pipeline
-	enrichCtx context.Context
+	store        store.Store
+	pipeline     *enrich.Pipeline
+	enrichCtx    context.Context
+	deletePolicy model.DeletePolicy
 }
 
-func NewAssetHandler(fs *firestore.Client, pipeline *enrich.Pipeline, enrichCtx context.Context) *AssetHandler {
-	return &AssetHandler{fs: fs, pipeline: pipeline, enrichCtx: enrichCtx}
+func NewAssetHandler(s store.Store, pipeline *enrich.Pipeline, enrichCtx context.Context, deletePolicy model.DeletePolicy) *AssetHandler {
+	return &AssetHandler{store: s, pipeline: pipeline, enrichCtx: enrichCtx, deletePolicy: deletePolicy}
 }
 
-// normalizeAsset normalizes an asset read from Firestore for backward compatibility.
+// normalizeAsset normalizes an asset read from the store for backward compatibility.
 // Existing assets have no "active" field; Firestore returns false for missing booleans.
 // If processingStatus is empty (legacy asset) and active is false, normalize to true.
 func normalizeAsset(a *model.Asset) {
@@ -46,6 +45,21 @@ func normalizeAsset(a *model.Asset) {
 	if a.ProcessingStatus == "" && !a.Active {
 		a.Active = true
 	}
+	if seconds, ok := assetSeconds(a); ok {
+		a.DurationSeconds = &seconds
+	}
+}
+
+// assetSeconds returns the length of a, parsing its duration when it was
+// stored before durationSeconds.
+func assetSeconds(a *model.Asset) (int, bool) {
+	if a.DurationSeconds != nil {
+		return *a.DurationSeconds, true
+	}
+	if a.Duration == nil {
+		return 0, false
+	}
+	return duration.Parse(*a.Duration)
 }
 
 func (h *AssetHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -57,67 +71,48 @@ func (h *AssetHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := h.fs.Collection("assets").
-		Where("disciplineId", "==", disciplineID)
-
-	techniqueID := r.URL.Query().Get("techniqueId")
-	categoryID := r.URL.Query().Get("categoryId")
-	tagID := r.URL.Query().Get("tagId")
-
-	if techniqueID != "" {
-		query = query.Where("techniqueIds", "array-contains", techniqueID)
-	} else if categoryID != "" {
-		query = query.Where("categoryIds", "array-contains", categoryID)
-	} else if tagID != "" {
-		query = query.Where("tagIds", "array-contains", tagID)
+	filter, err := parseListFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
-
-	query = query.OrderBy("createdAt", firestore.Desc)
-
-	// Pagination (optional - no limit by default, returns all)
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
-			query = query.Limit(parsed)
-		}
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
 	}
-	if o := r.URL.Query().Get("offset"); o != "" {
-		if parsed, err := strconv.Atoi(o); err == nil && parsed > 0 {
-			query = query.Offset(parsed)
+
+	var query store.Query
+	switch sort := r.URL.Query().Get("sort"); sort {
+	case "", "-createdAt":
+		query, _ = filter.pushDown(store.NewQuery().
+			Where("disciplineId", "==", disciplineID), "techniqueIds", "categoryIds", "tagIds")
+		query = filter.pushDownCreated(query).OrderBy("createdAt", store.Desc)
+	case "duration", "-duration":
+		// Only the duration range goes to the store, which leaves out
+		// assets of unknown length as ordering by a missing field does.
+		query = filter.pushDownDuration(store.NewQuery().Where("disciplineId", "==", disciplineID))
+		dir := store.Asc
+		if sort == "-duration" {
+			dir = store.Desc
 		}
+		query = query.OrderBy("durationSeconds", dir)
+	default:
+		writeError(w, http.StatusBadRequest, "sort must be -createdAt, duration or -duration")
+		return
 	}
 
-	iter := query.Documents(ctx)
-	defer iter.Stop()
-
 	// Check if admin wants to include inactive assets
 	includeInactive := r.URL.Query().Get("includeInactive") == "true"
 	isAdmin := middleware.GetUserRole(ctx, disciplineID) == "admin"
-
-	assets := []model.Asset{}
 	searchQuery := r.URL.Query().Get("q")
 
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			slog.Error("failed to list assets", "error", err)
-			writeError(w, http.StatusInternalServerError, "failed to list assets")
-			return
-		}
-
-		var a model.Asset
-		if err := doc.DataTo(&a); err != nil {
-			slog.Error("failed to parse asset", "docID", doc.Ref.ID, "error", err)
-			continue
-		}
-		a.ID = doc.Ref.ID
-		normalizeAsset(&a)
+	keep := func(a *model.Asset) bool {
+		normalizeAsset(a)
 
 		// Filter out inactive assets for non-admin users
 		if !a.Active && !(isAdmin && includeInactive) {
-			continue
+			return false
 		}
 
 		// Client-side title search
@@ -125,11 +120,31 @@ func (h *AssetHandler) List(w http.ResponseWriter, r *http.Request) {
 			slug := validate.GenerateSlug(searchQuery)
 			titleSlug := validate.GenerateSlug(a.Title)
 			if len(slug) > 0 && (len(titleSlug) < len(slug) || titleSlug[:len(slug)] != slug) {
-				continue
+				return false
 			}
 		}
 
-		assets = append(assets, a)
+		return filter.matchAsset(a)
+	}
+
+	list := h.store.Assets().List
+	idOf := func(a *model.Asset) string { return a.ID }
+	assets, next, err := listPage(ctx, list, query, page, idOf, keep)
+	if err != nil {
+		writeListError(w, err, "assets")
+		return
+	}
+	setNextLink(w, r, next)
+
+	if wantFacets(r) {
+		// Facets count the whole filtered list, not just this page
+		facets, err := listFacets(ctx, list, query, page, assets, next, idOf, keep, assetFacets)
+		if err != nil {
+			writeListError(w, err, "assets")
+			return
+		}
+		writeJSON(w, http.StatusOK, model.FacetedList[model.Asset]{Items: assets, Facets: facets})
+		return
 	}
 
 	writeJSON(w, http.StatusOK, assets)
@@ -139,23 +154,16 @@ func (h *AssetHandler) Get(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
-	doc, err := h.fs.Collection("assets").Doc(id).Get(ctx)
+	a, err := h.store.Assets().Get(ctx, id)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "asset not found")
 			return
 		}
 		writeError(w, http.StatusInternalServerError, "failed to get asset")
 		return
 	}
-
-	var a model.Asset
-	if err := doc.DataTo(&a); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse asset")
-		return
-	}
-	a.ID = doc.Ref.ID
-	normalizeAsset(&a)
+	normalizeAsset(a)
 
 	// Non-admin users cannot see inactive assets
 	if !a.Active {
@@ -221,6 +229,24 @@ func (h *AssetHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var dur *string
+	var durationSeconds *int
+	if req.Duration != nil {
+		var err error
+		if dur, durationSeconds, err = parseDuration("duration", *req.Duration); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	refs := newRefCheck(disciplineID).
+		add("techniqueIds", refTechnique, req.TechniqueIDs...).
+		add("categoryIds", refCategory, req.CategoryIDs...).
+		add("tagIds", refTag, req.TagIDs...)
+	if _, ok := checkRefs(w, ctx, h.store, refs, "failed to create asset"); !ok {
+		return
+	}
+
 	if req.TechniqueIDs == nil {
 		req.TechniqueIDs = []string{}
 	}
@@ -245,47 +271,17 @@ func (h *AssetHandler) Create(w http.ResponseWriter, r *http.Request) {
 		processingStatus = "pending"
 	}
 
-	data := map[string]interface{}{
-		"disciplineId":     disciplineID,
-		"url":              req.URL,
-		"title":            title,
-		"description":      validate.StripAllHTML(req.Description),
-		"type":             req.Type,
-		"videoType":        req.VideoType,
-		"originator":       req.Originator,
-		"thumbnailUrl":     req.ThumbnailURL,
-		"techniqueIds":     req.TechniqueIDs,
-		"categoryIds":      req.CategoryIDs,
-		"tagIds":           req.TagIDs,
-		"ownerUid":         uid,
-		"active":           active,
-		"processingStatus": processingStatus,
-		"createdAt":        now,
-		"updatedAt":        now,
-	}
-
-	ref, _, err := h.fs.Collection("assets").Add(ctx, data)
-	if err != nil {
-		slog.Error("failed to create asset", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to create asset")
-		return
-	}
-
-	// Trigger async enrichment for YouTube URLs
-	if enrichmentEnabled {
-		go h.pipeline.EnrichAsset(h.enrichCtx, ref.ID, req.URL, disciplineID, uid)
-	}
-
 	a := model.Asset{
-		ID:               ref.ID,
 		DisciplineID:     disciplineID,
 		URL:              req.URL,
 		Title:            title,
-		Description:      data["description"].(string),
+		Description:      validate.StripAllHTML(req.Description),
 		Type:             model.AssetType(req.Type),
 		VideoType:        req.VideoType,
 		Originator:       req.Originator,
 		ThumbnailURL:     req.ThumbnailURL,
+		Duration:         dur,
+		DurationSeconds:  durationSeconds,
 		TechniqueIDs:     req.TechniqueIDs,
 		CategoryIDs:      req.CategoryIDs,
 		TagIDs:           req.TagIDs,
@@ -296,6 +292,17 @@ func (h *AssetHandler) Create(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:        now,
 	}
 
+	if _, err := h.store.Assets().Create(ctx, &a); err != nil {
+		slog.Error("failed to create asset", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to create asset")
+		return
+	}
+
+	// Trigger async enrichment for YouTube URLs
+	if enrichmentEnabled {
+		go h.pipeline.EnrichAsset(h.enrichCtx, a.ID, req.URL, disciplineID, uid)
+	}
+
 	writeJSON(w, http.StatusCreated, a)
 }
 
@@ -303,10 +310,9 @@ func (h *AssetHandler) Update(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
-	ref := h.fs.Collection("assets").Doc(id)
-	doc, err := ref.Get(ctx)
+	existing, err := h.store.Assets().Get(ctx, id)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "asset not found")
 			return
 		}
@@ -314,12 +320,6 @@ func (h *AssetHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var existing model.Asset
-	if err := doc.DataTo(&existing); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse asset")
-		return
-	}
-
 	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
 		writeError(w, http.StatusForbidden, "editor role required")
 		return
@@ -331,12 +331,20 @@ func (h *AssetHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updates := []firestore.Update{
+	refs := newRefCheck(existing.DisciplineID).
+		add("techniqueIds", refTechnique, req.TechniqueIDs...).
+		add("categoryIds", refCategory, req.CategoryIDs...).
+		add("tagIds", refTag, req.TagIDs...)
+	if _, ok := checkRefs(w, ctx, h.store, refs, "failed to update asset"); !ok {
+		return
+	}
+
+	updates := []store.Update{
 		{Path: "updatedAt", Value: time.Now()},
 	}
 
 	if req.URL != nil {
-		updates = append(updates, firestore.Update{Path: "url", Value: *req.URL})
+		updates = append(updates, store.Update{Path: "url", Value: *req.URL})
 	}
 	if req.Title != nil {
 		title := validate.StripAllHTML(*req.Title)
@@ -344,56 +352,59 @@ func (h *AssetHandler) Update(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		updates = append(updates, firestore.Update{Path: "title", Value: title})
+		updates = append(updates, store.Update{Path: "title", Value: title})
 	}
 	if req.Description != nil {
-		updates = append(updates, firestore.Update{Path: "description", Value: validate.StripAllHTML(*req.Description)})
+		updates = append(updates, store.Update{Path: "description", Value: validate.StripAllHTML(*req.Description)})
 	}
 	if req.Type != nil {
 		if err := validate.EnumWhitelist("type", *req.Type, []string{"video", "web", "image"}); err != nil {
 			writeError(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		updates = append(updates, firestore.Update{Path: "type", Value: *req.Type})
+		updates = append(updates, store.Update{Path: "type", Value: *req.Type})
 	}
 	if req.VideoType != nil {
-		updates = append(updates, firestore.Update{Path: "videoType", Value: req.VideoType})
+		updates = append(updates, store.Update{Path: "videoType", Value: req.VideoType})
 	}
 	if req.Originator != nil {
-		updates = append(updates, firestore.Update{Path: "originator", Value: req.Originator})
+		updates = append(updates, store.Update{Path: "originator", Value: req.Originator})
 	}
 	if req.ThumbnailURL != nil {
-		updates = append(updates, firestore.Update{Path: "thumbnailUrl", Value: req.ThumbnailURL})
+		updates = append(updates, store.Update{Path: "thumbnailUrl", Value: req.ThumbnailURL})
+	}
+	if req.Duration != nil {
+		text, seconds, err := parseDuration("duration", *req.Duration)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		updates = append(updates,
+			store.Update{Path: "duration", Value: text},
+			store.Update{Path: "durationSeconds", Value: seconds})
 	}
 	if req.TechniqueIDs != nil {
-		updates = append(updates, firestore.Update{Path: "techniqueIds", Value: req.TechniqueIDs})
+		updates = append(updates, store.Update{Path: "techniqueIds", Value: req.TechniqueIDs})
 	}
 	if req.CategoryIDs != nil {
-		updates = append(updates, firestore.Update{Path: "categoryIds", Value: req.CategoryIDs})
+		updates = append(updates, store.Update{Path: "categoryIds", Value: req.CategoryIDs})
 	}
 	if req.TagIDs != nil {
-		updates = append(updates, firestore.Update{Path: "tagIds", Value: req.TagIDs})
+		updates = append(updates, store.Update{Path: "tagIds", Value: req.TagIDs})
 	}
 
-	if _, err := ref.Update(ctx, updates); err != nil {
+	if err := h.store.Assets().Update(ctx, id, updates); err != nil {
 		slog.Error("failed to update asset", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to update asset")
 		return
 	}
 
-	updatedDoc, err := ref.Get(ctx)
+	updated, err := h.store.Assets().Get(ctx, id)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to get updated asset")
 		return
 	}
-
-	var updated model.Asset
-	if err := updatedDoc.DataTo(&updated); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse updated asset")
-		return
-	}
-	updated.ID = id
-	normalizeAsset(&updated)
+	normalizeAsset(updated)
 
 	writeJSON(w, http.StatusOK, updated)
 }
@@ -402,10 +413,9 @@ func (h *AssetHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
-	ref := h.fs.Collection("assets").Doc(id)
-	doc, err := ref.Get(ctx)
+	existing, err := h.store.Assets().Get(ctx, id)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "asset not found")
 			return
 		}
@@ -413,18 +423,16 @@ func (h *AssetHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var existing model.Asset
-	if err := doc.DataTo(&existing); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse asset")
+	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
 		return
 	}
 
-	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
-		writeError(w, http.StatusForbidden, "editor role required")
+	if !deleteReferences(w, r, h.store, referent{kind: refAsset, id: id, disciplineID: existing.DisciplineID}, h.deletePolicy) {
 		return
 	}
 
-	if _, err := ref.Delete(ctx); err != nil {
+	if err := h.store.Assets().Delete(ctx, id); err != nil {
 		slog.Error("failed to delete asset", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to delete asset")
 		return
@@ -432,3 +440,27 @@ func (h *AssetHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Usages lists the edges and curriculum elements that refer to the asset.
+// GET /api/v1/assets/{id}/usages
+func (h *AssetHandler) Usages(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	existing, err := h.store.Assets().Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "asset not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to get asset")
+		return
+	}
+
+	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
+		return
+	}
+
+	writeUsages(w, ctx, h.store, referent{kind: refAsset, id: id, disciplineID: existing.DisciplineID})
+}
@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/thomas/skillhive-api/internal/middleware"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/schedule"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+// feedMaxAge is how long calendar apps and shared caches may reuse a feed.
+// Apps poll feeds every few hours anyway.
+const feedMaxAge = 5 * time.Minute
+
+type CalendarHandler struct {
+	store store.Store
+}
+
+func NewCalendarHandler(s store.Store) *CalendarHandler {
+	return &CalendarHandler{store: s}
+}
+
+func feedPath(f *model.CalendarFeed) {
+	f.Path = "/calendar/" + f.ID + ".ics"
+}
+
+// ListFeeds lists the user's calendar feeds, newest first.
+// GET /api/v1/calendar/feeds
+func (h *CalendarHandler) ListFeeds(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	feeds, err := h.store.CalendarFeeds().List(ctx, store.NewQuery().
+		Where("ownerUid", "==", middleware.GetUserUID(ctx)).
+		OrderBy("createdAt", store.Desc))
+	if err != nil {
+		slog.Error("failed to list calendar feeds", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list calendar feeds")
+		return
+	}
+	for i := range feeds {
+		feedPath(&feeds[i])
+	}
+
+	writeJSON(w, http.StatusOK, feeds)
+}
+
+// CreateFeed creates a calendar feed of one discipline, or with no body the
+// user's own feed.
+// POST /api/v1/calendar/feeds
+func (h *CalendarHandler) CreateFeed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req model.CreateCalendarFeedRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if req.DisciplineID != "" {
+		if _, err := h.store.Disciplines().Get(ctx, req.DisciplineID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				writeError(w, http.StatusBadRequest, "discipline not found")
+				return
+			}
+			slog.Error("failed to get discipline", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to create calendar feed")
+			return
+		}
+	}
+
+	f := model.CalendarFeed{
+		ID:           rand.Text(),
+		OwnerUID:     middleware.GetUserUID(ctx),
+		DisciplineID: req.DisciplineID,
+		CreatedAt:    time.Now(),
+	}
+	if err := h.store.CalendarFeeds().Set(ctx, f.ID, &f); err != nil {
+		slog.Error("failed to create calendar feed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to create calendar feed")
+		return
+	}
+	feedPath(&f)
+
+	writeJSON(w, http.StatusCreated, f)
+}
+
+// DeleteFeed revokes one of the user's calendar feeds.
+// DELETE /api/v1/calendar/feeds/{id}
+func (h *CalendarHandler) DeleteFeed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	f, err := h.store.CalendarFeeds().Get(ctx, chi.URLParam(r, "id"))
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		slog.Error("failed to get calendar feed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete calendar feed")
+		return
+	}
+	if err != nil || f.OwnerUID != middleware.GetUserUID(ctx) {
+		writeError(w, http.StatusNotFound, "calendar feed not found")
+		return
+	}
+
+	if err := h.store.CalendarFeeds().Delete(ctx, f.ID); err != nil {
+		slog.Error("failed to delete calendar feed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete calendar feed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Feed serves a calendar feed to anyone holding its token: the sessions it
+// covers whose curriculum the feed's owner may view, as iCalendar events.
+// GET /calendar/{token}.ics
+func (h *CalendarHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	f, err := h.store.CalendarFeeds().Get(ctx, chi.URLParam(r, "token"))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "calendar feed not found")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to get calendar feed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get calendar feed")
+		return
+	}
+
+	name := "SkillHive"
+	var sessions []model.Session
+	if f.DisciplineID != "" {
+		d, err := h.store.Disciplines().Get(ctx, f.DisciplineID)
+		if err == nil {
+			name += " – " + d.Name
+		} else if !errors.Is(err, store.ErrNotFound) {
+			slog.Error("failed to get discipline", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to get calendar feed")
+			return
+		}
+		sessions, err = h.store.Sessions().List(ctx, store.NewQuery().
+			Where("disciplineId", "==", f.DisciplineID).
+			OrderBy("startsAt", store.Asc))
+	} else {
+		sessions, err = h.personalSessions(ctx, f.OwnerUID)
+	}
+	if err != nil {
+		slog.Error("failed to list feed sessions", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get calendar feed")
+		return
+	}
+
+	views := newCurriculumViews(h.store, f.OwnerUID)
+	var events []schedule.Event
+	for i := range sessions {
+		s := &sessions[i]
+		c, err := views.get(ctx, s.CurriculumID)
+		if err != nil {
+			slog.Error("failed to get session curriculum", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to get calendar feed")
+			return
+		}
+		loc, err := time.LoadLocation(s.TimeZone)
+		if c == nil || err != nil {
+			continue
+		}
+		events = append(events, schedule.Event{
+			UID:         s.ID + "@skillhive",
+			Start:       s.StartsAt.In(loc),
+			Duration:    time.Duration(s.DurationSeconds) * time.Second,
+			Rule:        s.Recurrence,
+			Summary:     sessionTitle(s, c),
+			Location:    s.Location,
+			Description: c.Description,
+			Stamp:       s.UpdatedAt,
+		})
+	}
+
+	var body bytes.Buffer
+	if err := schedule.WriteCalendar(&body, name, events); err != nil {
+		slog.Error("failed to write calendar feed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get calendar feed")
+		return
+	}
+
+	writeCached(w, r, "text/calendar; charset=utf-8", body.Bytes(), feedMaxAge)
+}
+
+// personalSessions returns the sessions a user scheduled and those of the
+// curricula they own or collaborate on, earliest first.
+func (h *CalendarHandler) personalSessions(ctx context.Context, uid string) ([]model.Session, error) {
+	var curriculumIDs []string
+	for _, q := range []store.Query{
+		store.NewQuery().Where("ownerUid", "==", uid),
+		store.NewQuery().Where("editorUids", "array-contains", uid),
+		store.NewQuery().Where("viewerUids", "array-contains", uid),
+	} {
+		curricula, err := h.store.Curricula().List(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range curricula {
+			curriculumIDs = append(curriculumIDs, c.ID)
+		}
+	}
+
+	sessions, err := h.store.Sessions().List(ctx, store.NewQuery().Where("ownerUid", "==", uid))
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range curriculumIDs {
+		more, err := h.store.Sessions().List(ctx, store.NewQuery().Where("curriculumId", "==", id))
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, more...)
+	}
+
+	slices.SortFunc(sessions, func(a, b model.Session) int {
+		if c := a.StartsAt.Compare(b.StartsAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return slices.CompactFunc(sessions, func(a, b model.Session) bool { return a.ID == b.ID }), nil
+}
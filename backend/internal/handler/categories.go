@@ -2,26 +2,25 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/go-chi/chi/v5"
 	"github.com/thomas/skillhive-api/internal/middleware"
 	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
 	"github.com/thomas/skillhive-api/internal/validate"
-	"google.golang.org/api/iterator"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type CategoryHandler struct {
-	fs *firestore.Client
+	store        store.Store
+	deletePolicy model.DeletePolicy
 }
 
-func NewCategoryHandler(fs *firestore.Client) *CategoryHandler {
-	return &CategoryHandler{fs: fs}
+func NewCategoryHandler(s store.Store, deletePolicy model.DeletePolicy) *CategoryHandler {
+	return &CategoryHandler{store: s, deletePolicy: deletePolicy}
 }
 
 func (h *CategoryHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -34,32 +33,25 @@ func (h *CategoryHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := h.fs.Collection("categories").
-		Where("disciplineId", "==", disciplineID).
-		OrderBy("name", firestore.Asc)
-
-	iter := query.Documents(ctx)
-	defer iter.Stop()
-
-	categories := []model.Category{}
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			slog.Error("failed to list categories", "error", err)
-			writeError(w, http.StatusInternalServerError, "failed to list categories")
+	// The tree needs every category, so it is never paginated
+	var page pageRequest
+	if !asTree {
+		var err error
+		if page, err = parsePage(r); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid cursor")
 			return
 		}
+	}
 
-		var c model.Category
-		if err := doc.DataTo(&c); err != nil {
-			slog.Error("failed to parse category", "docID", doc.Ref.ID, "error", err)
-			continue
-		}
-		c.ID = doc.Ref.ID
-		categories = append(categories, c)
+	query := store.NewQuery().
+		Where("disciplineId", "==", disciplineID).
+		OrderBy("name", store.Asc)
+
+	categories, next, err := listPage(ctx, h.store.Categories().List, query, page,
+		func(c *model.Category) string { return c.ID }, nil)
+	if err != nil {
+		writeListError(w, err, "categories")
+		return
 	}
 
 	if asTree {
@@ -68,6 +60,7 @@ func (h *CategoryHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setNextLink(w, r, next)
 	writeJSON(w, http.StatusOK, categories)
 }
 
@@ -103,9 +96,9 @@ func (h *CategoryHandler) Get(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
-	doc, err := h.fs.Collection("categories").Doc(id).Get(ctx)
+	c, err := h.store.Categories().Get(ctx, id)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "category not found")
 			return
 		}
@@ -113,13 +106,6 @@ func (h *CategoryHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var c model.Category
-	if err := doc.DataTo(&c); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse category")
-		return
-	}
-	c.ID = doc.Ref.ID
-
 	writeJSON(w, http.StatusOK, c)
 }
 
@@ -157,61 +143,45 @@ func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	// Validate parent exists and belongs to same discipline
 	if req.ParentID != nil && *req.ParentID != "" {
-		parentDoc, err := h.fs.Collection("categories").Doc(*req.ParentID).Get(ctx)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "parent category not found")
-			return
-		}
-		parentDiscipline, _ := parentDoc.DataAt("disciplineId")
-		if parentDiscipline != disciplineID {
-			writeError(w, http.StatusBadRequest, "parent category must belong to the same discipline")
+		refs := newRefCheck(disciplineID).add("parentId", refCategory, *req.ParentID)
+		if _, ok := checkRefs(w, ctx, h.store, refs, "failed to create category"); !ok {
 			return
 		}
 	}
 
 	// Check slug uniqueness
-	existing := h.fs.Collection("categories").
+	existing, err := h.store.Categories().List(ctx, store.NewQuery().
 		Where("disciplineId", "==", disciplineID).
 		Where("slug", "==", slug).
-		Limit(1).
-		Documents(ctx)
-	defer existing.Stop()
-	if doc, err := existing.Next(); err == nil && doc != nil {
-		writeError(w, http.StatusConflict, "a category with this name already exists in this discipline")
-		return
-	}
-
-	now := time.Now()
-	data := map[string]interface{}{
-		"disciplineId": disciplineID,
-		"name":         validate.StripAllHTML(req.Name),
-		"slug":         slug,
-		"description":  validate.StripAllHTML(req.Description),
-		"parentId":     req.ParentID,
-		"ownerUid":     uid,
-		"createdAt":    now,
-		"updatedAt":    now,
-	}
-
-	ref, _, err := h.fs.Collection("categories").Add(ctx, data)
+		Limit(1))
 	if err != nil {
-		slog.Error("failed to create category", "error", err)
+		slog.Error("failed to check category slug", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to create category")
 		return
 	}
+	if len(existing) > 0 {
+		writeError(w, http.StatusConflict, "a category with this name already exists in this discipline")
+		return
+	}
 
+	now := time.Now()
 	c := model.Category{
-		ID:           ref.ID,
 		DisciplineID: disciplineID,
-		Name:         data["name"].(string),
+		Name:         validate.StripAllHTML(req.Name),
 		Slug:         slug,
-		Description:  data["description"].(string),
+		Description:  validate.StripAllHTML(req.Description),
 		ParentID:     req.ParentID,
 		OwnerUID:     uid,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
 
+	if _, err := h.store.Categories().Create(ctx, &c); err != nil {
+		slog.Error("failed to create category", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to create category")
+		return
+	}
+
 	writeJSON(w, http.StatusCreated, c)
 }
 
@@ -220,10 +190,9 @@ func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
 	uid := middleware.GetUserUID(ctx)
 	id := chi.URLParam(r, "id")
 
-	ref := h.fs.Collection("categories").Doc(id)
-	doc, err := ref.Get(ctx)
+	existing, err := h.store.Categories().Get(ctx, id)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "category not found")
 			return
 		}
@@ -231,32 +200,24 @@ func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var existing model.Category
-	if err := doc.DataTo(&existing); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse category")
-		return
-	}
-
 	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
 		writeError(w, http.StatusForbidden, "editor role required")
 		return
 	}
 
-	_ = uid // kept for ownership transfer below
-
 	var req model.UpdateCategoryRequest
 	if err := decodeJSON(r, &req); err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	updates := []firestore.Update{
+	updates := []store.Update{
 		{Path: "updatedAt", Value: time.Now()},
 	}
 
 	// Transfer ownership from system to user on edit
 	if existing.OwnerUID == "system" {
-		updates = append(updates, firestore.Update{Path: "ownerUid", Value: uid})
+		updates = append(updates, store.Update{Path: "ownerUid", Value: uid})
 	}
 
 	if req.Name != nil {
@@ -266,12 +227,12 @@ func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		updates = append(updates,
-			firestore.Update{Path: "name", Value: name},
-			firestore.Update{Path: "slug", Value: validate.GenerateSlug(name)},
+			store.Update{Path: "name", Value: name},
+			store.Update{Path: "slug", Value: validate.GenerateSlug(name)},
 		)
 	}
 	if req.Description != nil {
-		updates = append(updates, firestore.Update{Path: "description", Value: validate.StripAllHTML(*req.Description)})
+		updates = append(updates, store.Update{Path: "description", Value: validate.StripAllHTML(*req.Description)})
 	}
 	if req.ParentID != nil {
 		// Prevent self-reference
@@ -279,39 +240,37 @@ func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusBadRequest, "category cannot be its own parent")
 			return
 		}
-		// Prevent circular reference
+		// The parent must exist in the same discipline and not be below
+		// the category.
 		if *req.ParentID != "" {
-			if isCircular(ctx, h.fs, id, *req.ParentID) {
+			refs := newRefCheck(existing.DisciplineID).add("parentId", refCategory, *req.ParentID)
+			if _, ok := checkRefs(w, ctx, h.store, refs, "failed to update category"); !ok {
+				return
+			}
+			if isCircular(ctx, h.store, id, *req.ParentID) {
 				writeError(w, http.StatusBadRequest, "circular category reference detected")
 				return
 			}
 		}
-		updates = append(updates, firestore.Update{Path: "parentId", Value: req.ParentID})
+		updates = append(updates, store.Update{Path: "parentId", Value: req.ParentID})
 	}
 
-	if _, err := ref.Update(ctx, updates); err != nil {
+	if err := h.store.Categories().Update(ctx, id, updates); err != nil {
 		slog.Error("failed to update category", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to update category")
 		return
 	}
 
-	updatedDoc, err := ref.Get(ctx)
+	updated, err := h.store.Categories().Get(ctx, id)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to get updated category")
 		return
 	}
 
-	var updated model.Category
-	if err := updatedDoc.DataTo(&updated); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse updated category")
-		return
-	}
-	updated.ID = id
-
 	writeJSON(w, http.StatusOK, updated)
 }
 
-func isCircular(ctx context.Context, fs *firestore.Client, targetID, parentID string) bool {
+func isCircular(ctx context.Context, s store.Store, targetID, parentID string) bool {
 	// Walk up the parent chain; if we find targetID, it's circular
 	visited := map[string]bool{targetID: true}
 	current := parentID
@@ -324,19 +283,14 @@ func isCircular(ctx context.Context, fs *firestore.Client, targetID, parentID st
 		}
 		visited[current] = true
 
-		doc, err := fs.Collection("categories").Doc(current).Get(ctx)
+		c, err := s.Categories().Get(ctx, current)
 		if err != nil {
 			return false
 		}
-		pid, _ := doc.DataAt("parentId")
-		if pid == nil {
-			return false
-		}
-		pidStr, ok := pid.(string)
-		if !ok || pidStr == "" {
+		if c.ParentID == nil || *c.ParentID == "" {
 			return false
 		}
-		current = pidStr
+		current = *c.ParentID
 	}
 	return true
 }
@@ -345,10 +299,9 @@ func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
-	ref := h.fs.Collection("categories").Doc(id)
-	doc, err := ref.Get(ctx)
+	existing, err := h.store.Categories().Get(ctx, id)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "category not found")
 			return
 		}
@@ -356,42 +309,16 @@ func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var existing model.Category
-	if err := doc.DataTo(&existing); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse category")
-		return
-	}
-
 	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
 		writeError(w, http.StatusForbidden, "editor role required")
 		return
 	}
 
-	// Reassign children to grandparent (or null)
-	children := h.fs.Collection("categories").
-		Where("parentId", "==", id).
-		Documents(ctx)
-	defer children.Stop()
-
-	batch := h.fs.Batch()
-	for {
-		childDoc, err := children.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			slog.Error("failed to query children", "error", err)
-			break
-		}
-		batch.Update(childDoc.Ref, []firestore.Update{
-			{Path: "parentId", Value: existing.ParentID},
-			{Path: "updatedAt", Value: time.Now()},
-		})
+	if !deleteReferences(w, r, h.store, referent{kind: refCategory, id: id, disciplineID: existing.DisciplineID, parentID: existing.ParentID}, h.deletePolicy) {
+		return
 	}
 
-	batch.Delete(ref)
-
-	if _, err := batch.Commit(ctx); err != nil {
+	if err := h.store.Categories().Delete(ctx, id); err != nil {
 		slog.Error("failed to delete category", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to delete category")
 		return
@@ -399,3 +326,28 @@ func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Usages lists the techniques and assets in the category, and its
+// subcategories.
+// GET /api/v1/categories/{id}/usages
+func (h *CategoryHandler) Usages(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	existing, err := h.store.Categories().Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "category not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to get category")
+		return
+	}
+
+	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
+		return
+	}
+
+	writeUsages(w, ctx, h.store, referent{kind: refCategory, id: id, disciplineID: existing.DisciplineID, parentID: existing.ParentID})
+}
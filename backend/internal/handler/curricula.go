@@ -1,27 +1,29 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/go-chi/chi/v5"
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/duration"
 	"github.com/thomas/skillhive-api/internal/middleware"
 	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
 	"github.com/thomas/skillhive-api/internal/validate"
-	"google.golang.org/api/iterator"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type CurriculumHandler struct {
-	fs *firestore.Client
+	store store.Store
 }
 
-func NewCurriculumHandler(fs *firestore.Client) *CurriculumHandler {
-	return &CurriculumHandler{fs: fs}
+func NewCurriculumHandler(s store.Store) *CurriculumHandler {
+	return &CurriculumHandler{store: s}
 }
 
 func normalizeCurriculum(c *model.Curriculum) {
@@ -31,84 +33,83 @@ func normalizeCurriculum(c *model.Curriculum) {
 	if c.AllTagIDs == nil {
 		c.AllTagIDs = []string{}
 	}
+	c.TotalDuration = duration.Format(c.TotalDurationSeconds)
 }
 
+// requireCurriculum loads the curriculum named in the URL and checks that
+// the user has at least the given access to it, writing the error response
+// when either fails. A private curriculum the user may not view is reported
+// as not found.
+func requireCurriculum(w http.ResponseWriter, r *http.Request, s store.Store, need curriculum.Access) (*model.Curriculum, bool) {
+	c, err := s.Curricula().Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "curriculum not found")
+		} else {
+			writeError(w, http.StatusInternalServerError, "failed to get curriculum")
+		}
+		return nil, false
+	}
+	switch access := curriculum.AccessOf(c, middleware.GetUserUID(r.Context())); {
+	case access == curriculum.AccessNone:
+		writeError(w, http.StatusNotFound, "curriculum not found")
+		return nil, false
+	case access < need && need == curriculum.AccessOwner:
+		writeError(w, http.StatusForbidden, "only the curriculum owner can do this")
+		return nil, false
+	case access < need:
+		writeError(w, http.StatusForbidden, "curriculum edit access required")
+		return nil, false
+	}
+	return c, true
+}
+
+// List lists the curricula the user may view: the public ones and the
+// private ones they own or collaborate on.
 func (h *CurriculumHandler) List(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	disciplineID := r.URL.Query().Get("disciplineId")
 	searchQuery := r.URL.Query().Get("q")
 	tagID := r.URL.Query().Get("tagId")
 
-	query := h.fs.Collection("curricula").OrderBy("updatedAt", firestore.Desc)
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+
+	query := store.NewQuery()
 	if disciplineID != "" {
-		query = h.fs.Collection("curricula").
-			Where("disciplineId", "==", disciplineID).
-			OrderBy("updatedAt", firestore.Desc)
+		query = query.Where("disciplineId", "==", disciplineID)
 	}
 
 	// Add tag filter if provided (uses allTagIds for own + inherited tags)
 	if tagID != "" {
-		if disciplineID != "" {
-			query = h.fs.Collection("curricula").
-				Where("disciplineId", "==", disciplineID).
-				Where("allTagIds", "array-contains", tagID).
-				OrderBy("updatedAt", firestore.Desc)
-		} else {
-			query = h.fs.Collection("curricula").
-				Where("allTagIds", "array-contains", tagID).
-				OrderBy("updatedAt", firestore.Desc)
-		}
+		query = query.Where("allTagIds", "array-contains", tagID)
 	}
+	query = query.OrderBy("updatedAt", store.Desc)
 
-	iter := query.Documents(ctx)
-	defer iter.Stop()
-
-	curricula := []model.Curriculum{}
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			slog.Error("failed to list curricula", "error", err)
-			writeError(w, http.StatusInternalServerError, "failed to list curricula")
-			return
-		}
-
-		var c model.Curriculum
-		if err := doc.DataTo(&c); err != nil {
-			slog.Error("failed to parse curriculum", "docID", doc.Ref.ID, "error", err)
-			continue
+	uid := middleware.GetUserUID(ctx)
+	searchSlug := strings.ToLower(searchQuery)
+	keep := func(c *model.Curriculum) bool {
+		if curriculum.AccessOf(c, uid) == curriculum.AccessNone {
+			return false
 		}
-		c.ID = doc.Ref.ID
-		normalizeCurriculum(&c)
-
 		// Server-side text search on denormalized searchText
-		if searchQuery != "" {
-			searchSlug := strings.ToLower(searchQuery)
-			if !strings.Contains(c.SearchText, searchSlug) {
-				continue
-			}
-		}
+		return strings.Contains(c.SearchText, searchSlug)
+	}
 
-		// Count elements
-		elemIter := doc.Ref.Collection("elements").Documents(ctx)
-		count := 0
-		for {
-			_, err := elemIter.Next()
-			if err == iterator.Done {
-				break
-			}
-			if err != nil {
-				break
-			}
-			count++
-		}
-		elemIter.Stop()
-		c.ElementCount = count
+	curricula, next, err := listPage(ctx, h.store.Curricula().List, query, page,
+		func(c *model.Curriculum) string { return c.ID }, keep)
+	if err != nil {
+		writeListError(w, err, "curricula")
+		return
+	}
 
-		curricula = append(curricula, c)
+	for i := range curricula {
+		normalizeCurriculum(&curricula[i])
 	}
+	setNextLink(w, r, next)
 
 	writeJSON(w, http.StatusOK, curricula)
 }
@@ -119,81 +120,59 @@ func (h *CurriculumHandler) ListPublic(w http.ResponseWriter, r *http.Request) {
 	tagID := r.URL.Query().Get("tagId")
 	disciplineID := r.URL.Query().Get("disciplineId")
 
-	query := h.fs.Collection("curricula").
-		Where("isPublic", "==", true).
-		OrderBy("updatedAt", firestore.Desc)
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+
+	query := store.NewQuery().Where("isPublic", "==", true)
 
 	// Add tag filter if provided
 	if tagID != "" {
-		query = h.fs.Collection("curricula").
-			Where("isPublic", "==", true).
-			Where("allTagIds", "array-contains", tagID).
-			OrderBy("updatedAt", firestore.Desc)
+		query = query.Where("allTagIds", "array-contains", tagID)
 	}
+	query = query.OrderBy("updatedAt", store.Desc)
 
-	iter := query.Documents(ctx)
-	defer iter.Stop()
-
-	curricula := []model.Curriculum{}
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			slog.Error("failed to list public curricula", "error", err)
-			writeError(w, http.StatusInternalServerError, "failed to list public curricula")
-			return
-		}
-
-		var c model.Curriculum
-		if err := doc.DataTo(&c); err != nil {
-			slog.Error("failed to parse curriculum", "docID", doc.Ref.ID, "error", err)
-			continue
-		}
-		c.ID = doc.Ref.ID
-		normalizeCurriculum(&c)
-
-		// Post-filter by discipline (cannot combine all filters in Firestore)
-		if disciplineID != "" && c.DisciplineID != disciplineID {
-			continue
-		}
+	var keep func(*model.Curriculum) bool
+	if disciplineID != "" || searchQuery != "" {
+		keep = func(c *model.Curriculum) bool {
+			// Post-filter by discipline (cannot combine all filters in Firestore)
+			if disciplineID != "" && c.DisciplineID != disciplineID {
+				return false
+			}
 
-		// Server-side text search on denormalized searchText
-		if searchQuery != "" {
-			searchSlug := strings.ToLower(searchQuery)
-			if !strings.Contains(c.SearchText, searchSlug) {
-				continue
+			// Server-side text search on denormalized searchText
+			if searchQuery != "" {
+				searchSlug := strings.ToLower(searchQuery)
+				if !strings.Contains(c.SearchText, searchSlug) {
+					return false
+				}
 			}
+			return true
 		}
+	}
 
-		curricula = append(curricula, c)
+	curricula, next, err := listPage(ctx, h.store.Curricula().List, query, page,
+		func(c *model.Curriculum) string { return c.ID }, keep)
+	if err != nil {
+		writeListError(w, err, "public curricula")
+		return
+	}
+	for i := range curricula {
+		normalizeCurriculum(&curricula[i])
 	}
+	setNextLink(w, r, next)
 
 	writeJSON(w, http.StatusOK, curricula)
 }
 
 func (h *CurriculumHandler) Get(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	id := chi.URLParam(r, "id")
-
-	doc, err := h.fs.Collection("curricula").Doc(id).Get(ctx)
-	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			writeError(w, http.StatusNotFound, "curriculum not found")
-			return
-		}
-		writeError(w, http.StatusInternalServerError, "failed to get curriculum")
+	c, ok := requireCurriculum(w, r, h.store, curriculum.AccessView)
+	if !ok {
 		return
 	}
-
-	var c model.Curriculum
-	if err := doc.DataTo(&c); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse curriculum")
-		return
-	}
-	c.ID = doc.Ref.ID
-	normalizeCurriculum(&c)
+	normalizeCurriculum(c)
 
 	writeJSON(w, http.StatusOK, c)
 }
@@ -228,6 +207,20 @@ func (h *CurriculumHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var dur *string
+	if req.Duration != nil {
+		var err error
+		if dur, _, err = parseDuration("duration", *req.Duration); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	refs := newRefCheck(disciplineID).add("tagIds", refTag, req.TagIDs...)
+	if _, ok := checkRefs(w, ctx, h.store, refs, "failed to create curriculum"); !ok {
+		return
+	}
+
 	// Normalize tagIds: nil → empty slice
 	if req.TagIDs == nil {
 		req.TagIDs = []string{}
@@ -236,81 +229,59 @@ func (h *CurriculumHandler) Create(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
 	title := validate.StripAllHTML(req.Title)
 	description := validate.StripAllHTML(req.Description)
-	// Inline denorm: no elements exist yet, so allTagIds = own tagIds
-	// and searchText = lowered title + description. Must stay in sync
-	// with recomputeCurriculumDenorm.
-	data := map[string]interface{}{
-		"disciplineId": disciplineID,
-		"title":        title,
-		"description":  description,
-		"isPublic":     req.IsPublic,
-		"ownerUid":     uid,
-		"tagIds":       req.TagIDs,
-		"allTagIds":    req.TagIDs,
-		"searchText":   strings.ToLower(title + " " + description),
-		"createdAt":    now,
-		"updatedAt":    now,
-	}
-	if req.Duration != nil {
-		data["duration"] = validate.StripAllHTML(*req.Duration)
-	}
-
-	ref, _, err := h.fs.Collection("curricula").Add(ctx, data)
-	if err != nil {
-		slog.Error("failed to create curriculum", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to create curriculum")
-		return
-	}
-
 	c := model.Curriculum{
-		ID:           ref.ID,
 		DisciplineID: disciplineID,
 		Title:        title,
 		Description:  description,
+		Duration:     dur,
 		IsPublic:     req.IsPublic,
 		OwnerUID:     uid,
 		TagIDs:       req.TagIDs,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
+	// The curriculum starts its history with revision 1.
+	c.ID = store.NewID()
+	err := h.store.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		return curriculum.Create(h.store, tx, &c, nil, revisionChange(ctx, model.RevisionCreate, now))
+	})
+	if err != nil {
+		slog.Error("failed to create curriculum", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to create curriculum")
+		return
+	}
 
+	normalizeCurriculum(&c)
 	writeJSON(w, http.StatusCreated, c)
 }
 
 func (h *CurriculumHandler) Update(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	id := chi.URLParam(r, "id")
-
-	ref := h.fs.Collection("curricula").Doc(id)
-	doc, err := ref.Get(ctx)
-	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			writeError(w, http.StatusNotFound, "curriculum not found")
-			return
-		}
-		writeError(w, http.StatusInternalServerError, "failed to get curriculum")
+	existing, ok := requireCurriculum(w, r, h.store, curriculum.AccessEdit)
+	if !ok {
 		return
 	}
+	id := existing.ID
 
-	var existing model.Curriculum
-	if err := doc.DataTo(&existing); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse curriculum")
+	var req model.UpdateCurriculumRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
-		writeError(w, http.StatusForbidden, "editor role required")
+	if req.IsPublic != nil && *req.IsPublic != existing.IsPublic &&
+		curriculum.AccessOf(existing, middleware.GetUserUID(ctx)) != curriculum.AccessOwner {
+		writeError(w, http.StatusForbidden, "only the curriculum owner can change its visibility")
 		return
 	}
 
-	var req model.UpdateCurriculumRequest
-	if err := decodeJSON(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	refs := newRefCheck(existing.DisciplineID).add("tagIds", refTag, req.TagIDs...)
+	if _, ok := checkRefs(w, ctx, h.store, refs, "failed to update curriculum"); !ok {
 		return
 	}
 
-	updates := []firestore.Update{
-		{Path: "updatedAt", Value: time.Now()},
+	now := time.Now()
+	updates := []store.Update{
+		{Path: "updatedAt", Value: now},
 	}
 
 	if req.Title != nil {
@@ -319,110 +290,140 @@ func (h *CurriculumHandler) Update(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		updates = append(updates, firestore.Update{Path: "title", Value: title})
+		updates = append(updates, store.Update{Path: "title", Value: title})
 	}
 	if req.Description != nil {
-		updates = append(updates, firestore.Update{Path: "description", Value: validate.StripAllHTML(*req.Description)})
+		updates = append(updates, store.Update{Path: "description", Value: validate.StripAllHTML(*req.Description)})
 	}
 	if req.IsPublic != nil {
-		updates = append(updates, firestore.Update{Path: "isPublic", Value: *req.IsPublic})
+		updates = append(updates, store.Update{Path: "isPublic", Value: *req.IsPublic})
 	}
 	if req.Duration != nil {
-		updates = append(updates, firestore.Update{Path: "duration", Value: validate.StripAllHTML(*req.Duration)})
+		// durationSeconds follows as a derived field.
+		dur, _, err := parseDuration("duration", *req.Duration)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		updates = append(updates, store.Update{Path: "duration", Value: dur})
 	}
 	if req.TagIDs != nil {
-		updates = append(updates, firestore.Update{Path: "tagIds", Value: req.TagIDs})
+		updates = append(updates, store.Update{Path: "tagIds", Value: req.TagIDs})
 	}
 
-	if _, err := ref.Update(ctx, updates); err != nil {
-		slog.Error("failed to update curriculum", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to update curriculum")
+	// Title, description and tags feed the derived fields, which are
+	// recomputed with the update.
+	var updated *model.Curriculum
+	err := h.store.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		c, elements, err := curriculum.Load(h.store, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := store.ApplyUpdates(c, updates); err != nil {
+			return err
+		}
+		if err := curriculum.Save(h.store, tx, c, elements, revisionChange(ctx, model.RevisionUpdate, now), updates...); err != nil {
+			return err
+		}
+		curriculum.Derive(c, elements).Apply(c)
+		updated = c
+		return nil
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "curriculum not found")
 		return
 	}
-
-	// Recompute denormalized search data (title/description/tagIds may have changed)
-	if err := recomputeCurriculumDenorm(ctx, h.fs, id); err != nil {
-		slog.Error("failed to recompute curriculum denorm after update", "id", id, "error", err)
-	}
-
-	updatedDoc, err := ref.Get(ctx)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to get updated curriculum")
-		return
-	}
-
-	var updated model.Curriculum
-	if err := updatedDoc.DataTo(&updated); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse updated curriculum")
+		slog.Error("failed to update curriculum", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update curriculum")
 		return
 	}
-	updated.ID = id
-	normalizeCurriculum(&updated)
+	normalizeCurriculum(updated)
 
 	writeJSON(w, http.StatusOK, updated)
 }
 
 func (h *CurriculumHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	id := chi.URLParam(r, "id")
-
-	ref := h.fs.Collection("curricula").Doc(id)
-	doc, err := ref.Get(ctx)
-	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			writeError(w, http.StatusNotFound, "curriculum not found")
-			return
-		}
-		writeError(w, http.StatusInternalServerError, "failed to get curriculum")
+	existing, ok := requireCurriculum(w, r, h.store, curriculum.AccessOwner)
+	if !ok {
 		return
 	}
+	id := existing.ID
 
-	var existing model.Curriculum
-	if err := doc.DataTo(&existing); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse curriculum")
-		return
+	// Sessions and progress go first, so that a retry finds the curriculum
+	// and deletes what a failure here left behind.
+	err := deleteByCurriculum(ctx, h.store, h.store.Sessions(), id, func(s *model.Session) string { return s.ID })
+	if err == nil {
+		err = deleteByCurriculum(ctx, h.store, h.store.Progress(), id, func(p *model.Progress) string { return p.ID })
 	}
-
-	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
-		writeError(w, http.StatusForbidden, "editor role required")
+	if err != nil {
+		slog.Error("failed to delete curriculum sessions and progress", "curriculumID", id, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete curriculum")
 		return
 	}
 
-	// Delete all elements in subcollection first (no cascade in Firestore)
-	elemIter := ref.Collection("elements").Documents(ctx)
-	defer elemIter.Stop()
-
-	batch := h.fs.Batch()
-	batchCount := 0
-	for {
-		elemDoc, err := elemIter.Next()
-		if err == iterator.Done {
+	// Then the elements (no cascade in Firestore), in transactions
+	// that keep the derived fields in step, then the revisions, and the
+	// curriculum with the last ones.
+	const chunk = store.MaxBatchSize - 1
+	for done := false; !done; {
+		err := h.store.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+			c, elements, err := curriculum.Load(h.store, tx, id)
+			if err != nil {
+				return err
+			}
+			var revisions []model.CurriculumRevision
+			if err := tx.List(store.RevisionsPath(id), store.NewQuery().Limit(chunk), &revisions); err != nil {
+				return err
+			}
+			n := min(len(elements), chunk)
+			for _, e := range elements[:n] {
+				tx.Delete(h.store.Elements().Ref(id, e.ID))
+			}
+			if n < len(elements) {
+				tx.Update(h.store.Curricula().Ref(id), curriculum.Derive(c, elements[n:]).Updates())
+				return nil
+			}
+			m := min(len(revisions), chunk-n)
+			for _, rev := range revisions[:m] {
+				tx.Delete(h.store.Revisions().Ref(id, rev.ID))
+			}
+			done = m == len(revisions) && len(revisions) < chunk
+			if done {
+				tx.Delete(h.store.Curricula().Ref(id))
+			}
+			return nil
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			// Deleted concurrently.
 			break
 		}
 		if err != nil {
-			slog.Error("failed to iterate elements", "error", err)
-			break
-		}
-		batch.Delete(elemDoc.Ref)
-		batchCount++
-
-		// Firestore batch limit is 500
-		if batchCount >= 499 {
-			if _, err := batch.Commit(ctx); err != nil {
-				slog.Error("failed to delete elements batch", "error", err)
-			}
-			batch = h.fs.Batch()
-			batchCount = 0
+			slog.Error("failed to delete curriculum", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to delete curriculum")
+			return
 		}
 	}
 
-	batch.Delete(ref)
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	if _, err := batch.Commit(ctx); err != nil {
-		slog.Error("failed to delete curriculum", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to delete curriculum")
-		return
+// deleteByCurriculum removes the documents of a top-level collection that
+// belong to a deleted curriculum.
+func deleteByCurriculum[T any](ctx context.Context, s store.Store, repo store.Repo[T], curriculumID string, idOf func(*T) string) error {
+	docs, err := repo.List(ctx, store.NewQuery().Where("curriculumId", "==", curriculumID))
+	if err != nil {
+		return err
 	}
-
-	w.WriteHeader(http.StatusNoContent)
+	for chunk := range slices.Chunk(docs, store.MaxBatchSize) {
+		batch := s.Batch()
+		for i := range chunk {
+			batch.Delete(repo.Ref(idOf(&chunk[i])))
+		}
+		if err := batch.Commit(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/middleware"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+	"github.com/thomas/skillhive-api/internal/validate"
+)
+
+// maxCollaborators caps the users invited to one curriculum, whose UIDs are
+// stored on the curriculum document.
+const maxCollaborators = 100
+
+var (
+	errTooManyCollaborators = errors.New("too many collaborators")
+	errCollaboratorNotFound = errors.New("collaborator not found")
+)
+
+// CollaboratorHandler manages the users invited to a curriculum. Only the
+// owner invites and removes collaborators; editors can list them, and any
+// collaborator can remove themselves.
+type CollaboratorHandler struct {
+	store store.Store
+	users UserAdmin
+}
+
+func NewCollaboratorHandler(s store.Store, users UserAdmin) *CollaboratorHandler {
+	return &CollaboratorHandler{store: s, users: users}
+}
+
+// withAccount fills in the email and display name of each collaborator whose
+// account can be read.
+func (h *CollaboratorHandler) withAccount(ctx context.Context, collaborators []model.Collaborator) []model.Collaborator {
+	for i := range collaborators {
+		if u, err := h.users.GetUser(ctx, collaborators[i].UID); err == nil {
+			collaborators[i].Email, collaborators[i].DisplayName = u.Email, u.DisplayName
+		}
+	}
+	return collaborators
+}
+
+// List returns the collaborators of a curriculum, editors first.
+// GET /api/v1/curricula/{id}/collaborators
+func (h *CollaboratorHandler) List(w http.ResponseWriter, r *http.Request) {
+	c, ok := requireCurriculum(w, r, h.store, curriculum.AccessEdit)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.withAccount(r.Context(), curriculum.Collaborators(c)))
+}
+
+// Invite gives the user named by uid or email a role on the curriculum, or
+// changes the role of a collaborator. It returns 201 for a new collaborator
+// and 200 for a changed one.
+// POST /api/v1/curricula/{id}/collaborators
+func (h *CollaboratorHandler) Invite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	c, ok := requireCurriculum(w, r, h.store, curriculum.AccessOwner)
+	if !ok {
+		return
+	}
+
+	var req model.InviteCollaboratorRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validate.EnumWhitelist("role", req.Role, []string{"viewer", "editor"}); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if (req.UID == nil) == (req.Email == nil) {
+		writeError(w, http.StatusBadRequest, "exactly one of uid and email is required")
+		return
+	}
+
+	var u *auth.UserRecord
+	var err error
+	if req.UID != nil {
+		u, err = h.users.GetUser(ctx, *req.UID)
+	} else {
+		u, err = h.users.GetUserByEmail(ctx, strings.TrimSpace(*req.Email))
+	}
+	if err != nil {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	if u.UID == c.OwnerUID {
+		writeError(w, http.StatusBadRequest, "the owner cannot be invited to their own curriculum")
+		return
+	}
+	account := model.Collaborator{UID: u.UID, Role: model.CollaboratorRole(req.Role), Email: u.Email, DisplayName: u.DisplayName}
+
+	added := false
+	err = h.store.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		var doc model.Curriculum
+		if err := tx.Get(h.store.Curricula().Ref(c.ID), &doc); err != nil {
+			return err
+		}
+		added = !slices.Contains(doc.EditorUIDs, account.UID) && !slices.Contains(doc.ViewerUIDs, account.UID)
+		if added && len(doc.EditorUIDs)+len(doc.ViewerUIDs) >= maxCollaborators {
+			return errTooManyCollaborators
+		}
+		doc.EditorUIDs = slices.DeleteFunc(doc.EditorUIDs, func(uid string) bool { return uid == account.UID })
+		doc.ViewerUIDs = slices.DeleteFunc(doc.ViewerUIDs, func(uid string) bool { return uid == account.UID })
+		if account.Role == model.CollaboratorEditor {
+			doc.EditorUIDs = append(doc.EditorUIDs, account.UID)
+		} else {
+			doc.ViewerUIDs = append(doc.ViewerUIDs, account.UID)
+		}
+		tx.Update(h.store.Curricula().Ref(c.ID), collaboratorUpdates(&doc))
+		return nil
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		writeError(w, http.StatusNotFound, "curriculum not found")
+		return
+	case errors.Is(err, errTooManyCollaborators):
+		writeError(w, http.StatusUnprocessableEntity, "curriculum has too many collaborators")
+		return
+	case err != nil:
+		slog.Error("failed to invite collaborator", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to invite collaborator")
+		return
+	}
+
+	status := http.StatusOK
+	if added {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, account)
+}
+
+// Remove takes a collaborator off the curriculum. The owner can remove
+// anyone, and a collaborator themselves.
+// DELETE /api/v1/curricula/{id}/collaborators/{uid}
+func (h *CollaboratorHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	target := chi.URLParam(r, "uid")
+
+	need := curriculum.AccessOwner
+	if target == middleware.GetUserUID(ctx) {
+		need = curriculum.AccessView
+	}
+	c, ok := requireCurriculum(w, r, h.store, need)
+	if !ok {
+		return
+	}
+
+	err := h.store.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		var doc model.Curriculum
+		if err := tx.Get(h.store.Curricula().Ref(c.ID), &doc); err != nil {
+			return err
+		}
+		n := len(doc.EditorUIDs) + len(doc.ViewerUIDs)
+		doc.EditorUIDs = slices.DeleteFunc(doc.EditorUIDs, func(uid string) bool { return uid == target })
+		doc.ViewerUIDs = slices.DeleteFunc(doc.ViewerUIDs, func(uid string) bool { return uid == target })
+		if len(doc.EditorUIDs)+len(doc.ViewerUIDs) == n {
+			return errCollaboratorNotFound
+		}
+		tx.Update(h.store.Curricula().Ref(c.ID), collaboratorUpdates(&doc))
+		return nil
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		writeError(w, http.StatusNotFound, "curriculum not found")
+		return
+	case errors.Is(err, errCollaboratorNotFound):
+		writeError(w, http.StatusNotFound, "collaborator not found")
+		return
+	case err != nil:
+		slog.Error("failed to remove collaborator", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to remove collaborator")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// collaboratorUpdates writes the collaborator lists of c, as empty lists
+// rather than null once the last collaborator is gone.
+func collaboratorUpdates(c *model.Curriculum) []store.Update {
+	if c.EditorUIDs == nil {
+		c.EditorUIDs = []string{}
+	}
+	if c.ViewerUIDs == nil {
+		c.ViewerUIDs = []string{}
+	}
+	return []store.Update{
+		{Path: "editorUids", Value: c.EditorUIDs},
+		{Path: "viewerUids", Value: c.ViewerUIDs},
+	}
+}
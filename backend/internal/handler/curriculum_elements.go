@@ -1,121 +1,131 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"slices"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/go-chi/chi/v5"
-	"github.com/thomas/skillhive-api/internal/middleware"
+	"github.com/thomas/skillhive-api/internal/curriculum"
 	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
 	"github.com/thomas/skillhive-api/internal/validate"
-	"google.golang.org/api/iterator"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
+// maxCaptionLength caps the caption of a clip element.
+const maxCaptionLength = 500
+
 type ElementHandler struct {
-	fs *firestore.Client
+	store store.Store
 }
 
-func NewElementHandler(fs *firestore.Client) *ElementHandler {
-	return &ElementHandler{fs: fs}
+func NewElementHandler(s store.Store) *ElementHandler {
+	return &ElementHandler{store: s}
 }
 
 // verifyCurriculumAccess checks if the curriculum exists and the user can view it.
 // Returns (curriculumID, ok).
 func (h *ElementHandler) verifyCurriculumAccess(w http.ResponseWriter, r *http.Request) (string, bool) {
-	curriculumID := chi.URLParam(r, "id")
-
-	doc, err := h.fs.Collection("curricula").Doc(curriculumID).Get(r.Context())
-	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			writeError(w, http.StatusNotFound, "curriculum not found")
-		} else {
-			writeError(w, http.StatusInternalServerError, "failed to get curriculum")
-		}
+	c, ok := requireCurriculum(w, r, h.store, curriculum.AccessView)
+	if !ok {
 		return "", false
 	}
-
-	_ = doc // curriculum exists, any authenticated user can read
-	return curriculumID, true
+	return c.ID, true
 }
 
-// verifyCurriculumEditor checks if the curriculum exists and the user has editor+ role.
-// Returns (curriculumID, ok).
+// verifyCurriculumEditor checks if the curriculum exists and the user is its
+// owner or an editor collaborator. Returns (curriculumID, ok).
 func (h *ElementHandler) verifyCurriculumEditor(w http.ResponseWriter, r *http.Request) (string, bool) {
-	ctx := r.Context()
-	curriculumID := chi.URLParam(r, "id")
-
-	doc, err := h.fs.Collection("curricula").Doc(curriculumID).Get(ctx)
-	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			writeError(w, http.StatusNotFound, "curriculum not found")
-		} else {
-			writeError(w, http.StatusInternalServerError, "failed to get curriculum")
-		}
+	c, ok := requireCurriculum(w, r, h.store, curriculum.AccessEdit)
+	if !ok {
 		return "", false
 	}
+	return c.ID, true
+}
 
-	disciplineID, _ := doc.DataAt("disciplineId")
-	disciplineStr, _ := disciplineID.(string)
-
-	if err := middleware.RequireEditor(ctx, disciplineStr); err != nil {
-		writeError(w, http.StatusForbidden, "editor role required")
-		return "", false
+// checkClip validates the segment a clip plays of asset a, which must be a
+// video. The end is checked against the asset's duration when it has one.
+func checkClip(a *model.Asset, start, end *int) error {
+	if start == nil || end == nil {
+		return errors.New("startSeconds and endSeconds are required for clip elements")
 	}
-
-	return curriculumID, true
+	if a.Type != model.AssetTypeVideo {
+		return errors.New("clips must reference a video asset")
+	}
+	if *start < 0 || *end <= *start {
+		return errors.New("startSeconds must be at least 0 and before endSeconds")
+	}
+	if total, ok := assetSeconds(a); ok && *end > total {
+		return fmt.Errorf("endSeconds must not be past the end of the video (%d seconds)", total)
+	}
+	return nil
 }
 
+// ListElements lists the elements of a curriculum in reading order, each
+// section followed by its elements, or with tree=true as a tree of sections.
+// Sections carry the total duration of their elements, and technique and
+// asset elements the status of their snapshot.
 func (h *ElementHandler) ListElements(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	curriculumID, ok := h.verifyCurriculumAccess(w, r)
 	if !ok {
 		return
 	}
+	asTree := r.URL.Query().Get("tree") == "true"
 
-	iter := h.fs.Collection("curricula").Doc(curriculumID).
-		Collection("elements").
-		OrderBy("ord", firestore.Asc).
-		Documents(ctx)
-	defer iter.Stop()
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
 
-	elements := []model.CurriculumElement{}
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			slog.Error("failed to list elements", "error", err)
-			writeError(w, http.StatusInternalServerError, "failed to list elements")
-			return
+	// Reading order depends on the sections, so the elements are read whole
+	// and paged in memory. The tree is never paginated.
+	elements, err := h.store.Elements().List(ctx, curriculumID, store.NewQuery())
+	if err != nil {
+		writeListError(w, err, "elements")
+		return
+	}
+	if err := curriculum.NewSources(ctx, h.store).Check(elements); err != nil {
+		writeListError(w, err, "elements")
+		return
+	}
+	elements = curriculum.Order(elements)
+	curriculum.SetTotals(elements)
+	for i := range elements {
+		if elements[i].Items == nil {
+			elements[i].Items = []string{}
 		}
+		curriculum.SetClipLinks(&elements[i])
+	}
+	if asTree {
+		writeJSON(w, http.StatusOK, curriculum.Tree(elements))
+		return
+	}
 
-		var e model.CurriculumElement
-		if err := doc.DataTo(&e); err != nil {
-			slog.Error("failed to parse element", "docID", doc.Ref.ID, "error", err)
-			continue
-		}
-		e.ID = doc.Ref.ID
-		if e.Items == nil {
-			e.Items = []string{}
-		}
-		elements = append(elements, e)
+	elements, next, err := slicePage(elements, page, func(e *model.CurriculumElement) string { return e.ID })
+	if err != nil {
+		writeListError(w, err, "elements")
+		return
 	}
+	setNextLink(w, r, next)
 
 	writeJSON(w, http.StatusOK, elements)
 }
 
 func (h *ElementHandler) CreateElement(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	curriculumID, ok := h.verifyCurriculumEditor(w, r)
+	// The discipline is needed to check the technique or asset referenced.
+	c, ok := requireCurriculum(w, r, h.store, curriculum.AccessEdit)
 	if !ok {
 		return
 	}
+	curriculumID := c.ID
 
 	var req model.CreateElementRequest
 	if err := decodeJSON(r, &req); err != nil {
@@ -123,7 +133,7 @@ func (h *ElementHandler) CreateElement(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := validate.EnumWhitelist("type", req.Type, []string{"technique", "asset", "text", "image", "list"}); err != nil {
+	if err := validate.EnumWhitelist("type", req.Type, []string{"technique", "asset", "text", "image", "list", "section", "clip"}); err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -149,6 +159,32 @@ func (h *ElementHandler) CreateElement(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusBadRequest, "title is required for text elements")
 			return
 		}
+	case "section":
+		if req.Title == nil || *req.Title == "" {
+			writeError(w, http.StatusBadRequest, "title is required for section elements")
+			return
+		}
+		if req.Duration != nil {
+			writeError(w, http.StatusBadRequest, "sections take their duration from their elements")
+			return
+		}
+	case "clip":
+		if req.AssetID == nil || *req.AssetID == "" {
+			writeError(w, http.StatusBadRequest, "assetId is required for clip elements")
+			return
+		}
+		if req.Duration != nil {
+			writeError(w, http.StatusBadRequest, "clips take their duration from startSeconds and endSeconds")
+			return
+		}
+	}
+	if req.Type != "clip" && (req.StartSeconds != nil || req.EndSeconds != nil || req.Caption != nil) {
+		writeError(w, http.StatusBadRequest, "startSeconds, endSeconds and caption are only for clip elements")
+		return
+	}
+	parentID := ""
+	if req.ParentID != nil {
+		parentID = *req.ParentID
 	}
 
 	// Sanitize inputs
@@ -165,128 +201,55 @@ func (h *ElementHandler) CreateElement(w http.ResponseWriter, r *http.Request) {
 			req.Details = &s
 		}
 	}
+	var durationSeconds *int
 	if req.Duration != nil {
-		s := validate.StripAllHTML(*req.Duration)
-		req.Duration = &s
+		var err error
+		if req.Duration, durationSeconds, err = parseDuration("duration", *req.Duration); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 	}
 	for i, item := range req.Items {
 		req.Items[i] = validate.StripAllHTML(item)
 	}
-
-	// Find max ord
-	elemColl := h.fs.Collection("curricula").Doc(curriculumID).Collection("elements")
-	maxOrd := 0
-	ordIter := elemColl.OrderBy("ord", firestore.Desc).Limit(1).Documents(ctx)
-	if lastDoc, err := ordIter.Next(); err == nil {
-		if ord, err := lastDoc.DataAt("ord"); err == nil {
-			if ordInt, ok := ord.(int64); ok {
-				maxOrd = int(ordInt)
-			}
+	if req.Caption != nil {
+		s := validate.StripAllHTML(*req.Caption)
+		if err := validate.MaxLength("caption", s, maxCaptionLength); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
 		}
+		req.Caption = &s
+	}
+
+	refs := newRefCheck(c.DisciplineID)
+	if req.TechniqueID != nil {
+		refs.add("techniqueId", refTechnique, *req.TechniqueID)
+	}
+	if req.AssetID != nil {
+		refs.add("assetId", refAsset, *req.AssetID)
+	}
+	docs, ok := checkRefs(w, ctx, h.store, refs, "failed to create element")
+	if !ok {
+		return
 	}
-	ordIter.Stop()
 
 	// Build snapshot if technique or asset reference
 	var snapshot *model.Snapshot
 	if req.Type == "technique" && req.TechniqueID != nil {
-		techDoc, err := h.fs.Collection("techniques").Doc(*req.TechniqueID).Get(ctx)
-		if err == nil {
-			s := &model.Snapshot{}
-			if name, _ := techDoc.DataAt("name"); name != nil {
-				if nameStr, ok := name.(string); ok {
-					s.Name = nameStr
-				}
-			}
-			if desc, _ := techDoc.DataAt("description"); desc != nil {
-				if descStr, ok := desc.(string); ok {
-					s.Description = descStr
-				}
-			}
-			if tags, _ := techDoc.DataAt("tagIds"); tags != nil {
-				if tagSlice, ok := tags.([]interface{}); ok {
-					for _, t := range tagSlice {
-						if tStr, ok := t.(string); ok {
-							s.TagIDs = append(s.TagIDs, tStr)
-						}
-					}
-				}
-			}
-			snapshot = s
-		}
+		snapshot = curriculum.TechniqueSnapshot(docs.techniques[*req.TechniqueID])
 	} else if req.Type == "asset" && req.AssetID != nil {
-		assetDoc, err := h.fs.Collection("assets").Doc(*req.AssetID).Get(ctx)
-		if err == nil {
-			s := &model.Snapshot{}
-			if name, _ := assetDoc.DataAt("title"); name != nil {
-				if nameStr, ok := name.(string); ok {
-					s.Name = nameStr
-				}
-			}
-			if thumb, _ := assetDoc.DataAt("thumbnailUrl"); thumb != nil {
-				if thumbStr, ok := thumb.(string); ok {
-					s.ThumbnailURL = thumbStr
-				}
-			}
-			if u, _ := assetDoc.DataAt("url"); u != nil {
-				if uStr, ok := u.(string); ok {
-					s.URL = uStr
-				}
-			}
-			if desc, _ := assetDoc.DataAt("description"); desc != nil {
-				if descStr, ok := desc.(string); ok {
-					s.Description = descStr
-				}
-			}
-			if tags, _ := assetDoc.DataAt("tagIds"); tags != nil {
-				if tagSlice, ok := tags.([]interface{}); ok {
-					for _, t := range tagSlice {
-						if tStr, ok := t.(string); ok {
-							s.TagIDs = append(s.TagIDs, tStr)
-						}
-					}
-				}
-			}
-			snapshot = s
+		snapshot = curriculum.AssetSnapshot(docs.assets[*req.AssetID])
+	} else if req.Type == "clip" {
+		a := docs.assets[*req.AssetID]
+		if err := checkClip(a, req.StartSeconds, req.EndSeconds); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
 		}
+		snapshot = curriculum.AssetSnapshot(a)
 	}
 
 	now := time.Now()
-	data := map[string]interface{}{
-		"type":        req.Type,
-		"techniqueId": req.TechniqueID,
-		"assetId":     req.AssetID,
-		"title":       req.Title,
-		"details":     req.Details,
-		"imageUrl":    req.ImageURL,
-		"duration":    req.Duration,
-		"items":       req.Items,
-		"ord":         maxOrd + 1,
-		"snapshot":    snapshot,
-		"createdAt":   now,
-		"updatedAt":   now,
-	}
-
-	ref, _, err := elemColl.Add(ctx, data)
-	if err != nil {
-		slog.Error("failed to create element", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to create element")
-		return
-	}
-
-	// Update curriculum updatedAt
-	if _, err := h.fs.Collection("curricula").Doc(curriculumID).Update(ctx, []firestore.Update{
-		{Path: "updatedAt", Value: now},
-	}); err != nil {
-		slog.Error("failed to update curriculum updatedAt", "curriculumID", curriculumID, "error", err)
-	}
-
-	// Recompute curriculum denormalized search data
-	if err := recomputeCurriculumDenorm(ctx, h.fs, curriculumID); err != nil {
-		slog.Error("failed to recompute curriculum denorm after element create", "curriculumID", curriculumID, "error", err)
-	}
-
 	elem := model.CurriculumElement{
-		ID:          ref.ID,
 		Type:        model.ElementType(req.Type),
 		TechniqueID: req.TechniqueID,
 		AssetID:     req.AssetID,
@@ -295,11 +258,56 @@ func (h *ElementHandler) CreateElement(w http.ResponseWriter, r *http.Request) {
 		ImageURL:    req.ImageURL,
 		Duration:    req.Duration,
 		Items:       req.Items,
-		Ord:         maxOrd + 1,
 		Snapshot:    snapshot,
 		CreatedAt:   now,
 		UpdatedAt:   now,
+
+		DurationSeconds: durationSeconds,
+		StartSeconds:    req.StartSeconds,
+		EndSeconds:      req.EndSeconds,
+		Caption:         req.Caption,
+	}
+
+	if parentID != "" {
+		elem.ParentID = &parentID
+	}
+
+	// Append the element to its section, or the top level, and update the
+	// curriculum's derived fields atomically, so that concurrent writes
+	// neither share an ord nor leave the counters, tags or search text stale.
+	elem.ID = store.NewID()
+	err := h.store.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		c, elements, err := curriculum.Load(h.store, tx, curriculumID)
+		if err != nil {
+			return err
+		}
+		if err := curriculum.CheckParent(elements, parentID, &elem); err != nil {
+			return err
+		}
+		elem.Ord = 1
+		for _, e := range elements {
+			if curriculum.ParentOf(&e) == parentID {
+				elem.Ord = max(elem.Ord, e.Ord+1)
+			}
+		}
+		tx.Set(h.store.Elements().Ref(curriculumID, elem.ID), &elem)
+		return curriculum.Save(h.store, tx, c, append(elements, elem), revisionChange(ctx, model.RevisionElementCreate, now),
+			store.Update{Path: "updatedAt", Value: now})
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "curriculum not found")
+		return
+	}
+	if errors.Is(err, curriculum.ErrInvalidParent) {
+		writeError(w, http.StatusBadRequest, "parentId must be a section of the curriculum, and sections cannot be nested")
+		return
 	}
+	if err != nil {
+		slog.Error("failed to create element", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to create element")
+		return
+	}
+	curriculum.SetClipLinks(&elem)
 
 	writeJSON(w, http.StatusCreated, elem)
 }
@@ -312,10 +320,9 @@ func (h *ElementHandler) UpdateElement(w http.ResponseWriter, r *http.Request) {
 	}
 	elemID := chi.URLParam(r, "elemId")
 
-	ref := h.fs.Collection("curricula").Doc(curriculumID).Collection("elements").Doc(elemID)
-	existingDoc, err := ref.Get(ctx)
+	existing, err := h.store.Elements().Get(ctx, curriculumID, elemID)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "element not found")
 		} else {
 			writeError(w, http.StatusInternalServerError, "failed to get element")
@@ -324,77 +331,174 @@ func (h *ElementHandler) UpdateElement(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Read element type for sanitization routing
-	elemType, _ := existingDoc.DataAt("type")
-	elemTypeStr, _ := elemType.(string)
+	elemTypeStr := string(existing.Type)
 
 	var req model.CreateElementRequest
 	if err := decodeJSON(r, &req); err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if req.ParentID != nil {
+		writeError(w, http.StatusBadRequest, "elements are moved between sections by reordering")
+		return
+	}
+	if req.Duration != nil && existing.Type == model.ElementTypeSection {
+		writeError(w, http.StatusBadRequest, "sections take their duration from their elements")
+		return
+	}
+
+	// Asset elements become clips, and clips asset elements, by changing
+	// their type.
+	elemType := existing.Type
+	convert := req.Type != "" && req.Type != string(existing.Type)
+	if convert {
+		switch {
+		case existing.Type == model.ElementTypeAsset && req.Type == "clip":
+		case existing.Type == model.ElementTypeClip && req.Type == "asset":
+		default:
+			writeError(w, http.StatusBadRequest, "only asset elements can be converted to clips, and clips back to asset elements")
+			return
+		}
+		elemType = model.ElementType(req.Type)
+	}
+	if elemType != model.ElementTypeClip && (req.StartSeconds != nil || req.EndSeconds != nil || req.Caption != nil) {
+		writeError(w, http.StatusBadRequest, "startSeconds, endSeconds and caption are only for clip elements")
+		return
+	}
+	if req.Duration != nil && elemType == model.ElementTypeClip {
+		writeError(w, http.StatusBadRequest, "clips take their duration from startSeconds and endSeconds")
+		return
+	}
+
+	now := time.Now()
+	updates := []store.Update{
+		{Path: "updatedAt", Value: now},
+	}
 
-	updates := []firestore.Update{
-		{Path: "updatedAt", Value: time.Now()},
+	switch {
+	case convert && elemType == model.ElementTypeAsset:
+		updates = append(updates,
+			store.Update{Path: "type", Value: elemType},
+			store.Update{Path: "startSeconds", Value: nil},
+			store.Update{Path: "endSeconds", Value: nil},
+			store.Update{Path: "caption", Value: nil})
+	case elemType == model.ElementTypeClip && (convert || req.StartSeconds != nil || req.EndSeconds != nil):
+		start, end := existing.StartSeconds, existing.EndSeconds
+		if req.StartSeconds != nil {
+			start = req.StartSeconds
+		}
+		if req.EndSeconds != nil {
+			end = req.EndSeconds
+		}
+		if existing.AssetID == nil {
+			writeError(w, http.StatusBadRequest, "the element has no asset to clip")
+			return
+		}
+		a, err := h.store.Assets().Get(ctx, *existing.AssetID)
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusBadRequest, "the element's asset no longer exists")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to get asset")
+			return
+		}
+		if err := checkClip(a, start, end); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		updates = append(updates,
+			store.Update{Path: "startSeconds", Value: start},
+			store.Update{Path: "endSeconds", Value: end})
+		if convert {
+			updates = append(updates,
+				store.Update{Path: "type", Value: elemType},
+				store.Update{Path: "duration", Value: nil},
+				store.Update{Path: "durationSeconds", Value: nil})
+		}
+	}
+	if req.Caption != nil {
+		s := validate.StripAllHTML(*req.Caption)
+		if err := validate.MaxLength("caption", s, maxCaptionLength); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		updates = append(updates, store.Update{Path: "caption", Value: &s})
 	}
 
 	if req.Title != nil {
 		s := validate.StripAllHTML(*req.Title)
-		updates = append(updates, firestore.Update{Path: "title", Value: &s})
+		updates = append(updates, store.Update{Path: "title", Value: &s})
 	}
 	if req.Details != nil {
 		if elemTypeStr == "text" || elemTypeStr == "list" {
 			s := validate.SanitizeMarkdown(*req.Details)
-			updates = append(updates, firestore.Update{Path: "details", Value: &s})
+			updates = append(updates, store.Update{Path: "details", Value: &s})
 		} else {
 			s := validate.StripAllHTML(*req.Details)
-			updates = append(updates, firestore.Update{Path: "details", Value: &s})
+			updates = append(updates, store.Update{Path: "details", Value: &s})
 		}
 	}
 	if req.Duration != nil {
-		s := validate.StripAllHTML(*req.Duration)
-		updates = append(updates, firestore.Update{Path: "duration", Value: &s})
+		text, seconds, err := parseDuration("duration", *req.Duration)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		updates = append(updates,
+			store.Update{Path: "duration", Value: text},
+			store.Update{Path: "durationSeconds", Value: seconds})
 	}
 	if req.ImageURL != nil {
 		if _, err := url.ParseRequestURI(*req.ImageURL); err != nil {
 			writeError(w, http.StatusBadRequest, "imageUrl must be a valid URL")
 			return
 		}
-		updates = append(updates, firestore.Update{Path: "imageUrl", Value: req.ImageURL})
+		updates = append(updates, store.Update{Path: "imageUrl", Value: req.ImageURL})
 	}
 	if req.Items != nil {
 		for i, item := range req.Items {
 			req.Items[i] = validate.StripAllHTML(item)
 		}
-		updates = append(updates, firestore.Update{Path: "items", Value: req.Items})
+		updates = append(updates, store.Update{Path: "items", Value: req.Items})
 	}
 
-	if _, err := ref.Update(ctx, updates); err != nil {
-		slog.Error("failed to update element", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to update element")
+	var updated model.CurriculumElement
+	err = h.store.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		c, elements, err := curriculum.Load(h.store, tx, curriculumID)
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(elements, func(e model.CurriculumElement) bool { return e.ID == elemID })
+		if i < 0 {
+			return store.ErrNotFound
+		}
+		if err := store.ApplyUpdates(&elements[i], updates); err != nil {
+			return err
+		}
+		updated = elements[i]
+		tx.Update(h.store.Elements().Ref(curriculumID, elemID), updates)
+		return curriculum.Save(h.store, tx, c, elements, revisionChange(ctx, model.RevisionElementUpdate, now))
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "element not found")
 		return
 	}
-
-	// Recompute curriculum denormalized search data
-	if err := recomputeCurriculumDenorm(ctx, h.fs, curriculumID); err != nil {
-		slog.Error("failed to recompute curriculum denorm after element update", "curriculumID", curriculumID, "error", err)
-	}
-
-	updatedDoc, err := ref.Get(ctx)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to get updated element")
+		slog.Error("failed to update element", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update element")
 		return
 	}
-
-	var updated model.CurriculumElement
-	if err := updatedDoc.DataTo(&updated); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse updated element")
-		return
+	if updated.Items == nil {
+		updated.Items = []string{}
 	}
-	updated.ID = elemID
+	curriculum.SetClipLinks(&updated)
 
 	writeJSON(w, http.StatusOK, updated)
 }
 
+// DeleteElement deletes an element; deleting a section deletes its elements
+// too.
 func (h *ElementHandler) DeleteElement(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	curriculumID, ok := h.verifyCurriculumEditor(w, r)
@@ -403,21 +507,88 @@ func (h *ElementHandler) DeleteElement(w http.ResponseWriter, r *http.Request) {
 	}
 	elemID := chi.URLParam(r, "elemId")
 
-	ref := h.fs.Collection("curricula").Doc(curriculumID).Collection("elements").Doc(elemID)
-	if _, err := ref.Delete(ctx); err != nil {
+	err := h.store.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		c, elements, err := curriculum.Load(h.store, tx, curriculumID)
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(elements, func(e model.CurriculumElement) bool { return e.ID == elemID }) {
+			// Already gone: deleting is idempotent.
+			return nil
+		}
+		var gone []string
+		for _, e := range elements {
+			if e.ID == elemID || curriculum.ParentOf(&e) == elemID {
+				gone = append(gone, e.ID)
+			}
+		}
+		if len(gone) > curriculum.ElementsPerTx {
+			return curriculum.ErrTooLarge
+		}
+		for _, id := range gone {
+			tx.Delete(h.store.Elements().Ref(curriculumID, id))
+		}
+		return curriculum.Save(h.store, tx, c, slices.DeleteFunc(elements, func(e model.CurriculumElement) bool {
+			return slices.Contains(gone, e.ID)
+		}),
+			revisionChange(ctx, model.RevisionElementDelete, time.Now()))
+	})
+	if errors.Is(err, curriculum.ErrTooLarge) {
+		writeError(w, http.StatusUnprocessableEntity, "section has too many elements to delete at once")
+		return
+	}
+	if err != nil {
 		slog.Error("failed to delete element", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to delete element")
 		return
 	}
 
-	// Recompute curriculum denormalized search data
-	if err := recomputeCurriculumDenorm(ctx, h.fs, curriculumID); err != nil {
-		slog.Error("failed to recompute curriculum denorm after element delete", "curriculumID", curriculumID, "error", err)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RefreshElements re-snapshots the technique and asset elements named in
+// elementIds, or all of them when it is empty, from their current sources.
+// Elements whose source is gone keep their snapshot and are reported as
+// broken.
+// POST /api/v1/curricula/{id}/elements/refresh
+func (h *ElementHandler) RefreshElements(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	curriculumID, ok := h.verifyCurriculumEditor(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.RefreshElementsRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	report, err := curriculum.Refresh(ctx, h.store, curriculumID, req.ElementIDs,
+		revisionChange(ctx, model.RevisionElementRefresh, time.Now()))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "element not found")
+		return
+	}
+	if errors.Is(err, curriculum.ErrTooLarge) {
+		writeError(w, http.StatusUnprocessableEntity, "too many elements to refresh at once")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to refresh elements", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to refresh elements")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
 }
 
+// ReorderElements places the elements listed in orderedIds, in that order,
+// first among the elements of section parentId, or of the top level when it
+// is unset, moving them there from wherever they are. The other elements
+// there follow in their current order.
 func (h *ElementHandler) ReorderElements(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	curriculumID, ok := h.verifyCurriculumEditor(w, r)
@@ -435,25 +606,58 @@ func (h *ElementHandler) ReorderElements(w http.ResponseWriter, r *http.Request)
 		writeError(w, http.StatusBadRequest, "orderedIds must not be empty")
 		return
 	}
-
-	elemColl := h.fs.Collection("curricula").Doc(curriculumID).Collection("elements")
-	batch := h.fs.Batch()
-	now := time.Now()
-
-	for i, elemID := range req.OrderedIDs {
-		ref := elemColl.Doc(elemID)
-		batch.Update(ref, []firestore.Update{
-			{Path: "ord", Value: i + 1},
-			{Path: "updatedAt", Value: now},
-		})
+	for i, id := range req.OrderedIDs {
+		if slices.Contains(req.OrderedIDs[:i], id) {
+			writeError(w, http.StatusBadRequest, "orderedIds must not repeat an element")
+			return
+		}
+	}
+	parentID := ""
+	if req.ParentID != nil {
+		parentID = *req.ParentID
 	}
 
-	// Also update curriculum updatedAt
-	batch.Update(h.fs.Collection("curricula").Doc(curriculumID), []firestore.Update{
-		{Path: "updatedAt", Value: now},
+	now := time.Now()
+	err := h.store.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		c, elements, err := curriculum.Load(h.store, tx, curriculumID)
+		if err != nil {
+			return err
+		}
+		elements, moved, err := curriculum.Arrange(elements, parentID, req.OrderedIDs)
+		if err != nil {
+			return err
+		}
+		if len(moved) > curriculum.ElementsPerTx {
+			return curriculum.ErrTooLarge
+		}
+		for i := range elements {
+			if !slices.Contains(moved, elements[i].ID) {
+				continue
+			}
+			elements[i].UpdatedAt = now
+			tx.Update(h.store.Elements().Ref(curriculumID, elements[i].ID), []store.Update{
+				{Path: "ord", Value: elements[i].Ord},
+				{Path: "parentId", Value: elements[i].ParentID},
+				{Path: "updatedAt", Value: now},
+			})
+		}
+		// Also update curriculum updatedAt
+		return curriculum.Save(h.store, tx, c, elements, revisionChange(ctx, model.RevisionElementReorder, now),
+			store.Update{Path: "updatedAt", Value: now})
 	})
-
-	if _, err := batch.Commit(ctx); err != nil {
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "element not found")
+		return
+	}
+	if errors.Is(err, curriculum.ErrInvalidParent) {
+		writeError(w, http.StatusBadRequest, "parentId must be a section of the curriculum, and sections cannot be nested")
+		return
+	}
+	if errors.Is(err, curriculum.ErrTooLarge) {
+		writeError(w, http.StatusUnprocessableEntity, "too many elements to move at once")
+		return
+	}
+	if err != nil {
 		slog.Error("failed to reorder elements", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to reorder elements")
 		return
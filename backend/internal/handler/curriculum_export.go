@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/export"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+type ExportHandler struct {
+	store store.Store
+	fetch export.ImageFetcher
+}
+
+// NewExportHandler returns an ExportHandler that downloads the images it
+// embeds in PDFs with fetch; nil uses export.FetchImage.
+func NewExportHandler(s store.Store, fetch export.ImageFetcher) *ExportHandler {
+	if fetch == nil {
+		fetch = export.FetchImage
+	}
+	return &ExportHandler{store: s, fetch: fetch}
+}
+
+// Export renders a curriculum and its elements, in order, as a handout.
+// GET /api/v1/curricula/{id}/export?format=md|html|pdf
+func (h *ExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	format := export.FormatMarkdown
+	if v := r.URL.Query().Get("format"); v != "" {
+		var ok bool
+		if format, ok = export.ParseFormat(v); !ok {
+			writeError(w, http.StatusBadRequest, "format must be md, html or pdf")
+			return
+		}
+	}
+
+	c, ok := requireCurriculum(w, r, h.store, curriculum.AccessView)
+	if !ok {
+		return
+	}
+	elements, err := h.store.Elements().List(ctx, c.ID, store.NewQuery())
+	if err != nil {
+		slog.Error("failed to list elements", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list elements")
+		return
+	}
+
+	handout := export.NewHandout(c, curriculum.Order(elements))
+	var buf bytes.Buffer
+	switch format {
+	case export.FormatHTML:
+		err = export.HTML(&buf, handout)
+	case export.FormatPDF:
+		err = export.PDF(&buf, handout, export.FetchImages(ctx, h.fetch, handout.ImageURLs()))
+	default:
+		err = export.Markdown(&buf, handout)
+	}
+	if err != nil {
+		slog.Error("failed to export curriculum", "format", format, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to export curriculum")
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", handout.Filename(format)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/middleware"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+	"github.com/thomas/skillhive-api/internal/validate"
+)
+
+// Fork copies a curriculum and its elements into a new private curriculum
+// owned by the caller, in the discipline given by disciplineId (default: the
+// source's). Any curriculum the caller can view can be forked into a
+// discipline they are an editor of.
+// POST /api/v1/curricula/{id}/fork?disciplineId=X
+func (h *CurriculumHandler) Fork(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	src, ok := requireCurriculum(w, r, h.store, curriculum.AccessView)
+	if !ok {
+		return
+	}
+	id := src.ID
+
+	disciplineID := r.URL.Query().Get("disciplineId")
+	if disciplineID == "" {
+		disciplineID = src.DisciplineID
+	}
+	if err := middleware.RequireEditor(ctx, disciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
+		return
+	}
+
+	var req model.ForkCurriculumRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	title := src.Title
+	if req.Title != nil {
+		title = validate.StripAllHTML(*req.Title)
+		if err := validate.StringLength("title", title, 1, 200); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	now := time.Now()
+	fork := model.Curriculum{
+		ID:           store.NewID(),
+		DisciplineID: disciplineID,
+		Title:        title,
+		OwnerUID:     middleware.GetUserUID(ctx),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	err := h.store.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		src, elements, err := curriculum.Load(h.store, tx, id)
+		if err != nil {
+			return err
+		}
+		remap, err := curriculum.NewRemap(h.store, tx, src.DisciplineID, disciplineID, src.TagIDs, elements)
+		if err != nil {
+			return err
+		}
+		return curriculum.Fork(h.store, tx, src, elements, remap, &fork, revisionChange(ctx, model.RevisionFork, now))
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		writeError(w, http.StatusNotFound, "curriculum not found")
+		return
+	case errors.Is(err, curriculum.ErrTooLarge):
+		writeError(w, http.StatusUnprocessableEntity, "curriculum has too many elements to fork")
+		return
+	case err != nil:
+		slog.Error("failed to fork curriculum", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to fork curriculum")
+		return
+	}
+	normalizeCurriculum(&fork)
+
+	writeJSON(w, http.StatusCreated, fork)
+}
+
+// Pull merges the changes made upstream since a fork was created or last
+// pulled. With dryRun=true it only reports what would change. The caller
+// must be able to edit the fork and still view the upstream curriculum.
+// POST /api/v1/curricula/{id}/pull?dryRun=true
+func (h *CurriculumHandler) Pull(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+
+	existing, ok := requireCurriculum(w, r, h.store, curriculum.AccessEdit)
+	if !ok {
+		return
+	}
+	id := existing.ID
+
+	if existing.SourceCurriculumID != "" {
+		upstream, err := h.store.Curricula().Get(ctx, existing.SourceCurriculumID)
+		switch {
+		case err == nil && curriculum.AccessOf(upstream, middleware.GetUserUID(ctx)) == curriculum.AccessNone:
+			writeError(w, http.StatusForbidden, "upstream curriculum is no longer visible to you")
+			return
+		case err != nil && !errors.Is(err, store.ErrNotFound):
+			writeError(w, http.StatusInternalServerError, "failed to get curriculum")
+			return
+		}
+		// A missing upstream is reported by curriculum.Pull.
+	}
+
+	var report *curriculum.PullReport
+	err := h.store.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		var err error
+		report, err = curriculum.Pull(h.store, tx, id, dryRun, revisionChange(ctx, model.RevisionPull, time.Now()))
+		return err
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		writeError(w, http.StatusNotFound, "curriculum not found")
+		return
+	case errors.Is(err, curriculum.ErrNotFork):
+		writeError(w, http.StatusBadRequest, "curriculum is not a fork")
+		return
+	case errors.Is(err, curriculum.ErrUpstreamGone):
+		writeError(w, http.StatusConflict, "upstream curriculum no longer exists")
+		return
+	case errors.Is(err, curriculum.ErrNoBaseRevision):
+		writeError(w, http.StatusConflict, "upstream history does not reach back to the fork")
+		return
+	case errors.Is(err, curriculum.ErrTooLarge):
+		writeError(w, http.StatusUnprocessableEntity, "too many upstream changes to pull at once")
+		return
+	case err != nil:
+		slog.Error("failed to pull upstream changes", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to pull upstream changes")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
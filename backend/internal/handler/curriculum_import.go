@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/middleware"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/outline"
+)
+
+// Import builds a curriculum from a Markdown or YAML outline. Without
+// commit it only returns the preview, listing the technique and asset
+// references that matched nothing; committing with unresolved references
+// requires allowUnresolved, which imports them as text elements.
+// POST /api/v1/curricula/import?disciplineId=X
+func (h *CurriculumHandler) Import(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	disciplineID := r.URL.Query().Get("disciplineId")
+
+	if disciplineID == "" {
+		writeError(w, http.StatusBadRequest, "disciplineId query parameter is required")
+		return
+	}
+
+	if err := middleware.RequireEditor(ctx, disciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
+		return
+	}
+
+	var req model.ImportCurriculumRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	format := outline.Format(req.Format)
+	if format != outline.FormatMarkdown && format != outline.FormatYAML {
+		writeError(w, http.StatusBadRequest, "format must be markdown or yaml")
+		return
+	}
+
+	draft, err := outline.Parse(format, req.Source)
+	if err != nil {
+		writeOutlineError(w, err)
+		return
+	}
+	if req.Title != nil {
+		draft.Title = *req.Title
+	}
+	preview, err := outline.Build(ctx, outline.NewResolver(h.store, disciplineID), draft)
+	if err != nil {
+		writeOutlineError(w, err)
+		return
+	}
+
+	if !req.Commit {
+		writeJSON(w, http.StatusOK, preview)
+		return
+	}
+	if len(preview.Unresolved) > 0 && !req.AllowUnresolved {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":      "outline has unresolved references",
+			"unresolved": preview.Unresolved,
+		})
+		return
+	}
+
+	err = outline.Commit(ctx, h.store, preview, revisionChange(ctx, model.RevisionCreate, time.Now()))
+	switch {
+	case errors.Is(err, curriculum.ErrTooLarge):
+		writeError(w, http.StatusUnprocessableEntity, "outline has too many elements")
+		return
+	case err != nil:
+		slog.Error("failed to import curriculum", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to import curriculum")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, preview)
+}
+
+// writeOutlineError writes the response for an outline that failed to parse
+// or build.
+func writeOutlineError(w http.ResponseWriter, err error) {
+	var syntaxErr *outline.SyntaxError
+	switch {
+	case errors.Is(err, outline.ErrNoTitle):
+		writeError(w, http.StatusBadRequest, "outline has no title")
+	case errors.As(err, &syntaxErr):
+		writeError(w, http.StatusBadRequest, syntaxErr.Error())
+	default:
+		slog.Error("failed to build outline", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to import curriculum")
+	}
+}
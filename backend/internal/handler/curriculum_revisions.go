@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/middleware"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+type RevisionHandler struct {
+	store store.Store
+}
+
+func NewRevisionHandler(s store.Store) *RevisionHandler {
+	return &RevisionHandler{store: s}
+}
+
+// revisionChange describes a write by the requesting user for the revision
+// that records it.
+func revisionChange(ctx context.Context, action model.RevisionAction, at time.Time) curriculum.Change {
+	return curriculum.Change{Action: action, ActorUID: middleware.GetUserUID(ctx), At: at}
+}
+
+// parseRevisionNumber parses a revision number, which starts at 1.
+func parseRevisionNumber(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	return n, err == nil && n > 0
+}
+
+// getCurriculum loads the curriculum named in the URL, which the user must
+// be able to view, writing the error response when that fails.
+func (h *RevisionHandler) getCurriculum(w http.ResponseWriter, r *http.Request) (*model.Curriculum, bool) {
+	return requireCurriculum(w, r, h.store, curriculum.AccessView)
+}
+
+// getRevision loads revision number n of a curriculum, writing the error
+// response when that fails.
+func (h *RevisionHandler) getRevision(w http.ResponseWriter, r *http.Request, curriculumID string, n int) (*model.CurriculumRevision, bool) {
+	rev, err := h.store.Revisions().Get(r.Context(), curriculumID, curriculum.RevisionID(n))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "revision not found")
+		} else {
+			writeError(w, http.StatusInternalServerError, "failed to get revision")
+		}
+		return nil, false
+	}
+	return rev, true
+}
+
+// List returns the revisions of a curriculum, newest first, without their
+// content.
+// GET /api/v1/curricula/{id}/revisions
+func (h *RevisionHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	c, ok := h.getCurriculum(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+
+	list := func(ctx context.Context, q store.Query) ([]model.CurriculumRevision, error) {
+		return h.store.Revisions().List(ctx, c.ID, q)
+	}
+	revisions, next, err := listPage(ctx, list, store.NewQuery().OrderBy("number", store.Desc), page,
+		func(rev *model.CurriculumRevision) string { return rev.ID }, nil)
+	if err != nil {
+		writeListError(w, err, "revisions")
+		return
+	}
+	setNextLink(w, r, next)
+
+	writeJSON(w, http.StatusOK, revisions)
+}
+
+// Get returns one revision with the curriculum and elements it recorded.
+// GET /api/v1/curricula/{id}/revisions/{rev}
+func (h *RevisionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	n, ok := parseRevisionNumber(chi.URLParam(r, "rev"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid revision number")
+		return
+	}
+	c, ok := h.getCurriculum(w, r)
+	if !ok {
+		return
+	}
+	rev, ok := h.getRevision(w, r, c.ID, n)
+	if !ok {
+		return
+	}
+
+	content, err := curriculum.Content(rev)
+	if err != nil {
+		slog.Error("failed to decode revision", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get revision")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, curriculum.RevisionDetail{CurriculumRevision: *rev, Content: *content})
+}
+
+// Diff compares two revisions. to defaults to the latest revision and from
+// to the one before to.
+// GET /api/v1/curricula/{id}/revisions/diff?from=N&to=M
+func (h *RevisionHandler) Diff(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.getCurriculum(w, r)
+	if !ok {
+		return
+	}
+
+	to := c.Revision
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, ok = parseRevisionNumber(v); !ok {
+			writeError(w, http.StatusBadRequest, "to must be a revision number")
+			return
+		}
+	}
+	from := to - 1
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, ok = parseRevisionNumber(v); !ok {
+			writeError(w, http.StatusBadRequest, "from must be a revision number")
+			return
+		}
+	}
+	if from < 1 || to < 1 {
+		writeError(w, http.StatusNotFound, "revision not found")
+		return
+	}
+
+	fromRev, ok := h.getRevision(w, r, c.ID, from)
+	if !ok {
+		return
+	}
+	toRev, ok := h.getRevision(w, r, c.ID, to)
+	if !ok {
+		return
+	}
+	diff, err := curriculum.DiffRevisions(fromRev, toRev)
+	if err != nil {
+		slog.Error("failed to diff revisions", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to diff revisions")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, diff)
+}
+
+// Restore brings a curriculum and its elements back to a past revision,
+// recorded as a new revision, and returns the restored curriculum.
+// POST /api/v1/curricula/{id}/revisions/{rev}/restore
+func (h *RevisionHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	n, ok := parseRevisionNumber(chi.URLParam(r, "rev"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid revision number")
+		return
+	}
+	c, ok := requireCurriculum(w, r, h.store, curriculum.AccessEdit)
+	if !ok {
+		return
+	}
+
+	var restored *model.Curriculum
+	err := h.store.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		var err error
+		restored, err = curriculum.Restore(h.store, tx, c.ID, n, revisionChange(ctx, model.RevisionRestore, time.Now()))
+		return err
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		writeError(w, http.StatusNotFound, "revision not found")
+		return
+	case errors.Is(err, curriculum.ErrTooLarge):
+		writeError(w, http.StatusUnprocessableEntity, "revision has too many elements to restore")
+		return
+	case err != nil:
+		slog.Error("failed to restore revision", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to restore revision")
+		return
+	}
+	normalizeCurriculum(restored)
+
+	writeJSON(w, http.StatusOK, restored)
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/duration"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+// shareMaxAge is how long clients and shared caches may reuse a shared
+// curriculum, which bounds how long a revoked link keeps working there.
+const shareMaxAge = 60 * time.Second
+
+type ShareHandler struct {
+	store store.Store
+}
+
+func NewShareHandler(s store.Store) *ShareHandler {
+	return &ShareHandler{store: s}
+}
+
+func shareLink(c *model.Curriculum) model.ShareLink {
+	link := model.ShareLink{Token: *c.ShareToken, Path: "/share/" + *c.ShareToken}
+	if !c.ShareExpiresAt.IsZero() {
+		link.ExpiresAt = &c.ShareExpiresAt
+	}
+	return link
+}
+
+// GetLink returns the share link of a curriculum.
+// GET /api/v1/curricula/{id}/share
+func (h *ShareHandler) GetLink(w http.ResponseWriter, r *http.Request) {
+	c, ok := requireCurriculum(w, r, h.store, curriculum.AccessOwner)
+	if !ok {
+		return
+	}
+	if c.ShareToken == nil {
+		writeError(w, http.StatusNotFound, "curriculum is not shared")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, shareLink(c))
+}
+
+// PutLink creates the share link of a curriculum (201), or changes its
+// expiry and, with rotate, its token (200).
+// PUT /api/v1/curricula/{id}/share
+func (h *ShareHandler) PutLink(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	c, ok := requireCurriculum(w, r, h.store, curriculum.AccessOwner)
+	if !ok {
+		return
+	}
+
+	var req model.ShareCurriculumRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	var expires time.Time
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			writeError(w, http.StatusBadRequest, "expiresAt must be in the future")
+			return
+		}
+		expires = req.ExpiresAt.UTC()
+	}
+
+	created := false
+	err := h.store.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		var doc model.Curriculum
+		if err := tx.Get(h.store.Curricula().Ref(c.ID), &doc); err != nil {
+			return err
+		}
+		created = doc.ShareToken == nil
+		if created || req.Rotate {
+			token := rand.Text()
+			doc.ShareToken = &token
+		}
+		doc.ShareExpiresAt = expires
+		tx.Update(h.store.Curricula().Ref(c.ID), []store.Update{
+			{Path: "shareToken", Value: *doc.ShareToken},
+			{Path: "shareExpiresAt", Value: expires},
+		})
+		c = &doc
+		return nil
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "curriculum not found")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to share curriculum", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to share curriculum")
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, shareLink(c))
+}
+
+// Revoke removes the share link of a curriculum.
+// DELETE /api/v1/curricula/{id}/share
+func (h *ShareHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	c, ok := requireCurriculum(w, r, h.store, curriculum.AccessOwner)
+	if !ok {
+		return
+	}
+	if c.ShareToken == nil {
+		writeError(w, http.StatusNotFound, "curriculum is not shared")
+		return
+	}
+
+	err := h.store.Curricula().Update(r.Context(), c.ID, []store.Update{
+		{Path: "shareToken", Value: nil},
+		{Path: "shareExpiresAt", Value: nil},
+	})
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		slog.Error("failed to revoke share link", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to revoke share link")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get serves a shared curriculum and its elements to anyone holding the
+// token. Asset elements whose asset is gone or inactive lose their snapshot,
+// and references into the library are left out. Responses carry an ETag and
+// may be cached briefly.
+// GET /share/{token}
+func (h *ShareHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	token := chi.URLParam(r, "token")
+
+	found, err := h.store.Curricula().List(ctx, store.NewQuery().Where("shareToken", "==", token).Limit(1))
+	if err != nil {
+		slog.Error("failed to find shared curriculum", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get shared curriculum")
+		return
+	}
+	if len(found) == 0 {
+		writeError(w, http.StatusNotFound, "share link not found")
+		return
+	}
+	c := &found[0]
+	now := time.Now()
+	if !c.ShareExpiresAt.IsZero() && !now.Before(c.ShareExpiresAt) {
+		writeError(w, http.StatusGone, "share link has expired")
+		return
+	}
+
+	elements, err := h.store.Elements().List(ctx, c.ID, store.NewQuery())
+	if err != nil {
+		slog.Error("failed to list elements", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get shared curriculum")
+		return
+	}
+	elements, err = h.publicElements(ctx, elements)
+	if err != nil {
+		slog.Error("failed to check shared assets", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get shared curriculum")
+		return
+	}
+	shared := model.SharedCurriculum{
+		Title:                c.Title,
+		Description:          c.Description,
+		Duration:             c.Duration,
+		ElementCount:         c.ElementCount,
+		TotalDurationSeconds: c.TotalDurationSeconds,
+		TotalDuration:        duration.Format(c.TotalDurationSeconds),
+		UpdatedAt:            c.UpdatedAt,
+		Elements:             curriculum.Tree(elements),
+	}
+
+	body, err := json.Marshal(shared)
+	if err != nil {
+		slog.Error("failed to encode shared curriculum", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get shared curriculum")
+		return
+	}
+	maxAge := shareMaxAge
+	if !c.ShareExpiresAt.IsZero() {
+		maxAge = min(maxAge, c.ShareExpiresAt.Sub(now))
+	}
+
+	writeCached(w, r, "application/json", append(body, '\n'), maxAge)
+}
+
+// publicElements strips what a shared curriculum must not reveal: the IDs
+// of library entries and fork sources, snapshot tags, and the snapshots of
+// assets that are missing, inactive or detached.
+func (h *ShareHandler) publicElements(ctx context.Context, elements []model.CurriculumElement) ([]model.CurriculumElement, error) {
+	visible := map[string]bool{}
+	for i := range elements {
+		e := &elements[i]
+		if e.AssetID != nil {
+			id := *e.AssetID
+			if _, seen := visible[id]; !seen {
+				a, err := h.store.Assets().Get(ctx, id)
+				switch {
+				case errors.Is(err, store.ErrNotFound):
+					visible[id] = false
+				case err != nil:
+					return nil, err
+				default:
+					normalizeAsset(a)
+					visible[id] = a.Active
+				}
+			}
+			if !visible[id] {
+				e.Snapshot = nil
+			}
+		} else if e.Type == model.ElementTypeAsset || e.Type == model.ElementTypeClip {
+			// Detached from a deleted asset.
+			e.Snapshot = nil
+		}
+		if e.Snapshot != nil {
+			snap := *e.Snapshot
+			snap.TagIDs = nil
+			e.Snapshot = &snap
+		}
+		if e.Items == nil {
+			e.Items = []string{}
+		}
+		curriculum.SetClipLinks(e)
+		e.TechniqueID, e.AssetID, e.SourceElementID = nil, nil, ""
+	}
+	return elements, nil
+}
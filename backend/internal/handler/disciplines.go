@@ -5,44 +5,26 @@ import (
 	"log/slog"
 	"net/http"
 
-	"cloud.google.com/go/firestore"
 	"github.com/thomas/skillhive-api/internal/model"
-	"google.golang.org/api/iterator"
+	"github.com/thomas/skillhive-api/internal/store"
 )
 
 type DisciplineHandler struct {
-	fs *firestore.Client
+	store store.Store
 }
 
-func NewDisciplineHandler(fs *firestore.Client) *DisciplineHandler {
-	return &DisciplineHandler{fs: fs}
+func NewDisciplineHandler(s store.Store) *DisciplineHandler {
+	return &DisciplineHandler{store: s}
 }
 
 func (h *DisciplineHandler) List(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	iter := h.fs.Collection("disciplines").Documents(ctx)
-	defer iter.Stop()
-
-	var disciplines []model.Discipline
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			slog.Error("failed to list disciplines", "error", err)
-			http.Error(w, `{"error":"failed to list disciplines"}`, http.StatusInternalServerError)
-			return
-		}
-
-		var d model.Discipline
-		if err := doc.DataTo(&d); err != nil {
-			slog.Error("failed to parse discipline", "docID", doc.Ref.ID, "error", err)
-			continue
-		}
-		d.ID = doc.Ref.ID
-		disciplines = append(disciplines, d)
+	disciplines, err := h.store.Disciplines().List(ctx, store.NewQuery())
+	if err != nil {
+		slog.Error("failed to list disciplines", "error", err)
+		http.Error(w, `{"error":"failed to list disciplines"}`, http.StatusInternalServerError)
+		return
 	}
 
 	if disciplines == nil {
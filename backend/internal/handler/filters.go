@@ -0,0 +1,370 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/duration"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+// Tag match modes: "all" requires every tag, "any" at least one.
+const (
+	tagModeAll = "all"
+	tagModeAny = "any"
+)
+
+// maxFilterValues caps the number of values of one multi-valued filter.
+const maxFilterValues = 100
+
+// listFilter holds the combinable filters of the technique and asset lists.
+// Multi-valued filters accept repeated parameters (tagId=a&tagId=b) and
+// comma-separated lists (tagIds=a,b). Values of one filter are OR'ed, except
+// tags, which follow tagMode. Different filters are AND'ed.
+//
+// Firestore allows a single array-contains(-any) filter per query and only
+// the combinations backed by composite indexes, so pushDown hands the store
+// one array filter and everything is (re)checked in memory by match*.
+type listFilter struct {
+	techniqueIDs  []string
+	categoryIDs   []string
+	tagIDs        []string
+	tagMode       string
+	videoTypes    []string
+	originators   []string
+	statuses      []string
+	createdAfter  time.Time // inclusive
+	createdBefore time.Time // exclusive
+	// minSeconds and maxSeconds bound the duration of assets, inclusive;
+	// 0 leaves that end open.
+	minSeconds int
+	maxSeconds int
+}
+
+func parseListFilter(r *http.Request) (listFilter, error) {
+	q := r.URL.Query()
+	var f listFilter
+	var err error
+
+	if f.techniqueIDs, err = multiValue(q, "techniqueId", "techniqueIds"); err != nil {
+		return f, err
+	}
+	if f.categoryIDs, err = multiValue(q, "categoryId", "categoryIds"); err != nil {
+		return f, err
+	}
+	if f.tagIDs, err = multiValue(q, "tagId", "tagIds"); err != nil {
+		return f, err
+	}
+	if f.videoTypes, err = multiValue(q, "videoType"); err != nil {
+		return f, err
+	}
+	if f.originators, err = multiValue(q, "originator"); err != nil {
+		return f, err
+	}
+	if f.statuses, err = multiValue(q, "processingStatus"); err != nil {
+		return f, err
+	}
+
+	switch f.tagMode = q.Get("tagMode"); f.tagMode {
+	case "":
+		f.tagMode = tagModeAll
+	case tagModeAll, tagModeAny:
+	default:
+		return f, fmt.Errorf("tagMode must be all or any")
+	}
+
+	if f.createdAfter, err = timeParam(q, "createdAfter"); err != nil {
+		return f, err
+	}
+	if f.createdBefore, err = timeParam(q, "createdBefore"); err != nil {
+		return f, err
+	}
+	if !f.createdAfter.IsZero() && !f.createdBefore.IsZero() && !f.createdAfter.Before(f.createdBefore) {
+		return f, fmt.Errorf("createdAfter must be before createdBefore")
+	}
+
+	if f.minSeconds, err = durationParam(q, "minDuration"); err != nil {
+		return f, err
+	}
+	if f.maxSeconds, err = durationParam(q, "maxDuration"); err != nil {
+		return f, err
+	}
+	if f.minSeconds > 0 && f.maxSeconds > 0 && f.minSeconds > f.maxSeconds {
+		return f, fmt.Errorf("minDuration must not be longer than maxDuration")
+	}
+	return f, nil
+}
+
+// multiValue collects the non-empty, de-duplicated values of the given
+// parameters, splitting each on commas.
+func multiValue(q url.Values, names ...string) ([]string, error) {
+	var out []string
+	for _, name := range names {
+		for _, raw := range q[name] {
+			for _, v := range strings.Split(raw, ",") {
+				if v = strings.TrimSpace(v); v != "" && !slices.Contains(out, v) {
+					out = append(out, v)
+				}
+			}
+		}
+	}
+	if len(out) > maxFilterValues {
+		return nil, fmt.Errorf("%s accepts at most %d values", names[len(names)-1], maxFilterValues)
+	}
+	return out, nil
+}
+
+// timeParam parses an RFC 3339 timestamp or a YYYY-MM-DD date (midnight UTC).
+func timeParam(q url.Values, name string) (time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", name)
+}
+
+// durationParam parses a duration such as 5m or 1:30:00 into seconds.
+func durationParam(q url.Values, name string) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	seconds, ok := duration.Parse(v)
+	if !ok {
+		return 0, fmt.Errorf("%s must be a duration such as %s", name, duration.Examples)
+	}
+	return seconds, nil
+}
+
+func (f listFilter) arrayValues(field string) []string {
+	switch field {
+	case "techniqueIds":
+		return f.techniqueIDs
+	case "categoryIds":
+		return f.categoryIDs
+	case "tagIds":
+		return f.tagIDs
+	}
+	return nil
+}
+
+// pushDown adds the first pushable array filter of fields (in order of
+// preference) to q. exact reports whether q then expresses the whole filter,
+// so that nothing has to be checked in memory.
+func (f listFilter) pushDown(q store.Query, fields ...string) (_ store.Query, exact bool) {
+	active := f.activeFilters()
+	for _, field := range fields {
+		values := f.arrayValues(field)
+		switch {
+		case len(values) == 0:
+			continue
+		case len(values) == 1:
+			return q.Where(field, store.OpArrayContains, values[0]), active == 1
+		case field == "tagIds" && f.tagMode == tagModeAll:
+			return q.Where(field, store.OpArrayContains, values[0]), false
+		case len(values) <= store.MaxDisjunction:
+			return q.Where(field, store.OpArrayContainsAny, values), active == 1
+		}
+	}
+	return q, active == 0
+}
+
+// pushDownCreated adds the created-at range to q. Only valid for queries
+// ordered by createdAt first.
+func (f listFilter) pushDownCreated(q store.Query) store.Query {
+	if !f.createdAfter.IsZero() {
+		q = q.Where("createdAt", store.OpGreaterEqual, f.createdAfter)
+	}
+	if !f.createdBefore.IsZero() {
+		q = q.Where("createdAt", store.OpLess, f.createdBefore)
+	}
+	return q
+}
+
+// pushDownDuration adds the duration range to q. Only valid for queries
+// ordered by durationSeconds first.
+func (f listFilter) pushDownDuration(q store.Query) store.Query {
+	if f.minSeconds > 0 {
+		q = q.Where("durationSeconds", store.OpGreaterEqual, f.minSeconds)
+	}
+	if f.maxSeconds > 0 {
+		q = q.Where("durationSeconds", store.OpLessEqual, f.maxSeconds)
+	}
+	return q
+}
+
+// checkTechniqueFilter rejects the filters that only apply to assets, which
+// matchTechnique would otherwise ignore.
+func (f listFilter) checkTechniqueFilter() error {
+	for _, p := range []struct {
+		name string
+		set  bool
+	}{
+		{"techniqueIds", len(f.techniqueIDs) > 0},
+		{"videoType", len(f.videoTypes) > 0},
+		{"originator", len(f.originators) > 0},
+		{"processingStatus", len(f.statuses) > 0},
+		{"minDuration", f.minSeconds > 0},
+		{"maxDuration", f.maxSeconds > 0},
+	} {
+		if p.set {
+			return fmt.Errorf("%s only filters assets", p.name)
+		}
+	}
+	return nil
+}
+
+func (f listFilter) activeFilters() int {
+	n := 0
+	for _, values := range [][]string{f.techniqueIDs, f.categoryIDs, f.tagIDs, f.videoTypes, f.originators, f.statuses} {
+		if len(values) > 0 {
+			n++
+		}
+	}
+	if !f.createdAfter.IsZero() || !f.createdBefore.IsZero() {
+		n++
+	}
+	if f.minSeconds > 0 || f.maxSeconds > 0 {
+		n++
+	}
+	return n
+}
+
+func (f listFilter) matchTechnique(t *model.Technique) bool {
+	return matchAnyOf(t.CategoryIDs, f.categoryIDs) &&
+		f.matchTags(t.TagIDs) &&
+		f.matchCreated(t.CreatedAt)
+}
+
+func (f listFilter) matchAsset(a *model.Asset) bool {
+	return matchAnyOf(a.TechniqueIDs, f.techniqueIDs) &&
+		matchAnyOf(a.CategoryIDs, f.categoryIDs) &&
+		f.matchTags(a.TagIDs) &&
+		matchOptional(a.VideoType, f.videoTypes, false) &&
+		matchOptional(a.Originator, f.originators, true) &&
+		(len(f.statuses) == 0 || slices.Contains(f.statuses, a.ProcessingStatus)) &&
+		f.matchCreated(a.CreatedAt) &&
+		f.matchDuration(a)
+}
+
+// matchDuration excludes assets of unknown length once either bound is set.
+func (f listFilter) matchDuration(a *model.Asset) bool {
+	if f.minSeconds == 0 && f.maxSeconds == 0 {
+		return true
+	}
+	seconds, ok := assetSeconds(a)
+	return ok && seconds >= f.minSeconds && (f.maxSeconds == 0 || seconds <= f.maxSeconds)
+}
+
+func (f listFilter) matchTags(ids []string) bool {
+	if f.tagMode == tagModeAny {
+		return matchAnyOf(ids, f.tagIDs)
+	}
+	for _, want := range f.tagIDs {
+		if !slices.Contains(ids, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f listFilter) matchCreated(t time.Time) bool {
+	if !f.createdAfter.IsZero() && t.Before(f.createdAfter) {
+		return false
+	}
+	if !f.createdBefore.IsZero() && !t.Before(f.createdBefore) {
+		return false
+	}
+	return true
+}
+
+// matchAnyOf reports whether ids contains one of want (or want is empty).
+func matchAnyOf(ids, want []string) bool {
+	if len(want) == 0 {
+		return true
+	}
+	for _, id := range ids {
+		if slices.Contains(want, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOptional reports whether an optional string field equals one of want.
+func matchOptional(v *string, want []string, foldCase bool) bool {
+	if len(want) == 0 {
+		return true
+	}
+	if v == nil {
+		return false
+	}
+	for _, w := range want {
+		if *v == w || (foldCase && strings.EqualFold(*v, w)) {
+			return true
+		}
+	}
+	return false
+}
+
+func techniqueFacets(techniques []model.Technique) model.Facets {
+	facets := model.Facets{Tags: map[string]int{}, Categories: map[string]int{}}
+	for _, t := range techniques {
+		countIDs(facets.Tags, t.TagIDs)
+		countIDs(facets.Categories, t.CategoryIDs)
+	}
+	return facets
+}
+
+func assetFacets(assets []model.Asset) model.Facets {
+	facets := model.Facets{Tags: map[string]int{}, Categories: map[string]int{}, VideoTypes: map[string]int{}}
+	for _, a := range assets {
+		countIDs(facets.Tags, a.TagIDs)
+		countIDs(facets.Categories, a.CategoryIDs)
+		if a.VideoType != nil && *a.VideoType != "" {
+			facets.VideoTypes[*a.VideoType]++
+		}
+	}
+	return facets
+}
+
+func countIDs(counts map[string]int, ids []string) {
+	for _, id := range ids {
+		counts[id]++
+	}
+}
+
+// wantFacets reports whether the request asks for facet counts.
+func wantFacets(r *http.Request) bool {
+	return r.URL.Query().Get("facets") == "true"
+}
+
+// listFacets counts the facets of the whole filtered list for its first
+// page, of which items and next are the result. Later pages get nil, since
+// counting means reading the whole list again.
+func listFacets[T any](ctx context.Context, list func(context.Context, store.Query) ([]T, error), q store.Query, p pageRequest, items []T, next *store.Cursor, idOf func(*T) string, keep func(*T) bool, count func([]T) model.Facets) (*model.Facets, error) {
+	if p.after != nil || p.offset > 0 {
+		return nil, nil
+	}
+	all := items
+	if next != nil {
+		var err error
+		if all, _, err = listPage(ctx, list, q, pageRequest{}, idOf, keep); err != nil {
+			return nil, err
+		}
+	}
+	facets := count(all)
+	return &facets, nil
+}
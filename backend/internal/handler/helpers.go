@@ -1,9 +1,16 @@
 package handler
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/duration"
+	"github.com/thomas/skillhive-api/internal/validate"
 )
 
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
@@ -26,3 +33,35 @@ func decodeJSON(r *http.Request, v interface{}) error {
 	}
 	return nil
 }
+
+// writeCached writes a response that clients and shared caches may reuse
+// for maxAge, with an ETag so that they can revalidate it cheaply.
+func writeCached(w http.ResponseWriter, r *http.Request, contentType string, body []byte, maxAge time.Duration) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// parseDuration validates the duration field name and returns it in clock
+// notation with its seconds. An empty value gives nil for both, which clears
+// the field.
+func parseDuration(name, raw string) (*string, *int, error) {
+	raw = strings.TrimSpace(validate.StripAllHTML(raw))
+	if raw == "" {
+		return nil, nil, nil
+	}
+	text, seconds, ok := duration.Normalize(raw)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s must be a duration such as %s", name, duration.Examples)
+	}
+	return &text, &seconds, nil
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+// minScanChunk is the smallest number of documents read per store query
+// while filling a page that is filtered in memory.
+const minScanChunk = 100
+
+// pageRequest holds the pagination parameters of a list request:
+//
+//	limit   page size; without it the whole (remaining) list is returned
+//	cursor  opaque token from the rel="next" Link of the previous page
+//	offset  deprecated: skips that many items, billed as reads by Firestore
+type pageRequest struct {
+	limit  int
+	offset int
+	after  *store.Cursor
+}
+
+func parsePage(r *http.Request) (pageRequest, error) {
+	var p pageRequest
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			p.limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed > 0 {
+			p.offset = parsed
+		}
+	}
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		c, err := store.DecodeCursor(token)
+		if err != nil {
+			return p, err
+		}
+		p.after = &c
+	}
+	return p, nil
+}
+
+// listPage reads one page of the ordered query q. keep, when not nil,
+// filters documents in memory; the store is then read in chunks until the
+// page is full. next is set when more documents follow the page.
+func listPage[T any](ctx context.Context, list func(context.Context, store.Query) ([]T, error), q store.Query, p pageRequest, idOf func(*T) string, keep func(*T) bool) (items []T, next *store.Cursor, err error) {
+	if p.after != nil {
+		q = q.StartAfter(*p.after)
+	}
+
+	// Read one document past the page to learn whether there is a next one.
+	want := 0
+	if p.limit > 0 {
+		want = p.offset + p.limit + 1
+	}
+	chunk := want
+	if keep != nil && chunk > 0 {
+		chunk = max(chunk, minScanChunk)
+	}
+
+	items = []T{}
+	skipped := 0
+	for {
+		docs, err := list(ctx, q.Limit(chunk))
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range docs {
+			if keep != nil && !keep(&docs[i]) {
+				continue
+			}
+			if skipped < p.offset {
+				skipped++
+				continue
+			}
+			items = append(items, docs[i])
+		}
+		if chunk == 0 || len(docs) < chunk || (want > 0 && len(items) > p.limit) {
+			break
+		}
+		q = q.StartAfter(store.CursorAt(q, &docs[len(docs)-1], idOf(&docs[len(docs)-1])))
+	}
+
+	if p.limit > 0 && len(items) > p.limit {
+		items = items[:p.limit]
+		last := &items[len(items)-1]
+		c := store.CursorAt(q, last, idOf(last))
+		next = &c
+	}
+	return items, next, nil
+}
+
+// slicePage returns one page of items, which are already in list order.
+// Its cursors hold the position and ID of the last item of a page: the next
+// page starts after that item, or at its position once it is gone.
+func slicePage[T any](items []T, p pageRequest, idOf func(*T) string) (page []T, next *store.Cursor, err error) {
+	start := 0
+	if c := p.after; c != nil {
+		if len(c.Fields) != 1 || c.Fields[0] != "position" {
+			return nil, nil, store.ErrInvalidCursor
+		}
+		pos, ok := c.Values[0].(int64)
+		if !ok || pos < 0 {
+			return nil, nil, store.ErrInvalidCursor
+		}
+		start = int(pos)
+		if i := slices.IndexFunc(items, func(it T) bool { return idOf(&it) == c.ID }); i >= 0 {
+			start = i + 1
+		}
+	}
+	start = min(start+p.offset, len(items))
+	end := len(items)
+	if p.limit > 0 {
+		end = min(start+p.limit, len(items))
+	}
+	if end < len(items) {
+		next = &store.Cursor{Fields: []string{"position"}, Values: []interface{}{int64(end)}, ID: idOf(&items[end-1])}
+	}
+	return items[start:end], next, nil
+}
+
+// setNextLink advertises the next page in an RFC 8288 Link header. The
+// link repeats the request with the cursor replaced and offset dropped.
+func setNextLink(w http.ResponseWriter, r *http.Request, next *store.Cursor) {
+	if next == nil {
+		return
+	}
+	params := r.URL.Query()
+	params.Del("offset")
+	params.Set("cursor", next.Encode())
+	w.Header().Add("Link", "<"+r.URL.Path+"?"+params.Encode()+`>; rel="next"`)
+}
+
+// writeListError reports a failed list query. A cursor that does not fit
+// the query is a client error.
+func writeListError(w http.ResponseWriter, err error, what string) {
+	if errors.Is(err, store.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+	slog.Error("failed to list "+what, "error", err)
+	writeError(w, http.StatusInternalServerError, "failed to list "+what)
+}
@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/middleware"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+	"github.com/thomas/skillhive-api/internal/validate"
+)
+
+type ProgressHandler struct {
+	store store.Store
+}
+
+func NewProgressHandler(s store.Store) *ProgressHandler {
+	return &ProgressHandler{store: s}
+}
+
+// Get returns the user's progress through a curriculum they may view.
+// GET /api/v1/curricula/{id}/progress
+func (h *ProgressHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	c, ok := requireCurriculum(w, r, h.store, curriculum.AccessView)
+	if !ok {
+		return
+	}
+
+	elements, err := h.store.Elements().List(ctx, c.ID, store.NewQuery())
+	if err != nil {
+		slog.Error("failed to list elements", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get progress")
+		return
+	}
+	records, err := h.store.Progress().List(ctx, store.NewQuery().
+		Where("curriculumId", "==", c.ID).
+		Where("uid", "==", middleware.GetUserUID(ctx)))
+	if err != nil {
+		slog.Error("failed to list progress", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get progress")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, curriculum.Progress(c, elements, records))
+}
+
+// Update sets the user's own progress on an element of a curriculum they may
+// view.
+// PUT /api/v1/curricula/{id}/progress/{elemId}
+func (h *ProgressHandler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid := middleware.GetUserUID(ctx)
+	c, ok := requireCurriculum(w, r, h.store, curriculum.AccessView)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateProgressRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.State == nil && req.Notes == nil {
+		writeError(w, http.StatusBadRequest, "state or notes is required")
+		return
+	}
+	if req.State != nil && !slices.Contains(model.ProgressStates, *req.State) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("state must be one of: %s", strings.Join(model.ProgressStates, ", ")))
+		return
+	}
+	if req.Notes != nil {
+		if err := validate.MaxLength("notes", *req.Notes, 2000); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	e, err := h.store.Elements().Get(ctx, c.ID, chi.URLParam(r, "elemId"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "element not found")
+			return
+		}
+		slog.Error("failed to get element", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update progress")
+		return
+	}
+	if !curriculum.TakesProgress(e) {
+		writeError(w, http.StatusBadRequest, "sections take no progress")
+		return
+	}
+
+	id := model.ProgressID(uid, c.ID, e.ID)
+	var p model.Progress
+	err = h.store.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		p = model.Progress{}
+		err := tx.Get(h.store.Progress().Ref(id), &p)
+		if errors.Is(err, store.ErrNotFound) {
+			p = model.Progress{UID: uid, DisciplineID: c.DisciplineID, CurriculumID: c.ID, ElementID: e.ID, State: model.ProgressNotStarted}
+		} else if err != nil {
+			return err
+		}
+		now := time.Now()
+		if req.State != nil {
+			setProgressState(&p, *req.State, now)
+		}
+		if req.Notes != nil {
+			p.Notes = strings.TrimSpace(validate.StripAllHTML(*req.Notes))
+		}
+		p.UpdatedAt = now
+		tx.Set(h.store.Progress().Ref(id), &p)
+		return nil
+	})
+	if err != nil {
+		slog.Error("failed to update progress", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update progress")
+		return
+	}
+	p.ID = id
+
+	writeJSON(w, http.StatusOK, p)
+}
+
+// setProgressState moves p to state, stamping when it was first started and
+// when it was mastered.
+func setProgressState(p *model.Progress, state string, now time.Time) {
+	if state != model.ProgressNotStarted && p.StartedAt.IsZero() {
+		p.StartedAt = now
+	}
+	switch {
+	case state != model.ProgressMastered:
+		p.CompletedAt = time.Time{}
+	case p.State != model.ProgressMastered:
+		p.CompletedAt = now
+	}
+	p.State = state
+}
+
+// progressByCurriculum groups progress records by curriculum, in the order
+// the curricula first appear.
+func progressByCurriculum(records []model.Progress) ([]string, map[string][]model.Progress) {
+	var ids []string
+	groups := map[string][]model.Progress{}
+	for _, p := range records {
+		if _, ok := groups[p.CurriculumID]; !ok {
+			ids = append(ids, p.CurriculumID)
+		}
+		groups[p.CurriculumID] = append(groups[p.CurriculumID], p)
+	}
+	return ids, groups
+}
+
+// viewableWithElements loads a curriculum and its elements, or returns nil
+// when it is gone or uid may not view it.
+func viewableWithElements(ctx context.Context, s store.Store, id, uid string) (*model.Curriculum, []model.CurriculumElement, error) {
+	c, err := s.Curricula().Get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if curriculum.AccessOf(c, uid) == curriculum.AccessNone {
+		return nil, nil, nil
+	}
+	elements, err := s.Elements().List(ctx, id, store.NewQuery())
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, elements, nil
+}
+
+// List summarizes the user's progress through every curriculum they have
+// recorded progress on and may still view, optionally in one discipline,
+// most recently active first.
+// GET /api/v1/progress?disciplineId=
+func (h *ProgressHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid := middleware.GetUserUID(ctx)
+
+	query := store.NewQuery().Where("uid", "==", uid)
+	if disciplineID := r.URL.Query().Get("disciplineId"); disciplineID != "" {
+		query = query.Where("disciplineId", "==", disciplineID)
+	}
+	records, err := h.store.Progress().List(ctx, query)
+	if err != nil {
+		slog.Error("failed to list progress", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list progress")
+		return
+	}
+
+	ids, groups := progressByCurriculum(records)
+	summaries := []model.CurriculumProgress{}
+	for _, id := range ids {
+		c, elements, err := viewableWithElements(ctx, h.store, id, uid)
+		if err != nil {
+			slog.Error("failed to load curriculum", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to list progress")
+			return
+		}
+		if c == nil {
+			continue
+		}
+		p := curriculum.Progress(c, elements, groups[id])
+		if len(p.Elements) == 0 {
+			continue
+		}
+		p.Elements = nil
+		summaries = append(summaries, p)
+	}
+	slices.SortFunc(summaries, func(a, b model.CurriculumProgress) int {
+		if c := b.UpdatedAt.Compare(a.UpdatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.CurriculumID, b.CurriculumID)
+	})
+
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// Report aggregates the progress of all learners through the curricula of a
+// discipline, or one of them, for its editors. Curricula the editor may not
+// view are left out. Reports are sorted by title.
+// GET /api/v1/progress/report?disciplineId=&curriculumId=
+func (h *ProgressHandler) Report(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	disciplineID := r.URL.Query().Get("disciplineId")
+
+	if disciplineID == "" {
+		writeError(w, http.StatusBadRequest, "disciplineId query parameter is required")
+		return
+	}
+	if err := middleware.RequireEditor(ctx, disciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
+		return
+	}
+
+	query := store.NewQuery().Where("disciplineId", "==", disciplineID)
+	if curriculumID := r.URL.Query().Get("curriculumId"); curriculumID != "" {
+		query = query.Where("curriculumId", "==", curriculumID)
+	}
+	records, err := h.store.Progress().List(ctx, query)
+	if err != nil {
+		slog.Error("failed to list progress", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get progress report")
+		return
+	}
+
+	ids, groups := progressByCurriculum(records)
+	reports := []model.ProgressReport{}
+	for _, id := range ids {
+		c, elements, err := viewableWithElements(ctx, h.store, id, middleware.GetUserUID(ctx))
+		if err != nil {
+			slog.Error("failed to load curriculum", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to get progress report")
+			return
+		}
+		if c == nil {
+			continue
+		}
+		if rep := curriculum.Report(c, elements, groups[id]); rep.Learners > 0 {
+			reports = append(reports, rep)
+		}
+	}
+	slices.SortFunc(reports, func(a, b model.ProgressReport) int {
+		if c := strings.Compare(a.Title, b.Title); c != 0 {
+			return c
+		}
+		return strings.Compare(a.CurriculumID, b.CurriculumID)
+	})
+
+	writeJSON(w, http.StatusOK, reports)
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+	"github.com/thomas/skillhive-api/internal/validate"
+)
+
+// refField is a request field naming documents of one kind by ID.
+type refField struct {
+	name string
+	kind string
+	ids  []string
+}
+
+// refCheck checks that the IDs named by a request resolve to tags,
+// categories, techniques, assets and curricula of its discipline. The
+// documents of each kind are loaded in one batch.
+type refCheck struct {
+	disciplineID string
+	fields       []refField
+	// uid is the user curricula must be visible to.
+	uid string
+}
+
+func newRefCheck(disciplineID string) *refCheck {
+	return &refCheck{disciplineID: disciplineID}
+}
+
+// add queues the IDs of a field, which name documents of kind, for checking.
+func (c *refCheck) add(field, kind string, ids ...string) *refCheck {
+	if len(ids) > 0 {
+		c.fields = append(c.fields, refField{name: field, kind: kind, ids: ids})
+	}
+	return c
+}
+
+// viewer has curricula that uid may not view count as missing, as they are
+// to the user.
+func (c *refCheck) viewer(uid string) *refCheck {
+	c.uid = uid
+	return c
+}
+
+// refDocs are the techniques, assets and curricula a refCheck loaded, by ID.
+type refDocs struct {
+	techniques map[string]*model.Technique
+	assets     map[string]*model.Asset
+	curricula  map[string]*model.Curriculum
+}
+
+// run loads the named documents. It returns the error to respond with when a
+// field names a missing document or one of another discipline.
+func (c *refCheck) run(ctx context.Context, s store.Store) (*refDocs, *model.ReferenceError, error) {
+	// IDs that are not valid document IDs name nothing and are not looked up.
+	wanted := map[string][]string{}
+	for _, f := range c.fields {
+		for _, id := range f.ids {
+			if validate.FirestoreID(f.name, id) == nil && !slices.Contains(wanted[f.kind], id) {
+				wanted[f.kind] = append(wanted[f.kind], id)
+			}
+		}
+	}
+
+	docs := &refDocs{
+		techniques: map[string]*model.Technique{},
+		assets:     map[string]*model.Asset{},
+		curricula:  map[string]*model.Curriculum{},
+	}
+	disciplineOf := map[string]map[string]string{}
+	for kind, ids := range wanted {
+		found := map[string]string{}
+		switch kind {
+		case refTag:
+			tags, err := s.Tags().GetAll(ctx, ids)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, t := range tags {
+				found[t.ID] = t.DisciplineID
+			}
+		case refCategory:
+			categories, err := s.Categories().GetAll(ctx, ids)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, c := range categories {
+				found[c.ID] = c.DisciplineID
+			}
+		case refTechnique:
+			techniques, err := s.Techniques().GetAll(ctx, ids)
+			if err != nil {
+				return nil, nil, err
+			}
+			for i, t := range techniques {
+				found[t.ID] = t.DisciplineID
+				docs.techniques[t.ID] = &techniques[i]
+			}
+		case refAsset:
+			assets, err := s.Assets().GetAll(ctx, ids)
+			if err != nil {
+				return nil, nil, err
+			}
+			for i, a := range assets {
+				found[a.ID] = a.DisciplineID
+				docs.assets[a.ID] = &assets[i]
+			}
+		case refCurriculum:
+			curricula, err := s.Curricula().GetAll(ctx, ids)
+			if err != nil {
+				return nil, nil, err
+			}
+			for i, cur := range curricula {
+				if curriculum.AccessOf(&cur, c.uid) != curriculum.AccessNone {
+					found[cur.ID] = cur.DisciplineID
+					docs.curricula[cur.ID] = &curricula[i]
+				}
+			}
+		}
+		disciplineOf[kind] = found
+	}
+
+	var invalid []model.InvalidReference
+	for _, f := range c.fields {
+		bad := model.InvalidReference{Field: f.name}
+		for _, id := range f.ids {
+			disciplineID, ok := disciplineOf[f.kind][id]
+			switch {
+			case !ok:
+				bad.Missing = append(bad.Missing, id)
+			case disciplineID != c.disciplineID:
+				bad.OtherDiscipline = append(bad.OtherDiscipline, id)
+			}
+		}
+		if bad.Missing != nil || bad.OtherDiscipline != nil {
+			invalid = append(invalid, bad)
+		}
+	}
+	if invalid != nil {
+		return nil, &model.ReferenceError{
+			Error:  "references must name existing documents of this discipline",
+			Fields: invalid,
+		}, nil
+	}
+	return docs, nil, nil
+}
+
+// checkRefs runs c, writing the error response and returning false if the
+// request names a document it must not. failed is the message of a 500.
+func checkRefs(w http.ResponseWriter, ctx context.Context, s store.Store, c *refCheck, failed string) (*refDocs, bool) {
+	docs, invalid, err := c.run(ctx, s)
+	if err != nil {
+		slog.Error("failed to check references", "error", err)
+		writeError(w, http.StatusInternalServerError, failed)
+		return nil, false
+	}
+	if invalid != nil {
+		writeJSON(w, http.StatusBadRequest, invalid)
+		return nil, false
+	}
+	return docs, true
+}
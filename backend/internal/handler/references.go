@@ -0,0 +1,493 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/merge"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+// The kinds of referent.
+const (
+	refTag       = "tag"
+	refCategory  = "category"
+	refTechnique = "technique"
+	refAsset     = "asset"
+	// Curricula are only referenced, by sessions.
+	refCurriculum = "curriculum"
+)
+
+// DeletePolicies are the policies deletes follow when the request names
+// none. An empty policy is DeleteDetach.
+type DeletePolicies struct {
+	Tags       model.DeletePolicy
+	Categories model.DeletePolicy
+	Techniques model.DeletePolicy
+	Assets     model.DeletePolicy
+}
+
+// referent is a tag, category, technique or asset that other documents refer
+// to by ID. parentID is the parent of a category.
+type referent struct {
+	kind         string
+	id           string
+	disciplineID string
+	parentID     *string
+}
+
+// refers reports whether a curriculum element refers to ref: by its
+// techniqueId or assetId, or, for tags, by the tags of its snapshot.
+func (ref referent) refers(e *model.CurriculumElement) bool {
+	switch ref.kind {
+	case refTag:
+		return e.Snapshot != nil && slices.Contains(e.Snapshot.TagIDs, ref.id)
+	case refTechnique:
+		return e.TechniqueID != nil && *e.TechniqueID == ref.id
+	case refAsset:
+		return e.AssetID != nil && *e.AssetID == ref.id
+	}
+	return false
+}
+
+// replacement is what the references to a referent move to. Technique and
+// Asset are set for those kinds, to snapshot them.
+type replacement struct {
+	ID        string
+	Technique *model.Technique
+	Asset     *model.Asset
+}
+
+// usages are the documents that refer to a referent, as loaded to rewrite
+// them.
+type usages struct {
+	ref        referent
+	techniques []model.Technique
+	assets     []model.Asset
+	categories []model.Category
+	edges      []model.TechniqueEdge
+	curricula  []model.CurriculumUsage
+	// clips are the clip elements among the usages of an asset, by
+	// curriculum, to check against a replacement.
+	clips map[string][]model.CurriculumElement
+}
+
+// replaceID removes id from ids, putting the replacement in its place unless
+// there is none or ids hold it already.
+func replaceID(ids []string, id string, repl *replacement) []string {
+	if repl != nil {
+		return merge.RetargetIDs(ids, []string{id}, repl.ID)
+	}
+	out := []string{}
+	for _, v := range ids {
+		if v != id {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// findUsages loads the documents that refer to ref. Tags are found through
+// the allTagIds of curricula; technique and asset elements by reading the
+// curricula of the discipline.
+func findUsages(ctx context.Context, s store.Store, ref referent) (*usages, error) {
+	u := &usages{ref: ref, curricula: []model.CurriculumUsage{}, clips: map[string][]model.CurriculumElement{}}
+	has := func(field string) store.Query {
+		return store.NewQuery().Where(field, store.OpArrayContains, ref.id)
+	}
+	var err error
+	switch ref.kind {
+	case refTag:
+		if u.techniques, err = s.Techniques().List(ctx, has("tagIds")); err != nil {
+			return nil, err
+		}
+		u.assets, err = s.Assets().List(ctx, has("tagIds"))
+	case refCategory:
+		if u.techniques, err = s.Techniques().List(ctx, has("categoryIds")); err != nil {
+			return nil, err
+		}
+		if u.assets, err = s.Assets().List(ctx, has("categoryIds")); err != nil {
+			return nil, err
+		}
+		u.categories, err = s.Categories().List(ctx, store.NewQuery().Where("parentId", "==", ref.id))
+	case refTechnique:
+		if u.assets, err = s.Assets().List(ctx, has("techniqueIds")); err != nil {
+			return nil, err
+		}
+		for _, field := range []string{"fromId", "toId"} {
+			edges, err := s.TechniqueEdges().List(ctx, store.NewQuery().Where(field, "==", ref.id))
+			if err != nil {
+				return nil, err
+			}
+			u.edges = append(u.edges, edges...)
+		}
+	case refAsset:
+		u.edges, err = s.TechniqueEdges().List(ctx, has("assetIds"))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var q store.Query
+	switch ref.kind {
+	case refTag:
+		q = has("allTagIds")
+	case refTechnique, refAsset:
+		q = store.NewQuery().Where("disciplineId", "==", ref.disciplineID)
+	default:
+		return u, nil
+	}
+	err = curriculum.Each(ctx, s, q, func(c *model.Curriculum) error {
+		elements, err := s.Elements().List(ctx, c.ID, store.NewQuery())
+		if err != nil {
+			return err
+		}
+		cu := model.CurriculumUsage{
+			CurriculumID: c.ID,
+			Title:        c.Title,
+			Tagged:       ref.kind == refTag && slices.Contains(c.TagIDs, ref.id),
+			ElementIDs:   []string{},
+		}
+		for i := range elements {
+			if ref.refers(&elements[i]) {
+				cu.ElementIDs = append(cu.ElementIDs, elements[i].ID)
+				if elements[i].Type == model.ElementTypeClip {
+					u.clips[c.ID] = append(u.clips[c.ID], elements[i])
+				}
+			}
+		}
+		if cu.Tagged || len(cu.ElementIDs) > 0 {
+			u.curricula = append(u.curricula, cu)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// report lists the usages by ID. Total counts every referring document: a
+// curriculum once if its own tags refer, and each of its elements that does.
+func (u *usages) report() model.Usages {
+	r := model.Usages{
+		TechniqueIDs: []string{},
+		AssetIDs:     []string{},
+		CategoryIDs:  []string{},
+		EdgeIDs:      []string{},
+		Curricula:    u.curricula,
+	}
+	for _, t := range u.techniques {
+		r.TechniqueIDs = append(r.TechniqueIDs, t.ID)
+	}
+	for _, a := range u.assets {
+		r.AssetIDs = append(r.AssetIDs, a.ID)
+	}
+	for _, c := range u.categories {
+		r.CategoryIDs = append(r.CategoryIDs, c.ID)
+	}
+	for _, e := range u.edges {
+		r.EdgeIDs = append(r.EdgeIDs, e.ID)
+	}
+	r.Total = len(r.TechniqueIDs) + len(r.AssetIDs) + len(r.CategoryIDs) + len(r.EdgeIDs)
+	for _, c := range u.curricula {
+		r.Total += len(c.ElementIDs)
+		if c.Tagged {
+			r.Total++
+		}
+	}
+	return r
+}
+
+// clipConflicts lists the clip elements among the usages that do not fit
+// the replacement asset, as checkClip would reject them.
+func (u *usages) clipConflicts(repl *replacement) model.Usages {
+	r := model.Usages{
+		TechniqueIDs: []string{},
+		AssetIDs:     []string{},
+		CategoryIDs:  []string{},
+		EdgeIDs:      []string{},
+		Curricula:    []model.CurriculumUsage{},
+	}
+	for _, c := range u.curricula {
+		cu := model.CurriculumUsage{CurriculumID: c.CurriculumID, Title: c.Title, ElementIDs: []string{}}
+		for _, e := range u.clips[c.CurriculumID] {
+			if checkClip(repl.Asset, e.StartSeconds, e.EndSeconds) != nil {
+				cu.ElementIDs = append(cu.ElementIDs, e.ID)
+			}
+		}
+		if len(cu.ElementIDs) > 0 {
+			r.Curricula = append(r.Curricula, cu)
+			r.Total += len(cu.ElementIDs)
+		}
+	}
+	return r
+}
+
+// detachAsset unlinks an asset or clip element from its deleted asset. The
+// snapshot keeps the asset's name and description but not its links. Clips
+// cannot be without an asset, so they become text elements holding their
+// title and caption.
+func detachAsset(e *model.CurriculumElement) {
+	e.AssetID = nil
+	if e.Snapshot != nil {
+		snap := *e.Snapshot
+		snap.URL, snap.ThumbnailURL = "", ""
+		e.Snapshot = &snap
+	}
+	if e.Type != model.ElementTypeClip {
+		return
+	}
+	e.Type = model.ElementTypeText
+	if e.Title == nil && e.Snapshot != nil && e.Snapshot.Name != "" {
+		e.Title = &e.Snapshot.Name
+	}
+	if e.Details == nil {
+		e.Details = e.Caption
+	}
+	e.Snapshot, e.StartSeconds, e.EndSeconds, e.Caption = nil, nil, nil, nil
+}
+
+// apply rewrites the usages to refer to repl instead, or, when repl is nil,
+// removes the references: subcategories move up to the category's parent,
+// the edges of a technique are deleted, and elements keep their snapshot
+// but lose their techniqueId or assetId (see detachAsset). Each curriculum
+// is saved as a revision. Techniques and assets keep their updatedAt: only
+// their references change.
+func (u *usages) apply(ctx context.Context, s store.Store, repl *replacement, change curriculum.Change) error {
+	ref := u.ref
+	var writes []func(store.Batch)
+	update := func(r store.DocRef, updates ...store.Update) {
+		writes = append(writes, func(b store.Batch) { b.Update(r, updates) })
+	}
+	del := func(r store.DocRef) {
+		writes = append(writes, func(b store.Batch) { b.Delete(r) })
+	}
+
+	field := map[string]string{refTag: "tagIds", refCategory: "categoryIds", refTechnique: "techniqueIds"}[ref.kind]
+	for _, t := range u.techniques {
+		ids := map[string][]string{refTag: t.TagIDs, refCategory: t.CategoryIDs}[ref.kind]
+		update(s.Techniques().Ref(t.ID), store.Update{Path: field, Value: replaceID(ids, ref.id, repl)})
+	}
+	for _, a := range u.assets {
+		ids := map[string][]string{refTag: a.TagIDs, refCategory: a.CategoryIDs, refTechnique: a.TechniqueIDs}[ref.kind]
+		update(s.Assets().Ref(a.ID), store.Update{Path: field, Value: replaceID(ids, ref.id, repl)})
+	}
+	for _, c := range u.categories {
+		parentID := ref.parentID
+		if repl != nil {
+			parentID = &repl.ID
+		}
+		update(s.Categories().Ref(c.ID),
+			store.Update{Path: "parentId", Value: parentID},
+			store.Update{Path: "updatedAt", Value: change.At})
+	}
+
+	switch {
+	case ref.kind == refAsset:
+		for _, e := range u.edges {
+			update(s.TechniqueEdges().Ref(e.ID), store.Update{Path: "assetIds", Value: replaceID(e.AssetIDs, ref.id, repl)})
+		}
+	case repl == nil:
+		for _, e := range u.edges {
+			del(s.TechniqueEdges().Ref(e.ID))
+		}
+	case len(u.edges) > 0:
+		moved, removed, err := merge.PlanEdges(ctx, s, repl.ID, []string{ref.id})
+		if err != nil {
+			return err
+		}
+		for _, e := range moved {
+			update(s.TechniqueEdges().Ref(e.ID),
+				store.Update{Path: "fromId", Value: e.FromID},
+				store.Update{Path: "toId", Value: e.ToID},
+				store.Update{Path: "updatedAt", Value: change.At})
+		}
+		for _, id := range removed {
+			del(s.TechniqueEdges().Ref(id))
+		}
+	}
+	if err := store.CommitInBatches(ctx, s, writes); err != nil {
+		return err
+	}
+
+	rw := curriculum.Rewriter{Element: func(e *model.CurriculumElement) bool {
+		if !ref.refers(e) {
+			return false
+		}
+		switch {
+		case ref.kind == refTag:
+			e.Snapshot.TagIDs = replaceID(e.Snapshot.TagIDs, ref.id, repl)
+		case ref.kind == refTechnique && repl == nil:
+			e.TechniqueID = nil
+		case ref.kind == refTechnique:
+			e.TechniqueID, e.Snapshot = &repl.ID, curriculum.TechniqueSnapshot(repl.Technique)
+		case repl == nil:
+			detachAsset(e)
+		default:
+			e.AssetID, e.Snapshot = &repl.ID, curriculum.AssetSnapshot(repl.Asset)
+		}
+		return true
+	}}
+	if ref.kind == refTag {
+		rw.Tags = func(ids []string) ([]string, bool) {
+			if !slices.Contains(ids, ref.id) {
+				return ids, false
+			}
+			return replaceID(ids, ref.id, repl), true
+		}
+	}
+	for _, c := range u.curricula {
+		if _, _, err := curriculum.Rewrite(ctx, s, c.CurriculumID, rw, false, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadReplacement reports an error unless id names another referent of the
+// same kind and discipline as ref, and, for categories, not one below it.
+func loadReplacement(ctx context.Context, s store.Store, ref referent, id string) (repl *replacement, bad error, err error) {
+	bad = fmt.Errorf("replacementId must name another %s of this discipline", ref.kind)
+	if id == ref.id {
+		return nil, bad, nil
+	}
+	repl = &replacement{ID: id}
+	var disciplineID string
+	switch ref.kind {
+	case refTag:
+		var t *model.Tag
+		if t, err = s.Tags().Get(ctx, id); err == nil {
+			disciplineID = t.DisciplineID
+		}
+	case refCategory:
+		var c *model.Category
+		if c, err = s.Categories().Get(ctx, id); err == nil {
+			disciplineID = c.DisciplineID
+		}
+	case refTechnique:
+		if repl.Technique, err = s.Techniques().Get(ctx, id); err == nil {
+			disciplineID = repl.Technique.DisciplineID
+		}
+	case refAsset:
+		if repl.Asset, err = s.Assets().Get(ctx, id); err == nil {
+			disciplineID = repl.Asset.DisciplineID
+		}
+	}
+	if errors.Is(err, store.ErrNotFound) || err == nil && disciplineID != ref.disciplineID {
+		return nil, bad, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if ref.kind == refCategory && isCircular(ctx, s, ref.id, id) {
+		return nil, errors.New("replacementId must not be a subcategory of the category"), nil
+	}
+	return repl, nil, nil
+}
+
+// parseDeletePolicy reads the policy and replacementId query parameters of a
+// delete, defaulting to def.
+func parseDeletePolicy(r *http.Request, def model.DeletePolicy) (model.DeletePolicy, string, error) {
+	policy := model.DeletePolicy(r.URL.Query().Get("policy"))
+	replacementID := r.URL.Query().Get("replacementId")
+	switch {
+	case policy == "" && def != "":
+		policy = def
+	case policy == "":
+		policy = model.DeleteDetach
+	case policy != model.DeleteRestrict && policy != model.DeleteDetach && policy != model.DeleteReassign:
+		return "", "", errors.New("policy must be restrict, detach or reassign")
+	}
+	if policy == model.DeleteReassign && replacementID == "" {
+		return "", "", errors.New("replacementId is required to reassign")
+	}
+	if policy != model.DeleteReassign && replacementID != "" {
+		return "", "", errors.New("replacementId is only allowed to reassign")
+	}
+	return policy, replacementID, nil
+}
+
+// deleteReferences carries out the delete policy of the request for the
+// references to ref, before ref itself is deleted. It writes the error
+// response and returns false if ref must not be deleted.
+func deleteReferences(w http.ResponseWriter, r *http.Request, s store.Store, ref referent, def model.DeletePolicy) bool {
+	ctx := r.Context()
+	failed := "failed to delete " + ref.kind
+
+	policy, replacementID, err := parseDeletePolicy(r, def)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	var repl *replacement
+	if policy == model.DeleteReassign {
+		var bad error
+		repl, bad, err = loadReplacement(ctx, s, ref, replacementID)
+		if err != nil {
+			slog.Error("failed to get replacement", "error", err)
+			writeError(w, http.StatusInternalServerError, failed)
+			return false
+		}
+		if bad != nil {
+			writeError(w, http.StatusBadRequest, bad.Error())
+			return false
+		}
+	}
+
+	u, err := findUsages(ctx, s, ref)
+	if err != nil {
+		slog.Error("failed to find usages", "error", err)
+		writeError(w, http.StatusInternalServerError, failed)
+		return false
+	}
+	if policy == model.DeleteRestrict {
+		if report := u.report(); report.Total > 0 {
+			writeJSON(w, http.StatusConflict, model.UsageConflict{
+				Error:  fmt.Sprintf("the %s is in use", ref.kind),
+				Usages: report,
+			})
+			return false
+		}
+		return true
+	}
+	if repl != nil && ref.kind == refAsset {
+		if conflicts := u.clipConflicts(repl); conflicts.Total > 0 {
+			writeJSON(w, http.StatusConflict, model.UsageConflict{
+				Error:  "clips of the asset would not fit the replacement",
+				Usages: conflicts,
+			})
+			return false
+		}
+	}
+
+	action := model.RevisionDetach
+	if repl != nil {
+		action = model.RevisionReassign
+	}
+	if err := u.apply(ctx, s, repl, revisionChange(ctx, action, time.Now())); err != nil {
+		slog.Error("failed to rewrite references", "kind", ref.kind, "error", err)
+		writeError(w, http.StatusInternalServerError, failed)
+		return false
+	}
+	return true
+}
+
+// writeUsages responds with the usages of ref.
+func writeUsages(w http.ResponseWriter, ctx context.Context, s store.Store, ref referent) {
+	u, err := findUsages(ctx, s, ref)
+	if err != nil {
+		slog.Error("failed to find usages", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to find usages")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, u.report())
+}
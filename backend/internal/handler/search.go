@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/thomas/skillhive-api/internal/middleware"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/search"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type SearchHandler struct {
+	index *search.Index
+}
+
+func NewSearchHandler(ix *search.Index) *SearchHandler {
+	return &SearchHandler{index: ix}
+}
+
+// Search ranks techniques, assets and curricula of a discipline against q.
+// types is an optional comma-separated subset of technique, asset, curriculum.
+// Private curricula are only found by their owner and collaborators.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	disciplineID := r.URL.Query().Get("disciplineId")
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	if disciplineID == "" {
+		writeError(w, http.StatusBadRequest, "disciplineId query parameter is required")
+		return
+	}
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "q query parameter is required")
+		return
+	}
+	if len(q) > 200 {
+		writeError(w, http.StatusBadRequest, "q must be at most 200 characters")
+		return
+	}
+
+	var types []string
+	if t := r.URL.Query().Get("types"); t != "" {
+		for _, typ := range strings.Split(t, ",") {
+			switch typ = strings.TrimSpace(typ); typ {
+			case model.SearchTypeTechnique, model.SearchTypeAsset, model.SearchTypeCurriculum:
+				types = append(types, typ)
+			default:
+				writeError(w, http.StatusBadRequest, "types must be a comma-separated list of technique, asset, curriculum")
+				return
+			}
+		}
+	}
+
+	limit := defaultSearchLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = min(parsed, maxSearchLimit)
+		}
+	}
+
+	// Search uses the raw query so a trailing space can end prefix matching.
+	writeJSON(w, http.StatusOK, h.index.Search(disciplineID, r.URL.Query().Get("q"), types, limit,
+		middleware.GetUserUID(r.Context())))
+}
@@ -0,0 +1,646 @@
+package handler
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/duration"
+	"github.com/thomas/skillhive-api/internal/middleware"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/schedule"
+	"github.com/thomas/skillhive-api/internal/store"
+	"github.com/thomas/skillhive-api/internal/validate"
+)
+
+const (
+	// defaultSessionSeconds is the length of a session whose curriculum has
+	// no duration.
+	defaultSessionSeconds = 3600
+	// maxSessionSeconds bounds the length of one occurrence.
+	maxSessionSeconds = 24 * 3600
+	// maxScheduleDays bounds the window of the schedule query.
+	maxScheduleDays = 92
+)
+
+type SessionHandler struct {
+	store store.Store
+}
+
+func NewSessionHandler(s store.Store) *SessionHandler {
+	return &SessionHandler{store: s}
+}
+
+// normalizeSession reports the start of a session in its own time zone.
+func normalizeSession(s *model.Session) {
+	if loc, err := time.LoadLocation(s.TimeZone); err == nil {
+		s.StartsAt = s.StartsAt.In(loc)
+	}
+}
+
+// parseTimeZone resolves an IANA time zone name, UTC when empty.
+func parseTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return nil, errors.New("timeZone must be an IANA time zone such as Europe/Berlin")
+	}
+	return loc, nil
+}
+
+// parseStartsAt reads a time with an offset, or a local time in loc.
+func parseStartsAt(raw string, loc *time.Location) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.In(loc), nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, raw, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("startsAt must be a time such as 2026-10-19T18:30 or 2026-10-19T18:30:00+02:00")
+}
+
+// parseRecurrence validates a recurrence rule and returns it in canonical
+// form; empty means a single occurrence.
+func parseRecurrence(raw string, loc *time.Location) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	rule, err := schedule.ParseRule(raw, loc)
+	if err != nil {
+		return "", err
+	}
+	return rule.String(), nil
+}
+
+// checkRecurrenceStart checks that startsAt, in loc, is an occurrence of
+// the canonical recurrence, as calendar apps take it to be one.
+func checkRecurrenceStart(startsAt time.Time, loc *time.Location, recurrence string) error {
+	if recurrence == "" {
+		return nil
+	}
+	rule, err := schedule.ParseRule(recurrence, loc)
+	if err != nil {
+		return err
+	}
+	return rule.CheckStart(startsAt.In(loc))
+}
+
+// sessionLength validates the duration of a session.
+func sessionLength(raw string) (string, int, error) {
+	text, seconds, err := parseDuration("duration", raw)
+	if err != nil {
+		return "", 0, err
+	}
+	if seconds == nil || *seconds < 60 || *seconds > maxSessionSeconds {
+		return "", 0, errors.New("duration must be between 1 minute and 24 hours")
+	}
+	return *text, *seconds, nil
+}
+
+// sessionEnd returns the end of the last occurrence of a session, given its
+// canonical recurrence, to store as its endsAt.
+func sessionEnd(startsAt time.Time, loc *time.Location, recurrence string, seconds int) time.Time {
+	var rule *schedule.Rule
+	if recurrence != "" {
+		var err error
+		if rule, err = schedule.ParseRule(recurrence, loc); err != nil {
+			return schedule.Forever
+		}
+	}
+	return schedule.End(startsAt.In(loc), rule, time.Duration(seconds)*time.Second).UTC()
+}
+
+// curriculumViews loads the curricula of sessions once each and reports
+// those a user may view.
+type curriculumViews struct {
+	store store.Store
+	uid   string
+	seen  map[string]*model.Curriculum
+}
+
+func newCurriculumViews(s store.Store, uid string) *curriculumViews {
+	return &curriculumViews{store: s, uid: uid, seen: map[string]*model.Curriculum{}}
+}
+
+// get returns the curriculum if the user may view it, or nil when it is
+// hidden from them or gone.
+func (v *curriculumViews) get(ctx context.Context, id string) (*model.Curriculum, error) {
+	if c, ok := v.seen[id]; ok {
+		return c, nil
+	}
+	c, err := v.store.Curricula().Get(ctx, id)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c = nil
+	case err != nil:
+		return nil, err
+	case curriculum.AccessOf(c, v.uid) == curriculum.AccessNone:
+		c = nil
+	}
+	v.seen[id] = c
+	return c, nil
+}
+
+// sessionTitle is the title of a session in calendars.
+func sessionTitle(s *model.Session, c *model.Curriculum) string {
+	if s.Title != "" {
+		return s.Title
+	}
+	return c.Title
+}
+
+// List lists the sessions of a discipline, optionally of one curriculum,
+// whose curriculum the user may view, earliest first.
+// GET /api/v1/sessions?disciplineId=&curriculumId=
+func (h *SessionHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	disciplineID := r.URL.Query().Get("disciplineId")
+	curriculumID := r.URL.Query().Get("curriculumId")
+
+	if disciplineID == "" {
+		writeError(w, http.StatusBadRequest, "disciplineId query parameter is required")
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+
+	query := store.NewQuery().Where("disciplineId", "==", disciplineID)
+	if curriculumID != "" {
+		query = query.Where("curriculumId", "==", curriculumID)
+	}
+	query = query.OrderBy("startsAt", store.Asc)
+
+	views := newCurriculumViews(h.store, middleware.GetUserUID(ctx))
+	var viewErr error
+	keep := func(s *model.Session) bool {
+		c, err := views.get(ctx, s.CurriculumID)
+		if err != nil && viewErr == nil {
+			viewErr = err
+		}
+		return c != nil
+	}
+
+	sessions, next, err := listPage(ctx, h.store.Sessions().List, query, page,
+		func(s *model.Session) string { return s.ID }, keep)
+	if err == nil {
+		err = viewErr
+	}
+	if err != nil {
+		writeListError(w, err, "sessions")
+		return
+	}
+
+	for i := range sessions {
+		normalizeSession(&sessions[i])
+	}
+	setNextLink(w, r, next)
+
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+// requireSession loads the session named in the URL, reporting it as not
+// found when the user may not view its curriculum. Sessions of a deleted
+// curriculum remain visible so that they can be removed.
+func (h *SessionHandler) requireSession(w http.ResponseWriter, r *http.Request) (*model.Session, bool) {
+	s, _, ok := h.loadSession(w, r)
+	return s, ok
+}
+
+// requireSessionEditor loads the session named in the URL for an editor of
+// its discipline to change. As on Create, the editor must be able to view
+// the curriculum; once it is deleted, only the session's owner may change
+// the session.
+func (h *SessionHandler) requireSessionEditor(w http.ResponseWriter, r *http.Request) (*model.Session, bool) {
+	ctx := r.Context()
+	s, c, ok := h.loadSession(w, r)
+	if !ok {
+		return nil, false
+	}
+	if err := middleware.RequireEditor(ctx, s.DisciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
+		return nil, false
+	}
+	if c == nil && s.OwnerUID != middleware.GetUserUID(ctx) {
+		writeError(w, http.StatusNotFound, "session not found")
+		return nil, false
+	}
+	return s, true
+}
+
+// loadSession loads the session named in the URL and its curriculum, nil
+// when deleted, failing when the user may not view the curriculum.
+func (h *SessionHandler) loadSession(w http.ResponseWriter, r *http.Request) (*model.Session, *model.Curriculum, bool) {
+	ctx := r.Context()
+	s, err := h.store.Sessions().Get(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "session not found")
+		} else {
+			slog.Error("failed to get session", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to get session")
+		}
+		return nil, nil, false
+	}
+	c, err := h.store.Curricula().Get(ctx, s.CurriculumID)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return s, nil, true
+	case err != nil:
+		slog.Error("failed to get session curriculum", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get session")
+		return nil, nil, false
+	case curriculum.AccessOf(c, middleware.GetUserUID(ctx)) == curriculum.AccessNone:
+		writeError(w, http.StatusNotFound, "session not found")
+		return nil, nil, false
+	}
+	return s, c, true
+}
+
+// Get returns a session.
+// GET /api/v1/sessions/{id}
+func (h *SessionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.requireSession(w, r)
+	if !ok {
+		return
+	}
+	normalizeSession(s)
+
+	writeJSON(w, http.StatusOK, s)
+}
+
+// Create schedules a curriculum the user may view in the discipline.
+// POST /api/v1/sessions?disciplineId=
+func (h *SessionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid := middleware.GetUserUID(ctx)
+	disciplineID := r.URL.Query().Get("disciplineId")
+
+	if disciplineID == "" {
+		writeError(w, http.StatusBadRequest, "disciplineId query parameter is required")
+		return
+	}
+
+	if err := middleware.RequireEditor(ctx, disciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
+		return
+	}
+
+	var req model.CreateSessionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate.Required("curriculumId", req.CurriculumID); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validate.Required("startsAt", req.StartsAt); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validate.MaxLength("title", req.Title, 200); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validate.MaxLength("location", req.Location, 200); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	loc, err := parseTimeZone(req.TimeZone)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	startsAt, err := parseStartsAt(req.StartsAt, loc)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	recurrence, err := parseRecurrence(req.Recurrence, loc)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := checkRecurrenceStart(startsAt, loc, recurrence); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	refs := newRefCheck(disciplineID).viewer(uid).add("curriculumId", refCurriculum, req.CurriculumID)
+	docs, ok := checkRefs(w, ctx, h.store, refs, "failed to create session")
+	if !ok {
+		return
+	}
+	c := docs.curricula[req.CurriculumID]
+
+	seconds := cmp.Or(c.DurationSeconds, c.TotalDurationSeconds, defaultSessionSeconds)
+	length := duration.Format(seconds)
+	if req.Duration != nil {
+		if length, seconds, err = sessionLength(*req.Duration); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	} else if seconds > maxSessionSeconds {
+		seconds, length = maxSessionSeconds, duration.Format(maxSessionSeconds)
+	}
+
+	now := time.Now()
+	s := model.Session{
+		DisciplineID:    disciplineID,
+		CurriculumID:    c.ID,
+		Title:           strings.TrimSpace(validate.StripAllHTML(req.Title)),
+		StartsAt:        startsAt.UTC(),
+		TimeZone:        loc.String(),
+		Duration:        length,
+		DurationSeconds: seconds,
+		Recurrence:      recurrence,
+		EndsAt:          sessionEnd(startsAt, loc, recurrence, seconds),
+		Location:        strings.TrimSpace(validate.StripAllHTML(req.Location)),
+		OwnerUID:        uid,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	id, err := h.store.Sessions().Create(ctx, &s)
+	if err != nil {
+		slog.Error("failed to create session", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to create session")
+		return
+	}
+	s.ID = id
+	normalizeSession(&s)
+
+	writeJSON(w, http.StatusCreated, s)
+}
+
+// Update changes a session. A new time zone keeps the wall-clock start time
+// unless startsAt is given too.
+// PATCH /api/v1/sessions/{id}
+func (h *SessionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	existing, ok := h.requireSessionEditor(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateSessionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updates := []store.Update{{Path: "updatedAt", Value: time.Now()}}
+
+	if req.Title != nil {
+		if err := validate.MaxLength("title", *req.Title, 200); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		updates = append(updates, store.Update{Path: "title", Value: strings.TrimSpace(validate.StripAllHTML(*req.Title))})
+	}
+	if req.Location != nil {
+		if err := validate.MaxLength("location", *req.Location, 200); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		updates = append(updates, store.Update{Path: "location", Value: strings.TrimSpace(validate.StripAllHTML(*req.Location))})
+	}
+	// The start, length and recurrence determine endsAt.
+	startsAt, seconds, recurrence := existing.StartsAt, existing.DurationSeconds, existing.Recurrence
+	if req.Duration != nil {
+		var length string
+		var err error
+		if length, seconds, err = sessionLength(*req.Duration); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		updates = append(updates,
+			store.Update{Path: "duration", Value: length},
+			store.Update{Path: "durationSeconds", Value: seconds})
+	}
+
+	loc, err := parseTimeZone(existing.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	if req.TimeZone != nil {
+		newLoc, err := parseTimeZone(*req.TimeZone)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if req.StartsAt == nil {
+			// Keep the wall-clock time in the new zone.
+			t := existing.StartsAt.In(loc)
+			startsAt = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, newLoc)
+			updates = append(updates, store.Update{Path: "startsAt", Value: startsAt.UTC()})
+		}
+		loc = newLoc
+		updates = append(updates, store.Update{Path: "timeZone", Value: loc.String()})
+	}
+	if req.StartsAt != nil {
+		if startsAt, err = parseStartsAt(*req.StartsAt, loc); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		updates = append(updates, store.Update{Path: "startsAt", Value: startsAt.UTC()})
+	}
+	if req.Recurrence != nil {
+		if recurrence, err = parseRecurrence(*req.Recurrence, loc); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		updates = append(updates, store.Update{Path: "recurrence", Value: recurrence})
+	}
+	if req.StartsAt != nil || req.TimeZone != nil || req.Recurrence != nil {
+		if err := checkRecurrenceStart(startsAt, loc, recurrence); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	updates = append(updates, store.Update{Path: "endsAt", Value: sessionEnd(startsAt, loc, recurrence, seconds)})
+
+	if err := h.store.Sessions().Update(ctx, existing.ID, updates); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "session not found")
+			return
+		}
+		slog.Error("failed to update session", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update session")
+		return
+	}
+
+	updated, err := h.store.Sessions().Get(ctx, existing.ID)
+	if err != nil {
+		slog.Error("failed to get updated session", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get updated session")
+		return
+	}
+	normalizeSession(updated)
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// Delete removes a session.
+// DELETE /api/v1/sessions/{id}
+func (h *SessionHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	existing, ok := h.requireSessionEditor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Sessions().Delete(ctx, existing.ID); err != nil {
+		slog.Error("failed to delete session", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete session")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseScheduleTime reads a schedule bound: a date, the start of that day
+// in loc, or a time with an offset.
+func parseScheduleTime(name, raw string, loc *time.Location) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", raw, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.In(loc), nil
+	}
+	return time.Time{}, fmt.Errorf("%s must be a date such as 2026-10-19 or an RFC 3339 time", name)
+}
+
+// Schedule lists the occurrences of the sessions of a discipline that
+// overlap a window, by default the current week (Monday to Monday) in tz,
+// earliest first.
+// GET /api/v1/schedule?disciplineId=&from=&to=&tz=
+func (h *SessionHandler) Schedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := r.URL.Query()
+	disciplineID := params.Get("disciplineId")
+
+	if disciplineID == "" {
+		writeError(w, http.StatusBadRequest, "disciplineId query parameter is required")
+		return
+	}
+	loc, err := parseTimeZone(params.Get("tz"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, strings.Replace(err.Error(), "timeZone", "tz", 1))
+		return
+	}
+
+	today := time.Now().In(loc)
+	from := time.Date(today.Year(), today.Month(), today.Day()-(int(today.Weekday())+6)%7, 0, 0, 0, 0, loc)
+	if raw := params.Get("from"); raw != "" {
+		if from, err = parseScheduleTime("from", raw, loc); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	to := from.AddDate(0, 0, 7)
+	if raw := params.Get("to"); raw != "" {
+		if to, err = parseScheduleTime("to", raw, loc); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if !to.After(from) {
+		writeError(w, http.StatusBadRequest, "to must be after from")
+		return
+	}
+	if to.After(from.AddDate(0, 0, maxScheduleDays)) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("the schedule spans at most %d days", maxScheduleDays))
+		return
+	}
+
+	// Queries take one range filter: sessions that ended before the window,
+	// which grow with history, are left out here, and those that start after
+	// it below.
+	sessions, err := h.store.Sessions().List(ctx, store.NewQuery().
+		Where("disciplineId", "==", disciplineID).
+		Where("endsAt", ">", from.UTC()).
+		OrderBy("endsAt", store.Asc))
+	if err != nil {
+		slog.Error("failed to list sessions", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get schedule")
+		return
+	}
+
+	views := newCurriculumViews(h.store, middleware.GetUserUID(ctx))
+	occurrences := []model.Occurrence{}
+	for i := range sessions {
+		s := &sessions[i]
+		if !s.StartsAt.Before(to) {
+			continue
+		}
+		c, err := views.get(ctx, s.CurriculumID)
+		if err != nil {
+			slog.Error("failed to get session curriculum", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to get schedule")
+			return
+		}
+		if c == nil {
+			continue
+		}
+		starts, err := sessionOccurrences(s, from, to)
+		if err != nil {
+			slog.Warn("skipping session with invalid schedule", "sessionID", s.ID, "error", err)
+			continue
+		}
+		length := time.Duration(s.DurationSeconds) * time.Second
+		for _, t := range starts {
+			occurrences = append(occurrences, model.Occurrence{
+				SessionID:    s.ID,
+				CurriculumID: s.CurriculumID,
+				Title:        sessionTitle(s, c),
+				StartsAt:     t.In(loc),
+				EndsAt:       t.Add(length).In(loc),
+				Location:     s.Location,
+			})
+		}
+	}
+	slices.SortStableFunc(occurrences, func(a, b model.Occurrence) int {
+		if c := a.StartsAt.Compare(b.StartsAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.SessionID, b.SessionID)
+	})
+
+	writeJSON(w, http.StatusOK, occurrences)
+}
+
+// sessionOccurrences expands a stored session within [from, to).
+func sessionOccurrences(s *model.Session, from, to time.Time) ([]time.Time, error) {
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	var rule *schedule.Rule
+	if s.Recurrence != "" {
+		if rule, err = schedule.ParseRule(s.Recurrence, loc); err != nil {
+			return nil, err
+		}
+	}
+	length := time.Duration(s.DurationSeconds) * time.Second
+	return schedule.Occurrences(s.StartsAt.In(loc), rule, length, from, to), nil
+}
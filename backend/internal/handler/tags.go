@@ -1,26 +1,25 @@
 package handler
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/go-chi/chi/v5"
 	"github.com/thomas/skillhive-api/internal/middleware"
 	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
 	"github.com/thomas/skillhive-api/internal/validate"
-	"google.golang.org/api/iterator"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type TagHandler struct {
-	fs *firestore.Client
+	store        store.Store
+	deletePolicy model.DeletePolicy
 }
 
-func NewTagHandler(fs *firestore.Client) *TagHandler {
-	return &TagHandler{fs: fs}
+func NewTagHandler(s store.Store, deletePolicy model.DeletePolicy) *TagHandler {
+	return &TagHandler{store: s, deletePolicy: deletePolicy}
 }
 
 func (h *TagHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -32,33 +31,23 @@ func (h *TagHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := h.fs.Collection("tags").
-		Where("disciplineId", "==", disciplineID).
-		OrderBy("name", firestore.Asc)
-
-	iter := query.Documents(ctx)
-	defer iter.Stop()
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
 
-	tags := []model.Tag{}
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			slog.Error("failed to list tags", "error", err)
-			writeError(w, http.StatusInternalServerError, "failed to list tags")
-			return
-		}
+	query := store.NewQuery().
+		Where("disciplineId", "==", disciplineID).
+		OrderBy("name", store.Asc)
 
-		var t model.Tag
-		if err := doc.DataTo(&t); err != nil {
-			slog.Error("failed to parse tag", "docID", doc.Ref.ID, "error", err)
-			continue
-		}
-		t.ID = doc.Ref.ID
-		tags = append(tags, t)
+	tags, next, err := listPage(ctx, h.store.Tags().List, query, page,
+		func(t *model.Tag) string { return t.ID }, nil)
+	if err != nil {
+		writeListError(w, err, "tags")
+		return
 	}
+	setNextLink(w, r, next)
 
 	writeJSON(w, http.StatusOK, tags)
 }
@@ -67,9 +56,9 @@ func (h *TagHandler) Get(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
-	doc, err := h.fs.Collection("tags").Doc(id).Get(ctx)
+	t, err := h.store.Tags().Get(ctx, id)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "tag not found")
 			return
 		}
@@ -78,13 +67,6 @@ func (h *TagHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var t model.Tag
-	if err := doc.DataTo(&t); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse tag")
-		return
-	}
-	t.ID = doc.Ref.ID
-
 	writeJSON(w, http.StatusOK, t)
 }
 
@@ -125,14 +107,10 @@ func (h *TagHandler) Create(w http.ResponseWriter, r *http.Request) {
 	slug := validate.GenerateSlug(req.Name)
 
 	// Check slug uniqueness within discipline
-	existing := h.fs.Collection("tags").
-		Where("disciplineId", "==", disciplineID).
-		Where("slug", "==", slug).
-		Limit(1).
-		Documents(ctx)
-	defer existing.Stop()
-
-	if doc, err := existing.Next(); err == nil && doc != nil {
+	if exists, err := h.slugExists(r, disciplineID, slug); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create tag")
+		return
+	} else if exists {
 		writeError(w, http.StatusConflict, "a tag with this name already exists in this discipline")
 		return
 	}
@@ -149,35 +127,36 @@ func (h *TagHandler) Create(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:    now,
 	}
 
-	ref, _, err := h.fs.Collection("tags").Add(ctx, map[string]interface{}{
-		"disciplineId": t.DisciplineID,
-		"name":         t.Name,
-		"slug":         t.Slug,
-		"description":  t.Description,
-		"color":        t.Color,
-		"ownerUid":     t.OwnerUID,
-		"createdAt":    t.CreatedAt,
-		"updatedAt":    t.UpdatedAt,
-	})
-	if err != nil {
+	if _, err := h.store.Tags().Create(ctx, &t); err != nil {
 		slog.Error("failed to create tag", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to create tag")
 		return
 	}
 
-	t.ID = ref.ID
 	writeJSON(w, http.StatusCreated, t)
 }
 
+// slugExists reports whether a tag with the slug exists in the discipline.
+func (h *TagHandler) slugExists(r *http.Request, disciplineID, slug string) (bool, error) {
+	existing, err := h.store.Tags().List(r.Context(), store.NewQuery().
+		Where("disciplineId", "==", disciplineID).
+		Where("slug", "==", slug).
+		Limit(1))
+	if err != nil {
+		slog.Error("failed to check tag slug", "error", err)
+		return false, err
+	}
+	return len(existing) > 0, nil
+}
+
 func (h *TagHandler) Update(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	uid := middleware.GetUserUID(ctx)
 	id := chi.URLParam(r, "id")
 
-	ref := h.fs.Collection("tags").Doc(id)
-	doc, err := ref.Get(ctx)
+	existing, err := h.store.Tags().Get(ctx, id)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "tag not found")
 			return
 		}
@@ -185,32 +164,24 @@ func (h *TagHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var existing model.Tag
-	if err := doc.DataTo(&existing); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse tag")
-		return
-	}
-
 	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
 		writeError(w, http.StatusForbidden, "editor role required")
 		return
 	}
 
-	_ = uid // kept for ownership transfer below
-
 	var req model.UpdateTagRequest
 	if err := decodeJSON(r, &req); err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	updates := []firestore.Update{
+	updates := []store.Update{
 		{Path: "updatedAt", Value: time.Now()},
 	}
 
 	// Transfer ownership from system to user on edit
 	if existing.OwnerUID == "system" {
-		updates = append(updates, firestore.Update{Path: "ownerUid", Value: uid})
+		updates = append(updates, store.Update{Path: "ownerUid", Value: uid})
 	}
 
 	if req.Name != nil {
@@ -223,21 +194,18 @@ func (h *TagHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 		// Check slug uniqueness if name changed
 		if slug != existing.Slug {
-			check := h.fs.Collection("tags").
-				Where("disciplineId", "==", existing.DisciplineID).
-				Where("slug", "==", slug).
-				Limit(1).
-				Documents(ctx)
-			defer check.Stop()
-			if d, err := check.Next(); err == nil && d != nil {
+			if exists, err := h.slugExists(r, existing.DisciplineID, slug); err != nil {
+				writeError(w, http.StatusInternalServerError, "failed to update tag")
+				return
+			} else if exists {
 				writeError(w, http.StatusConflict, "a tag with this name already exists in this discipline")
 				return
 			}
 		}
 
 		updates = append(updates,
-			firestore.Update{Path: "name", Value: name},
-			firestore.Update{Path: "slug", Value: slug},
+			store.Update{Path: "name", Value: name},
+			store.Update{Path: "slug", Value: slug},
 		)
 	}
 	if req.Description != nil {
@@ -246,32 +214,25 @@ func (h *TagHandler) Update(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		updates = append(updates, firestore.Update{Path: "description", Value: desc})
+		updates = append(updates, store.Update{Path: "description", Value: desc})
 	}
 	if req.Color != nil {
-		updates = append(updates, firestore.Update{Path: "color", Value: req.Color})
+		updates = append(updates, store.Update{Path: "color", Value: req.Color})
 	}
 
-	if _, err := ref.Update(ctx, updates); err != nil {
+	if err := h.store.Tags().Update(ctx, id, updates); err != nil {
 		slog.Error("failed to update tag", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to update tag")
 		return
 	}
 
 	// Fetch updated document
-	updatedDoc, err := ref.Get(ctx)
+	updated, err := h.store.Tags().Get(ctx, id)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to get updated tag")
 		return
 	}
 
-	var updated model.Tag
-	if err := updatedDoc.DataTo(&updated); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse updated tag")
-		return
-	}
-	updated.ID = id
-
 	writeJSON(w, http.StatusOK, updated)
 }
 
@@ -279,10 +240,9 @@ func (h *TagHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
-	ref := h.fs.Collection("tags").Doc(id)
-	doc, err := ref.Get(ctx)
+	existing, err := h.store.Tags().Get(ctx, id)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "tag not found")
 			return
 		}
@@ -290,18 +250,16 @@ func (h *TagHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var existing model.Tag
-	if err := doc.DataTo(&existing); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse tag")
+	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
 		return
 	}
 
-	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
-		writeError(w, http.StatusForbidden, "editor role required")
+	if !deleteReferences(w, r, h.store, referent{kind: refTag, id: id, disciplineID: existing.DisciplineID}, h.deletePolicy) {
 		return
 	}
 
-	if _, err := ref.Delete(ctx); err != nil {
+	if err := h.store.Tags().Delete(ctx, id); err != nil {
 		slog.Error("failed to delete tag", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to delete tag")
 		return
@@ -309,3 +267,28 @@ func (h *TagHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Usages lists the techniques, assets and curricula tagged with the tag,
+// and the curriculum elements whose snapshot is.
+// GET /api/v1/tags/{id}/usages
+func (h *TagHandler) Usages(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	existing, err := h.store.Tags().Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "tag not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to get tag")
+		return
+	}
+
+	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
+		return
+	}
+
+	writeUsages(w, ctx, h.store, referent{kind: refTag, id: id, disciplineID: existing.DisciplineID})
+}
@@ -0,0 +1,346 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/thomas/skillhive-api/internal/middleware"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+	"github.com/thomas/skillhive-api/internal/validate"
+)
+
+// maxEdgeAssets bounds the assets that explain one technique edge.
+const maxEdgeAssets = 20
+
+type TechniqueEdgeHandler struct {
+	store store.Store
+}
+
+func NewTechniqueEdgeHandler(s store.Store) *TechniqueEdgeHandler {
+	return &TechniqueEdgeHandler{store: s}
+}
+
+// normalizeTechniqueEdge replaces nil slices with empty arrays for JSON.
+func normalizeTechniqueEdge(e *model.TechniqueEdge) {
+	if e.AssetIDs == nil {
+		e.AssetIDs = []string{}
+	}
+}
+
+// validEdgeType checks a technique edge type.
+func validEdgeType(t string) error {
+	if !slices.Contains(model.EdgeTypes, t) {
+		return fmt.Errorf("type must be one of: %s", strings.Join(model.EdgeTypes, ", "))
+	}
+	return nil
+}
+
+// List lists the technique edges of a discipline, optionally only those of
+// one type or touching one technique, oldest first.
+// GET /api/v1/technique-edges?disciplineId=&techniqueId=&type=
+func (h *TechniqueEdgeHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	disciplineID := r.URL.Query().Get("disciplineId")
+	techniqueID := r.URL.Query().Get("techniqueId")
+	edgeType := r.URL.Query().Get("type")
+
+	if disciplineID == "" {
+		writeError(w, http.StatusBadRequest, "disciplineId query parameter is required")
+		return
+	}
+	if edgeType != "" {
+		if err := validEdgeType(edgeType); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+
+	query := store.NewQuery().
+		Where("disciplineId", "==", disciplineID).
+		OrderBy("createdAt", store.Asc)
+
+	var keep func(*model.TechniqueEdge) bool
+	if techniqueID != "" || edgeType != "" {
+		keep = func(e *model.TechniqueEdge) bool {
+			if techniqueID != "" && e.FromID != techniqueID && e.ToID != techniqueID {
+				return false
+			}
+			return edgeType == "" || e.Type == edgeType
+		}
+	}
+
+	edges, next, err := listPage(ctx, h.store.TechniqueEdges().List, query, page,
+		func(e *model.TechniqueEdge) string { return e.ID }, keep)
+	if err != nil {
+		writeListError(w, err, "technique edges")
+		return
+	}
+	for i := range edges {
+		normalizeTechniqueEdge(&edges[i])
+	}
+	setNextLink(w, r, next)
+
+	writeJSON(w, http.StatusOK, edges)
+}
+
+// getEdge loads the technique edge named in the URL.
+func (h *TechniqueEdgeHandler) getEdge(w http.ResponseWriter, r *http.Request) (*model.TechniqueEdge, bool) {
+	e, err := h.store.TechniqueEdges().Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "technique edge not found")
+		} else {
+			slog.Error("failed to get technique edge", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to get technique edge")
+		}
+		return nil, false
+	}
+	normalizeTechniqueEdge(e)
+	return e, true
+}
+
+// Get returns a technique edge.
+// GET /api/v1/technique-edges/{id}
+func (h *TechniqueEdgeHandler) Get(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.getEdge(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, e)
+}
+
+// checkEdgeAssets reports an error unless ids name at most maxEdgeAssets
+// distinct assets. Whether they are assets of the discipline is left to
+// refCheck.
+func checkEdgeAssets(ids []string) error {
+	if len(ids) > maxEdgeAssets {
+		return fmt.Errorf("assetIds must have at most %d entries", maxEdgeAssets)
+	}
+	for i, id := range ids {
+		if slices.Contains(ids[:i], id) {
+			return errors.New("assetIds must not repeat an asset")
+		}
+	}
+	return nil
+}
+
+// edgeExists reports whether there is an edge of the type between the two
+// techniques other than the one with ID except.
+func (h *TechniqueEdgeHandler) edgeExists(ctx context.Context, fromID, toID, edgeType, except string) (bool, error) {
+	existing, err := h.store.TechniqueEdges().List(ctx, store.NewQuery().
+		Where("fromId", "==", fromID).
+		Where("toId", "==", toID).
+		Where("type", "==", edgeType))
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(existing, func(e model.TechniqueEdge) bool { return e.ID != except }), nil
+}
+
+// Create adds an edge between two techniques of a discipline.
+// POST /api/v1/technique-edges?disciplineId=
+func (h *TechniqueEdgeHandler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid := middleware.GetUserUID(ctx)
+	disciplineID := r.URL.Query().Get("disciplineId")
+
+	if disciplineID == "" {
+		writeError(w, http.StatusBadRequest, "disciplineId query parameter is required")
+		return
+	}
+
+	if err := middleware.RequireEditor(ctx, disciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
+		return
+	}
+
+	var req model.CreateTechniqueEdgeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validEdgeType(req.Type); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validate.MaxLength("notes", req.Notes, 2000); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.FromID != "" && req.FromID == req.ToID {
+		writeError(w, http.StatusBadRequest, "an edge must join two different techniques")
+		return
+	}
+	if err := validate.Required("fromId", req.FromID); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validate.Required("toId", req.ToID); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := checkEdgeAssets(req.AssetIDs); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	refs := newRefCheck(disciplineID).
+		add("fromId", refTechnique, req.FromID).
+		add("toId", refTechnique, req.ToID).
+		add("assetIds", refAsset, req.AssetIDs...)
+	if _, ok := checkRefs(w, ctx, h.store, refs, "failed to create technique edge"); !ok {
+		return
+	}
+
+	exists, err := h.edgeExists(ctx, req.FromID, req.ToID, req.Type, "")
+	if err != nil {
+		slog.Error("failed to check technique edge", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to create technique edge")
+		return
+	}
+	if exists {
+		writeError(w, http.StatusConflict, "an edge of this type already joins these techniques")
+		return
+	}
+
+	if req.AssetIDs == nil {
+		req.AssetIDs = []string{}
+	}
+
+	now := time.Now()
+	e := model.TechniqueEdge{
+		DisciplineID: disciplineID,
+		FromID:       req.FromID,
+		ToID:         req.ToID,
+		Type:         req.Type,
+		Notes:        strings.TrimSpace(validate.StripAllHTML(req.Notes)),
+		AssetIDs:     req.AssetIDs,
+		OwnerUID:     uid,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	id, err := h.store.TechniqueEdges().Create(ctx, &e)
+	if err != nil {
+		slog.Error("failed to create technique edge", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to create technique edge")
+		return
+	}
+	e.ID = id
+
+	writeJSON(w, http.StatusCreated, e)
+}
+
+// Update changes the type, notes or assets of a technique edge.
+// PATCH /api/v1/technique-edges/{id}
+func (h *TechniqueEdgeHandler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	existing, ok := h.getEdge(w, r)
+	if !ok {
+		return
+	}
+
+	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
+		return
+	}
+
+	var req model.UpdateTechniqueEdgeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updates := []store.Update{
+		{Path: "updatedAt", Value: time.Now()},
+	}
+
+	if req.Type != nil && *req.Type != existing.Type {
+		if err := validEdgeType(*req.Type); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		exists, err := h.edgeExists(ctx, existing.FromID, existing.ToID, *req.Type, existing.ID)
+		if err != nil {
+			slog.Error("failed to check technique edge", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to update technique edge")
+			return
+		}
+		if exists {
+			writeError(w, http.StatusConflict, "an edge of this type already joins these techniques")
+			return
+		}
+		updates = append(updates, store.Update{Path: "type", Value: *req.Type})
+	}
+	if req.Notes != nil {
+		if err := validate.MaxLength("notes", *req.Notes, 2000); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		updates = append(updates, store.Update{Path: "notes", Value: strings.TrimSpace(validate.StripAllHTML(*req.Notes))})
+	}
+	if req.AssetIDs != nil {
+		if err := checkEdgeAssets(req.AssetIDs); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		refs := newRefCheck(existing.DisciplineID).add("assetIds", refAsset, req.AssetIDs...)
+		if _, ok := checkRefs(w, ctx, h.store, refs, "failed to update technique edge"); !ok {
+			return
+		}
+		updates = append(updates, store.Update{Path: "assetIds", Value: req.AssetIDs})
+	}
+
+	if err := h.store.TechniqueEdges().Update(ctx, existing.ID, updates); err != nil {
+		slog.Error("failed to update technique edge", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update technique edge")
+		return
+	}
+
+	updated, err := h.store.TechniqueEdges().Get(ctx, existing.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get updated technique edge")
+		return
+	}
+	normalizeTechniqueEdge(updated)
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// Delete removes a technique edge.
+// DELETE /api/v1/technique-edges/{id}
+func (h *TechniqueEdgeHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	existing, ok := h.getEdge(w, r)
+	if !ok {
+		return
+	}
+
+	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
+		return
+	}
+
+	if err := h.store.TechniqueEdges().Delete(ctx, existing.ID); err != nil {
+		slog.Error("failed to delete technique edge", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete technique edge")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,319 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/thomas/skillhive-api/internal/graph"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+const (
+	// defaultChainLength and maxChainLength bound the edges of a chain.
+	defaultChainLength = 3
+	maxChainLength     = 6
+	// maxChains bounds the chains returned by one query.
+	maxChains = 100
+)
+
+type GraphHandler struct {
+	store store.Store
+}
+
+func NewGraphHandler(s store.Store) *GraphHandler {
+	return &GraphHandler{store: s}
+}
+
+// techniqueGraph is the technique graph of a discipline as loaded for a
+// traversal.
+type techniqueGraph struct {
+	nodes map[string]model.GraphNode
+	model.TechniqueGraph
+}
+
+// loadTechniqueGraph loads the techniques of a discipline, by name, and its
+// edges, oldest first.
+func loadTechniqueGraph(ctx context.Context, s store.Store, disciplineID string) (*techniqueGraph, error) {
+	techniques, err := s.Techniques().List(ctx, store.NewQuery().
+		Where("disciplineId", "==", disciplineID).
+		OrderBy("name", store.Asc))
+	if err != nil {
+		return nil, err
+	}
+	edges, err := s.TechniqueEdges().List(ctx, store.NewQuery().
+		Where("disciplineId", "==", disciplineID).
+		OrderBy("createdAt", store.Asc))
+	if err != nil {
+		return nil, err
+	}
+
+	g := &techniqueGraph{
+		nodes:          map[string]model.GraphNode{},
+		TechniqueGraph: model.TechniqueGraph{DisciplineID: disciplineID, Nodes: []model.GraphNode{}, Edges: edges},
+	}
+	for _, t := range techniques {
+		n := model.GraphNode{ID: t.ID, Name: t.Name, Slug: t.Slug}
+		g.nodes[t.ID] = n
+		g.Nodes = append(g.Nodes, n)
+	}
+	for i := range g.Edges {
+		normalizeTechniqueEdge(&g.Edges[i])
+	}
+	return g, nil
+}
+
+// node returns the node of a technique, with only its ID should the
+// technique be gone.
+func (g *techniqueGraph) node(id string) model.GraphNode {
+	if n, ok := g.nodes[id]; ok {
+		return n
+	}
+	return model.GraphNode{ID: id}
+}
+
+// path describes the steps walked from a technique.
+func (g *techniqueGraph) path(from string, steps []graph.Step) model.TechniquePath {
+	p := model.TechniquePath{
+		Length:     len(steps),
+		Techniques: []model.GraphNode{g.node(from)},
+		Edges:      []model.TechniqueEdge{},
+	}
+	for _, s := range steps {
+		p.Techniques = append(p.Techniques, g.node(s.To))
+		p.Edges = append(p.Edges, *s.Edge)
+	}
+	return p
+}
+
+// parseEdgeTypes reads the comma-separated types query parameter, which
+// restricts a traversal to edges of those types.
+func parseEdgeTypes(r *http.Request) ([]string, error) {
+	raw := r.URL.Query().Get("types")
+	if raw == "" {
+		return nil, nil
+	}
+	types := strings.Split(raw, ",")
+	for i, t := range types {
+		types[i] = strings.TrimSpace(t)
+		if !slices.Contains(model.EdgeTypes, types[i]) {
+			return nil, fmt.Errorf("types must be a comma-separated list of: %s", strings.Join(model.EdgeTypes, ", "))
+		}
+	}
+	return types, nil
+}
+
+// getTechnique loads a technique, writing the error response if it fails.
+func (h *GraphHandler) getTechnique(w http.ResponseWriter, ctx context.Context, id string) (*model.Technique, bool) {
+	t, err := h.store.Techniques().Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "technique not found")
+		} else {
+			slog.Error("failed to get technique", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to get technique")
+		}
+		return nil, false
+	}
+	return t, true
+}
+
+// loadGraph loads the technique graph of a discipline, writing the error
+// response if it fails.
+func (h *GraphHandler) loadGraph(w http.ResponseWriter, ctx context.Context, disciplineID string) (*techniqueGraph, bool) {
+	g, err := loadTechniqueGraph(ctx, h.store, disciplineID)
+	if err != nil {
+		slog.Error("failed to load technique graph", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to load technique graph")
+		return nil, false
+	}
+	return g, true
+}
+
+// Neighbors lists the techniques one edge away from a technique, with those
+// edges: outgoing ones first, each oldest first.
+// GET /api/v1/techniques/{id}/neighbors?direction=out|in|both&types=
+func (h *GraphHandler) Neighbors(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	direction := graph.Both
+	if v := r.URL.Query().Get("direction"); v != "" {
+		if v != graph.Out && v != graph.In && v != graph.Both {
+			writeError(w, http.StatusBadRequest, "direction must be out, in or both")
+			return
+		}
+		direction = v
+	}
+	types, err := parseEdgeTypes(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	t, ok := h.getTechnique(w, ctx, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+	g, ok := h.loadGraph(w, ctx, t.DisciplineID)
+	if !ok {
+		return
+	}
+
+	neighbors := []model.Neighbor{}
+	for _, s := range graph.New(g.Edges, types).Neighbors(t.ID, direction) {
+		neighbors = append(neighbors, model.Neighbor{Direction: s.Direction, Edge: *s.Edge, Technique: g.node(s.To)})
+	}
+
+	writeJSON(w, http.StatusOK, neighbors)
+}
+
+// Path returns a shortest path between two techniques of a discipline. It
+// follows edges only in their direction unless directed is false.
+// GET /api/v1/graph/path?from=&to=&types=&directed=
+func (h *GraphHandler) Path(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	fromID := r.URL.Query().Get("from")
+	toID := r.URL.Query().Get("to")
+
+	if fromID == "" || toID == "" {
+		writeError(w, http.StatusBadRequest, "from and to query parameters are required")
+		return
+	}
+	directed := true
+	if v := r.URL.Query().Get("directed"); v != "" {
+		var err error
+		if directed, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, "directed must be true or false")
+			return
+		}
+	}
+	types, err := parseEdgeTypes(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	from, ok := h.getTechnique(w, ctx, fromID)
+	if !ok {
+		return
+	}
+	to, ok := h.getTechnique(w, ctx, toID)
+	if !ok {
+		return
+	}
+	if from.DisciplineID != to.DisciplineID {
+		writeError(w, http.StatusNotFound, "no path between these techniques")
+		return
+	}
+	g, ok := h.loadGraph(w, ctx, from.DisciplineID)
+	if !ok {
+		return
+	}
+
+	steps, found := graph.New(g.Edges, types).ShortestPath(from.ID, to.ID, directed)
+	if !found {
+		writeError(w, http.StatusNotFound, "no path between these techniques")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, g.path(from.ID, steps))
+}
+
+// Chains lists the chains of outgoing edges from a technique of up to
+// length edges that visit no technique twice, each as long as it can be.
+// GET /api/v1/graph/chains?from=&length=&types=
+func (h *GraphHandler) Chains(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	fromID := r.URL.Query().Get("from")
+
+	if fromID == "" {
+		writeError(w, http.StatusBadRequest, "from query parameter is required")
+		return
+	}
+	length := defaultChainLength
+	if v := r.URL.Query().Get("length"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxChainLength {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("length must be between 1 and %d", maxChainLength))
+			return
+		}
+		length = n
+	}
+	types, err := parseEdgeTypes(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	from, ok := h.getTechnique(w, ctx, fromID)
+	if !ok {
+		return
+	}
+	g, ok := h.loadGraph(w, ctx, from.DisciplineID)
+	if !ok {
+		return
+	}
+
+	chains, truncated := graph.New(g.Edges, types).Chains(from.ID, length, maxChains)
+	resp := model.TechniqueChains{Chains: []model.TechniquePath{}, Truncated: truncated}
+	for _, steps := range chains {
+		resp.Chains = append(resp.Chains, g.path(from.ID, steps))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Export returns the technique graph of a discipline as JSON, or as a
+// Graphviz DOT file.
+// GET /api/v1/graph/export?disciplineId=&format=json|dot&types=
+func (h *GraphHandler) Export(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	disciplineID := r.URL.Query().Get("disciplineId")
+
+	if disciplineID == "" {
+		writeError(w, http.StatusBadRequest, "disciplineId query parameter is required")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "dot" {
+		writeError(w, http.StatusBadRequest, "format must be json or dot")
+		return
+	}
+	types, err := parseEdgeTypes(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	g, ok := h.loadGraph(w, ctx, disciplineID)
+	if !ok {
+		return
+	}
+	if types != nil {
+		g.Edges = slices.DeleteFunc(g.Edges, func(e model.TechniqueEdge) bool { return !slices.Contains(types, e.Type) })
+	}
+
+	if format != "dot" {
+		writeJSON(w, http.StatusOK, g.TechniqueGraph)
+		return
+	}
+	var buf bytes.Buffer
+	if err := graph.WriteDOT(&buf, &g.TechniqueGraph); err != nil {
+		slog.Error("failed to export technique graph", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to export technique graph")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", disciplineID+"-techniques.dot"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
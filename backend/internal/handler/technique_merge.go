@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/thomas/skillhive-api/internal/merge"
+	"github.com/thomas/skillhive-api/internal/middleware"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+// Merge merges techniques of the same discipline into this one and deletes
+// them. The technique gains their categories, tags, descriptions, and their
+// names and aliases as aliases; assets, curriculum elements and edges that
+// refer to them are pointed at it. With dryRun=true it only reports what
+// would change.
+// POST /api/v1/techniques/{id}/merge?dryRun=true
+func (h *TechniqueHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid := middleware.GetUserUID(ctx)
+	id := chi.URLParam(r, "id")
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+
+	target, err := h.store.Techniques().Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "technique not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to get technique")
+		return
+	}
+
+	if err := middleware.RequireEditor(ctx, target.DisciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
+		return
+	}
+
+	var req model.MergeTechniquesRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.SourceIDs) == 0 {
+		writeError(w, http.StatusBadRequest, "sourceIds is required")
+		return
+	}
+	if len(req.SourceIDs) > merge.MaxSources {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("sourceIds must have at most %d entries", merge.MaxSources))
+		return
+	}
+
+	sources := make([]model.Technique, 0, len(req.SourceIDs))
+	for i, sourceID := range req.SourceIDs {
+		if sourceID == target.ID {
+			writeError(w, http.StatusBadRequest, "a technique cannot be merged into itself")
+			return
+		}
+		if slices.Contains(req.SourceIDs[:i], sourceID) {
+			writeError(w, http.StatusBadRequest, "sourceIds must not repeat a technique")
+			return
+		}
+		t, err := h.store.Techniques().Get(ctx, sourceID)
+		if errors.Is(err, store.ErrNotFound) || err == nil && t.DisciplineID != target.DisciplineID {
+			writeError(w, http.StatusBadRequest, "sourceIds must name techniques of this discipline")
+			return
+		}
+		if err != nil {
+			slog.Error("failed to get technique", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to merge techniques")
+			return
+		}
+		sources = append(sources, *t)
+	}
+
+	merged, added, err := merge.Technique(target, sources)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	now := time.Now()
+	if !dryRun {
+		merged.UpdatedAt = now
+		if merged.OwnerUID == "system" {
+			merged.OwnerUID = uid
+		}
+	}
+
+	report, err := merge.Apply(ctx, h.store, merged, req.SourceIDs, dryRun,
+		revisionChange(ctx, model.RevisionTechniqueMerge, now))
+	if err != nil {
+		slog.Error("failed to merge techniques", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to merge techniques")
+		return
+	}
+	report.AliasesAdded = added
+	normalizeTechnique(&report.Technique)
+
+	writeJSON(w, http.StatusOK, report)
+}
@@ -1,27 +1,69 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
-	"strconv"
+	"slices"
+	"strings"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/go-chi/chi/v5"
 	"github.com/thomas/skillhive-api/internal/middleware"
 	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
 	"github.com/thomas/skillhive-api/internal/validate"
-	"google.golang.org/api/iterator"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type TechniqueHandler struct {
-	fs *firestore.Client
+	store        store.Store
+	deletePolicy model.DeletePolicy
 }
 
-func NewTechniqueHandler(fs *firestore.Client) *TechniqueHandler {
-	return &TechniqueHandler{fs: fs}
+func NewTechniqueHandler(s store.Store, deletePolicy model.DeletePolicy) *TechniqueHandler {
+	return &TechniqueHandler{store: s, deletePolicy: deletePolicy}
+}
+
+// normalizeTechnique replaces nil slices with empty arrays for JSON.
+func normalizeTechnique(t *model.Technique) {
+	if t.CategoryIDs == nil {
+		t.CategoryIDs = []string{}
+	}
+	if t.TagIDs == nil {
+		t.TagIDs = []string{}
+	}
+	if t.Aliases == nil {
+		t.Aliases = []string{}
+	}
+}
+
+// parseAliases cleans the aliases of a technique named name (see
+// validate.Aliases), of which there may be at most model.MaxTechniqueAliases.
+func parseAliases(name string, raw []string) ([]string, []string, error) {
+	if len(raw) > model.MaxTechniqueAliases {
+		return nil, nil, fmt.Errorf("aliases must have at most %d entries", model.MaxTechniqueAliases)
+	}
+	return validate.Aliases(name, raw)
+}
+
+// techniqueNameTaken reports whether a technique of the discipline other
+// than the one with ID except is known by any of slugs, by name or alias.
+func techniqueNameTaken(ctx context.Context, s store.Store, disciplineID string, slugs []string, except string) (bool, error) {
+	for _, field := range []struct{ name, op string }{{"slug", store.OpIn}, {"aliasSlugs", store.OpArrayContainsAny}} {
+		found, err := s.Techniques().List(ctx, store.NewQuery().
+			Where("disciplineId", "==", disciplineID).
+			Where(field.name, field.op, slugs).
+			Limit(2))
+		if err != nil {
+			return false, err
+		}
+		if slices.ContainsFunc(found, func(t model.Technique) bool { return t.ID != except }) {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 func (h *TechniqueHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -33,90 +75,63 @@ func (h *TechniqueHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := h.fs.Collection("techniques").
-		Where("disciplineId", "==", disciplineID)
-
-	// Filter by category (array-contains)
-	categoryID := r.URL.Query().Get("categoryId")
-	tagID := r.URL.Query().Get("tagId")
-
-	if categoryID != "" {
-		query = query.Where("categoryIds", "array-contains", categoryID)
-	} else if tagID != "" {
-		// Can't combine two array-contains, so only one at a time
-		query = query.Where("tagIds", "array-contains", tagID)
+	filter, err := parseListFilter(r)
+	if err == nil {
+		err = filter.checkTechniqueFilter()
 	}
-
-	query = query.OrderBy("name", firestore.Asc)
-
-	// Pagination (optional - no limit by default, returns all)
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
-			query = query.Limit(parsed)
-		}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
-	if o := r.URL.Query().Get("offset"); o != "" {
-		if parsed, err := strconv.Atoi(o); err == nil && parsed > 0 {
-			query = query.Offset(parsed)
-		}
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
 	}
 
-	iter := query.Documents(ctx)
-	defer iter.Stop()
+	query, exact := filter.pushDown(store.NewQuery().
+		Where("disciplineId", "==", disciplineID), "categoryIds", "tagIds")
+	query = query.OrderBy("name", store.Asc)
+
+	// Filters the store could not apply are checked in memory
+	var keep func(*model.Technique) bool
+	if searchQuery := r.URL.Query().Get("q"); !exact || searchQuery != "" {
+		keep = func(t *model.Technique) bool {
+			// Client-side text filter if search query provided
+			if searchQuery != "" {
+				lower := validate.GenerateSlug(searchQuery)
+				slugMatch := len(lower) > 0 && slices.ContainsFunc(t.Slugs(), func(slug string) bool {
+					return strings.HasPrefix(slug, lower)
+				})
+				if !slugMatch {
+					return false
+				}
+			}
+			return filter.matchTechnique(t)
+		}
+	}
 
-	techniques := []model.Technique{}
-	searchQuery := r.URL.Query().Get("q")
+	list := h.store.Techniques().List
+	idOf := func(t *model.Technique) string { return t.ID }
+	techniques, next, err := listPage(ctx, list, query, page, idOf, keep)
+	if err != nil {
+		writeListError(w, err, "techniques")
+		return
+	}
+	for i := range techniques {
+		normalizeTechnique(&techniques[i])
+	}
+	setNextLink(w, r, next)
 
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
+	if wantFacets(r) {
+		// Facets count the whole filtered list, not just this page
+		facets, err := listFacets(ctx, list, query, page, techniques, next, idOf, keep, techniqueFacets)
 		if err != nil {
-			slog.Error("failed to list techniques", "error", err)
-			writeError(w, http.StatusInternalServerError, "failed to list techniques")
+			writeListError(w, err, "techniques")
 			return
 		}
-
-		var t model.Technique
-		if err := doc.DataTo(&t); err != nil {
-			slog.Error("failed to parse technique", "docID", doc.Ref.ID, "error", err)
-			continue
-		}
-		t.ID = doc.Ref.ID
-
-		// Normalize nil slices to empty arrays for JSON
-		if t.CategoryIDs == nil {
-			t.CategoryIDs = []string{}
-		}
-		if t.TagIDs == nil {
-			t.TagIDs = []string{}
-		}
-
-		// Client-side text filter if search query provided
-		if searchQuery != "" {
-			lower := validate.GenerateSlug(searchQuery)
-			slugMatch := len(lower) > 0 && len(t.Slug) >= len(lower) && t.Slug[:len(lower)] == lower
-			if !slugMatch {
-				continue
-			}
-		}
-
-		// Client-side filter for tagId when categoryId was used with array-contains
-		if categoryID != "" && tagID != "" {
-			found := false
-			for _, tid := range t.TagIDs {
-				if tid == tagID {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
-			}
-		}
-
-		techniques = append(techniques, t)
+		writeJSON(w, http.StatusOK, model.FacetedList[model.Technique]{Items: techniques, Facets: facets})
+		return
 	}
 
 	writeJSON(w, http.StatusOK, techniques)
@@ -126,44 +141,26 @@ func (h *TechniqueHandler) Get(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
-	doc, err := h.fs.Collection("techniques").Doc(id).Get(ctx)
+	t, err := h.store.Techniques().Get(ctx, id)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "technique not found")
 			return
 		}
 		writeError(w, http.StatusInternalServerError, "failed to get technique")
 		return
 	}
-
-	var t model.Technique
-	if err := doc.DataTo(&t); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse technique")
-		return
-	}
-	t.ID = doc.Ref.ID
-
-	// Normalize nil slices
-	if t.CategoryIDs == nil {
-		t.CategoryIDs = []string{}
-	}
-	if t.TagIDs == nil {
-		t.TagIDs = []string{}
-	}
+	normalizeTechnique(t)
 
 	// Resolve categories
 	if len(t.CategoryIDs) > 0 {
 		t.Categories = []model.Category{}
 		for _, catID := range t.CategoryIDs {
-			catDoc, err := h.fs.Collection("categories").Doc(catID).Get(ctx)
+			cat, err := h.store.Categories().Get(ctx, catID)
 			if err != nil {
 				continue
 			}
-			var cat model.Category
-			if err := catDoc.DataTo(&cat); err == nil {
-				cat.ID = catDoc.Ref.ID
-				t.Categories = append(t.Categories, cat)
-			}
+			t.Categories = append(t.Categories, *cat)
 		}
 	}
 
@@ -171,15 +168,11 @@ func (h *TechniqueHandler) Get(w http.ResponseWriter, r *http.Request) {
 	if len(t.TagIDs) > 0 {
 		t.Tags = []model.Tag{}
 		for _, tagID := range t.TagIDs {
-			tagDoc, err := h.fs.Collection("tags").Doc(tagID).Get(ctx)
+			tag, err := h.store.Tags().Get(ctx, tagID)
 			if err != nil {
 				continue
 			}
-			var tag model.Tag
-			if err := tagDoc.DataTo(&tag); err == nil {
-				tag.ID = tagDoc.Ref.ID
-				t.Tags = append(t.Tags, tag)
-			}
+			t.Tags = append(t.Tags, *tag)
 		}
 	}
 
@@ -217,16 +210,28 @@ func (h *TechniqueHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	slug := validate.GenerateSlug(req.Name)
+	aliases, aliasSlugs, err := parseAliases(req.Name, req.Aliases)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	// Check slug uniqueness
-	existing := h.fs.Collection("techniques").
-		Where("disciplineId", "==", disciplineID).
-		Where("slug", "==", slug).
-		Limit(1).
-		Documents(ctx)
-	defer existing.Stop()
-	if doc, err := existing.Next(); err == nil && doc != nil {
-		writeError(w, http.StatusConflict, "a technique with this name already exists in this discipline")
+	// Check slug uniqueness, against names and aliases alike
+	taken, err := techniqueNameTaken(ctx, h.store, disciplineID, append([]string{slug}, aliasSlugs...), "")
+	if err != nil {
+		slog.Error("failed to check technique slug", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to create technique")
+		return
+	}
+	if taken {
+		writeError(w, http.StatusConflict, "a technique with this name or alias already exists in this discipline")
+		return
+	}
+
+	refs := newRefCheck(disciplineID).
+		add("categoryIds", refCategory, req.CategoryIDs...).
+		add("tagIds", refTag, req.TagIDs...)
+	if _, ok := checkRefs(w, ctx, h.store, refs, "failed to create technique"); !ok {
 		return
 	}
 
@@ -242,6 +247,8 @@ func (h *TechniqueHandler) Create(w http.ResponseWriter, r *http.Request) {
 		DisciplineID: disciplineID,
 		Name:         validate.StripAllHTML(req.Name),
 		Slug:         slug,
+		Aliases:      aliases,
+		AliasSlugs:   aliasSlugs,
 		Description:  validate.StripAllHTML(req.Description),
 		CategoryIDs:  req.CategoryIDs,
 		TagIDs:       req.TagIDs,
@@ -250,24 +257,12 @@ func (h *TechniqueHandler) Create(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:    now,
 	}
 
-	ref, _, err := h.fs.Collection("techniques").Add(ctx, map[string]interface{}{
-		"disciplineId": t.DisciplineID,
-		"name":         t.Name,
-		"slug":         t.Slug,
-		"description":  t.Description,
-		"categoryIds":  t.CategoryIDs,
-		"tagIds":       t.TagIDs,
-		"ownerUid":     t.OwnerUID,
-		"createdAt":    t.CreatedAt,
-		"updatedAt":    t.UpdatedAt,
-	})
-	if err != nil {
+	if _, err := h.store.Techniques().Create(ctx, &t); err != nil {
 		slog.Error("failed to create technique", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to create technique")
 		return
 	}
 
-	t.ID = ref.ID
 	writeJSON(w, http.StatusCreated, t)
 }
 
@@ -276,10 +271,9 @@ func (h *TechniqueHandler) Update(w http.ResponseWriter, r *http.Request) {
 	uid := middleware.GetUserUID(ctx)
 	id := chi.URLParam(r, "id")
 
-	ref := h.fs.Collection("techniques").Doc(id)
-	doc, err := ref.Get(ctx)
+	existing, err := h.store.Techniques().Get(ctx, id)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "technique not found")
 			return
 		}
@@ -287,79 +281,92 @@ func (h *TechniqueHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var existing model.Technique
-	if err := doc.DataTo(&existing); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse technique")
-		return
-	}
-
 	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
 		writeError(w, http.StatusForbidden, "editor role required")
 		return
 	}
 
-	_ = uid // kept for ownership transfer below
-
 	var req model.UpdateTechniqueRequest
 	if err := decodeJSON(r, &req); err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	updates := []firestore.Update{
+	refs := newRefCheck(existing.DisciplineID).
+		add("categoryIds", refCategory, req.CategoryIDs...).
+		add("tagIds", refTag, req.TagIDs...)
+	if _, ok := checkRefs(w, ctx, h.store, refs, "failed to update technique"); !ok {
+		return
+	}
+
+	updates := []store.Update{
 		{Path: "updatedAt", Value: time.Now()},
 	}
 
 	// Transfer ownership from system to user on edit
 	if existing.OwnerUID == "system" {
-		updates = append(updates, firestore.Update{Path: "ownerUid", Value: uid})
+		updates = append(updates, store.Update{Path: "ownerUid", Value: uid})
 	}
 
-	if req.Name != nil {
-		name := validate.StripAllHTML(*req.Name)
-		if err := validate.StringLength("name", name, 1, 200); err != nil {
+	if req.Name != nil || req.Aliases != nil {
+		name := existing.Name
+		if req.Name != nil {
+			name = validate.StripAllHTML(*req.Name)
+			if err := validate.StringLength("name", name, 1, 200); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		// Renaming may turn an alias into the name, which then drops it
+		raw := existing.Aliases
+		if req.Aliases != nil {
+			raw = req.Aliases
+		}
+		aliases, aliasSlugs, err := parseAliases(name, raw)
+		if err != nil {
 			writeError(w, http.StatusBadRequest, err.Error())
 			return
 		}
+		slug := validate.GenerateSlug(name)
+		taken, err := techniqueNameTaken(ctx, h.store, existing.DisciplineID, append([]string{slug}, aliasSlugs...), id)
+		if err != nil {
+			slog.Error("failed to check technique slug", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to update technique")
+			return
+		}
+		if taken {
+			writeError(w, http.StatusConflict, "a technique with this name or alias already exists in this discipline")
+			return
+		}
 		updates = append(updates,
-			firestore.Update{Path: "name", Value: name},
-			firestore.Update{Path: "slug", Value: validate.GenerateSlug(name)},
+			store.Update{Path: "name", Value: name},
+			store.Update{Path: "slug", Value: slug},
+			store.Update{Path: "aliases", Value: aliases},
+			store.Update{Path: "aliasSlugs", Value: aliasSlugs},
 		)
 	}
 	if req.Description != nil {
-		updates = append(updates, firestore.Update{Path: "description", Value: validate.StripAllHTML(*req.Description)})
+		updates = append(updates, store.Update{Path: "description", Value: validate.StripAllHTML(*req.Description)})
 	}
 	if req.CategoryIDs != nil {
-		updates = append(updates, firestore.Update{Path: "categoryIds", Value: req.CategoryIDs})
+		updates = append(updates, store.Update{Path: "categoryIds", Value: req.CategoryIDs})
 	}
 	if req.TagIDs != nil {
-		updates = append(updates, firestore.Update{Path: "tagIds", Value: req.TagIDs})
+		updates = append(updates, store.Update{Path: "tagIds", Value: req.TagIDs})
 	}
 
-	if _, err := ref.Update(ctx, updates); err != nil {
+	if err := h.store.Techniques().Update(ctx, id, updates); err != nil {
 		slog.Error("failed to update technique", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to update technique")
 		return
 	}
 
-	updatedDoc, err := ref.Get(ctx)
+	updated, err := h.store.Techniques().Get(ctx, id)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to get updated technique")
 		return
 	}
-
-	var updated model.Technique
-	if err := updatedDoc.DataTo(&updated); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse updated technique")
-		return
-	}
-	updated.ID = id
-	if updated.CategoryIDs == nil {
-		updated.CategoryIDs = []string{}
-	}
-	if updated.TagIDs == nil {
-		updated.TagIDs = []string{}
-	}
+	normalizeTechnique(updated)
 
 	writeJSON(w, http.StatusOK, updated)
 }
@@ -368,10 +375,9 @@ func (h *TechniqueHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
-	ref := h.fs.Collection("techniques").Doc(id)
-	doc, err := ref.Get(ctx)
+	existing, err := h.store.Techniques().Get(ctx, id)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "technique not found")
 			return
 		}
@@ -379,18 +385,16 @@ func (h *TechniqueHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var existing model.Technique
-	if err := doc.DataTo(&existing); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to parse technique")
+	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
 		return
 	}
 
-	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
-		writeError(w, http.StatusForbidden, "editor role required")
+	if !deleteReferences(w, r, h.store, referent{kind: refTechnique, id: id, disciplineID: existing.DisciplineID}, h.deletePolicy) {
 		return
 	}
 
-	if _, err := ref.Delete(ctx); err != nil {
+	if err := h.store.Techniques().Delete(ctx, id); err != nil {
 		slog.Error("failed to delete technique", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to delete technique")
 		return
@@ -398,3 +402,28 @@ func (h *TechniqueHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Usages lists the assets, edges and curriculum elements that refer to the
+// technique.
+// GET /api/v1/techniques/{id}/usages
+func (h *TechniqueHandler) Usages(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	existing, err := h.store.Techniques().Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "technique not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to get technique")
+		return
+	}
+
+	if err := middleware.RequireEditor(ctx, existing.DisciplineID); err != nil {
+		writeError(w, http.StatusForbidden, "editor role required")
+		return
+	}
+
+	writeUsages(w, ctx, h.store, referent{kind: refTechnique, id: id, disciplineID: existing.DisciplineID})
+}
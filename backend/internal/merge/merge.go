@@ -0,0 +1,280 @@
+// Package merge folds techniques of a discipline into one. The merged
+// technique gains the categories, tags, descriptions and names of the
+// others, and every reference to them, from assets, curriculum elements and
+// technique edges, is pointed at it.
+package merge
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+	"github.com/thomas/skillhive-api/internal/validate"
+)
+
+// MaxSources bounds the techniques merged at once. It keeps the source IDs
+// within one "in" filter.
+const MaxSources = 20
+
+// unionIDs appends to ids those of more it does not hold yet.
+func unionIDs(ids []string, more ...[]string) []string {
+	out := slices.Clone(ids)
+	if out == nil {
+		out = []string{}
+	}
+	for _, m := range more {
+		for _, id := range m {
+			if !slices.Contains(out, id) {
+				out = append(out, id)
+			}
+		}
+	}
+	return out
+}
+
+// RetargetIDs replaces the IDs of sources in ids with that of the target,
+// once.
+func RetargetIDs(ids, sourceIDs []string, targetID string) []string {
+	out := []string{}
+	for _, id := range ids {
+		if slices.Contains(sourceIDs, id) {
+			id = targetID
+		}
+		if !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// Technique returns target with the categories, tags, descriptions and
+// names of sources merged in, and the aliases it gained. The sources' names
+// and aliases become aliases, and their stored slugs alias slugs, so that
+// their slugs keep resolving. Its errors are about the request, not the
+// store.
+func Technique(target *model.Technique, sources []model.Technique) (*model.Technique, []string, error) {
+	merged := *target
+	raw := slices.Clone(target.Aliases)
+	descriptions := []string{}
+	if d := strings.TrimSpace(target.Description); d != "" {
+		descriptions = append(descriptions, d)
+	}
+	for _, s := range sources {
+		merged.CategoryIDs = unionIDs(merged.CategoryIDs, s.CategoryIDs)
+		merged.TagIDs = unionIDs(merged.TagIDs, s.TagIDs)
+		if d := strings.TrimSpace(s.Description); d != "" && !slices.Contains(descriptions, d) {
+			descriptions = append(descriptions, d)
+		}
+		raw = append(append(raw, s.Name), s.Aliases...)
+	}
+	merged.Description = strings.Join(descriptions, "\n\n")
+
+	aliases, slugs, err := validate.Aliases(target.Name, raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(aliases) > model.MaxTechniqueAliases {
+		return nil, nil, fmt.Errorf("the merged technique would have more than %d aliases", model.MaxTechniqueAliases)
+	}
+	// Stored slugs need not match the name's, e.g. for techniques created by
+	// enrichment, and slugs kept by earlier merges have no alias of their
+	// own.
+	kept := slices.Clone(target.AliasSlugs)
+	for _, s := range sources {
+		kept = append(append(kept, s.Slug), s.AliasSlugs...)
+	}
+	for _, slug := range kept {
+		if slug != "" && slug != target.Slug && !slices.Contains(slugs, slug) {
+			slugs = append(slugs, slug)
+		}
+	}
+	merged.Aliases, merged.AliasSlugs = aliases, slugs
+	added := []string{}
+	for _, a := range aliases {
+		if !slices.Contains(target.Aliases, a) {
+			added = append(added, a)
+		}
+	}
+	return &merged, added, nil
+}
+
+// edgeKey identifies an edge by its ends and type, of which there may be
+// only one.
+type edgeKey struct{ from, to, typ string }
+
+// PlanEdges works out which edges of the sources move to the target and
+// which are removed because they would join the target to itself or repeat
+// another edge. It returns the moved edges with their new ends.
+func PlanEdges(ctx context.Context, s store.Store, targetID string, sourceIDs []string) (moved []model.TechniqueEdge, removed []string, err error) {
+	edges := map[string]model.TechniqueEdge{}
+	var order []string
+	kept := map[edgeKey]bool{}
+	for _, q := range []struct {
+		field string
+		op    string
+		value interface{}
+	}{
+		{"fromId", store.OpEqual, targetID},
+		{"toId", store.OpEqual, targetID},
+		{"fromId", store.OpIn, sourceIDs},
+		{"toId", store.OpIn, sourceIDs},
+	} {
+		found, err := s.TechniqueEdges().List(ctx, store.NewQuery().Where(q.field, q.op, q.value))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, e := range found {
+			if q.value == targetID && !slices.Contains(sourceIDs, e.FromID) && !slices.Contains(sourceIDs, e.ToID) {
+				kept[edgeKey{e.FromID, e.ToID, e.Type}] = true
+				continue
+			}
+			if _, ok := edges[e.ID]; !ok {
+				edges[e.ID] = e
+				order = append(order, e.ID)
+			}
+		}
+	}
+
+	// Oldest edges win over later ones they would repeat.
+	slices.SortFunc(order, func(a, b string) int { return edges[a].CreatedAt.Compare(edges[b].CreatedAt) })
+	moved, removed = []model.TechniqueEdge{}, []string{}
+	for _, id := range order {
+		e := edges[id]
+		e.FromID = RetargetIDs([]string{e.FromID}, sourceIDs, targetID)[0]
+		e.ToID = RetargetIDs([]string{e.ToID}, sourceIDs, targetID)[0]
+		key := edgeKey{e.FromID, e.ToID, e.Type}
+		if e.FromID == e.ToID || kept[key] {
+			removed = append(removed, e.ID)
+			continue
+		}
+		kept[key] = true
+		moved = append(moved, e)
+	}
+	return moved, removed, nil
+}
+
+// retargetCurricula points the technique elements of the discipline's
+// curricula that refer to a source at the merged technique, each curriculum
+// in a transaction of its own that records a revision.
+func retargetCurricula(ctx context.Context, s store.Store, merged *model.Technique, sourceIDs []string, dryRun bool, change curriculum.Change) ([]model.MergedCurriculum, error) {
+	report := []model.MergedCurriculum{}
+	q := store.NewQuery().Where("disciplineId", store.OpEqual, merged.DisciplineID)
+	err := curriculum.Each(ctx, s, q, func(c *model.Curriculum) error {
+		// Elements are read first so that only curricula that refer to a
+		// source take a transaction.
+		elements, err := s.Elements().List(ctx, c.ID, store.NewQuery())
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(elements, func(e model.CurriculumElement) bool {
+			return e.Type == model.ElementTypeTechnique && e.TechniqueID != nil && slices.Contains(sourceIDs, *e.TechniqueID)
+		}) {
+			return nil
+		}
+		changed, err := curriculum.Retarget(ctx, s, c.ID, sourceIDs, merged, dryRun, change)
+		if err != nil {
+			return err
+		}
+		if len(changed) > 0 {
+			report = append(report, model.MergedCurriculum{CurriculumID: c.ID, Title: c.Title, ElementIDs: changed})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Apply saves merged, as returned by Technique, points the assets, edges and
+// curriculum elements that refer to the sources at it, and deletes the
+// sources. With dryRun it writes nothing and only reports what would
+// change. The report leaves AliasesAdded to the caller.
+//
+// merged is saved first and the sources deleted last, so that a merge that
+// fails part way can be run again.
+func Apply(ctx context.Context, s store.Store, merged *model.Technique, sourceIDs []string, dryRun bool, change curriculum.Change) (*model.TechniqueMergeReport, error) {
+	assets, err := s.Assets().List(ctx, store.NewQuery().
+		Where("disciplineId", "==", merged.DisciplineID).
+		Where("techniqueIds", store.OpArrayContainsAny, sourceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("list assets: %w", err)
+	}
+	moved, removed, err := PlanEdges(ctx, s, merged.ID, sourceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("list technique edges: %w", err)
+	}
+
+	report := &model.TechniqueMergeReport{
+		Technique:    *merged,
+		SourceIDs:    sourceIDs,
+		AliasesAdded: []string{},
+		AssetIDs:     []string{},
+		EdgesMoved:   []string{},
+		EdgesRemoved: removed,
+		Applied:      !dryRun,
+	}
+	for _, a := range assets {
+		report.AssetIDs = append(report.AssetIDs, a.ID)
+	}
+	for _, e := range moved {
+		report.EdgesMoved = append(report.EdgesMoved, e.ID)
+	}
+
+	if !dryRun {
+		if err := s.Techniques().Update(ctx, merged.ID, []store.Update{
+			{Path: "categoryIds", Value: merged.CategoryIDs},
+			{Path: "tagIds", Value: merged.TagIDs},
+			{Path: "description", Value: merged.Description},
+			{Path: "aliases", Value: merged.Aliases},
+			{Path: "aliasSlugs", Value: merged.AliasSlugs},
+			{Path: "ownerUid", Value: merged.OwnerUID},
+			{Path: "updatedAt", Value: merged.UpdatedAt},
+		}); err != nil {
+			return nil, fmt.Errorf("update technique: %w", err)
+		}
+
+		// Only the references change, so assets keep their updatedAt and
+		// their snapshots stay current.
+		var writes []func(store.Batch)
+		for _, a := range assets {
+			ref, ids := s.Assets().Ref(a.ID), RetargetIDs(a.TechniqueIDs, sourceIDs, merged.ID)
+			writes = append(writes, func(b store.Batch) { b.Update(ref, []store.Update{{Path: "techniqueIds", Value: ids}}) })
+		}
+		for _, e := range moved {
+			ref, updates := s.TechniqueEdges().Ref(e.ID), []store.Update{
+				{Path: "fromId", Value: e.FromID},
+				{Path: "toId", Value: e.ToID},
+				{Path: "updatedAt", Value: change.At},
+			}
+			writes = append(writes, func(b store.Batch) { b.Update(ref, updates) })
+		}
+		for _, edgeID := range removed {
+			ref := s.TechniqueEdges().Ref(edgeID)
+			writes = append(writes, func(b store.Batch) { b.Delete(ref) })
+		}
+		if err := store.CommitInBatches(ctx, s, writes); err != nil {
+			return nil, fmt.Errorf("move technique references: %w", err)
+		}
+	}
+
+	if report.Curricula, err = retargetCurricula(ctx, s, merged, sourceIDs, dryRun, change); err != nil {
+		return nil, fmt.Errorf("retarget curriculum elements: %w", err)
+	}
+
+	if !dryRun {
+		var writes []func(store.Batch)
+		for _, sourceID := range sourceIDs {
+			ref := s.Techniques().Ref(sourceID)
+			writes = append(writes, func(b store.Batch) { b.Delete(ref) })
+		}
+		if err := store.CommitInBatches(ctx, s, writes); err != nil {
+			return nil, fmt.Errorf("delete merged techniques: %w", err)
+		}
+	}
+	return report, nil
+}
@@ -14,7 +14,13 @@ type contextKey string
 const UserUIDKey contextKey = "userUID"
 const UserRolesKey contextKey = "userRoles"
 
-func FirebaseAuth(authClient *auth.Client) func(http.Handler) http.Handler {
+// TokenVerifier verifies Firebase ID tokens. *auth.Client satisfies it; tests
+// substitute a fake.
+type TokenVerifier interface {
+	VerifyIDToken(ctx context.Context, idToken string) (*auth.Token, error)
+}
+
+func FirebaseAuth(verifier TokenVerifier) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			slog.Info("FirebaseAuth middleware called", "path", r.URL.Path, "method", r.Method)
@@ -34,7 +40,7 @@ func FirebaseAuth(authClient *auth.Client) func(http.Handler) http.Handler {
 			}
 
 			slog.Info("FirebaseAuth: verifying token", "tokenLen", len(parts[1]))
-			token, err := authClient.VerifyIDToken(r.Context(), parts[1])
+			token, err := verifier.VerifyIDToken(r.Context(), parts[1])
 			if err != nil {
 				slog.Error("FirebaseAuth: token verification failed", "error", err)
 				http.Error(w, `{"error":"invalid or expired token"}`, http.StatusUnauthorized)
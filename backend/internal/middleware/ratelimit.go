@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limiterIdle is how long a client's limiter is kept after its last request.
+const limiterIdle = 10 * time.Minute
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimit allows each client perSecond requests on average, in bursts of
+// up to burst, and answers 429 beyond that. Clients are told apart by the
+// address the proxy in front of the API appends to X-Forwarded-For, or the
+// remote address without one.
+func RateLimit(perSecond float64, burst int) func(http.Handler) http.Handler {
+	var mu sync.Mutex
+	clients := map[string]*clientLimiter{}
+	lastSweep := time.Now()
+
+	allow := func(key string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		if now.Sub(lastSweep) > limiterIdle {
+			for k, c := range clients {
+				if now.Sub(c.lastSeen) > limiterIdle {
+					delete(clients, k)
+				}
+			}
+			lastSweep = now
+		}
+		c, ok := clients[key]
+		if !ok {
+			c = &clientLimiter{limiter: rate.NewLimiter(rate.Limit(perSecond), burst)}
+			clients[key] = c
+		}
+		c.lastSeen = now
+		return c.limiter.Allow()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !allow(clientAddr(r)) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"error":"too many requests"}`))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientAddr returns the last X-Forwarded-For address, which the client
+// cannot forge, or the host of the remote address.
+func clientAddr(r *http.Request) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		parts := strings.Split(fwd, ",")
+		if addr := strings.TrimSpace(parts[len(parts)-1]); addr != "" {
+			return addr
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
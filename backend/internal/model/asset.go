@@ -39,6 +39,9 @@ type Asset struct {
 	ProcessingError  *string   `json:"processingError" firestore:"processingError,omitempty"`
 	CreatedAt        time.Time `json:"createdAt" firestore:"createdAt"`
 	UpdatedAt        time.Time `json:"updatedAt" firestore:"updatedAt"`
+	// DurationSeconds is Duration in seconds, which the asset list filters
+	// and sorts by.
+	DurationSeconds *int `json:"durationSeconds" firestore:"durationSeconds,omitempty"`
 }
 
 type CreateAssetRequest struct {
@@ -49,6 +52,7 @@ type CreateAssetRequest struct {
 	VideoType    *string  `json:"videoType"`
 	Originator   *string  `json:"originator"`
 	ThumbnailURL *string  `json:"thumbnailUrl"`
+	Duration     *string  `json:"duration"`
 	TechniqueIDs []string `json:"techniqueIds"`
 	CategoryIDs  []string `json:"categoryIds"`
 	TagIDs       []string `json:"tagIds"`
@@ -62,6 +66,7 @@ type UpdateAssetRequest struct {
 	VideoType    *string  `json:"videoType"`
 	Originator   *string  `json:"originator"`
 	ThumbnailURL *string  `json:"thumbnailUrl"`
+	Duration     *string  `json:"duration"`
 	TechniqueIDs []string `json:"techniqueIds"`
 	CategoryIDs  []string `json:"categoryIds"`
 	TagIDs       []string `json:"tagIds"`
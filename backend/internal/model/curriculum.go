@@ -15,7 +15,32 @@ type Curriculum struct {
 	SearchText   string    `json:"-" firestore:"searchText"`
 	CreatedAt    time.Time `json:"createdAt" firestore:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt" firestore:"updatedAt"`
-	ElementCount int       `json:"elementCount,omitempty" firestore:"-"`
+	// ElementCount and TotalDurationSeconds summarize the elements. They are
+	// updated in the same transaction as every element write.
+	ElementCount         int `json:"elementCount" firestore:"elementCount"`
+	TotalDurationSeconds int `json:"totalDurationSeconds" firestore:"totalDurationSeconds"`
+	// TotalDuration is TotalDurationSeconds in clock notation; not stored.
+	TotalDuration string `json:"totalDuration" firestore:"-"`
+	// DurationSeconds is Duration in seconds, 0 when it is unset. It is
+	// derived from Duration with the other derived fields.
+	DurationSeconds int `json:"durationSeconds,omitempty" firestore:"durationSeconds,omitempty"`
+	// Revision is the number of the latest revision, 0 until the first
+	// recorded change.
+	Revision int `json:"revision" firestore:"revision"`
+	// SourceCurriculumID is set on forks to the curriculum they were copied
+	// from, and SourceRevision to the revision of it they last caught up with.
+	SourceCurriculumID string `json:"sourceCurriculumId,omitempty" firestore:"sourceCurriculumId,omitempty"`
+	SourceRevision     int    `json:"sourceRevision,omitempty" firestore:"sourceRevision,omitempty"`
+	// EditorUIDs and ViewerUIDs are the users invited to collaborate on the
+	// curriculum, each in at most one of them. They are read and changed
+	// through the collaborators endpoints only.
+	EditorUIDs []string `json:"-" firestore:"editorUids"`
+	ViewerUIDs []string `json:"-" firestore:"viewerUids"`
+	// ShareToken, when set, serves the curriculum read-only and without
+	// authentication at /share/{token}, until ShareExpiresAt unless that is
+	// zero. Only the owner sees them, through the share endpoints.
+	ShareToken     *string   `json:"-" firestore:"shareToken,omitempty"`
+	ShareExpiresAt time.Time `json:"-" firestore:"shareExpiresAt,omitempty"`
 }
 
 type ElementType string
@@ -26,6 +51,12 @@ const (
 	ElementTypeText      ElementType = "text"
 	ElementTypeImage     ElementType = "image"
 	ElementTypeList      ElementType = "list"
+	// ElementTypeSection groups the elements that name it as their parent.
+	// Sections sit at the top level and take their duration from their
+	// elements.
+	ElementTypeSection ElementType = "section"
+	// ElementTypeClip plays StartSeconds to EndSeconds of a video asset.
+	ElementTypeClip ElementType = "clip"
 )
 
 type CurriculumElement struct {
@@ -42,14 +73,54 @@ type CurriculumElement struct {
 	Snapshot    *Snapshot   `json:"snapshot,omitempty" firestore:"snapshot,omitempty"`
 	CreatedAt   time.Time   `json:"createdAt" firestore:"createdAt"`
 	UpdatedAt   time.Time   `json:"updatedAt" firestore:"updatedAt"`
+	// SourceElementID is set on elements of a fork to the upstream element
+	// they were copied from.
+	SourceElementID string `json:"sourceElementId,omitempty" firestore:"sourceElementId,omitempty"`
+	// ParentID is the section holding the element, nil at the top level.
+	// Ord numbers the element among its siblings.
+	ParentID *string `json:"parentId" firestore:"parentId,omitempty"`
+	// DurationSeconds is Duration in seconds. Elements written before it
+	// existed have only Duration.
+	DurationSeconds *int `json:"durationSeconds,omitempty" firestore:"durationSeconds,omitempty"`
+	// StartSeconds and EndSeconds bound the segment of the asset a clip
+	// element plays, and Caption says what to watch for in it.
+	StartSeconds *int    `json:"startSeconds,omitempty" firestore:"startSeconds,omitempty"`
+	EndSeconds   *int    `json:"endSeconds,omitempty" firestore:"endSeconds,omitempty"`
+	Caption      *string `json:"caption,omitempty" firestore:"caption,omitempty"`
+	// TotalDurationSeconds sums the durations of a section's elements, and
+	// Children holds them in the tree view. Neither is stored.
+	TotalDurationSeconds int                 `json:"totalDurationSeconds,omitempty" firestore:"-"`
+	Children             []CurriculumElement `json:"children,omitempty" firestore:"-"`
+	// SnapshotStatus tells, in element lists, whether the snapshot of a
+	// technique or asset element matches its source. It is not stored.
+	SnapshotStatus SnapshotStatus `json:"snapshotStatus,omitempty" firestore:"-"`
+	// ClipURL links to the start of a clip, and EmbedURL, for YouTube
+	// videos, embeds just the clip. Neither is stored.
+	ClipURL  string `json:"clipUrl,omitempty" firestore:"-"`
+	EmbedURL string `json:"embedUrl,omitempty" firestore:"-"`
 }
 
+// SnapshotStatus compares the snapshot of an element with its source.
+type SnapshotStatus string
+
+const (
+	// SnapshotCurrent: the source has not changed since the snapshot.
+	SnapshotCurrent SnapshotStatus = "current"
+	// SnapshotStale: the source changed, or the element has no snapshot.
+	SnapshotStale SnapshotStatus = "stale"
+	// SnapshotBroken: the source was deleted.
+	SnapshotBroken SnapshotStatus = "broken"
+)
+
 type Snapshot struct {
 	Name         string   `json:"name,omitempty" firestore:"name,omitempty"`
 	ThumbnailURL string   `json:"thumbnailUrl,omitempty" firestore:"thumbnailUrl,omitempty"`
 	URL          string   `json:"url,omitempty" firestore:"url,omitempty"`
 	Description  string   `json:"description,omitempty" firestore:"description,omitempty"`
 	TagIDs       []string `json:"tagIds,omitempty" firestore:"tagIds,omitempty"`
+	// SourceUpdatedAt is the updatedAt of the technique or asset when the
+	// snapshot was taken; zero on snapshots older than this field.
+	SourceUpdatedAt time.Time `json:"sourceUpdatedAt,omitzero" firestore:"sourceUpdatedAt,omitempty"`
 }
 
 type CreateCurriculumRequest struct {
@@ -77,8 +148,94 @@ type CreateElementRequest struct {
 	ImageURL    *string  `json:"imageUrl"`
 	Duration    *string  `json:"duration"`
 	Items       []string `json:"items"`
+	ParentID    *string  `json:"parentId"`
+	// StartSeconds, EndSeconds and Caption are for clip elements. On update,
+	// Type "clip" converts an asset element to a clip and "asset" back.
+	StartSeconds *int    `json:"startSeconds"`
+	EndSeconds   *int    `json:"endSeconds"`
+	Caption      *string `json:"caption"`
+}
+
+// CollaboratorRole is the access a collaborator is given to one curriculum.
+type CollaboratorRole string
+
+const (
+	CollaboratorViewer CollaboratorRole = "viewer"
+	CollaboratorEditor CollaboratorRole = "editor"
+)
+
+// Collaborator is a user invited to a curriculum. Email and DisplayName are
+// filled in from the user's account when it can be read.
+type Collaborator struct {
+	UID         string           `json:"uid"`
+	Role        CollaboratorRole `json:"role"`
+	Email       string           `json:"email,omitempty"`
+	DisplayName string           `json:"displayName,omitempty"`
+}
+
+// InviteCollaboratorRequest invites the user given by exactly one of UID and
+// Email, or changes the role of one already invited.
+type InviteCollaboratorRequest struct {
+	UID   *string `json:"uid"`
+	Email *string `json:"email"`
+	Role  string  `json:"role"`
+}
+
+// ShareLink is the public link of a curriculum. Path is relative to the API
+// host.
+type ShareLink struct {
+	Token     string     `json:"token"`
+	Path      string     `json:"path"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// ShareCurriculumRequest creates the share link of a curriculum or changes
+// its expiry; Rotate replaces the token of an existing link. A nil ExpiresAt
+// makes the link last until it is revoked.
+type ShareCurriculumRequest struct {
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Rotate    bool       `json:"rotate"`
+}
+
+// SharedCurriculum is what a share link serves: the content of a curriculum
+// without its owner, collaborators or tags. Elements are nested under their
+// sections as in the tree view.
+type SharedCurriculum struct {
+	Title                string              `json:"title"`
+	Description          string              `json:"description"`
+	Duration             *string             `json:"duration,omitempty"`
+	ElementCount         int                 `json:"elementCount"`
+	TotalDurationSeconds int                 `json:"totalDurationSeconds"`
+	TotalDuration        string              `json:"totalDuration"`
+	UpdatedAt            time.Time           `json:"updatedAt"`
+	Elements             []CurriculumElement `json:"elements"`
+}
+
+// RefreshElementsRequest names the elements to re-snapshot; when empty,
+// every technique and asset element is checked.
+type RefreshElementsRequest struct {
+	ElementIDs []string `json:"elementIds"`
+}
+
+type ForkCurriculumRequest struct {
+	Title *string `json:"title"`
+}
+
+// ImportCurriculumRequest imports an outline (see package outline). Title
+// overrides the title the outline gives. Without Commit only the preview is
+// returned; AllowUnresolved commits references that matched nothing as text
+// elements.
+type ImportCurriculumRequest struct {
+	Format          string  `json:"format"`
+	Source          string  `json:"source"`
+	Title           *string `json:"title"`
+	Commit          bool    `json:"commit"`
+	AllowUnresolved bool    `json:"allowUnresolved"`
 }
 
+// ReorderElementsRequest places the listed elements, in order, first among
+// the elements of section ParentID, or of the top level when it is empty.
 type ReorderElementsRequest struct {
+	ParentID   *string  `json:"parentId"`
 	OrderedIDs []string `json:"orderedIds"`
 }
@@ -0,0 +1,16 @@
+package model
+
+// Facets counts how many items of a filtered list carry each tag, category
+// and video type, so clients can offer narrowing options.
+type Facets struct {
+	Tags       map[string]int `json:"tags"`
+	Categories map[string]int `json:"categories"`
+	VideoTypes map[string]int `json:"videoTypes,omitempty"`
+}
+
+// FacetedList is the list response returned when facets are requested.
+// Facets are only counted for the first page of a list.
+type FacetedList[T any] struct {
+	Items  []T     `json:"items"`
+	Facets *Facets `json:"facets,omitempty"`
+}
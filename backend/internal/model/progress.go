@@ -0,0 +1,79 @@
+package model
+
+import "time"
+
+// Progress states of a learner on one element, from least to most advanced.
+const (
+	ProgressNotStarted = "not-started"
+	ProgressWatched    = "watched"
+	ProgressDrilled    = "drilled"
+	ProgressMastered   = "mastered"
+)
+
+// ProgressStates lists the progress states in order.
+var ProgressStates = []string{ProgressNotStarted, ProgressWatched, ProgressDrilled, ProgressMastered}
+
+// Progress records how far a learner got with one curriculum element. Its
+// ID is derived from the three keys (see ProgressID).
+type Progress struct {
+	ID           string `json:"-" firestore:"-"`
+	UID          string `json:"uid" firestore:"uid"`
+	DisciplineID string `json:"disciplineId" firestore:"disciplineId"`
+	CurriculumID string `json:"curriculumId" firestore:"curriculumId"`
+	ElementID    string `json:"elementId" firestore:"elementId"`
+	State        string `json:"state" firestore:"state"`
+	Notes        string `json:"notes" firestore:"notes"`
+	// StartedAt is when the element first left not-started; CompletedAt
+	// when it was last mastered, zero unless it is.
+	StartedAt   time.Time `json:"startedAt,omitzero" firestore:"startedAt,omitempty"`
+	CompletedAt time.Time `json:"completedAt,omitzero" firestore:"completedAt,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// ProgressID returns the document ID of a learner's progress on an element.
+// The UID comes last, as the other IDs are generated and contain no "_".
+func ProgressID(uid, curriculumID, elementID string) string {
+	return curriculumID + "_" + elementID + "_" + uid
+}
+
+// UpdateProgressRequest sets the state of an element, its notes, or both.
+type UpdateProgressRequest struct {
+	State *string `json:"state"`
+	Notes *string `json:"notes"`
+}
+
+// ProgressCounts counts elements by progress state.
+type ProgressCounts struct {
+	NotStarted int `json:"notStarted"`
+	Watched    int `json:"watched"`
+	Drilled    int `json:"drilled"`
+	Mastered   int `json:"mastered"`
+}
+
+// CurriculumProgress is one learner's progress through a curriculum. Total
+// counts the elements that take progress, all but sections, and
+// PercentComplete the share of them mastered, rounded down.
+type CurriculumProgress struct {
+	CurriculumID    string         `json:"curriculumId"`
+	Title           string         `json:"title"`
+	Total           int            `json:"total"`
+	Counts          ProgressCounts `json:"counts"`
+	PercentComplete int            `json:"percentComplete"`
+	// UpdatedAt is the learner's latest change, zero without progress.
+	UpdatedAt time.Time `json:"updatedAt,omitzero"`
+	// Elements holds the learner's records, on the single curriculum view.
+	Elements []Progress `json:"elements,omitempty"`
+}
+
+// ProgressReport aggregates the progress of every learner through a
+// curriculum: how many have started and completed it, their average
+// PercentComplete, and their element counts summed.
+type ProgressReport struct {
+	CurriculumID   string         `json:"curriculumId"`
+	Title          string         `json:"title"`
+	Total          int            `json:"total"`
+	Learners       int            `json:"learners"`
+	Completed      int            `json:"completed"`
+	AveragePercent int            `json:"averagePercent"`
+	Counts         ProgressCounts `json:"counts"`
+}
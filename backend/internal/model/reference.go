@@ -0,0 +1,16 @@
+package model
+
+// ReferenceError is the response to a request naming tags, categories,
+// techniques or assets that do not exist or belong to another discipline.
+type ReferenceError struct {
+	Error  string             `json:"error"`
+	Fields []InvalidReference `json:"fields"`
+}
+
+// InvalidReference lists the IDs of one request field that name no
+// document, or a document of another discipline.
+type InvalidReference struct {
+	Field           string   `json:"field"`
+	Missing         []string `json:"missing,omitempty"`
+	OtherDiscipline []string `json:"otherDiscipline,omitempty"`
+}
@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// RevisionAction names the change that produced a curriculum revision.
+type RevisionAction string
+
+const (
+	RevisionCreate         RevisionAction = "create"
+	RevisionUpdate         RevisionAction = "update"
+	RevisionElementCreate  RevisionAction = "element.create"
+	RevisionElementUpdate  RevisionAction = "element.update"
+	RevisionElementDelete  RevisionAction = "element.delete"
+	RevisionElementReorder RevisionAction = "element.reorder"
+	RevisionElementRefresh RevisionAction = "element.refresh"
+	RevisionRestore        RevisionAction = "restore"
+	RevisionFork           RevisionAction = "fork"
+	RevisionPull           RevisionAction = "pull"
+	RevisionTechniqueMerge RevisionAction = "technique.merge"
+	RevisionDetach         RevisionAction = "reference.detach"
+	RevisionReassign       RevisionAction = "reference.reassign"
+)
+
+// CurriculumRevision is an immutable record of a curriculum and all of its
+// elements right after one change. Revisions are numbered from 1 within a
+// curriculum, and the number is also the document ID.
+type CurriculumRevision struct {
+	ID           string         `json:"id" firestore:"-"`
+	Number       int            `json:"number" firestore:"number"`
+	Action       RevisionAction `json:"action" firestore:"action"`
+	ActorUID     string         `json:"actorUid" firestore:"actorUid"`
+	RestoredFrom int            `json:"restoredFrom,omitempty" firestore:"restoredFrom,omitempty"`
+	Title        string         `json:"title" firestore:"title"`
+	ElementCount int            `json:"elementCount" firestore:"elementCount"`
+	CreatedAt    time.Time      `json:"createdAt" firestore:"createdAt"`
+	// Content is the JSON encoding of a RevisionContent. Revisions are never
+	// queried by content, so it is stored as a single opaque value.
+	Content string `json:"-" firestore:"content"`
+}
+
+// RevisionContent is the state of a curriculum recorded by a revision.
+// Elements are in curriculum order and keep their IDs.
+type RevisionContent struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	Duration    *string             `json:"duration,omitempty"`
+	IsPublic    bool                `json:"isPublic"`
+	TagIDs      []string            `json:"tagIds"`
+	Elements    []CurriculumElement `json:"elements"`
+}
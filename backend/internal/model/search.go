@@ -0,0 +1,19 @@
+package model
+
+// Search result types.
+const (
+	SearchTypeTechnique  = "technique"
+	SearchTypeAsset      = "asset"
+	SearchTypeCurriculum = "curriculum"
+)
+
+// SearchResult is one ranked hit of GET /search. Highlights maps a field name
+// to an HTML-escaped snippet with matched words wrapped in <mark>.
+type SearchResult struct {
+	Type         string            `json:"type"`
+	ID           string            `json:"id"`
+	DisciplineID string            `json:"disciplineId"`
+	Title        string            `json:"title"`
+	Score        float64           `json:"score"`
+	Highlights   map[string]string `json:"highlights"`
+}
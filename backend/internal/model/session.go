@@ -0,0 +1,84 @@
+package model
+
+import "time"
+
+// Session schedules a curriculum: where it is taught, from StartsAt on,
+// once or repeating by Recurrence. StartsAt is the first occurrence; its
+// wall-clock time in TimeZone is kept by every repeat, across daylight
+// saving changes.
+type Session struct {
+	ID           string `json:"id" firestore:"-"`
+	DisciplineID string `json:"disciplineId" firestore:"disciplineId"`
+	CurriculumID string `json:"curriculumId" firestore:"curriculumId"`
+	// Title names the session in calendars; empty uses the curriculum's.
+	Title    string    `json:"title" firestore:"title"`
+	StartsAt time.Time `json:"startsAt" firestore:"startsAt"`
+	TimeZone string    `json:"timeZone" firestore:"timeZone"`
+	// Duration is the length of each occurrence in clock notation.
+	Duration        string `json:"duration" firestore:"duration"`
+	DurationSeconds int    `json:"durationSeconds" firestore:"durationSeconds"`
+	// Recurrence is an iCalendar RRULE without the "RRULE:" prefix, empty
+	// for a single occurrence (see package schedule).
+	Recurrence string `json:"recurrence" firestore:"recurrence"`
+	// EndsAt is when the last occurrence ends, far in the future for a
+	// session that repeats without end (see schedule.End). The schedule
+	// skips sessions that ended before its window by it.
+	EndsAt    time.Time `json:"-" firestore:"endsAt"`
+	Location  string    `json:"location" firestore:"location"`
+	OwnerUID  string    `json:"ownerUid" firestore:"ownerUid"`
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// CreateSessionRequest schedules a curriculum. StartsAt is read in TimeZone
+// (default UTC) when it has no offset, as in "2026-10-19T18:30:00". Duration
+// defaults to the curriculum's planned duration, then its total, then an
+// hour.
+type CreateSessionRequest struct {
+	CurriculumID string  `json:"curriculumId"`
+	Title        string  `json:"title"`
+	StartsAt     string  `json:"startsAt"`
+	TimeZone     string  `json:"timeZone"`
+	Duration     *string `json:"duration"`
+	Recurrence   string  `json:"recurrence"`
+	Location     string  `json:"location"`
+}
+
+// UpdateSessionRequest changes the given fields of a session. An empty
+// Recurrence makes it a single occurrence.
+type UpdateSessionRequest struct {
+	Title      *string `json:"title"`
+	StartsAt   *string `json:"startsAt"`
+	TimeZone   *string `json:"timeZone"`
+	Duration   *string `json:"duration"`
+	Recurrence *string `json:"recurrence"`
+	Location   *string `json:"location"`
+}
+
+// Occurrence is one meeting of a session, as listed by the schedule.
+type Occurrence struct {
+	SessionID    string    `json:"sessionId"`
+	CurriculumID string    `json:"curriculumId"`
+	Title        string    `json:"title"`
+	StartsAt     time.Time `json:"startsAt"`
+	EndsAt       time.Time `json:"endsAt"`
+	Location     string    `json:"location"`
+}
+
+// CalendarFeed is a secret iCalendar feed URL of a user's sessions: those of
+// one discipline, or with no DisciplineID the sessions of curricula they own
+// or collaborate on. The ID is the secret token.
+type CalendarFeed struct {
+	ID           string    `json:"id" firestore:"-"`
+	OwnerUID     string    `json:"ownerUid" firestore:"ownerUid"`
+	DisciplineID string    `json:"disciplineId" firestore:"disciplineId"`
+	CreatedAt    time.Time `json:"createdAt" firestore:"createdAt"`
+	// Path is the feed's URL relative to the API host; not stored.
+	Path string `json:"path" firestore:"-"`
+}
+
+// CreateCalendarFeedRequest creates a feed of one discipline, or with an
+// empty DisciplineID the user's own feed.
+type CreateCalendarFeedRequest struct {
+	DisciplineID string `json:"disciplineId"`
+}
@@ -2,17 +2,24 @@ package model
 
 import "time"
 
+// MaxTechniqueAliases bounds the aliases of one technique.
+const MaxTechniqueAliases = 20
+
 type Technique struct {
-	ID           string    `json:"id" firestore:"-"`
-	DisciplineID string    `json:"disciplineId" firestore:"disciplineId"`
-	Name         string    `json:"name" firestore:"name"`
-	Slug         string    `json:"slug" firestore:"slug"`
-	Description  string    `json:"description" firestore:"description"`
-	CategoryIDs  []string  `json:"categoryIds" firestore:"categoryIds"`
-	TagIDs       []string  `json:"tagIds" firestore:"tagIds"`
-	OwnerUID     string    `json:"ownerUid" firestore:"ownerUid"`
-	CreatedAt    time.Time `json:"createdAt" firestore:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt" firestore:"updatedAt"`
+	ID           string `json:"id" firestore:"-"`
+	DisciplineID string `json:"disciplineId" firestore:"disciplineId"`
+	Name         string `json:"name" firestore:"name"`
+	Slug         string `json:"slug" firestore:"slug"`
+	// Aliases are other names of the technique; AliasSlugs are their slugs,
+	// which resolve to it like Slug does.
+	Aliases     []string  `json:"aliases" firestore:"aliases"`
+	AliasSlugs  []string  `json:"-" firestore:"aliasSlugs"`
+	Description string    `json:"description" firestore:"description"`
+	CategoryIDs []string  `json:"categoryIds" firestore:"categoryIds"`
+	TagIDs      []string  `json:"tagIds" firestore:"tagIds"`
+	OwnerUID    string    `json:"ownerUid" firestore:"ownerUid"`
+	CreatedAt   time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt" firestore:"updatedAt"`
 
 	// Resolved relations (not stored in Firestore)
 	Categories []Category `json:"categories,omitempty" firestore:"-"`
@@ -21,6 +28,7 @@ type Technique struct {
 
 type CreateTechniqueRequest struct {
 	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases"`
 	Description string   `json:"description"`
 	CategoryIDs []string `json:"categoryIds"`
 	TagIDs      []string `json:"tagIds"`
@@ -28,7 +36,41 @@ type CreateTechniqueRequest struct {
 
 type UpdateTechniqueRequest struct {
 	Name        *string  `json:"name"`
+	Aliases     []string `json:"aliases"`
 	Description *string  `json:"description"`
 	CategoryIDs []string `json:"categoryIds"`
 	TagIDs      []string `json:"tagIds"`
 }
+
+// Slugs returns the slugs t is known by: its own, then its aliases'.
+func (t *Technique) Slugs() []string {
+	return append([]string{t.Slug}, t.AliasSlugs...)
+}
+
+// MergeTechniquesRequest names the techniques to merge into another.
+type MergeTechniquesRequest struct {
+	SourceIDs []string `json:"sourceIds"`
+}
+
+// MergedCurriculum lists the elements of a curriculum that a technique
+// merge points at the merged technique.
+type MergedCurriculum struct {
+	CurriculumID string   `json:"curriculumId"`
+	Title        string   `json:"title"`
+	ElementIDs   []string `json:"elementIds"`
+}
+
+// TechniqueMergeReport describes a technique merge: the merged technique,
+// the aliases it gained, and the references moved to it from the sources.
+// Edges that would duplicate another edge, or join the technique to itself,
+// are removed. Applied is false on a dry run.
+type TechniqueMergeReport struct {
+	Technique    Technique          `json:"technique"`
+	SourceIDs    []string           `json:"sourceIds"`
+	AliasesAdded []string           `json:"aliasesAdded"`
+	AssetIDs     []string           `json:"assetIds"`
+	Curricula    []MergedCurriculum `json:"curricula"`
+	EdgesMoved   []string           `json:"edgesMoved"`
+	EdgesRemoved []string           `json:"edgesRemoved"`
+	Applied      bool               `json:"applied"`
+}
@@ -0,0 +1,85 @@
+package model
+
+import "time"
+
+// Technique edge types. Edges are directed: a sweep leads-to a position, a
+// frame counters a pass, a grip is a setup-for a submission, a technique is
+// a variation-of another, and one requires another to be learned first.
+const (
+	EdgeLeadsTo     = "leads-to"
+	EdgeCounters    = "counters"
+	EdgeSetupFor    = "setup-for"
+	EdgeVariationOf = "variation-of"
+	EdgeRequires    = "requires"
+)
+
+// EdgeTypes lists the technique edge types.
+var EdgeTypes = []string{EdgeLeadsTo, EdgeCounters, EdgeSetupFor, EdgeVariationOf, EdgeRequires}
+
+// TechniqueEdge is a typed, directed relationship between two techniques of
+// one discipline, optionally explained by notes and assets.
+type TechniqueEdge struct {
+	ID           string    `json:"id" firestore:"-"`
+	DisciplineID string    `json:"disciplineId" firestore:"disciplineId"`
+	FromID       string    `json:"fromId" firestore:"fromId"`
+	ToID         string    `json:"toId" firestore:"toId"`
+	Type         string    `json:"type" firestore:"type"`
+	Notes        string    `json:"notes" firestore:"notes"`
+	AssetIDs     []string  `json:"assetIds" firestore:"assetIds"`
+	OwnerUID     string    `json:"ownerUid" firestore:"ownerUid"`
+	CreatedAt    time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+type CreateTechniqueEdgeRequest struct {
+	FromID   string   `json:"fromId"`
+	ToID     string   `json:"toId"`
+	Type     string   `json:"type"`
+	Notes    string   `json:"notes"`
+	AssetIDs []string `json:"assetIds"`
+}
+
+// UpdateTechniqueEdgeRequest changes an edge's type, notes or assets; its
+// ends are fixed.
+type UpdateTechniqueEdgeRequest struct {
+	Type     *string  `json:"type"`
+	Notes    *string  `json:"notes"`
+	AssetIDs []string `json:"assetIds"`
+}
+
+// GraphNode is a technique as a node of the technique graph.
+type GraphNode struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// Neighbor is a technique one edge away, and that edge. Direction is "out"
+// for edges from the technique asked about and "in" for edges to it.
+type Neighbor struct {
+	Direction string        `json:"direction"`
+	Edge      TechniqueEdge `json:"edge"`
+	Technique GraphNode     `json:"technique"`
+}
+
+// TechniquePath is a walk through the graph: Techniques has one more entry
+// than Edges, and Edges[i] joins Techniques[i] and Techniques[i+1].
+type TechniquePath struct {
+	Length     int             `json:"length"`
+	Techniques []GraphNode     `json:"techniques"`
+	Edges      []TechniqueEdge `json:"edges"`
+}
+
+// TechniqueChains lists the chains from a technique. Truncated is set when
+// there were more than were returned.
+type TechniqueChains struct {
+	Chains    []TechniquePath `json:"chains"`
+	Truncated bool            `json:"truncated"`
+}
+
+// TechniqueGraph is the technique graph of a discipline, as exported.
+type TechniqueGraph struct {
+	DisciplineID string          `json:"disciplineId"`
+	Nodes        []GraphNode     `json:"nodes"`
+	Edges        []TechniqueEdge `json:"edges"`
+}
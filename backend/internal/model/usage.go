@@ -0,0 +1,42 @@
+package model
+
+// DeletePolicy says what deleting a tag, category, technique or asset does
+// to the documents that refer to it.
+type DeletePolicy string
+
+const (
+	// DeleteRestrict refuses the delete while anything refers to it.
+	DeleteRestrict DeletePolicy = "restrict"
+	// DeleteDetach removes the references, then deletes it.
+	DeleteDetach DeletePolicy = "detach"
+	// DeleteReassign moves the references to a replacement, then deletes it.
+	DeleteReassign DeletePolicy = "reassign"
+)
+
+// Usages lists the documents that refer to a tag, category, technique or
+// asset. CategoryIDs are the subcategories of a category.
+type Usages struct {
+	Total        int               `json:"total"`
+	TechniqueIDs []string          `json:"techniqueIds"`
+	AssetIDs     []string          `json:"assetIds"`
+	CategoryIDs  []string          `json:"categoryIds"`
+	EdgeIDs      []string          `json:"edgeIds"`
+	Curricula    []CurriculumUsage `json:"curricula"`
+}
+
+// CurriculumUsage lists what of a curriculum refers to a tag, technique or
+// asset: its own tags when Tagged, and the elements, by reference or, for
+// tags, by snapshot.
+type CurriculumUsage struct {
+	CurriculumID string   `json:"curriculumId"`
+	Title        string   `json:"title"`
+	Tagged       bool     `json:"tagged"`
+	ElementIDs   []string `json:"elementIds"`
+}
+
+// UsageConflict is the response to a restricted delete of something still
+// in use.
+type UsageConflict struct {
+	Error  string `json:"error"`
+	Usages Usages `json:"usages"`
+}
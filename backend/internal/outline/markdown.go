@@ -0,0 +1,122 @@
+package outline
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+var (
+	mdHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	mdItem    = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(.*)$`)
+	mdNumber  = regexp.MustCompile(`^\d+(?:\.\d+)*[.)]\s+`)
+)
+
+// ParseMarkdown reads a Markdown outline:
+//
+//   - The first level-1 heading is the curriculum title, and the paragraphs
+//     after it, up to the next heading or list, its description.
+//   - Every other heading starts a section. Its paragraphs become a text
+//     element and its bullet or numbered lists a list element, both titled
+//     with the heading; a heading with nothing under it is a text element
+//     on its own. A trailing "(10m)" gives the element a duration, and a
+//     leading "1." is dropped, so exported handouts read back.
+//   - A paragraph or list item that is a YouTube link, an image, a line
+//     starting "technique:" or "asset:", or the name of a technique becomes
+//     an element of its own (see Build).
+func ParseMarkdown(src string) (*Draft, error) {
+	d := &Draft{}
+	var section *Entry // heading of the current section, until it is used
+	sectionTitle, inSection := "Notes", false
+	open := -1 // index in d.Entries of the entry taking more lines
+	var para []string
+	paraLine := 0
+
+	// add appends a paragraph or item to the open entry of type t, starting
+	// one if needed.
+	add := func(t model.ElementType, l Line) {
+		if !inSection && t == model.ElementTypeText && len(d.Entries) == 0 && d.Title != "" {
+			if d.Description != "" {
+				d.Description += "\n\n"
+			}
+			d.Description += l.Text
+			return
+		}
+		if open < 0 || d.Entries[open].Type != t {
+			e := Entry{Line: l.N, Type: t, Title: sectionTitle, Lines: []Line{}}
+			if section != nil {
+				e.Duration, section = section.Duration, nil
+			}
+			d.Entries = append(d.Entries, e)
+			open = len(d.Entries) - 1
+		}
+		d.Entries[open].Lines = append(d.Entries[open].Lines, l)
+	}
+	flushPara := func() {
+		if len(para) > 0 {
+			add(model.ElementTypeText, Line{N: paraLine, Text: strings.Join(para, "\n")})
+			para = nil
+		}
+	}
+	// endSection keeps a heading with nothing under it as an element.
+	endSection := func() {
+		flushPara()
+		if section != nil {
+			d.Entries = append(d.Entries, *section)
+			section = nil
+		}
+		open = -1
+	}
+
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		n, line := i+1, lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch m := mdHeading.FindStringSubmatch(trimmed); {
+		case trimmed == "":
+			flushPara()
+		case strings.HasPrefix(trimmed, "```"):
+			// A fenced block stays in its paragraph as written.
+			if len(para) == 0 {
+				paraLine = n
+			}
+			para = append(para, line)
+			for i++; i < len(lines); i++ {
+				para = append(para, lines[i])
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), "```") {
+					break
+				}
+			}
+		case m != nil && len(m[1]) == 1 && d.Title == "" && len(d.Entries) == 0 && !inSection:
+			flushPara()
+			d.Title = m[2]
+		case m != nil:
+			endSection()
+			title, dur := splitDuration(mdNumber.ReplaceAllString(m[2], ""))
+			if title == "" {
+				return nil, &SyntaxError{Line: n, Msg: "empty heading"}
+			}
+			section = &Entry{Line: n, Type: model.ElementTypeText, Title: title, Duration: dur, Lines: []Line{}}
+			sectionTitle, inSection = title, true
+		case mdItem.MatchString(line) && (len(para) == 0 || !strings.HasPrefix(line, " ")):
+			flushPara()
+			add(model.ElementTypeList, Line{N: n, Text: strings.TrimSpace(mdItem.FindStringSubmatch(line)[1])})
+		case len(para) == 0 && open >= 0 && d.Entries[open].Type == model.ElementTypeList && line != trimmed:
+			// An indented line continues the last list item.
+			items := d.Entries[open].Lines
+			items[len(items)-1].Text += " " + trimmed
+		default:
+			if len(para) == 0 {
+				paraLine = n
+				// A paragraph ends the list before it.
+				if open >= 0 && d.Entries[open].Type == model.ElementTypeList {
+					open = -1
+				}
+			}
+			para = append(para, trimmed)
+		}
+	}
+	endSection()
+	return d, nil
+}
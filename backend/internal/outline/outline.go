@@ -0,0 +1,391 @@
+// Package outline turns a class plan written as a Markdown outline or a YAML
+// document into a curriculum and its elements. Lines that name a technique
+// or link a video are resolved against the discipline's techniques and
+// assets; what cannot be resolved is reported in a preview before anything
+// is written.
+package outline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/duration"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+	"github.com/thomas/skillhive-api/internal/validate"
+	"github.com/thomas/skillhive-api/internal/youtube"
+)
+
+// Format is an outline format.
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatYAML     Format = "yaml"
+)
+
+// ErrNoTitle is returned when an outline does not give the curriculum a
+// title.
+var ErrNoTitle = errors.New("outline: no curriculum title")
+
+// SyntaxError reports a problem with an outline, at a line if Line is not 0.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Line == 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Draft is a parsed outline whose references are not resolved yet.
+type Draft struct {
+	Title       string
+	Description string
+	Duration    string
+	Entries     []Entry
+}
+
+// Entry is one element of a draft. Technique entries name their technique
+// in Ref, by slug or name, and asset entries give the asset's URL.
+type Entry struct {
+	Line     int
+	Type     model.ElementType
+	Title    string
+	Details  string
+	Items    []string
+	Duration string
+	ImageURL string
+	Ref      string
+	// Lines holds the paragraphs of a text entry or the items of a list
+	// entry read from free text. Build gives those that name a technique or
+	// link a video or image an element of their own. A text entry with empty
+	// Lines is a heading on its own, which may name a technique.
+	Lines []Line
+}
+
+// Line is a paragraph or list item of free text.
+type Line struct {
+	N    int
+	Text string
+}
+
+// Parse reads an outline in format f.
+func Parse(f Format, src string) (*Draft, error) {
+	switch f {
+	case FormatMarkdown:
+		return ParseMarkdown(src)
+	case FormatYAML:
+		return ParseYAML(src)
+	}
+	return nil, fmt.Errorf("outline: unknown format %q", f)
+}
+
+// Unresolved is a reference of an outline that matched nothing. Its element
+// is previewed, and imported if allowed, as a text element.
+type Unresolved struct {
+	Line int `json:"line"`
+	// Element is the index of the element in the preview.
+	Element int    `json:"element"`
+	Kind    string `json:"kind"`
+	Ref     string `json:"ref"`
+}
+
+// Preview is what importing an outline creates: the curriculum, without ID
+// and owner until it is committed, and its elements in order.
+type Preview struct {
+	Curriculum model.Curriculum          `json:"curriculum"`
+	Elements   []model.CurriculumElement `json:"elements"`
+	Unresolved []Unresolved              `json:"unresolved"`
+}
+
+// Resolver finds the techniques and assets of a discipline that outline
+// lines refer to.
+type Resolver struct {
+	store        store.Store
+	disciplineID string
+	techniques   map[string]*model.Technique // by slug, loaded on first use
+	assets       map[string]*model.Asset     // by URL as written
+}
+
+func NewResolver(s store.Store, disciplineID string) *Resolver {
+	return &Resolver{store: s, disciplineID: disciplineID, assets: map[string]*model.Asset{}}
+}
+
+// Technique returns the technique whose slug matches ref, a slug or a
+// technique name, or nil. Aliases match too, but never shadow a name.
+func (r *Resolver) Technique(ctx context.Context, ref string) (*model.Technique, error) {
+	if r.techniques == nil {
+		all, err := r.store.Techniques().List(ctx, store.NewQuery().Where("disciplineId", store.OpEqual, r.disciplineID))
+		if err != nil {
+			return nil, err
+		}
+		r.techniques = make(map[string]*model.Technique, len(all))
+		for i := range all {
+			for _, slug := range all[i].AliasSlugs {
+				if _, ok := r.techniques[slug]; !ok {
+					r.techniques[slug] = &all[i]
+				}
+			}
+		}
+		for i := range all {
+			r.techniques[all[i].Slug] = &all[i]
+		}
+	}
+	return r.techniques[validate.GenerateSlug(ref)], nil
+}
+
+// Asset returns the asset at url, or nil. A YouTube video matches whichever
+// of its URL forms the asset was saved with.
+func (r *Resolver) Asset(ctx context.Context, url string) (*model.Asset, error) {
+	if a, ok := r.assets[url]; ok {
+		return a, nil
+	}
+	urls := []string{url}
+	if id := youtube.ExtractVideoID(url); id != "" {
+		for _, host := range []string{"https://www.youtube.com/", "https://youtube.com/", "https://m.youtube.com/"} {
+			urls = append(urls, host+"watch?v="+id, host+"shorts/"+id, host+"embed/"+id)
+		}
+		urls = append(urls, "https://youtu.be/"+id)
+	}
+	found, err := r.store.Assets().List(ctx, store.NewQuery().
+		Where("disciplineId", store.OpEqual, r.disciplineID).
+		Where("url", store.OpIn, urls).
+		Limit(1))
+	if err != nil {
+		return nil, err
+	}
+	var a *model.Asset
+	if len(found) > 0 {
+		a = &found[0]
+	}
+	r.assets[url] = a
+	return a, nil
+}
+
+var (
+	imageLine     = regexp.MustCompile(`^!\[([^\]]*)\]\(<?([^()<>\s]+)>?\)$`)
+	urlLine       = regexp.MustCompile(`^<?(https?://\S+?)>?$`)
+	prefixedLine  = regexp.MustCompile(`(?i)^(technique|asset):\s*(.+)$`)
+	trailDuration = regexp.MustCompile(`^(.*?)\s*\(([^()]+)\)$`)
+)
+
+// splitDuration separates a trailing "(5:00)" or "(10m)" from text.
+func splitDuration(text string) (rest, dur string) {
+	if m := trailDuration.FindStringSubmatch(text); m != nil && m[1] != "" {
+		if _, ok := duration.Parse(m[2]); ok {
+			return m[1], m[2]
+		}
+	}
+	return text, ""
+}
+
+// classify returns the entry for a line of free text that stands for an
+// element of its own: an image, a YouTube link or a URL given as an asset,
+// a line starting "technique:", or a line naming an existing technique.
+func classify(ctx context.Context, r *Resolver, l Line) (*Entry, error) {
+	text, dur := splitDuration(strings.TrimSpace(l.Text))
+	if strings.Contains(text, "\n") {
+		return nil, nil
+	}
+	if m := imageLine.FindStringSubmatch(text); m != nil {
+		return &Entry{Line: l.N, Type: model.ElementTypeImage, Title: m[1], ImageURL: m[2], Duration: dur}, nil
+	}
+	if m := prefixedLine.FindStringSubmatch(text); m != nil {
+		ref := strings.Trim(strings.TrimSpace(m[2]), "<>")
+		return &Entry{Line: l.N, Type: model.ElementType(strings.ToLower(m[1])), Ref: ref, Duration: dur}, nil
+	}
+	if m := urlLine.FindStringSubmatch(text); m != nil && youtube.IsYouTubeURL(m[1]) {
+		return &Entry{Line: l.N, Type: model.ElementTypeAsset, Ref: m[1], Duration: dur}, nil
+	}
+	t, err := r.Technique(ctx, text)
+	if t == nil || err != nil {
+		return nil, err
+	}
+	return &Entry{Line: l.N, Type: model.ElementTypeTechnique, Ref: text, Duration: dur}, nil
+}
+
+// expand splits entries read from free text around the lines that stand
+// for elements of their own. The parts keep the entry's title; its
+// duration goes with the first.
+func expand(ctx context.Context, r *Resolver, entries []Entry) ([]Entry, error) {
+	var out []Entry
+	for _, entry := range entries {
+		if entry.Lines == nil {
+			out = append(out, entry)
+			continue
+		}
+		if len(entry.Lines) == 0 {
+			// A heading on its own may name a technique.
+			ref, err := classify(ctx, r, Line{N: entry.Line, Text: entry.Title})
+			if err != nil {
+				return nil, err
+			}
+			if ref != nil && ref.Type == model.ElementTypeTechnique {
+				ref.Duration = entry.Duration
+				entry = *ref
+			}
+			entry.Lines = nil
+			out = append(out, entry)
+			continue
+		}
+
+		var group []Line
+		flush := func() {
+			if len(group) == 0 {
+				return
+			}
+			part := entry
+			part.Line, part.Lines = group[0].N, nil
+			for _, l := range group {
+				if entry.Type == model.ElementTypeList {
+					part.Items = append(part.Items, l.Text)
+				} else if part.Details == "" {
+					part.Details = l.Text
+				} else {
+					part.Details += "\n\n" + l.Text
+				}
+			}
+			out = append(out, part)
+			entry.Duration, group = "", nil
+		}
+		for _, l := range entry.Lines {
+			ref, err := classify(ctx, r, l)
+			if err != nil {
+				return nil, err
+			}
+			if ref == nil {
+				group = append(group, l)
+				continue
+			}
+			flush()
+			out = append(out, *ref)
+		}
+		flush()
+	}
+	return out, nil
+}
+
+// Build resolves a draft into the curriculum and elements it describes,
+// sanitized like the API sanitizes input.
+func Build(ctx context.Context, r *Resolver, d *Draft) (*Preview, error) {
+	title := validate.StripAllHTML(d.Title)
+	if title == "" {
+		return nil, ErrNoTitle
+	}
+	if err := validate.StringLength("title", title, 1, 200); err != nil {
+		return nil, &SyntaxError{Msg: err.Error()}
+	}
+	p := &Preview{
+		Curriculum: model.Curriculum{
+			DisciplineID: r.disciplineID,
+			Title:        title,
+			Description:  validate.StripAllHTML(d.Description),
+			TagIDs:       []string{},
+		},
+		Elements:   []model.CurriculumElement{},
+		Unresolved: []Unresolved{},
+	}
+	if d.Duration != "" {
+		text, seconds, ok := duration.Normalize(validate.StripAllHTML(d.Duration))
+		if !ok {
+			return nil, &SyntaxError{Msg: "duration must be a duration such as " + duration.Examples}
+		}
+		p.Curriculum.Duration, p.Curriculum.DurationSeconds = &text, seconds
+	}
+
+	entries, err := expand(ctx, r, d.Entries)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		e := model.CurriculumElement{Type: entry.Type, Ord: len(p.Elements) + 1}
+		if entry.Title != "" {
+			e.Title = optional(validate.StripAllHTML(entry.Title))
+		}
+		if entry.Details != "" {
+			details := validate.StripAllHTML(entry.Details)
+			if entry.Type == model.ElementTypeText || entry.Type == model.ElementTypeList {
+				details = validate.SanitizeMarkdown(entry.Details)
+			}
+			e.Details = &details
+		}
+		if entry.Duration != "" {
+			text, seconds, ok := duration.Normalize(validate.StripAllHTML(entry.Duration))
+			if !ok {
+				return nil, &SyntaxError{Line: entry.Line, Msg: "duration must be a duration such as " + duration.Examples}
+			}
+			e.Duration, e.DurationSeconds = &text, &seconds
+		}
+		for _, item := range entry.Items {
+			e.Items = append(e.Items, validate.StripAllHTML(item))
+		}
+		if entry.Type == model.ElementTypeImage {
+			if _, err := url.ParseRequestURI(entry.ImageURL); err != nil {
+				return nil, &SyntaxError{Line: entry.Line, Msg: "image must be a valid URL"}
+			}
+			e.ImageURL = optional(entry.ImageURL)
+		}
+
+		resolved := true
+		switch entry.Type {
+		case model.ElementTypeTechnique:
+			t, err := r.Technique(ctx, entry.Ref)
+			if err != nil {
+				return nil, err
+			}
+			if resolved = t != nil; resolved {
+				e.TechniqueID, e.Snapshot = optional(t.ID), curriculum.TechniqueSnapshot(t)
+			}
+		case model.ElementTypeAsset:
+			a, err := r.Asset(ctx, entry.Ref)
+			if err != nil {
+				return nil, err
+			}
+			if resolved = a != nil; resolved {
+				e.AssetID, e.Snapshot = optional(a.ID), curriculum.AssetSnapshot(a)
+			}
+		}
+		if !resolved {
+			p.Unresolved = append(p.Unresolved, Unresolved{
+				Line: entry.Line, Element: len(p.Elements), Kind: string(entry.Type), Ref: entry.Ref,
+			})
+			e.Type = model.ElementTypeText
+			if e.Title == nil {
+				e.Title = optional(validate.StripAllHTML(entry.Ref))
+			}
+		}
+		if (e.Type == model.ElementTypeText || e.Type == model.ElementTypeList) && (e.Title == nil || *e.Title == "") {
+			return nil, &SyntaxError{Line: entry.Line, Msg: fmt.Sprintf("%s element needs a title", e.Type)}
+		}
+		p.Elements = append(p.Elements, e)
+	}
+	return p, nil
+}
+
+func optional(s string) *string {
+	s = strings.TrimSpace(s)
+	return &s
+}
+
+// Commit writes a preview as a new curriculum owned by the actor of change,
+// filling in the IDs and timestamps of p.
+func Commit(ctx context.Context, s store.Store, p *Preview, change curriculum.Change) error {
+	c := &p.Curriculum
+	c.ID, c.OwnerUID = store.NewID(), change.ActorUID
+	c.CreatedAt, c.UpdatedAt = change.At, change.At
+	for i := range p.Elements {
+		p.Elements[i].CreatedAt, p.Elements[i].UpdatedAt = change.At, change.At
+	}
+	return s.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		return curriculum.Create(s, tx, c, p.Elements, change)
+	})
+}
@@ -0,0 +1,157 @@
+package outline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/thomas/skillhive-api/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// ParseYAML reads a YAML outline:
+//
+//	title: White Belt Week 1
+//	description: Fundamentals
+//	duration: 1h
+//	elements:
+//	  - text: Warm up and explain the plan.
+//	    title: Intro
+//	    duration: 10m
+//	  - technique: armbar
+//	  - asset: https://youtu.be/abc
+//	  - image: https://example.com/grips.png
+//	  - list: [Belt, Water]
+//	    title: Bring
+//	  - Kimura
+//
+// Each element has exactly one of text, list, technique, asset or image,
+// and optionally a title, details and duration. A bare string is a text
+// element with that title, or a technique element if it names one.
+func ParseYAML(src string) (*Draft, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+		return nil, &SyntaxError{Msg: "invalid YAML: " + strings.TrimPrefix(err.Error(), "yaml: ")}
+	}
+	if len(doc.Content) == 0 {
+		return nil, ErrNoTitle
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, &SyntaxError{Line: root.Line, Msg: "outline must be a mapping"}
+	}
+
+	d := &Draft{}
+	err := eachKey(root, func(key string, v *yaml.Node) error {
+		switch key {
+		case "title":
+			return scalar(v, &d.Title)
+		case "description":
+			return scalar(v, &d.Description)
+		case "duration":
+			return scalar(v, &d.Duration)
+		case "elements":
+			if v.Kind != yaml.SequenceNode {
+				return &SyntaxError{Line: v.Line, Msg: "elements must be a list"}
+			}
+			for _, n := range v.Content {
+				e, err := yamlEntry(n)
+				if err != nil {
+					return err
+				}
+				d.Entries = append(d.Entries, e)
+			}
+		}
+		return nil
+	}, "title", "description", "duration", "elements")
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func yamlEntry(n *yaml.Node) (Entry, error) {
+	e := Entry{Line: n.Line}
+	if n.Kind == yaml.ScalarNode {
+		e.Type, e.Title, e.Lines = model.ElementTypeText, n.Value, []Line{}
+		return e, nil
+	}
+	if n.Kind != yaml.MappingNode {
+		return e, &SyntaxError{Line: n.Line, Msg: "element must be a mapping or a string"}
+	}
+	err := eachKey(n, func(key string, v *yaml.Node) error {
+		switch key {
+		case "title":
+			return scalar(v, &e.Title)
+		case "details":
+			return scalar(v, &e.Details)
+		case "duration":
+			return scalar(v, &e.Duration)
+		}
+		if e.Type != "" {
+			return &SyntaxError{Line: v.Line, Msg: fmt.Sprintf("element is both %s and %s", e.Type, key)}
+		}
+		e.Type = model.ElementType(key)
+		switch e.Type {
+		case model.ElementTypeList:
+			if v.Kind != yaml.SequenceNode {
+				return &SyntaxError{Line: v.Line, Msg: "list must be a list of strings"}
+			}
+			for _, item := range v.Content {
+				var s string
+				if err := scalar(item, &s); err != nil {
+					return err
+				}
+				e.Items = append(e.Items, s)
+			}
+			return nil
+		case model.ElementTypeImage:
+			return scalar(v, &e.ImageURL)
+		}
+		return scalar(v, &e.Ref)
+	}, "title", "details", "duration", "text", "list", "technique", "asset", "image")
+	if err != nil {
+		return e, err
+	}
+	switch e.Type {
+	case "":
+		return e, &SyntaxError{Line: n.Line, Msg: "element needs one of text, list, technique, asset or image"}
+	case model.ElementTypeText:
+		if e.Details != "" {
+			return e, &SyntaxError{Line: n.Line, Msg: "text element takes its details from text"}
+		}
+		e.Details, e.Ref = e.Ref, ""
+	}
+	return e, nil
+}
+
+// eachKey calls fn for each key of mapping n, rejecting keys not in allowed
+// and keys given twice.
+func eachKey(n *yaml.Node, fn func(key string, v *yaml.Node) error, allowed ...string) error {
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		known := false
+		for _, a := range allowed {
+			known = known || a == k.Value
+		}
+		if !known {
+			return &SyntaxError{Line: k.Line, Msg: fmt.Sprintf("unknown key %q", k.Value)}
+		}
+		if seen[k.Value] {
+			return &SyntaxError{Line: k.Line, Msg: fmt.Sprintf("duplicate key %q", k.Value)}
+		}
+		seen[k.Value] = true
+		if err := fn(k.Value, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scalar(n *yaml.Node, dst *string) error {
+	if n.Kind != yaml.ScalarNode {
+		return &SyntaxError{Line: n.Line, Msg: "expected a string"}
+	}
+	*dst = n.Value
+	return nil
+}
@@ -0,0 +1,117 @@
+package schedule
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	// Sessions name IANA zones; embed the database so that they load on
+	// hosts without one, such as the distroless image.
+	_ "time/tzdata"
+)
+
+// Event is a session written as an iCalendar VEVENT.
+type Event struct {
+	UID string
+	// Start is the first occurrence, in the event's time zone.
+	Start    time.Time
+	Duration time.Duration
+	// Rule is an RRULE value, empty for a single occurrence.
+	Rule        string
+	Summary     string
+	Location    string
+	Description string
+	// Stamp is when the event was last changed.
+	Stamp time.Time
+}
+
+// WriteCalendar writes events as an iCalendar stream named name. Starts are
+// written with a TZID of their IANA zone, which calendar apps resolve
+// without a VTIMEZONE; UTC starts are written in UTC.
+func WriteCalendar(w io.Writer, name string, events []Event) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) { writeFolded(bw, s) }
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//SkillHive//Schedule//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeText(name))
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID)
+		line("DTSTAMP:" + e.Stamp.UTC().Format("20060102T150405Z"))
+		if loc := e.Start.Location().String(); loc == "UTC" {
+			line("DTSTART:" + e.Start.UTC().Format("20060102T150405Z"))
+		} else {
+			line("DTSTART;TZID=" + loc + ":" + e.Start.Format("20060102T150405"))
+		}
+		line("DURATION:" + isoDuration(e.Duration))
+		if e.Rule != "" {
+			line("RRULE:" + e.Rule)
+		}
+		line("SUMMARY:" + escapeText(e.Summary))
+		if e.Location != "" {
+			line("LOCATION:" + escapeText(e.Location))
+		}
+		if e.Description != "" {
+			line("DESCRIPTION:" + escapeText(e.Description))
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// maxLine is the number of octets a content line may have before folding.
+const maxLine = 75
+
+// writeFolded writes s as a CRLF-terminated content line, folded so that no
+// line exceeds maxLine octets and no UTF-8 sequence is split.
+func writeFolded(w *bufio.Writer, s string) {
+	limit := maxLine
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space, which counts.
+		limit = maxLine - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escapeText escapes an iCalendar TEXT value.
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// isoDuration formats d as an iCalendar DURATION such as PT1H30M.
+func isoDuration(d time.Duration) string {
+	s := int(d.Seconds())
+	if s <= 0 {
+		return "PT0S"
+	}
+	var b strings.Builder
+	b.WriteString("PT")
+	if h := s / 3600; h > 0 {
+		fmt.Fprintf(&b, "%dH", h)
+	}
+	if m := s / 60 % 60; m > 0 {
+		fmt.Fprintf(&b, "%dM", m)
+	}
+	if sec := s % 60; sec > 0 {
+		fmt.Fprintf(&b, "%dS", sec)
+	}
+	return b.String()
+}
@@ -0,0 +1,246 @@
+// Package schedule expands the recurrence rules of scheduled sessions into
+// occurrences and writes sessions as iCalendar (RFC 5545) events.
+//
+// Rules are a subset of the iCalendar RRULE: FREQ=DAILY, WEEKLY or MONTHLY,
+// with INTERVAL, BYDAY (weekly only), and at most one of COUNT and UNTIL.
+// Weeks start on Monday. Monthly rules repeat the day of the month of the
+// first occurrence and skip months without it, as calendar apps do.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies a rule may repeat at.
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+)
+
+// MaxInterval bounds INTERVAL, which keeps expansion cheap.
+const MaxInterval = 366
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq     string
+	Interval int
+	// ByDay lists the weekdays of a weekly rule in week order, Monday first.
+	ByDay []time.Weekday
+	// Count is the number of occurrences, Until the last instant one may
+	// start at; both are zero for a rule that repeats forever.
+	Count int
+	Until time.Time
+}
+
+// ParseRule parses an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10".
+// A date-only UNTIL (20261231) includes that whole day in loc.
+func ParseRule(s string, loc *time.Location) (*Rule, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	r := &Rule{Interval: 1}
+	seen := map[string]bool{}
+	for part := range strings.SplitSeq(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("recurrence part %q must be NAME=value", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("recurrence repeats %s", name)
+		}
+		seen[name] = true
+		switch name {
+		case "FREQ":
+			if value != Daily && value != Weekly && value != Monthly {
+				return nil, errors.New("recurrence FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+			r.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > MaxInterval {
+				return nil, fmt.Errorf("recurrence INTERVAL must be 1-%d", MaxInterval)
+			}
+			r.Interval = n
+		case "BYDAY":
+			for day := range strings.SplitSeq(value, ",") {
+				wd, ok := weekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("recurrence BYDAY has unknown day %q", day)
+				}
+				if !slices.Contains(r.ByDay, wd) {
+					r.ByDay = append(r.ByDay, wd)
+				}
+			}
+			slices.SortFunc(r.ByDay, func(a, b time.Weekday) int { return weekIndex(a) - weekIndex(b) })
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, errors.New("recurrence COUNT must be a positive number")
+			}
+			r.Count = n
+		case "UNTIL":
+			until, err := parseUntil(value, loc)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = until
+		case "WKST":
+			if value != "MO" {
+				return nil, errors.New("recurrence WKST must be MO")
+			}
+		default:
+			return nil, fmt.Errorf("recurrence %s is not supported", name)
+		}
+	}
+	switch {
+	case r.Freq == "":
+		return nil, errors.New("recurrence FREQ is required")
+	case r.Count > 0 && !r.Until.IsZero():
+		return nil, errors.New("recurrence may not have both COUNT and UNTIL")
+	case len(r.ByDay) > 0 && r.Freq != Weekly:
+		return nil, errors.New("recurrence BYDAY is only supported with FREQ=WEEKLY")
+	}
+	return r, nil
+}
+
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseInLocation("20060102", value, loc); err == nil {
+		return d.AddDate(0, 0, 1).Add(-time.Second).UTC(), nil
+	}
+	return time.Time{}, errors.New("recurrence UNTIL must be a date (20261231) or a UTC time (20261231T235959Z)")
+}
+
+// String returns the rule in canonical RRULE form, UNTIL in UTC.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = strings.ToUpper(wd.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// CheckStart reports an error when start, in its location, is not one of
+// the days of a weekly BYDAY rule. iCalendar counts DTSTART as the first
+// occurrence whatever the rule, while Occurrences would skip it.
+func (r *Rule) CheckStart(start time.Time) error {
+	if len(r.ByDay) > 0 && !slices.Contains(r.ByDay, start.Weekday()) {
+		return fmt.Errorf("recurrence BYDAY must include the weekday of the start (%s)", start.Weekday())
+	}
+	return nil
+}
+
+// weekIndex numbers weekdays from Monday (0) to Sunday (6).
+func weekIndex(wd time.Weekday) int {
+	return (int(wd) + 6) % 7
+}
+
+// Forever is the end of a session that repeats without end.
+var Forever = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// End returns when the last occurrence of a session ends, as expanded by
+// Occurrences, or Forever when the rule repeats without end. With UNTIL it
+// is UNTIL plus length, which no occurrence ends after.
+func End(start time.Time, r *Rule, length time.Duration) time.Time {
+	switch {
+	case r == nil:
+		return start.Add(length)
+	case !r.Until.IsZero():
+		return r.Until.Add(length)
+	case r.Count == 0:
+		return Forever
+	}
+	starts := Occurrences(start, r, length, start, Forever)
+	if len(starts) == 0 {
+		return start.Add(length)
+	}
+	return starts[len(starts)-1].Add(length)
+}
+
+// maxPeriods bounds the periods Occurrences steps through, so that a rule
+// cannot make it loop for long.
+const maxPeriods = 50000
+
+// Occurrences returns the starts of the occurrences of a session whose first
+// occurrence starts at start, each lasting length, that overlap [from, to).
+// Occurrences keep the wall-clock time of start in its location. A nil rule
+// is a single occurrence.
+func Occurrences(start time.Time, r *Rule, length time.Duration, from, to time.Time) []time.Time {
+	var out []time.Time
+	keep := func(t time.Time) {
+		if t.Before(to) && t.Add(length).After(from) {
+			out = append(out, t)
+		}
+	}
+	if r == nil {
+		keep(start)
+		return out
+	}
+
+	loc := start.Location()
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	at := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, hh, mm, ss, 0, loc) }
+	days := r.ByDay
+	if r.Freq == Weekly && len(days) == 0 {
+		days = []time.Weekday{start.Weekday()}
+	}
+	// The Monday of the first week, for weekly rules.
+	monday := d - weekIndex(start.Weekday())
+
+	count := 0
+	for period := 0; period < maxPeriods; period++ {
+		var candidates []time.Time
+		switch r.Freq {
+		case Daily:
+			candidates = []time.Time{at(y, m, d+period*r.Interval)}
+		case Weekly:
+			for _, wd := range days {
+				candidates = append(candidates, at(y, m, monday+period*7*r.Interval+weekIndex(wd)))
+			}
+		case Monthly:
+			// Months without the day are skipped and not counted.
+			if t := at(y, m+time.Month(period*r.Interval), d); t.Day() == d {
+				candidates = []time.Time{t}
+			}
+		}
+		for _, t := range candidates {
+			if t.Before(start) {
+				continue
+			}
+			if !r.Until.IsZero() && t.After(r.Until) || !t.Before(to) {
+				return out
+			}
+			count++
+			keep(t)
+			if r.Count > 0 && count >= r.Count {
+				return out
+			}
+		}
+	}
+	return out
+}
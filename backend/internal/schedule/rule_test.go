@@ -0,0 +1,194 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestParseRule(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	for _, tt := range []struct {
+		in, want, wantErr string
+	}{
+		{in: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{in: "rrule:freq=weekly;byday=WE,MO,WE;count=4", want: "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4"},
+		{in: "FREQ=WEEKLY;INTERVAL=2;BYDAY=SU,SA;WKST=MO", want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=SA,SU"},
+		{in: "FREQ=MONTHLY;INTERVAL=1", want: "FREQ=MONTHLY"},
+		// A date-only UNTIL includes that day in the session's zone.
+		{in: "FREQ=DAILY;UNTIL=20261231", want: "FREQ=DAILY;UNTIL=20261231T225959Z"},
+		{in: "FREQ=DAILY;UNTIL=20261231T120000Z", want: "FREQ=DAILY;UNTIL=20261231T120000Z"},
+		{in: "", wantErr: "NAME=value"},
+		{in: "INTERVAL=2", wantErr: "FREQ is required"},
+		{in: "FREQ=YEARLY", wantErr: "DAILY, WEEKLY or MONTHLY"},
+		{in: "FREQ=DAILY;FREQ=WEEKLY", wantErr: "repeats FREQ"},
+		{in: "FREQ=DAILY;INTERVAL=0", wantErr: "INTERVAL must be"},
+		{in: "FREQ=DAILY;INTERVAL=367", wantErr: "INTERVAL must be"},
+		{in: "FREQ=WEEKLY;BYDAY=XX", wantErr: "unknown day"},
+		{in: "FREQ=DAILY;BYDAY=MO", wantErr: "only supported with FREQ=WEEKLY"},
+		{in: "FREQ=DAILY;COUNT=0", wantErr: "COUNT must be"},
+		{in: "FREQ=DAILY;UNTIL=tomorrow", wantErr: "UNTIL must be"},
+		{in: "FREQ=DAILY;COUNT=2;UNTIL=20261231", wantErr: "both COUNT and UNTIL"},
+		{in: "FREQ=WEEKLY;WKST=SU", wantErr: "WKST must be MO"},
+		{in: "FREQ=DAILY;BYHOUR=9", wantErr: "BYHOUR is not supported"},
+	} {
+		r, err := ParseRule(tt.in, berlin)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseRule(%q): got error %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRule(%q): %v", tt.in, err)
+			continue
+		}
+		if got := r.String(); got != tt.want {
+			t.Errorf("ParseRule(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	newYork := mustLoad(t, "America/New_York")
+	utc := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	for _, tt := range []struct {
+		name     string
+		start    time.Time
+		rule     string
+		length   time.Duration
+		from, to string
+		want     []string
+	}{
+		{"single", time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC), "", time.Hour,
+			"2026-03-01T00:00:00Z", "2026-04-01T00:00:00Z",
+			[]string{"2026-03-02T18:00:00Z"}},
+		{"single outside", time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC), "", time.Hour,
+			"2026-03-02T19:00:00Z", "2026-04-01T00:00:00Z", nil},
+		{"overlapping the start of the window", time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC), "", time.Hour,
+			"2026-03-02T18:30:00Z", "2026-04-01T00:00:00Z",
+			[]string{"2026-03-02T18:00:00Z"}},
+		{"daily interval", time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC), "FREQ=DAILY;INTERVAL=3;COUNT=3", time.Hour,
+			"2026-03-01T00:00:00Z", "2026-04-01T00:00:00Z",
+			[]string{"2026-03-02T09:00:00Z", "2026-03-05T09:00:00Z", "2026-03-08T09:00:00Z"}},
+		{"weekly byday counts from the start", time.Date(2026, 3, 4, 18, 30, 0, 0, time.UTC), "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3", time.Hour,
+			"2026-03-01T00:00:00Z", "2026-04-01T00:00:00Z",
+			[]string{"2026-03-04T18:30:00Z", "2026-03-09T18:30:00Z", "2026-03-11T18:30:00Z"}},
+		{"biweekly", time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC), "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", time.Hour,
+			"2026-03-01T00:00:00Z", "2026-03-21T00:00:00Z",
+			[]string{"2026-03-02T18:00:00Z", "2026-03-06T18:00:00Z", "2026-03-16T18:00:00Z", "2026-03-20T18:00:00Z"}},
+		{"until is inclusive", time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC), "FREQ=DAILY;UNTIL=20260304T090000Z", time.Hour,
+			"2026-03-01T00:00:00Z", "2026-04-01T00:00:00Z",
+			[]string{"2026-03-02T09:00:00Z", "2026-03-03T09:00:00Z", "2026-03-04T09:00:00Z"}},
+		{"window within a count", time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC), "FREQ=DAILY;COUNT=10", time.Hour,
+			"2026-03-05T00:00:00Z", "2026-03-07T00:00:00Z",
+			[]string{"2026-03-05T09:00:00Z", "2026-03-06T09:00:00Z"}},
+		{"monthly skips short months", time.Date(2026, 1, 31, 18, 0, 0, 0, time.UTC), "FREQ=MONTHLY;COUNT=3", time.Hour,
+			"2026-01-01T00:00:00Z", "2027-01-01T00:00:00Z",
+			[]string{"2026-01-31T18:00:00Z", "2026-03-31T18:00:00Z", "2026-05-31T18:00:00Z"}},
+		// Repeats keep the local time as Berlin moves to summer time on
+		// 29 March 2026 and New York back to standard time on 1 November.
+		{"spring forward", time.Date(2026, 3, 28, 18, 30, 0, 0, berlin), "FREQ=DAILY;COUNT=2", time.Hour,
+			"2026-03-01T00:00:00Z", "2026-04-01T00:00:00Z",
+			[]string{"2026-03-28T17:30:00Z", "2026-03-29T16:30:00Z"}},
+		{"fall back", time.Date(2026, 10, 31, 9, 0, 0, 0, newYork), "FREQ=DAILY;COUNT=2", time.Hour,
+			"2026-10-01T00:00:00Z", "2026-12-01T00:00:00Z",
+			[]string{"2026-10-31T13:00:00Z", "2026-11-01T14:00:00Z"}},
+	} {
+		var r *Rule
+		if tt.rule != "" {
+			var err error
+			if r, err = ParseRule(tt.rule, tt.start.Location()); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+		}
+		var got []string
+		for _, o := range Occurrences(tt.start, r, tt.length, utc(tt.from), utc(tt.to)) {
+			got = append(got, o.UTC().Format(time.RFC3339))
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOccurrencesStopAfterMaxPeriods(t *testing.T) {
+	start := time.Date(2000, 1, 1, 9, 0, 0, 0, time.UTC)
+	r, err := ParseRule("FREQ=DAILY", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Occurrences(start, r, time.Hour, start, Forever)
+	if len(got) != maxPeriods {
+		t.Fatalf("got %d occurrences, want %d", len(got), maxPeriods)
+	}
+	if last := got[len(got)-1]; !last.Equal(start.AddDate(0, 0, maxPeriods-1)) {
+		t.Errorf("last occurrence: got %v", last)
+	}
+}
+
+func TestCheckStart(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	// Tuesday 24 March 2026, 00:30 in Berlin and still Monday in UTC.
+	start := time.Date(2026, 3, 24, 0, 30, 0, 0, berlin)
+	for _, tt := range []struct {
+		rule    string
+		at      time.Time
+		wantErr bool
+	}{
+		{"FREQ=WEEKLY", start, false},
+		{"FREQ=DAILY", start, false},
+		{"FREQ=WEEKLY;BYDAY=TU,TH", start, false},
+		{"FREQ=WEEKLY;BYDAY=MO,WE", start, true},
+		{"FREQ=WEEKLY;BYDAY=MO,WE", start.UTC(), false},
+	} {
+		r, err := ParseRule(tt.rule, berlin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.CheckStart(tt.at); (err != nil) != tt.wantErr {
+			t.Errorf("CheckStart(%q, %v): got %v, want error %v", tt.rule, tt.at, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEnd(t *testing.T) {
+	start := time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		rule string
+		want time.Time
+	}{
+		{"", start.Add(time.Hour)},
+		{"FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3", time.Date(2026, 3, 9, 19, 0, 0, 0, time.UTC)},
+		{"FREQ=DAILY;UNTIL=20260310T120000Z", time.Date(2026, 3, 10, 13, 0, 0, 0, time.UTC)},
+		{"FREQ=MONTHLY", Forever},
+	} {
+		var r *Rule
+		if tt.rule != "" {
+			var err error
+			if r, err = ParseRule(tt.rule, time.UTC); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := End(start, r, time.Hour); !got.Equal(tt.want) {
+			t.Errorf("End(%q) = %v, want %v", tt.rule, got, tt.want)
+		}
+	}
+}
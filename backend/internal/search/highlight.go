@@ -0,0 +1,85 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// snippetLen is the approximate length of a highlighted excerpt; shorter
+// field texts are returned whole.
+const snippetLen = 160
+
+// highlight returns text with words whose index term is in terms wrapped in
+// <mark>, cut to an excerpt around the first match. Everything else is
+// HTML-escaped. ok is false when nothing matched.
+func highlight(text string, terms map[string]bool) (string, bool) {
+	var hits []token
+	for _, t := range tokenize(text) {
+		if terms[t.term] {
+			hits = append(hits, t)
+		}
+	}
+	if len(hits) == 0 {
+		return "", false
+	}
+
+	start, end := 0, len(text)
+	if len(text) > snippetLen {
+		start = min(wordStart(text, hits[0].start-snippetLen/4), hits[0].start)
+		end = wordEnd(text, start+snippetLen, hits[0].end)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, h := range hits {
+		if h.start < pos || h.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:h.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[h.start:h.end]))
+		b.WriteString("</mark>")
+		pos = h.end
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return strings.TrimSpace(b.String()), true
+}
+
+// wordStart moves i forward to the start of the next word (or 0).
+func wordStart(text string, i int) int {
+	if i <= 0 {
+		return 0
+	}
+	for i < len(text) && text[i] != ' ' {
+		i++
+	}
+	for i < len(text) && text[i] == ' ' {
+		i++
+	}
+	return i
+}
+
+// wordEnd moves i back to the end of the previous word, but not before floor.
+func wordEnd(text string, i, floor int) int {
+	if i >= len(text) {
+		return len(text)
+	}
+	j := i
+	for j > floor && text[j] != ' ' {
+		j--
+	}
+	if j <= floor {
+		for i > floor && !utf8.RuneStart(text[i]) {
+			i--
+		}
+		return i
+	}
+	return j
+}
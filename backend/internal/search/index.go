@@ -0,0 +1,258 @@
+// Package search maintains an in-process inverted index over techniques,
+// assets and curricula with BM25 ranking and highlighted snippets.
+//
+// The index lives in memory and is rebuilt from the store on startup; Wrap
+// keeps it current for every write made through the wrapped store. Writes
+// made by other processes (another Cloud Run instance, a maintenance command)
+// are only picked up by the next Rebuild.
+package search
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+// BM25 parameters.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// prefixWeight scales matches of a partially typed last query word.
+	prefixWeight = 0.5
+)
+
+// Field is a piece of document text with a ranking boost.
+type Field struct {
+	Name  string
+	Text  string
+	Boost float64
+}
+
+// Document is what gets indexed for one entity.
+type Document struct {
+	Type         string
+	ID           string
+	DisciplineID string
+	Title        string
+	Fields       []Field
+	// Readers, when not nil, are the only users the document is returned to.
+	Readers []string
+}
+
+type docKey struct {
+	typ string
+	id  string
+}
+
+type indexedDoc struct {
+	Document
+	length float64            // boosted token count
+	terms  map[string]float64 // term -> boosted frequency
+}
+
+// shard holds the postings of one discipline, so IDF and average length are
+// computed over the documents that can actually be returned together.
+type shard struct {
+	docs     map[docKey]*indexedDoc
+	postings map[string]map[docKey]float64
+	totalLen float64
+}
+
+// Index is a concurrency-safe inverted index.
+type Index struct {
+	mu     sync.RWMutex
+	shards map[string]*shard
+	owner  map[docKey]string // doc -> discipline, to find it on removal
+
+	// While a rebuild runs, writes are also recorded in journal so they can
+	// be replayed on the fresh index before it replaces this one.
+	rebuildMu  sync.Mutex
+	rebuilding bool
+	journal    []journalEntry
+}
+
+// journalEntry is a Put (doc set) or Remove (doc nil) made during a rebuild.
+type journalEntry struct {
+	key docKey
+	doc *indexedDoc
+}
+
+// NewIndex returns an empty index.
+func NewIndex() *Index {
+	return &Index{shards: map[string]*shard{}, owner: map[docKey]string{}}
+}
+
+// Put adds or replaces a document.
+func (ix *Index) Put(doc Document) {
+	d := &indexedDoc{Document: doc, terms: map[string]float64{}}
+	for _, f := range doc.Fields {
+		for _, t := range tokenize(f.Text) {
+			d.terms[t.term] += f.Boost
+			d.length += f.Boost
+		}
+	}
+
+	key := docKey{doc.Type, doc.ID}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.putLocked(key, d)
+	if ix.rebuilding {
+		ix.journal = append(ix.journal, journalEntry{key: key, doc: d})
+	}
+}
+
+func (ix *Index) putLocked(key docKey, d *indexedDoc) {
+	ix.removeLocked(key)
+
+	doc := d.Document
+	sh := ix.shards[doc.DisciplineID]
+	if sh == nil {
+		sh = &shard{docs: map[docKey]*indexedDoc{}, postings: map[string]map[docKey]float64{}}
+		ix.shards[doc.DisciplineID] = sh
+	}
+	sh.docs[key] = d
+	sh.totalLen += d.length
+	for term, tf := range d.terms {
+		p := sh.postings[term]
+		if p == nil {
+			p = map[docKey]float64{}
+			sh.postings[term] = p
+		}
+		p[key] = tf
+	}
+	ix.owner[key] = doc.DisciplineID
+}
+
+// Remove deletes a document; removing an unknown document is a no-op.
+func (ix *Index) Remove(typ, id string) {
+	key := docKey{typ, id}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.removeLocked(key)
+	if ix.rebuilding {
+		ix.journal = append(ix.journal, journalEntry{key: key})
+	}
+}
+
+func (ix *Index) removeLocked(key docKey) {
+	disciplineID, ok := ix.owner[key]
+	if !ok {
+		return
+	}
+	delete(ix.owner, key)
+	sh := ix.shards[disciplineID]
+	d := sh.docs[key]
+	delete(sh.docs, key)
+	sh.totalLen -= d.length
+	for term := range d.terms {
+		delete(sh.postings[term], key)
+		if len(sh.postings[term]) == 0 {
+			delete(sh.postings, term)
+		}
+	}
+}
+
+// Len returns the number of indexed documents.
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.owner)
+}
+
+// Search ranks the documents of a discipline that reader may see against q.
+// types restricts the result types (all when empty); limit caps the number
+// of results.
+func (ix *Index) Search(disciplineID, q string, types []string, limit int, reader string) []model.SearchResult {
+	terms := parseQuery(q)
+	if len(terms) == 0 {
+		return []model.SearchResult{}
+	}
+	allowed := map[string]bool{}
+	for _, t := range types {
+		allowed[t] = true
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	sh := ix.shards[disciplineID]
+	if sh == nil || len(sh.docs) == 0 {
+		return []model.SearchResult{}
+	}
+
+	n := float64(len(sh.docs))
+	avgLen := sh.totalLen / n
+	scores := map[docKey]float64{}
+	matched := map[string]bool{} // index terms to highlight
+
+	addTerm := func(term string, weight float64) {
+		postings := sh.postings[term]
+		if len(postings) == 0 {
+			return
+		}
+		matched[term] = true
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for key, tf := range postings {
+			if len(allowed) > 0 && !allowed[key.typ] {
+				continue
+			}
+			if r := sh.docs[key].Readers; r != nil && !slices.Contains(r, reader) {
+				continue
+			}
+			norm := bm25K1 * (1 - bm25B + bm25B*sh.docs[key].length/avgLen)
+			scores[key] += weight * idf * tf * (bm25K1 + 1) / (tf + norm)
+		}
+	}
+	for _, qt := range terms {
+		addTerm(qt.term, 1)
+		if len(qt.prefix) < 2 {
+			continue
+		}
+		for term := range sh.postings {
+			if term != qt.term && strings.HasPrefix(term, qt.prefix) {
+				addTerm(term, prefixWeight)
+			}
+		}
+	}
+
+	keys := make([]docKey, 0, len(scores))
+	for key := range scores {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if scores[keys[i]] != scores[keys[j]] {
+			return scores[keys[i]] > scores[keys[j]]
+		}
+		if keys[i].typ != keys[j].typ {
+			return keys[i].typ < keys[j].typ
+		}
+		return keys[i].id < keys[j].id
+	})
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	results := make([]model.SearchResult, 0, len(keys))
+	for _, key := range keys {
+		d := sh.docs[key]
+		r := model.SearchResult{
+			Type:         d.Type,
+			ID:           d.ID,
+			DisciplineID: d.DisciplineID,
+			Title:        d.Title,
+			Score:        math.Round(scores[key]*1000) / 1000,
+			Highlights:   map[string]string{},
+		}
+		for _, f := range d.Fields {
+			if snippet, ok := highlight(f.Text, matched); ok {
+				r.Highlights[f.Name] = snippet
+			}
+		}
+		results = append(results, r)
+	}
+	return results
+}
@@ -0,0 +1,400 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+	"github.com/thomas/skillhive-api/internal/validate"
+)
+
+// Field boosts: names and titles matter most.
+const (
+	boostTitle       = 3
+	boostOriginator  = 2
+	boostDescription = 1
+	boostContent     = 1
+)
+
+// TechniqueDocument builds the indexed form of a technique.
+func TechniqueDocument(t *model.Technique) Document {
+	return Document{
+		Type: model.SearchTypeTechnique, ID: t.ID, DisciplineID: t.DisciplineID, Title: t.Name,
+		Fields: []Field{
+			{Name: "name", Text: t.Name, Boost: boostTitle},
+			{Name: "aliases", Text: strings.Join(t.Aliases, " "), Boost: boostTitle},
+			{Name: "description", Text: t.Description, Boost: boostDescription},
+		},
+	}
+}
+
+// AssetDocument builds the indexed form of an asset.
+func AssetDocument(a *model.Asset) Document {
+	d := Document{
+		Type: model.SearchTypeAsset, ID: a.ID, DisciplineID: a.DisciplineID, Title: a.Title,
+		Fields: []Field{
+			{Name: "title", Text: a.Title, Boost: boostTitle},
+			{Name: "description", Text: a.Description, Boost: boostDescription},
+		},
+	}
+	if a.Originator != nil {
+		d.Fields = append(d.Fields, Field{Name: "originator", Text: *a.Originator, Boost: boostOriginator})
+	}
+	return d
+}
+
+// CurriculumDocument builds the indexed form of a curriculum. Element text
+// (titles, details, list items, captions and snapshots) is indexed as
+// "content".
+func CurriculumDocument(c *model.Curriculum, elements []model.CurriculumElement) Document {
+	var parts []string
+	for _, e := range elements {
+		if e.Title != nil {
+			parts = append(parts, *e.Title)
+		}
+		if e.Details != nil {
+			parts = append(parts, validate.StripAllHTML(*e.Details))
+		}
+		parts = append(parts, e.Items...)
+		if e.Caption != nil {
+			parts = append(parts, *e.Caption)
+		}
+		if e.Snapshot != nil {
+			parts = append(parts, e.Snapshot.Name, e.Snapshot.Description)
+		}
+	}
+	var content []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			content = append(content, p)
+		}
+	}
+	return Document{
+		Type: model.SearchTypeCurriculum, ID: c.ID, DisciplineID: c.DisciplineID, Title: c.Title,
+		Fields: []Field{
+			{Name: "title", Text: c.Title, Boost: boostTitle},
+			{Name: "description", Text: c.Description, Boost: boostDescription},
+			{Name: "content", Text: strings.Join(content, " · "), Boost: boostContent},
+		},
+		Readers: curriculum.Readers(c),
+	}
+}
+
+// assetSearchable mirrors the list visibility rules: inactive assets are
+// hidden, except legacy assets without a processing status.
+func assetSearchable(a *model.Asset) bool {
+	return a.Active || a.ProcessingStatus == ""
+}
+
+// Rebuild replaces the index contents with everything in the store. Writes
+// indexed while it runs are replayed on the rebuilt index, so it is safe to
+// call while the store is serving writes.
+func (ix *Index) Rebuild(ctx context.Context, s store.Store) error {
+	ix.rebuildMu.Lock()
+	defer ix.rebuildMu.Unlock()
+	ix.mu.Lock()
+	ix.rebuilding, ix.journal = true, nil
+	ix.mu.Unlock()
+	defer func() {
+		ix.mu.Lock()
+		ix.rebuilding, ix.journal = false, nil
+		ix.mu.Unlock()
+	}()
+
+	fresh := NewIndex()
+	techniques, err := s.Techniques().List(ctx, store.NewQuery())
+	if err != nil {
+		return err
+	}
+	for i := range techniques {
+		fresh.Put(TechniqueDocument(&techniques[i]))
+	}
+	assets, err := s.Assets().List(ctx, store.NewQuery())
+	if err != nil {
+		return err
+	}
+	for i := range assets {
+		if assetSearchable(&assets[i]) {
+			fresh.Put(AssetDocument(&assets[i]))
+		}
+	}
+	curricula, err := s.Curricula().List(ctx, store.NewQuery())
+	if err != nil {
+		return err
+	}
+	for i := range curricula {
+		elements, err := s.Elements().List(ctx, curricula[i].ID, store.NewQuery())
+		if err != nil {
+			return err
+		}
+		fresh.Put(CurriculumDocument(&curricula[i], elements))
+	}
+
+	ix.mu.Lock()
+	for _, e := range ix.journal {
+		if e.doc != nil {
+			fresh.putLocked(e.key, e.doc)
+		} else {
+			fresh.removeLocked(e.key)
+		}
+	}
+	ix.shards, ix.owner = fresh.shards, fresh.owner
+	ix.mu.Unlock()
+	slog.Info("search index rebuilt", "documents", len(fresh.owner))
+	return nil
+}
+
+// Wrap returns a Store that reindexes techniques, assets and curricula after
+// every successful write made through it, including batched and
+// transactional writes.
+func Wrap(s store.Store, ix *Index) store.Store {
+	return &indexedStore{Store: s, ix: ix}
+}
+
+type indexedStore struct {
+	store.Store
+	ix *Index
+}
+
+func (s *indexedStore) Techniques() store.TechniqueRepo {
+	return &indexedRepo[model.Technique]{s.Store.Techniques(), func(ctx context.Context, id string) { s.refreshTechnique(ctx, id) }}
+}
+
+func (s *indexedStore) Assets() store.AssetRepo {
+	return &indexedRepo[model.Asset]{s.Store.Assets(), func(ctx context.Context, id string) { s.refreshAsset(ctx, id) }}
+}
+
+func (s *indexedStore) Curricula() store.CurriculumRepo {
+	return &indexedRepo[model.Curriculum]{s.Store.Curricula(), func(ctx context.Context, id string) { s.refreshCurriculum(ctx, id) }}
+}
+
+func (s *indexedStore) Elements() store.ElementRepo {
+	return &indexedElements{s.Store.Elements(), s}
+}
+
+func (s *indexedStore) Batch() store.Batch {
+	return &indexedBatch{Batch: s.Store.Batch(), s: s}
+}
+
+func (s *indexedStore) RunTransaction(ctx context.Context, fn func(ctx context.Context, tx store.Tx) error) error {
+	var refs []store.DocRef
+	err := s.Store.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		// Only the writes of the attempt that commits count.
+		itx := &indexedTx{Tx: tx}
+		err := fn(ctx, itx)
+		refs = itx.refs
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.refreshRefs(ctx, refs)
+	return nil
+}
+
+func (s *indexedStore) refreshTechnique(ctx context.Context, id string) {
+	t, err := s.Store.Techniques().Get(ctx, id)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		s.ix.Remove(model.SearchTypeTechnique, id)
+	case err != nil:
+		slog.Error("search: failed to reindex technique", "id", id, "error", err)
+	default:
+		s.ix.Put(TechniqueDocument(t))
+	}
+}
+
+func (s *indexedStore) refreshAsset(ctx context.Context, id string) {
+	a, err := s.Store.Assets().Get(ctx, id)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		s.ix.Remove(model.SearchTypeAsset, id)
+	case err != nil:
+		slog.Error("search: failed to reindex asset", "id", id, "error", err)
+	case !assetSearchable(a):
+		s.ix.Remove(model.SearchTypeAsset, id)
+	default:
+		s.ix.Put(AssetDocument(a))
+	}
+}
+
+func (s *indexedStore) refreshCurriculum(ctx context.Context, id string) {
+	c, err := s.Store.Curricula().Get(ctx, id)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		s.ix.Remove(model.SearchTypeCurriculum, id)
+		return
+	case err != nil:
+		slog.Error("search: failed to reindex curriculum", "id", id, "error", err)
+		return
+	}
+	elements, err := s.Store.Elements().List(ctx, id, store.NewQuery())
+	if err != nil {
+		slog.Error("search: failed to reindex curriculum elements", "id", id, "error", err)
+		return
+	}
+	s.ix.Put(CurriculumDocument(c, elements))
+}
+
+// refreshRef reindexes whatever document a batch write touched.
+func (s *indexedStore) refreshRef(ctx context.Context, ref store.DocRef) {
+	parts := strings.Split(ref.Collection, "/")
+	switch {
+	case ref.Collection == store.CollTechniques:
+		s.refreshTechnique(ctx, ref.ID)
+	case ref.Collection == store.CollAssets:
+		s.refreshAsset(ctx, ref.ID)
+	case ref.Collection == store.CollCurricula:
+		s.refreshCurriculum(ctx, ref.ID)
+	case len(parts) == 3 && parts[0] == store.CollCurricula && parts[2] == store.CollElements:
+		s.refreshCurriculum(ctx, parts[1])
+	}
+}
+
+// indexedRepo reindexes a document after each write to it.
+type indexedRepo[T any] struct {
+	store.Repo[T]
+	refresh func(ctx context.Context, id string)
+}
+
+func (r *indexedRepo[T]) Create(ctx context.Context, doc *T) (string, error) {
+	id, err := r.Repo.Create(ctx, doc)
+	if err == nil {
+		r.refresh(ctx, id)
+	}
+	return id, err
+}
+
+func (r *indexedRepo[T]) Set(ctx context.Context, id string, doc *T) error {
+	err := r.Repo.Set(ctx, id, doc)
+	if err == nil {
+		r.refresh(ctx, id)
+	}
+	return err
+}
+
+func (r *indexedRepo[T]) Update(ctx context.Context, id string, updates []store.Update) error {
+	err := r.Repo.Update(ctx, id, updates)
+	if err == nil {
+		r.refresh(ctx, id)
+	}
+	return err
+}
+
+func (r *indexedRepo[T]) Delete(ctx context.Context, id string) error {
+	err := r.Repo.Delete(ctx, id)
+	if err == nil {
+		r.refresh(ctx, id)
+	}
+	return err
+}
+
+// indexedElements reindexes the parent curriculum after element writes.
+type indexedElements struct {
+	store.ElementRepo
+	s *indexedStore
+}
+
+func (r *indexedElements) Create(ctx context.Context, curriculumID string, e *model.CurriculumElement) (string, error) {
+	id, err := r.ElementRepo.Create(ctx, curriculumID, e)
+	if err == nil {
+		r.s.refreshCurriculum(ctx, curriculumID)
+	}
+	return id, err
+}
+
+func (r *indexedElements) Set(ctx context.Context, curriculumID, id string, e *model.CurriculumElement) error {
+	err := r.ElementRepo.Set(ctx, curriculumID, id, e)
+	if err == nil {
+		r.s.refreshCurriculum(ctx, curriculumID)
+	}
+	return err
+}
+
+func (r *indexedElements) Update(ctx context.Context, curriculumID, id string, updates []store.Update) error {
+	err := r.ElementRepo.Update(ctx, curriculumID, id, updates)
+	if err == nil {
+		r.s.refreshCurriculum(ctx, curriculumID)
+	}
+	return err
+}
+
+func (r *indexedElements) Delete(ctx context.Context, curriculumID, id string) error {
+	err := r.ElementRepo.Delete(ctx, curriculumID, id)
+	if err == nil {
+		r.s.refreshCurriculum(ctx, curriculumID)
+	}
+	return err
+}
+
+// indexedBatch reindexes every touched document once the batch commits.
+type indexedBatch struct {
+	store.Batch
+	s    *indexedStore
+	refs []store.DocRef
+}
+
+func (b *indexedBatch) Set(ref store.DocRef, doc interface{}) {
+	b.Batch.Set(ref, doc)
+	b.refs = append(b.refs, ref)
+}
+
+func (b *indexedBatch) Update(ref store.DocRef, updates []store.Update) {
+	b.Batch.Update(ref, updates)
+	b.refs = append(b.refs, ref)
+}
+
+func (b *indexedBatch) Delete(ref store.DocRef) {
+	b.Batch.Delete(ref)
+	b.refs = append(b.refs, ref)
+}
+
+func (b *indexedBatch) Commit(ctx context.Context) error {
+	if err := b.Batch.Commit(ctx); err != nil {
+		return err
+	}
+	b.s.refreshRefs(ctx, b.refs)
+	return nil
+}
+
+// indexedTx records the documents a transaction writes.
+type indexedTx struct {
+	store.Tx
+	refs []store.DocRef
+}
+
+func (t *indexedTx) Set(ref store.DocRef, doc interface{}) {
+	t.Tx.Set(ref, doc)
+	t.refs = append(t.refs, ref)
+}
+
+func (t *indexedTx) Update(ref store.DocRef, updates []store.Update) {
+	t.Tx.Update(ref, updates)
+	t.refs = append(t.refs, ref)
+}
+
+func (t *indexedTx) Delete(ref store.DocRef) {
+	t.Tx.Delete(ref)
+	t.refs = append(t.refs, ref)
+}
+
+// refreshRefs reindexes the documents touched by a batch or transaction.
+func (s *indexedStore) refreshRefs(ctx context.Context, refs []store.DocRef) {
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		// Many element writes of one curriculum need a single reindex.
+		key := ref.Path()
+		if strings.HasSuffix(ref.Collection, "/"+store.CollElements) {
+			key = ref.Collection
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		s.refreshRef(ctx, ref)
+	}
+}
@@ -0,0 +1,75 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// token is a word of the source text with its byte offsets and index term.
+type token struct {
+	start, end int
+	term       string
+}
+
+// stopWords are too common to be useful as index terms.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "into": true,
+	"is": true, "it": true, "of": true, "on": true, "or": true, "the": true,
+	"this": true, "to": true, "with": true,
+}
+
+// tokenize splits text into lower-cased, stemmed terms. Letters and digits
+// form words; everything else separates them. Stop words are dropped.
+func tokenize(text string) []token {
+	var out []token
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := strings.ToLower(text[start:end])
+		if !stopWords[word] {
+			out = append(out, token{start: start, end: end, term: Stem(word)})
+		}
+		start = -1
+	}
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+	return out
+}
+
+// queryTerm is a parsed query word. prefix is the unstemmed word, used to
+// match partially typed last words ("arm" finds "armbar").
+type queryTerm struct {
+	term   string
+	prefix string
+}
+
+// parseQuery tokenizes a query. The last word is also matched as a prefix
+// unless the query ends in a separator, as search-as-you-type clients send.
+func parseQuery(q string) []queryTerm {
+	var out []queryTerm
+	seen := map[string]bool{}
+	tokens := tokenize(q)
+	for i, t := range tokens {
+		if seen[t.term] {
+			continue
+		}
+		seen[t.term] = true
+		qt := queryTerm{term: t.term}
+		if i == len(tokens)-1 && t.end == len(q) {
+			qt.prefix = strings.ToLower(q[t.start:t.end])
+		}
+		out = append(out, qt)
+	}
+	return out
+}
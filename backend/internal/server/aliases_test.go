@@ -0,0 +1,61 @@
+package server_test
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/outline"
+)
+
+func TestTechniqueAliases(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		rec := ts.do("POST", "/api/v1/techniques?disciplineId=bjj", tokEditor, map[string]interface{}{
+			"name": "Kimura", "aliases": []string{"Double Wristlock", " <b>ude-garami</b> ", "kimura", "Ude Garami"},
+		})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		kimura := decode[model.Technique](t, rec)
+		if !slices.Equal(kimura.Aliases, []string{"Double Wristlock", "ude-garami"}) {
+			t.Errorf("aliases: got %q", kimura.Aliases)
+		}
+
+		// Names and aliases are unique together.
+		for _, body := range []map[string]interface{}{
+			{"name": "Double Wristlock"},
+			{"name": "Americana", "aliases": []string{"Ude Garami"}},
+			{"name": "Keylock", "aliases": []string{"armbar"}},
+		} {
+			if rec := ts.do("POST", "/api/v1/techniques?disciplineId=bjj", tokEditor, body); rec.Code != http.StatusConflict {
+				t.Errorf("create %v: got %d, want 409", body, rec.Code)
+			}
+		}
+		if rec := ts.do("PATCH", "/api/v1/techniques/"+fixTechnique, tokEditor, map[string]string{"name": "Double Wristlock"}); rec.Code != http.StatusConflict {
+			t.Errorf("rename onto an alias: got %d, want 409", rec.Code)
+		}
+
+		// Renaming a technique to one of its aliases drops that alias.
+		rec = ts.do("PATCH", "/api/v1/techniques/"+kimura.ID, tokEditor, map[string]string{"name": "Ude-Garami"})
+		if got := decode[model.Technique](t, rec); rec.Code != http.StatusOK || !slices.Equal(got.Aliases, []string{"Double Wristlock"}) {
+			t.Errorf("rename to alias: got %d %q", rec.Code, got.Aliases)
+		}
+
+		// The list filter, search and outline imports match aliases.
+		list := decode[[]model.Technique](t, ts.do("GET", "/api/v1/techniques?disciplineId=bjj&q=double", tokViewer, nil))
+		if len(list) != 1 || list[0].ID != kimura.ID {
+			t.Errorf("q=double: got %+v", list)
+		}
+		results := decode[[]model.SearchResult](t, ts.do("GET", "/api/v1/search?disciplineId=bjj&q=wristlock", tokViewer, nil))
+		if len(results) == 0 || results[0].ID != kimura.ID {
+			t.Errorf("search wristlock: got %+v", results)
+		}
+		rec = ts.do("POST", "/api/v1/curricula/import?disciplineId=bjj", tokEditor,
+			map[string]interface{}{"format": "markdown", "source": "# Locks\n\n## Double wristlock\n"})
+		p := decode[outline.Preview](t, rec)
+		if len(p.Elements) != 1 || p.Elements[0].TechniqueID == nil || *p.Elements[0].TechniqueID != kimura.ID {
+			t.Errorf("import by alias: got %+v", p.Elements)
+		}
+	})
+}
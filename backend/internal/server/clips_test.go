@@ -0,0 +1,93 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+func TestClipElements(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		curr := "/api/v1/curricula/" + fixCurriculum
+		length := "PT1H2M"
+		if err := ts.store.Assets().Set(context.Background(), "asset-instructional", &model.Asset{
+			DisciplineID: "bjj", URL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", Title: "Armbar Instructional",
+			Type: model.AssetTypeVideo, Duration: &length, Active: true, ProcessingStatus: "completed", OwnerUID: "system",
+		}); err != nil {
+			t.Fatalf("seed asset: %v", err)
+		}
+
+		rec := ts.do("POST", curr+"/elements", tokEditor, map[string]interface{}{
+			"type": "clip", "assetId": "asset-instructional", "startSeconds": 750, "endSeconds": 910, "caption": "Watch the hips",
+		})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create clip: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		clip := decode[model.CurriculumElement](t, rec)
+		if clip.ClipURL != "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=750s" ||
+			clip.EmbedURL != "https://www.youtube.com/embed/dQw4w9WgXcQ?start=750&end=910" {
+			t.Errorf("clip links: %q, %q", clip.ClipURL, clip.EmbedURL)
+		}
+		if c := decode[model.Curriculum](t, ts.do("GET", curr, tokEditor, nil)); c.TotalDurationSeconds != 300+160 {
+			t.Errorf("total duration: got %d, want %d", c.TotalDurationSeconds, 300+160)
+		}
+
+		for _, tt := range []struct {
+			name    string
+			body    map[string]interface{}
+			wantErr string
+		}{
+			{"past the end", map[string]interface{}{"startSeconds": 3600, "endSeconds": 3721}, "past the end of the video (3720 seconds)"},
+			{"backwards", map[string]interface{}{"startSeconds": 60, "endSeconds": 60}, "before endSeconds"},
+			{"no end", map[string]interface{}{"startSeconds": 60}, "are required"},
+			{"duration", map[string]interface{}{"startSeconds": 0, "endSeconds": 60, "duration": "5:00"}, "take their duration"},
+			{"web asset", map[string]interface{}{"assetId": fixAsset, "startSeconds": 0, "endSeconds": 60}, "video asset"},
+		} {
+			body := map[string]interface{}{"type": "clip", "assetId": "asset-instructional"}
+			for k, v := range tt.body {
+				body[k] = v
+			}
+			rec := ts.do("POST", curr+"/elements", tokEditor, body)
+			if rec.Code != http.StatusBadRequest || !strings.Contains(decode[map[string]string](t, rec)["error"], tt.wantErr) {
+				t.Errorf("%s: got %d (%s), want 400 %q", tt.name, rec.Code, rec.Body.String(), tt.wantErr)
+			}
+		}
+		if rec := ts.do("POST", curr+"/elements", tokEditor, map[string]interface{}{"type": "text", "title": "x", "startSeconds": 5}); rec.Code != http.StatusBadRequest {
+			t.Errorf("text with startSeconds: got %d", rec.Code)
+		}
+
+		// An asset element becomes a clip and back.
+		rec = ts.do("POST", curr+"/elements", tokEditor, map[string]string{"type": "asset", "assetId": "asset-instructional", "duration": "1:02:00"})
+		asset := decode[model.CurriculumElement](t, rec).ID
+		if rec := ts.do("PUT", curr+"/elements/"+asset, tokEditor, map[string]string{"type": "clip"}); rec.Code != http.StatusBadRequest {
+			t.Errorf("convert without segment: got %d", rec.Code)
+		}
+		rec = ts.do("PUT", curr+"/elements/"+asset, tokEditor, map[string]interface{}{"type": "clip", "startSeconds": 0, "endSeconds": 90})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("convert to clip: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		if e := decode[model.CurriculumElement](t, rec); e.Type != model.ElementTypeClip || e.Duration != nil || *e.EndSeconds != 90 || e.ClipURL == "" {
+			t.Errorf("converted clip: got %+v", e)
+		}
+		if c := decode[model.Curriculum](t, ts.do("GET", curr, tokEditor, nil)); c.TotalDurationSeconds != 300+160+90 {
+			t.Errorf("total after conversion: got %d", c.TotalDurationSeconds)
+		}
+		if rec := ts.do("PUT", curr+"/elements/"+asset, tokEditor, map[string]string{"type": "text"}); rec.Code != http.StatusBadRequest {
+			t.Errorf("convert clip to text: got %d", rec.Code)
+		}
+		rec = ts.do("PUT", curr+"/elements/"+asset, tokEditor, map[string]string{"type": "asset"})
+		if e := decode[model.CurriculumElement](t, rec); rec.Code != http.StatusOK || e.Type != model.ElementTypeAsset || e.StartSeconds != nil || e.ClipURL != "" {
+			t.Errorf("convert back: got %d %+v", rec.Code, e)
+		}
+
+		// Exports link to the start of the clip.
+		md := ts.do("GET", curr+"/export?format=md", tokEditor, nil).Body.String()
+		if !strings.Contains(md, "Armbar Instructional [12:30–15:10] (2:40)") || !strings.Contains(md, "<"+clip.ClipURL+">") ||
+			!strings.Contains(md, "Watch the hips") {
+			t.Errorf("markdown export:\n%s", md)
+		}
+	})
+}
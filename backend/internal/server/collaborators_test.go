@@ -0,0 +1,88 @@
+package server_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+func TestCurriculumCollaborators(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		rec := ts.do("POST", "/api/v1/curricula?disciplineId=bjj", tokEditor, map[string]string{"title": "Competition Team"})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create curriculum: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		id := decode[model.Curriculum](t, rec).ID
+		curr := "/api/v1/curricula/" + id
+
+		// visible reports whether the viewer can find the curriculum by ID,
+		// in the list and in search.
+		visible := func() [3]bool {
+			var got [3]bool
+			got[0] = ts.do("GET", curr, tokViewer, nil).Code == http.StatusOK
+			for _, c := range decode[[]model.Curriculum](t, ts.do("GET", "/api/v1/curricula?disciplineId=bjj", tokViewer, nil)) {
+				got[1] = got[1] || c.ID == id
+			}
+			for _, r := range decode[[]model.SearchResult](t, ts.do("GET", "/api/v1/search?disciplineId=bjj&q=competition", tokViewer, nil)) {
+				got[2] = got[2] || r.ID == id
+			}
+			return got
+		}
+		if got := visible(); got != [3]bool{} {
+			t.Fatalf("private curriculum visible to others: %v", got)
+		}
+
+		// Invited as a viewer by email, the user can read but not edit.
+		rec = ts.do("POST", curr+"/collaborators", tokEditor, map[string]string{"email": "viewer@example.com", "role": "viewer"})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("invite: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		if c := decode[model.Collaborator](t, rec); c.UID != tokViewer || c.Role != model.CollaboratorViewer || c.Email != "viewer@example.com" {
+			t.Errorf("invite: got %+v", c)
+		}
+		if got := visible(); got != [3]bool{true, true, true} {
+			t.Errorf("viewer collaborator: visible %v", got)
+		}
+		if rec := ts.do("PATCH", curr, tokViewer, map[string]string{"title": "Mine"}); rec.Code != http.StatusForbidden {
+			t.Errorf("viewer update: got %d", rec.Code)
+		}
+
+		// Promoted to editor, they can change the curriculum and its elements
+		// but not its visibility, collaborators or existence.
+		if rec := ts.do("POST", curr+"/collaborators", tokEditor, map[string]string{"uid": tokViewer, "role": "editor"}); rec.Code != http.StatusOK {
+			t.Fatalf("promote: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		for _, step := range []struct {
+			method, path string
+			body         interface{}
+			want         int
+		}{
+			{"PATCH", curr, map[string]string{"title": "Competition Squad"}, http.StatusOK},
+			{"POST", curr + "/elements", map[string]string{"type": "text", "title": "Warm-up"}, http.StatusCreated},
+			{"GET", curr + "/collaborators", nil, http.StatusOK},
+			{"PATCH", curr, map[string]bool{"isPublic": true}, http.StatusForbidden},
+			{"POST", curr + "/collaborators", map[string]string{"uid": tokNoRole, "role": "viewer"}, http.StatusForbidden},
+			{"DELETE", curr, nil, http.StatusForbidden},
+		} {
+			if rec := ts.do(step.method, step.path, tokViewer, step.body); rec.Code != step.want {
+				t.Errorf("editor collaborator %s %s: got %d, want %d (%s)", step.method, step.path, rec.Code, step.want, rec.Body.String())
+			}
+		}
+		list := decode[[]model.Collaborator](t, ts.do("GET", curr+"/collaborators", tokEditor, nil))
+		if len(list) != 1 || list[0].UID != tokViewer || list[0].Role != model.CollaboratorEditor || list[0].DisplayName != tokViewer {
+			t.Errorf("collaborators: got %+v", list)
+		}
+
+		// Leaving hides the curriculum again.
+		if rec := ts.do("DELETE", curr+"/collaborators/"+tokViewer, tokViewer, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("leave: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		if got := visible(); got != [3]bool{} {
+			t.Errorf("after leaving: visible %v", got)
+		}
+		if list := decode[[]model.Collaborator](t, ts.do("GET", curr+"/collaborators", tokEditor, nil)); len(list) != 0 {
+			t.Errorf("collaborators after leaving: got %+v", list)
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package server_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+func TestCurriculumCounters(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		ctx := context.Background()
+		curr := "/api/v1/curricula/" + fixCurriculum
+		counters := func() curriculum.Counters {
+			t.Helper()
+			rec := ts.do("GET", "/api/v1/curricula?disciplineId=bjj", tokViewer, nil)
+			for _, c := range decode[[]model.Curriculum](t, rec) {
+				if c.ID == fixCurriculum {
+					return curriculum.Stored(&c).Counters
+				}
+			}
+			t.Fatalf("curriculum %s not listed", fixCurriculum)
+			return curriculum.Counters{}
+		}
+		want := func(step string, count, seconds int) {
+			t.Helper()
+			if got := counters(); got != (curriculum.Counters{ElementCount: count, TotalDurationSeconds: seconds}) {
+				t.Errorf("%s: got %+v, want %d elements and %ds", step, got, count, seconds)
+			}
+		}
+
+		rec := ts.do("POST", curr+"/elements", tokEditor, map[string]string{"type": "text", "title": "Drill", "duration": "15m"})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create element: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		elem := decode[model.CurriculumElement](t, rec)
+		want("create", 2, 300+900)
+
+		if rec := ts.do("PUT", curr+"/elements/"+elem.ID, tokEditor, map[string]string{"duration": "1:00:00"}); rec.Code != http.StatusOK {
+			t.Fatalf("update element: got %d", rec.Code)
+		}
+		want("update duration", 2, 300+3600)
+		if rec := ts.do("PUT", curr+"/elements/"+elem.ID, tokEditor, map[string]string{"title": "Drills"}); rec.Code != http.StatusOK {
+			t.Fatalf("update element: got %d", rec.Code)
+		}
+		want("update title", 2, 300+3600)
+
+		for range 2 {
+			if rec := ts.do("DELETE", curr+"/elements/"+elem.ID, tokEditor, nil); rec.Code != http.StatusNoContent {
+				t.Fatalf("delete element: got %d", rec.Code)
+			}
+		}
+		want("delete twice", 1, 300)
+
+		// Concurrent creates are all counted and get distinct ords.
+		var wg sync.WaitGroup
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ts.do("POST", curr+"/elements", tokEditor, map[string]string{"type": "text", "title": fmt.Sprint("Step ", i), "duration": "PT1M"})
+			}()
+		}
+		wg.Wait()
+		want("concurrent creates", 9, 300+8*60)
+		elements, err := ts.store.Elements().List(ctx, fixCurriculum, store.NewQuery())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ords := map[int]bool{}
+		for _, e := range elements {
+			ords[e.Ord] = true
+		}
+		if len(elements) != 9 || len(ords) != 9 {
+			t.Errorf("concurrent creates: got %d elements with %d distinct ords", len(elements), len(ords))
+		}
+		c, err := ts.store.Curricula().Get(ctx, fixCurriculum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range 8 {
+			if step := fmt.Sprint("step ", i); !strings.Contains(c.SearchText, step) {
+				t.Errorf("concurrent creates: searchText %q lacks %q", c.SearchText, step)
+			}
+		}
+
+		// Reconcile reports drift, and only fixes it outside a dry run.
+		if err := ts.store.Curricula().Update(ctx, fixCurriculum, []store.Update{{Path: "elementCount", Value: 42}}); err != nil {
+			t.Fatal(err)
+		}
+		drift, err := curriculum.Reconcile(ctx, ts.store, fixCurriculum, true)
+		if err != nil || drift == nil || drift.Stored.ElementCount != 42 || drift.Actual.ElementCount != 9 {
+			t.Fatalf("dry run: got %+v, %v", drift, err)
+		}
+		want("dry run", 42, 300+8*60)
+		var reported []string
+		checked, err := curriculum.ReconcileAll(ctx, ts.store, "", false,
+			func(d curriculum.Drift) { reported = append(reported, d.CurriculumID) },
+			func(id string, err error) { t.Errorf("reconcile %s: %v", id, err) })
+		if err != nil || checked != 2 || len(reported) != 1 || reported[0] != fixCurriculum {
+			t.Errorf("reconcile all: checked %d, reported %v, %v", checked, reported, err)
+		}
+		want("reconciled", 9, 300+8*60)
+		if drift, err := curriculum.Reconcile(ctx, ts.store, fixCurriculum, false); drift != nil || err != nil {
+			t.Errorf("after reconcile: got %+v, %v", drift, err)
+		}
+
+		// Deleting the curriculum takes its elements along.
+		if rec := ts.do("DELETE", curr, tokEditor, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("delete curriculum: got %d", rec.Code)
+		}
+		if left, err := ts.store.Elements().List(ctx, fixCurriculum, store.NewQuery()); err != nil || len(left) != 0 {
+			t.Errorf("elements after delete: got %d, %v", len(left), err)
+		}
+		if rec := ts.do("POST", curr+"/elements", tokEditor, map[string]string{"type": "text", "title": "Late"}); rec.Code != http.StatusNotFound {
+			t.Errorf("create in deleted curriculum: got %d, want 404", rec.Code)
+		}
+	})
+}
+
+func TestCurriculumDenormRepair(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		type report struct {
+			Checked  int
+			Drifted  []curriculum.Drift
+			Repaired bool
+		}
+		call := func(method, path string) report {
+			t.Helper()
+			rec := ts.do(method, path, tokAdmin, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s %s: got %d (%s)", method, path, rec.Code, rec.Body.String())
+			}
+			return decode[report](t, rec)
+		}
+
+		if got := call("GET", "/api/v1/admin/curricula/denorm?disciplineId=bjj"); got.Checked != 1 || len(got.Drifted) != 0 {
+			t.Fatalf("clean report: got %+v", got)
+		}
+
+		// Writes that bypass the API leave the derived fields stale.
+		err := ts.store.Curricula().Update(context.Background(), fixCurriculum, []store.Update{
+			{Path: "allTagIds", Value: []string{}},
+			{Path: "searchText", Value: "stale"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := call("GET", "/api/v1/admin/curricula/denorm?disciplineId=bjj")
+		if len(got.Drifted) != 1 || got.Repaired {
+			t.Fatalf("report: got %+v", got)
+		}
+		d := got.Drifted[0]
+		if d.CurriculumID != fixCurriculum || strings.Join(d.Fields, ",") != "allTagIds,searchText" ||
+			d.Actual.SearchText != "white belt fundamentals intro" || !containsAll(d.Actual.AllTagIDs, fixTag) {
+			t.Errorf("drift: got %+v", d)
+		}
+		if got := call("GET", "/api/v1/admin/curricula/denorm?disciplineId=bjj"); len(got.Drifted) != 1 {
+			t.Errorf("report must not repair: got %+v", got)
+		}
+
+		if got := call("POST", "/api/v1/admin/curricula/denorm/repair?disciplineId=bjj"); len(got.Drifted) != 1 || !got.Repaired {
+			t.Errorf("repair: got %+v", got)
+		}
+		if got := call("GET", "/api/v1/admin/curricula/denorm?disciplineId=bjj"); len(got.Drifted) != 0 {
+			t.Errorf("after repair: got %+v", got)
+		}
+		rec := ts.do("GET", "/api/v1/curricula?tagId="+fixTag, tokViewer, nil)
+		if got := decode[[]model.Curriculum](t, rec); len(got) != 1 {
+			t.Errorf("tag filter after repair: got %d curricula", len(got))
+		}
+	})
+}
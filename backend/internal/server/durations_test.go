@@ -0,0 +1,92 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+func TestDurations(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		// Durations are accepted in any notation and stored in clock notation
+		// and seconds.
+		created := map[string]string{}
+		for _, a := range []struct{ title, duration string }{
+			{"Rolling Highlights", "4m 30s"},
+			{"Guard Passing Masterclass", "PT1H2M3S"},
+			{"Hip Escape Drill", "1:30"},
+		} {
+			rec := ts.do("POST", "/api/v1/assets?disciplineId=bjj", tokEditor, map[string]string{
+				"url": "https://example.com/" + a.title, "title": a.title, "duration": a.duration,
+			})
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("create %s: got %d (%s)", a.title, rec.Code, rec.Body.String())
+			}
+			got := decode[model.Asset](t, rec)
+			created[*got.Duration] = got.ID
+		}
+		long := created["1:02:03"]
+		if long == "" || created["4:30"] == "" || created["1:30"] == "" {
+			t.Fatalf("normalized durations: got %v", created)
+		}
+		if a := decode[model.Asset](t, ts.do("GET", "/api/v1/assets/"+long, tokViewer, nil)); a.DurationSeconds == nil || *a.DurationSeconds != 3723 {
+			t.Errorf("durationSeconds: got %v", a.DurationSeconds)
+		}
+
+		// An asset stored with only the YouTube duration still filters.
+		legacy := "PT3M"
+		if err := ts.store.Assets().Set(context.Background(), "asset-legacy", &model.Asset{
+			DisciplineID: "bjj", URL: "https://example.com/legacy", Title: "Legacy Short", Type: model.AssetTypeVideo,
+			Duration: &legacy, Active: true, ProcessingStatus: "completed", OwnerUID: "system",
+		}); err != nil {
+			t.Fatalf("seed asset: %v", err)
+		}
+
+		list := func(query string) string {
+			t.Helper()
+			rec := ts.do("GET", "/api/v1/assets?disciplineId=bjj&"+query, tokViewer, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s: got %d (%s)", query, rec.Code, rec.Body.String())
+			}
+			var titles []string
+			for _, a := range decode[[]model.Asset](t, rec) {
+				titles = append(titles, a.Title)
+			}
+			return strings.Join(titles, ", ")
+		}
+		for _, tt := range []struct{ query, want string }{
+			{"maxDuration=5m&sort=duration", "Hip Escape Drill, Rolling Highlights"},
+			{"maxDuration=5m", "Hip Escape Drill, Rolling Highlights, Legacy Short"},
+			{"minDuration=1:30&maxDuration=4:30&sort=-duration", "Rolling Highlights, Hip Escape Drill"},
+			{"minDuration=1h", "Guard Passing Masterclass"},
+			{"sort=-duration&limit=2", "Guard Passing Masterclass, Rolling Highlights"},
+		} {
+			if got := list(tt.query); got != tt.want {
+				t.Errorf("%s: got %q, want %q", tt.query, got, tt.want)
+			}
+		}
+
+		// Curricula report their planned and summed durations.
+		curr := "/api/v1/curricula/" + fixCurriculum
+		if rec := ts.do("PATCH", curr, tokEditor, map[string]string{"duration": "1h 30m"}); rec.Code != http.StatusOK {
+			t.Fatalf("set duration: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		rec := ts.do("POST", curr+"/elements", tokEditor, map[string]string{"type": "text", "title": "Drill", "duration": "15m"})
+		if e := decode[model.CurriculumElement](t, rec); rec.Code != http.StatusCreated || *e.Duration != "15:00" || *e.DurationSeconds != 900 {
+			t.Fatalf("create element: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		c := decode[model.Curriculum](t, ts.do("GET", curr, tokViewer, nil))
+		if *c.Duration != "1:30:00" || c.DurationSeconds != 5400 || c.TotalDurationSeconds != 1200 || c.TotalDuration != "20:00" {
+			t.Errorf("curriculum durations: %q %d, total %d %q", *c.Duration, c.DurationSeconds, c.TotalDurationSeconds, c.TotalDuration)
+		}
+		if rec := ts.do("PATCH", curr, tokEditor, map[string]string{"duration": ""}); rec.Code != http.StatusOK {
+			t.Fatalf("clear duration: got %d", rec.Code)
+		}
+		if c := decode[model.Curriculum](t, ts.do("GET", curr, tokViewer, nil)); c.Duration != nil || c.DurationSeconds != 0 {
+			t.Errorf("cleared duration: got %v %d", c.Duration, c.DurationSeconds)
+		}
+	})
+}
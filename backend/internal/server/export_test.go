@@ -0,0 +1,77 @@
+package server_test
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCurriculumExport(t *testing.T) {
+	ts := newTestServer(t)
+	curr := "/api/v1/curricula/" + fixCurriculum
+	for _, body := range []map[string]interface{}{
+		{"type": "asset", "assetId": fixAsset, "duration": "PT2M30S"},
+		{"type": "text", "title": "Drills", "details": "**Grip** the wrist, see [notes](https://example.com/notes)\n\n- one\n- two"},
+		{"type": "image", "imageUrl": "https://img.test/grips.png", "duration": "10m"},
+		{"type": "list", "title": "Checklist", "items": []string{"Belt", "Water"}},
+	} {
+		if rec := ts.do("POST", curr+"/elements", tokEditor, body); rec.Code != http.StatusCreated {
+			t.Fatalf("create element: got %d (%s)", rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := ts.do("GET", curr+"/export?format=md", tokViewer, nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/markdown") {
+		t.Fatalf("md: got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	md := rec.Body.String()
+	for _, want := range []string{
+		"# White Belt\n", "5 elements · total 17:30",
+		"## 1. Intro (5:00)", "## 2. Armbar Basics (2:30)\n\n<https://example.com/armbar>",
+		"**Grip** the wrist", "![Image](<https://img.test/grips.png>)", "## 5. Checklist\n\n- Belt\n- Water",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("md: missing %q in\n%s", want, md)
+		}
+	}
+
+	rec = ts.do("GET", curr+"/export?format=html", tokViewer, nil)
+	html := rec.Body.String()
+	for _, want := range []string{
+		"<h1>White Belt</h1>", `<span class="duration">2:30</span>`,
+		`<strong>Grip</strong> the wrist, see <a href="https://example.com/notes" rel="nofollow">notes</a>`,
+		"<li>one</li>", `<img class="image" src="https://img.test/grips.png"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("html: missing %q in\n%s", want, html)
+		}
+	}
+
+	rec = ts.do("GET", curr+"/export?format=pdf", tokViewer, nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" ||
+		rec.Header().Get("Content-Disposition") != `attachment; filename="white-belt.pdf"` {
+		t.Fatalf("pdf: got %d %v", rec.Code, rec.Header())
+	}
+	pdf := rec.Body.Bytes()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("pdf: missing header or trailer")
+	}
+	// The fetched image is embedded, and startxref points at the xref table.
+	if !bytes.Contains(pdf, []byte("/Subtype /Image /Width 4 /Height 3")) {
+		t.Error("pdf: image not embedded")
+	}
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatal("pdf: no startxref")
+	}
+	if off, _ := strconv.Atoi(string(m[1])); !bytes.HasPrefix(pdf[off:], []byte("xref\n")) {
+		t.Errorf("pdf: startxref %d does not point at xref", off)
+	}
+
+	if rec := ts.do("GET", curr+"/export?format=docx", tokViewer, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown format: got %d", rec.Code)
+	}
+}
@@ -0,0 +1,365 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/handler"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/search"
+	"github.com/thomas/skillhive-api/internal/server"
+	"github.com/thomas/skillhive-api/internal/store"
+	"google.golang.org/api/iterator"
+)
+
+// Test principals. Each token is also the user's UID.
+const (
+	tokViewer    = "viewer"     // explicit viewer in bjj
+	tokNoRole    = "norole"     // no roles at all (defaults to viewer)
+	tokEditor    = "editor"     // editor in bjj
+	tokAdmin     = "admin"      // admin in bjj
+	tokJKDEditor = "jkd-editor" // editor in jkd only
+	tokJKDAdmin  = "jkd-admin"  // admin in jkd only
+)
+
+var principals = map[string]map[string]string{
+	tokViewer:    {"bjj": "viewer"},
+	tokNoRole:    {},
+	tokEditor:    {"bjj": "editor"},
+	tokAdmin:     {"bjj": "admin"},
+	tokJKDEditor: {"jkd": "editor"},
+	tokJKDAdmin:  {"jkd": "admin"},
+}
+
+// fakeVerifier accepts the tokens in principals.
+type fakeVerifier struct{}
+
+func (fakeVerifier) VerifyIDToken(_ context.Context, idToken string) (*auth.Token, error) {
+	roles, ok := principals[idToken]
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	claimRoles := map[string]interface{}{}
+	for k, v := range roles {
+		claimRoles[k] = v
+	}
+	return &auth.Token{UID: idToken, Claims: map[string]interface{}{"roles": claimRoles}}, nil
+}
+
+// fakeUsers is an in-memory handler.UserAdmin.
+type fakeUsers struct {
+	users map[string]*auth.UserRecord
+}
+
+func newFakeUsers() *fakeUsers {
+	f := &fakeUsers{users: map[string]*auth.UserRecord{}}
+	for uid, roles := range principals {
+		claimRoles := map[string]interface{}{}
+		for k, v := range roles {
+			claimRoles[k] = v
+		}
+		f.users[uid] = &auth.UserRecord{
+			UserInfo:     &auth.UserInfo{UID: uid, Email: uid + "@example.com", DisplayName: uid},
+			CustomClaims: map[string]interface{}{"roles": claimRoles},
+		}
+	}
+	return f
+}
+
+func (f *fakeUsers) Users(_ context.Context, pageToken string) handler.UserIterator {
+	uids := make([]string, 0, len(f.users))
+	for uid := range f.users {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	it := &fakeUserIterator{info: &iterator.PageInfo{Token: pageToken}}
+	for _, uid := range uids {
+		it.records = append(it.records, &auth.ExportedUserRecord{UserRecord: f.users[uid]})
+	}
+	return it
+}
+
+func (f *fakeUsers) GetUser(_ context.Context, uid string) (*auth.UserRecord, error) {
+	u, ok := f.users[uid]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return u, nil
+}
+
+func (f *fakeUsers) GetUserByEmail(_ context.Context, email string) (*auth.UserRecord, error) {
+	for _, u := range f.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (f *fakeUsers) SetCustomUserClaims(_ context.Context, uid string, claims map[string]interface{}) error {
+	u, ok := f.users[uid]
+	if !ok {
+		return errors.New("user not found")
+	}
+	u.CustomClaims = claims
+	return nil
+}
+
+// fakeUserIterator pages like the Firebase iterator: fakeUserPageSize users
+// per page, page tokens are start offsets, and PageInfo().Token moves to the
+// next page's token whenever a page is fetched.
+type fakeUserIterator struct {
+	records []*auth.ExportedUserRecord
+	info    *iterator.PageInfo
+	buf     []*auth.ExportedUserRecord
+	atEnd   bool
+}
+
+const fakeUserPageSize = 2
+
+func (it *fakeUserIterator) Next() (*auth.ExportedUserRecord, error) {
+	if len(it.buf) == 0 && !it.atEnd {
+		start, _ := strconv.Atoi(it.info.Token)
+		end := min(start+fakeUserPageSize, len(it.records))
+		it.buf = it.records[start:end]
+		it.info.Token = ""
+		if end < len(it.records) {
+			it.info.Token = strconv.Itoa(end)
+		}
+		it.atEnd = it.info.Token == ""
+	}
+	if len(it.buf) == 0 {
+		return nil, iterator.Done
+	}
+	u := it.buf[0]
+	it.buf = it.buf[1:]
+	return u, nil
+}
+
+func (it *fakeUserIterator) PageInfo() *iterator.PageInfo {
+	return it.info
+}
+
+// Fixture IDs seeded into every test server.
+const (
+	fixTag         = "tag-guard"
+	fixTag2        = "tag-sweep"
+	fixCategory    = "cat-guard"
+	fixChildCat    = "cat-closed-guard"
+	fixTechnique   = "tech-armbar"
+	fixTriangle    = "tech-triangle"
+	fixEdge        = "edge-triangle-armbar"
+	fixAsset       = "asset-armbar"
+	fixInactive    = "asset-pending"
+	fixCurriculum  = "curr-white-belt"
+	fixElement     = "elem-intro"
+	fixJKDTag      = "tag-jkd"
+	fixJKDAsset    = "asset-jkd"
+	fixJKDCurricul = "curr-jkd"
+)
+
+type testServer struct {
+	t     *testing.T
+	h     http.Handler
+	store store.Store
+	users *fakeUsers
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	return newTestServerWith(t, store.NewMemory())
+}
+
+// newSQLiteTestServer runs the router on a fresh in-memory SQLite database.
+func newSQLiteTestServer(t *testing.T) *testServer {
+	t.Helper()
+	s, err := store.NewSQL(context.Background(), "sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return newTestServerWith(t, s)
+}
+
+// forEachBackend runs fn against a fresh test server on every Store backend.
+func forEachBackend(t *testing.T, fn func(t *testing.T, ts *testServer)) {
+	t.Run("memory", func(t *testing.T) { fn(t, newTestServer(t)) })
+	t.Run("sqlite", func(t *testing.T) { fn(t, newSQLiteTestServer(t)) })
+}
+
+func newTestServerWith(t *testing.T, raw store.Store) *testServer {
+	t.Helper()
+	seed(t, raw)
+	index := search.NewIndex()
+	s := search.Wrap(raw, index)
+	if err := index.Rebuild(context.Background(), s); err != nil {
+		t.Fatalf("build search index: %v", err)
+	}
+	users := newFakeUsers()
+	return &testServer{
+		t:     t,
+		store: s,
+		users: users,
+		h: server.NewRouter(server.Deps{
+			Store:              s,
+			Search:             index,
+			Verifier:           fakeVerifier{},
+			Users:              users,
+			FetchImage:         fakeFetchImage,
+			CORSAllowedOrigins: "http://localhost:5173",
+		}),
+	}
+}
+
+func seed(t *testing.T, s store.Store) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	str := func(v string) *string { return &v }
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	for _, d := range []model.Discipline{
+		{ID: "bjj", Name: "Brazilian Jiu-Jitsu", Slug: "bjj"},
+		{ID: "jkd", Name: "Jeet Kune Do", Slug: "jkd"},
+	} {
+		d.CreatedAt, d.UpdatedAt = now, now
+		must(s.Disciplines().Set(ctx, d.ID, &d))
+	}
+
+	for _, tg := range []model.Tag{
+		{ID: fixTag, DisciplineID: "bjj", Name: "Guard", Slug: "guard"},
+		{ID: fixTag2, DisciplineID: "bjj", Name: "Sweep", Slug: "sweep"},
+		{ID: fixJKDTag, DisciplineID: "jkd", Name: "Trapping", Slug: "trapping"},
+	} {
+		tg.OwnerUID, tg.CreatedAt, tg.UpdatedAt = "system", now, now
+		must(s.Tags().Set(ctx, tg.ID, &tg))
+	}
+
+	for _, c := range []model.Category{
+		{ID: fixCategory, DisciplineID: "bjj", Name: "Guard", Slug: "guard"},
+		{ID: fixChildCat, DisciplineID: "bjj", Name: "Closed Guard", Slug: "closed-guard", ParentID: str(fixCategory)},
+	} {
+		c.OwnerUID, c.CreatedAt, c.UpdatedAt = "system", now, now
+		must(s.Categories().Set(ctx, c.ID, &c))
+	}
+
+	must(s.Techniques().Set(ctx, fixTechnique, &model.Technique{
+		DisciplineID: "bjj", Name: "Armbar", Slug: "armbar", Description: "Straight arm lock",
+		CategoryIDs: []string{fixChildCat}, TagIDs: []string{fixTag2},
+		OwnerUID: "system", CreatedAt: now, UpdatedAt: now,
+	}))
+	// The triangle sets up the armbar.
+	must(s.Techniques().Set(ctx, fixTriangle, &model.Technique{
+		DisciplineID: "bjj", Name: "Triangle", Slug: "triangle", Description: "Leg choke",
+		CategoryIDs: []string{}, TagIDs: []string{},
+		OwnerUID: "system", CreatedAt: now, UpdatedAt: now,
+	}))
+	must(s.TechniqueEdges().Set(ctx, fixEdge, &model.TechniqueEdge{
+		DisciplineID: "bjj", FromID: fixTriangle, ToID: fixTechnique, Type: model.EdgeSetupFor, AssetIDs: []string{},
+		OwnerUID: "system", CreatedAt: now, UpdatedAt: now,
+	}))
+
+	for _, a := range []model.Asset{
+		{ID: fixAsset, DisciplineID: "bjj", URL: "https://example.com/armbar", Title: "Armbar Basics", Type: model.AssetTypeWeb,
+			TechniqueIDs: []string{fixTechnique}, TagIDs: []string{fixTag}, Active: true, ProcessingStatus: "completed"},
+		{ID: fixInactive, DisciplineID: "bjj", URL: "https://youtu.be/abc", Title: "Processing...", Type: model.AssetTypeVideo,
+			Active: false, ProcessingStatus: "failed"},
+		{ID: fixJKDAsset, DisciplineID: "jkd", URL: "https://example.com/jkd", Title: "Straight Lead", Type: model.AssetTypeWeb,
+			Active: true, ProcessingStatus: "completed"},
+	} {
+		a.OwnerUID, a.CreatedAt, a.UpdatedAt = "system", now, now
+		must(s.Assets().Set(ctx, a.ID, &a))
+	}
+
+	// The bjj editor owns the white belt curriculum, the jkd editor the
+	// private JKD one.
+	for _, c := range []model.Curriculum{
+		{ID: fixCurriculum, DisciplineID: "bjj", Title: "White Belt", Description: "Fundamentals", IsPublic: true,
+			OwnerUID: tokEditor, TagIDs: []string{fixTag}, AllTagIDs: []string{fixTag}, SearchText: "white belt fundamentals intro",
+			ElementCount: 1, TotalDurationSeconds: 300},
+		{ID: fixJKDCurricul, DisciplineID: "jkd", Title: "JKD Basics", OwnerUID: tokJKDEditor, TagIDs: []string{}, AllTagIDs: []string{},
+			SearchText: "jkd basics"},
+	} {
+		c.CreatedAt, c.UpdatedAt = now, now
+		must(s.Curricula().Set(ctx, c.ID, &c))
+	}
+
+	must(s.Elements().Set(ctx, fixCurriculum, fixElement, &model.CurriculumElement{
+		Type: model.ElementTypeText, Title: str("Intro"), Duration: str("5:00"), Ord: 1, CreatedAt: now, UpdatedAt: now,
+	}))
+	// The white belt curriculum starts with one revision.
+	must(s.RunTransaction(ctx, func(ctx context.Context, tx store.Tx) error {
+		c, elements, err := curriculum.Load(s, tx, fixCurriculum)
+		if err != nil {
+			return err
+		}
+		return curriculum.Save(s, tx, c, elements, curriculum.Change{Action: model.RevisionCreate, ActorUID: "system", At: now})
+	}))
+}
+
+// fakeFetchImage serves a small PNG for URLs under https://img.test/ and
+// fails for anything else, so exports never reach the network.
+func fakeFetchImage(_ context.Context, url string) ([]byte, error) {
+	if !strings.HasPrefix(url, "https://img.test/") {
+		return nil, errors.New("no such image")
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 3))); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// do sends a request through the router. body may be nil, a string, or a
+// value that is JSON-encoded.
+func (ts *testServer) do(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	ts.t.Helper()
+	var buf bytes.Buffer
+	switch b := body.(type) {
+	case nil:
+	case string:
+		buf.WriteString(b)
+	default:
+		if err := json.NewEncoder(&buf).Encode(b); err != nil {
+			ts.t.Fatalf("encode body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	ts.h.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode unmarshals a response body, failing the test on error.
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %T: %v (body %q)", v, err, rec.Body.String())
+	}
+	return v
+}
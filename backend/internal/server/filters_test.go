@@ -0,0 +1,148 @@
+package server_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+func TestListFilters(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		ctx := context.Background()
+		str := func(v string) *string { return &v }
+		day := func(d int) time.Time { return time.Date(2025, 3, d, 12, 0, 0, 0, time.UTC) }
+
+		if err := ts.store.Techniques().Set(ctx, "tech-scissor", &model.Technique{
+			DisciplineID: "bjj", Name: "Scissor Sweep", Slug: "scissor-sweep",
+			CategoryIDs: []string{fixCategory}, TagIDs: []string{fixTag, fixTag2},
+			OwnerUID: "system", CreatedAt: day(1), UpdatedAt: day(1),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range []model.Asset{
+			{ID: "asset-danaher", Title: "Leg Lock System", VideoType: str("instructional"), Originator: str("John Danaher"),
+				TechniqueIDs: []string{fixTechnique}, TagIDs: []string{fixTag2}, CreatedAt: day(10)},
+			{ID: "asset-short", Title: "Quick Sweep", VideoType: str("short"), Originator: str("Someone"),
+				TechniqueIDs: []string{fixTechnique, "tech-scissor"}, TagIDs: []string{fixTag, fixTag2}, CreatedAt: day(20)},
+		} {
+			a.DisciplineID, a.URL, a.Type, a.Active, a.ProcessingStatus = "bjj", "https://example.com/"+a.ID, model.AssetTypeVideo, true, "completed"
+			a.CategoryIDs, a.OwnerUID, a.UpdatedAt = []string{}, "system", a.CreatedAt
+			if err := ts.store.Assets().Set(ctx, a.ID, &a); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		list := func(path, token string) []string {
+			t.Helper()
+			rec := ts.do("GET", path, token, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s: got %d (%s)", path, rec.Code, rec.Body.String())
+			}
+			var ids []string
+			if strings.HasPrefix(path, "/api/v1/techniques") {
+				for _, x := range decode[[]model.Technique](t, rec) {
+					ids = append(ids, x.ID)
+				}
+			} else {
+				for _, x := range decode[[]model.Asset](t, rec) {
+					ids = append(ids, x.ID)
+				}
+			}
+			sort.Strings(ids)
+			return ids
+		}
+
+		tests := []struct {
+			path string
+			want string
+		}{
+			// Tags default to all-of; tagMode=any makes them one-of.
+			{"/api/v1/techniques?disciplineId=bjj&tagIds=tag-guard,tag-sweep", "tech-scissor"},
+			{"/api/v1/techniques?disciplineId=bjj&tagId=tag-guard&tagId=tag-sweep&tagMode=any", "tech-armbar tech-scissor"},
+			// Categories are one-of and combine with tags.
+			{"/api/v1/techniques?disciplineId=bjj&categoryIds=cat-guard,cat-closed-guard", "tech-armbar tech-scissor"},
+			{"/api/v1/techniques?disciplineId=bjj&categoryId=cat-closed-guard&tagId=tag-sweep", "tech-armbar"},
+			{"/api/v1/techniques?disciplineId=bjj&createdAfter=2025-02-01", "tech-scissor"},
+			// techniqueId no longer hides the tag filter.
+			{"/api/v1/assets?disciplineId=bjj&techniqueId=tech-armbar&tagId=tag-guard", "asset-armbar asset-short"},
+			{"/api/v1/assets?disciplineId=bjj&techniqueId=tech-armbar&tagIds=tag-guard,tag-sweep", "asset-short"},
+			{"/api/v1/assets?disciplineId=bjj&videoType=instructional,short", "asset-danaher asset-short"},
+			{"/api/v1/assets?disciplineId=bjj&originator=john+danaher", "asset-danaher"},
+			{"/api/v1/assets?disciplineId=bjj&createdAfter=2025-03-05&createdBefore=2025-03-20T12:00:00Z", "asset-danaher"},
+			{"/api/v1/assets?disciplineId=bjj&processingStatus=failed", ""},
+			// Pagination applies after in-memory filtering (newest first).
+			{"/api/v1/assets?disciplineId=bjj&tagId=tag-sweep&limit=1", "asset-short"},
+			{"/api/v1/assets?disciplineId=bjj&tagId=tag-sweep&limit=1&offset=1", "asset-danaher"},
+		}
+		for _, tt := range tests {
+			if got := strings.Join(list(tt.path, tokViewer), " "); got != tt.want {
+				t.Errorf("%s: got %q, want %q", tt.path, got, tt.want)
+			}
+		}
+
+		// More one-of values than Firestore allows in one disjunction.
+		many := []string{fixTag2}
+		for i := range 40 {
+			many = append(many, fmt.Sprintf("tag-%d", i))
+		}
+		path := "/api/v1/assets?disciplineId=bjj&tagMode=any&tagIds=" + strings.Join(many, ",")
+		if got := strings.Join(list(path, tokViewer), " "); got != "asset-danaher asset-short" {
+			t.Errorf("40 tags: got %q", got)
+		}
+
+		// The admin list takes the same filters.
+		if got := strings.Join(list("/api/v1/admin/assets?disciplineId=bjj&processingStatus=failed,completed&videoType=short", tokAdmin), " "); got != "asset-short" {
+			t.Errorf("admin filters: got %q", got)
+		}
+
+		// Facets count the whole filtered list, not just the page.
+		rec := ts.do("GET", "/api/v1/assets?disciplineId=bjj&tagId=tag-sweep&facets=true&limit=1", tokViewer, nil)
+		got := decode[model.FacetedList[model.Asset]](t, rec)
+		if len(got.Items) != 1 || got.Facets.Tags[fixTag2] != 2 || got.Facets.Tags[fixTag] != 1 ||
+			got.Facets.VideoTypes["short"] != 1 || got.Facets.VideoTypes["instructional"] != 1 {
+			t.Errorf("asset facets: got %+v", got)
+		}
+		// Later pages leave them out rather than read the whole list again.
+		rec = ts.do("GET", nextLink.FindStringSubmatch(rec.Header().Get("Link"))[1], tokViewer, nil)
+		if got := decode[model.FacetedList[model.Asset]](t, rec); len(got.Items) != 1 || got.Facets != nil {
+			t.Errorf("second page facets: got %+v", got)
+		}
+		rec = ts.do("GET", "/api/v1/techniques?disciplineId=bjj&facets=true", tokViewer, nil)
+		techFacets := decode[model.FacetedList[model.Technique]](t, rec).Facets
+		if techFacets.Categories[fixCategory] != 1 || techFacets.Categories[fixChildCat] != 1 || techFacets.Tags[fixTag2] != 2 || techFacets.VideoTypes != nil {
+			t.Errorf("technique facets: got %+v", techFacets)
+		}
+
+		for _, bad := range []string{
+			"/api/v1/techniques?disciplineId=bjj&tagMode=some",
+			"/api/v1/assets?disciplineId=bjj&createdAfter=yesterday",
+			"/api/v1/assets?disciplineId=bjj&createdAfter=2025-03-02&createdBefore=2025-03-01",
+		} {
+			if rec := ts.do("GET", bad, tokViewer, nil); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", bad, rec.Code)
+			}
+		}
+
+		// Techniques refuse the filters that only apply to assets.
+		for _, tt := range []struct{ query, param string }{
+			{"techniqueId=" + fixTechnique, "techniqueIds"},
+			{"techniqueIds=" + fixTechnique, "techniqueIds"},
+			{"videoType=instructional", "videoType"},
+			{"originator=Danaher", "originator"},
+			{"processingStatus=completed", "processingStatus"},
+			{"minDuration=5m", "minDuration"},
+			{"maxDuration=1:00:00", "maxDuration"},
+		} {
+			rec := ts.do("GET", "/api/v1/techniques?disciplineId=bjj&"+tt.query, tokViewer, nil)
+			if rec.Code != http.StatusBadRequest || !strings.Contains(decode[map[string]string](t, rec)["error"], tt.param) {
+				t.Errorf("techniques?%s: got %d (%s), want 400 naming %s", tt.query, rec.Code, rec.Body.String(), tt.param)
+			}
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package server_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+func TestCurriculumForkAcrossDisciplines(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		rec := ts.do("POST", "/api/v1/tags?disciplineId=jkd", tokJKDEditor, map[string]string{"name": "Guard"})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create tag: got %d", rec.Code)
+		}
+		jkdGuard := decode[model.Tag](t, rec).ID
+		rec = ts.do("POST", "/api/v1/curricula/"+fixCurriculum+"/elements", tokEditor,
+			map[string]string{"type": "technique", "techniqueId": fixTechnique})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create element: got %d (%s)", rec.Code, rec.Body.String())
+		}
+
+		rec = ts.do("POST", "/api/v1/curricula/"+fixCurriculum+"/fork?disciplineId=jkd", tokJKDEditor, nil)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("fork: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		fork := decode[model.Curriculum](t, rec)
+		if fork.DisciplineID != "jkd" || fork.OwnerUID != tokJKDEditor || fork.IsPublic || fork.Title != "White Belt" ||
+			fork.SourceCurriculumID != fixCurriculum || fork.SourceRevision != 2 || fork.Revision != 1 || fork.ElementCount != 2 {
+			t.Errorf("fork: got %+v", fork)
+		}
+		if strings.Join(fork.TagIDs, ",") != jkdGuard {
+			t.Errorf("fork tags: got %v, want [%s]", fork.TagIDs, jkdGuard)
+		}
+
+		elements := decode[[]model.CurriculumElement](t, ts.do("GET", "/api/v1/curricula/"+fork.ID+"/elements", tokJKDEditor, nil))
+		if len(elements) != 2 || elements[0].SourceElementID != fixElement || elements[0].ID == fixElement {
+			t.Fatalf("fork elements: got %+v", elements)
+		}
+		// jkd has no armbar: the reference is dropped, the snapshot kept.
+		if tech := elements[1]; tech.TechniqueID != nil || tech.Snapshot == nil || tech.Snapshot.Name != "Armbar" {
+			t.Errorf("technique element: got %+v", tech)
+		}
+	})
+}
+
+func TestCurriculumForkPull(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		rec := ts.do("POST", "/api/v1/curricula?disciplineId=bjj", tokEditor, map[string]interface{}{"title": "Blue Belt", "isPublic": true})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create curriculum: got %d", rec.Code)
+		}
+		upstream := "/api/v1/curricula/" + decode[model.Curriculum](t, rec).ID
+		ids := map[string]string{}
+		for _, title := range []string{"A", "B", "C"} {
+			rec := ts.do("POST", upstream+"/elements", tokEditor, map[string]string{"type": "text", "title": title})
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("create element: got %d", rec.Code)
+			}
+			ids[title] = decode[model.CurriculumElement](t, rec).ID
+		}
+
+		rec = ts.do("POST", upstream+"/fork", tokEditor, map[string]string{"title": "My Blue Belt"})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("fork: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		fork := decode[model.Curriculum](t, rec)
+		if fork.Title != "My Blue Belt" || fork.SourceRevision != 4 || fork.ElementCount != 3 {
+			t.Errorf("fork: got %+v", fork)
+		}
+		forkPath := "/api/v1/curricula/" + fork.ID
+		mine := map[string]string{}
+		for _, e := range decode[[]model.CurriculumElement](t, ts.do("GET", forkPath+"/elements", tokEditor, nil)) {
+			mine[*e.Title] = e.ID
+		}
+
+		for _, step := range []struct {
+			method, path string
+			body         interface{}
+		}{
+			{"PATCH", upstream, map[string]string{"description": "Upstream notes"}},
+			{"PUT", upstream + "/elements/" + ids["B"], map[string]string{"title": "B2"}},
+			{"PUT", upstream + "/elements/" + ids["C"], map[string]string{"title": "C upstream"}},
+			{"DELETE", upstream + "/elements/" + ids["A"], nil},
+			{"POST", upstream + "/elements", map[string]string{"type": "text", "title": "D"}},
+			{"PUT", forkPath + "/elements/" + mine["C"], map[string]string{"title": "C mine"}},
+		} {
+			if rec := ts.do(step.method, step.path, tokEditor, step.body); rec.Code >= 300 {
+				t.Fatalf("%s %s: got %d", step.method, step.path, rec.Code)
+			}
+		}
+
+		check := func(report curriculum.PullReport) {
+			t.Helper()
+			if report.FromRevision != 4 || report.ToRevision != 9 || strings.Join(report.Fields, ",") != "description" ||
+				len(report.Added) != 1 || *report.Added[0].Title != "D" ||
+				len(report.Updated) != 1 || *report.Updated[0].Title != "B2" ||
+				len(report.Removed) != 1 || report.Removed[0].ID != mine["A"] ||
+				len(report.Conflicts) != 1 || report.Conflicts[0].ElementID != mine["C"] {
+				t.Errorf("pull report: got %+v", report)
+			}
+		}
+		titles := func() string {
+			var titles []string
+			for _, e := range decode[[]model.CurriculumElement](t, ts.do("GET", forkPath+"/elements", tokEditor, nil)) {
+				titles = append(titles, *e.Title)
+			}
+			return strings.Join(titles, ",")
+		}
+
+		rec = ts.do("POST", forkPath+"/pull?dryRun=true", tokEditor, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("dry run: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		if report := decode[curriculum.PullReport](t, rec); report.Applied {
+			t.Error("dry run: reported as applied")
+		} else {
+			check(report)
+		}
+		if got := titles(); got != "A,B,C mine" {
+			t.Errorf("after dry run: got %s", got)
+		}
+
+		rec = ts.do("POST", forkPath+"/pull", tokEditor, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("pull: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		check(decode[curriculum.PullReport](t, rec))
+		if got := titles(); got != "B2,C mine,D" {
+			t.Errorf("after pull: got %s", got)
+		}
+		c := decode[model.Curriculum](t, ts.do("GET", forkPath, tokEditor, nil))
+		if c.SourceRevision != 9 || c.Description != "Upstream notes" || c.Title != "My Blue Belt" || c.ElementCount != 3 {
+			t.Errorf("pulled fork: got %+v", c)
+		}
+		rev := decode[curriculum.RevisionDetail](t, ts.do("GET", forkPath+"/revisions/3", tokEditor, nil))
+		if rev.Action != model.RevisionPull {
+			t.Errorf("pull revision: got %s", rev.Action)
+		}
+
+		// Caught up: nothing left to pull.
+		report := decode[curriculum.PullReport](t, ts.do("POST", forkPath+"/pull", tokEditor, nil))
+		if report.FromRevision != 9 || len(report.Added)+len(report.Updated)+len(report.Removed)+len(report.Conflicts) != 0 {
+			t.Errorf("second pull: got %+v", report)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package server_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+func TestTechniqueGraph(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		technique := func(name string) string {
+			t.Helper()
+			rec := ts.do("POST", "/api/v1/techniques?disciplineId=bjj", tokEditor, map[string]string{"name": name})
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("create technique %s: got %d (%s)", name, rec.Code, rec.Body.String())
+			}
+			return decode[model.Technique](t, rec).ID
+		}
+		edge := func(from, to, typ string) model.TechniqueEdge {
+			t.Helper()
+			rec := ts.do("POST", "/api/v1/technique-edges?disciplineId=bjj", tokEditor,
+				map[string]interface{}{"fromId": from, "toId": to, "type": typ, "assetIds": []string{fixAsset}})
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("create edge %s %s %s: got %d (%s)", from, typ, to, rec.Code, rec.Body.String())
+			}
+			return decode[model.TechniqueEdge](t, rec)
+		}
+		names := func(nodes []model.GraphNode) string {
+			var s []string
+			for _, n := range nodes {
+				s = append(s, n.Name)
+			}
+			return strings.Join(s, " > ")
+		}
+
+		// The seeded triangle sets up the armbar; both end in a kimura, and
+		// the hip bump counters the triangle.
+		omoplata, kimura, hipBump := technique("Omoplata"), technique("Kimura"), technique("Hip Bump")
+		edge(fixTriangle, omoplata, model.EdgeLeadsTo)
+		edge(omoplata, kimura, model.EdgeLeadsTo)
+		edge(fixTechnique, kimura, model.EdgeLeadsTo)
+		counter := edge(hipBump, fixTriangle, model.EdgeCounters)
+		if len(counter.AssetIDs) != 1 || counter.OwnerUID != tokEditor {
+			t.Errorf("created edge: got %+v", counter)
+		}
+
+		neighbors := decode[[]model.Neighbor](t, ts.do("GET", "/api/v1/techniques/"+fixTriangle+"/neighbors", tokViewer, nil))
+		var got []string
+		for _, n := range neighbors {
+			got = append(got, n.Direction+" "+n.Edge.Type+" "+n.Technique.Name)
+		}
+		if want := "out setup-for Armbar, out leads-to Omoplata, in counters Hip Bump"; strings.Join(got, ", ") != want {
+			t.Errorf("neighbors: got %q, want %q", strings.Join(got, ", "), want)
+		}
+		neighbors = decode[[]model.Neighbor](t, ts.do("GET", "/api/v1/techniques/"+fixTriangle+"/neighbors?direction=out&types=leads-to", tokViewer, nil))
+		if len(neighbors) != 1 || neighbors[0].Technique.ID != omoplata {
+			t.Errorf("outgoing leads-to neighbors: got %+v", neighbors)
+		}
+
+		rec := ts.do("GET", "/api/v1/graph/path?from="+fixTriangle+"&to="+kimura, tokViewer, nil)
+		path := decode[model.TechniquePath](t, rec)
+		if rec.Code != http.StatusOK || path.Length != 2 || names(path.Techniques) != "Triangle > Armbar > Kimura" || len(path.Edges) != 2 {
+			t.Errorf("path: got %d %+v", rec.Code, path)
+		}
+		if rec := ts.do("GET", "/api/v1/graph/path?from="+kimura+"&to="+hipBump, tokViewer, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("directed path against the edges: got %d", rec.Code)
+		}
+		path = decode[model.TechniquePath](t, ts.do("GET", "/api/v1/graph/path?from="+kimura+"&to="+hipBump+"&directed=false", tokViewer, nil))
+		if names(path.Techniques) != "Kimura > Omoplata > Triangle > Hip Bump" {
+			t.Errorf("undirected path: got %q", names(path.Techniques))
+		}
+
+		chains := decode[model.TechniqueChains](t, ts.do("GET", "/api/v1/graph/chains?from="+fixTriangle, tokViewer, nil))
+		got = nil
+		for _, c := range chains.Chains {
+			got = append(got, names(c.Techniques))
+		}
+		if want := "Triangle > Armbar > Kimura, Triangle > Omoplata > Kimura"; strings.Join(got, ", ") != want || chains.Truncated {
+			t.Errorf("chains: got %q (truncated %v), want %q", strings.Join(got, ", "), chains.Truncated, want)
+		}
+		chains = decode[model.TechniqueChains](t, ts.do("GET", "/api/v1/graph/chains?from="+fixTriangle+"&length=1&types=leads-to", tokViewer, nil))
+		if len(chains.Chains) != 1 || names(chains.Chains[0].Techniques) != "Triangle > Omoplata" {
+			t.Errorf("short leads-to chains: got %+v", chains)
+		}
+
+		rec = ts.do("GET", "/api/v1/graph/export?disciplineId=bjj&format=dot", tokViewer, nil)
+		dot := rec.Body.String()
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/vnd.graphviz") {
+			t.Errorf("dot content type: got %q", ct)
+		}
+		for _, want := range []string{
+			`digraph "bjj" {`,
+			`"` + fixTriangle + `" [label="Triangle"];`,
+			`"` + fixTriangle + `" -> "` + fixTechnique + `" [label="setup-for"];`,
+		} {
+			if !strings.Contains(dot, want) {
+				t.Errorf("dot export lacks %q:\n%s", want, dot)
+			}
+		}
+		exported := decode[model.TechniqueGraph](t, ts.do("GET", "/api/v1/graph/export?disciplineId=bjj&types=counters", tokViewer, nil))
+		if len(exported.Nodes) != 5 || len(exported.Edges) != 1 || exported.Edges[0].ID != counter.ID {
+			t.Errorf("json export: got %d nodes and %+v", len(exported.Nodes), exported.Edges)
+		}
+
+		// Deleting a technique deletes its edges.
+		if rec := ts.do("DELETE", "/api/v1/techniques/"+fixTriangle, tokEditor, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("delete technique: got %d", rec.Code)
+		}
+		left := decode[[]model.TechniqueEdge](t, ts.do("GET", "/api/v1/technique-edges?disciplineId=bjj", tokViewer, nil))
+		if len(left) != 2 {
+			t.Errorf("edges after delete: got %+v", left)
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package server_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/outline"
+)
+
+const markdownOutline = `# Week 1
+
+Fundamentals for new students.
+
+## 1. Warm up (10m)
+
+Shrimp the length of the mat.
+
+- Armbar
+- Hip escapes
+- https://youtu.be/dQw4w9WgXcQ
+
+## Armbar (5:00)
+
+## Finish
+
+asset: https://example.com/armbar
+
+![Grips](https://img.test/grips.png)
+`
+
+func TestCurriculumImportMarkdown(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		body := map[string]interface{}{"format": "markdown", "source": markdownOutline}
+		rec := ts.do("POST", "/api/v1/curricula/import?disciplineId=bjj", tokEditor, body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("preview: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		p := decode[outline.Preview](t, rec)
+		if p.Curriculum.Title != "Week 1" || p.Curriculum.Description != "Fundamentals for new students." || p.Curriculum.ID != "" {
+			t.Errorf("curriculum: got %+v", p.Curriculum)
+		}
+		var got []string
+		for _, e := range p.Elements {
+			s := string(e.Type)
+			if e.Title != nil {
+				s += ":" + *e.Title
+			}
+			if e.Duration != nil {
+				s += "@" + *e.Duration
+			}
+			got = append(got, s)
+		}
+		want := "text:Warm up@10:00 technique list:Warm up text:https://youtu.be/dQw4w9WgXcQ technique@5:00 asset image:Grips"
+		if strings.Join(got, " ") != want {
+			t.Errorf("elements:\n got %s\nwant %s", strings.Join(got, " "), want)
+		}
+		if tech := p.Elements[1]; tech.TechniqueID == nil || *tech.TechniqueID != fixTechnique || tech.Snapshot == nil || tech.Snapshot.Name != "Armbar" {
+			t.Errorf("technique: got %+v", tech)
+		}
+		if asset := p.Elements[5]; asset.AssetID == nil || *asset.AssetID != fixAsset {
+			t.Errorf("asset: got %+v", asset)
+		}
+		if len(p.Unresolved) != 1 || p.Unresolved[0].Element != 3 || p.Unresolved[0].Line != 11 || p.Unresolved[0].Kind != "asset" {
+			t.Errorf("unresolved: got %+v", p.Unresolved)
+		}
+
+		// Committing needs allowUnresolved while a reference matches nothing.
+		body["commit"] = true
+		if rec := ts.do("POST", "/api/v1/curricula/import?disciplineId=bjj", tokEditor, body); rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("commit with unresolved: got %d", rec.Code)
+		}
+		body["allowUnresolved"] = true
+		rec = ts.do("POST", "/api/v1/curricula/import?disciplineId=bjj", tokEditor, body)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("commit: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		c := decode[outline.Preview](t, rec).Curriculum
+		if c.ID == "" || c.OwnerUID != tokEditor || c.ElementCount != 7 || c.Revision != 1 || c.TotalDurationSeconds != 15*60 {
+			t.Errorf("committed: got %+v", c)
+		}
+		elements := decode[[]model.CurriculumElement](t, ts.do("GET", "/api/v1/curricula/"+c.ID+"/elements", tokEditor, nil))
+		if len(elements) != 7 || elements[2].Ord != 3 || len(elements[2].Items) != 1 || elements[2].Items[0] != "Hip escapes" {
+			t.Errorf("stored elements: got %+v", elements)
+		}
+	})
+}
+
+func TestCurriculumImportYAML(t *testing.T) {
+	ts := newTestServer(t)
+	source := `title: Week 2
+duration: 1h
+elements:
+  - text: Explain the plan.
+    title: Intro
+  - technique: armbar
+    duration: 15m
+  - list: [Belt, Water]
+    title: Bring
+  - Kimura
+`
+	rec := ts.do("POST", "/api/v1/curricula/import?disciplineId=bjj", tokEditor,
+		map[string]interface{}{"format": "yaml", "source": source, "title": "Week Two", "commit": true})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("commit: got %d (%s)", rec.Code, rec.Body.String())
+	}
+	p := decode[outline.Preview](t, rec)
+	if p.Curriculum.Title != "Week Two" || p.Curriculum.Duration == nil || *p.Curriculum.Duration != "1:00:00" || p.Curriculum.DurationSeconds != 3600 || len(p.Elements) != 4 {
+		t.Fatalf("preview: got %+v", p)
+	}
+	if e := p.Elements[0]; e.Type != model.ElementTypeText || *e.Details != "Explain the plan." {
+		t.Errorf("text: got %+v", e)
+	}
+	if e := p.Elements[1]; e.Type != model.ElementTypeTechnique || *e.TechniqueID != fixTechnique {
+		t.Errorf("technique: got %+v", e)
+	}
+	// A bare string naming no technique is a text element.
+	if e := p.Elements[3]; e.Type != model.ElementTypeText || *e.Title != "Kimura" || len(p.Unresolved) != 0 {
+		t.Errorf("bare string: got %+v", e)
+	}
+
+	for name, source := range map[string]string{
+		"unknown key":   "title: X\nelements:\n  - text: a\n    colour: red\n",
+		"two types":     "title: X\nelements:\n  - technique: armbar\n    asset: https://example.com/armbar\n",
+		"no type":       "title: X\nelements:\n  - title: a\n",
+		"no title":      "elements: []\n",
+		"invalid YAML":  "title: [\n",
+		"untitled list": "title: X\nelements:\n  - list: [a]\n",
+	} {
+		rec := ts.do("POST", "/api/v1/curricula/import?disciplineId=bjj", tokEditor,
+			map[string]interface{}{"format": "yaml", "source": source})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d (%s)", name, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := ts.do("POST", "/api/v1/curricula/import?disciplineId=bjj", tokEditor,
+		map[string]interface{}{"format": "docx", "source": "x"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown format: got %d", rec.Code)
+	}
+}
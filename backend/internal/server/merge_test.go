@@ -0,0 +1,121 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+func TestTechniqueMerge(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		now := time.Now()
+		if err := ts.store.Assets().Set(context.Background(), "asset-triangle", &model.Asset{
+			DisciplineID: "bjj", URL: "https://example.com/triangle", Title: "Triangle to Armbar", Type: model.AssetTypeWeb,
+			TechniqueIDs: []string{fixTriangle, fixTechnique}, TagIDs: []string{}, Active: true, ProcessingStatus: "completed",
+			OwnerUID: "system", CreatedAt: now, UpdatedAt: now,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		curr := "/api/v1/curricula/" + fixCurriculum
+		rec := ts.do("POST", curr+"/elements", tokEditor, map[string]string{"type": "technique", "techniqueId": fixTriangle})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create element: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		element := decode[model.CurriculumElement](t, rec)
+
+		// Besides the seeded edge from the triangle to the armbar, which
+		// would join the armbar to itself, the triangle leads to a kimura the
+		// armbar leads to already, and a hip bump counters it.
+		var ids []string
+		for _, name := range []string{"Kimura", "Hip Bump"} {
+			ids = append(ids, decode[model.Technique](t, ts.do("POST", "/api/v1/techniques?disciplineId=bjj", tokEditor, map[string]string{"name": name})).ID)
+		}
+		kimura, hipBump := ids[0], ids[1]
+		edge := func(from, to, typ string) string {
+			t.Helper()
+			rec := ts.do("POST", "/api/v1/technique-edges?disciplineId=bjj", tokEditor, map[string]string{"fromId": from, "toId": to, "type": typ})
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("create edge: got %d (%s)", rec.Code, rec.Body.String())
+			}
+			return decode[model.TechniqueEdge](t, rec).ID
+		}
+		edge(fixTechnique, kimura, model.EdgeLeadsTo)
+		repeated := edge(fixTriangle, kimura, model.EdgeLeadsTo)
+		counter := edge(hipBump, fixTriangle, model.EdgeCounters)
+
+		merge := map[string][]string{"sourceIds": {fixTriangle}}
+		rec = ts.do("POST", "/api/v1/techniques/"+fixTechnique+"/merge?dryRun=true", tokEditor, merge)
+		dry := decode[model.TechniqueMergeReport](t, rec)
+		if rec.Code != http.StatusOK || dry.Applied || !slices.Equal(dry.AliasesAdded, []string{"Triangle"}) ||
+			!slices.Equal(dry.AssetIDs, []string{"asset-triangle"}) || !slices.Equal(dry.EdgesMoved, []string{counter}) ||
+			!slices.Equal(dry.EdgesRemoved, []string{fixEdge, repeated}) ||
+			len(dry.Curricula) != 1 || !slices.Equal(dry.Curricula[0].ElementIDs, []string{element.ID}) {
+			t.Fatalf("dry run: got %d %+v", rec.Code, dry)
+		}
+		if rec := ts.do("GET", "/api/v1/techniques/"+fixTriangle, tokViewer, nil); rec.Code != http.StatusOK {
+			t.Errorf("dry run deleted the source: got %d", rec.Code)
+		}
+
+		rec = ts.do("POST", "/api/v1/techniques/"+fixTechnique+"/merge", tokEditor, merge)
+		report := decode[model.TechniqueMergeReport](t, rec)
+		if rec.Code != http.StatusOK || !report.Applied {
+			t.Fatalf("merge: got %d %+v", rec.Code, report)
+		}
+		armbar := decode[model.Technique](t, ts.do("GET", "/api/v1/techniques/"+fixTechnique, tokViewer, nil))
+		if !slices.Equal(armbar.Aliases, []string{"Triangle"}) || armbar.Description != "Straight arm lock\n\nLeg choke" ||
+			armbar.OwnerUID != tokEditor {
+			t.Errorf("merged technique: got %+v", armbar)
+		}
+		if rec := ts.do("GET", "/api/v1/techniques/"+fixTriangle, tokViewer, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("source after merge: got %d", rec.Code)
+		}
+
+		// References to the triangle now point at the armbar.
+		a := decode[model.Asset](t, ts.do("GET", "/api/v1/assets/asset-triangle", tokViewer, nil))
+		if !slices.Equal(a.TechniqueIDs, []string{fixTechnique}) {
+			t.Errorf("asset techniques: got %q", a.TechniqueIDs)
+		}
+		for _, e := range decode[[]model.CurriculumElement](t, ts.do("GET", curr+"/elements", tokEditor, nil)) {
+			if e.ID == element.ID && (*e.TechniqueID != fixTechnique || e.Snapshot.Name != "Armbar") {
+				t.Errorf("element: got %+v", e)
+			}
+		}
+		revisions := decode[[]model.CurriculumRevision](t, ts.do("GET", curr+"/revisions", tokEditor, nil))
+		if revisions[0].Action != model.RevisionTechniqueMerge {
+			t.Errorf("latest revision: got %s", revisions[0].Action)
+		}
+		var edges []string
+		for _, e := range decode[[]model.TechniqueEdge](t, ts.do("GET", "/api/v1/technique-edges?disciplineId=bjj", tokViewer, nil)) {
+			edges = append(edges, e.FromID+" "+e.Type+" "+e.ToID)
+		}
+		slices.Sort(edges)
+		if want := []string{fixTechnique + " leads-to " + kimura, hipBump + " counters " + fixTechnique}; !slices.Equal(edges, slices.Sorted(slices.Values(want))) {
+			t.Errorf("edges: got %q", edges)
+		}
+
+		// A stored slug that differs from the name's, as enrichment makes
+		// them, keeps resolving too, along with the slugs merged before.
+		if err := ts.store.Techniques().Set(context.Background(), "tech-kimura-guard", &model.Technique{
+			DisciplineID: "bjj", Name: "Kimura (from guard)", Slug: "kimura-(from-guard)",
+			Aliases: []string{}, AliasSlugs: []string{}, CategoryIDs: []string{}, TagIDs: []string{},
+			OwnerUID: "system", CreatedAt: now, UpdatedAt: now,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		rec = ts.do("POST", "/api/v1/techniques/"+fixTechnique+"/merge", tokEditor, map[string][]string{"sourceIds": {"tech-kimura-guard"}})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("second merge: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		got, err := ts.store.Techniques().Get(context.Background(), fixTechnique)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"triangle", "kimura-from-guard", "kimura-(from-guard)"}; !slices.Equal(got.AliasSlugs, want) {
+			t.Errorf("alias slugs: got %q, want %q", got.AliasSlugs, want)
+		}
+	})
+}
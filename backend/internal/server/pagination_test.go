@@ -0,0 +1,159 @@
+package server_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+var nextLink = regexp.MustCompile(`^<([^>]+)>; rel="next"$`)
+
+// pages follows the rel="next" links from path and returns the IDs of every
+// page.
+func pages(t *testing.T, ts *testServer, path, token string) [][]string {
+	t.Helper()
+	var out [][]string
+	for path != "" && len(out) < 50 {
+		rec := ts.do("GET", path, token, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got %d (%s)", path, rec.Code, rec.Body.String())
+		}
+		var ids []string
+		for _, item := range decode[[]struct{ ID string }](t, rec) {
+			ids = append(ids, item.ID)
+		}
+		out = append(out, ids)
+		path = ""
+		if m := nextLink.FindStringSubmatch(rec.Header().Get("Link")); m != nil {
+			path = m[1]
+		}
+	}
+	return out
+}
+
+func TestCursorPagination(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		ctx := context.Background()
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		// Equal names so that only the document ID orders them.
+		for i := range 5 {
+			tag := model.Tag{DisciplineID: "bjj", Name: "Pass", Slug: fmt.Sprintf("pass-%d", i), OwnerUID: "system", CreatedAt: now, UpdatedAt: now}
+			if err := ts.store.Tags().Set(ctx, fmt.Sprintf("tag-pass-%d", i), &tag); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		all := pages(t, ts, "/api/v1/tags?disciplineId=bjj", tokViewer)
+		if len(all) != 1 || len(all[0]) != 7 {
+			t.Fatalf("unpaginated: got %v", all)
+		}
+		got := pages(t, ts, "/api/v1/tags?disciplineId=bjj&limit=3", tokViewer)
+		if len(got) != 3 || !slices.Equal(slices.Concat(got...), all[0]) {
+			t.Errorf("limit=3: got %v, want %v in pages of 3", got, all[0])
+		}
+
+		// A tag inserted before the cursor does not shift the next page.
+		rec := ts.do("GET", "/api/v1/tags?disciplineId=bjj&limit=3", tokViewer, nil)
+		next := nextLink.FindStringSubmatch(rec.Header().Get("Link"))[1]
+		tag := model.Tag{DisciplineID: "bjj", Name: "Armlock", Slug: "armlock", OwnerUID: "system", CreatedAt: now, UpdatedAt: now}
+		if err := ts.store.Tags().Set(ctx, "tag-armlock", &tag); err != nil {
+			t.Fatal(err)
+		}
+		rest := pages(t, ts, next, tokViewer)
+		if !slices.Equal(slices.Concat(rest...), all[0][3:]) {
+			t.Errorf("after insert: got %v, want %v", rest, all[0][3:])
+		}
+
+		// Pages filtered in memory are still full; the inactive asset is
+		// skipped without ending the listing early.
+		got = pages(t, ts, "/api/v1/assets?disciplineId=bjj&includeInactive=true&limit=1", tokAdmin)
+		if ids := slices.Concat(got...); len(got) != 2 || !containsAll(ids, fixAsset, fixInactive) {
+			t.Errorf("admin assets: got %v", got)
+		}
+		if got := pages(t, ts, "/api/v1/assets?disciplineId=bjj&limit=1", tokViewer); len(got) != 1 || !slices.Equal(got[0], []string{fixAsset}) {
+			t.Errorf("viewer assets: got %v", got)
+		}
+
+		// Elements, curricula and categories use the same links.
+		for i := range 3 {
+			rec := ts.do("POST", "/api/v1/curricula/"+fixCurriculum+"/elements", tokEditor, map[string]string{"type": "text", "title": fmt.Sprint("Step ", i)})
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("create element: got %d", rec.Code)
+			}
+		}
+		if got := pages(t, ts, "/api/v1/curricula/"+fixCurriculum+"/elements?limit=2", tokViewer); len(got) != 2 || len(slices.Concat(got...)) != 4 || got[0][0] != fixElement {
+			t.Errorf("elements: got %v", got)
+		}
+		if got := pages(t, ts, "/api/v1/curricula?limit=1", tokJKDEditor); len(slices.Concat(got...)) != 2 {
+			t.Errorf("curricula: got %v", got)
+		}
+		if got := pages(t, ts, "/api/v1/curricula/public?disciplineId=bjj&limit=1", tokViewer); len(got) != 1 || got[0][0] != fixCurriculum {
+			t.Errorf("public curricula: got %v", got)
+		}
+		if got := pages(t, ts, "/api/v1/categories?disciplineId=bjj&limit=1", tokViewer); len(got) != 2 {
+			t.Errorf("categories: got %v", got)
+		}
+
+		// Cursors are opaque and tied to their list's ordering.
+		cursor := strings.SplitN(next, "cursor=", 2)[1]
+		for _, bad := range []string{
+			"/api/v1/tags?disciplineId=bjj&cursor=garbage",
+			"/api/v1/assets?disciplineId=bjj&cursor=" + cursor,
+		} {
+			if rec := ts.do("GET", bad, tokViewer, nil); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", bad, rec.Code)
+			}
+		}
+	})
+}
+
+func TestListUsersPagination(t *testing.T) {
+	ts := newTestServer(t)
+	list := func(path string) (model.UsersListResponse, string) {
+		t.Helper()
+		rec := ts.do("GET", path, tokAdmin, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got %d (%s)", path, rec.Code, rec.Body.String())
+		}
+		return decode[model.UsersListResponse](t, rec), rec.Header().Get("Link")
+	}
+
+	// Pages of 3 over Firebase pages of 2 must neither skip nor repeat.
+	var uids []string
+	path := "/api/v1/admin/users?disciplineId=bjj&pageSize=3"
+	for i := 0; path != "" && i < 10; i++ {
+		resp, link := list(path)
+		for _, u := range resp.Users {
+			uids = append(uids, u.UID)
+		}
+		path = ""
+		if resp.NextPageToken != "" {
+			m := nextLink.FindStringSubmatch(link)
+			if m == nil {
+				t.Fatalf("missing Link header with nextPageToken")
+			}
+			path = m[1]
+		}
+	}
+	if len(uids) != len(principals) || !containsAll(uids, tokViewer, tokNoRole, tokEditor, tokAdmin, tokJKDEditor, tokJKDAdmin) {
+		t.Errorf("users: got %v", uids)
+	}
+
+	// The role filter keeps going through later Firebase pages.
+	resp, _ := list("/api/v1/admin/users?disciplineId=bjj&role=viewer&pageSize=1")
+	if len(resp.Users) != 1 || resp.Users[0].UID != tokViewer {
+		t.Errorf("role=viewer: got %+v", resp.Users)
+	}
+
+	if rec := ts.do("GET", "/api/v1/admin/users?disciplineId=bjj&pageToken=%25%25", tokAdmin, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad page token: got %d, want 400", rec.Code)
+	}
+}
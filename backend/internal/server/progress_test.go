@@ -0,0 +1,84 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+func TestProgress(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		curr := "/api/v1/curricula/" + fixCurriculum
+		set := func(token, elemID string, body map[string]string) *model.Progress {
+			t.Helper()
+			rec := ts.do("PUT", curr+"/progress/"+elemID, token, body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("set progress as %s: got %d (%s)", token, rec.Code, rec.Body.String())
+			}
+			p := decode[model.Progress](t, rec)
+			return &p
+		}
+
+		// A section groups elements and takes no progress of its own.
+		rec := ts.do("POST", curr+"/elements", tokEditor, map[string]string{"type": "section", "title": "Part 2"})
+		section := decode[model.CurriculumElement](t, rec)
+		rec = ts.do("POST", curr+"/elements", tokEditor, map[string]string{"type": "text", "title": "Drill", "parentId": section.ID})
+		drill := decode[model.CurriculumElement](t, rec)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create element: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		if rec := ts.do("PUT", curr+"/progress/"+section.ID, tokViewer, map[string]string{"state": "watched"}); rec.Code != http.StatusBadRequest {
+			t.Errorf("section progress: got %d", rec.Code)
+		}
+
+		p := set(tokViewer, fixElement, map[string]string{"state": "watched", "notes": "<b>Keep</b> the elbows in"})
+		if p.State != model.ProgressWatched || p.Notes != "Keep the elbows in" || p.StartedAt.IsZero() || !p.CompletedAt.IsZero() {
+			t.Fatalf("watched: got %+v", p)
+		}
+		started := p.StartedAt
+		p = set(tokViewer, fixElement, map[string]string{"state": "mastered"})
+		if p.Notes != "Keep the elbows in" || !p.StartedAt.Equal(started) || p.CompletedAt.IsZero() {
+			t.Errorf("mastered: got %+v", p)
+		}
+
+		got := decode[model.CurriculumProgress](t, ts.do("GET", curr+"/progress", tokViewer, nil))
+		if got.Total != 2 || got.Counts.Mastered != 1 || got.Counts.NotStarted != 1 || got.PercentComplete != 50 || len(got.Elements) != 1 {
+			t.Errorf("curriculum progress: got %+v", got)
+		}
+		list := decode[[]model.CurriculumProgress](t, ts.do("GET", "/api/v1/progress?disciplineId=bjj", tokViewer, nil))
+		if len(list) != 1 || list[0].PercentComplete != 50 || list[0].Elements != nil {
+			t.Errorf("my progress: got %+v", list)
+		}
+
+		// Another learner completes the curriculum; editors see the totals.
+		set(tokNoRole, fixElement, map[string]string{"state": "mastered"})
+		set(tokNoRole, drill.ID, map[string]string{"state": "mastered"})
+		rec = ts.do("GET", "/api/v1/progress/report?disciplineId=bjj", tokEditor, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("report: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		reports := decode[[]model.ProgressReport](t, rec)
+		want := model.ProgressReport{
+			CurriculumID: fixCurriculum, Title: "White Belt", Total: 2, Learners: 2, Completed: 1, AveragePercent: 75,
+			Counts: model.ProgressCounts{NotStarted: 1, Mastered: 3},
+		}
+		if len(reports) != 1 || reports[0] != want {
+			t.Errorf("report: got %+v, want %+v", reports, want)
+		}
+
+		// Progress on a hidden curriculum cannot be recorded, and progress
+		// goes with its curriculum.
+		if rec := ts.do("PUT", "/api/v1/curricula/"+fixJKDCurricul+"/progress/x", tokViewer, map[string]string{"state": "watched"}); rec.Code != http.StatusNotFound {
+			t.Errorf("private curriculum: got %d", rec.Code)
+		}
+		if rec := ts.do("DELETE", curr, tokEditor, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("delete curriculum: got %d", rec.Code)
+		}
+		if left, err := ts.store.Progress().List(context.Background(), store.NewQuery()); err != nil || len(left) != 0 {
+			t.Errorf("progress after delete: got %d (%v)", len(left), err)
+		}
+	})
+}
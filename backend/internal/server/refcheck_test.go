@@ -0,0 +1,107 @@
+package server_test
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+func TestReferenceValidation(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		curr := "/api/v1/curricula/" + fixCurriculum
+		for _, tt := range []struct {
+			name         string
+			token        string
+			method, path string
+			body         interface{}
+			want         []model.InvalidReference
+		}{
+			{"asset create", tokEditor, "POST", "/api/v1/assets?disciplineId=bjj",
+				map[string]interface{}{"url": "https://example.com/x", "title": "X", "type": "web",
+					"techniqueIds": []string{fixTechnique}, "categoryIds": []string{fixCategory}, "tagIds": []string{fixJKDTag, "missing"}},
+				[]model.InvalidReference{{Field: "tagIds", Missing: []string{"missing"}, OtherDiscipline: []string{fixJKDTag}}}},
+			{"asset update", tokEditor, "PATCH", "/api/v1/assets/" + fixAsset,
+				map[string][]string{"techniqueIds": {fixTechnique, "tech-missing"}, "categoryIds": {"bad/id"}},
+				[]model.InvalidReference{
+					{Field: "techniqueIds", Missing: []string{"tech-missing"}},
+					{Field: "categoryIds", Missing: []string{"bad/id"}},
+				}},
+			{"technique create", tokEditor, "POST", "/api/v1/techniques?disciplineId=bjj",
+				map[string]interface{}{"name": "Kimura", "tagIds": []string{fixTag, fixJKDTag}},
+				[]model.InvalidReference{{Field: "tagIds", OtherDiscipline: []string{fixJKDTag}}}},
+			{"technique update", tokEditor, "PATCH", "/api/v1/techniques/" + fixTechnique,
+				map[string][]string{"categoryIds": {"cat-missing"}},
+				[]model.InvalidReference{{Field: "categoryIds", Missing: []string{"cat-missing"}}}},
+			{"category create", tokJKDEditor, "POST", "/api/v1/categories?disciplineId=jkd",
+				map[string]string{"name": "Trapping", "parentId": fixCategory},
+				[]model.InvalidReference{{Field: "parentId", OtherDiscipline: []string{fixCategory}}}},
+			{"category update", tokEditor, "PATCH", "/api/v1/categories/" + fixChildCat,
+				map[string]string{"parentId": "cat-missing"},
+				[]model.InvalidReference{{Field: "parentId", Missing: []string{"cat-missing"}}}},
+			{"curriculum create", tokEditor, "POST", "/api/v1/curricula?disciplineId=bjj",
+				map[string]interface{}{"title": "Blue Belt", "tagIds": []string{fixJKDTag}},
+				[]model.InvalidReference{{Field: "tagIds", OtherDiscipline: []string{fixJKDTag}}}},
+			{"curriculum update", tokEditor, "PATCH", curr,
+				map[string][]string{"tagIds": {""}},
+				[]model.InvalidReference{{Field: "tagIds", Missing: []string{""}}}},
+			{"technique element", tokEditor, "POST", curr + "/elements",
+				map[string]string{"type": "technique", "techniqueId": "tech-missing"},
+				[]model.InvalidReference{{Field: "techniqueId", Missing: []string{"tech-missing"}}}},
+			{"asset element", tokEditor, "POST", curr + "/elements",
+				map[string]string{"type": "asset", "assetId": fixJKDAsset},
+				[]model.InvalidReference{{Field: "assetId", OtherDiscipline: []string{fixJKDAsset}}}},
+			{"clip element", tokEditor, "POST", curr + "/elements",
+				map[string]interface{}{"type": "clip", "assetId": "missing", "startSeconds": 0, "endSeconds": 60},
+				[]model.InvalidReference{{Field: "assetId", Missing: []string{"missing"}}}},
+			{"edge create", tokEditor, "POST", "/api/v1/technique-edges?disciplineId=bjj",
+				map[string]interface{}{"fromId": fixTechnique, "toId": "tech-missing", "type": "counters", "assetIds": []string{fixJKDAsset}},
+				[]model.InvalidReference{
+					{Field: "toId", Missing: []string{"tech-missing"}},
+					{Field: "assetIds", OtherDiscipline: []string{fixJKDAsset}},
+				}},
+			{"edge of another discipline", tokJKDEditor, "POST", "/api/v1/technique-edges?disciplineId=jkd",
+				map[string]string{"fromId": fixTechnique, "toId": fixTriangle, "type": "counters"},
+				[]model.InvalidReference{
+					{Field: "fromId", OtherDiscipline: []string{fixTechnique}},
+					{Field: "toId", OtherDiscipline: []string{fixTriangle}},
+				}},
+			{"edge update", tokEditor, "PATCH", "/api/v1/technique-edges/" + fixEdge,
+				map[string][]string{"assetIds": {fixAsset, "asset-missing"}},
+				[]model.InvalidReference{{Field: "assetIds", Missing: []string{"asset-missing"}}}},
+			{"session of another discipline", tokJKDEditor, "POST", "/api/v1/sessions?disciplineId=jkd",
+				map[string]string{"curriculumId": fixCurriculum, "startsAt": "2026-10-19T18:30"},
+				[]model.InvalidReference{{Field: "curriculumId", OtherDiscipline: []string{fixCurriculum}}}},
+			// A curriculum the user may not view is missing to them.
+			{"session of a private curriculum", tokJKDAdmin, "POST", "/api/v1/sessions?disciplineId=jkd",
+				map[string]string{"curriculumId": fixJKDCurricul, "startsAt": "2026-10-19T18:30"},
+				[]model.InvalidReference{{Field: "curriculumId", Missing: []string{fixJKDCurricul}}}},
+		} {
+			rec := ts.do(tt.method, tt.path, tt.token, tt.body)
+			got := decode[model.ReferenceError](t, rec)
+			if rec.Code != http.StatusBadRequest || !slices.EqualFunc(got.Fields, tt.want, func(a, b model.InvalidReference) bool {
+				return a.Field == b.Field && slices.Equal(a.Missing, b.Missing) && slices.Equal(a.OtherDiscipline, b.OtherDiscipline)
+			}) {
+				t.Errorf("%s: got %d %+v, want %+v", tt.name, rec.Code, got.Fields, tt.want)
+			}
+		}
+
+		// Nothing was written by the rejected requests.
+		if a := decode[model.Asset](t, ts.do("GET", "/api/v1/assets/"+fixAsset, tokViewer, nil)); !slices.Equal(a.TechniqueIDs, []string{fixTechnique}) {
+			t.Errorf("asset techniques: got %q", a.TechniqueIDs)
+		}
+		if n := len(decode[[]model.CurriculumElement](t, ts.do("GET", curr+"/elements", tokEditor, nil))); n != 1 {
+			t.Errorf("elements: got %d, want 1", n)
+		}
+
+		// References of the discipline are accepted, and snapshotted.
+		rec := ts.do("POST", curr+"/elements", tokEditor, map[string]string{"type": "technique", "techniqueId": fixTriangle})
+		if e := decode[model.CurriculumElement](t, rec); rec.Code != http.StatusCreated || e.Snapshot == nil || e.Snapshot.Name != "Triangle" {
+			t.Errorf("technique element: got %d %+v", rec.Code, e)
+		}
+		if rec := ts.do("PATCH", "/api/v1/techniques/"+fixTechnique, tokEditor, map[string][]string{"tagIds": {fixTag}, "categoryIds": {fixCategory}}); rec.Code != http.StatusOK {
+			t.Errorf("technique update: got %d (%s)", rec.Code, rec.Body.String())
+		}
+	})
+}
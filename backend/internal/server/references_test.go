@@ -0,0 +1,148 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+func TestDeletePolicies(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		curr := "/api/v1/curricula/" + fixCurriculum
+		add := func(body map[string]string) string {
+			t.Helper()
+			rec := ts.do("POST", curr+"/elements", tokEditor, body)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("create element: got %d (%s)", rec.Code, rec.Body.String())
+			}
+			return decode[model.CurriculumElement](t, rec).ID
+		}
+		tech := add(map[string]string{"type": "technique", "techniqueId": fixTechnique})
+		asset := add(map[string]string{"type": "asset", "assetId": fixAsset})
+		elements := func() map[string]model.CurriculumElement {
+			t.Helper()
+			got := map[string]model.CurriculumElement{}
+			for _, e := range decode[[]model.CurriculumElement](t, ts.do("GET", curr+"/elements", tokEditor, nil)) {
+				got[e.ID] = e
+			}
+			return got
+		}
+		latestAction := func() model.RevisionAction {
+			t.Helper()
+			return decode[[]model.CurriculumRevision](t, ts.do("GET", curr+"/revisions", tokEditor, nil))[0].Action
+		}
+
+		// The guard tag is on an asset, the curriculum itself and, through
+		// the asset's snapshot, one of its elements.
+		u := decode[model.Usages](t, ts.do("GET", "/api/v1/tags/"+fixTag+"/usages", tokEditor, nil))
+		if !slices.Equal(u.AssetIDs, []string{fixAsset}) || len(u.Curricula) != 1 || !u.Curricula[0].Tagged ||
+			!slices.Equal(u.Curricula[0].ElementIDs, []string{asset}) || u.Total != 3 {
+			t.Fatalf("tag usages: got %+v", u)
+		}
+		rec := ts.do("DELETE", "/api/v1/tags/"+fixTag+"?policy=restrict", tokEditor, nil)
+		if conflict := decode[model.UsageConflict](t, rec); rec.Code != http.StatusConflict || conflict.Usages.Total != 3 {
+			t.Errorf("restricted delete: got %d %+v", rec.Code, conflict)
+		}
+		if rec := ts.do("DELETE", "/api/v1/tags/"+fixTag+"?policy=reassign&replacementId="+fixTag2, tokEditor, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("reassign tag: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		c := decode[model.Curriculum](t, ts.do("GET", curr, tokEditor, nil))
+		if !slices.Equal(c.TagIDs, []string{fixTag2}) || slices.Contains(c.AllTagIDs, fixTag) || latestAction() != model.RevisionReassign {
+			t.Errorf("curriculum after reassign: tags %q, all %q", c.TagIDs, c.AllTagIDs)
+		}
+		if a := decode[model.Asset](t, ts.do("GET", "/api/v1/assets/"+fixAsset, tokViewer, nil)); !slices.Equal(a.TagIDs, []string{fixTag2}) {
+			t.Errorf("asset tags after reassign: got %q", a.TagIDs)
+		}
+		if e := elements()[asset]; !slices.Equal(e.Snapshot.TagIDs, []string{fixTag2}) {
+			t.Errorf("snapshot tags after reassign: got %q", e.Snapshot.TagIDs)
+		}
+
+		// Reassigning the armbar moves its element to the triangle and drops
+		// the edge that would join the triangle to itself.
+		if rec := ts.do("DELETE", "/api/v1/techniques/"+fixTechnique+"?policy=reassign&replacementId="+fixTriangle, tokEditor, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("reassign technique: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		if e := elements()[tech]; e.TechniqueID == nil || *e.TechniqueID != fixTriangle || e.Snapshot.Name != "Triangle" {
+			t.Errorf("technique element after reassign: got %+v", e)
+		}
+		if rec := ts.do("GET", "/api/v1/technique-edges/"+fixEdge, tokViewer, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("self-joining edge: got %d", rec.Code)
+		}
+
+		// Detaching the asset, by default, keeps the element's snapshot but
+		// not its links.
+		kimura := decode[model.Technique](t, ts.do("POST", "/api/v1/techniques?disciplineId=bjj", tokEditor, map[string]string{"name": "Kimura"}))
+		rec = ts.do("POST", "/api/v1/technique-edges?disciplineId=bjj", tokEditor,
+			map[string]interface{}{"fromId": fixTriangle, "toId": kimura.ID, "type": "leads-to", "assetIds": []string{fixAsset}})
+		edge := decode[model.TechniqueEdge](t, rec)
+		if rec := ts.do("DELETE", "/api/v1/assets/"+fixAsset, tokEditor, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("detach asset: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		if e := elements()[asset]; e.AssetID != nil || e.Snapshot == nil || e.Snapshot.Name != "Armbar Basics" || e.Snapshot.URL != "" || latestAction() != model.RevisionDetach {
+			t.Errorf("asset element after detach: got %+v", e)
+		}
+		if e := decode[model.TechniqueEdge](t, ts.do("GET", "/api/v1/technique-edges/"+edge.ID, tokViewer, nil)); len(e.AssetIDs) != 0 {
+			t.Errorf("edge assets after detach: got %q", e.AssetIDs)
+		}
+
+		// Subcategories move up to the parent of a detached category.
+		if rec := ts.do("DELETE", "/api/v1/categories/"+fixCategory+"?policy=restrict", tokEditor, nil); rec.Code != http.StatusConflict {
+			t.Errorf("restricted category delete: got %d", rec.Code)
+		}
+		if rec := ts.do("DELETE", "/api/v1/categories/"+fixCategory, tokEditor, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("detach category: got %d", rec.Code)
+		}
+		if child := decode[model.Category](t, ts.do("GET", "/api/v1/categories/"+fixChildCat, tokViewer, nil)); child.ParentID != nil {
+			t.Errorf("subcategory parent: got %q", *child.ParentID)
+		}
+	})
+}
+
+func TestDeleteClipAsset(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		curr := "/api/v1/curricula/" + fixCurriculum
+		for id, length := range map[string]string{"asset-long": "PT1H2M", "asset-short": "PT5M"} {
+			if err := ts.store.Assets().Set(context.Background(), id, &model.Asset{
+				DisciplineID: "bjj", URL: "https://www.youtube.com/watch?v=" + id, Title: "Armbar Instructional",
+				Type: model.AssetTypeVideo, Duration: &length, Active: true, ProcessingStatus: "completed", OwnerUID: "system",
+			}); err != nil {
+				t.Fatalf("seed asset: %v", err)
+			}
+		}
+		rec := ts.do("POST", curr+"/elements", tokEditor, map[string]interface{}{
+			"type": "clip", "assetId": "asset-long", "startSeconds": 750, "endSeconds": 910, "caption": "Watch the hips",
+		})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create clip: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		clip := decode[model.CurriculumElement](t, rec)
+
+		// The clip ends past the end of the five minute video.
+		rec = ts.do("DELETE", "/api/v1/assets/asset-long?policy=reassign&replacementId=asset-short", tokEditor, nil)
+		conflict := decode[model.UsageConflict](t, rec)
+		if rec.Code != http.StatusConflict || conflict.Usages.Total != 1 || len(conflict.Usages.Curricula) != 1 ||
+			!slices.Equal(conflict.Usages.Curricula[0].ElementIDs, []string{clip.ID}) {
+			t.Fatalf("reassign to a shorter video: got %d %+v", rec.Code, conflict)
+		}
+		if rec := ts.do("GET", "/api/v1/assets/asset-long", tokViewer, nil); rec.Code != http.StatusOK {
+			t.Errorf("refused reassign deleted the asset: got %d", rec.Code)
+		}
+
+		// Detached, the clip becomes a text element.
+		if rec := ts.do("DELETE", "/api/v1/assets/asset-long", tokEditor, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("detach asset: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		for _, e := range decode[[]model.CurriculumElement](t, ts.do("GET", curr+"/elements", tokEditor, nil)) {
+			if e.ID != clip.ID {
+				continue
+			}
+			if e.Type != model.ElementTypeText || e.AssetID != nil || e.Snapshot != nil || e.StartSeconds != nil ||
+				e.Title == nil || *e.Title != "Armbar Instructional" || e.Details == nil || *e.Details != "Watch the hips" {
+				t.Errorf("detached clip: got %+v", e)
+			}
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+func TestCurriculumRevisions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		rec := ts.do("POST", "/api/v1/curricula?disciplineId=bjj", tokEditor, map[string]string{"title": "Blue Belt"})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create curriculum: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		curr := "/api/v1/curricula/" + decode[model.Curriculum](t, rec).ID
+
+		ids := map[string]string{}
+		for _, title := range []string{"A", "B", "C"} {
+			rec := ts.do("POST", curr+"/elements", tokEditor, map[string]string{"type": "text", "title": title})
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("create element: got %d", rec.Code)
+			}
+			ids[title] = decode[model.CurriculumElement](t, rec).ID
+		}
+		for _, step := range []struct {
+			method, path string
+			body         interface{}
+		}{
+			{"PUT", curr + "/elements/" + ids["B"], map[string]string{"title": "B2"}},
+			{"PUT", curr + "/elements/reorder", map[string][]string{"orderedIds": {ids["C"], ids["A"], ids["B"]}}},
+			{"DELETE", curr + "/elements/" + ids["A"], nil},
+		} {
+			if rec := ts.do(step.method, step.path, tokEditor, step.body); rec.Code >= 300 {
+				t.Fatalf("%s %s: got %d", step.method, step.path, rec.Code)
+			}
+		}
+
+		var actions []string
+		for _, rev := range decode[[]model.CurriculumRevision](t, ts.do("GET", curr+"/revisions", tokEditor, nil)) {
+			actions = append(actions, string(rev.Action))
+		}
+		if got := strings.Join(actions, ","); got != "element.delete,element.reorder,element.update,"+
+			"element.create,element.create,element.create,create" {
+			t.Errorf("revisions: got %s", got)
+		}
+
+		rec = ts.do("GET", curr+"/revisions/diff?from=4&to=7", tokEditor, nil)
+		diff := decode[curriculum.RevisionDiff](t, rec)
+		if len(diff.Removed) != 1 || diff.Removed[0].ID != ids["A"] || len(diff.Added) != 0 || len(diff.Fields) != 0 ||
+			len(diff.Moved) != 1 || len(diff.Changed) != 1 || diff.Changed[0].ID != ids["B"] ||
+			strings.Join(diff.Changed[0].Fields, ",") != "title" {
+			t.Errorf("diff 4..7: got %s", rec.Body.String())
+		}
+		rec = ts.do("GET", curr+"/revisions/diff", tokEditor, nil)
+		if diff := decode[curriculum.RevisionDiff](t, rec); diff.From != 6 || diff.To != 7 || len(diff.Removed) != 1 || len(diff.Moved) != 0 {
+			t.Errorf("default diff: got %s", rec.Body.String())
+		}
+
+		// Restoring revision 4 brings back A, the old order and B's title.
+		rec = ts.do("POST", curr+"/revisions/4/restore", tokEditor, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("restore: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		if c := decode[model.Curriculum](t, rec); c.Revision != 8 || c.ElementCount != 3 {
+			t.Errorf("restored curriculum: got revision %d with %d elements", c.Revision, c.ElementCount)
+		}
+		var titles []string
+		for _, e := range decode[[]model.CurriculumElement](t, ts.do("GET", curr+"/elements", tokEditor, nil)) {
+			titles = append(titles, *e.Title)
+		}
+		if got := strings.Join(titles, ","); got != "A,B,C" {
+			t.Errorf("restored elements: got %s", got)
+		}
+		rev := decode[curriculum.RevisionDetail](t, ts.do("GET", curr+"/revisions/8", tokEditor, nil))
+		if rev.Action != model.RevisionRestore || rev.RestoredFrom != 4 || len(rev.Content.Elements) != 3 {
+			t.Errorf("restore revision: got %+v", rev)
+		}
+		rec = ts.do("GET", curr+"/revisions/diff?from=4&to=8", tokEditor, nil)
+		if diff := decode[curriculum.RevisionDiff](t, rec); len(diff.Fields)+len(diff.Added)+len(diff.Removed)+len(diff.Moved)+len(diff.Changed) != 0 {
+			t.Errorf("diff 4..8: got %s", rec.Body.String())
+		}
+
+		// Revisions go with their curriculum.
+		if rec := ts.do("DELETE", curr, tokEditor, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("delete curriculum: got %d", rec.Code)
+		}
+		id := strings.TrimPrefix(curr, "/api/v1/curricula/")
+		if left, err := ts.store.Revisions().List(context.Background(), id, store.NewQuery()); err != nil || len(left) != 0 {
+			t.Errorf("revisions after delete: got %d, %v", len(left), err)
+		}
+	})
+}
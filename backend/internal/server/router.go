@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/thomas/skillhive-api/internal/enrich"
+	"github.com/thomas/skillhive-api/internal/export"
+	"github.com/thomas/skillhive-api/internal/handler"
+	"github.com/thomas/skillhive-api/internal/middleware"
+	"github.com/thomas/skillhive-api/internal/search"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+// Share links and calendar feeds are read without authentication, so each
+// client is limited to shareRate requests per second on average, in bursts
+// of shareBurst.
+const (
+	shareRate  = 2
+	shareBurst = 30
+)
+
+// Deps holds everything the router needs. Pipeline may be nil, in which case
+// asset enrichment is disabled. Search should be the index kept current by
+// search.Wrap around Store; when nil an empty index is used. FetchImage
+// downloads the images embedded in PDF exports; nil uses export.FetchImage.
+// DeletePolicies are the default delete policies of tags, categories,
+// techniques and assets.
+type Deps struct {
+	Store              store.Store
+	Search             *search.Index
+	Verifier           middleware.TokenVerifier
+	Users              handler.UserAdmin
+	Pipeline           *enrich.Pipeline
+	EnrichCtx          context.Context
+	FetchImage         export.ImageFetcher
+	CORSAllowedOrigins string
+	DeletePolicies     handler.DeletePolicies
+}
+
+// NewRouter builds the HTTP handler with all middleware and routes.
+func NewRouter(d Deps) http.Handler {
+	if d.EnrichCtx == nil {
+		d.EnrichCtx = context.Background()
+	}
+	if d.Search == nil {
+		d.Search = search.NewIndex()
+	}
+
+	r := chi.NewRouter()
+
+	// Global middleware stack
+	r.Use(chimiddleware.RequestSize(1 << 20)) // 1MB
+	r.Use(chimiddleware.Timeout(30 * time.Second))
+	r.Use(middleware.CORSHandler(d.CORSAllowedOrigins).Handler)
+	r.Use(securityHeaders)
+	r.Use(middleware.Logging)
+
+	// Public routes
+	r.Get("/health", handler.HealthCheck)
+
+	// Handlers
+	disciplineHandler := handler.NewDisciplineHandler(d.Store)
+	tagHandler := handler.NewTagHandler(d.Store, d.DeletePolicies.Tags)
+	categoryHandler := handler.NewCategoryHandler(d.Store, d.DeletePolicies.Categories)
+	techniqueHandler := handler.NewTechniqueHandler(d.Store, d.DeletePolicies.Techniques)
+	techniqueEdgeHandler := handler.NewTechniqueEdgeHandler(d.Store)
+	graphHandler := handler.NewGraphHandler(d.Store)
+	assetHandler := handler.NewAssetHandler(d.Store, d.Pipeline, d.EnrichCtx, d.DeletePolicies.Assets)
+	oembedHandler := handler.NewOEmbedHandler()
+	curriculumHandler := handler.NewCurriculumHandler(d.Store)
+	collaboratorHandler := handler.NewCollaboratorHandler(d.Store, d.Users)
+	shareHandler := handler.NewShareHandler(d.Store)
+	elementHandler := handler.NewElementHandler(d.Store)
+	revisionHandler := handler.NewRevisionHandler(d.Store)
+	exportHandler := handler.NewExportHandler(d.Store, d.FetchImage)
+	adminHandler := handler.NewAdminHandler(d.Users, d.Store, d.Pipeline, d.EnrichCtx)
+	searchHandler := handler.NewSearchHandler(d.Search)
+	sessionHandler := handler.NewSessionHandler(d.Store)
+	calendarHandler := handler.NewCalendarHandler(d.Store)
+	progressHandler := handler.NewProgressHandler(d.Store)
+
+	// Public share links: read-only, unauthenticated and rate-limited
+	r.Route("/share", func(r chi.Router) {
+		r.Use(middleware.RateLimit(shareRate, shareBurst))
+		r.Get("/{token}", shareHandler.Get)
+	})
+
+	// Public calendar feeds: read-only, unauthenticated and rate-limited
+	r.Route("/calendar", func(r chi.Router) {
+		r.Use(middleware.RateLimit(shareRate, shareBurst))
+		r.Get("/{token}.ics", calendarHandler.Feed)
+	})
+
+	// Protected API routes
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(middleware.FirebaseAuth(d.Verifier))
+
+		// Admin routes FIRST (to prevent /assets/{id} from matching /admin/assets/...)
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middleware.RequireAnyAdmin)
+
+			// User management
+			r.Get("/users", adminHandler.ListUsers)
+			r.Get("/users/search", adminHandler.SearchUsers)
+			r.Get("/users/{uid}", adminHandler.GetUser)
+			r.Put("/users/{uid}/role", adminHandler.SetRole)
+			r.Delete("/users/{uid}/role", adminHandler.RevokeRole)
+
+			// Asset processing management
+			r.Get("/assets", adminHandler.ListAssets)
+			r.Patch("/assets/{id}/active", adminHandler.ToggleAssetActive)
+			r.Post("/assets/{id}/enrich", adminHandler.RetryEnrichment)
+			r.Patch("/assets/{id}/status", adminHandler.UpdateAssetStatus)
+
+			// Curriculum denormalization
+			r.Get("/curricula/denorm", adminHandler.CurriculumDenormReport)
+			r.Post("/curricula/denorm/repair", adminHandler.RepairCurriculumDenorm)
+		})
+
+		// Full-text search across techniques, assets and curricula
+		r.Get("/search", searchHandler.Search)
+
+		// Disciplines (read-only)
+		r.Get("/disciplines", disciplineHandler.List)
+
+		// Tags
+		r.Get("/tags", tagHandler.List)
+		r.Post("/tags", tagHandler.Create)
+		r.Get("/tags/{id}", tagHandler.Get)
+		r.Patch("/tags/{id}", tagHandler.Update)
+		r.Delete("/tags/{id}", tagHandler.Delete)
+		r.Get("/tags/{id}/usages", tagHandler.Usages)
+
+		// Categories
+		r.Get("/categories", categoryHandler.List)
+		r.Post("/categories", categoryHandler.Create)
+		r.Get("/categories/{id}", categoryHandler.Get)
+		r.Patch("/categories/{id}", categoryHandler.Update)
+		r.Delete("/categories/{id}", categoryHandler.Delete)
+		r.Get("/categories/{id}/usages", categoryHandler.Usages)
+
+		// Techniques
+		r.Get("/techniques", techniqueHandler.List)
+		r.Post("/techniques", techniqueHandler.Create)
+		r.Get("/techniques/{id}", techniqueHandler.Get)
+		r.Patch("/techniques/{id}", techniqueHandler.Update)
+		r.Delete("/techniques/{id}", techniqueHandler.Delete)
+		r.Get("/techniques/{id}/usages", techniqueHandler.Usages)
+		r.Post("/techniques/{id}/merge", techniqueHandler.Merge)
+		r.Get("/techniques/{id}/neighbors", graphHandler.Neighbors)
+
+		// Technique graph
+		r.Get("/technique-edges", techniqueEdgeHandler.List)
+		r.Post("/technique-edges", techniqueEdgeHandler.Create)
+		r.Get("/technique-edges/{id}", techniqueEdgeHandler.Get)
+		r.Patch("/technique-edges/{id}", techniqueEdgeHandler.Update)
+		r.Delete("/technique-edges/{id}", techniqueEdgeHandler.Delete)
+		r.Get("/graph/path", graphHandler.Path)
+		r.Get("/graph/chains", graphHandler.Chains)
+		r.Get("/graph/export", graphHandler.Export)
+
+		// Assets
+		r.Get("/assets", assetHandler.List)
+		r.Post("/assets", assetHandler.Create)
+		r.Get("/assets/{id}", assetHandler.Get)
+		r.Patch("/assets/{id}", assetHandler.Update)
+		r.Delete("/assets/{id}", assetHandler.Delete)
+		r.Get("/assets/{id}/usages", assetHandler.Usages)
+
+		// YouTube oEmbed
+		r.Post("/youtube/resolve", oembedHandler.ResolveYouTube)
+
+		// Curricula
+		r.Get("/curricula", curriculumHandler.List)
+		r.Get("/curricula/public", curriculumHandler.ListPublic)
+		r.Post("/curricula", curriculumHandler.Create)
+		r.Post("/curricula/import", curriculumHandler.Import)
+		r.Get("/curricula/{id}", curriculumHandler.Get)
+		r.Patch("/curricula/{id}", curriculumHandler.Update)
+		r.Delete("/curricula/{id}", curriculumHandler.Delete)
+		r.Post("/curricula/{id}/fork", curriculumHandler.Fork)
+		r.Post("/curricula/{id}/pull", curriculumHandler.Pull)
+		r.Get("/curricula/{id}/export", exportHandler.Export)
+
+		// Curriculum elements
+		r.Get("/curricula/{id}/elements", elementHandler.ListElements)
+		r.Post("/curricula/{id}/elements", elementHandler.CreateElement)
+		r.Put("/curricula/{id}/elements/{elemId}", elementHandler.UpdateElement)
+		r.Delete("/curricula/{id}/elements/{elemId}", elementHandler.DeleteElement)
+		r.Put("/curricula/{id}/elements/reorder", elementHandler.ReorderElements)
+		r.Post("/curricula/{id}/elements/refresh", elementHandler.RefreshElements)
+
+		// Curriculum collaborators
+		r.Get("/curricula/{id}/collaborators", collaboratorHandler.List)
+		r.Post("/curricula/{id}/collaborators", collaboratorHandler.Invite)
+		r.Delete("/curricula/{id}/collaborators/{uid}", collaboratorHandler.Remove)
+
+		// Curriculum share link
+		r.Get("/curricula/{id}/share", shareHandler.GetLink)
+		r.Put("/curricula/{id}/share", shareHandler.PutLink)
+		r.Delete("/curricula/{id}/share", shareHandler.Revoke)
+
+		// Curriculum revisions
+		r.Get("/curricula/{id}/revisions", revisionHandler.List)
+		r.Get("/curricula/{id}/revisions/diff", revisionHandler.Diff)
+		r.Get("/curricula/{id}/revisions/{rev}", revisionHandler.Get)
+		r.Post("/curricula/{id}/revisions/{rev}/restore", revisionHandler.Restore)
+
+		// Learner progress
+		r.Get("/curricula/{id}/progress", progressHandler.Get)
+		r.Put("/curricula/{id}/progress/{elemId}", progressHandler.Update)
+		r.Get("/progress", progressHandler.List)
+		r.Get("/progress/report", progressHandler.Report)
+
+		// Scheduled sessions
+		r.Get("/sessions", sessionHandler.List)
+		r.Post("/sessions", sessionHandler.Create)
+		r.Get("/sessions/{id}", sessionHandler.Get)
+		r.Patch("/sessions/{id}", sessionHandler.Update)
+		r.Delete("/sessions/{id}", sessionHandler.Delete)
+		r.Get("/schedule", sessionHandler.Schedule)
+
+		// Calendar feeds
+		r.Get("/calendar/feeds", calendarHandler.ListFeeds)
+		r.Post("/calendar/feeds", calendarHandler.CreateFeed)
+		r.Delete("/calendar/feeds/{id}", calendarHandler.DeleteFeed)
+	})
+
+	return r
+}
+
+func securityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("X-XSS-Protection", "1; mode=block")
+		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		next.ServeHTTP(w, r)
+	})
+}
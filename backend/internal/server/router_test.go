@@ -0,0 +1,714 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+var allPrincipals = []string{tokViewer, tokNoRole, tokEditor, tokAdmin, tokJKDEditor, tokJKDAdmin}
+
+// everyone expects the same status for every principal.
+func everyone(status int) map[string]int {
+	m := map[string]int{}
+	for _, p := range allPrincipals {
+		m[p] = status
+	}
+	return m
+}
+
+// bjjEditors expects status for bjj editors and admins, 403 for everyone else.
+func bjjEditors(status int) map[string]int {
+	m := everyone(http.StatusForbidden)
+	m[tokEditor] = status
+	m[tokAdmin] = status
+	return m
+}
+
+// jkdEditors expects status for jkd editors and admins, 403 for everyone else.
+func jkdEditors(status int) map[string]int {
+	m := everyone(http.StatusForbidden)
+	m[tokJKDEditor] = status
+	m[tokJKDAdmin] = status
+	return m
+}
+
+// bjjAdmin expects status for the bjj admin only.
+func bjjAdmin(status int) map[string]int {
+	m := everyone(http.StatusForbidden)
+	m[tokAdmin] = status
+	return m
+}
+
+// only expects status for principal p, and others for everyone else.
+func only(p string, status, others int) map[string]int {
+	m := everyone(others)
+	m[p] = status
+	return m
+}
+
+// anyAdmin expects status for an admin of any discipline.
+func anyAdmin(status int) map[string]int {
+	m := everyone(http.StatusForbidden)
+	m[tokAdmin] = status
+	m[tokJKDAdmin] = status
+	return m
+}
+
+func TestAuthentication(t *testing.T) {
+	ts := newTestServer(t)
+
+	if rec := ts.do("GET", "/health", "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("health without auth: got %d", rec.Code)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing header", "", http.StatusUnauthorized},
+		{"wrong scheme", "Basic abc", http.StatusUnauthorized},
+		{"unknown token", "Bearer nope", http.StatusUnauthorized},
+		{"valid token", "Bearer " + tokViewer, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/disciplines", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			ts.h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("got %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRoleMatrix(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		want   map[string]int
+	}{
+		// Reads are open to every authenticated user.
+		{"list disciplines", "GET", "/api/v1/disciplines", nil, everyone(200)},
+		{"list tags", "GET", "/api/v1/tags?disciplineId=bjj", nil, everyone(200)},
+		{"get tag", "GET", "/api/v1/tags/" + fixTag, nil, everyone(200)},
+		{"list categories", "GET", "/api/v1/categories?disciplineId=bjj&tree=true", nil, everyone(200)},
+		{"get category", "GET", "/api/v1/categories/" + fixCategory, nil, everyone(200)},
+		{"list techniques", "GET", "/api/v1/techniques?disciplineId=bjj", nil, everyone(200)},
+		{"get technique", "GET", "/api/v1/techniques/" + fixTechnique, nil, everyone(200)},
+		{"list assets", "GET", "/api/v1/assets?disciplineId=bjj", nil, everyone(200)},
+		{"get asset", "GET", "/api/v1/assets/" + fixAsset, nil, everyone(200)},
+		{"list curricula", "GET", "/api/v1/curricula?disciplineId=bjj", nil, everyone(200)},
+		{"list public curricula", "GET", "/api/v1/curricula/public", nil, everyone(200)},
+		{"get curriculum", "GET", "/api/v1/curricula/" + fixCurriculum, nil, everyone(200)},
+		{"list elements", "GET", "/api/v1/curricula/" + fixCurriculum + "/elements", nil, everyone(200)},
+		{"list revisions", "GET", "/api/v1/curricula/" + fixCurriculum + "/revisions", nil, everyone(200)},
+		{"get revision", "GET", "/api/v1/curricula/" + fixCurriculum + "/revisions/1", nil, everyone(200)},
+		{"export curriculum", "GET", "/api/v1/curricula/" + fixCurriculum + "/export?format=pdf", nil, everyone(200)},
+		{"diff revisions", "GET", "/api/v1/curricula/" + fixCurriculum + "/revisions/diff?from=1&to=1", nil, everyone(200)},
+
+		// Private curricula are hidden from all but their owner and collaborators.
+		{"get private curriculum", "GET", "/api/v1/curricula/" + fixJKDCurricul, nil, only(tokJKDEditor, 200, 404)},
+		{"list private elements", "GET", "/api/v1/curricula/" + fixJKDCurricul + "/elements", nil, only(tokJKDEditor, 200, 404)},
+		{"export private curriculum", "GET", "/api/v1/curricula/" + fixJKDCurricul + "/export", nil, only(tokJKDEditor, 200, 404)},
+		{"list private revisions", "GET", "/api/v1/curricula/" + fixJKDCurricul + "/revisions", nil, only(tokJKDEditor, 200, 404)},
+
+		// Inactive assets are only visible to admins of their discipline.
+		{"get inactive asset", "GET", "/api/v1/assets/" + fixInactive, nil, func() map[string]int {
+			m := everyone(http.StatusNotFound)
+			m[tokAdmin] = 200
+			return m
+		}()},
+
+		// Writes require editor in the owning discipline.
+		{"create tag", "POST", "/api/v1/tags?disciplineId=bjj", map[string]string{"name": "Half Guard"}, bjjEditors(201)},
+		{"update tag", "PATCH", "/api/v1/tags/" + fixTag, map[string]string{"description": "x"}, bjjEditors(200)},
+		{"delete tag", "DELETE", "/api/v1/tags/" + fixTag, nil, bjjEditors(204)},
+		{"update jkd tag", "PATCH", "/api/v1/tags/" + fixJKDTag, map[string]string{"description": "x"}, jkdEditors(200)},
+		{"create category", "POST", "/api/v1/categories?disciplineId=bjj", map[string]string{"name": "Mount"}, bjjEditors(201)},
+		{"update category", "PATCH", "/api/v1/categories/" + fixCategory, map[string]string{"description": "x"}, bjjEditors(200)},
+		{"delete category", "DELETE", "/api/v1/categories/" + fixCategory, nil, bjjEditors(204)},
+		{"create technique", "POST", "/api/v1/techniques?disciplineId=bjj", map[string]string{"name": "Kimura"}, bjjEditors(201)},
+		{"update technique", "PATCH", "/api/v1/techniques/" + fixTechnique, map[string]string{"description": "x"}, bjjEditors(200)},
+		{"delete technique", "DELETE", "/api/v1/techniques/" + fixTechnique, nil, bjjEditors(204)},
+		{"create asset", "POST", "/api/v1/assets?disciplineId=bjj",
+			map[string]string{"url": "https://example.com/a", "title": "A", "type": "web"}, bjjEditors(201)},
+		{"update asset", "PATCH", "/api/v1/assets/" + fixAsset, map[string]string{"title": "Renamed"}, bjjEditors(200)},
+		{"delete asset", "DELETE", "/api/v1/assets/" + fixAsset, nil, bjjEditors(204)},
+		{"update jkd asset", "PATCH", "/api/v1/assets/" + fixJKDAsset, map[string]string{"title": "Renamed"}, jkdEditors(200)},
+		{"tag usages", "GET", "/api/v1/tags/" + fixTag + "/usages", nil, bjjEditors(200)},
+		{"category usages", "GET", "/api/v1/categories/" + fixCategory + "/usages", nil, bjjEditors(200)},
+		{"technique usages", "GET", "/api/v1/techniques/" + fixTechnique + "/usages", nil, bjjEditors(200)},
+		{"asset usages", "GET", "/api/v1/assets/" + fixAsset + "/usages", nil, bjjEditors(200)},
+		{"reassign technique", "DELETE", "/api/v1/techniques/" + fixTechnique + "?policy=reassign&replacementId=" + fixTriangle, nil, bjjEditors(204)},
+		{"create curriculum", "POST", "/api/v1/curricula?disciplineId=bjj", map[string]string{"title": "Blue Belt"}, bjjEditors(201)},
+		{"import curriculum", "POST", "/api/v1/curricula/import?disciplineId=bjj",
+			map[string]interface{}{"format": "markdown", "source": "# Blue Belt\n\n## Armbar", "commit": true}, bjjEditors(201)},
+		{"fork curriculum", "POST", "/api/v1/curricula/" + fixCurriculum + "/fork", nil, bjjEditors(201)},
+		{"fork curriculum into jkd", "POST", "/api/v1/curricula/" + fixCurriculum + "/fork?disciplineId=jkd", nil, jkdEditors(201)},
+
+		// Curriculum writes need edit access to the curriculum itself rather
+		// than a discipline role; the bjj editor owns the white belt one.
+		{"update curriculum", "PATCH", "/api/v1/curricula/" + fixCurriculum, map[string]string{"title": "Renamed"}, only(tokEditor, 200, 403)},
+		{"delete curriculum", "DELETE", "/api/v1/curricula/" + fixCurriculum, nil, only(tokEditor, 204, 403)},
+		{"create element", "POST", "/api/v1/curricula/" + fixCurriculum + "/elements",
+			map[string]string{"type": "text", "title": "Warmup"}, only(tokEditor, 201, 403)},
+		{"update element", "PUT", "/api/v1/curricula/" + fixCurriculum + "/elements/" + fixElement,
+			map[string]string{"title": "Welcome"}, only(tokEditor, 200, 403)},
+		{"delete element", "DELETE", "/api/v1/curricula/" + fixCurriculum + "/elements/" + fixElement, nil, only(tokEditor, 204, 403)},
+		{"refresh elements", "POST", "/api/v1/curricula/" + fixCurriculum + "/elements/refresh", nil, only(tokEditor, 200, 403)},
+		{"restore revision", "POST", "/api/v1/curricula/" + fixCurriculum + "/revisions/1/restore", nil, only(tokEditor, 200, 403)},
+		{"fork private curriculum", "POST", "/api/v1/curricula/" + fixJKDCurricul + "/fork", nil, only(tokJKDEditor, 201, 404)},
+		{"pull into non-fork", "POST", "/api/v1/curricula/" + fixCurriculum + "/pull", nil, only(tokEditor, 400, 403)},
+		{"reorder elements", "PUT", "/api/v1/curricula/" + fixCurriculum + "/elements/reorder",
+			map[string][]string{"orderedIds": {fixElement}}, only(tokEditor, 200, 403)},
+
+		// Only owners manage collaborators; editor collaborators can list them.
+		{"list collaborators", "GET", "/api/v1/curricula/" + fixCurriculum + "/collaborators", nil, only(tokEditor, 200, 403)},
+		{"invite collaborator", "POST", "/api/v1/curricula/" + fixCurriculum + "/collaborators",
+			map[string]string{"uid": tokViewer, "role": "editor"}, only(tokEditor, 201, 403)},
+		{"invite to private curriculum", "POST", "/api/v1/curricula/" + fixJKDCurricul + "/collaborators",
+			map[string]string{"email": "viewer@example.com", "role": "viewer"}, only(tokJKDEditor, 201, 404)},
+
+		// Only owners share a curriculum by link.
+		{"share curriculum", "PUT", "/api/v1/curricula/" + fixCurriculum + "/share", nil, only(tokEditor, 201, 403)},
+		{"share private curriculum", "PUT", "/api/v1/curricula/" + fixJKDCurricul + "/share", nil, only(tokJKDEditor, 201, 404)},
+
+		// Scheduling a curriculum needs editor in its discipline; the schedule
+		// and calendar feeds are open to everyone.
+		{"list sessions", "GET", "/api/v1/sessions?disciplineId=bjj", nil, everyone(200)},
+		{"get schedule", "GET", "/api/v1/schedule?disciplineId=bjj", nil, everyone(200)},
+		{"create session", "POST", "/api/v1/sessions?disciplineId=bjj",
+			map[string]string{"curriculumId": fixCurriculum, "startsAt": "2026-10-19T18:30"}, bjjEditors(201)},
+		{"create calendar feed", "POST", "/api/v1/calendar/feeds", map[string]string{"disciplineId": "bjj"}, everyone(201)},
+
+		// Learners record their own progress; editors see the discipline's.
+		{"get progress", "GET", "/api/v1/curricula/" + fixCurriculum + "/progress", nil, everyone(200)},
+		{"record progress", "PUT", "/api/v1/curricula/" + fixCurriculum + "/progress/" + fixElement,
+			map[string]string{"state": "watched"}, everyone(200)},
+		{"list my progress", "GET", "/api/v1/progress", nil, everyone(200)},
+		{"progress report", "GET", "/api/v1/progress/report?disciplineId=bjj", nil, bjjEditors(200)},
+		{"private curriculum progress", "GET", "/api/v1/curricula/" + fixJKDCurricul + "/progress", nil, only(tokJKDEditor, 200, 404)},
+
+		// The technique graph is open to everyone; editors change its edges.
+		{"list technique edges", "GET", "/api/v1/technique-edges?disciplineId=bjj", nil, everyone(200)},
+		{"get technique edge", "GET", "/api/v1/technique-edges/" + fixEdge, nil, everyone(200)},
+		{"technique neighbors", "GET", "/api/v1/techniques/" + fixTechnique + "/neighbors", nil, everyone(200)},
+		{"graph path", "GET", "/api/v1/graph/path?from=" + fixTriangle + "&to=" + fixTechnique, nil, everyone(200)},
+		{"graph chains", "GET", "/api/v1/graph/chains?from=" + fixTriangle, nil, everyone(200)},
+		{"graph export", "GET", "/api/v1/graph/export?disciplineId=bjj&format=dot", nil, everyone(200)},
+		{"create technique edge", "POST", "/api/v1/technique-edges?disciplineId=bjj",
+			map[string]string{"fromId": fixTechnique, "toId": fixTriangle, "type": "leads-to"}, bjjEditors(201)},
+		{"update technique edge", "PATCH", "/api/v1/technique-edges/" + fixEdge, map[string]string{"notes": "Off the hip"}, bjjEditors(200)},
+		{"delete technique edge", "DELETE", "/api/v1/technique-edges/" + fixEdge, nil, bjjEditors(204)},
+		{"merge techniques", "POST", "/api/v1/techniques/" + fixTechnique + "/merge",
+			map[string][]string{"sourceIds": {fixTriangle}}, bjjEditors(200)},
+
+		// Admin routes: RequireAnyAdmin on the group, then a per-discipline check.
+		{"admin list users", "GET", "/api/v1/admin/users?disciplineId=bjj", nil, bjjAdmin(200)},
+		{"admin search users", "GET", "/api/v1/admin/users/search?email=viewer@example.com", nil, anyAdmin(200)},
+		{"admin get user", "GET", "/api/v1/admin/users/" + tokViewer, nil, anyAdmin(200)},
+		{"admin set role", "PUT", "/api/v1/admin/users/" + tokViewer + "/role",
+			map[string]string{"disciplineId": "bjj", "role": "editor"}, bjjAdmin(200)},
+		{"admin revoke role", "DELETE", "/api/v1/admin/users/" + tokViewer + "/role?disciplineId=bjj", nil, bjjAdmin(204)},
+		{"admin list assets", "GET", "/api/v1/admin/assets?disciplineId=bjj", nil, bjjAdmin(200)},
+		{"admin toggle active", "PATCH", "/api/v1/admin/assets/" + fixInactive + "/active",
+			map[string]bool{"active": true}, bjjAdmin(200)},
+		{"admin set status", "PATCH", "/api/v1/admin/assets/" + fixInactive + "/status",
+			map[string]string{"processingStatus": "completed"}, bjjAdmin(200)},
+		{"admin curriculum denorm report", "GET", "/api/v1/admin/curricula/denorm?disciplineId=bjj", nil, bjjAdmin(200)},
+		{"admin curriculum denorm repair", "POST", "/api/v1/admin/curricula/denorm/repair?disciplineId=bjj", nil, bjjAdmin(200)},
+		// No pipeline is configured, so any admin gets 503 before the discipline check.
+		{"admin retry enrichment", "POST", "/api/v1/admin/assets/" + fixInactive + "/enrich", nil, anyAdmin(503)},
+	}
+
+	for _, tt := range tests {
+		for _, p := range allPrincipals {
+			want, ok := tt.want[p]
+			if !ok {
+				t.Fatalf("%s: no expectation for %s", tt.name, p)
+			}
+			t.Run(tt.name+"/"+p, func(t *testing.T) {
+				ts := newTestServer(t)
+				rec := ts.do(tt.method, tt.path, p, tt.body)
+				if rec.Code != want {
+					t.Errorf("%s %s as %s: got %d, want %d (body %s)",
+						tt.method, tt.path, p, rec.Code, want, rec.Body.String())
+				}
+			})
+		}
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	long := strings.Repeat("x", 301)
+	curr := "/api/v1/curricula/" + fixCurriculum
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		token   string
+		body    interface{}
+		want    int
+		wantErr string
+	}{
+		// disciplineId is required on lists and creates
+		{"tags list no discipline", "GET", "/api/v1/tags", tokViewer, nil, 400, "disciplineId query parameter is required"},
+		{"categories list no discipline", "GET", "/api/v1/categories", tokViewer, nil, 400, "disciplineId query parameter is required"},
+		{"techniques list no discipline", "GET", "/api/v1/techniques", tokViewer, nil, 400, "disciplineId query parameter is required"},
+		{"assets list no discipline", "GET", "/api/v1/assets", tokViewer, nil, 400, "disciplineId query parameter is required"},
+		{"tag create no discipline", "POST", "/api/v1/tags", tokEditor, map[string]string{"name": "x"}, 400, "disciplineId query parameter is required"},
+		{"curriculum create no discipline", "POST", "/api/v1/curricula", tokEditor, map[string]string{"title": "x"}, 400, "disciplineId query parameter is required"},
+
+		// Request bodies
+		{"malformed json", "POST", "/api/v1/tags?disciplineId=bjj", tokEditor, "{", 400, "invalid request body"},
+		{"unknown field", "POST", "/api/v1/tags?disciplineId=bjj", tokEditor, map[string]string{"name": "x", "bogus": "y"}, 400, "unknown field"},
+
+		// Tags
+		{"tag name required", "POST", "/api/v1/tags?disciplineId=bjj", tokEditor, map[string]string{}, 400, "name is required"},
+		{"tag name too long", "POST", "/api/v1/tags?disciplineId=bjj", tokEditor, map[string]string{"name": long[:101]}, 400, "name must be at most 100 characters"},
+		{"tag description too long", "POST", "/api/v1/tags?disciplineId=bjj", tokEditor,
+			map[string]string{"name": "x", "description": strings.Repeat("d", 501)}, 400, "description must be at most 500 characters"},
+		{"tag duplicate", "POST", "/api/v1/tags?disciplineId=bjj", tokEditor, map[string]string{"name": "Guard"}, 409, "already exists"},
+		{"tag rename duplicate", "PATCH", "/api/v1/tags/" + fixTag, tokEditor, map[string]string{"name": "Sweep"}, 409, "already exists"},
+		{"tag rename empty", "PATCH", "/api/v1/tags/" + fixTag, tokEditor, map[string]string{"name": ""}, 400, "name must be at least 1 characters"},
+
+		// Categories
+		{"category name required", "POST", "/api/v1/categories?disciplineId=bjj", tokEditor, map[string]string{}, 400, "name is required"},
+		{"category duplicate", "POST", "/api/v1/categories?disciplineId=bjj", tokEditor, map[string]string{"name": "Guard"}, 409, "already exists"},
+		{"category self parent", "PATCH", "/api/v1/categories/" + fixCategory, tokEditor,
+			map[string]string{"parentId": fixCategory}, 400, "cannot be its own parent"},
+		{"category circular parent", "PATCH", "/api/v1/categories/" + fixCategory, tokEditor,
+			map[string]string{"parentId": fixChildCat}, 400, "circular"},
+
+		// Techniques
+		{"technique name required", "POST", "/api/v1/techniques?disciplineId=bjj", tokEditor, map[string]string{}, 400, "name is required"},
+		{"technique duplicate", "POST", "/api/v1/techniques?disciplineId=bjj", tokEditor, map[string]string{"name": "Armbar"}, 409, "already exists"},
+		{"technique too many aliases", "PATCH", "/api/v1/techniques/" + fixTechnique, tokEditor,
+			map[string][]string{"aliases": strings.Split(strings.Repeat("a,", 20)+"a", ",")}, 400, "aliases must have at most 20 entries"},
+		{"technique alias too long", "POST", "/api/v1/techniques?disciplineId=bjj", tokEditor,
+			map[string]interface{}{"name": "Kimura", "aliases": []string{long}}, 400, "alias must be at most 200 characters"},
+		{"technique name too long", "PATCH", "/api/v1/techniques/" + fixTechnique, tokEditor,
+			map[string]string{"name": long[:201]}, 400, "name must be at most 200 characters"},
+		{"merge without sources", "POST", "/api/v1/techniques/" + fixTechnique + "/merge", tokEditor,
+			map[string][]string{"sourceIds": {}}, 400, "sourceIds is required"},
+		{"merge into itself", "POST", "/api/v1/techniques/" + fixTechnique + "/merge", tokEditor,
+			map[string][]string{"sourceIds": {fixTechnique}}, 400, "cannot be merged into itself"},
+		{"merge missing source", "POST", "/api/v1/techniques/" + fixTechnique + "/merge", tokEditor,
+			map[string][]string{"sourceIds": {"missing"}}, 400, "sourceIds must name techniques of this discipline"},
+
+		// Delete policies
+		{"delete bad policy", "DELETE", "/api/v1/tags/" + fixTag + "?policy=cascade", tokEditor, nil, 400, "policy must be restrict, detach or reassign"},
+		{"reassign without replacement", "DELETE", "/api/v1/assets/" + fixAsset + "?policy=reassign", tokEditor, nil, 400, "replacementId is required"},
+		{"replacement without reassign", "DELETE", "/api/v1/tags/" + fixTag + "?replacementId=" + fixTag2, tokEditor, nil, 400, "only allowed to reassign"},
+		{"reassign to itself", "DELETE", "/api/v1/tags/" + fixTag + "?policy=reassign&replacementId=" + fixTag, tokEditor, nil, 400,
+			"replacementId must name another tag of this discipline"},
+		{"reassign across disciplines", "DELETE", "/api/v1/tags/" + fixTag + "?policy=reassign&replacementId=" + fixJKDTag, tokEditor, nil, 400,
+			"replacementId must name another tag of this discipline"},
+		{"reassign to subcategory", "DELETE", "/api/v1/categories/" + fixCategory + "?policy=reassign&replacementId=" + fixChildCat, tokEditor, nil, 400,
+			"must not be a subcategory"},
+
+		// Assets
+		{"asset url required", "POST", "/api/v1/assets?disciplineId=bjj", tokEditor, map[string]string{"title": "x"}, 400, "url is required"},
+		{"asset title required", "POST", "/api/v1/assets?disciplineId=bjj", tokEditor, map[string]string{"url": "https://example.com"}, 400, "title is required"},
+		{"asset title too long", "POST", "/api/v1/assets?disciplineId=bjj", tokEditor,
+			map[string]string{"url": "https://example.com", "title": long}, 400, "title must be at most 300 characters"},
+		{"asset bad type", "POST", "/api/v1/assets?disciplineId=bjj", tokEditor,
+			map[string]string{"url": "https://example.com", "title": "x", "type": "pdf"}, 400, "type must be one of"},
+		{"asset update bad type", "PATCH", "/api/v1/assets/" + fixAsset, tokEditor, map[string]string{"type": "pdf"}, 400, "type must be one of"},
+		{"asset bad duration", "POST", "/api/v1/assets?disciplineId=bjj", tokEditor,
+			map[string]string{"url": "https://example.com", "title": "x", "duration": "a while"}, 400, "duration must be a duration such as"},
+		{"asset list bad sort", "GET", "/api/v1/assets?disciplineId=bjj&sort=title", tokViewer, nil, 400, "sort must be"},
+		{"asset list bad duration", "GET", "/api/v1/assets?disciplineId=bjj&maxDuration=short", tokViewer, nil, 400, "maxDuration must be a duration"},
+		{"asset list empty duration range", "GET", "/api/v1/assets?disciplineId=bjj&minDuration=10m&maxDuration=5m", tokViewer, nil, 400,
+			"minDuration must not be longer than maxDuration"},
+
+		// Sessions
+		{"session list no discipline", "GET", "/api/v1/sessions", tokViewer, nil, 400, "disciplineId query parameter is required"},
+		{"session curriculum required", "POST", "/api/v1/sessions?disciplineId=bjj", tokEditor,
+			map[string]string{"startsAt": "2026-10-19T18:30"}, 400, "curriculumId is required"},
+		{"session bad start", "POST", "/api/v1/sessions?disciplineId=bjj", tokEditor,
+			map[string]string{"curriculumId": fixCurriculum, "startsAt": "next monday"}, 400, "startsAt must be a time"},
+		{"session bad time zone", "POST", "/api/v1/sessions?disciplineId=bjj", tokEditor,
+			map[string]string{"curriculumId": fixCurriculum, "startsAt": "2026-10-19T18:30", "timeZone": "Mars/Olympus"}, 400, "timeZone must be an IANA time zone"},
+		{"session bad recurrence", "POST", "/api/v1/sessions?disciplineId=bjj", tokEditor,
+			map[string]string{"curriculumId": fixCurriculum, "startsAt": "2026-10-19T18:30", "recurrence": "FREQ=HOURLY"}, 400, "recurrence FREQ must be"},
+		{"session count and until", "POST", "/api/v1/sessions?disciplineId=bjj", tokEditor,
+			map[string]string{"curriculumId": fixCurriculum, "startsAt": "2026-10-19T18:30", "recurrence": "FREQ=DAILY;COUNT=2;UNTIL=20261231"}, 400,
+			"recurrence may not have both COUNT and UNTIL"},
+		{"session bad duration", "POST", "/api/v1/sessions?disciplineId=bjj", tokEditor,
+			map[string]string{"curriculumId": fixCurriculum, "startsAt": "2026-10-19T18:30", "duration": "30s"}, 400, "duration must be between"},
+		{"schedule too long", "GET", "/api/v1/schedule?disciplineId=bjj&from=2026-01-01&to=2026-06-01", tokViewer, nil, 400, "the schedule spans at most 92 days"},
+		{"schedule backwards", "GET", "/api/v1/schedule?disciplineId=bjj&from=2026-02-01&to=2026-01-01", tokViewer, nil, 400, "to must be after from"},
+		{"schedule bad tz", "GET", "/api/v1/schedule?disciplineId=bjj&tz=Nowhere", tokViewer, nil, 400, "tz must be an IANA time zone"},
+		{"feed unknown discipline", "POST", "/api/v1/calendar/feeds", tokViewer, map[string]string{"disciplineId": "chess"}, 400, "discipline not found"},
+
+		// Progress
+		{"progress empty", "PUT", curr + "/progress/" + fixElement, tokViewer, map[string]string{}, 400, "state or notes is required"},
+		{"progress bad state", "PUT", curr + "/progress/" + fixElement, tokViewer, map[string]string{"state": "done"}, 400,
+			"state must be one of: not-started, watched, drilled, mastered"},
+		{"progress notes too long", "PUT", curr + "/progress/" + fixElement, tokViewer,
+			map[string]string{"notes": strings.Repeat("n", 2001)}, 400, "notes must be at most 2000 characters"},
+		{"progress report no discipline", "GET", "/api/v1/progress/report", tokEditor, nil, 400, "disciplineId query parameter is required"},
+
+		// Technique graph
+		{"edge list no discipline", "GET", "/api/v1/technique-edges", tokViewer, nil, 400, "disciplineId query parameter is required"},
+		{"edge bad type", "POST", "/api/v1/technique-edges?disciplineId=bjj", tokEditor,
+			map[string]string{"fromId": fixTechnique, "toId": fixTriangle, "type": "beats"}, 400,
+			"type must be one of: leads-to, counters, setup-for, variation-of, requires"},
+		{"edge to itself", "POST", "/api/v1/technique-edges?disciplineId=bjj", tokEditor,
+			map[string]string{"fromId": fixTechnique, "toId": fixTechnique, "type": "counters"}, 400, "an edge must join two different techniques"},
+		{"edge from required", "POST", "/api/v1/technique-edges?disciplineId=bjj", tokEditor,
+			map[string]string{"toId": fixTriangle, "type": "counters"}, 400, "fromId is required"},
+		{"edge duplicate", "POST", "/api/v1/technique-edges?disciplineId=bjj", tokEditor,
+			map[string]string{"fromId": fixTriangle, "toId": fixTechnique, "type": "setup-for"}, 409, "an edge of this type already joins these techniques"},
+		{"neighbors bad direction", "GET", "/api/v1/techniques/" + fixTechnique + "/neighbors?direction=up", tokViewer, nil, 400, "direction must be out, in or both"},
+		{"path bad types", "GET", "/api/v1/graph/path?from=" + fixTriangle + "&to=" + fixTechnique + "&types=beats", tokViewer, nil, 400,
+			"types must be a comma-separated list of"},
+		{"path to required", "GET", "/api/v1/graph/path?from=" + fixTriangle, tokViewer, nil, 400, "from and to query parameters are required"},
+		{"chains too long", "GET", "/api/v1/graph/chains?from=" + fixTriangle + "&length=7", tokViewer, nil, 400, "length must be between 1 and 6"},
+		{"export bad format", "GET", "/api/v1/graph/export?disciplineId=bjj&format=svg", tokViewer, nil, 400, "format must be json or dot"},
+
+		// Curricula and elements
+		{"curriculum title required", "POST", "/api/v1/curricula?disciplineId=bjj", tokEditor, map[string]string{}, 400, "title is required"},
+		{"curriculum title too long", "PATCH", curr, tokEditor, map[string]string{"title": long[:201]}, 400, "title must be at most 200 characters"},
+		{"curriculum bad duration", "PATCH", curr, tokEditor, map[string]string{"duration": "90"}, 400, "duration must be a duration such as"},
+		{"element bad duration", "PUT", curr + "/elements/" + fixElement, tokEditor, map[string]string{"duration": "ten minutes"}, 400, "duration must be a duration such as"},
+		{"element bad type", "POST", curr + "/elements", tokEditor, map[string]string{"type": "video"}, 400, "type must be one of"},
+		{"image element needs url", "POST", curr + "/elements", tokEditor, map[string]string{"type": "image"}, 400, "imageUrl is required"},
+		{"image element bad url", "POST", curr + "/elements", tokEditor, map[string]string{"type": "image", "imageUrl": "not a url"}, 400, "imageUrl must be a valid URL"},
+		{"text element needs title", "POST", curr + "/elements", tokEditor, map[string]string{"type": "text"}, 400, "title is required for text elements"},
+		{"list element needs title", "POST", curr + "/elements", tokEditor, map[string]string{"type": "list"}, 400, "title is required for list elements"},
+		{"element update bad url", "PUT", curr + "/elements/" + fixElement, tokEditor, map[string]string{"imageUrl": "::"}, 400, "imageUrl must be a valid URL"},
+		{"reorder empty", "PUT", curr + "/elements/reorder", tokEditor, map[string][]string{"orderedIds": {}}, 400, "orderedIds must not be empty"},
+		{"section needs title", "POST", curr + "/elements", tokEditor, map[string]string{"type": "section"}, 400, "title is required for section elements"},
+		{"section duration", "POST", curr + "/elements", tokEditor,
+			map[string]string{"type": "section", "title": "Warm-up", "duration": "10m"}, 400, "sections take their duration"},
+		{"element parent not a section", "POST", curr + "/elements", tokEditor,
+			map[string]string{"type": "text", "title": "Drill", "parentId": fixElement}, 400, "parentId must be a section"},
+		{"element update parent", "PUT", curr + "/elements/" + fixElement, tokEditor, map[string]string{"parentId": "x"}, 400, "moved between sections by reordering"},
+		{"reorder repeated", "PUT", curr + "/elements/reorder", tokEditor,
+			map[string][]string{"orderedIds": {fixElement, fixElement}}, 400, "must not repeat"},
+		{"reorder into non-section", "PUT", curr + "/elements/reorder", tokEditor,
+			map[string]interface{}{"parentId": fixElement, "orderedIds": []string{fixElement}}, 400, "parentId must be a section"},
+		{"invite bad role", "POST", curr + "/collaborators", tokEditor, map[string]string{"uid": tokViewer, "role": "admin"}, 400, "role must be one of"},
+		{"invite nobody", "POST", curr + "/collaborators", tokEditor, map[string]string{"role": "viewer"}, 400, "exactly one of uid and email"},
+		{"invite owner", "POST", curr + "/collaborators", tokEditor, map[string]string{"uid": tokEditor, "role": "editor"}, 400, "owner cannot be invited"},
+		{"share expired", "PUT", curr + "/share", tokEditor, map[string]string{"expiresAt": "2020-01-01T00:00:00Z"}, 400, "must be in the future"},
+		{"share unknown field", "PUT", curr + "/share", tokEditor, map[string]bool{"public": true}, 400, "unknown field"},
+
+		// YouTube resolve
+		{"resolve url required", "POST", "/api/v1/youtube/resolve", tokViewer, map[string]string{}, 400, "url is required"},
+		{"resolve non-youtube", "POST", "/api/v1/youtube/resolve", tokViewer, map[string]string{"url": "https://example.com"}, 400, "only YouTube URLs"},
+
+		// Admin
+		{"admin users no discipline", "GET", "/api/v1/admin/users", tokAdmin, nil, 400, "disciplineId query parameter is required"},
+		{"admin search no email", "GET", "/api/v1/admin/users/search", tokAdmin, nil, 400, "email query parameter is required"},
+		{"admin set role no discipline", "PUT", "/api/v1/admin/users/viewer/role", tokAdmin, map[string]string{"role": "editor"}, 400, "disciplineId is required"},
+		{"admin set bad role", "PUT", "/api/v1/admin/users/viewer/role", tokAdmin,
+			map[string]string{"disciplineId": "bjj", "role": "owner"}, 400, "role must be viewer, editor, or admin"},
+		{"admin demote self", "PUT", "/api/v1/admin/users/admin/role", tokAdmin,
+			map[string]string{"disciplineId": "bjj", "role": "editor"}, 400, "cannot change your own admin role"},
+		{"admin revoke self", "DELETE", "/api/v1/admin/users/admin/role?disciplineId=bjj", tokAdmin, nil, 400, "cannot revoke your own admin role"},
+		{"admin revoke no discipline", "DELETE", "/api/v1/admin/users/viewer/role", tokAdmin, nil, 400, "disciplineId query parameter is required"},
+		{"admin assets no discipline", "GET", "/api/v1/admin/assets", tokAdmin, nil, 400, "disciplineId query parameter is required"},
+		{"admin bad status", "PATCH", "/api/v1/admin/assets/" + fixAsset + "/status", tokAdmin,
+			map[string]string{"processingStatus": "done"}, 400, "processingStatus must be one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t)
+			rec := ts.do(tt.method, tt.path, tt.token, tt.body)
+			if rec.Code != tt.want {
+				t.Fatalf("got %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+			body := decode[map[string]string](t, rec)
+			if !strings.Contains(body["error"], tt.wantErr) {
+				t.Errorf("error %q does not contain %q", body["error"], tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	curr := "/api/v1/curricula/" + fixCurriculum
+	tests := []struct {
+		method string
+		path   string
+		token  string
+		body   interface{}
+	}{
+		{"GET", "/api/v1/tags/missing", tokViewer, nil},
+		{"PATCH", "/api/v1/tags/missing", tokEditor, map[string]string{}},
+		{"DELETE", "/api/v1/tags/missing", tokEditor, nil},
+		{"GET", "/api/v1/categories/missing", tokViewer, nil},
+		{"PATCH", "/api/v1/categories/missing", tokEditor, map[string]string{}},
+		{"DELETE", "/api/v1/categories/missing", tokEditor, nil},
+		{"GET", "/api/v1/techniques/missing", tokViewer, nil},
+		{"PATCH", "/api/v1/techniques/missing", tokEditor, map[string]string{}},
+		{"DELETE", "/api/v1/techniques/missing", tokEditor, nil},
+		{"GET", "/api/v1/assets/missing", tokViewer, nil},
+		{"PATCH", "/api/v1/assets/missing", tokEditor, map[string]string{}},
+		{"DELETE", "/api/v1/assets/missing", tokEditor, nil},
+		{"GET", "/api/v1/tags/missing/usages", tokEditor, nil},
+		{"GET", "/api/v1/categories/missing/usages", tokEditor, nil},
+		{"GET", "/api/v1/techniques/missing/usages", tokEditor, nil},
+		{"GET", "/api/v1/assets/missing/usages", tokEditor, nil},
+		{"GET", "/api/v1/curricula/missing", tokViewer, nil},
+		{"PATCH", "/api/v1/curricula/missing", tokEditor, map[string]string{}},
+		{"DELETE", "/api/v1/curricula/missing", tokEditor, nil},
+		{"GET", "/api/v1/curricula/missing/elements", tokViewer, nil},
+		{"POST", "/api/v1/curricula/missing/elements", tokEditor, map[string]string{"type": "text", "title": "x"}},
+		{"PUT", curr + "/elements/missing", tokEditor, map[string]string{"title": "x"}},
+		{"PUT", "/api/v1/curricula/missing/elements/reorder", tokEditor, map[string][]string{"orderedIds": {"a"}}},
+		{"GET", "/api/v1/curricula/missing/revisions", tokViewer, nil},
+		{"GET", curr + "/revisions/99", tokViewer, nil},
+		{"POST", curr + "/revisions/99/restore", tokEditor, nil},
+		{"POST", "/api/v1/curricula/missing/fork", tokEditor, nil},
+		{"GET", "/api/v1/curricula/missing/export", tokViewer, nil},
+		{"POST", "/api/v1/curricula/missing/pull", tokEditor, nil},
+		{"GET", "/api/v1/curricula/missing/collaborators", tokEditor, nil},
+		{"POST", curr + "/collaborators", tokEditor, map[string]string{"email": "nobody@example.com", "role": "viewer"}},
+		{"DELETE", curr + "/collaborators/" + tokViewer, tokEditor, nil},
+		{"GET", curr + "/share", tokEditor, nil},
+		{"DELETE", curr + "/share", tokEditor, nil},
+		{"GET", "/share/missing", "", nil},
+		{"GET", "/api/v1/curricula/missing/progress", tokViewer, nil},
+		{"PUT", curr + "/progress/missing", tokViewer, map[string]string{"state": "watched"}},
+		{"GET", "/api/v1/sessions/missing", tokViewer, nil},
+		{"GET", "/api/v1/technique-edges/missing", tokViewer, nil},
+		{"PATCH", "/api/v1/technique-edges/missing", tokEditor, map[string]string{}},
+		{"DELETE", "/api/v1/technique-edges/missing", tokEditor, nil},
+		{"GET", "/api/v1/techniques/missing/neighbors", tokViewer, nil},
+		{"POST", "/api/v1/techniques/missing/merge", tokEditor, map[string][]string{"sourceIds": {fixTriangle}}},
+		{"GET", "/api/v1/graph/path?from=missing&to=" + fixTechnique, tokViewer, nil},
+		{"GET", "/api/v1/graph/chains?from=missing", tokViewer, nil},
+		{"PATCH", "/api/v1/sessions/missing", tokEditor, map[string]string{}},
+		{"DELETE", "/api/v1/sessions/missing", tokEditor, nil},
+		{"DELETE", "/api/v1/calendar/feeds/missing", tokViewer, nil},
+		{"GET", "/calendar/missing.ics", "", nil},
+		{"GET", "/api/v1/admin/users/missing", tokAdmin, nil},
+		{"PATCH", "/api/v1/admin/assets/missing/active", tokAdmin, map[string]bool{"active": true}},
+		{"PATCH", "/api/v1/admin/assets/missing/status", tokAdmin, map[string]string{"processingStatus": ""}},
+		{"GET", "/api/v1/admin/users/search?email=nobody@example.com", tokAdmin, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			ts := newTestServer(t)
+			rec := ts.do(tt.method, tt.path, tt.token, tt.body)
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("got %d, want 404 (body %s)", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestElementDenormalization(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		ctx := context.Background()
+		curr := "/api/v1/curricula/" + fixCurriculum
+
+		getCurriculum := func() *model.Curriculum {
+			t.Helper()
+			c, err := ts.store.Curricula().Get(ctx, fixCurriculum)
+			if err != nil {
+				t.Fatalf("get curriculum: %v", err)
+			}
+			return c
+		}
+		searchHits := func(q string) int {
+			t.Helper()
+			rec := ts.do("GET", "/api/v1/curricula?disciplineId=bjj&q="+q, tokViewer, nil)
+			if rec.Code != 200 {
+				t.Fatalf("search: got %d", rec.Code)
+			}
+			return len(decode[[]model.Curriculum](t, rec))
+		}
+
+		// 1. Adding a technique element snapshots it and inherits its tags.
+		rec := ts.do("POST", curr+"/elements", tokEditor, map[string]string{"type": "technique", "techniqueId": fixTechnique})
+		if rec.Code != 201 {
+			t.Fatalf("create technique element: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		techElem := decode[model.CurriculumElement](t, rec)
+		if techElem.Ord != 2 {
+			t.Errorf("ord: got %d, want 2", techElem.Ord)
+		}
+		if techElem.Snapshot == nil || techElem.Snapshot.Name != "Armbar" {
+			t.Fatalf("snapshot: got %+v", techElem.Snapshot)
+		}
+		c := getCurriculum()
+		if !containsAll(c.AllTagIDs, fixTag, fixTag2) {
+			t.Errorf("allTagIds after technique add: got %v", c.AllTagIDs)
+		}
+		if !strings.Contains(c.SearchText, "armbar") || !strings.Contains(c.SearchText, "straight arm lock") {
+			t.Errorf("searchText after technique add: got %q", c.SearchText)
+		}
+		if n := searchHits("armbar"); n != 1 {
+			t.Errorf("search armbar: got %d hits", n)
+		}
+
+		// 2. Tag filter uses allTagIds (own + inherited).
+		rec = ts.do("GET", "/api/v1/curricula?tagId="+fixTag2, tokViewer, nil)
+		if got := decode[[]model.Curriculum](t, rec); len(got) != 1 || got[0].ElementCount != 2 {
+			t.Errorf("tag filter: got %+v", got)
+		}
+
+		// 3. Asset elements snapshot title/url and tags.
+		rec = ts.do("POST", curr+"/elements", tokEditor, map[string]string{"type": "asset", "assetId": fixAsset})
+		if rec.Code != 201 {
+			t.Fatalf("create asset element: got %d", rec.Code)
+		}
+		assetElem := decode[model.CurriculumElement](t, rec)
+		if assetElem.Snapshot == nil || assetElem.Snapshot.URL != "https://example.com/armbar" {
+			t.Errorf("asset snapshot: got %+v", assetElem.Snapshot)
+		}
+
+		// 4. Updating an element's text is reflected in searchText.
+		rec = ts.do("PUT", curr+"/elements/"+fixElement, tokEditor, map[string]string{"title": "Shrimping drill"})
+		if rec.Code != 200 {
+			t.Fatalf("update element: got %d", rec.Code)
+		}
+		if n := searchHits("shrimping"); n != 1 {
+			t.Errorf("search shrimping after update: got %d hits", n)
+		}
+		if n := searchHits("intro"); n != 0 {
+			t.Errorf("search intro after rename: got %d hits", n)
+		}
+
+		// 5. Deleting the technique element drops its tags and text.
+		rec = ts.do("DELETE", curr+"/elements/"+techElem.ID, tokEditor, nil)
+		if rec.Code != 204 {
+			t.Fatalf("delete element: got %d", rec.Code)
+		}
+		c = getCurriculum()
+		if containsAll(c.AllTagIDs, fixTag2) {
+			t.Errorf("allTagIds after delete still has %s: %v", fixTag2, c.AllTagIDs)
+		}
+		if strings.Contains(c.SearchText, "straight arm lock") {
+			t.Errorf("searchText after delete: got %q", c.SearchText)
+		}
+
+		// 6. Changing the curriculum's own tags recomputes allTagIds; the asset
+		// element still contributes its tag.
+		if err := ts.store.Tags().Set(context.Background(), "tag-new", &model.Tag{DisciplineID: "bjj", Name: "New", Slug: "new"}); err != nil {
+			t.Fatal(err)
+		}
+		rec = ts.do("PATCH", curr, tokEditor, map[string][]string{"tagIds": {"tag-new"}})
+		if rec.Code != 200 {
+			t.Fatalf("patch curriculum: got %d", rec.Code)
+		}
+		updated := decode[model.Curriculum](t, rec)
+		if len(updated.AllTagIDs) != 2 || !containsAll(updated.AllTagIDs, "tag-new", fixTag) {
+			t.Errorf("allTagIds after tag change: got %v", updated.AllTagIDs)
+		}
+
+		// 7. Reorder rewrites ord.
+		rec = ts.do("PUT", curr+"/elements/reorder", tokEditor, map[string][]string{"orderedIds": {assetElem.ID, fixElement}})
+		if rec.Code != 200 {
+			t.Fatalf("reorder: got %d", rec.Code)
+		}
+		rec = ts.do("GET", curr+"/elements", tokViewer, nil)
+		elems := decode[[]model.CurriculumElement](t, rec)
+		if len(elems) != 2 || elems[0].ID != assetElem.ID || elems[0].Ord != 1 || elems[1].ID != fixElement {
+			t.Errorf("after reorder: got %+v", elems)
+		}
+
+		// 8. Deleting the curriculum removes its elements.
+		rec = ts.do("DELETE", curr, tokEditor, nil)
+		if rec.Code != 204 {
+			t.Fatalf("delete curriculum: got %d", rec.Code)
+		}
+		left, err := ts.store.Elements().List(ctx, fixCurriculum, store.NewQuery())
+		if err != nil || len(left) != 0 {
+			t.Errorf("elements after curriculum delete: %v, %v", left, err)
+		}
+	})
+}
+
+func TestCategoryDeleteReparentsChildren(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		rec := ts.do("DELETE", "/api/v1/categories/"+fixCategory, tokEditor, nil)
+		if rec.Code != 204 {
+			t.Fatalf("delete: got %d", rec.Code)
+		}
+		child, err := ts.store.Categories().Get(context.Background(), fixChildCat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if child.ParentID != nil && *child.ParentID != "" {
+			t.Errorf("child parentId: got %q, want empty", *child.ParentID)
+		}
+	})
+}
+
+func TestInactiveAssetsHiddenFromLists(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		tests := []struct {
+			token string
+			query string
+			want  int
+		}{
+			{tokViewer, "", 1},
+			{tokViewer, "&includeInactive=true", 1},
+			{tokAdmin, "", 1},
+			{tokAdmin, "&includeInactive=true", 2},
+		}
+		for _, tt := range tests {
+			rec := ts.do("GET", "/api/v1/assets?disciplineId=bjj"+tt.query, tt.token, nil)
+			if got := len(decode[[]model.Asset](t, rec)); got != tt.want {
+				t.Errorf("%s%s: got %d assets, want %d", tt.token, tt.query, got, tt.want)
+			}
+		}
+
+		// The admin list shows everything and filters by status.
+		rec := ts.do("GET", "/api/v1/admin/assets?disciplineId=bjj&status=failed", tokAdmin, nil)
+		if got := decode[[]model.Asset](t, rec); len(got) != 1 || got[0].ID != fixInactive {
+			t.Errorf("admin status filter: got %+v", got)
+		}
+	})
+}
+
+func TestSetRoleUpdatesClaims(t *testing.T) {
+	ts := newTestServer(t)
+	rec := ts.do("PUT", "/api/v1/admin/users/"+tokViewer+"/role", tokAdmin, map[string]string{"disciplineId": "bjj", "role": "editor"})
+	if rec.Code != 200 {
+		t.Fatalf("set role: got %d (%s)", rec.Code, rec.Body.String())
+	}
+	if got := decode[model.UserInfo](t, rec).Roles["bjj"]; got != "editor" {
+		t.Errorf("role: got %q, want editor", got)
+	}
+
+	rec = ts.do("PUT", "/api/v1/admin/users/"+tokViewer+"/role", tokAdmin, map[string]string{"disciplineId": "nope", "role": "editor"})
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("unknown discipline as bjj admin: got %d, want 403", rec.Code)
+	}
+}
+
+func containsAll(haystack []string, needles ...string) bool {
+	set := map[string]bool{}
+	for _, h := range haystack {
+		set[h] = true
+	}
+	for _, n := range needles {
+		if !set[n] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package server_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+func TestSearch(t *testing.T) {
+	ts := newTestServer(t)
+	search := func(query string) []model.SearchResult {
+		t.Helper()
+		rec := ts.do("GET", "/api/v1/search?disciplineId=bjj&"+query, tokViewer, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("search %s: got %d (%s)", query, rec.Code, rec.Body.String())
+		}
+		return decode[[]model.SearchResult](t, rec)
+	}
+	ids := func(results []model.SearchResult) []string {
+		var out []string
+		for _, r := range results {
+			out = append(out, r.Type+":"+r.ID)
+		}
+		return out
+	}
+
+	// Seeded data is indexed and stemming matches "armbars" to "Armbar".
+	got := search("q=armbars")
+	if len(got) != 2 || !containsAll(ids(got), "technique:"+fixTechnique, "asset:"+fixAsset) {
+		t.Fatalf("armbars: got %v", ids(got))
+	}
+	// Every matched word is highlighted.
+	got = search("q=straight+arm+lock")
+	if len(got) == 0 || got[0].ID != fixTechnique {
+		t.Fatalf("straight arm lock: got %v", ids(got))
+	}
+	if h := got[0].Highlights["description"]; h != "<mark>Straight</mark> <mark>arm</mark> <mark>lock</mark>" {
+		t.Errorf("highlight: got %q", h)
+	}
+
+	// Inactive assets are not searchable; types filters result kinds.
+	if got := search("q=processing"); len(got) != 0 {
+		t.Errorf("inactive asset found: %v", ids(got))
+	}
+	if got := search("q=armbar&types=asset"); len(got) != 1 || got[0].Type != model.SearchTypeAsset {
+		t.Errorf("types=asset: got %v", ids(got))
+	}
+	// The last word is matched as a prefix while typing.
+	if got := search("q=arm"); len(got) != 2 {
+		t.Errorf("prefix arm: got %v", ids(got))
+	}
+
+	// Creates, updates and deletes keep the index current, including
+	// curriculum element text.
+	rec := ts.do("POST", "/api/v1/techniques?disciplineId=bjj", tokEditor, map[string]string{
+		"name": "Kimura", "description": "Shoulder lock from <b>closed</b> guard",
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create technique: got %d", rec.Code)
+	}
+	kimura := decode[model.Technique](t, rec)
+	if got := search("q=shoulder+locks"); len(got) != 2 || got[0].ID != kimura.ID {
+		t.Fatalf("after create: got %v", ids(got))
+	}
+
+	rec = ts.do("POST", "/api/v1/curricula/"+fixCurriculum+"/elements", tokEditor, map[string]string{"type": "technique", "techniqueId": kimura.ID})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create element: got %d", rec.Code)
+	}
+	got = search("q=kimura&types=curriculum")
+	if len(got) != 1 || got[0].ID != fixCurriculum || !strings.Contains(got[0].Highlights["content"], "<mark>Kimura</mark>") {
+		t.Errorf("curriculum element text: got %+v", got)
+	}
+
+	rec = ts.do("PATCH", "/api/v1/techniques/"+kimura.ID, tokEditor, map[string]string{"name": "Double Wristlock"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update technique: got %d", rec.Code)
+	}
+	if got := search("q=wristlock&types=technique"); len(got) != 1 {
+		t.Errorf("after rename: got %v", ids(got))
+	}
+	if got := search("q=kimura&types=technique"); len(got) != 0 {
+		t.Errorf("old name still indexed: %v", ids(got))
+	}
+
+	ts.do("DELETE", "/api/v1/techniques/"+kimura.ID, tokEditor, nil)
+	ts.do("DELETE", "/api/v1/curricula/"+fixCurriculum, tokEditor, nil)
+	if got := search("q=kimura+wristlock"); len(got) != 0 {
+		t.Errorf("after delete: got %v", ids(got))
+	}
+
+	// Other disciplines are separate.
+	rec = ts.do("GET", "/api/v1/search?disciplineId=jkd&q=armbar", tokViewer, nil)
+	if got := decode[[]model.SearchResult](t, rec); len(got) != 0 {
+		t.Errorf("jkd: got %v", ids(got))
+	}
+
+	for _, bad := range []string{"/api/v1/search?q=x", "/api/v1/search?disciplineId=bjj", "/api/v1/search?disciplineId=bjj&q=x&types=tag"} {
+		if rec := ts.do("GET", bad, tokViewer, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", bad, rec.Code)
+		}
+	}
+}
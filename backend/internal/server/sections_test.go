@@ -0,0 +1,131 @@
+package server_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+func TestCurriculumSections(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		curr := "/api/v1/curricula/" + fixCurriculum
+		create := func(body map[string]string) string {
+			t.Helper()
+			rec := ts.do("POST", curr+"/elements", tokEditor, body)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("create %v: got %d (%s)", body, rec.Code, rec.Body.String())
+			}
+			return decode[model.CurriculumElement](t, rec).ID
+		}
+		warmup := create(map[string]string{"type": "section", "title": "Warm-up"})
+		drilling := create(map[string]string{"type": "section", "title": "Drilling"})
+		create(map[string]string{"type": "text", "title": "Shrimp", "duration": "5m", "parentId": warmup})
+		rolls := create(map[string]string{"type": "text", "title": "Rolls", "duration": "3m", "parentId": warmup})
+		armbar := create(map[string]string{"type": "technique", "techniqueId": fixTechnique, "duration": "10m", "parentId": drilling})
+		if rec := ts.do("POST", curr+"/elements", tokEditor,
+			map[string]string{"type": "section", "title": "Inner", "parentId": warmup}); rec.Code != http.StatusBadRequest {
+			t.Errorf("nested section: got %d", rec.Code)
+		}
+
+		// layout lists the flat view as title@ord, children indented.
+		layout := func() string {
+			t.Helper()
+			var got []string
+			for _, e := range decode[[]model.CurriculumElement](t, ts.do("GET", curr+"/elements", tokViewer, nil)) {
+				s := ""
+				if e.ParentID != nil {
+					s = "  "
+				}
+				if e.Title != nil {
+					s += *e.Title
+				} else {
+					s += e.Snapshot.Name
+				}
+				got = append(got, s)
+			}
+			return strings.Join(got, "|")
+		}
+		if got, want := layout(), "Intro|Warm-up|  Shrimp|  Rolls|Drilling|  Armbar"; got != want {
+			t.Errorf("flat view:\n got %s\nwant %s", got, want)
+		}
+
+		tree := decode[[]model.CurriculumElement](t, ts.do("GET", curr+"/elements?tree=true", tokViewer, nil))
+		if len(tree) != 3 || len(tree[1].Children) != 2 || tree[1].TotalDurationSeconds != 480 ||
+			tree[1].Children[1].ID != rolls || tree[1].Children[1].Ord != 2 || tree[2].TotalDurationSeconds != 600 {
+			t.Fatalf("tree: got %+v", tree)
+		}
+		c := decode[model.Curriculum](t, ts.do("GET", curr, tokViewer, nil))
+		if c.ElementCount != 6 || c.TotalDurationSeconds != 300+480+600 {
+			t.Errorf("counters: got %d elements, %ds", c.ElementCount, c.TotalDurationSeconds)
+		}
+
+		// Paging the flat view follows the reading order.
+		rec := ts.do("GET", curr+"/elements?limit=4", tokViewer, nil)
+		if page := decode[[]model.CurriculumElement](t, rec); len(page) != 4 || page[3].ID != rolls {
+			t.Fatalf("first page: got %+v", page)
+		}
+		next := rec.Header().Get("Link")
+		next = next[strings.Index(next, "<")+1 : strings.Index(next, ">")]
+		if page := decode[[]model.CurriculumElement](t, ts.do("GET", next, tokViewer, nil)); len(page) != 2 || page[1].ID != armbar {
+			t.Fatalf("second page: got %+v", page)
+		}
+
+		reorder := func(body map[string]interface{}) {
+			t.Helper()
+			if rec := ts.do("PUT", curr+"/elements/reorder", tokEditor, body); rec.Code != http.StatusOK {
+				t.Fatalf("reorder %v: got %d (%s)", body, rec.Code, rec.Body.String())
+			}
+		}
+		// Into a section, out of one, and sections among themselves.
+		reorder(map[string]interface{}{"parentId": drilling, "orderedIds": []string{rolls, fixElement}})
+		if got, want := layout(), "Warm-up|  Shrimp|Drilling|  Rolls|  Intro|  Armbar"; got != want {
+			t.Errorf("into section:\n got %s\nwant %s", got, want)
+		}
+		reorder(map[string]interface{}{"orderedIds": []string{drilling, fixElement, warmup}})
+		if got, want := layout(), "Drilling|  Rolls|  Armbar|Intro|Warm-up|  Shrimp"; got != want {
+			t.Errorf("out of section:\n got %s\nwant %s", got, want)
+		}
+		if rec := ts.do("PUT", curr+"/elements/reorder", tokEditor,
+			map[string]interface{}{"parentId": warmup, "orderedIds": []string{drilling}}); rec.Code != http.StatusBadRequest {
+			t.Errorf("section into section: got %d", rec.Code)
+		}
+
+		// Exports number the elements of a section under it.
+		md := ts.do("GET", curr+"/export", tokViewer, nil).Body.String()
+		for _, want := range []string{"## 1. Drilling (13:00)\n", "### 1.2. Armbar (10:00)\n", "## 2. Intro (5:00)\n", "### 3.1. Shrimp (5:00)\n"} {
+			if !strings.Contains(md, want) {
+				t.Errorf("export: missing %q in\n%s", want, md)
+			}
+		}
+
+		// Forks keep the sections, pointing at their own copies.
+		rec = ts.do("POST", curr+"/fork", tokEditor, nil)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("fork: got %d", rec.Code)
+		}
+		fork := "/api/v1/curricula/" + decode[model.Curriculum](t, rec).ID
+		forked := decode[[]model.CurriculumElement](t, ts.do("GET", fork+"/elements?tree=true", tokEditor, nil))
+		if len(forked) != 3 || forked[0].ID == drilling || len(forked[0].Children) != 2 || *forked[0].Children[0].ParentID != forked[0].ID {
+			t.Errorf("fork tree: got %+v", forked)
+		}
+		// Pulling places a new upstream element in the fork's copy of its section.
+		create(map[string]string{"type": "text", "title": "Bridges", "parentId": warmup})
+		if rec := ts.do("POST", fork+"/pull", tokEditor, nil); rec.Code != http.StatusOK {
+			t.Fatalf("pull: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		forked = decode[[]model.CurriculumElement](t, ts.do("GET", fork+"/elements?tree=true", tokEditor, nil))
+		if got := forked[2].Children; len(got) != 2 || *got[1].Title != "Bridges" || got[1].Ord != 2 {
+			t.Errorf("pulled into section: got %+v", got)
+		}
+
+		// Deleting a section deletes its elements.
+		if rec := ts.do("DELETE", curr+"/elements/"+drilling, tokEditor, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("delete section: got %d", rec.Code)
+		}
+		if got, want := layout(), "Intro|Warm-up|  Shrimp|  Bridges"; got != want {
+			t.Errorf("after delete:\n got %s\nwant %s", got, want)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+func TestSessions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		// A weekly class keeps its local time across the change to summer
+		// time on 29 March 2026.
+		rec := ts.do("POST", "/api/v1/sessions?disciplineId=bjj", tokEditor, map[string]string{
+			"curriculumId": fixCurriculum, "startsAt": "2026-03-23T18:30", "timeZone": "Europe/Berlin",
+			"duration": "1h 30m", "recurrence": "freq=weekly;byday=WE,MO;count=4", "location": "Main Mat",
+		})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		s := decode[model.Session](t, rec)
+		if s.Recurrence != "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4" || s.Duration != "1:30:00" || s.DurationSeconds != 5400 ||
+			s.StartsAt.Format(time.RFC3339) != "2026-03-23T18:30:00+01:00" {
+			t.Fatalf("create: got %+v", s)
+		}
+
+		rec = ts.do("GET", "/api/v1/schedule?disciplineId=bjj&from=2026-03-23&to=2026-04-13&tz=Europe/Berlin", tokViewer, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("schedule: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		var got []string
+		for _, o := range decode[[]model.Occurrence](t, rec) {
+			if o.Title != "White Belt" || o.Location != "Main Mat" || o.EndsAt.Sub(o.StartsAt) != 90*time.Minute {
+				t.Errorf("occurrence: got %+v", o)
+			}
+			got = append(got, o.StartsAt.Format(time.RFC3339))
+		}
+		want := "2026-03-23T18:30:00+01:00 2026-03-25T18:30:00+01:00 2026-03-30T18:30:00+02:00 2026-04-01T18:30:00+02:00"
+		if strings.Join(got, " ") != want {
+			t.Errorf("schedule: got %v, want %s", got, want)
+		}
+
+		// Sessions store when they end, which keeps finished ones out of the
+		// schedule query.
+		stored, err := ts.store.Sessions().Get(context.Background(), s.ID)
+		if err != nil || stored.EndsAt.Format(time.RFC3339) != "2026-04-01T18:00:00Z" {
+			t.Errorf("endsAt: got %v (%v)", stored.EndsAt, err)
+		}
+		rec = ts.do("POST", "/api/v1/sessions?disciplineId=bjj", tokEditor, map[string]string{
+			"curriculumId": fixCurriculum, "startsAt": "2025-01-06T10:00:00Z", "duration": "1h", "recurrence": "FREQ=DAILY",
+		})
+		forever := decode[model.Session](t, rec)
+		if rec := ts.do("PATCH", "/api/v1/sessions/"+forever.ID, tokEditor, map[string]string{"recurrence": "FREQ=DAILY;COUNT=3"}); rec.Code != http.StatusOK {
+			t.Fatalf("update recurrence: got %d", rec.Code)
+		}
+		if stored, err := ts.store.Sessions().Get(context.Background(), forever.ID); err != nil || stored.EndsAt.Format(time.RFC3339) != "2025-01-08T11:00:00Z" {
+			t.Errorf("endsAt after update: got %v (%v)", stored.EndsAt, err)
+		}
+		if n := len(decode[[]model.Occurrence](t, ts.do("GET", "/api/v1/schedule?disciplineId=bjj&from=2025-01-08", tokViewer, nil))); n != 1 {
+			t.Errorf("schedule of the last day: got %d occurrences, want 1", n)
+		}
+		if rec := ts.do("DELETE", "/api/v1/sessions/"+forever.ID, tokEditor, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("delete session: got %d", rec.Code)
+		}
+
+		// Moving the class to New York keeps its local time.
+		rec = ts.do("PATCH", "/api/v1/sessions/"+s.ID, tokEditor, map[string]string{"timeZone": "America/New_York"})
+		if s := decode[model.Session](t, rec); rec.Code != http.StatusOK || s.StartsAt.Format(time.RFC3339) != "2026-03-23T18:30:00-04:00" {
+			t.Fatalf("update: got %d (%s)", rec.Code, rec.Body.String())
+		}
+
+		// Calendar apps count the start as an occurrence, so a weekly rule
+		// must include its weekday.
+		rec = ts.do("POST", "/api/v1/sessions?disciplineId=bjj", tokEditor, map[string]string{
+			"curriculumId": fixCurriculum, "startsAt": "2026-03-24T18:30", "timeZone": "Europe/Berlin", "recurrence": "FREQ=WEEKLY;BYDAY=MO,WE",
+		})
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "Tuesday") {
+			t.Errorf("create starting off BYDAY: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		for _, body := range []map[string]string{
+			{"startsAt": "2026-03-24T18:30"},
+			{"recurrence": "FREQ=WEEKLY;BYDAY=TU,TH"},
+			// A Monday evening in New York is Tuesday in Tokyo.
+			{"startsAt": "2026-03-23T23:30:00-04:00", "timeZone": "Asia/Tokyo"},
+		} {
+			if rec := ts.do("PATCH", "/api/v1/sessions/"+s.ID, tokEditor, body); rec.Code != http.StatusBadRequest {
+				t.Errorf("update %v off BYDAY: got %d (%s)", body, rec.Code, rec.Body.String())
+			}
+		}
+
+		// Sessions of a private curriculum are hidden with it.
+		rec = ts.do("POST", "/api/v1/sessions?disciplineId=jkd", tokJKDEditor, map[string]string{
+			"curriculumId": fixJKDCurricul, "startsAt": "2026-03-24T19:00:00Z", "recurrence": "FREQ=DAILY;UNTIL=20260326",
+		})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create private: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		private := decode[model.Session](t, rec)
+		if private.Duration != "1:00:00" {
+			t.Errorf("default duration: got %q", private.Duration)
+		}
+		if rec := ts.do("GET", "/api/v1/sessions/"+private.ID, tokJKDAdmin, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("get private: got %d", rec.Code)
+		}
+		if n := len(decode[[]model.Occurrence](t, ts.do("GET", "/api/v1/schedule?disciplineId=jkd&from=2026-03-23", tokJKDEditor, nil))); n != 3 {
+			t.Errorf("private schedule: got %d occurrences, want 3", n)
+		}
+		if n := len(decode[[]model.Session](t, ts.do("GET", "/api/v1/sessions?disciplineId=jkd", tokJKDAdmin, nil))); n != 0 {
+			t.Errorf("sessions of hidden curriculum: got %d", n)
+		}
+		// Editors of the discipline cannot change them either.
+		if rec := ts.do("PATCH", "/api/v1/sessions/"+private.ID, tokJKDAdmin, map[string]string{"location": "Garage"}); rec.Code != http.StatusNotFound {
+			t.Errorf("update private: got %d", rec.Code)
+		}
+		if rec := ts.do("DELETE", "/api/v1/sessions/"+private.ID, tokJKDAdmin, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("delete private: got %d", rec.Code)
+		}
+
+		// The owner's personal feed serves the private class to calendar apps.
+		rec = ts.do("POST", "/api/v1/calendar/feeds", tokJKDEditor, nil)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create feed: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		feed := decode[model.CalendarFeed](t, rec)
+		if feed.ID == "" || feed.Path != "/calendar/"+feed.ID+".ics" {
+			t.Fatalf("create feed: got %+v", feed)
+		}
+		rec = ts.do("GET", feed.Path, "", nil)
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/calendar") {
+			t.Fatalf("feed: got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		ics := rec.Body.String()
+		for _, line := range []string{
+			"BEGIN:VCALENDAR\r\n",
+			"UID:" + private.ID + "@skillhive\r\n",
+			"DTSTART:20260324T190000Z\r\n",
+			"DURATION:PT1H\r\n",
+			"RRULE:FREQ=DAILY;UNTIL=20260326T235959Z\r\n",
+			"SUMMARY:JKD Basics\r\n",
+		} {
+			if !strings.Contains(ics, line) {
+				t.Errorf("feed lacks %q:\n%s", line, ics)
+			}
+		}
+		if strings.Contains(ics, s.ID) {
+			t.Errorf("personal feed lists another coach's session:\n%s", ics)
+		}
+
+		// A viewer's bjj feed has the public class in its zone.
+		rec = ts.do("POST", "/api/v1/calendar/feeds", tokViewer, map[string]string{"disciplineId": "bjj"})
+		bjj := decode[model.CalendarFeed](t, rec)
+		ics = ts.do("GET", bjj.Path, "", nil).Body.String()
+		if !strings.Contains(ics, "DTSTART;TZID=America/New_York:20260323T183000\r\n") ||
+			!strings.Contains(ics, "LOCATION:Main Mat\r\n") {
+			t.Errorf("discipline feed:\n%s", ics)
+		}
+
+		// Feeds are revoked by their owner, and sessions go with their curriculum.
+		if rec := ts.do("DELETE", "/api/v1/calendar/feeds/"+bjj.ID, tokJKDEditor, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("delete someone else's feed: got %d", rec.Code)
+		}
+		if rec := ts.do("DELETE", "/api/v1/calendar/feeds/"+bjj.ID, tokViewer, nil); rec.Code != http.StatusNoContent {
+			t.Errorf("delete feed: got %d", rec.Code)
+		}
+		if rec := ts.do("GET", bjj.Path, "", nil); rec.Code != http.StatusNotFound {
+			t.Errorf("revoked feed: got %d", rec.Code)
+		}
+		if rec := ts.do("DELETE", "/api/v1/curricula/"+fixJKDCurricul, tokJKDEditor, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("delete curriculum: got %d", rec.Code)
+		}
+		if _, err := ts.store.Sessions().Get(context.Background(), private.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("session of deleted curriculum: got %v", err)
+		}
+
+		// A session left behind by its curriculum is its owner's to remove.
+		if err := ts.store.Curricula().Delete(context.Background(), fixCurriculum); err != nil {
+			t.Fatal(err)
+		}
+		if rec := ts.do("PATCH", "/api/v1/sessions/"+s.ID, tokAdmin, map[string]string{"location": "Garage"}); rec.Code != http.StatusNotFound {
+			t.Errorf("update orphaned session: got %d", rec.Code)
+		}
+		if rec := ts.do("DELETE", "/api/v1/sessions/"+s.ID, tokAdmin, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("delete another's orphaned session: got %d", rec.Code)
+		}
+		if rec := ts.do("DELETE", "/api/v1/sessions/"+s.ID, tokEditor, nil); rec.Code != http.StatusNoContent {
+			t.Errorf("delete orphaned session: got %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+func TestCurriculumShareLink(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		ctx := context.Background()
+		curr := "/api/v1/curricula/" + fixJKDCurricul
+		str := func(v string) *string { return &v }
+
+		// An asset element whose asset is inactive keeps no snapshot in the
+		// shared copy.
+		if err := ts.store.Elements().Set(ctx, fixJKDCurricul, "elem-pending", &model.CurriculumElement{
+			Type: model.ElementTypeAsset, AssetID: str(fixInactive), Ord: 1,
+			Snapshot: &model.Snapshot{Name: "Processing...", URL: "https://youtu.be/abc"},
+		}); err != nil {
+			t.Fatalf("seed element: %v", err)
+		}
+		if err := ts.store.Elements().Set(ctx, fixJKDCurricul, "elem-lead", &model.CurriculumElement{
+			Type: model.ElementTypeAsset, AssetID: str(fixJKDAsset), Ord: 2,
+			Snapshot: &model.Snapshot{Name: "Straight Lead", URL: "https://example.com/jkd", TagIDs: []string{fixJKDTag}},
+		}); err != nil {
+			t.Fatalf("seed element: %v", err)
+		}
+
+		// Nor does one detached from a deleted asset before detaching
+		// dropped the links.
+		if err := ts.store.Elements().Set(ctx, fixJKDCurricul, "elem-detached", &model.CurriculumElement{
+			Type: model.ElementTypeAsset, Ord: 3,
+			Snapshot: &model.Snapshot{Name: "Deleted Drill", URL: "https://youtu.be/deleted"},
+		}); err != nil {
+			t.Fatalf("seed element: %v", err)
+		}
+
+		rec := ts.do("PUT", curr+"/share", tokJKDEditor, nil)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("share: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		link := decode[model.ShareLink](t, rec)
+		if link.Token == "" || link.Path != "/share/"+link.Token || link.ExpiresAt != nil {
+			t.Fatalf("share: got %+v", link)
+		}
+		if got := decode[model.ShareLink](t, ts.do("GET", curr+"/share", tokJKDEditor, nil)); got != link {
+			t.Errorf("get link: got %+v, want %+v", got, link)
+		}
+
+		// Anyone with the link reads the private curriculum, without library
+		// references or inactive assets.
+		rec = ts.do("GET", link.Path, "", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("shared: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=60" {
+			t.Errorf("Cache-Control = %q", cc)
+		}
+		shared := decode[model.SharedCurriculum](t, rec)
+		if shared.Title != "JKD Basics" || len(shared.Elements) != 3 {
+			t.Fatalf("shared: got %+v", shared)
+		}
+		pending, lead, detached := shared.Elements[0], shared.Elements[1], shared.Elements[2]
+		if pending.Snapshot != nil || pending.AssetID != nil {
+			t.Errorf("inactive asset element leaked: %+v", pending)
+		}
+		if detached.Snapshot != nil {
+			t.Errorf("detached asset element leaked: %+v", detached)
+		}
+		if lead.Snapshot == nil || lead.Snapshot.Name != "Straight Lead" || lead.Snapshot.TagIDs != nil || lead.AssetID != nil {
+			t.Errorf("active asset element: got %+v", lead)
+		}
+		if strings.Contains(rec.Body.String(), tokJKDEditor) {
+			t.Errorf("shared curriculum names its owner: %s", rec.Body.String())
+		}
+
+		// The ETag answers conditional requests.
+		req := httptest.NewRequest("GET", link.Path, nil)
+		req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+		cached := httptest.NewRecorder()
+		ts.h.ServeHTTP(cached, req)
+		if cached.Code != http.StatusNotModified || cached.Body.Len() != 0 {
+			t.Errorf("conditional get: got %d (%s)", cached.Code, cached.Body.String())
+		}
+
+		// Rotating replaces the token; the old one stops working.
+		rec = ts.do("PUT", curr+"/share", tokJKDEditor, map[string]bool{"rotate": true})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("rotate: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		rotated := decode[model.ShareLink](t, rec)
+		if rotated.Token == link.Token {
+			t.Fatalf("rotate kept the token")
+		}
+		if rec := ts.do("GET", link.Path, "", nil); rec.Code != http.StatusNotFound {
+			t.Errorf("old token: got %d", rec.Code)
+		}
+
+		// An expired link is gone.
+		expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		rec = ts.do("PUT", curr+"/share", tokJKDEditor, map[string]time.Time{"expiresAt": expires})
+		if got := decode[model.ShareLink](t, rec); got.Token != rotated.Token || got.ExpiresAt == nil || !got.ExpiresAt.Equal(expires) {
+			t.Fatalf("set expiry: got %+v", got)
+		}
+		if err := ts.store.Curricula().Update(ctx, fixJKDCurricul, []store.Update{
+			{Path: "shareExpiresAt", Value: time.Now().Add(-time.Minute)},
+		}); err != nil {
+			t.Fatalf("expire link: %v", err)
+		}
+		if rec := ts.do("GET", rotated.Path, "", nil); rec.Code != http.StatusGone {
+			t.Errorf("expired link: got %d", rec.Code)
+		}
+
+		// Revoking removes the link.
+		if rec := ts.do("DELETE", curr+"/share", tokJKDEditor, nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("revoke: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		if rec := ts.do("GET", rotated.Path, "", nil); rec.Code != http.StatusNotFound {
+			t.Errorf("revoked link: got %d", rec.Code)
+		}
+		if rec := ts.do("GET", curr+"/share", tokJKDEditor, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("revoked link settings: got %d", rec.Code)
+		}
+	})
+}
+
+func TestShareRateLimit(t *testing.T) {
+	ts := newTestServer(t)
+	var rec *httptest.ResponseRecorder
+	for range 50 {
+		rec = ts.do("GET", "/share/missing", "", nil)
+		if rec.Code == http.StatusTooManyRequests {
+			break
+		}
+	}
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("50 requests: last got %d, want 429", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("429 content type: got %q", ct)
+	}
+}
@@ -0,0 +1,111 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/thomas/skillhive-api/internal/curriculum"
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+func TestElementSnapshotRefresh(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		curr := "/api/v1/curricula/" + fixCurriculum
+
+		add := func(body map[string]string) string {
+			t.Helper()
+			rec := ts.do("POST", curr+"/elements", tokEditor, body)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("create element: got %d (%s)", rec.Code, rec.Body.String())
+			}
+			return decode[model.CurriculumElement](t, rec).ID
+		}
+		tech := add(map[string]string{"type": "technique", "techniqueId": fixTechnique})
+		asset := add(map[string]string{"type": "asset", "assetId": fixAsset})
+
+		statuses := func() map[string]model.CurriculumElement {
+			t.Helper()
+			got := map[string]model.CurriculumElement{}
+			for _, e := range decode[[]model.CurriculumElement](t, ts.do("GET", curr+"/elements", tokViewer, nil)) {
+				got[e.ID] = e
+			}
+			return got
+		}
+		got := statuses()
+		if got[tech].SnapshotStatus != model.SnapshotCurrent || got[asset].SnapshotStatus != model.SnapshotCurrent ||
+			got[fixElement].SnapshotStatus != "" {
+			t.Fatalf("initial status: tech %q, asset %q, text %q",
+				got[tech].SnapshotStatus, got[asset].SnapshotStatus, got[fixElement].SnapshotStatus)
+		}
+		if got[tech].Snapshot.SourceUpdatedAt.IsZero() {
+			t.Errorf("snapshot has no sourceUpdatedAt")
+		}
+
+		// Renaming the technique makes its snapshot stale; deleting the asset
+		// from under the element, which the API would detach, breaks the other.
+		if rec := ts.do("PATCH", "/api/v1/techniques/"+fixTechnique, tokEditor, map[string]string{"name": "Straight Armbar"}); rec.Code != http.StatusOK {
+			t.Fatalf("rename technique: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		if err := ts.store.Assets().Delete(context.Background(), fixAsset); err != nil {
+			t.Fatal(err)
+		}
+		got = statuses()
+		if got[tech].SnapshotStatus != model.SnapshotStale || got[asset].SnapshotStatus != model.SnapshotBroken {
+			t.Fatalf("after changes: tech %q, asset %q", got[tech].SnapshotStatus, got[asset].SnapshotStatus)
+		}
+
+		if rec := ts.do("POST", curr+"/elements/refresh", tokViewer, nil); rec.Code != http.StatusForbidden {
+			t.Errorf("viewer refresh: got %d", rec.Code)
+		}
+		if rec := ts.do("POST", curr+"/elements/refresh", tokEditor, map[string][]string{"elementIds": {"missing"}}); rec.Code != http.StatusNotFound {
+			t.Errorf("refresh unknown element: got %d", rec.Code)
+		}
+		before := decode[model.Curriculum](t, ts.do("GET", curr, tokEditor, nil)).Revision
+		rec := ts.do("POST", curr+"/elements/refresh", tokEditor, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("refresh: got %d (%s)", rec.Code, rec.Body.String())
+		}
+		report := decode[curriculum.RefreshReport](t, rec)
+		if len(report.Refreshed) != 1 || report.Refreshed[0] != tech || len(report.Broken) != 1 || report.Broken[0] != asset {
+			t.Errorf("refresh report: got %+v", report)
+		}
+		got = statuses()
+		if got[tech].SnapshotStatus != model.SnapshotCurrent || got[tech].Snapshot.Name != "Straight Armbar" {
+			t.Errorf("refreshed element: got %+v", got[tech])
+		}
+		if got[asset].Snapshot == nil || got[asset].Snapshot.Name != "Armbar Basics" {
+			t.Errorf("broken element lost its snapshot: %+v", got[asset])
+		}
+		revs := decode[[]model.CurriculumRevision](t, ts.do("GET", curr+"/revisions", tokEditor, nil))
+		if revs[0].Number != before+1 || revs[0].Action != model.RevisionElementRefresh {
+			t.Errorf("latest revision: got %d %q, want %d %q", revs[0].Number, revs[0].Action, before+1, model.RevisionElementRefresh)
+		}
+
+		// Refreshing current snapshots writes nothing.
+		if r := decode[curriculum.RefreshReport](t, ts.do("POST", curr+"/elements/refresh", tokEditor, nil)); len(r.Refreshed) != 0 {
+			t.Errorf("second refresh: got %+v", r)
+		}
+		if n := decode[model.Curriculum](t, ts.do("GET", curr, tokEditor, nil)).Revision; n != before+1 {
+			t.Errorf("second refresh recorded a revision: %d", n)
+		}
+
+		// The sweep refreshes stale snapshots across curricula.
+		if rec := ts.do("PATCH", "/api/v1/techniques/"+fixTechnique, tokEditor, map[string]string{"name": "Juji Gatame"}); rec.Code != http.StatusOK {
+			t.Fatalf("rename technique: got %d", rec.Code)
+		}
+		var reports []curriculum.RefreshReport
+		checked, err := curriculum.RefreshAll(context.Background(), ts.store,
+			func(r curriculum.RefreshReport) { reports = append(reports, r) },
+			func(id string, err error) { t.Errorf("refresh %s: %v", id, err) })
+		if err != nil {
+			t.Fatalf("sweep: %v", err)
+		}
+		if checked != 2 || len(reports) != 1 || reports[0].CurriculumID != fixCurriculum || len(reports[0].Refreshed) != 1 {
+			t.Errorf("sweep: checked %d, reports %+v", checked, reports)
+		}
+		if name := statuses()[tech].Snapshot.Name; name != "Juji Gatame" {
+			t.Errorf("swept snapshot name: got %q", name)
+		}
+	})
+}
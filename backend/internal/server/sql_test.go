@@ -0,0 +1,125 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/store"
+)
+
+// TestSQLMatchesMemory checks that read endpoints return byte-identical JSON
+// on the memory and SQLite backends.
+func TestSQLMatchesMemory(t *testing.T) {
+	mem := newTestServer(t)
+	sqlite := newSQLiteTestServer(t)
+
+	paths := []struct{ path, token string }{
+		{"/api/v1/disciplines", tokViewer},
+		{"/api/v1/tags?disciplineId=bjj", tokViewer},
+		{"/api/v1/categories?disciplineId=bjj", tokViewer},
+		{"/api/v1/categories?disciplineId=bjj&tree=true", tokViewer},
+		{"/api/v1/techniques?disciplineId=bjj", tokViewer},
+		{"/api/v1/techniques?disciplineId=bjj&categoryId=" + fixChildCat, tokViewer},
+		{"/api/v1/techniques/" + fixTechnique, tokViewer},
+		{"/api/v1/assets?disciplineId=bjj", tokViewer},
+		{"/api/v1/assets?disciplineId=bjj&tagId=" + fixTag, tokViewer},
+		{"/api/v1/admin/assets?disciplineId=bjj", tokAdmin},
+		{"/api/v1/curricula?disciplineId=bjj", tokViewer},
+		{"/api/v1/curricula?tagId=" + fixTag, tokViewer},
+		{"/api/v1/curricula/" + fixCurriculum + "/elements", tokViewer},
+	}
+	for _, p := range paths {
+		want := mem.do("GET", p.path, p.token, nil)
+		got := sqlite.do("GET", p.path, p.token, nil)
+		if got.Code != want.Code || got.Body.String() != want.Body.String() {
+			t.Errorf("GET %s:\n sqlite %d %s\n memory %d %s", p.path, got.Code, got.Body, want.Code, want.Body)
+		}
+	}
+}
+
+// TestSQLRoundTrip stores documents with every optional field populated and
+// reads them back unchanged, including the array fields kept in join tables.
+func TestSQLRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.NewSQL(ctx, "sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	now := time.Date(2025, 3, 4, 5, 6, 7, 890, time.UTC)
+	str := func(v string) *string { return &v }
+	asset := model.Asset{
+		DisciplineID: "bjj", URL: "https://youtu.be/x", Title: "T", Description: "D", Type: model.AssetTypeVideo,
+		VideoType: str("short"), Originator: str("Gordon"), ThumbnailURL: str("https://img"), Duration: str("PT1M"),
+		TechniqueIDs: []string{"b", "a", "c"}, CategoryIDs: []string{}, TagIDs: []string{"t1"},
+		OwnerUID: "u", Active: true, ProcessingStatus: "completed", ProcessingError: str(""),
+		CreatedAt: now, UpdatedAt: now,
+	}
+	id, err := s.Assets().Create(ctx, &asset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotAsset, err := s.Assets().Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameJSON(t, gotAsset, &asset)
+
+	elem := model.CurriculumElement{
+		Type: model.ElementTypeList, Title: str("List"), Items: []string{"one", "two"}, Ord: 3,
+		Snapshot:  &model.Snapshot{Name: "N", URL: "https://u", TagIDs: []string{"t2", "t1"}},
+		CreatedAt: now, UpdatedAt: now,
+	}
+	if err := s.Elements().Set(ctx, "c1", "e1", &elem); err != nil {
+		t.Fatal(err)
+	}
+	gotElem, err := s.Elements().Get(ctx, "c1", "e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameJSON(t, gotElem, &elem)
+
+	// Updates rewrite join tables; nested paths allocate the snapshot.
+	if err := s.Elements().Update(ctx, "c1", "e1", []store.Update{
+		{Path: "items", Value: []string{"three"}},
+		{Path: "snapshot.tagIds", Value: nil},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	gotElem, _ = s.Elements().Get(ctx, "c1", "e1")
+	if len(gotElem.Items) != 1 || gotElem.Items[0] != "three" || gotElem.Snapshot == nil || gotElem.Snapshot.TagIDs != nil {
+		t.Errorf("after update: %+v %+v", gotElem, gotElem.Snapshot)
+	}
+
+	// Elements are scoped to their curriculum.
+	if _, err := s.Elements().Get(ctx, "c2", "e1"); err != store.ErrNotFound {
+		t.Errorf("get in other curriculum: got %v, want ErrNotFound", err)
+	}
+	if err := s.Assets().Update(ctx, "missing", nil); err != store.ErrNotFound {
+		t.Errorf("update missing: got %v, want ErrNotFound", err)
+	}
+
+	// A failing batch leaves nothing behind.
+	b := s.Batch()
+	b.Delete(s.Assets().Ref(id))
+	b.Update(s.Assets().Ref("missing"), []store.Update{{Path: "title", Value: "x"}})
+	if err := b.Commit(ctx); err != store.ErrNotFound {
+		t.Errorf("batch: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.Assets().Get(ctx, id); err != nil {
+		t.Errorf("asset after rolled back batch: %v", err)
+	}
+}
+
+func assertSameJSON(t *testing.T, got, want interface{}) {
+	t.Helper()
+	g, _ := json.Marshal(got)
+	w, _ := json.Marshal(want)
+	if string(g) != string(w) {
+		t.Errorf("round trip mismatch:\n got  %s\n want %s", g, w)
+	}
+}
@@ -46,6 +46,32 @@ func NewFirebaseClients(ctx context.Context, projectID, keyPath string) (*Fireba
 	}, nil
 }
 
+// NewFirebaseAuthClients initializes only Firebase Auth. It is used when data
+// lives in a non-Firestore Store, so no Firestore database is required.
+func NewFirebaseAuthClients(ctx context.Context, projectID, keyPath string) (*FirebaseClients, error) {
+	var opts []option.ClientOption
+	if keyPath != "" {
+		opts = append(opts, option.WithCredentialsFile(keyPath))
+	}
+
+	conf := &firebase.Config{ProjectID: projectID}
+	app, err := firebase.NewApp(ctx, conf, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	authClient, err := app.Auth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Firebase auth client initialized", "project", projectID)
+	return &FirebaseClients{
+		App:  app,
+		Auth: authClient,
+	}, nil
+}
+
 func (fc *FirebaseClients) Close() {
 	if fc.Firestore != nil {
 		fc.Firestore.Close()
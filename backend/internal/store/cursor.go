@@ -0,0 +1,161 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// ErrInvalidCursor is returned for malformed cursor tokens and for cursors
+// that do not belong to the query they are used with.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in an ordered query: the values of the ordered
+// fields and the ID of a document. Because the ID breaks every tie, a page
+// that starts after a cursor neither repeats nor skips documents when others
+// are inserted or deleted in between.
+type Cursor struct {
+	Fields []string
+	Values []interface{}
+	ID     string
+}
+
+// CursorAt returns the cursor of doc (a *T or T of the queried collection)
+// with the given ID under the ordering of q.
+func CursorAt(q Query, doc interface{}, id string) Cursor {
+	v := reflect.ValueOf(doc)
+	c := Cursor{ID: id}
+	for _, o := range effectiveOrders(q) {
+		value, _ := lookupField(v, o.Field)
+		c.Fields = append(c.Fields, o.Field)
+		c.Values = append(c.Values, value)
+	}
+	return c
+}
+
+func (c Cursor) matches(orders []Order) error {
+	if len(c.Fields) != len(orders) || len(c.Values) != len(orders) {
+		return fmt.Errorf("%w: ordering does not match", ErrInvalidCursor)
+	}
+	for i, o := range orders {
+		if c.Fields[i] != o.Field {
+			return fmt.Errorf("%w: ordering does not match", ErrInvalidCursor)
+		}
+	}
+	return nil
+}
+
+// cursorValue is the JSON form of one ordered value; exactly one field is
+// set so that the type survives the round trip.
+type cursorValue struct {
+	S *string    `json:"s,omitempty"`
+	I *int64     `json:"i,omitempty"`
+	F *float64   `json:"f,omitempty"`
+	B *bool      `json:"b,omitempty"`
+	T *time.Time `json:"t,omitempty"`
+}
+
+type cursorToken struct {
+	Fields []string       `json:"o"`
+	Values []*cursorValue `json:"v"`
+	ID     string         `json:"id"`
+}
+
+// Encode returns the opaque, URL-safe token form of c.
+func (c Cursor) Encode() string {
+	tok := cursorToken{Fields: c.Fields, Values: make([]*cursorValue, len(c.Values)), ID: c.ID}
+	for i, v := range c.Values {
+		switch x := normalizeAny(v).(type) {
+		case string:
+			tok.Values[i] = &cursorValue{S: &x}
+		case int64:
+			tok.Values[i] = &cursorValue{I: &x}
+		case float64:
+			tok.Values[i] = &cursorValue{F: &x}
+		case bool:
+			tok.Values[i] = &cursorValue{B: &x}
+		case time.Time:
+			x = x.UTC()
+			tok.Values[i] = &cursorValue{T: &x}
+		}
+	}
+	data, _ := json.Marshal(tok)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a token produced by Cursor.Encode.
+func DecodeCursor(token string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var tok cursorToken
+	if err := json.Unmarshal(data, &tok); err != nil || tok.ID == "" || len(tok.Fields) != len(tok.Values) {
+		return Cursor{}, ErrInvalidCursor
+	}
+	c := Cursor{Fields: tok.Fields, Values: make([]interface{}, len(tok.Values)), ID: tok.ID}
+	for i, v := range tok.Values {
+		switch {
+		case v == nil:
+		case v.S != nil:
+			c.Values[i] = *v.S
+		case v.I != nil:
+			c.Values[i] = *v.I
+		case v.F != nil:
+			c.Values[i] = *v.F
+		case v.B != nil:
+			c.Values[i] = *v.B
+		case v.T != nil:
+			c.Values[i] = *v.T
+		}
+	}
+	return c, nil
+}
+
+// compareOrdered compares two documents, given by their ordered values and
+// IDs, in query order: negative when a comes first.
+func compareOrdered(orders []Order, a []interface{}, aID string, b []interface{}, bID string) int {
+	for i, o := range orders {
+		if c := compareValues(a[i], b[i]); c != 0 {
+			if o.Dir == Desc {
+				return -c
+			}
+			return c
+		}
+	}
+	// Ties are broken on document ID in the direction of the last order.
+	c := cmpString(aID, bID)
+	if len(orders) > 0 && orders[len(orders)-1].Dir == Desc {
+		return -c
+	}
+	return c
+}
+
+func cmpString(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// orderedValues returns the values of the ordered fields of a document.
+func orderedValues(v reflect.Value, orders []Order) []interface{} {
+	values := make([]interface{}, len(orders))
+	for i, o := range orders {
+		values[i], _ = lookupField(v, o.Field)
+	}
+	return values
+}
+
+// cursorValues returns the cursor values with the document ID appended, as
+// Firestore's StartAfter expects them.
+func (c Cursor) cursorValues() []interface{} {
+	return append(slices.Clone(c.Values), c.ID)
+}
@@ -0,0 +1,539 @@
+package store
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This file holds the reflection helpers that let non-Firestore backends
+// address model fields by their stored (firestore tag) names, so queries and
+// updates are written identically for every backend.
+
+type storedField struct {
+	index     []int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // reflect.Type -> map[string]storedField
+
+// storedFields returns the firestore-tagged fields of a struct type keyed by
+// their stored name. Fields tagged "-" are skipped.
+func storedFields(t reflect.Type) map[string]storedField {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(map[string]storedField)
+	}
+	fields := make(map[string]storedField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("firestore")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = storedField{index: f.Index, omitEmpty: strings.Contains(opts, "omitempty")}
+	}
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// lookupField resolves a dotted field path on a struct value. present is false
+// when the field would be absent from the stored document (an omitempty zero
+// value or a nil intermediate pointer).
+func lookupField(v reflect.Value, path string) (value interface{}, present bool) {
+	for _, part := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil, false
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return nil, false
+		}
+		sf, ok := storedFields(v.Type())[part]
+		if !ok {
+			return nil, false
+		}
+		v = v.FieldByIndex(sf.index)
+		if sf.omitEmpty && v.IsZero() {
+			return nil, false
+		}
+	}
+	return normalizeValue(v), true
+}
+
+// normalizeValue converts a field value into one of nil, bool, int64,
+// float64, time.Time, string or []interface{} for comparison.
+func normalizeValue(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = normalizeValue(v.Index(i))
+		}
+		return out
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			return t
+		}
+	}
+	return v.Interface()
+}
+
+// typeRank orders values of different types the way Firestore does.
+func typeRank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case int64, float64:
+		return 2
+	case time.Time:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	default:
+		return 6
+	}
+}
+
+// compareValues returns -1, 0 or 1. Both arguments must be normalized.
+func compareValues(a, b interface{}) int {
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		return cmpInt(ra, rb)
+	}
+	switch av := a.(type) {
+	case nil:
+		return 0
+	case bool:
+		bv := b.(bool)
+		switch {
+		case av == bv:
+			return 0
+		case !av:
+			return -1
+		default:
+			return 1
+		}
+	case int64, float64:
+		af, bf := toFloat(a), toFloat(b)
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	case time.Time:
+		bv := b.(time.Time)
+		switch {
+		case av.Before(bv):
+			return -1
+		case av.After(bv):
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(av, b.(string))
+	case []interface{}:
+		bv := b.([]interface{})
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if c := compareValues(av[i], bv[i]); c != 0 {
+				return c
+			}
+		}
+		return cmpInt(len(av), len(bv))
+	}
+	return 0
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+// normalizeAny normalizes an arbitrary filter argument.
+func normalizeAny(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return normalizeValue(reflect.ValueOf(v))
+}
+
+// matchFilter evaluates a single filter against a struct value.
+func matchFilter(doc reflect.Value, f Filter) bool {
+	value, present := lookupField(doc, f.Field)
+	if !present {
+		return false
+	}
+	arg := normalizeAny(f.Value)
+	switch f.Op {
+	case OpEqual:
+		return compareValues(value, arg) == 0
+	case OpNotEqual:
+		return value != nil && compareValues(value, arg) != 0
+	case OpLess, OpLessEqual, OpGreater, OpGreaterEqual:
+		if typeRank(value) != typeRank(arg) {
+			return false
+		}
+		c := compareValues(value, arg)
+		switch f.Op {
+		case OpLess:
+			return c < 0
+		case OpLessEqual:
+			return c <= 0
+		case OpGreater:
+			return c > 0
+		default:
+			return c >= 0
+		}
+	case OpIn:
+		for _, candidate := range arg.([]interface{}) {
+			if compareValues(value, candidate) == 0 {
+				return true
+			}
+		}
+		return false
+	case OpArrayContains:
+		items, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range items {
+			if compareValues(item, arg) == 0 {
+				return true
+			}
+		}
+		return false
+	case OpArrayContainsAny:
+		items, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range items {
+			for _, candidate := range arg.([]interface{}) {
+				if compareValues(item, candidate) == 0 {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// ApplyUpdates applies updates to a document held in memory, such as one
+// read in a Tx, to see what the stored document will look like.
+func ApplyUpdates(doc interface{}, updates []Update) error {
+	ptr := reflect.ValueOf(doc)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("store: cannot update %T", doc)
+	}
+	for _, u := range updates {
+		if err := applyUpdate(ptr, u.Path, u.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyUpdate sets the field at a dotted path on the struct pointed to by ptr.
+func applyUpdate(ptr reflect.Value, path string, value interface{}) error {
+	v := ptr
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return fmt.Errorf("store: cannot update %s: %s is not an object", path, strings.Join(parts[:i], "."))
+		}
+		sf, ok := storedFields(v.Type())[part]
+		if !ok {
+			return fmt.Errorf("store: unknown field %s", path)
+		}
+		v = v.FieldByIndex(sf.index)
+	}
+	if err := assignValue(v, value); err != nil {
+		return fmt.Errorf("store: cannot update %s: %w", path, err)
+	}
+	return nil
+}
+
+// assignValue stores value into dst, converting between pointer and value
+// forms and between compatible element types.
+func assignValue(dst reflect.Value, value interface{}) error {
+	if value == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	src := reflect.ValueOf(cloneValue(value))
+	return assignReflect(dst, src)
+}
+
+func assignReflect(dst, src reflect.Value) error {
+	dt := dst.Type()
+	switch {
+	case src.Type().AssignableTo(dt):
+		dst.Set(src)
+		return nil
+	case src.Kind() == reflect.Ptr:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dt))
+			return nil
+		}
+		return assignReflect(dst, src.Elem())
+	case dt.Kind() == reflect.Ptr:
+		elem := reflect.New(dt.Elem())
+		if err := assignReflect(elem.Elem(), src); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	case src.Kind() == reflect.Interface:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dt))
+			return nil
+		}
+		return assignReflect(dst, src.Elem())
+	case dt.Kind() == reflect.Slice && src.Kind() == reflect.Slice:
+		out := reflect.MakeSlice(dt, src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			if err := assignReflect(out.Index(i), src.Index(i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(out)
+		return nil
+	case sameKindFamily(src.Kind(), dt.Kind()):
+		dst.Set(src.Convert(dt))
+		return nil
+	}
+	return fmt.Errorf("type %s is not assignable to %s", src.Type(), dt)
+}
+
+// cloneValue returns a deep copy of v so stored documents never alias
+// caller-owned memory.
+func cloneValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return deepCopy(reflect.ValueOf(v)).Interface()
+}
+
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(deepCopy(v.Elem()))
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return out
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(deepCopy(v.Elem()))
+		return out
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			out.Field(i).Set(deepCopy(v.Field(i)))
+		}
+		return out
+	}
+	return v
+}
+
+// sameKindFamily reports whether values of kind a convert losslessly enough
+// to kind b (numbers to numbers, strings to named string types).
+func sameKindFamily(a, b reflect.Kind) bool {
+	return (isNumberKind(a) && isNumberKind(b)) ||
+		(a == reflect.String && b == reflect.String) ||
+		(a == reflect.Bool && b == reflect.Bool)
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// DecodeDocument fills the struct pointed to by dst from a document map keyed
+// by stored field names, as found in Firestore JSON exports. Values are
+// converted to the field types (RFC 3339 strings to time.Time, JSON numbers
+// to ints, nested maps to structs). Keys without a matching field are
+// returned so callers can report them.
+func DecodeDocument(data map[string]interface{}, dst interface{}) (skipped []string, err error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("store: DecodeDocument needs a struct pointer, got %T", dst)
+	}
+	return decodeStruct(v.Elem(), data, "")
+}
+
+func decodeStruct(v reflect.Value, data map[string]interface{}, prefix string) ([]string, error) {
+	var skipped []string
+	fields := storedFields(v.Type())
+	for key, value := range data {
+		sf, ok := fields[key]
+		if !ok {
+			skipped = append(skipped, prefix+key)
+			continue
+		}
+		more, err := decodeLoose(v.FieldByIndex(sf.index), value, prefix+key)
+		if err != nil {
+			return nil, err
+		}
+		skipped = append(skipped, more...)
+	}
+	sort.Strings(skipped)
+	return skipped, nil
+}
+
+func decodeLoose(dst reflect.Value, value interface{}, path string) ([]string, error) {
+	if value == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil, nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		elem := reflect.New(dst.Type().Elem())
+		skipped, err := decodeLoose(elem.Elem(), value, path)
+		if err != nil {
+			return nil, err
+		}
+		dst.Set(elem)
+		return skipped, nil
+	}
+	if dst.Type() == reflect.TypeOf(time.Time{}) {
+		switch t := value.(type) {
+		case time.Time:
+			dst.Set(reflect.ValueOf(t))
+			return nil, nil
+		case string:
+			parsed, err := time.Parse(time.RFC3339Nano, t)
+			if err != nil {
+				return nil, fmt.Errorf("store: %s: invalid timestamp %q", path, t)
+			}
+			dst.Set(reflect.ValueOf(parsed))
+			return nil, nil
+		}
+	}
+	switch dst.Kind() {
+	case reflect.Struct:
+		if m, ok := value.(map[string]interface{}); ok {
+			return decodeStruct(dst, m, path+".")
+		}
+	case reflect.Slice:
+		if items, ok := value.([]interface{}); ok {
+			out := reflect.MakeSlice(dst.Type(), len(items), len(items))
+			for i, item := range items {
+				if _, err := decodeLoose(out.Index(i), item, path); err != nil {
+					return nil, err
+				}
+			}
+			dst.Set(out)
+			return nil, nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch n := value.(type) {
+		case float64:
+			dst.SetInt(int64(n))
+			return nil, nil
+		case int64:
+			dst.SetInt(n)
+			return nil, nil
+		}
+	}
+	if err := assignValue(dst, value); err != nil {
+		return nil, fmt.Errorf("store: %s: %w", path, err)
+	}
+	return nil, nil
+}
+
+// setDocID sets the ID field of a model document, which is not stored.
+func setDocID(doc reflect.Value, id string) {
+	if f := reflect.Indirect(doc).FieldByName("ID"); f.IsValid() && f.Kind() == reflect.String {
+		f.SetString(id)
+	}
+}
@@ -0,0 +1,418 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+
+	"cloud.google.com/go/firestore"
+	"github.com/thomas/skillhive-api/internal/model"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreStore is the production Store backed by Cloud Firestore.
+type FirestoreStore struct {
+	fs *firestore.Client
+}
+
+// NewFirestore wraps an existing Firestore client. The caller keeps ownership
+// of the client; Close is a no-op.
+func NewFirestore(fs *firestore.Client) *FirestoreStore {
+	return &FirestoreStore{fs: fs}
+}
+
+// Client exposes the underlying Firestore client for maintenance commands.
+func (s *FirestoreStore) Client() *firestore.Client { return s.fs }
+
+func (s *FirestoreStore) Disciplines() DisciplineRepo {
+	return &fsRepo[model.Discipline]{fsDocs[model.Discipline]{s.fs, func(d *model.Discipline, id string) { d.ID = id }}, CollDisciplines}
+}
+
+func (s *FirestoreStore) Tags() TagRepo {
+	return &fsRepo[model.Tag]{fsDocs[model.Tag]{s.fs, func(t *model.Tag, id string) { t.ID = id }}, CollTags}
+}
+
+func (s *FirestoreStore) Categories() CategoryRepo {
+	return &fsRepo[model.Category]{fsDocs[model.Category]{s.fs, func(c *model.Category, id string) { c.ID = id }}, CollCategories}
+}
+
+func (s *FirestoreStore) Techniques() TechniqueRepo {
+	return &fsRepo[model.Technique]{fsDocs[model.Technique]{s.fs, func(t *model.Technique, id string) { t.ID = id }}, CollTechniques}
+}
+
+func (s *FirestoreStore) Assets() AssetRepo {
+	return &fsRepo[model.Asset]{fsDocs[model.Asset]{s.fs, func(a *model.Asset, id string) { a.ID = id }}, CollAssets}
+}
+
+func (s *FirestoreStore) Curricula() CurriculumRepo {
+	return &fsRepo[model.Curriculum]{fsDocs[model.Curriculum]{s.fs, func(c *model.Curriculum, id string) { c.ID = id }}, CollCurricula}
+}
+
+func (s *FirestoreStore) Elements() ElementRepo {
+	return &fsSubRepo[model.CurriculumElement]{fsDocs[model.CurriculumElement]{s.fs, func(e *model.CurriculumElement, id string) { e.ID = id }}, ElementsPath}
+}
+
+func (s *FirestoreStore) Revisions() RevisionRepo {
+	return &fsSubRepo[model.CurriculumRevision]{fsDocs[model.CurriculumRevision]{s.fs, func(r *model.CurriculumRevision, id string) { r.ID = id }}, RevisionsPath}
+}
+
+func (s *FirestoreStore) Sessions() SessionRepo {
+	return &fsRepo[model.Session]{fsDocs[model.Session]{s.fs, func(x *model.Session, id string) { x.ID = id }}, CollSessions}
+}
+
+func (s *FirestoreStore) CalendarFeeds() CalendarFeedRepo {
+	return &fsRepo[model.CalendarFeed]{fsDocs[model.CalendarFeed]{s.fs, func(f *model.CalendarFeed, id string) { f.ID = id }}, CollCalendarFeeds}
+}
+
+func (s *FirestoreStore) Progress() ProgressRepo {
+	return &fsRepo[model.Progress]{fsDocs[model.Progress]{s.fs, func(p *model.Progress, id string) { p.ID = id }}, CollProgress}
+}
+
+func (s *FirestoreStore) TechniqueEdges() TechniqueEdgeRepo {
+	return &fsRepo[model.TechniqueEdge]{fsDocs[model.TechniqueEdge]{s.fs, func(e *model.TechniqueEdge, id string) { e.ID = id }}, CollTechniqueEdges}
+}
+
+func (s *FirestoreStore) Batch() Batch {
+	return &fsBatch{fs: s.fs, batch: s.fs.Batch()}
+}
+
+func (s *FirestoreStore) RunTransaction(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	err := s.fs.RunTransaction(ctx, func(ctx context.Context, ftx *firestore.Transaction) error {
+		t := &fsTx{fs: s.fs, tx: ftx}
+		if err := fn(ctx, t); err != nil {
+			return err
+		}
+		if t.n > MaxBatchSize {
+			return ErrBatchTooLarge
+		}
+		return t.err
+	})
+	return mapFirestoreError(err)
+}
+
+func (s *FirestoreStore) Close() error { return nil }
+
+// fsDocs implements document operations for any collection path.
+type fsDocs[T any] struct {
+	fs    *firestore.Client
+	setID func(*T, string)
+}
+
+func (d fsDocs[T]) get(ctx context.Context, path, id string) (*T, error) {
+	doc, err := d.fs.Collection(path).Doc(id).Get(ctx)
+	if err != nil {
+		return nil, mapFirestoreError(err)
+	}
+	var v T
+	if err := doc.DataTo(&v); err != nil {
+		return nil, err
+	}
+	d.setID(&v, doc.Ref.ID)
+	return &v, nil
+}
+
+func (d fsDocs[T]) list(ctx context.Context, path string, q Query) ([]T, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	iter := firestoreQuery(d.fs, path, q).Documents(ctx)
+	defer iter.Stop()
+
+	var out []T
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var v T
+		if err := doc.DataTo(&v); err != nil {
+			slog.Error("failed to parse document", "path", path, "docID", doc.Ref.ID, "error", err)
+			continue
+		}
+		d.setID(&v, doc.Ref.ID)
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// firestoreQuery translates a validated Query on a collection path.
+func firestoreQuery(fs *firestore.Client, path string, q Query) firestore.Query {
+	fq := fs.Collection(path).Query
+	for _, f := range q.filters {
+		fq = fq.Where(f.Field, f.Op, f.Value)
+	}
+	orders := q.orders
+	if q.after != nil {
+		// The cursor needs the implicit ordering spelled out.
+		orders = effectiveOrders(q)
+	}
+	lastDir := firestore.Asc
+	for _, o := range orders {
+		dir := firestore.Asc
+		if o.Dir == Desc {
+			dir = firestore.Desc
+		}
+		fq = fq.OrderBy(o.Field, dir)
+		lastDir = dir
+	}
+	if q.after != nil {
+		// Firestore breaks ties on the document name in the direction of
+		// the last order, so the existing indexes cover this.
+		fq = fq.OrderBy(firestore.DocumentID, lastDir).StartAfter(q.after.cursorValues()...)
+	}
+	if q.limit > 0 {
+		fq = fq.Limit(q.limit)
+	}
+	if q.offset > 0 {
+		fq = fq.Offset(q.offset)
+	}
+	return fq
+}
+
+func (d fsDocs[T]) create(ctx context.Context, path string, doc *T) (string, error) {
+	ref, _, err := d.fs.Collection(path).Add(ctx, doc)
+	if err != nil {
+		return "", err
+	}
+	d.setID(doc, ref.ID)
+	return ref.ID, nil
+}
+
+func (d fsDocs[T]) set(ctx context.Context, path, id string, doc *T) error {
+	if _, err := d.fs.Collection(path).Doc(id).Set(ctx, doc); err != nil {
+		return err
+	}
+	d.setID(doc, id)
+	return nil
+}
+
+func (d fsDocs[T]) update(ctx context.Context, path, id string, updates []Update) error {
+	_, err := d.fs.Collection(path).Doc(id).Update(ctx, toFirestoreUpdates(updates))
+	return mapFirestoreError(err)
+}
+
+func (d fsDocs[T]) delete(ctx context.Context, path, id string) error {
+	_, err := d.fs.Collection(path).Doc(id).Delete(ctx)
+	return err
+}
+
+// fsRepo is a Repo over a top-level Firestore collection.
+type fsRepo[T any] struct {
+	docs fsDocs[T]
+	coll string
+}
+
+func (r *fsRepo[T]) Get(ctx context.Context, id string) (*T, error) {
+	return r.docs.get(ctx, r.coll, id)
+}
+
+func (r *fsRepo[T]) GetAll(ctx context.Context, ids []string) ([]T, error) {
+	refs := make([]*firestore.DocumentRef, len(ids))
+	for i, id := range ids {
+		refs[i] = r.docs.fs.Collection(r.coll).Doc(id)
+	}
+	snaps, err := r.docs.fs.GetAll(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	out := []T{}
+	for _, doc := range snaps {
+		if !doc.Exists() {
+			continue
+		}
+		var v T
+		if err := doc.DataTo(&v); err != nil {
+			return nil, err
+		}
+		r.docs.setID(&v, doc.Ref.ID)
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (r *fsRepo[T]) List(ctx context.Context, q Query) ([]T, error) {
+	return r.docs.list(ctx, r.coll, q)
+}
+
+func (r *fsRepo[T]) Create(ctx context.Context, doc *T) (string, error) {
+	return r.docs.create(ctx, r.coll, doc)
+}
+
+func (r *fsRepo[T]) Set(ctx context.Context, id string, doc *T) error {
+	return r.docs.set(ctx, r.coll, id, doc)
+}
+
+func (r *fsRepo[T]) Update(ctx context.Context, id string, updates []Update) error {
+	return r.docs.update(ctx, r.coll, id, updates)
+}
+
+func (r *fsRepo[T]) Delete(ctx context.Context, id string) error {
+	return r.docs.delete(ctx, r.coll, id)
+}
+
+func (r *fsRepo[T]) Ref(id string) DocRef {
+	return DocRef{Collection: r.coll, ID: id}
+}
+
+// fsSubRepo is a repository over a subcollection of curricula, such as
+// curricula/{id}/elements.
+type fsSubRepo[T any] struct {
+	docs fsDocs[T]
+	path func(curriculumID string) string
+}
+
+func (r *fsSubRepo[T]) Get(ctx context.Context, curriculumID, id string) (*T, error) {
+	return r.docs.get(ctx, r.path(curriculumID), id)
+}
+
+func (r *fsSubRepo[T]) List(ctx context.Context, curriculumID string, q Query) ([]T, error) {
+	return r.docs.list(ctx, r.path(curriculumID), q)
+}
+
+func (r *fsSubRepo[T]) Create(ctx context.Context, curriculumID string, doc *T) (string, error) {
+	return r.docs.create(ctx, r.path(curriculumID), doc)
+}
+
+func (r *fsSubRepo[T]) Set(ctx context.Context, curriculumID, id string, doc *T) error {
+	return r.docs.set(ctx, r.path(curriculumID), id, doc)
+}
+
+func (r *fsSubRepo[T]) Update(ctx context.Context, curriculumID, id string, updates []Update) error {
+	return r.docs.update(ctx, r.path(curriculumID), id, updates)
+}
+
+func (r *fsSubRepo[T]) Delete(ctx context.Context, curriculumID, id string) error {
+	return r.docs.delete(ctx, r.path(curriculumID), id)
+}
+
+func (r *fsSubRepo[T]) Ref(curriculumID, id string) DocRef {
+	return DocRef{Collection: r.path(curriculumID), ID: id}
+}
+
+// fsBatch wraps a Firestore WriteBatch.
+type fsBatch struct {
+	fs    *firestore.Client
+	batch *firestore.WriteBatch
+	n     int
+}
+
+func (b *fsBatch) Set(ref DocRef, doc interface{}) {
+	b.batch.Set(b.fs.Doc(ref.Path()), doc)
+	b.n++
+}
+
+func (b *fsBatch) Update(ref DocRef, updates []Update) {
+	b.batch.Update(b.fs.Doc(ref.Path()), toFirestoreUpdates(updates))
+	b.n++
+}
+
+func (b *fsBatch) Delete(ref DocRef) {
+	b.batch.Delete(b.fs.Doc(ref.Path()))
+	b.n++
+}
+
+func (b *fsBatch) Len() int { return b.n }
+
+func (b *fsBatch) Commit(ctx context.Context) error {
+	if b.n == 0 {
+		return nil
+	}
+	if b.n > MaxBatchSize {
+		return ErrBatchTooLarge
+	}
+	_, err := b.batch.Commit(ctx)
+	return mapFirestoreError(err)
+}
+
+func toFirestoreUpdates(updates []Update) []firestore.Update {
+	out := make([]firestore.Update, len(updates))
+	for i, u := range updates {
+		out[i] = firestore.Update{Path: u.Path, Value: u.Value}
+	}
+	return out
+}
+
+func mapFirestoreError(err error) error {
+	if err != nil && status.Code(err) == codes.NotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+// fsTx adapts a Firestore transaction, which enforces reads before writes
+// itself. Write errors are reported when the transaction function returns.
+type fsTx struct {
+	fs  *firestore.Client
+	tx  *firestore.Transaction
+	n   int
+	err error
+}
+
+func (t *fsTx) Get(ref DocRef, dst interface{}) error {
+	if t.n > 0 {
+		return ErrReadAfterWrite
+	}
+	doc, err := t.tx.Get(t.fs.Doc(ref.Path()))
+	if err != nil {
+		return mapFirestoreError(err)
+	}
+	if err := doc.DataTo(dst); err != nil {
+		return err
+	}
+	setDocID(reflect.ValueOf(dst), ref.ID)
+	return nil
+}
+
+func (t *fsTx) List(collection string, q Query, dst interface{}) error {
+	if t.n > 0 {
+		return ErrReadAfterWrite
+	}
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	out := reflect.ValueOf(dst)
+	if out.Kind() != reflect.Ptr || out.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("store: cannot list into %T", dst)
+	}
+	docs, err := t.tx.Documents(firestoreQuery(t.fs, collection, q)).GetAll()
+	if err != nil {
+		return err
+	}
+	items := reflect.MakeSlice(out.Elem().Type(), 0, len(docs))
+	for _, doc := range docs {
+		v := reflect.New(out.Elem().Type().Elem())
+		if err := doc.DataTo(v.Interface()); err != nil {
+			slog.Error("failed to parse document", "path", collection, "docID", doc.Ref.ID, "error", err)
+			continue
+		}
+		setDocID(v, doc.Ref.ID)
+		items = reflect.Append(items, v.Elem())
+	}
+	out.Elem().Set(items)
+	return nil
+}
+
+func (t *fsTx) Set(ref DocRef, doc interface{}) {
+	t.record(t.tx.Set(t.fs.Doc(ref.Path()), doc))
+}
+
+func (t *fsTx) Update(ref DocRef, updates []Update) {
+	t.record(t.tx.Update(t.fs.Doc(ref.Path()), toFirestoreUpdates(updates)))
+}
+
+func (t *fsTx) Delete(ref DocRef) {
+	t.record(t.tx.Delete(t.fs.Doc(ref.Path())))
+}
+
+func (t *fsTx) record(err error) {
+	t.n++
+	if t.err == nil {
+		t.err = err
+	}
+}
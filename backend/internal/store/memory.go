@@ -0,0 +1,601 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+// MemoryStore is a process-local Store for development and tests. It applies
+// the same query validation, filter, ordering and batch semantics as
+// Firestore, so handlers cannot tell the two apart.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	colls map[string]memCollection
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *MemoryStore {
+	return &MemoryStore{colls: make(map[string]memCollection)}
+}
+
+func (s *MemoryStore) Disciplines() DisciplineRepo {
+	return &memRepo[model.Discipline]{s, CollDisciplines, func(d *model.Discipline, id string) { d.ID = id }}
+}
+
+func (s *MemoryStore) Tags() TagRepo {
+	return &memRepo[model.Tag]{s, CollTags, func(t *model.Tag, id string) { t.ID = id }}
+}
+
+func (s *MemoryStore) Categories() CategoryRepo {
+	return &memRepo[model.Category]{s, CollCategories, func(c *model.Category, id string) { c.ID = id }}
+}
+
+func (s *MemoryStore) Techniques() TechniqueRepo {
+	return &memRepo[model.Technique]{s, CollTechniques, func(t *model.Technique, id string) { t.ID = id }}
+}
+
+func (s *MemoryStore) Assets() AssetRepo {
+	return &memRepo[model.Asset]{s, CollAssets, func(a *model.Asset, id string) { a.ID = id }}
+}
+
+func (s *MemoryStore) Curricula() CurriculumRepo {
+	return &memRepo[model.Curriculum]{s, CollCurricula, func(c *model.Curriculum, id string) { c.ID = id }}
+}
+
+func (s *MemoryStore) Elements() ElementRepo {
+	return &memSubRepo[model.CurriculumElement]{memRepo[model.CurriculumElement]{s, "", func(e *model.CurriculumElement, id string) { e.ID = id }}, ElementsPath}
+}
+
+func (s *MemoryStore) Revisions() RevisionRepo {
+	return &memSubRepo[model.CurriculumRevision]{memRepo[model.CurriculumRevision]{s, "", func(r *model.CurriculumRevision, id string) { r.ID = id }}, RevisionsPath}
+}
+
+func (s *MemoryStore) Sessions() SessionRepo {
+	return &memRepo[model.Session]{s, CollSessions, func(x *model.Session, id string) { x.ID = id }}
+}
+
+func (s *MemoryStore) CalendarFeeds() CalendarFeedRepo {
+	return &memRepo[model.CalendarFeed]{s, CollCalendarFeeds, func(f *model.CalendarFeed, id string) { f.ID = id }}
+}
+
+func (s *MemoryStore) Progress() ProgressRepo {
+	return &memRepo[model.Progress]{s, CollProgress, func(p *model.Progress, id string) { p.ID = id }}
+}
+
+func (s *MemoryStore) TechniqueEdges() TechniqueEdgeRepo {
+	return &memRepo[model.TechniqueEdge]{s, CollTechniqueEdges, func(e *model.TechniqueEdge, id string) { e.ID = id }}
+}
+
+func (s *MemoryStore) Batch() Batch {
+	return &memBatch{s: s}
+}
+
+func (s *MemoryStore) RunTransaction(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	// Holding the write lock serializes transactions with every other
+	// access, so fn never has to be retried.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx := &memTx{s: s}
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+	return s.apply(tx.ops)
+}
+
+func (s *MemoryStore) Close() error { return nil }
+
+// memCollection is the type-erased view of a collection used by batches.
+type memCollection interface {
+	setAny(id string, doc interface{}) error
+	update(id string, updates []Update) error
+	remove(id string)
+	// snapshot captures a document so a failed batch can restore it.
+	snapshot(id string) (doc interface{}, exists bool)
+	restore(id string, doc interface{}, exists bool)
+	// getAny and queryAny return a *T and a []T copied out of the store.
+	getAny(id string) (doc interface{}, exists bool)
+	queryAny(q Query) interface{}
+}
+
+// memDocs holds the documents of one collection path.
+type memDocs[T any] struct {
+	docs  map[string]*T
+	setID func(*T, string)
+}
+
+func (c *memDocs[T]) setAny(id string, doc interface{}) error {
+	var v T
+	switch d := doc.(type) {
+	case T:
+		v = d
+	case *T:
+		v = *d
+	default:
+		return fmt.Errorf("store: cannot store %T in collection of %T", doc, v)
+	}
+	c.put(id, &v)
+	return nil
+}
+
+func (c *memDocs[T]) put(id string, doc *T) {
+	stored := cloneValue(doc).(*T)
+	c.setID(stored, "")
+	c.docs[id] = stored
+}
+
+func (c *memDocs[T]) update(id string, updates []Update) error {
+	doc, ok := c.docs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	updated := cloneValue(doc).(*T)
+	for _, u := range updates {
+		if err := applyUpdate(reflect.ValueOf(updated), u.Path, u.Value); err != nil {
+			return err
+		}
+	}
+	c.docs[id] = updated
+	return nil
+}
+
+func (c *memDocs[T]) remove(id string) {
+	delete(c.docs, id)
+}
+
+func (c *memDocs[T]) snapshot(id string) (interface{}, bool) {
+	doc, ok := c.docs[id]
+	return doc, ok
+}
+
+func (c *memDocs[T]) restore(id string, doc interface{}, exists bool) {
+	if !exists {
+		delete(c.docs, id)
+		return
+	}
+	c.docs[id] = doc.(*T)
+}
+
+func (c *memDocs[T]) getAny(id string) (interface{}, bool) {
+	return c.get(id)
+}
+
+func (c *memDocs[T]) queryAny(q Query) interface{} {
+	return c.query(q)
+}
+
+func (c *memDocs[T]) get(id string) (*T, bool) {
+	doc, ok := c.docs[id]
+	if !ok {
+		return nil, false
+	}
+	out := cloneValue(doc).(*T)
+	c.setID(out, id)
+	return out, true
+}
+
+func (c *memDocs[T]) query(q Query) []T {
+	type entry struct {
+		id  string
+		doc *T
+	}
+	var matched []entry
+	for id, doc := range c.docs {
+		v := reflect.ValueOf(doc)
+		ok := true
+		for _, f := range q.filters {
+			if !matchFilter(v, f) {
+				ok = false
+				break
+			}
+		}
+		// Like Firestore, documents missing an ordered field are excluded.
+		for _, o := range q.orders {
+			if _, present := lookupField(v, o.Field); !present {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matched = append(matched, entry{id, doc})
+		}
+	}
+
+	orders := effectiveOrders(q)
+	keys := make(map[string][]interface{}, len(matched))
+	for _, m := range matched {
+		keys[m.id] = orderedValues(reflect.ValueOf(m.doc), orders)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i].id, matched[j].id
+		return compareOrdered(orders, keys[a], a, keys[b], b) < 0
+	})
+
+	if c := q.after; c != nil {
+		start := sort.Search(len(matched), func(i int) bool {
+			id := matched[i].id
+			return compareOrdered(orders, keys[id], id, c.Values, c.ID) > 0
+		})
+		matched = matched[start:]
+	}
+
+	if q.offset > 0 {
+		if q.offset >= len(matched) {
+			matched = nil
+		} else {
+			matched = matched[q.offset:]
+		}
+	}
+	if q.limit > 0 && len(matched) > q.limit {
+		matched = matched[:q.limit]
+	}
+
+	out := make([]T, 0, len(matched))
+	for _, m := range matched {
+		v := cloneValue(m.doc).(*T)
+		c.setID(v, m.id)
+		out = append(out, *v)
+	}
+	return out
+}
+
+// effectiveOrders adds the implicit ordering Firestore applies: a query with
+// a range filter and no explicit order is sorted by the range field.
+func effectiveOrders(q Query) []Order {
+	if len(q.orders) > 0 {
+		return q.orders
+	}
+	for _, f := range q.filters {
+		switch f.Op {
+		case OpNotEqual, OpLess, OpLessEqual, OpGreater, OpGreaterEqual:
+			return []Order{{Field: f.Field, Dir: Asc}}
+		}
+	}
+	return nil
+}
+
+// collectionOf returns the typed collection at path, creating it on demand.
+// The caller must hold s.mu for writing when create is true.
+func collectionOf[T any](s *MemoryStore, path string, setID func(*T, string), create bool) *memDocs[T] {
+	if c, ok := s.colls[path]; ok {
+		return c.(*memDocs[T])
+	}
+	c := &memDocs[T]{docs: make(map[string]*T), setID: setID}
+	if create {
+		s.colls[path] = c
+	}
+	return c
+}
+
+// memRepo implements Repo over a collection path.
+type memRepo[T any] struct {
+	s     *MemoryStore
+	coll  string
+	setID func(*T, string)
+}
+
+func (r *memRepo[T]) getAt(path, id string) (*T, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	doc, ok := collectionOf(r.s, path, r.setID, false).get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return doc, nil
+}
+
+func (r *memRepo[T]) listAt(path string, q Query) ([]T, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return collectionOf(r.s, path, r.setID, false).query(q), nil
+}
+
+func (r *memRepo[T]) setAt(path, id string, doc *T) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	collectionOf(r.s, path, r.setID, true).put(id, doc)
+	r.setID(doc, id)
+	return nil
+}
+
+func (r *memRepo[T]) createAt(path string, doc *T) (string, error) {
+	id, err := newDocumentID()
+	if err != nil {
+		return "", err
+	}
+	return id, r.setAt(path, id, doc)
+}
+
+func (r *memRepo[T]) updateAt(path, id string, updates []Update) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return collectionOf(r.s, path, r.setID, true).update(id, updates)
+}
+
+func (r *memRepo[T]) deleteAt(path, id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	collectionOf(r.s, path, r.setID, true).remove(id)
+	return nil
+}
+
+func (r *memRepo[T]) Get(_ context.Context, id string) (*T, error) {
+	return r.getAt(r.coll, id)
+}
+
+func (r *memRepo[T]) GetAll(_ context.Context, ids []string) ([]T, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	c := collectionOf(r.s, r.coll, r.setID, false)
+	out := []T{}
+	for _, id := range ids {
+		if doc, ok := c.get(id); ok {
+			out = append(out, *doc)
+		}
+	}
+	return out, nil
+}
+
+func (r *memRepo[T]) List(_ context.Context, q Query) ([]T, error) {
+	return r.listAt(r.coll, q)
+}
+
+func (r *memRepo[T]) Create(_ context.Context, doc *T) (string, error) {
+	return r.createAt(r.coll, doc)
+}
+
+func (r *memRepo[T]) Set(_ context.Context, id string, doc *T) error {
+	return r.setAt(r.coll, id, doc)
+}
+
+func (r *memRepo[T]) Update(_ context.Context, id string, updates []Update) error {
+	return r.updateAt(r.coll, id, updates)
+}
+
+func (r *memRepo[T]) Delete(_ context.Context, id string) error {
+	return r.deleteAt(r.coll, id)
+}
+
+func (r *memRepo[T]) Ref(id string) DocRef {
+	return DocRef{Collection: r.coll, ID: id}
+}
+
+// memSubRepo implements a subcollection repository of curricula.
+type memSubRepo[T any] struct {
+	repo memRepo[T]
+	path func(curriculumID string) string
+}
+
+func (r *memSubRepo[T]) Get(_ context.Context, curriculumID, id string) (*T, error) {
+	return r.repo.getAt(r.path(curriculumID), id)
+}
+
+func (r *memSubRepo[T]) List(_ context.Context, curriculumID string, q Query) ([]T, error) {
+	return r.repo.listAt(r.path(curriculumID), q)
+}
+
+func (r *memSubRepo[T]) Create(_ context.Context, curriculumID string, doc *T) (string, error) {
+	return r.repo.createAt(r.path(curriculumID), doc)
+}
+
+func (r *memSubRepo[T]) Set(_ context.Context, curriculumID, id string, doc *T) error {
+	return r.repo.setAt(r.path(curriculumID), id, doc)
+}
+
+func (r *memSubRepo[T]) Update(_ context.Context, curriculumID, id string, updates []Update) error {
+	return r.repo.updateAt(r.path(curriculumID), id, updates)
+}
+
+func (r *memSubRepo[T]) Delete(_ context.Context, curriculumID, id string) error {
+	return r.repo.deleteAt(r.path(curriculumID), id)
+}
+
+func (r *memSubRepo[T]) Ref(curriculumID, id string) DocRef {
+	return DocRef{Collection: r.path(curriculumID), ID: id}
+}
+
+// memBatch queues writes and applies them atomically on Commit.
+type memBatch struct {
+	s   *MemoryStore
+	ops []memOp
+}
+
+type memOp struct {
+	ref     DocRef
+	kind    string // "set", "update", "delete"
+	doc     interface{}
+	updates []Update
+}
+
+func (b *memBatch) Set(ref DocRef, doc interface{}) {
+	b.ops = append(b.ops, memOp{ref: ref, kind: "set", doc: cloneValue(doc)})
+}
+
+func (b *memBatch) Update(ref DocRef, updates []Update) {
+	b.ops = append(b.ops, memOp{ref: ref, kind: "update", updates: updates})
+}
+
+func (b *memBatch) Delete(ref DocRef) {
+	b.ops = append(b.ops, memOp{ref: ref, kind: "delete"})
+}
+
+func (b *memBatch) Len() int { return len(b.ops) }
+
+func (b *memBatch) Commit(_ context.Context) error {
+	if len(b.ops) == 0 {
+		return nil
+	}
+	b.s.mu.Lock()
+	defer b.s.mu.Unlock()
+	return b.s.apply(b.ops)
+}
+
+// apply performs queued writes, undoing them all if one fails. The caller
+// must hold s.mu for writing.
+func (s *MemoryStore) apply(ops []memOp) error {
+	if len(ops) > MaxBatchSize {
+		return ErrBatchTooLarge
+	}
+
+	type saved struct {
+		coll   memCollection
+		id     string
+		doc    interface{}
+		exists bool
+	}
+	var undo []saved
+	rollback := func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i].coll.restore(undo[i].id, undo[i].doc, undo[i].exists)
+		}
+	}
+
+	for _, op := range ops {
+		coll, err := s.collectionForRef(op.ref)
+		if err != nil {
+			rollback()
+			return err
+		}
+		doc, exists := coll.snapshot(op.ref.ID)
+		undo = append(undo, saved{coll, op.ref.ID, doc, exists})
+
+		switch op.kind {
+		case "set":
+			err = coll.setAny(op.ref.ID, op.doc)
+		case "update":
+			err = coll.update(op.ref.ID, op.updates)
+		case "delete":
+			coll.remove(op.ref.ID)
+		}
+		if err != nil {
+			rollback()
+			return err
+		}
+	}
+	return nil
+}
+
+// memTx reads the store directly and queues its writes until commit. The
+// store is locked for the whole transaction.
+type memTx struct {
+	s   *MemoryStore
+	ops []memOp
+}
+
+func (t *memTx) Get(ref DocRef, dst interface{}) error {
+	if len(t.ops) > 0 {
+		return ErrReadAfterWrite
+	}
+	coll, err := t.s.collectionForRef(ref)
+	if err != nil {
+		return err
+	}
+	doc, ok := coll.getAny(ref.ID)
+	if !ok {
+		return ErrNotFound
+	}
+	return assignTo(dst, reflect.ValueOf(doc).Elem())
+}
+
+func (t *memTx) List(collection string, q Query, dst interface{}) error {
+	if len(t.ops) > 0 {
+		return ErrReadAfterWrite
+	}
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	coll, err := t.s.collectionForRef(DocRef{Collection: collection})
+	if err != nil {
+		return err
+	}
+	return assignTo(dst, reflect.ValueOf(coll.queryAny(q)))
+}
+
+func (t *memTx) Set(ref DocRef, doc interface{}) {
+	t.ops = append(t.ops, memOp{ref: ref, kind: "set", doc: cloneValue(doc)})
+}
+
+func (t *memTx) Update(ref DocRef, updates []Update) {
+	t.ops = append(t.ops, memOp{ref: ref, kind: "update", updates: updates})
+}
+
+func (t *memTx) Delete(ref DocRef) {
+	t.ops = append(t.ops, memOp{ref: ref, kind: "delete"})
+}
+
+// assignTo stores v in *dst, checking that the types match.
+func assignTo(dst interface{}, v reflect.Value) error {
+	d := reflect.ValueOf(dst)
+	if d.Kind() != reflect.Ptr || d.IsNil() || d.Elem().Type() != v.Type() {
+		return fmt.Errorf("store: cannot load %s into %T", v.Type(), dst)
+	}
+	d.Elem().Set(v)
+	return nil
+}
+
+// collectionForRef resolves the typed collection a batch write targets.
+// The caller must hold s.mu for writing.
+func (s *MemoryStore) collectionForRef(ref DocRef) (memCollection, error) {
+	if c, ok := s.colls[ref.Collection]; ok {
+		return c, nil
+	}
+	switch collectionKind(ref.Collection) {
+	case CollDisciplines:
+		return collectionOf(s, ref.Collection, func(d *model.Discipline, id string) { d.ID = id }, true), nil
+	case CollTags:
+		return collectionOf(s, ref.Collection, func(t *model.Tag, id string) { t.ID = id }, true), nil
+	case CollCategories:
+		return collectionOf(s, ref.Collection, func(c *model.Category, id string) { c.ID = id }, true), nil
+	case CollTechniques:
+		return collectionOf(s, ref.Collection, func(t *model.Technique, id string) { t.ID = id }, true), nil
+	case CollAssets:
+		return collectionOf(s, ref.Collection, func(a *model.Asset, id string) { a.ID = id }, true), nil
+	case CollCurricula:
+		return collectionOf(s, ref.Collection, func(c *model.Curriculum, id string) { c.ID = id }, true), nil
+	case CollElements:
+		return collectionOf(s, ref.Collection, func(e *model.CurriculumElement, id string) { e.ID = id }, true), nil
+	case CollRevisions:
+		return collectionOf(s, ref.Collection, func(r *model.CurriculumRevision, id string) { r.ID = id }, true), nil
+	case CollSessions:
+		return collectionOf(s, ref.Collection, func(x *model.Session, id string) { x.ID = id }, true), nil
+	case CollCalendarFeeds:
+		return collectionOf(s, ref.Collection, func(f *model.CalendarFeed, id string) { f.ID = id }, true), nil
+	case CollProgress:
+		return collectionOf(s, ref.Collection, func(p *model.Progress, id string) { p.ID = id }, true), nil
+	case CollTechniqueEdges:
+		return collectionOf(s, ref.Collection, func(e *model.TechniqueEdge, id string) { e.ID = id }, true), nil
+	}
+	return nil, fmt.Errorf("store: unknown collection %q", ref.Collection)
+}
+
+// collectionKind returns the last segment of a collection path.
+func collectionKind(path string) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == '/' {
+			return path[i+1:]
+		}
+	}
+	return path
+}
+
+const idAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// newDocumentID returns a 20-character random ID in the Firestore format.
+func newDocumentID() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = idAlphabet[int(b)%len(idAlphabet)]
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,186 @@
+-- Initial schema. Array fields of the Firestore documents (techniqueIds,
+-- categoryIds, tagIds, ...) live in join tables ordered by pos. Referenced
+-- IDs carry no foreign key so dangling references behave as in Firestore.
+
+CREATE TABLE disciplines (
+    id          TEXT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    slug        TEXT NOT NULL,
+    description TEXT,
+    created_at  TEXT NOT NULL,
+    updated_at  TEXT NOT NULL
+);
+
+CREATE TABLE tags (
+    id            TEXT PRIMARY KEY,
+    discipline_id TEXT NOT NULL,
+    name          TEXT NOT NULL,
+    slug          TEXT NOT NULL,
+    description   TEXT NOT NULL,
+    color         TEXT,
+    owner_uid     TEXT NOT NULL,
+    created_at    TEXT NOT NULL,
+    updated_at    TEXT NOT NULL
+);
+CREATE INDEX tags_discipline_slug ON tags (discipline_id, slug);
+
+CREATE TABLE categories (
+    id            TEXT PRIMARY KEY,
+    discipline_id TEXT NOT NULL,
+    name          TEXT NOT NULL,
+    slug          TEXT NOT NULL,
+    description   TEXT NOT NULL,
+    parent_id     TEXT,
+    owner_uid     TEXT NOT NULL,
+    created_at    TEXT NOT NULL,
+    updated_at    TEXT NOT NULL
+);
+CREATE INDEX categories_discipline_slug ON categories (discipline_id, slug);
+CREATE INDEX categories_parent ON categories (parent_id);
+
+CREATE TABLE techniques (
+    id            TEXT PRIMARY KEY,
+    discipline_id TEXT NOT NULL,
+    name          TEXT NOT NULL,
+    slug          TEXT NOT NULL,
+    description   TEXT NOT NULL,
+    owner_uid     TEXT NOT NULL,
+    created_at    TEXT NOT NULL,
+    updated_at    TEXT NOT NULL
+);
+CREATE INDEX techniques_discipline_slug ON techniques (discipline_id, slug);
+CREATE INDEX techniques_discipline_name ON techniques (discipline_id, name);
+
+CREATE TABLE technique_categories (
+    technique_id TEXT NOT NULL REFERENCES techniques (id) ON DELETE CASCADE,
+    pos          BIGINT NOT NULL,
+    category_id  TEXT NOT NULL,
+    PRIMARY KEY (technique_id, pos)
+);
+CREATE INDEX technique_categories_category ON technique_categories (category_id);
+
+CREATE TABLE technique_tags (
+    technique_id TEXT NOT NULL REFERENCES techniques (id) ON DELETE CASCADE,
+    pos          BIGINT NOT NULL,
+    tag_id       TEXT NOT NULL,
+    PRIMARY KEY (technique_id, pos)
+);
+CREATE INDEX technique_tags_tag ON technique_tags (tag_id);
+
+CREATE TABLE assets (
+    id                TEXT PRIMARY KEY,
+    discipline_id     TEXT NOT NULL,
+    url               TEXT NOT NULL,
+    title             TEXT NOT NULL,
+    description       TEXT NOT NULL,
+    type              TEXT NOT NULL,
+    video_type        TEXT,
+    originator        TEXT,
+    thumbnail_url     TEXT,
+    duration          TEXT,
+    owner_uid         TEXT NOT NULL,
+    active            BIGINT NOT NULL,
+    processing_status TEXT NOT NULL,
+    processing_error  TEXT,
+    created_at        TEXT NOT NULL,
+    updated_at        TEXT NOT NULL
+);
+CREATE INDEX assets_discipline_created ON assets (discipline_id, created_at);
+
+CREATE TABLE asset_techniques (
+    asset_id     TEXT NOT NULL REFERENCES assets (id) ON DELETE CASCADE,
+    pos          BIGINT NOT NULL,
+    technique_id TEXT NOT NULL,
+    PRIMARY KEY (asset_id, pos)
+);
+CREATE INDEX asset_techniques_technique ON asset_techniques (technique_id);
+
+CREATE TABLE asset_categories (
+    asset_id    TEXT NOT NULL REFERENCES assets (id) ON DELETE CASCADE,
+    pos         BIGINT NOT NULL,
+    category_id TEXT NOT NULL,
+    PRIMARY KEY (asset_id, pos)
+);
+CREATE INDEX asset_categories_category ON asset_categories (category_id);
+
+CREATE TABLE asset_tags (
+    asset_id TEXT NOT NULL REFERENCES assets (id) ON DELETE CASCADE,
+    pos      BIGINT NOT NULL,
+    tag_id   TEXT NOT NULL,
+    PRIMARY KEY (asset_id, pos)
+);
+CREATE INDEX asset_tags_tag ON asset_tags (tag_id);
+
+CREATE TABLE curricula (
+    id            TEXT PRIMARY KEY,
+    discipline_id TEXT NOT NULL,
+    title         TEXT NOT NULL,
+    description   TEXT NOT NULL,
+    duration      TEXT,
+    is_public     BIGINT NOT NULL,
+    owner_uid     TEXT NOT NULL,
+    search_text   TEXT NOT NULL,
+    created_at    TEXT NOT NULL,
+    updated_at    TEXT NOT NULL
+);
+CREATE INDEX curricula_discipline_updated ON curricula (discipline_id, updated_at);
+
+CREATE TABLE curriculum_tags (
+    curriculum_id TEXT NOT NULL REFERENCES curricula (id) ON DELETE CASCADE,
+    pos           BIGINT NOT NULL,
+    tag_id        TEXT NOT NULL,
+    PRIMARY KEY (curriculum_id, pos)
+);
+CREATE INDEX curriculum_tags_tag ON curriculum_tags (tag_id);
+
+CREATE TABLE curriculum_all_tags (
+    curriculum_id TEXT NOT NULL REFERENCES curricula (id) ON DELETE CASCADE,
+    pos           BIGINT NOT NULL,
+    tag_id        TEXT NOT NULL,
+    PRIMARY KEY (curriculum_id, pos)
+);
+CREATE INDEX curriculum_all_tags_tag ON curriculum_all_tags (tag_id);
+
+-- Elements are a subcollection in Firestore, so their key is scoped by
+-- curriculum and they may outlive a deleted parent just like there.
+CREATE TABLE curriculum_elements (
+    curriculum_id          TEXT NOT NULL,
+    id                     TEXT NOT NULL,
+    type                   TEXT NOT NULL,
+    technique_id           TEXT,
+    asset_id               TEXT,
+    title                  TEXT,
+    details                TEXT,
+    image_url              TEXT,
+    duration               TEXT,
+    ord                    BIGINT NOT NULL,
+    has_snapshot           BIGINT NOT NULL,
+    snapshot_name          TEXT,
+    snapshot_thumbnail_url TEXT,
+    snapshot_url           TEXT,
+    snapshot_description   TEXT,
+    created_at             TEXT NOT NULL,
+    updated_at             TEXT NOT NULL,
+    PRIMARY KEY (curriculum_id, id)
+);
+CREATE INDEX curriculum_elements_technique ON curriculum_elements (technique_id);
+CREATE INDEX curriculum_elements_asset ON curriculum_elements (asset_id);
+
+CREATE TABLE element_items (
+    curriculum_id TEXT NOT NULL,
+    element_id    TEXT NOT NULL,
+    pos           BIGINT NOT NULL,
+    item          TEXT NOT NULL,
+    PRIMARY KEY (curriculum_id, element_id, pos),
+    FOREIGN KEY (curriculum_id, element_id) REFERENCES curriculum_elements (curriculum_id, id) ON DELETE CASCADE
+);
+
+CREATE TABLE element_snapshot_tags (
+    curriculum_id TEXT NOT NULL,
+    element_id    TEXT NOT NULL,
+    pos           BIGINT NOT NULL,
+    tag_id        TEXT NOT NULL,
+    PRIMARY KEY (curriculum_id, element_id, pos),
+    FOREIGN KEY (curriculum_id, element_id) REFERENCES curriculum_elements (curriculum_id, id) ON DELETE CASCADE
+);
+CREATE INDEX element_snapshot_tags_tag ON element_snapshot_tags (tag_id);
@@ -0,0 +1,5 @@
+-- Element count and summed element duration, kept up to date by the
+-- element handlers. Existing rows are fixed by backfill-curricula reconcile.
+
+ALTER TABLE curricula ADD COLUMN element_count BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE curricula ADD COLUMN total_duration_seconds BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,19 @@
+-- Curriculum revisions: one immutable row per change to a curriculum or its
+-- elements. content holds the JSON-encoded curriculum and elements.
+
+ALTER TABLE curricula ADD COLUMN revision BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE curriculum_revisions (
+    curriculum_id TEXT NOT NULL,
+    id            TEXT NOT NULL,
+    number        BIGINT NOT NULL,
+    action        TEXT NOT NULL,
+    actor_uid     TEXT NOT NULL,
+    restored_from BIGINT,
+    title         TEXT NOT NULL,
+    element_count BIGINT NOT NULL,
+    content       TEXT NOT NULL,
+    created_at    TEXT NOT NULL,
+    PRIMARY KEY (curriculum_id, id)
+);
+CREATE INDEX curriculum_revisions_number ON curriculum_revisions (curriculum_id, number);
@@ -0,0 +1,7 @@
+-- Fork provenance: the upstream curriculum and revision of a fork, and the
+-- upstream element each of its elements was copied from.
+
+ALTER TABLE curricula ADD COLUMN source_curriculum_id TEXT;
+ALTER TABLE curricula ADD COLUMN source_revision BIGINT;
+ALTER TABLE curriculum_elements ADD COLUMN source_element_id TEXT;
+CREATE INDEX curricula_source ON curricula (source_curriculum_id);
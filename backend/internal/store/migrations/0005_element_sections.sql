@@ -0,0 +1,4 @@
+-- Section elements: the section holding an element, NULL at the top level.
+-- ord is numbered among the elements of the same parent.
+
+ALTER TABLE curriculum_elements ADD COLUMN parent_id TEXT;
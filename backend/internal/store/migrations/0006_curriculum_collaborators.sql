@@ -0,0 +1,18 @@
+-- Curriculum collaborators: the users invited to edit or view a curriculum.
+-- Private curricula are only visible to their owner and collaborators.
+
+CREATE TABLE curriculum_editors (
+    curriculum_id TEXT NOT NULL REFERENCES curricula (id) ON DELETE CASCADE,
+    pos           BIGINT NOT NULL,
+    user_uid      TEXT NOT NULL,
+    PRIMARY KEY (curriculum_id, pos)
+);
+CREATE INDEX curriculum_editors_user ON curriculum_editors (user_uid);
+
+CREATE TABLE curriculum_viewers (
+    curriculum_id TEXT NOT NULL REFERENCES curricula (id) ON DELETE CASCADE,
+    pos           BIGINT NOT NULL,
+    user_uid      TEXT NOT NULL,
+    PRIMARY KEY (curriculum_id, pos)
+);
+CREATE INDEX curriculum_viewers_user ON curriculum_viewers (user_uid);
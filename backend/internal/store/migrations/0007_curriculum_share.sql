@@ -0,0 +1,6 @@
+-- Share links: the token serving a curriculum without authentication, and
+-- when it stops working (NULL: until revoked).
+
+ALTER TABLE curricula ADD COLUMN share_token TEXT;
+ALTER TABLE curricula ADD COLUMN share_expires_at TEXT;
+CREATE UNIQUE INDEX curricula_share_token ON curricula (share_token);
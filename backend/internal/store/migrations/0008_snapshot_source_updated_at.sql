@@ -0,0 +1,4 @@
+-- When the technique or asset an element snapshots was last updated, to
+-- tell stale snapshots apart.
+
+ALTER TABLE curriculum_elements ADD COLUMN snapshot_source_updated_at TEXT;
@@ -0,0 +1,5 @@
+-- Clip elements: the segment of a video asset they play, and a caption.
+
+ALTER TABLE curriculum_elements ADD COLUMN start_seconds BIGINT;
+ALTER TABLE curriculum_elements ADD COLUMN end_seconds BIGINT;
+ALTER TABLE curriculum_elements ADD COLUMN caption TEXT;
@@ -0,0 +1,9 @@
+-- Durations in seconds next to their text, to sum, filter and sort by.
+-- Existing curricula are fixed by backfill-curricula reconcile, assets and
+-- elements by backfill-curricula durations.
+
+ALTER TABLE assets ADD COLUMN duration_seconds BIGINT;
+ALTER TABLE curricula ADD COLUMN duration_seconds BIGINT;
+ALTER TABLE curriculum_elements ADD COLUMN duration_seconds BIGINT;
+
+CREATE INDEX assets_discipline_duration ON assets (discipline_id, duration_seconds);
@@ -0,0 +1,29 @@
+-- Scheduled sessions of curricula, and the secret iCalendar feeds of them.
+-- A feed's id is its token.
+
+CREATE TABLE sessions (
+    id               TEXT PRIMARY KEY,
+    discipline_id    TEXT NOT NULL,
+    curriculum_id    TEXT NOT NULL,
+    title            TEXT NOT NULL,
+    starts_at        TEXT NOT NULL,
+    time_zone        TEXT NOT NULL,
+    duration         TEXT NOT NULL,
+    duration_seconds BIGINT NOT NULL,
+    recurrence       TEXT NOT NULL,
+    location         TEXT NOT NULL,
+    owner_uid        TEXT NOT NULL,
+    created_at       TEXT NOT NULL,
+    updated_at       TEXT NOT NULL
+);
+CREATE INDEX sessions_discipline ON sessions (discipline_id, starts_at);
+CREATE INDEX sessions_curriculum ON sessions (curriculum_id);
+CREATE INDEX sessions_owner ON sessions (owner_uid);
+
+CREATE TABLE calendar_feeds (
+    id            TEXT PRIMARY KEY,
+    owner_uid     TEXT NOT NULL,
+    discipline_id TEXT NOT NULL,
+    created_at    TEXT NOT NULL
+);
+CREATE INDEX calendar_feeds_owner ON calendar_feeds (owner_uid);
@@ -0,0 +1,18 @@
+-- Learners' progress through curriculum elements, one row per learner and
+-- element.
+
+CREATE TABLE progress (
+    id            TEXT PRIMARY KEY,
+    uid           TEXT NOT NULL,
+    discipline_id TEXT NOT NULL,
+    curriculum_id TEXT NOT NULL,
+    element_id    TEXT NOT NULL,
+    state         TEXT NOT NULL,
+    notes         TEXT NOT NULL,
+    started_at    TEXT,
+    completed_at  TEXT,
+    updated_at    TEXT NOT NULL
+);
+CREATE INDEX progress_uid_curriculum ON progress (uid, curriculum_id);
+CREATE INDEX progress_curriculum ON progress (curriculum_id);
+CREATE INDEX progress_discipline ON progress (discipline_id);
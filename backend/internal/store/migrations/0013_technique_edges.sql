@@ -0,0 +1,24 @@
+-- Typed, directed relationships between techniques.
+
+CREATE TABLE technique_edges (
+    id            TEXT PRIMARY KEY,
+    discipline_id TEXT NOT NULL,
+    from_id       TEXT NOT NULL,
+    to_id         TEXT NOT NULL,
+    type          TEXT NOT NULL,
+    notes         TEXT NOT NULL,
+    owner_uid     TEXT NOT NULL,
+    created_at    TEXT NOT NULL,
+    updated_at    TEXT NOT NULL
+);
+CREATE INDEX technique_edges_discipline ON technique_edges (discipline_id, created_at);
+CREATE INDEX technique_edges_from ON technique_edges (from_id);
+CREATE INDEX technique_edges_to ON technique_edges (to_id);
+
+CREATE TABLE technique_edge_assets (
+    edge_id  TEXT NOT NULL REFERENCES technique_edges (id) ON DELETE CASCADE,
+    pos      BIGINT NOT NULL,
+    asset_id TEXT NOT NULL,
+    PRIMARY KEY (edge_id, pos)
+);
+CREATE INDEX technique_edge_assets_asset ON technique_edge_assets (asset_id);
@@ -0,0 +1,17 @@
+-- Other names of techniques, and their slugs, which resolve to the technique
+-- like its own slug does.
+
+CREATE TABLE technique_aliases (
+    technique_id TEXT NOT NULL REFERENCES techniques (id) ON DELETE CASCADE,
+    pos          BIGINT NOT NULL,
+    alias        TEXT NOT NULL,
+    PRIMARY KEY (technique_id, pos)
+);
+
+CREATE TABLE technique_alias_slugs (
+    technique_id TEXT NOT NULL REFERENCES techniques (id) ON DELETE CASCADE,
+    pos          BIGINT NOT NULL,
+    slug         TEXT NOT NULL,
+    PRIMARY KEY (technique_id, pos)
+);
+CREATE INDEX technique_alias_slugs_slug ON technique_alias_slugs (slug);
@@ -0,0 +1,7 @@
+-- When the last occurrence of a session ends, so that the schedule skips
+-- sessions that are over. Existing sessions count as repeating forever until
+-- backfill-curricula sessions computes theirs.
+
+ALTER TABLE sessions ADD COLUMN ends_at TEXT NOT NULL DEFAULT '9999-12-31T00:00:00.000000000Z';
+
+CREATE INDEX sessions_discipline_end ON sessions (discipline_id, ends_at);
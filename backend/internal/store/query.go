@@ -0,0 +1,146 @@
+package store
+
+import "fmt"
+
+// Direction is the sort direction of an OrderBy clause.
+type Direction int
+
+const (
+	Asc Direction = iota + 1
+	Desc
+)
+
+// Filter operators. They use the Firestore spelling so queries read the same
+// regardless of the backend.
+const (
+	OpEqual            = "=="
+	OpNotEqual         = "!="
+	OpLess             = "<"
+	OpLessEqual        = "<="
+	OpGreater          = ">"
+	OpGreaterEqual     = ">="
+	OpIn               = "in"
+	OpArrayContains    = "array-contains"
+	OpArrayContainsAny = "array-contains-any"
+)
+
+// MaxDisjunction is the maximum number of values in an "in" or
+// "array-contains-any" filter (Firestore limit).
+const MaxDisjunction = 30
+
+// Filter is a single field predicate.
+type Filter struct {
+	Field string
+	Op    string
+	Value interface{}
+}
+
+// Order is a single sort clause.
+type Order struct {
+	Field string
+	Dir   Direction
+}
+
+// Query describes a collection query. Like firestore.Query it is immutable:
+// every builder method returns a modified copy.
+type Query struct {
+	filters []Filter
+	orders  []Order
+	limit   int
+	offset  int
+	after   *Cursor
+}
+
+// NewQuery returns an empty query matching every document.
+func NewQuery() Query {
+	return Query{}
+}
+
+// Where adds a field filter.
+func (q Query) Where(field, op string, value interface{}) Query {
+	q.filters = append(append([]Filter(nil), q.filters...), Filter{Field: field, Op: op, Value: value})
+	return q
+}
+
+// OrderBy adds a sort clause. Ties are always broken by document ID.
+func (q Query) OrderBy(field string, dir Direction) Query {
+	q.orders = append(append([]Order(nil), q.orders...), Order{Field: field, Dir: dir})
+	return q
+}
+
+// Limit caps the number of returned documents. Zero means no limit.
+func (q Query) Limit(n int) Query {
+	q.limit = n
+	return q
+}
+
+// Offset skips the first n matching documents.
+func (q Query) Offset(n int) Query {
+	q.offset = n
+	return q
+}
+
+// StartAfter resumes the query after the document c points at. c must have
+// been taken from a query with the same ordering (see CursorAt).
+func (q Query) StartAfter(c Cursor) Query {
+	q.after = &c
+	return q
+}
+
+func (q Query) Filters() []Filter { return q.filters }
+func (q Query) Orders() []Order   { return q.orders }
+func (q Query) LimitN() int       { return q.limit }
+func (q Query) OffsetN() int      { return q.offset }
+func (q Query) After() *Cursor    { return q.after }
+
+// Validate enforces the Firestore query restrictions so that a query which
+// works against one backend works against all of them.
+func (q Query) Validate() error {
+	arrayFilters := 0
+	disjunctions := 0
+	rangeField := ""
+	for _, f := range q.filters {
+		switch f.Op {
+		case OpEqual:
+		case OpNotEqual, OpLess, OpLessEqual, OpGreater, OpGreaterEqual:
+			if rangeField != "" && rangeField != f.Field {
+				return fmt.Errorf("store: range filters on multiple fields (%s, %s)", rangeField, f.Field)
+			}
+			rangeField = f.Field
+		case OpArrayContains:
+			arrayFilters++
+		case OpArrayContainsAny, OpIn:
+			if f.Op == OpArrayContainsAny {
+				arrayFilters++
+			}
+			disjunctions++
+			values, ok := f.Value.([]string)
+			if !ok {
+				return fmt.Errorf("store: %s filter on %s requires a []string value", f.Op, f.Field)
+			}
+			if len(values) == 0 || len(values) > MaxDisjunction {
+				return fmt.Errorf("store: %s filter on %s needs 1-%d values", f.Op, f.Field, MaxDisjunction)
+			}
+		default:
+			return fmt.Errorf("store: unsupported operator %q", f.Op)
+		}
+	}
+	if arrayFilters > 1 {
+		return fmt.Errorf("store: at most one array-contains or array-contains-any filter per query")
+	}
+	if disjunctions > 1 {
+		return fmt.Errorf("store: at most one in or array-contains-any filter per query")
+	}
+	if rangeField != "" && len(q.orders) > 0 && q.orders[0].Field != rangeField {
+		return fmt.Errorf("store: first orderBy must be on range field %s", rangeField)
+	}
+	if q.after != nil {
+		if err := q.after.matches(effectiveOrders(q)); err != nil {
+			return err
+		}
+	}
+	if q.limit < 0 || q.offset < 0 {
+		return fmt.Errorf("store: limit and offset must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,701 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" driver
+	"github.com/thomas/skillhive-api/internal/model"
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// SQLStore is a relational Store for self-hosted deployments. SQLite works
+// out of the box; Postgres is supported through pgx. Array fields live in
+// join tables, but queries, updates and batches keep Firestore semantics.
+type SQLStore struct {
+	db       *sql.DB
+	postgres bool
+}
+
+// NewSQL opens a SQL database and applies any pending schema migrations.
+// driver is "sqlite" or "postgres"; dsn is passed to the driver unchanged.
+func NewSQL(ctx context.Context, driver, dsn string) (*SQLStore, error) {
+	s := &SQLStore{}
+	var name string
+	switch driver {
+	case "sqlite":
+		name = "sqlite"
+	case "postgres":
+		name = "pgx"
+		s.postgres = true
+	default:
+		return nil, fmt.Errorf("store: unknown SQL driver %q (want sqlite or postgres)", driver)
+	}
+
+	db, err := sql.Open(name, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if !s.postgres {
+		// SQLite serializes writers anyway; one connection avoids "database
+		// is locked" errors and keeps :memory: databases shared.
+		db.SetMaxOpenConns(1)
+	}
+	s.db = db
+
+	if err := s.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// DB exposes the underlying database for maintenance commands.
+func (s *SQLStore) DB() *sql.DB { return s.db }
+
+func (s *SQLStore) Disciplines() DisciplineRepo {
+	return &sqlRepo[model.Discipline]{s, CollDisciplines, func(d *model.Discipline, id string) { d.ID = id }}
+}
+
+func (s *SQLStore) Tags() TagRepo {
+	return &sqlRepo[model.Tag]{s, CollTags, func(t *model.Tag, id string) { t.ID = id }}
+}
+
+func (s *SQLStore) Categories() CategoryRepo {
+	return &sqlRepo[model.Category]{s, CollCategories, func(c *model.Category, id string) { c.ID = id }}
+}
+
+func (s *SQLStore) Techniques() TechniqueRepo {
+	return &sqlRepo[model.Technique]{s, CollTechniques, func(t *model.Technique, id string) { t.ID = id }}
+}
+
+func (s *SQLStore) Assets() AssetRepo {
+	return &sqlRepo[model.Asset]{s, CollAssets, func(a *model.Asset, id string) { a.ID = id }}
+}
+
+func (s *SQLStore) Curricula() CurriculumRepo {
+	return &sqlRepo[model.Curriculum]{s, CollCurricula, func(c *model.Curriculum, id string) { c.ID = id }}
+}
+
+func (s *SQLStore) Elements() ElementRepo {
+	return &sqlSubRepo[model.CurriculumElement]{sqlRepo[model.CurriculumElement]{s, "", func(e *model.CurriculumElement, id string) { e.ID = id }}, ElementsPath}
+}
+
+func (s *SQLStore) Revisions() RevisionRepo {
+	return &sqlSubRepo[model.CurriculumRevision]{sqlRepo[model.CurriculumRevision]{s, "", func(r *model.CurriculumRevision, id string) { r.ID = id }}, RevisionsPath}
+}
+
+func (s *SQLStore) Sessions() SessionRepo {
+	return &sqlRepo[model.Session]{s, CollSessions, func(x *model.Session, id string) { x.ID = id }}
+}
+
+func (s *SQLStore) CalendarFeeds() CalendarFeedRepo {
+	return &sqlRepo[model.CalendarFeed]{s, CollCalendarFeeds, func(f *model.CalendarFeed, id string) { f.ID = id }}
+}
+
+func (s *SQLStore) Progress() ProgressRepo {
+	return &sqlRepo[model.Progress]{s, CollProgress, func(p *model.Progress, id string) { p.ID = id }}
+}
+
+func (s *SQLStore) TechniqueEdges() TechniqueEdgeRepo {
+	return &sqlRepo[model.TechniqueEdge]{s, CollTechniqueEdges, func(e *model.TechniqueEdge, id string) { e.ID = id }}
+}
+
+func (s *SQLStore) Batch() Batch {
+	return &sqlBatch{s: s}
+}
+
+// sqlTxAttempts bounds how often a transaction is run when Postgres
+// aborts it for a serialization failure.
+const sqlTxAttempts = 5
+
+func (s *SQLStore) RunTransaction(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	var opts *sql.TxOptions
+	if s.postgres {
+		// Serializable isolation detects the conflicting writes Firestore
+		// retries transactions for. SQLite's single connection already
+		// serializes everything.
+		opts = &sql.TxOptions{Isolation: sql.LevelSerializable}
+	}
+	for attempt := 1; ; attempt++ {
+		err := s.withTxOptions(ctx, opts, func(tx *sql.Tx) error {
+			t := &sqlTx{ctx: ctx, s: s, tx: tx}
+			if err := fn(ctx, t); err != nil {
+				return err
+			}
+			return s.apply(ctx, tx, t.ops)
+		})
+		var pgErr *pgconn.PgError
+		if attempt < sqlTxAttempts && errors.As(err, &pgErr) && pgErr.Code == "40001" {
+			continue
+		}
+		return err
+	}
+}
+
+func (s *SQLStore) Close() error { return s.db.Close() }
+
+// sqlExecer is satisfied by both *sql.DB and *sql.Tx.
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// rebind rewrites ? placeholders into the $n form Postgres expects.
+func (s *SQLStore) rebind(query string) string {
+	if !s.postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *SQLStore) exec(ctx context.Context, x sqlExecer, query string, args ...interface{}) error {
+	_, err := x.ExecContext(ctx, s.rebind(query), args...)
+	return err
+}
+
+// withTx runs fn in a transaction, rolling back if it returns an error.
+func (s *SQLStore) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return s.withTxOptions(ctx, nil, fn)
+}
+
+func (s *SQLStore) withTxOptions(ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// keyWhere returns the WHERE clause and arguments selecting one document.
+func keyWhere(t *sqlTable, scope, id string) (string, []interface{}) {
+	if t.scoped {
+		return "curriculum_id = ? AND id = ?", []interface{}{scope, id}
+	}
+	return "id = ?", []interface{}{id}
+}
+
+// ownerWhere returns the WHERE clause and arguments selecting the join rows
+// of one document.
+func ownerWhere(t *sqlTable, a sqlArray, scope, id string) (string, []interface{}) {
+	if t.scoped {
+		return "curriculum_id = ? AND " + a.owner + " = ?", []interface{}{scope, id}
+	}
+	return a.owner + " = ?", []interface{}{id}
+}
+
+// selectColumns lists the columns read for a table, prefixed with id.
+func selectColumns(t *sqlTable) string {
+	cols := []string{"id"}
+	for _, c := range t.columns {
+		cols = append(cols, c.name)
+	}
+	return strings.Join(cols, ", ")
+}
+
+// get loads a single document into a new value of t.typ.
+func (s *SQLStore) get(ctx context.Context, x sqlExecer, t *sqlTable, scope, id string) (reflect.Value, error) {
+	where, args := keyWhere(t, scope, id)
+	docs, err := s.query(ctx, x, t, scope, "SELECT "+selectColumns(t)+" FROM "+t.name+" WHERE "+where, args)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if len(docs) == 0 {
+		return reflect.Value{}, ErrNotFound
+	}
+	return docs[0].doc, nil
+}
+
+type sqlDoc struct {
+	id  string
+	doc reflect.Value // pointer to a t.typ struct
+}
+
+// query runs a SELECT of selectColumns(t) and decodes the rows, including
+// their array fields.
+func (s *SQLStore) query(ctx context.Context, x sqlExecer, t *sqlTable, scope, query string, args []interface{}) ([]sqlDoc, error) {
+	rows, err := x.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []sqlDoc
+	raw := make([]interface{}, len(t.columns)+1)
+	dest := make([]interface{}, len(raw))
+	for i := range raw {
+		dest[i] = &raw[i]
+	}
+	for rows.Next() {
+		for i := range raw {
+			raw[i] = nil
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		id, err := rawString(raw[0])
+		if err != nil {
+			return nil, err
+		}
+		doc := reflect.New(t.typ)
+		for i, c := range t.columns {
+			if err := t.decodeColumn(doc, c, raw[i+1]); err != nil {
+				return nil, fmt.Errorf("store: %s/%s: %w", t.name, id, err)
+			}
+		}
+		docs = append(docs, sqlDoc{id: id, doc: doc})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Release the connection before the join table queries: SQLite runs
+	// with a single connection.
+	rows.Close()
+	if err := s.loadArrays(ctx, x, t, scope, docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// sqlInChunk bounds the number of IDs bound in a single IN clause.
+const sqlInChunk = 500
+
+// loadArrays fills the array fields of docs from their join tables.
+func (s *SQLStore) loadArrays(ctx context.Context, x sqlExecer, t *sqlTable, scope string, docs []sqlDoc) error {
+	if len(docs) == 0 || len(t.arrays) == 0 {
+		return nil
+	}
+	for _, a := range t.arrays {
+		values := make(map[string][]string, len(docs))
+		for start := 0; start < len(docs); start += sqlInChunk {
+			end := min(start+sqlInChunk, len(docs))
+			var args []interface{}
+			where := ""
+			if t.scoped {
+				where = "curriculum_id = ? AND "
+				args = append(args, scope)
+			}
+			marks := make([]string, 0, end-start)
+			for _, d := range docs[start:end] {
+				marks = append(marks, "?")
+				args = append(args, d.id)
+			}
+			where += a.owner + " IN (" + strings.Join(marks, ", ") + ")"
+
+			query := "SELECT " + a.owner + ", " + a.value + " FROM " + a.table + " WHERE " + where + " ORDER BY " + a.owner + ", pos"
+			rows, err := x.QueryContext(ctx, s.rebind(query), args...)
+			if err != nil {
+				return err
+			}
+			for rows.Next() {
+				var owner, value string
+				if err := rows.Scan(&owner, &value); err != nil {
+					rows.Close()
+					return err
+				}
+				values[owner] = append(values[owner], value)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+		}
+		for _, d := range docs {
+			if err := t.decodeArray(d.doc, a, values[d.id]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// put inserts or replaces a document and its join rows.
+func (s *SQLStore) put(ctx context.Context, x sqlExecer, t *sqlTable, scope, id string, doc reflect.Value) error {
+	values, arrays := t.encodeRow(doc)
+
+	cols := t.keyColumns()
+	args := []interface{}{id}
+	if t.scoped {
+		args = []interface{}{scope, id}
+	}
+	var sets []string
+	for i, c := range t.columns {
+		cols = append(cols, c.name)
+		args = append(args, values[i])
+		sets = append(sets, c.name+" = excluded."+c.name)
+	}
+	marks := strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ")
+	query := "INSERT INTO " + t.name + " (" + strings.Join(cols, ", ") + ") VALUES (" + marks + ")" +
+		" ON CONFLICT (" + strings.Join(t.keyColumns(), ", ") + ") DO UPDATE SET " + strings.Join(sets, ", ")
+	if err := s.exec(ctx, x, query, args...); err != nil {
+		return err
+	}
+
+	for i, a := range t.arrays {
+		owner := t.ownerColumns(a)
+		where, ownerArgs := ownerWhere(t, a, scope, id)
+		if err := s.exec(ctx, x, "DELETE FROM "+a.table+" WHERE "+where, ownerArgs...); err != nil {
+			return err
+		}
+		insert := "INSERT INTO " + a.table + " (" + strings.Join(owner, ", ") + ", pos, " + a.value + ") VALUES (" +
+			strings.Repeat("?, ", len(owner)) + "?, ?)"
+		for pos, v := range arrays[i] {
+			rowArgs := append(append([]interface{}{}, ownerArgs...), pos, v)
+			if err := s.exec(ctx, x, insert, rowArgs...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// remove deletes a document and its join rows. The rows are deleted
+// explicitly because SQLite only cascades when foreign keys are enabled.
+func (s *SQLStore) remove(ctx context.Context, x sqlExecer, t *sqlTable, scope, id string) error {
+	for _, a := range t.arrays {
+		where, args := ownerWhere(t, a, scope, id)
+		if err := s.exec(ctx, x, "DELETE FROM "+a.table+" WHERE "+where, args...); err != nil {
+			return err
+		}
+	}
+	where, args := keyWhere(t, scope, id)
+	return s.exec(ctx, x, "DELETE FROM "+t.name+" WHERE "+where, args...)
+}
+
+// update loads a document, applies field updates and writes it back.
+func (s *SQLStore) update(ctx context.Context, x sqlExecer, t *sqlTable, scope, id string, updates []Update) error {
+	doc, err := s.get(ctx, x, t, scope, id)
+	if err != nil {
+		return err
+	}
+	for _, u := range updates {
+		if err := applyUpdate(doc, u.Path, u.Value); err != nil {
+			return err
+		}
+	}
+	return s.put(ctx, x, t, scope, id, doc)
+}
+
+// sqlRepo implements Repo over a table.
+type sqlRepo[T any] struct {
+	s     *SQLStore
+	coll  string
+	setID func(*T, string)
+}
+
+func (r *sqlRepo[T]) getAt(ctx context.Context, path, id string) (*T, error) {
+	t, scope, err := sqlTableFor(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := r.s.get(ctx, r.s.db, t, scope, id)
+	if err != nil {
+		return nil, err
+	}
+	out := doc.Interface().(*T)
+	r.setID(out, id)
+	return out, nil
+}
+
+func (r *sqlRepo[T]) listAt(ctx context.Context, path string, q Query) ([]T, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	t, scope, err := sqlTableFor(path)
+	if err != nil {
+		return nil, err
+	}
+	query, args, err := r.s.buildSelect(t, scope, q)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := r.s.query(ctx, r.s.db, t, scope, query, args)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]T, 0, len(docs))
+	for _, d := range docs {
+		v := d.doc.Interface().(*T)
+		r.setID(v, d.id)
+		out = append(out, *v)
+	}
+	return out, nil
+}
+
+func (r *sqlRepo[T]) setAt(ctx context.Context, path, id string, doc *T) error {
+	t, scope, err := sqlTableFor(path)
+	if err != nil {
+		return err
+	}
+	err = r.s.withTx(ctx, func(tx *sql.Tx) error {
+		return r.s.put(ctx, tx, t, scope, id, reflect.ValueOf(doc))
+	})
+	if err != nil {
+		return err
+	}
+	r.setID(doc, id)
+	return nil
+}
+
+func (r *sqlRepo[T]) createAt(ctx context.Context, path string, doc *T) (string, error) {
+	id, err := newDocumentID()
+	if err != nil {
+		return "", err
+	}
+	return id, r.setAt(ctx, path, id, doc)
+}
+
+func (r *sqlRepo[T]) updateAt(ctx context.Context, path, id string, updates []Update) error {
+	t, scope, err := sqlTableFor(path)
+	if err != nil {
+		return err
+	}
+	return r.s.withTx(ctx, func(tx *sql.Tx) error {
+		return r.s.update(ctx, tx, t, scope, id, updates)
+	})
+}
+
+func (r *sqlRepo[T]) deleteAt(ctx context.Context, path, id string) error {
+	t, scope, err := sqlTableFor(path)
+	if err != nil {
+		return err
+	}
+	return r.s.remove(ctx, r.s.db, t, scope, id)
+}
+
+func (r *sqlRepo[T]) Get(ctx context.Context, id string) (*T, error) {
+	return r.getAt(ctx, r.coll, id)
+}
+
+func (r *sqlRepo[T]) GetAll(ctx context.Context, ids []string) ([]T, error) {
+	out := []T{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	t, _, err := sqlTableFor(r.coll)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	docs, err := r.s.query(ctx, r.s.db, t, "",
+		"SELECT "+selectColumns(t)+" FROM "+t.name+" WHERE id IN ("+placeholders(len(ids))+")", args)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range docs {
+		v := d.doc.Interface().(*T)
+		r.setID(v, d.id)
+		out = append(out, *v)
+	}
+	return out, nil
+}
+
+func (r *sqlRepo[T]) List(ctx context.Context, q Query) ([]T, error) {
+	return r.listAt(ctx, r.coll, q)
+}
+
+func (r *sqlRepo[T]) Create(ctx context.Context, doc *T) (string, error) {
+	return r.createAt(ctx, r.coll, doc)
+}
+
+func (r *sqlRepo[T]) Set(ctx context.Context, id string, doc *T) error {
+	return r.setAt(ctx, r.coll, id, doc)
+}
+
+func (r *sqlRepo[T]) Update(ctx context.Context, id string, updates []Update) error {
+	return r.updateAt(ctx, r.coll, id, updates)
+}
+
+func (r *sqlRepo[T]) Delete(ctx context.Context, id string) error {
+	return r.deleteAt(ctx, r.coll, id)
+}
+
+func (r *sqlRepo[T]) Ref(id string) DocRef {
+	return DocRef{Collection: r.coll, ID: id}
+}
+
+// sqlSubRepo implements a subcollection repository of curricula.
+type sqlSubRepo[T any] struct {
+	repo sqlRepo[T]
+	path func(curriculumID string) string
+}
+
+func (r *sqlSubRepo[T]) Get(ctx context.Context, curriculumID, id string) (*T, error) {
+	return r.repo.getAt(ctx, r.path(curriculumID), id)
+}
+
+func (r *sqlSubRepo[T]) List(ctx context.Context, curriculumID string, q Query) ([]T, error) {
+	return r.repo.listAt(ctx, r.path(curriculumID), q)
+}
+
+func (r *sqlSubRepo[T]) Create(ctx context.Context, curriculumID string, doc *T) (string, error) {
+	return r.repo.createAt(ctx, r.path(curriculumID), doc)
+}
+
+func (r *sqlSubRepo[T]) Set(ctx context.Context, curriculumID, id string, doc *T) error {
+	return r.repo.setAt(ctx, r.path(curriculumID), id, doc)
+}
+
+func (r *sqlSubRepo[T]) Update(ctx context.Context, curriculumID, id string, updates []Update) error {
+	return r.repo.updateAt(ctx, r.path(curriculumID), id, updates)
+}
+
+func (r *sqlSubRepo[T]) Delete(ctx context.Context, curriculumID, id string) error {
+	return r.repo.deleteAt(ctx, r.path(curriculumID), id)
+}
+
+func (r *sqlSubRepo[T]) Ref(curriculumID, id string) DocRef {
+	return DocRef{Collection: r.path(curriculumID), ID: id}
+}
+
+// sqlBatch queues writes and applies them in a single transaction on Commit.
+type sqlBatch struct {
+	s   *SQLStore
+	ops []memOp
+}
+
+func (b *sqlBatch) Set(ref DocRef, doc interface{}) {
+	b.ops = append(b.ops, memOp{ref: ref, kind: "set", doc: cloneValue(doc)})
+}
+
+func (b *sqlBatch) Update(ref DocRef, updates []Update) {
+	b.ops = append(b.ops, memOp{ref: ref, kind: "update", updates: updates})
+}
+
+func (b *sqlBatch) Delete(ref DocRef) {
+	b.ops = append(b.ops, memOp{ref: ref, kind: "delete"})
+}
+
+func (b *sqlBatch) Len() int { return len(b.ops) }
+
+func (b *sqlBatch) Commit(ctx context.Context) error {
+	if len(b.ops) == 0 {
+		return nil
+	}
+	if len(b.ops) > MaxBatchSize {
+		return ErrBatchTooLarge
+	}
+	return b.s.withTx(ctx, func(tx *sql.Tx) error {
+		return b.s.apply(ctx, tx, b.ops)
+	})
+}
+
+// apply performs queued writes inside tx.
+func (s *SQLStore) apply(ctx context.Context, tx *sql.Tx, ops []memOp) error {
+	if len(ops) > MaxBatchSize {
+		return ErrBatchTooLarge
+	}
+	for _, op := range ops {
+		t, scope, err := sqlTableFor(op.ref.Collection)
+		if err != nil {
+			return err
+		}
+		switch op.kind {
+		case "set":
+			doc := reflect.ValueOf(op.doc)
+			if doc.Kind() != reflect.Ptr {
+				p := reflect.New(doc.Type())
+				p.Elem().Set(doc)
+				doc = p
+			}
+			if doc.Elem().Type() != t.typ {
+				return fmt.Errorf("store: cannot store %s in %s", doc.Elem().Type(), t.name)
+			}
+			err = s.put(ctx, tx, t, scope, op.ref.ID, doc)
+		case "update":
+			err = s.update(ctx, tx, t, scope, op.ref.ID, op.updates)
+		case "delete":
+			err = s.remove(ctx, tx, t, scope, op.ref.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqlTx reads through a database transaction and queues its writes until
+// fn returns, matching the Firestore rule that reads come first.
+type sqlTx struct {
+	ctx context.Context
+	s   *SQLStore
+	tx  *sql.Tx
+	ops []memOp
+}
+
+func (t *sqlTx) Get(ref DocRef, dst interface{}) error {
+	if len(t.ops) > 0 {
+		return ErrReadAfterWrite
+	}
+	table, scope, err := sqlTableFor(ref.Collection)
+	if err != nil {
+		return err
+	}
+	doc, err := t.s.get(t.ctx, t.tx, table, scope, ref.ID)
+	if err != nil {
+		return err
+	}
+	setDocID(doc, ref.ID)
+	return assignTo(dst, doc.Elem())
+}
+
+func (t *sqlTx) List(collection string, q Query, dst interface{}) error {
+	if len(t.ops) > 0 {
+		return ErrReadAfterWrite
+	}
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	table, scope, err := sqlTableFor(collection)
+	if err != nil {
+		return err
+	}
+	query, args, err := t.s.buildSelect(table, scope, q)
+	if err != nil {
+		return err
+	}
+	docs, err := t.s.query(t.ctx, t.tx, table, scope, query, args)
+	if err != nil {
+		return err
+	}
+	out := reflect.MakeSlice(reflect.SliceOf(table.typ), 0, len(docs))
+	for _, d := range docs {
+		setDocID(d.doc, d.id)
+		out = reflect.Append(out, d.doc.Elem())
+	}
+	return assignTo(dst, out)
+}
+
+func (t *sqlTx) Set(ref DocRef, doc interface{}) {
+	t.ops = append(t.ops, memOp{ref: ref, kind: "set", doc: cloneValue(doc)})
+}
+
+func (t *sqlTx) Update(ref DocRef, updates []Update) {
+	t.ops = append(t.ops, memOp{ref: ref, kind: "update", updates: updates})
+}
+
+func (t *sqlTx) Delete(ref DocRef) {
+	t.ops = append(t.ops, memOp{ref: ref, kind: "delete"})
+}
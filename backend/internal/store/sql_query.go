@@ -0,0 +1,193 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// buildSelect translates a validated Query into a SELECT over t. Array
+// filters become EXISTS subqueries on the join tables; ordering, the
+// missing-field rule and the document ID tie-break follow the memory store.
+func (s *SQLStore) buildSelect(t *sqlTable, scope string, q Query) (string, []interface{}, error) {
+	var where []string
+	var args []interface{}
+	if t.scoped {
+		where = append(where, "t.curriculum_id = ?")
+		args = append(args, scope)
+	}
+
+	for _, f := range q.filters {
+		clause, fargs, err := filterClause(t, f)
+		if err != nil {
+			return "", nil, err
+		}
+		where = append(where, clause)
+		args = append(args, fargs...)
+	}
+
+	var orderBy []string
+	orders := effectiveOrders(q)
+	for _, o := range orders {
+		c, ok := t.column(o.Field)
+		if !ok {
+			return "", nil, fmt.Errorf("store: cannot order %s by %s", t.name, o.Field)
+		}
+		// Like Firestore, documents missing an ordered field are excluded.
+		if c.omitEmpty {
+			where = append(where, "t."+c.name+" IS NOT NULL")
+		}
+		// Null sorts before every other value, as in Firestore.
+		if o.Dir == Desc {
+			orderBy = append(orderBy, "t."+c.name+" DESC NULLS LAST")
+		} else {
+			orderBy = append(orderBy, "t."+c.name+" ASC NULLS FIRST")
+		}
+	}
+	if len(orders) > 0 && orders[len(orders)-1].Dir == Desc {
+		orderBy = append(orderBy, "t.id DESC")
+	} else {
+		orderBy = append(orderBy, "t.id ASC")
+	}
+
+	if q.after != nil {
+		clause, cargs := cursorClause(t, orders, q.after)
+		where = append(where, clause)
+		args = append(args, cargs...)
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	for i, col := range strings.Split(selectColumns(t), ", ") {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("t." + col)
+	}
+	b.WriteString(" FROM " + t.name + " t")
+	if len(where) > 0 {
+		b.WriteString(" WHERE " + strings.Join(where, " AND "))
+	}
+	b.WriteString(" ORDER BY " + strings.Join(orderBy, ", "))
+	switch {
+	case q.limit > 0:
+		b.WriteString(" LIMIT " + strconv.Itoa(q.limit))
+	case q.offset > 0 && !s.postgres:
+		// SQLite only accepts OFFSET after a LIMIT.
+		b.WriteString(" LIMIT -1")
+	}
+	if q.offset > 0 {
+		b.WriteString(" OFFSET " + strconv.Itoa(q.offset))
+	}
+	return b.String(), args, nil
+}
+
+// cursorClause selects the rows that sort after c: a row is after c when it
+// equals c on the first i ordered columns and sorts after it on column i+1,
+// or equals it on every column and has a later ID. NULL placement matches
+// the ORDER BY of buildSelect. orders must have been checked by the caller.
+func cursorClause(t *sqlTable, orders []Order, c *Cursor) (string, []interface{}) {
+	var alts, eq []string
+	var args, eqArgs []interface{}
+	for i, o := range orders {
+		col, _ := t.column(o.Field)
+		name := "t." + col.name
+		v := sqlArg(normalizeAny(c.Values[i]))
+
+		var after string
+		switch {
+		case v == nil && o.Dir == Desc:
+			// Nothing but NULL sorts after NULL with NULLS LAST.
+		case v == nil:
+			after = name + " IS NOT NULL"
+		case o.Dir == Desc:
+			after = "(" + name + " < ? OR " + name + " IS NULL)"
+		default:
+			after = name + " > ?"
+		}
+		if after != "" {
+			alts = append(alts, strings.Join(append(slices.Clone(eq), after), " AND "))
+			args = append(args, eqArgs...)
+			if v != nil {
+				args = append(args, v)
+			}
+		}
+
+		if v == nil {
+			eq = append(eq, name+" IS NULL")
+		} else {
+			eq = append(eq, name+" = ?")
+			eqArgs = append(eqArgs, v)
+		}
+	}
+	idOp := " > ?"
+	if len(orders) > 0 && orders[len(orders)-1].Dir == Desc {
+		idOp = " < ?"
+	}
+	alts = append(alts, strings.Join(append(eq, "t.id"+idOp), " AND "))
+	args = append(append(args, eqArgs...), c.ID)
+	return "(" + strings.Join(alts, " OR ") + ")", args
+}
+
+// filterClause translates a single filter into SQL.
+func filterClause(t *sqlTable, f Filter) (string, []interface{}, error) {
+	arg := normalizeAny(f.Value)
+
+	if a, ok := t.array(f.Field); ok {
+		var match string
+		var args []interface{}
+		switch f.Op {
+		case OpArrayContains:
+			match = "j." + a.value + " = ?"
+			args = []interface{}{sqlArg(arg)}
+		case OpArrayContainsAny:
+			values := arg.([]interface{})
+			match = "j." + a.value + " IN (" + placeholders(len(values)) + ")"
+			for _, v := range values {
+				args = append(args, sqlArg(v))
+			}
+		default:
+			return "", nil, fmt.Errorf("store: %s filter on array field %s is not supported", f.Op, f.Field)
+		}
+		join := "j." + a.owner + " = t.id"
+		if t.scoped {
+			join = "j.curriculum_id = t.curriculum_id AND " + join
+		}
+		return "EXISTS (SELECT 1 FROM " + a.table + " j WHERE " + join + " AND " + match + ")", args, nil
+	}
+
+	c, ok := t.column(f.Field)
+	if !ok {
+		return "", nil, fmt.Errorf("store: cannot filter %s on %s", t.name, f.Field)
+	}
+	col := "t." + c.name
+	switch f.Op {
+	case OpEqual:
+		if arg == nil {
+			return col + " IS NULL", nil, nil
+		}
+		return col + " = ?", []interface{}{sqlArg(arg)}, nil
+	case OpNotEqual:
+		if arg == nil {
+			return col + " IS NOT NULL", nil, nil
+		}
+		return col + " IS NOT NULL AND " + col + " <> ?", []interface{}{sqlArg(arg)}, nil
+	case OpLess, OpLessEqual, OpGreater, OpGreaterEqual:
+		return col + " " + f.Op + " ?", []interface{}{sqlArg(arg)}, nil
+	case OpIn:
+		values := arg.([]interface{})
+		args := make([]interface{}, len(values))
+		for i, v := range values {
+			args[i] = sqlArg(v)
+		}
+		return col + " IN (" + placeholders(len(values)) + ")", args, nil
+	case OpArrayContains, OpArrayContainsAny:
+		return "", nil, fmt.Errorf("store: %s filter on non-array field %s", f.Op, f.Field)
+	}
+	return "", nil, fmt.Errorf("store: unsupported operator %q", f.Op)
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,380 @@
+package store
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+// sqlTimeFormat stores timestamps as fixed-width UTC text so that string
+// comparison in SQL matches chronological order on every driver.
+const sqlTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// sqlTable maps a collection onto a table plus one join table per array field.
+type sqlTable struct {
+	name string
+	typ  reflect.Type
+	// scoped tables hold a subcollection; their key is (curriculum_id, id).
+	scoped  bool
+	columns []sqlColumn
+	arrays  []sqlArray
+}
+
+// sqlColumn maps a stored field path to a column. A presence column records
+// whether an optional nested object (e.g. snapshot) exists.
+type sqlColumn struct {
+	field     string
+	name      string
+	presence  bool
+	omitEmpty bool
+}
+
+// sqlArray maps an array field to a join table of (owner, pos, value) rows.
+type sqlArray struct {
+	field string
+	table string
+	owner string
+	value string
+}
+
+var sqlTables = map[string]*sqlTable{
+	CollDisciplines: newSQLTable("disciplines", model.Discipline{}, false,
+		[]string{"name", "slug", "description", "createdAt", "updatedAt"}, nil),
+	CollTags: newSQLTable("tags", model.Tag{}, false,
+		[]string{"disciplineId", "name", "slug", "description", "color", "ownerUid", "createdAt", "updatedAt"}, nil),
+	CollCategories: newSQLTable("categories", model.Category{}, false,
+		[]string{"disciplineId", "name", "slug", "description", "parentId", "ownerUid", "createdAt", "updatedAt"}, nil),
+	CollTechniques: newSQLTable("techniques", model.Technique{}, false,
+		[]string{"disciplineId", "name", "slug", "description", "ownerUid", "createdAt", "updatedAt"},
+		[]sqlArray{
+			{field: "aliases", table: "technique_aliases", owner: "technique_id", value: "alias"},
+			{field: "aliasSlugs", table: "technique_alias_slugs", owner: "technique_id", value: "slug"},
+			{field: "categoryIds", table: "technique_categories", owner: "technique_id", value: "category_id"},
+			{field: "tagIds", table: "technique_tags", owner: "technique_id", value: "tag_id"},
+		}),
+	CollAssets: newSQLTable("assets", model.Asset{}, false,
+		[]string{"disciplineId", "url", "title", "description", "type", "videoType", "originator", "thumbnailUrl",
+			"duration", "durationSeconds", "ownerUid", "active", "processingStatus", "processingError", "createdAt", "updatedAt"},
+		[]sqlArray{
+			{field: "techniqueIds", table: "asset_techniques", owner: "asset_id", value: "technique_id"},
+			{field: "categoryIds", table: "asset_categories", owner: "asset_id", value: "category_id"},
+			{field: "tagIds", table: "asset_tags", owner: "asset_id", value: "tag_id"},
+		}),
+	CollCurricula: newSQLTable("curricula", model.Curriculum{}, false,
+		[]string{"disciplineId", "title", "description", "duration", "durationSeconds", "isPublic", "ownerUid", "searchText", "createdAt", "updatedAt",
+			"elementCount", "totalDurationSeconds", "revision", "sourceCurriculumId", "sourceRevision",
+			"shareToken", "shareExpiresAt"},
+		[]sqlArray{
+			{field: "tagIds", table: "curriculum_tags", owner: "curriculum_id", value: "tag_id"},
+			{field: "allTagIds", table: "curriculum_all_tags", owner: "curriculum_id", value: "tag_id"},
+			{field: "editorUids", table: "curriculum_editors", owner: "curriculum_id", value: "user_uid"},
+			{field: "viewerUids", table: "curriculum_viewers", owner: "curriculum_id", value: "user_uid"},
+		}),
+	CollElements: newSQLTable("curriculum_elements", model.CurriculumElement{}, true,
+		[]string{"type", "techniqueId", "assetId", "title", "details", "imageUrl", "duration", "durationSeconds", "ord",
+			"snapshot", "snapshot.name", "snapshot.thumbnailUrl", "snapshot.url", "snapshot.description",
+			"snapshot.sourceUpdatedAt",
+			"createdAt", "updatedAt", "sourceElementId", "parentId", "startSeconds", "endSeconds", "caption"},
+		[]sqlArray{
+			{field: "items", table: "element_items", owner: "element_id", value: "item"},
+			{field: "snapshot.tagIds", table: "element_snapshot_tags", owner: "element_id", value: "tag_id"},
+		}),
+	CollRevisions: newSQLTable("curriculum_revisions", model.CurriculumRevision{}, true,
+		[]string{"number", "action", "actorUid", "restoredFrom", "title", "elementCount", "content", "createdAt"}, nil),
+	CollSessions: newSQLTable("sessions", model.Session{}, false,
+		[]string{"disciplineId", "curriculumId", "title", "startsAt", "timeZone", "duration", "durationSeconds",
+			"recurrence", "endsAt", "location", "ownerUid", "createdAt", "updatedAt"}, nil),
+	CollCalendarFeeds: newSQLTable("calendar_feeds", model.CalendarFeed{}, false,
+		[]string{"ownerUid", "disciplineId", "createdAt"}, nil),
+	CollProgress: newSQLTable("progress", model.Progress{}, false,
+		[]string{"uid", "disciplineId", "curriculumId", "elementId", "state", "notes", "startedAt", "completedAt", "updatedAt"}, nil),
+	CollTechniqueEdges: newSQLTable("technique_edges", model.TechniqueEdge{}, false,
+		[]string{"disciplineId", "fromId", "toId", "type", "notes", "ownerUid", "createdAt", "updatedAt"},
+		[]sqlArray{
+			{field: "assetIds", table: "technique_edge_assets", owner: "edge_id", value: "asset_id"},
+		}),
+}
+
+// newSQLTable builds a table descriptor, deriving column names (snake_case of
+// the field path) and optionality from the model's firestore tags.
+func newSQLTable(name string, doc interface{}, scoped bool, fields []string, arrays []sqlArray) *sqlTable {
+	t := &sqlTable{name: name, typ: reflect.TypeOf(doc), scoped: scoped, arrays: arrays}
+	for _, f := range fields {
+		ft, omit := fieldType(t.typ, f)
+		col := sqlColumn{field: f, name: snakeCase(f), omitEmpty: omit}
+		if ft.Kind() == reflect.Ptr && ft.Elem().Kind() == reflect.Struct {
+			col.name = "has_" + col.name
+			col.presence = true
+		}
+		t.columns = append(t.columns, col)
+	}
+	return t
+}
+
+// fieldType resolves a dotted stored field path on a struct type. omitEmpty
+// reports whether the field may be absent from a stored document.
+func fieldType(t reflect.Type, path string) (reflect.Type, bool) {
+	omit := false
+	for _, part := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		sf, ok := storedFields(t)[part]
+		if !ok {
+			panic(fmt.Sprintf("store: %s has no stored field %s", t, path))
+		}
+		f := t.FieldByIndex(sf.index)
+		omit = omit || sf.omitEmpty
+		t = f.Type
+	}
+	return t, omit
+}
+
+func snakeCase(path string) string {
+	var b strings.Builder
+	for i, r := range path {
+		switch {
+		case r == '.':
+			b.WriteByte('_')
+		case r >= 'A' && r <= 'Z':
+			if i > 0 && path[i-1] != '.' {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r - 'A' + 'a')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func (t *sqlTable) column(field string) (sqlColumn, bool) {
+	for _, c := range t.columns {
+		if c.field == field && !c.presence {
+			return c, true
+		}
+	}
+	return sqlColumn{}, false
+}
+
+func (t *sqlTable) array(field string) (sqlArray, bool) {
+	for _, a := range t.arrays {
+		if a.field == field {
+			return a, true
+		}
+	}
+	return sqlArray{}, false
+}
+
+// ownerColumns returns the join table columns identifying the owning row.
+func (t *sqlTable) ownerColumns(a sqlArray) []string {
+	if t.scoped {
+		return []string{"curriculum_id", a.owner}
+	}
+	return []string{a.owner}
+}
+
+// keyColumns returns the primary key columns of the table.
+func (t *sqlTable) keyColumns() []string {
+	if t.scoped {
+		return []string{"curriculum_id", "id"}
+	}
+	return []string{"id"}
+}
+
+// sqlTableFor resolves the table and curriculum scope of a collection path.
+func sqlTableFor(path string) (*sqlTable, string, error) {
+	t, ok := sqlTables[collectionKind(path)]
+	if !ok {
+		return nil, "", fmt.Errorf("store: unknown collection %q", path)
+	}
+	scope := ""
+	if t.scoped {
+		parts := strings.Split(path, "/")
+		if len(parts) != 3 || parts[0] != CollCurricula {
+			return nil, "", fmt.Errorf("store: invalid subcollection path %q", path)
+		}
+		scope = parts[1]
+	}
+	return t, scope, nil
+}
+
+// encodeRow converts a document into column values in t.columns order and
+// the values of each array field in t.arrays order.
+func (t *sqlTable) encodeRow(doc reflect.Value) ([]interface{}, [][]interface{}) {
+	values := make([]interface{}, len(t.columns))
+	for i, c := range t.columns {
+		v, present := lookupField(doc, c.field)
+		if c.presence {
+			values[i] = boolToInt(present)
+			continue
+		}
+		if !present {
+			continue
+		}
+		values[i] = sqlArg(v)
+	}
+	arrays := make([][]interface{}, len(t.arrays))
+	for i, a := range t.arrays {
+		v, _ := lookupField(doc, a.field)
+		items, _ := v.([]interface{})
+		for _, item := range items {
+			arrays[i] = append(arrays[i], sqlArg(item))
+		}
+	}
+	return values, arrays
+}
+
+// decodeColumn stores a scanned column value into the document at ptr.
+func (t *sqlTable) decodeColumn(ptr reflect.Value, c sqlColumn, raw interface{}) error {
+	if raw == nil {
+		return nil
+	}
+	if c.presence {
+		if n, err := rawInt(raw); err != nil || n == 0 {
+			return err
+		}
+		f, err := fieldAt(ptr, c.field)
+		if err != nil {
+			return err
+		}
+		if f.IsNil() {
+			f.Set(reflect.New(f.Type().Elem()))
+		}
+		return nil
+	}
+	f, err := fieldAt(ptr, c.field)
+	if err != nil {
+		return err
+	}
+	if f.Kind() == reflect.Ptr {
+		f.Set(reflect.New(f.Type().Elem()))
+		f = f.Elem()
+	}
+	return decodeRaw(f, raw)
+}
+
+// decodeArray stores the values of a join table into the document at ptr.
+// Fields that are not omitempty decode to an empty slice rather than nil.
+func (t *sqlTable) decodeArray(ptr reflect.Value, a sqlArray, items []string) error {
+	_, omit := fieldType(t.typ, a.field)
+	if len(items) == 0 && omit {
+		return nil
+	}
+	if items == nil {
+		items = []string{}
+	}
+	return applyUpdate(ptr, a.field, items)
+}
+
+// fieldAt returns the settable field at a dotted path, allocating nil
+// intermediate pointers on the way.
+func fieldAt(ptr reflect.Value, path string) (reflect.Value, error) {
+	v := ptr
+	for _, part := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		sf, ok := storedFields(v.Type())[part]
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("store: unknown field %s", path)
+		}
+		v = v.FieldByIndex(sf.index)
+	}
+	return v, nil
+}
+
+// decodeRaw converts a driver value into the kind of dst.
+func decodeRaw(dst reflect.Value, raw interface{}) error {
+	if dst.Type() == reflect.TypeOf(time.Time{}) {
+		s, err := rawString(raw)
+		if err != nil {
+			if tv, ok := raw.(time.Time); ok {
+				dst.Set(reflect.ValueOf(tv.UTC()))
+				return nil
+			}
+			return err
+		}
+		tv, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return fmt.Errorf("store: invalid timestamp %q: %w", s, err)
+		}
+		dst.Set(reflect.ValueOf(tv))
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.String:
+		s, err := rawString(raw)
+		if err != nil {
+			return err
+		}
+		dst.SetString(s)
+	case reflect.Bool:
+		if b, ok := raw.(bool); ok {
+			dst.SetBool(b)
+			return nil
+		}
+		n, err := rawInt(raw)
+		if err != nil {
+			return err
+		}
+		dst.SetBool(n != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := rawInt(raw)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(n)
+	default:
+		return fmt.Errorf("store: cannot decode %T into %s", raw, dst.Type())
+	}
+	return nil
+}
+
+func rawString(raw interface{}) (string, error) {
+	switch v := raw.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+	return "", fmt.Errorf("store: expected text, got %T", raw)
+}
+
+func rawInt(raw interface{}) (int64, error) {
+	switch v := raw.(type) {
+	case int64:
+		return v, nil
+	case int32:
+		return int64(v), nil
+	case int:
+		return int64(v), nil
+	case bool:
+		return boolToInt(v), nil
+	}
+	return 0, fmt.Errorf("store: expected integer, got %T", raw)
+}
+
+// sqlArg converts a normalized value into a driver argument.
+func sqlArg(v interface{}) interface{} {
+	switch x := v.(type) {
+	case bool:
+		return boolToInt(x)
+	case time.Time:
+		return x.UTC().Format(sqlTimeFormat)
+	}
+	return v
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,195 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/thomas/skillhive-api/internal/model"
+)
+
+// Collection names shared by every Store implementation.
+const (
+	CollDisciplines    = "disciplines"
+	CollTags           = "tags"
+	CollCategories     = "categories"
+	CollTechniques     = "techniques"
+	CollAssets         = "assets"
+	CollCurricula      = "curricula"
+	CollElements       = "elements"
+	CollRevisions      = "revisions"
+	CollSessions       = "sessions"
+	CollCalendarFeeds  = "calendarFeeds"
+	CollProgress       = "progress"
+	CollTechniqueEdges = "techniqueEdges"
+)
+
+// MaxBatchSize is the maximum number of writes a single Batch may commit.
+// It mirrors the Firestore limit so code behaves the same on every backend.
+const MaxBatchSize = 500
+
+var (
+	// ErrNotFound is returned when a document does not exist.
+	ErrNotFound = errors.New("document not found")
+	// ErrBatchTooLarge is returned when a batch exceeds MaxBatchSize writes.
+	ErrBatchTooLarge = errors.New("batch exceeds maximum number of writes")
+	// ErrReadAfterWrite is returned when a transaction reads after writing.
+	ErrReadAfterWrite = errors.New("transaction reads must come before writes")
+)
+
+// Store bundles the typed repositories backing the API. Implementations must
+// share query semantics so handlers behave identically on every backend.
+type Store interface {
+	Disciplines() DisciplineRepo
+	Tags() TagRepo
+	Categories() CategoryRepo
+	Techniques() TechniqueRepo
+	Assets() AssetRepo
+	Curricula() CurriculumRepo
+	Elements() ElementRepo
+	Revisions() RevisionRepo
+	Sessions() SessionRepo
+	CalendarFeeds() CalendarFeedRepo
+	Progress() ProgressRepo
+	TechniqueEdges() TechniqueEdgeRepo
+
+	// Batch starts a new atomic write batch spanning any collection.
+	Batch() Batch
+	// RunTransaction runs fn in a read-write transaction and commits its
+	// writes when fn returns nil. fn may be called again if the transaction
+	// conflicts with another one, and must only access the store through tx.
+	RunTransaction(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error
+
+	Close() error
+}
+
+// Repo is the common set of operations on a top-level collection.
+type Repo[T any] interface {
+	// Get returns the document with the given ID or ErrNotFound.
+	Get(ctx context.Context, id string) (*T, error)
+	// GetAll returns the documents with the given IDs in one round trip,
+	// leaving out missing ones, in no particular order.
+	GetAll(ctx context.Context, ids []string) ([]T, error)
+	// List returns all documents matching the query.
+	List(ctx context.Context, q Query) ([]T, error)
+	// Create stores a new document with a generated ID and returns that ID.
+	Create(ctx context.Context, doc *T) (string, error)
+	// Set creates or overwrites the document with the given ID.
+	Set(ctx context.Context, id string, doc *T) error
+	// Update applies field updates; it returns ErrNotFound for missing documents.
+	Update(ctx context.Context, id string, updates []Update) error
+	// Delete removes the document. Deleting a missing document is not an error.
+	Delete(ctx context.Context, id string) error
+	// Ref returns a reference to the document for use in a Batch.
+	Ref(id string) DocRef
+}
+
+type DisciplineRepo interface{ Repo[model.Discipline] }
+type TagRepo interface{ Repo[model.Tag] }
+type CategoryRepo interface{ Repo[model.Category] }
+type TechniqueRepo interface{ Repo[model.Technique] }
+type AssetRepo interface{ Repo[model.Asset] }
+type CurriculumRepo interface{ Repo[model.Curriculum] }
+type SessionRepo interface{ Repo[model.Session] }
+type CalendarFeedRepo interface{ Repo[model.CalendarFeed] }
+type ProgressRepo interface{ Repo[model.Progress] }
+type TechniqueEdgeRepo interface{ Repo[model.TechniqueEdge] }
+
+// SubRepo is the common set of operations on a subcollection of curricula.
+type SubRepo[T any] interface {
+	Get(ctx context.Context, curriculumID, id string) (*T, error)
+	List(ctx context.Context, curriculumID string, q Query) ([]T, error)
+	Create(ctx context.Context, curriculumID string, doc *T) (string, error)
+	Set(ctx context.Context, curriculumID, id string, doc *T) error
+	Update(ctx context.Context, curriculumID, id string, updates []Update) error
+	Delete(ctx context.Context, curriculumID, id string) error
+	Ref(curriculumID, id string) DocRef
+}
+
+// ElementRepo manages the elements subcollection of a curriculum.
+type ElementRepo interface {
+	SubRepo[model.CurriculumElement]
+}
+
+// RevisionRepo manages the revisions subcollection of a curriculum.
+type RevisionRepo interface {
+	SubRepo[model.CurriculumRevision]
+}
+
+// Update sets a single field, identified by its stored (firestore) name.
+type Update struct {
+	Path  string
+	Value interface{}
+}
+
+// DocRef identifies a document by its collection path and ID.
+type DocRef struct {
+	Collection string
+	ID         string
+}
+
+// Path returns the slash-separated document path.
+func (r DocRef) Path() string {
+	return r.Collection + "/" + r.ID
+}
+
+// ElementsPath returns the collection path of a curriculum's elements.
+func ElementsPath(curriculumID string) string {
+	return CollCurricula + "/" + curriculumID + "/" + CollElements
+}
+
+// RevisionsPath returns the collection path of a curriculum's revisions.
+func RevisionsPath(curriculumID string) string {
+	return CollCurricula + "/" + curriculumID + "/" + CollRevisions
+}
+
+// Batch groups writes that are committed atomically.
+type Batch interface {
+	Set(ref DocRef, doc interface{})
+	Update(ref DocRef, updates []Update)
+	Delete(ref DocRef)
+	// Len returns the number of writes queued so far.
+	Len() int
+	Commit(ctx context.Context) error
+}
+
+// CommitInBatches applies writes in batches of at most MaxBatchSize. Each
+// batch is atomic; the writes as a whole are not.
+func CommitInBatches(ctx context.Context, s Store, writes []func(Batch)) error {
+	for chunk := range slices.Chunk(writes, MaxBatchSize) {
+		batch := s.Batch()
+		for _, write := range chunk {
+			write(batch)
+		}
+		if err := batch.Commit(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tx is a read-write transaction. As in Firestore, all reads must happen
+// before the first write, and writes are applied when the transaction
+// commits, so they are not visible to later reads of the same transaction.
+type Tx interface {
+	// Get loads the document at ref into dst, a pointer to its model type,
+	// or returns ErrNotFound.
+	Get(ref DocRef, dst interface{}) error
+	// List loads the documents of a collection path matching q into dst, a
+	// pointer to a slice of the model type.
+	List(collection string, q Query, dst interface{}) error
+	Set(ref DocRef, doc interface{})
+	Update(ref DocRef, updates []Update)
+	Delete(ref DocRef)
+}
+
+// NewID returns a new random document ID, for documents created in a Batch
+// or Tx.
+func NewID() string {
+	id, err := newDocumentID()
+	if err != nil {
+		// crypto/rand does not fail on supported platforms.
+		panic(err)
+	}
+	return id
+}
@@ -2,6 +2,7 @@ package validate
 
 import (
 	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -26,3 +27,23 @@ func GenerateSlug(text string) string {
 	s = trailingHyphens.ReplaceAllString(s, "")
 	return s
 }
+
+// Aliases strips HTML and surrounding space from the aliases of something
+// named name, and drops those with the slug of the name or of an earlier
+// alias. It returns the aliases and their slugs.
+func Aliases(name string, raw []string) ([]string, []string, error) {
+	aliases, slugs := []string{}, []string{}
+	for _, a := range raw {
+		a = strings.TrimSpace(StripAllHTML(a))
+		if err := StringLength("alias", a, 1, 200); err != nil {
+			return nil, nil, err
+		}
+		slug := GenerateSlug(a)
+		if slug == "" || slug == GenerateSlug(name) || slices.Contains(slugs, slug) {
+			continue
+		}
+		aliases = append(aliases, a)
+		slugs = append(slugs, slug)
+	}
+	return aliases, slugs, nil
+}
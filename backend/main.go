@@ -10,13 +10,14 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/go-chi/chi/v5"
-	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/thomas/skillhive-api/internal/config"
+	"github.com/thomas/skillhive-api/internal/curriculum"
 	"github.com/thomas/skillhive-api/internal/enrich"
 	"github.com/thomas/skillhive-api/internal/handler"
 	"github.com/thomas/skillhive-api/internal/llm"
-	"github.com/thomas/skillhive-api/internal/middleware"
+	"github.com/thomas/skillhive-api/internal/model"
+	"github.com/thomas/skillhive-api/internal/search"
+	"github.com/thomas/skillhive-api/internal/server"
 	"github.com/thomas/skillhive-api/internal/store"
 )
 
@@ -24,12 +25,23 @@ func main() {
 	cfg := config.Load()
 
 	ctx := context.Background()
-	clients, err := store.NewFirebaseClients(ctx, cfg.GCPProject, cfg.FirebaseKeyPath)
+	clients, dataStore, err := openStore(ctx, cfg)
 	if err != nil {
-		slog.Error("failed to initialize Firebase", "error", err)
+		slog.Error("failed to initialize store", "backend", cfg.StoreBackend, "error", err)
 		os.Exit(1)
 	}
 	defer clients.Close()
+	defer dataStore.Close()
+
+	// Keep the full-text index current for every write, and fill it from the
+	// existing data in the background so startup is not delayed.
+	searchIndex := search.NewIndex()
+	dataStore = search.Wrap(dataStore, searchIndex)
+	go func() {
+		if err := searchIndex.Rebuild(ctx, dataStore); err != nil {
+			slog.Error("failed to build search index", "error", err)
+		}
+	}()
 
 	// Initialize enrichment pipeline (optional — degrades gracefully if keys missing)
 	var pipeline *enrich.Pipeline
@@ -49,107 +61,36 @@ func main() {
 			slog.Error("failed to create LLM client", "error", err)
 			os.Exit(1)
 		}
-		pipeline = enrich.NewPipeline(clients.Firestore, llmClient, cfg.YouTubeAPIKey)
+		pipeline = enrich.NewPipeline(dataStore, llmClient, cfg.YouTubeAPIKey)
 		slog.Info("enrichment pipeline initialized", "model", cfg.GeminiModel)
 	} else {
 		slog.Info("enrichment pipeline disabled (GEMINI_API_KEY or YOUTUBE_API_KEY not set)")
 	}
 
-	r := chi.NewRouter()
-
-	// Global middleware stack
-	r.Use(chimiddleware.RequestSize(1 << 20)) // 1MB
-	r.Use(chimiddleware.Timeout(30 * time.Second))
-	r.Use(middleware.CORSHandler(cfg.CORSAllowedOrigins).Handler)
-	r.Use(securityHeaders)
-	r.Use(middleware.Logging)
-
-	// Public routes
-	r.Get("/health", handler.HealthCheck)
-
-	// Handlers
-	disciplineHandler := handler.NewDisciplineHandler(clients.Firestore)
-	tagHandler := handler.NewTagHandler(clients.Firestore)
-	categoryHandler := handler.NewCategoryHandler(clients.Firestore)
-	techniqueHandler := handler.NewTechniqueHandler(clients.Firestore)
-	assetHandler := handler.NewAssetHandler(clients.Firestore, pipeline, enrichCtx)
-	oembedHandler := handler.NewOEmbedHandler()
-	curriculumHandler := handler.NewCurriculumHandler(clients.Firestore)
-	elementHandler := handler.NewElementHandler(clients.Firestore)
-	adminHandler := handler.NewAdminHandler(clients.Auth, clients.Firestore, pipeline, enrichCtx)
-
-	// Protected API routes
-	r.Route("/api/v1", func(r chi.Router) {
-		r.Use(middleware.FirebaseAuth(clients.Auth))
-
-		// Admin routes FIRST (to prevent /assets/{id} from matching /admin/assets/...)
-		r.Route("/admin", func(r chi.Router) {
-			r.Use(middleware.RequireAnyAdmin)
-
-			// User management
-			r.Get("/users", adminHandler.ListUsers)
-			r.Get("/users/search", adminHandler.SearchUsers)
-			r.Get("/users/{uid}", adminHandler.GetUser)
-			r.Put("/users/{uid}/role", adminHandler.SetRole)
-			r.Delete("/users/{uid}/role", adminHandler.RevokeRole)
-
-			// Asset processing management
-			r.Get("/assets", adminHandler.ListAssets)
-			r.Patch("/assets/{id}/active", adminHandler.ToggleAssetActive)
-			r.Post("/assets/{id}/enrich", adminHandler.RetryEnrichment)
-			r.Patch("/assets/{id}/status", adminHandler.UpdateAssetStatus)
-		})
-
-		// Disciplines (read-only)
-		r.Get("/disciplines", disciplineHandler.List)
-
-		// Tags
-		r.Get("/tags", tagHandler.List)
-		r.Post("/tags", tagHandler.Create)
-		r.Get("/tags/{id}", tagHandler.Get)
-		r.Patch("/tags/{id}", tagHandler.Update)
-		r.Delete("/tags/{id}", tagHandler.Delete)
-
-		// Categories
-		r.Get("/categories", categoryHandler.List)
-		r.Post("/categories", categoryHandler.Create)
-		r.Get("/categories/{id}", categoryHandler.Get)
-		r.Patch("/categories/{id}", categoryHandler.Update)
-		r.Delete("/categories/{id}", categoryHandler.Delete)
-
-		// Techniques
-		r.Get("/techniques", techniqueHandler.List)
-		r.Post("/techniques", techniqueHandler.Create)
-		r.Get("/techniques/{id}", techniqueHandler.Get)
-		r.Patch("/techniques/{id}", techniqueHandler.Update)
-		r.Delete("/techniques/{id}", techniqueHandler.Delete)
-
-		// Assets
-		r.Get("/assets", assetHandler.List)
-		r.Post("/assets", assetHandler.Create)
-		r.Get("/assets/{id}", assetHandler.Get)
-		r.Patch("/assets/{id}", assetHandler.Update)
-		r.Delete("/assets/{id}", assetHandler.Delete)
-
-		// YouTube oEmbed
-		r.Post("/youtube/resolve", oembedHandler.ResolveYouTube)
-
-		// Curricula
-		r.Get("/curricula", curriculumHandler.List)
-		r.Get("/curricula/public", curriculumHandler.ListPublic)
-		r.Post("/curricula", curriculumHandler.Create)
-		r.Get("/curricula/{id}", curriculumHandler.Get)
-		r.Patch("/curricula/{id}", curriculumHandler.Update)
-		r.Delete("/curricula/{id}", curriculumHandler.Delete)
-
-		// Curriculum elements
-		r.Get("/curricula/{id}/elements", elementHandler.ListElements)
-		r.Post("/curricula/{id}/elements", elementHandler.CreateElement)
-		r.Put("/curricula/{id}/elements/{elemId}", elementHandler.UpdateElement)
-		r.Delete("/curricula/{id}/elements/{elemId}", elementHandler.DeleteElement)
-		r.Put("/curricula/{id}/elements/reorder", elementHandler.ReorderElements)
+	r := server.NewRouter(server.Deps{
+		Store:              dataStore,
+		Search:             searchIndex,
+		Verifier:           clients.Auth,
+		Users:              handler.NewFirebaseUserAdmin(clients.Auth),
+		Pipeline:           pipeline,
+		EnrichCtx:          enrichCtx,
+		CORSAllowedOrigins: cfg.CORSAllowedOrigins,
+		DeletePolicies: handler.DeletePolicies{
+			Tags:       model.DeletePolicy(cfg.DeletePolicyTags),
+			Categories: model.DeletePolicy(cfg.DeletePolicyCategories),
+			Techniques: model.DeletePolicy(cfg.DeletePolicyTechniques),
+			Assets:     model.DeletePolicy(cfg.DeletePolicyAssets),
+		},
 	})
 
+	// Refresh the snapshots of technique and asset elements whose source
+	// changed, across all curricula.
+	sweepCtx, sweepCancel := context.WithCancel(ctx)
+	defer sweepCancel()
+	if cfg.SnapshotSweepInterval > 0 {
+		go sweepSnapshots(sweepCtx, dataStore, cfg.SnapshotSweepInterval)
+	}
+
 	addr := fmt.Sprintf(":%s", cfg.Port)
 	srv := &http.Server{
 		Addr:         addr,
@@ -176,6 +117,7 @@ func main() {
 
 	// Cancel enrichment context to signal goroutines
 	enrichCancel()
+	sweepCancel()
 
 	// Wait for in-flight enrichments to drain
 	if pipeline != nil {
@@ -193,12 +135,84 @@ func main() {
 	slog.Info("server stopped")
 }
 
-func securityHeaders(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-		w.Header().Set("X-Frame-Options", "DENY")
-		w.Header().Set("X-XSS-Protection", "1; mode=block")
-		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
-		next.ServeHTTP(w, r)
-	})
+// openStore initializes Firebase and the data store selected by STORE_BACKEND.
+// "memory" keeps all data in process (seeded with the default disciplines),
+// "sqlite" and "postgres" use the database at DATABASE_URL; these only need
+// Firebase Auth. "firestore" uses Cloud Firestore.
+func openStore(ctx context.Context, cfg *config.Config) (*store.FirebaseClients, store.Store, error) {
+	switch cfg.StoreBackend {
+	case "memory":
+		clients, err := store.NewFirebaseAuthClients(ctx, cfg.GCPProject, cfg.FirebaseKeyPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		mem := store.NewMemory()
+		seedDisciplines(ctx, mem)
+		slog.Info("using in-memory store; data is lost on restart")
+		return clients, mem, nil
+	case "sqlite", "postgres":
+		clients, err := store.NewFirebaseAuthClients(ctx, cfg.GCPProject, cfg.FirebaseKeyPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		db, err := store.NewSQL(ctx, cfg.StoreBackend, cfg.DatabaseURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		seedDisciplines(ctx, db)
+		slog.Info("using SQL store", "driver", cfg.StoreBackend)
+		return clients, db, nil
+	case "firestore":
+		clients, err := store.NewFirebaseClients(ctx, cfg.GCPProject, cfg.FirebaseKeyPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		return clients, store.NewFirestore(clients.Firestore), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown STORE_BACKEND %q (want firestore, sqlite, postgres or memory)", cfg.StoreBackend)
+	}
+}
+
+// seedDisciplines adds the default disciplines that are missing from the store.
+func seedDisciplines(ctx context.Context, s store.Store) {
+	now := time.Now()
+	for _, d := range []model.Discipline{
+		{ID: "bjj", Name: "Brazilian Jiu-Jitsu", Slug: "bjj"},
+		{ID: "jkd", Name: "Jeet Kune Do", Slug: "jkd"},
+	} {
+		if _, err := s.Disciplines().Get(ctx, d.ID); err == nil {
+			continue
+		}
+		d.CreatedAt, d.UpdatedAt = now, now
+		if err := s.Disciplines().Set(ctx, d.ID, &d); err != nil {
+			slog.Error("failed to seed discipline", "slug", d.Slug, "error", err)
+		}
+	}
+}
+
+// sweepSnapshots refreshes the stale element snapshots of every curriculum
+// once per interval, until ctx is cancelled.
+func sweepSnapshots(ctx context.Context, s store.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		checked, err := curriculum.RefreshAll(ctx, s,
+			func(r curriculum.RefreshReport) {
+				slog.Info("refreshed curriculum snapshots", "curriculumId", r.CurriculumID,
+					"refreshed", len(r.Refreshed), "broken", len(r.Broken))
+			},
+			func(id string, err error) {
+				slog.Error("failed to refresh curriculum snapshots", "curriculumId", id, "error", err)
+			})
+		if err != nil {
+			slog.Error("snapshot sweep failed", "checked", checked, "error", err)
+			continue
+		}
+		slog.Info("snapshot sweep finished", "checked", checked)
+	}
 }
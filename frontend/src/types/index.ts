@@ -58,6 +58,7 @@ export interface Technique extends TimestampFields {
   disciplineId: string
   name: string
   slug: string
+  aliases: string[]
   description: string
   categoryIds: string[]
   tagIds: string[]
@@ -66,6 +67,94 @@ export interface Technique extends TimestampFields {
   tags?: Tag[]
 }
 
+export type DeletePolicy = 'restrict' | 'detach' | 'reassign'
+
+export interface InvalidReference {
+  field: string
+  missing?: string[]
+  otherDiscipline?: string[]
+}
+
+export interface ReferenceError {
+  error: string
+  fields: InvalidReference[]
+}
+
+export interface CurriculumUsage {
+  curriculumId: string
+  title: string
+  tagged: boolean
+  elementIds: string[]
+}
+
+export interface Usages {
+  total: number
+  techniqueIds: string[]
+  assetIds: string[]
+  categoryIds: string[]
+  edgeIds: string[]
+  curricula: CurriculumUsage[]
+}
+
+export interface MergedCurriculum {
+  curriculumId: string
+  title: string
+  elementIds: string[]
+}
+
+export interface TechniqueMergeReport {
+  technique: Technique
+  sourceIds: string[]
+  aliasesAdded: string[]
+  assetIds: string[]
+  curricula: MergedCurriculum[]
+  edgesMoved: string[]
+  edgesRemoved: string[]
+  applied: boolean
+}
+
+export type TechniqueEdgeType = 'leads-to' | 'counters' | 'setup-for' | 'variation-of' | 'requires'
+
+export interface TechniqueEdge extends TimestampFields {
+  id: string
+  disciplineId: string
+  fromId: string
+  toId: string
+  type: TechniqueEdgeType
+  notes: string
+  assetIds: string[]
+  ownerUid: string
+}
+
+export interface GraphNode {
+  id: string
+  name: string
+  slug: string
+}
+
+export interface TechniqueNeighbor {
+  direction: 'out' | 'in'
+  edge: TechniqueEdge
+  technique: GraphNode
+}
+
+export interface TechniquePath {
+  length: number
+  techniques: GraphNode[]
+  edges: TechniqueEdge[]
+}
+
+export interface TechniqueChains {
+  chains: TechniquePath[]
+  truncated: boolean
+}
+
+export interface TechniqueGraph {
+  disciplineId: string
+  nodes: GraphNode[]
+  edges: TechniqueEdge[]
+}
+
 export type AssetType = 'video' | 'web' | 'image'
 export type VideoType = 'short' | 'full' | 'instructional' | 'seminar'
 
@@ -87,6 +176,7 @@ export interface Asset extends TimestampFields {
   processingStatus: '' | 'pending' | 'enriching' | 'completed' | 'failed'
   processingError: string | null
   duration: string | null
+  durationSeconds: number | null
 }
 
 export interface Curriculum extends TimestampFields {
@@ -95,14 +185,120 @@ export interface Curriculum extends TimestampFields {
   title: string
   description: string
   duration?: string | null
+  durationSeconds?: number
   isPublic: boolean
   ownerUid: string
   elementCount?: number
+  totalDurationSeconds?: number
+  totalDuration?: string
+  revision?: number
+  sourceCurriculumId?: string
+  sourceRevision?: number
   tagIds: string[]
   allTagIds: string[]
 }
 
-export type ElementType = 'technique' | 'asset' | 'text' | 'image' | 'list'
+export type CollaboratorRole = 'viewer' | 'editor'
+
+export interface Collaborator {
+  uid: string
+  role: CollaboratorRole
+  email?: string
+  displayName?: string
+}
+
+export interface ShareLink {
+  token: string
+  path: string
+  expiresAt: string | null
+}
+
+export interface SharedCurriculum {
+  title: string
+  description: string
+  duration?: string
+  elementCount: number
+  totalDurationSeconds: number
+  totalDuration: string
+  updatedAt: string
+  elements: CurriculumElement[]
+}
+
+export type ProgressState = 'not-started' | 'watched' | 'drilled' | 'mastered'
+
+export interface Progress {
+  uid: string
+  disciplineId: string
+  curriculumId: string
+  elementId: string
+  state: ProgressState
+  notes: string
+  startedAt?: string
+  completedAt?: string
+  updatedAt: string
+}
+
+export interface ProgressCounts {
+  notStarted: number
+  watched: number
+  drilled: number
+  mastered: number
+}
+
+export interface CurriculumProgress {
+  curriculumId: string
+  title: string
+  total: number
+  counts: ProgressCounts
+  percentComplete: number
+  updatedAt?: string
+  elements?: Progress[]
+}
+
+export interface ProgressReport {
+  curriculumId: string
+  title: string
+  total: number
+  learners: number
+  completed: number
+  averagePercent: number
+  counts: ProgressCounts
+}
+
+export interface Session {
+  id: string
+  disciplineId: string
+  curriculumId: string
+  title: string
+  startsAt: string
+  timeZone: string
+  duration: string
+  durationSeconds: number
+  recurrence: string
+  location: string
+  ownerUid: string
+  createdAt: string
+  updatedAt: string
+}
+
+export interface Occurrence {
+  sessionId: string
+  curriculumId: string
+  title: string
+  startsAt: string
+  endsAt: string
+  location: string
+}
+
+export interface CalendarFeed {
+  id: string
+  ownerUid: string
+  disciplineId: string
+  createdAt: string
+  path: string
+}
+
+export type ElementType = 'technique' | 'asset' | 'text' | 'image' | 'list' | 'section' | 'clip'
 
 export interface CurriculumElement extends TimestampFields {
   id: string
@@ -113,17 +309,31 @@ export interface CurriculumElement extends TimestampFields {
   details: string | null
   imageUrl?: string | null
   duration?: string | null
+  durationSeconds?: number
   items?: string[]
   ord: number
+  parentId: string | null
+  totalDurationSeconds?: number
+  children?: CurriculumElement[]
+  sourceElementId?: string
+  startSeconds?: number
+  endSeconds?: number
+  caption?: string
+  clipUrl?: string
+  embedUrl?: string
   snapshot?: {
     name?: string
     thumbnailUrl?: string
     url?: string
     description?: string
     tagIds?: string[]
+    sourceUpdatedAt?: string
   }
+  snapshotStatus?: SnapshotStatus
 }
 
+export type SnapshotStatus = 'current' | 'stale' | 'broken'
+
 export interface OEmbedResponse {
   type: string
   version: string
@@ -42,16 +42,46 @@ export const curriculumSchema = z.object({
   tagIds: z.array(z.string()).optional().default([]),
 })
 
-export const curriculumElementSchema = z.object({
-  type: z.enum(['technique', 'asset', 'text', 'image', 'list']),
-  techniqueId: z.string().nullable().optional(),
-  assetId: z.string().nullable().optional(),
-  title: z.string().max(300).nullable().optional(),
-  details: z.string().max(5000).nullable().optional(),
-  imageUrl: z.string().url().nullable().optional(),
-  duration: z.string().max(50).nullable().optional(),
-  items: z.array(z.string().max(500)).max(100).optional(),
-})
+export const curriculumElementSchema = z
+  .object({
+    type: z.enum(['technique', 'asset', 'text', 'image', 'list', 'section', 'clip']),
+    techniqueId: z.string().nullable().optional(),
+    assetId: z.string().nullable().optional(),
+    title: z.string().max(300).nullable().optional(),
+    details: z.string().max(5000).nullable().optional(),
+    imageUrl: z.string().url().nullable().optional(),
+    duration: z.string().max(50).nullable().optional(),
+    items: z.array(z.string().max(500)).max(100).optional(),
+    parentId: z.string().nullable().optional(),
+    startSeconds: z.number().int().min(0).nullable().optional(),
+    endSeconds: z.number().int().nullable().optional(),
+    caption: z.string().max(500).nullable().optional(),
+  })
+  .superRefine((el, ctx) => {
+    const hasClipFields = el.startSeconds != null || el.endSeconds != null || el.caption != null
+    if (el.type !== 'clip') {
+      if (hasClipFields) {
+        ctx.addIssue({
+          code: 'custom',
+          message: 'startSeconds, endSeconds and caption are only for clip elements',
+        })
+      }
+      return
+    }
+    if (el.startSeconds == null || el.endSeconds == null) {
+      ctx.addIssue({
+        code: 'custom',
+        path: ['endSeconds'],
+        message: 'Start and end are required for clips',
+      })
+    } else if (el.endSeconds <= el.startSeconds) {
+      ctx.addIssue({
+        code: 'custom',
+        path: ['endSeconds'],
+        message: 'End must be after start',
+      })
+    }
+  })
 
 export type TagFormData = z.infer<typeof tagSchema>
 export type CategoryFormData = z.infer<typeof categorySchema>